@@ -2199,6 +2199,23 @@ func (r *ProtocolIncus) GetInstanceAccess(name string) (api.Access, error) {
 	return access, nil
 }
 
+// GetInstanceNetworkACLRules returns the rules of the network ACLs applying to the instance NICs, keyed by device name.
+func (r *ProtocolIncus) GetInstanceNetworkACLRules(name string) (map[string]api.NetworkACLNICRules, error) {
+	if !r.HasExtension("network_acl_nic_rules") {
+		return nil, fmt.Errorf(`The server is missing the required "network_acl_nic_rules" API extension`)
+	}
+
+	nicRules := map[string]api.NetworkACLNICRules{}
+
+	// Fetch the raw value.
+	_, err := r.queryStruct("GET", fmt.Sprintf("/instances/%s/network-acls", url.PathEscape(name)), nil, "", &nicRules)
+	if err != nil {
+		return nil, err
+	}
+
+	return nicRules, nil
+}
+
 // GetInstanceLogfiles returns a list of logfiles for the instance.
 func (r *ProtocolIncus) GetInstanceLogfiles(name string) ([]string, error) {
 	path, _, err := r.instanceTypeToPath(api.InstanceTypeAny)
@@ -5,6 +5,7 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"strings"
 
 	"github.com/lxc/incus/v6/shared/api"
 )
@@ -59,6 +60,95 @@ func (r *ProtocolIncus) GetNetworkACLsAllProjects() ([]api.NetworkACL, error) {
 	return acls, nil
 }
 
+// GetNetworkACLsWithFilter returns a filtered list of Network ACL structs.
+func (r *ProtocolIncus) GetNetworkACLsWithFilter(filters []string) ([]api.NetworkACL, error) {
+	if !r.HasExtension("network_acl_filter") {
+		return nil, fmt.Errorf(`The server is missing the required "network_acl_filter" API extension`)
+	}
+
+	acls := []api.NetworkACL{}
+
+	v := url.Values{}
+	v.Set("recursion", "1")
+	v.Set("filter", parseFilters(filters))
+
+	// Fetch the raw value.
+	_, err := r.queryStruct("GET", fmt.Sprintf("/network-acls?%s", v.Encode()), nil, "", &acls)
+	if err != nil {
+		return nil, err
+	}
+
+	return acls, nil
+}
+
+// SearchNetworkACLRules returns the Network ACL rules whose description contains the search string, ignoring case.
+func (r *ProtocolIncus) SearchNetworkACLRules(search string) ([]api.NetworkACLRuleSearchResult, error) {
+	if !r.HasExtension("network_acl_filter") {
+		return nil, fmt.Errorf(`The server is missing the required "network_acl_filter" API extension`)
+	}
+
+	acls := []api.NetworkACL{}
+
+	v := url.Values{}
+	v.Set("recursion", "1")
+	v.Set("search", search)
+
+	// Fetch the ACLs with matching rules or description.
+	_, err := r.queryStruct("GET", fmt.Sprintf("/network-acls?%s", v.Encode()), nil, "", &acls)
+	if err != nil {
+		return nil, err
+	}
+
+	results := []api.NetworkACLRuleSearchResult{}
+	search = strings.ToLower(search)
+
+	for _, acl := range acls {
+		for _, direction := range []string{"ingress", "egress"} {
+			rules := acl.Ingress
+			if direction == "egress" {
+				rules = acl.Egress
+			}
+
+			for i, rule := range rules {
+				if !strings.Contains(strings.ToLower(rule.Description), search) {
+					continue
+				}
+
+				results = append(results, api.NetworkACLRuleSearchResult{
+					Project:     acl.Project,
+					ACL:         acl.Name,
+					Direction:   direction,
+					Index:       i,
+					Description: rule.Description,
+				})
+			}
+		}
+	}
+
+	return results, nil
+}
+
+// FindNetworkACLRulesBySubject returns the Network ACL rules with a subject equal to, containing, contained in or
+// overlapping with the provided subject.
+func (r *ProtocolIncus) FindNetworkACLRulesBySubject(subject string) ([]api.NetworkACLRuleMatch, error) {
+	if !r.HasExtension("network_acl_subject_search") {
+		return nil, fmt.Errorf(`The server is missing the required "network_acl_subject_search" API extension`)
+	}
+
+	results := []api.NetworkACLRuleMatch{}
+
+	v := url.Values{}
+	v.Set("subject", subject)
+
+	// Fetch the raw value.
+	_, err := r.queryStruct("GET", fmt.Sprintf("/network-acls?%s", v.Encode()), nil, "", &results)
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
 // GetNetworkACL returns a Network ACL entry for the provided name.
 func (r *ProtocolIncus) GetNetworkACL(name string) (*api.NetworkACL, string, error) {
 	if !r.HasExtension("network_acl") {
@@ -113,6 +203,129 @@ func (r *ProtocolIncus) GetNetworkACLLogfile(name string) (io.ReadCloser, error)
 	return resp.Body, err
 }
 
+// GetNetworkACLLogStream returns a reader streaming the new ACL log entries as they get logged.
+// The stream stays open until the reader is closed.
+func (r *ProtocolIncus) GetNetworkACLLogStream(name string) (io.ReadCloser, error) {
+	if !r.HasExtension("network_acl_log_follow") {
+		return nil, fmt.Errorf(`The server is missing the required "network_acl_log_follow" API extension`)
+	}
+
+	// Prepare the HTTP request
+	url := fmt.Sprintf("%s/1.0/network-acls/%s/log?follow=true", r.httpBaseURL.String(), url.PathEscape(name))
+	url, err := r.setQueryAttributes(url)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// Send the request
+	resp, err := r.DoHTTP(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Check the return value for a cleaner error
+	if resp.StatusCode != http.StatusOK {
+		_, _, err := incusParseResponse(resp)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return resp.Body, err
+}
+
+// GetNetworkACLState returns whether the network ACL is in sync with the networks using it.
+func (r *ProtocolIncus) GetNetworkACLState(name string) (*api.NetworkACLState, error) {
+	if !r.HasExtension("network_acl_state") {
+		return nil, fmt.Errorf(`The server is missing the required "network_acl_state" API extension`)
+	}
+
+	aclState := api.NetworkACLState{}
+
+	// Fetch the raw value.
+	_, err := r.queryStruct("GET", fmt.Sprintf("/network-acls/%s/state", url.PathEscape(name)), nil, "", &aclState)
+	if err != nil {
+		return nil, err
+	}
+
+	return &aclState, nil
+}
+
+// GetNetworkACLConflicts returns the pairs of rules of the two network ACLs matching some of the same traffic with
+// conflicting actions.
+func (r *ProtocolIncus) GetNetworkACLConflicts(name string, other string) ([]api.NetworkACLRuleConflict, error) {
+	if !r.HasExtension("network_acl_conflicts") {
+		return nil, fmt.Errorf(`The server is missing the required "network_acl_conflicts" API extension`)
+	}
+
+	conflicts := []api.NetworkACLRuleConflict{}
+
+	v := url.Values{}
+	v.Set("with", other)
+
+	// Fetch the raw value.
+	_, err := r.queryStruct("GET", fmt.Sprintf("/network-acls/%s/conflicts?%s", url.PathEscape(name), v.Encode()), nil, "", &conflicts)
+	if err != nil {
+		return nil, err
+	}
+
+	return conflicts, nil
+}
+
+// GetNetworkACLRevisions returns the previous revisions of the network ACL (newest first).
+func (r *ProtocolIncus) GetNetworkACLRevisions(name string) ([]api.NetworkACLRevision, error) {
+	if !r.HasExtension("network_acl_revisions") {
+		return nil, fmt.Errorf(`The server is missing the required "network_acl_revisions" API extension`)
+	}
+
+	revisions := []api.NetworkACLRevision{}
+
+	// Fetch the raw value.
+	_, err := r.queryStruct("GET", fmt.Sprintf("/network-acls/%s/revisions?recursion=1", url.PathEscape(name)), nil, "", &revisions)
+	if err != nil {
+		return nil, err
+	}
+
+	return revisions, nil
+}
+
+// GetNetworkACLRevision returns a specific previous revision of the network ACL.
+func (r *ProtocolIncus) GetNetworkACLRevision(name string, revision int64) (*api.NetworkACLRevision, error) {
+	if !r.HasExtension("network_acl_revisions") {
+		return nil, fmt.Errorf(`The server is missing the required "network_acl_revisions" API extension`)
+	}
+
+	info := api.NetworkACLRevision{}
+
+	// Fetch the raw value.
+	_, err := r.queryStruct("GET", fmt.Sprintf("/network-acls/%s/revisions/%d", url.PathEscape(name), revision), nil, "", &info)
+	if err != nil {
+		return nil, err
+	}
+
+	return &info, nil
+}
+
+// RestoreNetworkACLRevision restores the network ACL to a previous revision.
+func (r *ProtocolIncus) RestoreNetworkACLRevision(name string, revision int64) error {
+	if !r.HasExtension("network_acl_revisions") {
+		return fmt.Errorf(`The server is missing the required "network_acl_revisions" API extension`)
+	}
+
+	// Send the request.
+	_, _, err := r.query("POST", fmt.Sprintf("/network-acls/%s/revisions/%d", url.PathEscape(name), revision), nil, "")
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
 // CreateNetworkACL defines a new network ACL using the provided struct.
 func (r *ProtocolIncus) CreateNetworkACL(acl api.NetworkACLsPost) error {
 	if !r.HasExtension("network_acl") {
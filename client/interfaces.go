@@ -155,6 +155,7 @@ type InstanceServer interface {
 	UpdateInstanceState(name string, state api.InstanceStatePut, ETag string) (op Operation, err error)
 
 	GetInstanceAccess(name string) (access api.Access, err error)
+	GetInstanceNetworkACLRules(name string) (nicRules map[string]api.NetworkACLNICRules, err error)
 
 	GetInstanceLogfiles(name string) (logfiles []string, err error)
 	GetInstanceLogfile(name string, filename string) (content io.ReadCloser, err error)
@@ -229,8 +230,17 @@ type InstanceServer interface {
 	GetNetworkACLNames() (names []string, err error)
 	GetNetworkACLs() (acls []api.NetworkACL, err error)
 	GetNetworkACLsAllProjects() (acls []api.NetworkACL, err error)
+	GetNetworkACLsWithFilter(filters []string) (acls []api.NetworkACL, err error)
+	SearchNetworkACLRules(search string) (results []api.NetworkACLRuleSearchResult, err error)
+	FindNetworkACLRulesBySubject(subject string) (results []api.NetworkACLRuleMatch, err error)
 	GetNetworkACL(name string) (acl *api.NetworkACL, ETag string, err error)
 	GetNetworkACLLogfile(name string) (log io.ReadCloser, err error)
+	GetNetworkACLLogStream(name string) (log io.ReadCloser, err error)
+	GetNetworkACLState(name string) (state *api.NetworkACLState, err error)
+	GetNetworkACLConflicts(name string, other string) (conflicts []api.NetworkACLRuleConflict, err error)
+	GetNetworkACLRevisions(name string) (revisions []api.NetworkACLRevision, err error)
+	GetNetworkACLRevision(name string, revision int64) (info *api.NetworkACLRevision, err error)
+	RestoreNetworkACLRevision(name string, revision int64) (err error)
 	CreateNetworkACL(acl api.NetworkACLsPost) (err error)
 	UpdateNetworkACL(name string, acl api.NetworkACLPut, ETag string) (err error)
 	RenameNetworkACL(name string, acl api.NetworkACLPost) (err error)
@@ -242,6 +242,8 @@ func (c *cmdNetworkACLShow) Run(cmd *cobra.Command, args []string) error {
 type cmdNetworkACLShowLog struct {
 	global     *cmdGlobal
 	networkACL *cmdNetworkACL
+
+	flagFollow bool
 }
 
 func (c *cmdNetworkACLShowLog) Command() *cobra.Command {
@@ -251,6 +253,8 @@ func (c *cmdNetworkACLShowLog) Command() *cobra.Command {
 	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G("Show network ACL log"))
 	cmd.RunE = c.Run
 
+	cmd.Flags().BoolVarP(&c.flagFollow, "follow", "f", false, i18n.G("Keep showing new log entries as they get logged"))
+
 	cmd.ValidArgsFunction = func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 		if len(args) == 0 {
 			return c.global.cmpNetworkACLs(toComplete)
@@ -281,7 +285,13 @@ func (c *cmdNetworkACLShowLog) Run(cmd *cobra.Command, args []string) error {
 	}
 
 	// Get the ACL log.
-	log, err := resource.server.GetNetworkACLLogfile(resource.name)
+	var log io.ReadCloser
+	if c.flagFollow {
+		log, err = resource.server.GetNetworkACLLogStream(resource.name)
+	} else {
+		log, err = resource.server.GetNetworkACLLogfile(resource.name)
+	}
+
 	if err != nil {
 		return err
 	}
@@ -1076,6 +1086,16 @@ func (c *cmdNetworkACLRule) RunRemove(cmd *cobra.Command, args []string) error {
 		}
 	}
 
+	// Normalise the subject filters like the subjects of the stored rules.
+	for _, k := range []string{"source", "destination"} {
+		v, found := filters[k]
+		if found {
+			filterRule := api.NetworkACLRule{Source: v}
+			filterRule.Normalise()
+			filters[k] = filterRule.Source
+		}
+	}
+
 	// isFilterMatch returns whether the supplied rule has matching field values in the filters supplied.
 	// If no filters are supplied, then the rule is considered to have matched.
 	isFilterMatch := func(rule *api.NetworkACLRule, filters map[string]string) bool {
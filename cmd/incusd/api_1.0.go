@@ -62,6 +62,7 @@ var api10 = []APIEndpoint{
 	instanceLogsCmd,
 	instanceMetadataCmd,
 	instanceMetadataTemplatesCmd,
+	instanceNetworkACLsCmd,
 	instancesCmd,
 	instanceRebuildCmd,
 	instanceSFTPCmd,
@@ -85,6 +86,10 @@ var api10 = []APIEndpoint{
 	networkACLCmd,
 	networkACLsCmd,
 	networkACLLogCmd,
+	networkACLStateCmd,
+	networkACLConflictsCmd,
+	networkACLRevisionsCmd,
+	networkACLRevisionCmd,
 	networkAllocationsCmd,
 	networkForwardCmd,
 	networkForwardsCmd,
@@ -1012,6 +1017,15 @@ func doApi10UpdateTriggers(d *Daemon, nodeChanged, clusterChanged map[string]str
 		}
 	}
 
+	// Compile and load the instance config scriptlet.
+	value, ok = clusterChanged["instances.config.scriptlet"]
+	if ok {
+		err := scriptletLoad.InstanceConfigSet(value)
+		if err != nil {
+			return fmt.Errorf("Failed saving instance config scriptlet: %w", err)
+		}
+	}
+
 	// Compile and load the instance placement scriptlet.
 	value, ok = clusterChanged["instances.placement.scriptlet"]
 	if ok {
@@ -61,6 +61,7 @@ var apiInternal = []APIEndpoint{
 	internalGarbageCollectorCmd,
 	internalImageOptimizeCmd,
 	internalImageRefreshCmd,
+	internalNetworkACLReconcileCmd,
 	internalRAFTSnapshotCmd,
 	internalReadyCmd,
 	internalShutdownCmd,
@@ -117,6 +118,12 @@ var internalGarbageCollectorCmd = APIEndpoint{
 	Get: APIEndpointAction{Handler: internalGC, AccessHandler: allowPermission(auth.ObjectTypeServer, auth.EntitlementCanEdit)},
 }
 
+var internalNetworkACLReconcileCmd = APIEndpoint{
+	Path: "network-acls/reconcile",
+
+	Post: APIEndpointAction{Handler: internalNetworkACLReconcile, AccessHandler: allowPermission(auth.ObjectTypeServer, auth.EntitlementCanEdit)},
+}
+
 var internalRAFTSnapshotCmd = APIEndpoint{
 	Path: "raft-snapshot",
 
@@ -1063,6 +1070,20 @@ func internalGC(d *Daemon, r *http.Request) response.Response {
 	return response.EmptySyncResponse
 }
 
+// internalNetworkACLReconcile checks for and repairs drift between network ACLs and OVN.
+// Drift is only reported when the dry-run query parameter is set. The response lists the missing port groups, the
+// stale ACLs and the orphaned port groups found (see acl.OVNDrift).
+func internalNetworkACLReconcile(d *Daemon, r *http.Request) response.Response {
+	dryRun := util.IsTrue(request.QueryParam(r, "dry-run"))
+
+	drift, err := networkACLReconcile(d.State(), dryRun)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.SyncResponse(true, drift)
+}
+
 func internalRAFTSnapshot(d *Daemon, r *http.Request) response.Response {
 	logger.Warn("Forced RAFT snapshot not supported")
 
@@ -19,6 +19,7 @@ import (
 	instanceDrivers "github.com/lxc/incus/v6/internal/server/instance/drivers"
 	"github.com/lxc/incus/v6/internal/server/locking"
 	"github.com/lxc/incus/v6/internal/server/metrics"
+	"github.com/lxc/incus/v6/internal/server/network/acl"
 	"github.com/lxc/incus/v6/internal/server/request"
 	"github.com/lxc/incus/v6/internal/server/response"
 	"github.com/lxc/incus/v6/internal/server/state"
@@ -34,6 +35,9 @@ type metricsCacheEntry struct {
 var metricsCache map[string]metricsCacheEntry
 var metricsCacheLock sync.Mutex
 
+// networkACLMetricsCache holds the network ACL metrics of each project (protected by metricsCacheLock).
+var networkACLMetricsCache map[string]metricsCacheEntry
+
 var metricsCmd = APIEndpoint{
 	Path: "metrics",
 
@@ -126,6 +130,10 @@ func metricsGet(d *Daemon, r *http.Request) response.Response {
 		return response.SmartError(err)
 	}
 
+	// Add network ACL metrics.
+	aclMetrics := networkACLMetrics(s, projectNames)
+	metricSet.Merge(aclMetrics)
+
 	// invalidProjectFilters returns project filters which are either not in cache or have expired.
 	invalidProjectFilters := func(projectNames []string) []dbCluster.InstanceFilter {
 		metricsCacheLock.Lock()
@@ -176,6 +184,7 @@ func metricsGet(d *Daemon, r *http.Request) response.Response {
 
 	// Setup a new response.
 	metricSet = metrics.NewMetricSet(nil)
+	metricSet.Merge(aclMetrics)
 
 	// Check if any of the missing data has been filled in since acquiring the lock.
 	// As its possible another request was already populating the cache when we tried to take the lock.
@@ -312,6 +321,42 @@ func getFilteredMetrics(s *state.State, r *http.Request, compress bool, metricSe
 	return response.SyncResponsePlain(true, compress, metricSet.String())
 }
 
+// networkACLMetrics returns the network ACL metrics of the specified projects.
+// The metrics of each project are cached for a few seconds so that scrapes don't repeatedly go through all the
+// users of the ACLs in the database.
+func networkACLMetrics(s *state.State, projectNames []string) *metrics.MetricSet {
+	out := metrics.NewMetricSet(nil)
+
+	metricsCacheLock.Lock()
+	defer metricsCacheLock.Unlock()
+
+	if networkACLMetricsCache == nil {
+		networkACLMetricsCache = map[string]metricsCacheEntry{}
+	}
+
+	for _, projectName := range projectNames {
+		cache, ok := networkACLMetricsCache[projectName]
+		if !ok || cache.expiry.Before(time.Now()) {
+			aclMetrics, err := acl.Metrics(s, projectName)
+			if err != nil {
+				logger.Warn("Failed to get network ACL metrics", logger.Ctx{"project": projectName, "err": err})
+				continue
+			}
+
+			cache = metricsCacheEntry{
+				expiry:  time.Now().Add(time.Duration(8) * time.Second),
+				metrics: aclMetrics,
+			}
+
+			networkACLMetricsCache[projectName] = cache
+		}
+
+		out.Merge(cache.metrics)
+	}
+
+	return out
+}
+
 func internalMetrics(ctx context.Context, daemonStartTime time.Time, tx *db.ClusterTx) *metrics.MetricSet {
 	out := metrics.NewMetricSet(nil)
 
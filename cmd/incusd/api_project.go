@@ -22,6 +22,7 @@ import (
 	"github.com/lxc/incus/v6/internal/server/db/operationtype"
 	"github.com/lxc/incus/v6/internal/server/lifecycle"
 	"github.com/lxc/incus/v6/internal/server/network"
+	"github.com/lxc/incus/v6/internal/server/network/acl"
 	"github.com/lxc/incus/v6/internal/server/operations"
 	projecthelpers "github.com/lxc/incus/v6/internal/server/project"
 	"github.com/lxc/incus/v6/internal/server/request"
@@ -334,7 +335,12 @@ func projectsPost(d *Daemon, r *http.Request) response.Response {
 		return response.BadRequest(err)
 	}
 
+	if project.Config["network.acls.seed"] != "" && util.IsFalseOrEmpty(project.Config["features.networks"]) {
+		return response.BadRequest(fmt.Errorf("Network ACLs can only be seeded into projects with features.networks enabled"))
+	}
+
 	var id int64
+	var systemACLs []string
 	err = s.DB.Cluster.Transaction(r.Context(), func(ctx context.Context, tx *db.ClusterTx) error {
 		id, err = cluster.CreateProject(ctx, tx.Tx(), cluster.Project{Description: project.Description, Name: project.Name})
 		if err != nil {
@@ -346,6 +352,13 @@ func projectsPost(d *Daemon, r *http.Request) response.Response {
 			return fmt.Errorf("Unable to create project config for project %q: %w", project.Name, err)
 		}
 
+		if util.IsTrue(project.Config["features.networks"]) {
+			systemACLs, err = acl.CreateSystemACLs(ctx, tx, project.Name)
+			if err != nil {
+				return err
+			}
+		}
+
 		if util.IsTrue(project.Config["features.profiles"]) {
 			err = projectCreateDefaultProfile(ctx, tx, project.Name)
 			if err != nil {
@@ -371,6 +384,43 @@ func projectsPost(d *Daemon, r *http.Request) response.Response {
 		logger.Error("Failed to add project to authorizer", logger.Ctx{"name": project.Name, "error": err})
 	}
 
+	for _, aclName := range systemACLs {
+		err = s.Authorizer.AddNetworkACL(r.Context(), project.Name, aclName)
+		if err != nil {
+			logger.Error("Failed to add network ACL to authorizer", logger.Ctx{"name": aclName, "project": project.Name, "error": err})
+		}
+	}
+
+	// Seed the project with copies of the requested network ACLs.
+	seedACLs := util.SplitNTrimSpace(project.Config["network.acls.seed"], ",", -1, true)
+	if len(seedACLs) > 0 {
+		createdACLs, err := acl.CopyToProject(s, api.ProjectDefaultName, project.Name, seedACLs, request.CreateRequestor(r))
+		if err != nil {
+			_ = s.DB.Cluster.Transaction(context.TODO(), func(ctx context.Context, tx *db.ClusterTx) error {
+				return cluster.DeleteProject(ctx, tx.Tx(), project.Name)
+			})
+
+			_ = s.Authorizer.DeleteProject(r.Context(), id, project.Name)
+
+			return response.SmartError(fmt.Errorf("Failed copying network ACLs into project %q: %w", project.Name, err))
+		}
+
+		for _, aclName := range createdACLs {
+			err = s.Authorizer.AddNetworkACL(r.Context(), project.Name, aclName)
+			if err != nil {
+				logger.Error("Failed to add network ACL to authorizer", logger.Ctx{"name": aclName, "project": project.Name, "error": err})
+			}
+
+			netACL, err := acl.LoadByName(s, project.Name, aclName)
+			if err != nil {
+				logger.Error("Failed to load copied network ACL", logger.Ctx{"name": aclName, "project": project.Name, "error": err})
+				continue
+			}
+
+			s.Events.SendLifecycle(project.Name, lifecycle.NetworkACLCreated.Event(netACL, request.CreateRequestor(r), nil))
+		}
+	}
+
 	requestor := request.CreateRequestor(r)
 	lc := lifecycle.ProjectCreated.Event(project.Name, requestor, nil)
 	s.Events.SendLifecycle(project.Name, lc)
@@ -726,6 +776,7 @@ func projectChange(ctx context.Context, s *state.State, project *api.Project, re
 	}
 
 	// Update the database entry.
+	var systemACLs []string
 	err = s.DB.Cluster.Transaction(ctx, func(ctx context.Context, tx *db.ClusterTx) error {
 		err := projecthelpers.AllowProjectUpdate(tx, project.Name, req.Config, configChanged)
 		if err != nil {
@@ -759,6 +810,13 @@ func projectChange(ctx context.Context, s *state.State, project *api.Project, re
 			}
 		}
 
+		if slices.Contains(configChanged, "features.networks") && util.IsTrue(req.Config["features.networks"]) {
+			systemACLs, err = acl.CreateSystemACLs(ctx, tx, project.Name)
+			if err != nil {
+				return err
+			}
+		}
+
 		return nil
 	})
 
@@ -766,6 +824,13 @@ func projectChange(ctx context.Context, s *state.State, project *api.Project, re
 		return response.SmartError(err)
 	}
 
+	for _, aclName := range systemACLs {
+		err = s.Authorizer.AddNetworkACL(ctx, project.Name, aclName)
+		if err != nil {
+			logger.Error("Failed to add network ACL to authorizer", logger.Ctx{"name": aclName, "project": project.Name, "error": err})
+		}
+	}
+
 	return response.EmptySyncResponse
 }
 
@@ -1453,6 +1518,29 @@ func projectValidateConfig(s *state.State, config map[string]string) error {
 		//  shortdesc: Maximum number of networks that the project can have
 		"limits.networks": validate.Optional(validate.IsUint32),
 
+		// gendoc:generate(entity=project, group=limits, key=limits.network_acls)
+		// This limit applies to the network ACLs stored in the project, which requires {config:option}`project-features:features.networks` to be enabled.
+		// ---
+		//  type: integer
+		//  shortdesc: Maximum number of network ACLs that the project can have
+		"limits.network_acls": validate.Optional(validate.IsUint32),
+
+		// gendoc:generate(entity=project, group=limits, key=limits.network_acl_rules)
+		// This value is the maximum value for the sum of the ingress and egress rules across all network ACLs of the project.
+		// ---
+		//  type: integer
+		//  shortdesc: Maximum number of network ACL rules that the project can have
+		"limits.network_acl_rules": validate.Optional(validate.IsUint32),
+
+		// gendoc:generate(entity=project, group=specific, key=network.acls.seed)
+		// Specify a comma-delimited list of network ACLs from the `default` project to copy into the project when it is created.
+		// References between the copied ACLs are preserved, and ACLs referencing other ACLs or network peers which aren't copied are skipped.
+		// This option requires {config:option}`project-features:features.networks` to be enabled and has no effect after the project is created.
+		// ---
+		//  type: string
+		//  shortdesc: Network ACLs to copy into the project on creation
+		"network.acls.seed": validate.Optional(validate.IsListOf(acl.ValidName)),
+
 		// gendoc:generate(entity=project, group=restricted, key=restricted)
 		// This option must be enabled to allow the `restricted.*` keys to take effect.
 		// To temporarily remove the restrictions, you can disable this option instead of clearing the related keys.
@@ -53,6 +53,7 @@ import (
 	instanceDrivers "github.com/lxc/incus/v6/internal/server/instance/drivers"
 	"github.com/lxc/incus/v6/internal/server/instance/instancetype"
 	"github.com/lxc/incus/v6/internal/server/loki"
+	"github.com/lxc/incus/v6/internal/server/network/acl"
 	"github.com/lxc/incus/v6/internal/server/network/ovn"
 	"github.com/lxc/incus/v6/internal/server/network/ovs"
 	networkZone "github.com/lxc/incus/v6/internal/server/network/zone"
@@ -1433,6 +1434,7 @@ func (d *Daemon) init() error {
 	syslogSocketEnabled := d.localConfig.SyslogSocket()
 	openfgaAPIURL, openfgaAPIToken, openfgaStoreID := d.globalConfig.OpenFGA()
 	instancePlacementScriptlet := d.globalConfig.InstancesPlacementScriptlet()
+	instanceConfigScriptlet := d.globalConfig.InstancesConfigScriptlet()
 
 	d.endpoints.NetworkUpdateTrustedProxy(d.globalConfig.HTTPSTrustedProxy())
 	d.globalConfigMu.Unlock()
@@ -1525,6 +1527,12 @@ func (d *Daemon) init() error {
 		logger.Info("Started DNS server")
 	}
 
+	// Create the missing network ACLs managed by the server.
+	err = acl.EnsureSystemACLs(d.State())
+	if err != nil {
+		logger.Warn("Failed creating system network ACLs", logger.Ctx{"err": err})
+	}
+
 	// Setup the networks.
 	if !d.db.Cluster.LocalNodeIsEvacuated() {
 		logger.Infof("Initializing networks")
@@ -1560,6 +1568,14 @@ func (d *Daemon) init() error {
 		}
 	}
 
+	// Load instance config scriptlet.
+	if instanceConfigScriptlet != "" {
+		err = scriptletLoad.InstanceConfigSet(instanceConfigScriptlet)
+		if err != nil {
+			logger.Warn("Failed loading instance config scriptlet", logger.Ctx{"err": err})
+		}
+	}
+
 	// Apply all patches that need to be run after networks are initialized.
 	err = patchesApply(d, patchPostNetworks)
 	if err != nil {
@@ -1683,6 +1699,15 @@ func (d *Daemon) init() error {
 
 		// Remove expired tokens (hourly)
 		d.tasks.Add(autoRemoveExpiredTokensTask(d))
+
+		// Apply network ACL rules entering or leaving their validity window (minutely)
+		d.tasks.Add(networkACLScheduleTask(d))
+
+		// Check for and repair drift between network ACLs and OVN (hourly)
+		d.tasks.Add(networkACLDriftTask(d))
+
+		// Resolve the host names used in network ACL rules (every 5 minutes)
+		d.tasks.Add(networkACLFQDNTask(d))
 	}
 
 	// Start all background tasks
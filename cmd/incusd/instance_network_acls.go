@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/gorilla/mux"
+
+	internalInstance "github.com/lxc/incus/v6/internal/instance"
+	"github.com/lxc/incus/v6/internal/server/instance"
+	"github.com/lxc/incus/v6/internal/server/network"
+	"github.com/lxc/incus/v6/internal/server/network/acl"
+	"github.com/lxc/incus/v6/internal/server/project"
+	"github.com/lxc/incus/v6/internal/server/request"
+	"github.com/lxc/incus/v6/internal/server/response"
+	"github.com/lxc/incus/v6/shared/api"
+)
+
+// swagger:operation GET /1.0/instances/{name}/network-acls instances instance_network_acls_get
+//
+//	Get the network ACL rules of the instance NICs
+//
+//	Gets the rules of the network ACLs applying to each NIC of the instance, keyed by device name.
+//	The rules of all the ACLs are merged in the order they are applied.
+//
+//	---
+//	produces:
+//	  - application/json
+//	parameters:
+//	  - in: query
+//	    name: project
+//	    description: Project name
+//	    type: string
+//	    example: default
+//	responses:
+//	  "200":
+//	    description: Network ACL rules
+//	    schema:
+//	      type: object
+//	      description: Sync response
+//	      properties:
+//	        type:
+//	          type: string
+//	          description: Response type
+//	          example: sync
+//	        status:
+//	          type: string
+//	          description: Status description
+//	          example: Success
+//	        status_code:
+//	          type: integer
+//	          description: Status code
+//	          example: 200
+//	        metadata:
+//	          type: object
+//	          additionalProperties:
+//	            $ref: "#/definitions/NetworkACLNICRules"
+//	  "400":
+//	    $ref: "#/responses/BadRequest"
+//	  "403":
+//	    $ref: "#/responses/Forbidden"
+//	  "404":
+//	    $ref: "#/responses/NotFound"
+//	  "500":
+//	    $ref: "#/responses/InternalServerError"
+func instanceNetworkACLsGet(d *Daemon, r *http.Request) response.Response {
+	s := d.State()
+
+	projectName := request.ProjectParam(r)
+	name, err := url.PathUnescape(mux.Vars(r)["name"])
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	if internalInstance.IsSnapshot(name) {
+		return response.BadRequest(fmt.Errorf("Invalid instance name"))
+	}
+
+	inst, err := instance.LoadByProjectAndName(s, projectName, name)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	// The ACLs and networks of the NICs are those of the instance's effective network project.
+	networkProjectName, _, err := project.NetworkProject(s.DB.Cluster, projectName)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	nicRules := map[string]*api.NetworkACLNICRules{}
+	for devName, devConfig := range inst.ExpandedDevices() {
+		// Only NICs connected to managed bridge and OVN networks can use network ACLs.
+		if devConfig["type"] != "nic" || devConfig["network"] == "" {
+			continue
+		}
+
+		n, err := network.LoadByName(s, networkProjectName, devConfig["network"])
+		if err != nil {
+			return response.SmartError(fmt.Errorf("Failed loading network %q of device %q: %w", devConfig["network"], devName, err))
+		}
+
+		if n.Type() != "bridge" && n.Type() != "ovn" {
+			continue
+		}
+
+		aclNet := acl.NetworkACLUsage{Name: n.Name(), Type: n.Type(), ID: n.ID(), Config: n.Config()}
+
+		nicRules[devName], err = acl.NICRules(s, networkProjectName, aclNet, devConfig)
+		if err != nil {
+			return response.SmartError(fmt.Errorf("Failed getting network ACL rules of device %q: %w", devName, err))
+		}
+	}
+
+	return response.SyncResponse(true, nicRules)
+}
@@ -165,6 +165,13 @@ var instanceAccessCmd = APIEndpoint{
 	Get: APIEndpointAction{Handler: instanceAccess, AccessHandler: allowPermission(auth.ObjectTypeInstance, auth.EntitlementCanView, "name")},
 }
 
+var instanceNetworkACLsCmd = APIEndpoint{
+	Name: "instanceNetworkACLs",
+	Path: "instances/{name}/network-acls",
+
+	Get: APIEndpointAction{Handler: instanceNetworkACLsGet, AccessHandler: allowPermission(auth.ObjectTypeInstance, auth.EntitlementCanView, "name")},
+}
+
 type instanceAutostartList []instance.Instance
 
 func (slice instanceAutostartList) Len() int {
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"maps"
 	"net/http"
 	"os"
 	"slices"
@@ -883,6 +884,7 @@ func instancesPost(d *Daemon, r *http.Request) response.Response {
 	var targetProject *api.Project
 	var profiles []api.Profile
 	var sourceInst *dbCluster.Instance
+	var sourceInstConfig map[string]string
 	var sourceImage *api.Image
 	var sourceImageRef string
 	var candidateMembers []db.NodeInfo
@@ -939,13 +941,15 @@ func instancesPost(d *Daemon, r *http.Request) response.Response {
 
 			req.Type = api.InstanceType(sourceInst.Type.String())
 
+			sourceInstArgs, err := tx.InstancesToInstanceArgs(ctx, true, *sourceInst)
+			if err != nil {
+				return err
+			}
+
+			sourceInstConfig = sourceInstArgs[sourceInst.ID].Config
+
 			// Use source instance's profiles if no profile override.
 			if req.Profiles == nil {
-				sourceInstArgs, err := tx.InstancesToInstanceArgs(ctx, true, *sourceInst)
-				if err != nil {
-					return err
-				}
-
 				req.Profiles = make([]string, 0, len(sourceInstArgs[sourceInst.ID].Profiles))
 				for _, profile := range sourceInstArgs[sourceInst.ID].Profiles {
 					req.Profiles = append(req.Profiles, profile.Name)
@@ -1075,21 +1079,47 @@ func instancesPost(d *Daemon, r *http.Request) response.Response {
 			}
 		}
 
-		if !clusterNotification {
-			// Check that the project's limits are not violated. Note this check is performed after
-			// automatically generated config values (such as ones from an InstanceType) have been set.
-			err = project.AllowInstanceCreation(tx, targetProjectName, req)
-			if err != nil {
-				return err
-			}
-		}
-
 		return nil
 	})
 	if err != nil {
 		return response.SmartError(err)
 	}
 
+	// Run instance config scriptlet if enabled. As with the placement scriptlet, this is done outside of any
+	// transaction, but before checking the project's limits so that the overrides are taken into account.
+	if !clusterNotification && s.GlobalConfig.InstancesConfigScriptlet() != "" {
+		// Copies get the config of their source instance, which the request only overrides.
+		config := maps.Clone(req.Config)
+		for key, value := range sourceInstConfig {
+			_, exists := config[key]
+			if !exists && internalInstance.InstanceIncludeWhenCopying(key, false) {
+				config[key] = value
+			}
+		}
+
+		expandedConfig := db.ExpandInstanceConfig(config, profiles)
+
+		overrides, err := scriptlet.InstanceConfigRun(r.Context(), logger.Log, targetProjectName, req.Name, string(req.Type), expandedConfig)
+		if err != nil {
+			return response.BadRequest(fmt.Errorf("Failed instance config scriptlet: %w", err))
+		}
+
+		for key, value := range overrides {
+			req.Config[key] = value
+		}
+	}
+
+	if !clusterNotification {
+		err = s.DB.Cluster.Transaction(r.Context(), func(ctx context.Context, tx *db.ClusterTx) error {
+			// Check that the project's limits are not violated. Note this check is performed after
+			// automatically generated config values (such as ones from an InstanceType) have been set.
+			return project.AllowInstanceCreation(tx, targetProjectName, req)
+		})
+		if err != nil {
+			return response.SmartError(err)
+		}
+	}
+
 	err = instance.ValidName(req.Name, false)
 	if err != nil {
 		return response.BadRequest(err)
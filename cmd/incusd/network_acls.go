@@ -4,14 +4,19 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"net/url"
+	"strconv"
 	"time"
 
 	"github.com/gorilla/mux"
 
+	"github.com/lxc/incus/v6/internal/filter"
 	"github.com/lxc/incus/v6/internal/server/auth"
+	"github.com/lxc/incus/v6/internal/server/cluster"
 	clusterRequest "github.com/lxc/incus/v6/internal/server/cluster/request"
 	"github.com/lxc/incus/v6/internal/server/db"
 	"github.com/lxc/incus/v6/internal/server/lifecycle"
@@ -19,6 +24,8 @@ import (
 	"github.com/lxc/incus/v6/internal/server/project"
 	"github.com/lxc/incus/v6/internal/server/request"
 	"github.com/lxc/incus/v6/internal/server/response"
+	"github.com/lxc/incus/v6/internal/server/state"
+	"github.com/lxc/incus/v6/internal/server/task"
 	localUtil "github.com/lxc/incus/v6/internal/server/util"
 	"github.com/lxc/incus/v6/internal/version"
 	"github.com/lxc/incus/v6/shared/api"
@@ -49,6 +56,31 @@ var networkACLLogCmd = APIEndpoint{
 	Get: APIEndpointAction{Handler: networkACLLogGet, AccessHandler: allowPermission(auth.ObjectTypeNetworkACL, auth.EntitlementCanView, "name")},
 }
 
+var networkACLStateCmd = APIEndpoint{
+	Path: "network-acls/{name}/state",
+
+	Get: APIEndpointAction{Handler: networkACLStateGet, AccessHandler: allowPermission(auth.ObjectTypeNetworkACL, auth.EntitlementCanView, "name")},
+}
+
+var networkACLConflictsCmd = APIEndpoint{
+	Path: "network-acls/{name}/conflicts",
+
+	Get: APIEndpointAction{Handler: networkACLConflictsGet, AccessHandler: allowPermission(auth.ObjectTypeNetworkACL, auth.EntitlementCanView, "name")},
+}
+
+var networkACLRevisionsCmd = APIEndpoint{
+	Path: "network-acls/{name}/revisions",
+
+	Get: APIEndpointAction{Handler: networkACLRevisionsGet, AccessHandler: allowPermission(auth.ObjectTypeNetworkACL, auth.EntitlementCanView, "name")},
+}
+
+var networkACLRevisionCmd = APIEndpoint{
+	Path: "network-acls/{name}/revisions/{revision}",
+
+	Get:  APIEndpointAction{Handler: networkACLRevisionGet, AccessHandler: allowPermission(auth.ObjectTypeNetworkACL, auth.EntitlementCanView, "name")},
+	Post: APIEndpointAction{Handler: networkACLRevisionPost, AccessHandler: allowPermission(auth.ObjectTypeNetworkACL, auth.EntitlementCanEdit, "name")},
+}
+
 // API endpoints.
 
 // swagger:operation GET /1.0/network-acls network-acls network_acls_get
@@ -71,6 +103,16 @@ var networkACLLogCmd = APIEndpoint{
 //      description: Retrieve network ACLs from all projects
 //      type: boolean
 //      example: true
+//    - in: query
+//      name: filter
+//      description: Collection filter
+//      type: string
+//      example: default
+//    - in: query
+//      name: search
+//      description: Only return the network ACLs whose description or rule descriptions contain the string
+//      type: string
+//      example: SEC-123
 //  responses:
 //    "200":
 //      description: API endpoints
@@ -125,6 +167,16 @@ var networkACLLogCmd = APIEndpoint{
 //	    description: Retrieve network ACLs from all projects
 //	    type: boolean
 //	    example: true
+//	  - in: query
+//	    name: filter
+//	    description: Collection filter
+//	    type: string
+//	    example: default
+//	  - in: query
+//	    name: search
+//	    description: Only return the network ACLs whose description or rule descriptions contain the string
+//	    type: string
+//	    example: SEC-123
 //	responses:
 //	  "200":
 //	    description: API endpoints
@@ -153,6 +205,67 @@ var networkACLLogCmd = APIEndpoint{
 //	    $ref: "#/responses/Forbidden"
 //	  "500":
 //	    $ref: "#/responses/InternalServerError"
+
+// swagger:operation GET /1.0/network-acls?subject network-acls network_acls_get_subject
+//
+//	Find the network ACL rules using a subject
+//
+//	Returns the rules of the network ACLs with a source or destination subject equal to, containing, contained in
+//	or overlapping with the subject. IP addresses, CIDRs and ranges are compared by the addresses they match, other
+//	subjects only match the same name.
+//
+//	---
+//	produces:
+//	  - application/json
+//	parameters:
+//	  - in: query
+//	    name: project
+//	    description: Project name
+//	    type: string
+//	    example: default
+//	  - in: query
+//	    name: all-projects
+//	    description: Search network ACLs from all projects
+//	    type: boolean
+//	    example: true
+//	  - in: query
+//	    name: filter
+//	    description: Collection filter restricting the searched network ACLs
+//	    type: string
+//	    example: default
+//	  - in: query
+//	    name: subject
+//	    description: Subject to find in the rules
+//	    type: string
+//	    example: 10.40.0.0/16
+//	responses:
+//	  "200":
+//	    description: API endpoints
+//	    schema:
+//	      type: object
+//	      description: Sync response
+//	      properties:
+//	        type:
+//	          type: string
+//	          description: Response type
+//	          example: sync
+//	        status:
+//	          type: string
+//	          description: Status description
+//	          example: Success
+//	        status_code:
+//	          type: integer
+//	          description: Status code
+//	          example: 200
+//	        metadata:
+//	          type: array
+//	          description: List of matching rule subjects
+//	          items:
+//	            $ref: "#/definitions/NetworkACLRuleMatch"
+//	  "403":
+//	    $ref: "#/responses/Forbidden"
+//	  "500":
+//	    $ref: "#/responses/InternalServerError"
 func networkACLsGet(d *Daemon, r *http.Request) response.Response {
 	s := d.State()
 
@@ -163,6 +276,16 @@ func networkACLsGet(d *Daemon, r *http.Request) response.Response {
 
 	recursion := localUtil.IsRecursionRequest(r)
 	allProjects := util.IsTrue(r.FormValue("all-projects"))
+	search := r.FormValue("search")
+	subject := r.FormValue("subject")
+
+	filterStr := r.FormValue("filter")
+	clauses, err := filter.Parse(filterStr, filter.QueryOperatorSet())
+	if err != nil {
+		return response.SmartError(fmt.Errorf("Invalid filter: %w", err))
+	}
+
+	filtered := clauses != nil && len(clauses.Clauses) > 0
 
 	var aclNames map[string][]string
 
@@ -197,35 +320,93 @@ func networkACLsGet(d *Daemon, r *http.Request) response.Response {
 		return response.SmartError(err)
 	}
 
+	// The ACLs only need loading when they're returned, searched or filtered.
+	load := recursion || search != "" || subject != "" || filtered
+
 	resultString := []string{}
-	resultMap := []api.NetworkACL{}
+	netACLs := []api.NetworkACL{}
 	for projectName, acls := range aclNames {
+		// Compute the usage of all the ACLs of the project at once, rather than walking all the resources for each ACL.
+		var usedBy map[string][]string
+		if (recursion || filtered) && subject == "" {
+			usedBy, _ = acl.ProjectUsage(s, projectName) // Ignore errors in ProjectUsage, UsedBy will be nil.
+		}
+
 		for _, aclName := range acls {
 			if !userHasPermission(auth.ObjectNetworkACL(projectName, aclName)) {
 				continue
 			}
 
-			if !recursion {
+			if !load {
 				resultString = append(resultString, fmt.Sprintf("/%s/network-acls/%s", version.APIVersion, aclName))
-			} else {
-				netACL, err := acl.LoadByName(s, projectName, aclName)
-				if err != nil {
-					continue
-				}
+				continue
+			}
 
-				netACLInfo := netACL.Info()
-				netACLInfo.UsedBy, _ = netACL.UsedBy() // Ignore errors in UsedBy, will return nil.
+			netACL, err := acl.LoadByName(s, projectName, aclName)
+			if err != nil {
+				continue
+			}
+
+			netACLInfo := netACL.Info()
+			netACLInfo.UsedBy = usedBy[aclName]
 
-				resultMap = append(resultMap, *netACLInfo)
+			if search != "" && !acl.SearchMatches(netACLInfo, search) {
+				continue
 			}
+
+			netACLs = append(netACLs, *netACLInfo)
+		}
+	}
+
+	if !load {
+		return response.SyncResponse(true, resultString)
+	}
+
+	if filtered {
+		netACLs, err = filterNetworkACLs(netACLs, clauses)
+		if err != nil {
+			return response.SmartError(err)
 		}
 	}
 
+	if subject != "" {
+		resultSubject := []api.NetworkACLRuleMatch{}
+		for _, netACLInfo := range netACLs {
+			resultSubject = append(resultSubject, acl.SearchRulesBySubject(&netACLInfo, subject)...)
+		}
+
+		return response.SyncResponse(true, resultSubject)
+	}
+
 	if !recursion {
+		for _, netACLInfo := range netACLs {
+			resultString = append(resultString, fmt.Sprintf("/%s/network-acls/%s", version.APIVersion, netACLInfo.Name))
+		}
+
 		return response.SyncResponse(true, resultString)
 	}
 
-	return response.SyncResponse(true, resultMap)
+	return response.SyncResponse(true, netACLs)
+}
+
+// filterNetworkACLs returns the network ACLs matching the filter clauses.
+func filterNetworkACLs(netACLs []api.NetworkACL, clauses *filter.ClauseSet) ([]api.NetworkACL, error) {
+	filtered := []api.NetworkACL{}
+
+	for _, netACL := range netACLs {
+		match, err := filter.Match(netACL, *clauses)
+		if err != nil {
+			return nil, err
+		}
+
+		if !match {
+			continue
+		}
+
+		filtered = append(filtered, netACL)
+	}
+
+	return filtered, nil
 }
 
 // swagger:operation POST /1.0/network-acls network-acls network_acls_post
@@ -281,7 +462,7 @@ func networkACLsPost(d *Daemon, r *http.Request) response.Response {
 		return response.BadRequest(fmt.Errorf("The network ACL already exists"))
 	}
 
-	err = acl.Create(s, projectName, &req)
+	warnings, err := acl.Create(s, projectName, &req)
 	if err != nil {
 		return response.SmartError(err)
 	}
@@ -299,7 +480,7 @@ func networkACLsPost(d *Daemon, r *http.Request) response.Response {
 	lc := lifecycle.NetworkACLCreated.Event(netACL, request.CreateRequestor(r), nil)
 	s.Events.SendLifecycle(projectName, lc)
 
-	return response.SyncResponseLocation(true, nil, lc.Source)
+	return response.SyncResponseLocation(true, networkACLWarnings(warnings), lc.Source)
 }
 
 // swagger:operation DELETE /1.0/network-acls/{name} network-acls network_acl_delete
@@ -443,6 +624,11 @@ func networkACLGet(d *Daemon, r *http.Request) response.Response {
 //      description: Project name
 //      type: string
 //      example: default
+//    - in: query
+//      name: validation
+//      description: Validation mode (warn by default to convert deprecated rule constructs, strict to reject them, or permissive to also convert CIDR subjects with host bits set)
+//      type: string
+//      example: permissive
 //    - in: body
 //      name: acl
 //      description: ACL configuration
@@ -478,6 +664,11 @@ func networkACLGet(d *Daemon, r *http.Request) response.Response {
 //	    description: Project name
 //	    type: string
 //	    example: default
+//	  - in: query
+//	    name: validation
+//	    description: Validation mode (warn by default to convert deprecated rule constructs, strict to reject them, or permissive to also convert CIDR subjects with host bits set)
+//	    type: string
+//	    example: permissive
 //	  - in: body
 //	    name: acl
 //	    description: ACL configuration
@@ -508,6 +699,11 @@ func networkACLPut(d *Daemon, r *http.Request) response.Response {
 		return response.SmartError(err)
 	}
 
+	validationMode, err := acl.ParseValidationMode(request.QueryParam(r, "validation"))
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
 	// Get the existing Network ACL.
 	netACL, err := acl.LoadByName(s, projectName, aclName)
 	if err != nil {
@@ -541,14 +737,43 @@ func networkACLPut(d *Daemon, r *http.Request) response.Response {
 
 	clientType := clusterRequest.UserAgentClientType(r.Header.Get("User-Agent"))
 
-	err = netACL.Update(&req, clientType)
+	oldInfo := netACL.Info()
+
+	warnings, err := netACL.Update(&req, clientType, request.CreateRequestor(r), validationMode)
 	if err != nil {
 		return response.SmartError(err)
 	}
 
-	s.Events.SendLifecycle(projectName, lifecycle.NetworkACLUpdated.Event(netACL, request.CreateRequestor(r), nil))
+	s.Events.SendLifecycle(projectName, lifecycle.NetworkACLUpdated.Event(netACL, request.CreateRequestor(r), networkACLUpdatedCtx(oldInfo, netACL.Info())))
 
-	return response.EmptySyncResponse
+	return networkACLWarningsResponse(warnings)
+}
+
+// networkACLWarningsResponse returns the response to an ACL update, with the warnings about the deprecated
+// constructs that were converted in its metadata.
+func networkACLWarningsResponse(warnings []string) response.Response {
+	return response.SyncResponse(true, networkACLWarnings(warnings))
+}
+
+// networkACLWarnings returns the response metadata listing the warnings about the deprecated constructs that were
+// converted when creating or updating an ACL. The list is always included, so the metadata has the same shape
+// whether or not there are warnings.
+func networkACLWarnings(warnings []string) map[string]any {
+	if warnings == nil {
+		warnings = []string{}
+	}
+
+	return map[string]any{"warnings": warnings}
+}
+
+// networkACLUpdatedCtx returns the lifecycle event context summarizing the rule changes of an ACL update.
+func networkACLUpdatedCtx(oldInfo *api.NetworkACL, newInfo *api.NetworkACL) logger.Ctx {
+	return logger.Ctx{
+		"ingress_rules_before": len(oldInfo.Ingress),
+		"ingress_rules":        len(newInfo.Ingress),
+		"egress_rules_before":  len(oldInfo.Egress),
+		"egress_rules":         len(newInfo.Egress),
+	}
 }
 
 // swagger:operation POST /1.0/network-acls/{name} network-acls network_acl_post
@@ -610,7 +835,7 @@ func networkACLPost(d *Daemon, r *http.Request) response.Response {
 		return response.SmartError(err)
 	}
 
-	err = netACL.Rename(req.Name)
+	err = netACL.Rename(req.Name, request.CreateRequestor(r))
 	if err != nil {
 		return response.SmartError(err)
 	}
@@ -632,6 +857,8 @@ func networkACLPost(d *Daemon, r *http.Request) response.Response {
 //
 //	Gets a specific network ACL log entries.
 //
+//	When following the log, the connection is kept open and new entries are sent as they get logged.
+//
 //	---
 //	produces:
 //	  - application/octet-stream
@@ -641,6 +868,11 @@ func networkACLPost(d *Daemon, r *http.Request) response.Response {
 //	    description: Project name
 //	    type: string
 //	    example: default
+//	  - in: query
+//	    name: follow
+//	    description: Whether to follow the log
+//	    type: boolean
+//	    example: true
 //	responses:
 //	  "200":
 //	     description: Raw log file
@@ -672,6 +904,30 @@ func networkACLLogGet(d *Daemon, r *http.Request) response.Response {
 	}
 
 	clientType := clusterRequest.UserAgentClientType(r.Header.Get("User-Agent"))
+
+	if util.IsTrue(request.QueryParam(r, "follow")) {
+		follow, err := netACL.FollowLog(clientType)
+		if err != nil {
+			return response.SmartError(err)
+		}
+
+		return response.ManualResponse(func(w http.ResponseWriter) error {
+			f, ok := w.(http.Flusher)
+			if !ok {
+				return fmt.Errorf("http.ResponseWriter is not type http.Flusher")
+			}
+
+			w.Header().Set("Content-Type", "application/octet-stream")
+
+			err := follow(r.Context(), &networkACLLogWriter{w: w, f: f})
+			if err != nil && r.Context().Err() == nil {
+				return err
+			}
+
+			return nil
+		})
+	}
+
 	log, err := netACL.GetLog(clientType)
 	if err != nil {
 		return response.SmartError(err)
@@ -684,3 +940,617 @@ func networkACLLogGet(d *Daemon, r *http.Request) response.Response {
 
 	return response.FileResponse(r, []response.FileResponseEntry{ent}, nil)
 }
+
+// networkACLLogWriter sends the followed log entries to the client as soon as they are written.
+type networkACLLogWriter struct {
+	w io.Writer
+	f http.Flusher
+}
+
+// Write writes the data and flushes it to the client.
+func (lw *networkACLLogWriter) Write(p []byte) (int, error) {
+	n, err := lw.w.Write(p)
+	if err != nil {
+		return n, err
+	}
+
+	lw.f.Flush()
+
+	return n, nil
+}
+
+// swagger:operation GET /1.0/network-acls/{name}/state network-acls network_acl_state_get
+//
+//	Get the network ACL state
+//
+//	Gets whether the rules applied to the OVN networks using the ACL match its current definition.
+//
+//	---
+//	produces:
+//	  - application/json
+//	parameters:
+//	  - in: query
+//	    name: project
+//	    description: Project name
+//	    type: string
+//	    example: default
+//	responses:
+//	  "200":
+//	    description: Network ACL state
+//	    schema:
+//	      type: object
+//	      description: Sync response
+//	      properties:
+//	        type:
+//	          type: string
+//	          description: Response type
+//	          example: sync
+//	        status:
+//	          type: string
+//	          description: Status description
+//	          example: Success
+//	        status_code:
+//	          type: integer
+//	          description: Status code
+//	          example: 200
+//	        metadata:
+//	          $ref: "#/definitions/NetworkACLState"
+//	  "403":
+//	    $ref: "#/responses/Forbidden"
+//	  "404":
+//	    $ref: "#/responses/NotFound"
+//	  "500":
+//	    $ref: "#/responses/InternalServerError"
+func networkACLStateGet(d *Daemon, r *http.Request) response.Response {
+	s := d.State()
+
+	projectName, _, err := project.NetworkProject(s.DB.Cluster, request.ProjectParam(r))
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	aclName, err := url.PathUnescape(mux.Vars(r)["name"])
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	netACL, err := acl.LoadByName(s, projectName, aclName)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	aclState, err := netACL.State()
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.SyncResponse(true, aclState)
+}
+
+// swagger:operation GET /1.0/network-acls/{name}/conflicts network-acls network_acl_conflicts_get
+//
+//	Get the conflicting rules of two network ACLs
+//
+//	Returns the pairs of rules of the network ACL and of another ACL of the same project matching some of the same
+//	traffic in the same direction, one of them allowing it and the other dropping or rejecting it.
+//	This is only advisory, such ACLs can be applied together.
+//
+//	---
+//	produces:
+//	  - application/json
+//	parameters:
+//	  - in: query
+//	    name: project
+//	    description: Project name
+//	    type: string
+//	    example: default
+//	  - in: query
+//	    name: with
+//	    description: Name of the other network ACL
+//	    type: string
+//	    example: web
+//	responses:
+//	  "200":
+//	    description: Conflicting rules
+//	    schema:
+//	      type: object
+//	      description: Sync response
+//	      properties:
+//	        type:
+//	          type: string
+//	          description: Response type
+//	          example: sync
+//	        status:
+//	          type: string
+//	          description: Status description
+//	          example: Success
+//	        status_code:
+//	          type: integer
+//	          description: Status code
+//	          example: 200
+//	        metadata:
+//	          type: array
+//	          description: List of conflicting rules
+//	          items:
+//	            $ref: "#/definitions/NetworkACLRuleConflict"
+//	  "400":
+//	    $ref: "#/responses/BadRequest"
+//	  "403":
+//	    $ref: "#/responses/Forbidden"
+//	  "404":
+//	    $ref: "#/responses/NotFound"
+//	  "500":
+//	    $ref: "#/responses/InternalServerError"
+func networkACLConflictsGet(d *Daemon, r *http.Request) response.Response {
+	s := d.State()
+
+	projectName, _, err := project.NetworkProject(s.DB.Cluster, request.ProjectParam(r))
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	aclName, err := url.PathUnescape(mux.Vars(r)["name"])
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	otherName := request.QueryParam(r, "with")
+	if otherName == "" {
+		return response.BadRequest(fmt.Errorf("The other network ACL must be specified with the with parameter"))
+	}
+
+	err = s.Authorizer.CheckPermission(r.Context(), r, auth.ObjectNetworkACL(projectName, otherName), auth.EntitlementCanView)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	netACL, err := acl.LoadByName(s, projectName, aclName)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	otherACL, err := acl.LoadByName(s, projectName, otherName)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.SyncResponse(true, acl.ConflictingRules(&netACL.Info().NetworkACLPut, &otherACL.Info().NetworkACLPut))
+}
+
+// swagger:operation GET /1.0/network-acls/{name}/revisions network-acls network_acl_revisions_get
+//
+//	Get the network ACL revisions
+//
+//	Returns a list of previous revisions of the network ACL (URLs).
+//
+//	---
+//	produces:
+//	  - application/json
+//	parameters:
+//	  - in: query
+//	    name: project
+//	    description: Project name
+//	    type: string
+//	    example: default
+//	responses:
+//	  "200":
+//	    description: API endpoints
+//	    schema:
+//	      type: object
+//	      description: Sync response
+//	      properties:
+//	        type:
+//	          type: string
+//	          description: Response type
+//	          example: sync
+//	        status:
+//	          type: string
+//	          description: Status description
+//	          example: Success
+//	        status_code:
+//	          type: integer
+//	          description: Status code
+//	          example: 200
+//	        metadata:
+//	          type: array
+//	          description: List of endpoints
+//	          items:
+//	            type: string
+//	          example: |-
+//	            [
+//	              "/1.0/network-acls/foo/revisions/2",
+//	              "/1.0/network-acls/foo/revisions/1"
+//	            ]
+//	  "403":
+//	    $ref: "#/responses/Forbidden"
+//	  "500":
+//	    $ref: "#/responses/InternalServerError"
+
+// swagger:operation GET /1.0/network-acls/{name}/revisions?recursion=1 network-acls network_acl_revisions_get_recursion1
+//
+//	Get the network ACL revisions
+//
+//	Returns a list of previous revisions of the network ACL (structs).
+//
+//	---
+//	produces:
+//	  - application/json
+//	parameters:
+//	  - in: query
+//	    name: project
+//	    description: Project name
+//	    type: string
+//	    example: default
+//	responses:
+//	  "200":
+//	    description: API endpoints
+//	    schema:
+//	      type: object
+//	      description: Sync response
+//	      properties:
+//	        type:
+//	          type: string
+//	          description: Response type
+//	          example: sync
+//	        status:
+//	          type: string
+//	          description: Status description
+//	          example: Success
+//	        status_code:
+//	          type: integer
+//	          description: Status code
+//	          example: 200
+//	        metadata:
+//	          type: array
+//	          description: List of network ACL revisions
+//	          items:
+//	            $ref: "#/definitions/NetworkACLRevision"
+//	  "403":
+//	    $ref: "#/responses/Forbidden"
+//	  "500":
+//	    $ref: "#/responses/InternalServerError"
+func networkACLRevisionsGet(d *Daemon, r *http.Request) response.Response {
+	s := d.State()
+
+	projectName, _, err := project.NetworkProject(s.DB.Cluster, request.ProjectParam(r))
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	aclName, err := url.PathUnescape(mux.Vars(r)["name"])
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	netACL, err := acl.LoadByName(s, projectName, aclName)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	revisions, err := netACL.Revisions()
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	if localUtil.IsRecursionRequest(r) {
+		return response.SyncResponse(true, revisions)
+	}
+
+	urls := make([]string, 0, len(revisions))
+	for _, revision := range revisions {
+		urls = append(urls, api.NewURL().Path(version.APIVersion, "network-acls", aclName, "revisions", strconv.FormatInt(revision.Revision, 10)).Project(projectName).String())
+	}
+
+	return response.SyncResponse(true, urls)
+}
+
+// swagger:operation GET /1.0/network-acls/{name}/revisions/{revision} network-acls network_acl_revision_get
+//
+//	Get the network ACL revision
+//
+//	Gets a specific previous revision of the network ACL.
+//
+//	---
+//	produces:
+//	  - application/json
+//	parameters:
+//	  - in: query
+//	    name: project
+//	    description: Project name
+//	    type: string
+//	    example: default
+//	responses:
+//	  "200":
+//	    description: ACL revision
+//	    schema:
+//	      type: object
+//	      description: Sync response
+//	      properties:
+//	        type:
+//	          type: string
+//	          description: Response type
+//	          example: sync
+//	        status:
+//	          type: string
+//	          description: Status description
+//	          example: Success
+//	        status_code:
+//	          type: integer
+//	          description: Status code
+//	          example: 200
+//	        metadata:
+//	          $ref: "#/definitions/NetworkACLRevision"
+//	  "403":
+//	    $ref: "#/responses/Forbidden"
+//	  "404":
+//	    $ref: "#/responses/NotFound"
+//	  "500":
+//	    $ref: "#/responses/InternalServerError"
+func networkACLRevisionGet(d *Daemon, r *http.Request) response.Response {
+	s := d.State()
+
+	netACL, revision, err := networkACLRevisionLoad(s, r)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	info, err := netACL.Revision(revision)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.SyncResponse(true, info)
+}
+
+// swagger:operation POST /1.0/network-acls/{name}/revisions/{revision} network-acls network_acl_revision_post
+//
+//	Restore the network ACL revision
+//
+//	Restores the network ACL to a previous revision.
+//	The revision goes through the same validation as a normal update.
+//
+//	---
+//	produces:
+//	  - application/json
+//	parameters:
+//	  - in: query
+//	    name: project
+//	    description: Project name
+//	    type: string
+//	    example: default
+//	responses:
+//	  "200":
+//	    $ref: "#/responses/EmptySyncResponse"
+//	  "400":
+//	    $ref: "#/responses/BadRequest"
+//	  "403":
+//	    $ref: "#/responses/Forbidden"
+//	  "404":
+//	    $ref: "#/responses/NotFound"
+//	  "500":
+//	    $ref: "#/responses/InternalServerError"
+func networkACLRevisionPost(d *Daemon, r *http.Request) response.Response {
+	s := d.State()
+
+	netACL, revision, err := networkACLRevisionLoad(s, r)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	info, err := netACL.Revision(revision)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	// Feed the stored version back through the normal update path so it gets fully validated, as referenced
+	// ACLs may have been deleted since the revision was recorded. The revision may also predate the current
+	// validation rules, so any deprecated constructs are converted.
+	req := info.NetworkACLPut
+
+	clientType := clusterRequest.UserAgentClientType(r.Header.Get("User-Agent"))
+
+	oldInfo := netACL.Info()
+
+	warnings, err := netACL.Update(&req, clientType, request.CreateRequestor(r), acl.ValidationModePermissive)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	ctx := networkACLUpdatedCtx(oldInfo, netACL.Info())
+	ctx["revision"] = revision
+
+	s.Events.SendLifecycle(netACL.Project(), lifecycle.NetworkACLUpdated.Event(netACL, request.CreateRequestor(r), ctx))
+
+	return networkACLWarningsResponse(warnings)
+}
+
+// networkACLRevisionLoad loads the network ACL and parses the revision number referenced by the request.
+func networkACLRevisionLoad(s *state.State, r *http.Request) (acl.NetworkACL, int64, error) {
+	projectName, _, err := project.NetworkProject(s.DB.Cluster, request.ProjectParam(r))
+	if err != nil {
+		return nil, -1, err
+	}
+
+	aclName, err := url.PathUnescape(mux.Vars(r)["name"])
+	if err != nil {
+		return nil, -1, err
+	}
+
+	revision, err := strconv.ParseInt(mux.Vars(r)["revision"], 10, 64)
+	if err != nil {
+		return nil, -1, api.StatusErrorf(http.StatusBadRequest, "Invalid revision %q", mux.Vars(r)["revision"])
+	}
+
+	netACL, err := acl.LoadByName(s, projectName, aclName)
+	if err != nil {
+		return nil, -1, err
+	}
+
+	return netACL, revision, nil
+}
+
+func networkACLScheduleTask(d *Daemon) (task.Func, task.Schedule) {
+	lastRun := time.Now()
+
+	f := func(ctx context.Context) {
+		s := d.State()
+		now := time.Now()
+
+		// OVN configuration is shared by all cluster members so only have the leader update it.
+		applyOVN := true
+
+		leader, err := s.Cluster.LeaderAddress()
+		if err == nil {
+			applyOVN = s.LocalConfig.ClusterAddress() == leader
+		} else if !errors.Is(err, cluster.ErrNodeIsNotClustered) {
+			logger.Error("Failed to get leader cluster member address", logger.Ctx{"err": err})
+			return
+		}
+
+		err = acl.RefreshScheduled(s, lastRun, now, applyOVN)
+		if err != nil {
+			logger.Error("Failed refreshing scheduled network ACL rules", logger.Ctx{"err": err})
+			return
+		}
+
+		lastRun = now
+	}
+
+	return f, task.Every(time.Minute)
+}
+
+// networkACLReconcile compares the OVN port groups of all network ACLs with their expected state and logs a summary
+// of the drift found. Unless dryRun is true, the drift is repaired.
+func networkACLReconcile(s *state.State, dryRun bool) (*acl.OVNDrift, error) {
+	ovnnb, _, err := s.OVN()
+	if err != nil {
+		return nil, err
+	}
+
+	l := logger.AddContext(logger.Ctx{"dryRun": dryRun})
+
+	drift, err := acl.OVNReconcile(s, l, ovnnb, dryRun)
+	if err != nil {
+		return nil, err
+	}
+
+	if drift.Empty() {
+		l.Debug("No drift found between network ACLs and OVN")
+		return drift, nil
+	}
+
+	ctx := logger.Ctx{"missingPortGroups": drift.MissingPortGroups, "staleACLs": drift.StaleACLs, "orphanedPortGroups": drift.OrphanedPortGroups}
+	if dryRun {
+		l.Warn("Found drift between network ACLs and OVN", ctx)
+	} else {
+		l.Warn("Repaired drift between network ACLs and OVN", ctx)
+	}
+
+	return drift, nil
+}
+
+// networkACLDriftTask periodically checks for and repairs drift between network ACLs and OVN.
+func networkACLDriftTask(d *Daemon) (task.Func, task.Schedule) {
+	f := func(ctx context.Context) {
+		s := d.State()
+
+		// OVN configuration is shared by all cluster members so only have the leader check it.
+		leader, err := s.Cluster.LeaderAddress()
+		if err == nil {
+			if s.LocalConfig.ClusterAddress() != leader {
+				return
+			}
+		} else if !errors.Is(err, cluster.ErrNodeIsNotClustered) {
+			logger.Error("Failed to get leader cluster member address", logger.Ctx{"err": err})
+			return
+		}
+
+		// Avoid connecting to OVN on systems that don't use it.
+		hasOVN, err := networkACLHasOVN(ctx, s)
+		if err != nil {
+			logger.Error("Failed loading networks", logger.Ctx{"err": err})
+			return
+		}
+
+		if !hasOVN {
+			return
+		}
+
+		_, err = networkACLReconcile(s, !s.GlobalConfig.NetworkACLsRepairDrift())
+		if err != nil {
+			logger.Error("Failed checking network ACLs for OVN drift", logger.Ctx{"err": err})
+		}
+	}
+
+	return f, task.Hourly()
+}
+
+// networkACLHasOVN returns whether any OVN network exists, in which case OVN is used to enforce network ACLs.
+func networkACLHasOVN(ctx context.Context, s *state.State) (bool, error) {
+	hasOVN := false
+	err := s.DB.Cluster.Transaction(ctx, func(ctx context.Context, tx *db.ClusterTx) error {
+		projectNetworks, err := tx.GetCreatedNetworks(ctx)
+		if err != nil {
+			return err
+		}
+
+		for _, networks := range projectNetworks {
+			for _, network := range networks {
+				if network.Type == "ovn" {
+					hasOVN = true
+					return nil
+				}
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return hasOVN, nil
+}
+
+// networkACLFQDNTask periodically resolves the host names used in network ACL rules and updates their OVN
+// address sets.
+func networkACLFQDNTask(d *Daemon) (task.Func, task.Schedule) {
+	f := func(ctx context.Context) {
+		s := d.State()
+
+		// OVN configuration is shared by all cluster members so only have the leader update it.
+		leader, err := s.Cluster.LeaderAddress()
+		if err == nil {
+			if s.LocalConfig.ClusterAddress() != leader {
+				return
+			}
+		} else if !errors.Is(err, cluster.ErrNodeIsNotClustered) {
+			logger.Error("Failed to get leader cluster member address", logger.Ctx{"err": err})
+			return
+		}
+
+		// Avoid connecting to OVN on systems that don't use it.
+		hasOVN, err := networkACLHasOVN(ctx, s)
+		if err != nil {
+			logger.Error("Failed loading networks", logger.Ctx{"err": err})
+			return
+		}
+
+		if !hasOVN {
+			return
+		}
+
+		ovnnb, _, err := s.OVN()
+		if err != nil {
+			logger.Error("Failed connecting to OVN", logger.Ctx{"err": err})
+			return
+		}
+
+		err = acl.OVNRefreshFQDNs(s, logger.Log, ovnnb)
+		if err != nil {
+			logger.Error("Failed refreshing network ACL host names", logger.Ctx{"err": err})
+		}
+	}
+
+	return f, task.Every(5 * time.Minute)
+}
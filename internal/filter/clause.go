@@ -74,6 +74,11 @@ func Parse(s string, op OperatorSet) (*ClauseSet, error) {
 
 		// support strings with spaces that are quoted
 		for _, symbol := range op.Quote {
+			if len(value) > len(symbol) && strings.HasPrefix(value, symbol) && strings.HasSuffix(value, symbol) {
+				value = value[len(symbol) : len(value)-len(symbol)]
+				break
+			}
+
 			if strings.HasPrefix(value, symbol) {
 				value = value[1:]
 				for {
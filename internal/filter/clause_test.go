@@ -47,3 +47,12 @@ func TestParse(t *testing.T) {
 	assert.Equal(t, "eq", clause2.Operator)
 	assert.Equal(t, "yuk", clause2.Value)
 }
+
+func TestParse_QuotedWord(t *testing.T) {
+	clauses, err := filter.Parse("description contains \"SEC-123\" and name eq web", filter.QueryOperatorSet())
+	require.NoError(t, err)
+	assert.Len(t, clauses.Clauses, 2)
+	assert.Equal(t, "contains", clauses.Clauses[0].Operator)
+	assert.Equal(t, "SEC-123", clauses.Clauses[0].Value)
+	assert.Equal(t, "web", clauses.Clauses[1].Value)
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"reflect"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 )
@@ -113,6 +114,19 @@ func (s ClauseSet) match(c Clause, objValue any) (bool, error) {
 	var valueSlice []string
 	var err error
 
+	// The contains operator takes the value as is, rather than as a regexp or JSON list.
+	if s.Ops.Contains != "" && c.Operator == s.Ops.Contains {
+		switch val := objValue.(type) {
+		case string:
+			// Comparison is case insensitive.
+			return strings.Contains(strings.ToLower(val), strings.ToLower(c.Value)), nil
+		case []string:
+			return slices.ContainsFunc(val, func(v string) bool { return strings.EqualFold(v, c.Value) }), nil
+		default:
+			return false, fmt.Errorf("Invalid operator %q for field %q", c.Operator, c.Field)
+		}
+	}
+
 	// If 'value' is type of string try to test value as a regexp.
 	valInfo := reflect.ValueOf(objValue)
 	kind := valInfo.Kind()
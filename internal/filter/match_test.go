@@ -81,3 +81,34 @@ func TestMatch_Image(t *testing.T) {
 		})
 	}
 }
+
+func TestMatch_Contains(t *testing.T) {
+	profile := api.Profile{
+		Name: "web",
+		ProfilePut: api.ProfilePut{
+			Description: "Web servers (SEC-123)",
+		},
+		UsedBy: []string{"/1.0/instances/c1"},
+	}
+
+	cases := map[string]any{
+		"description contains sec-123":            true,
+		"description contains \"servers (SEC\"":   true,
+		"description contains .*":                 false,
+		"not description contains SEC-456":        true,
+		"used_by contains /1.0/instances/c1":      true,
+		"used_by contains /1.0/instances/c2":      false,
+		"name contains we and name contains b":    true,
+		"description contains web or name eq foo": true,
+	}
+
+	for s := range cases {
+		t.Run(s, func(t *testing.T) {
+			f, err := filter.Parse(s, filter.QueryOperatorSet())
+			require.NoError(t, err)
+			match, err := filter.Match(profile, *f)
+			require.NoError(t, err)
+			assert.Equal(t, cases[s], match)
+		})
+	}
+}
@@ -12,6 +12,8 @@ type OperatorSet struct {
 	GreaterEqual string
 	LessEqual    string
 
+	Contains string
+
 	Negate string
 	Quote  []string
 }
@@ -28,6 +30,7 @@ func QueryOperatorSet() OperatorSet {
 		Or:        "or",
 		Equals:    "eq",
 		NotEquals: "ne",
+		Contains:  "contains",
 		Negate:    "not",
 		Quote:     []string{"\""},
 	}
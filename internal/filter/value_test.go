@@ -52,3 +52,32 @@ func TestValueOf_Instance(t *testing.T) {
 		})
 	}
 }
+
+func TestValueOf_NetworkACL(t *testing.T) {
+	acl := api.NetworkACL{
+		NetworkACLPost: api.NetworkACLPost{
+			Name: "web",
+		},
+		NetworkACLPut: api.NetworkACLPut{
+			Description: "Web servers",
+			Config: map[string]string{
+				"user.owner": "web-team",
+			},
+		},
+		Project: "default",
+	}
+
+	// Fields are looked up in all the inlined structs.
+	cases := map[string]any{}
+	cases["name"] = "web"
+	cases["description"] = "Web servers"
+	cases["config.user.owner"] = "web-team"
+	cases["project"] = "default"
+
+	for field := range cases {
+		t.Run(field, func(t *testing.T) {
+			value := filter.ValueOf(acl, field)
+			assert.Equal(t, cases[field], value)
+		})
+	}
+}
@@ -125,6 +125,26 @@ func (c *Config) MaxStandBy() int64 {
 	return c.m.GetInt64("cluster.max_standby")
 }
 
+// NetworkACLsRevisions returns the number of previous revisions to keep for each network ACL.
+func (c *Config) NetworkACLsRevisions() int64 {
+	return c.m.GetInt64("network.acls.revisions")
+}
+
+// NetworkACLsRepairDrift returns whether the periodic check repairs the OVN state of network ACLs or only reports drift.
+func (c *Config) NetworkACLsRepairDrift() bool {
+	return c.m.GetBool("network.acls.repair_drift")
+}
+
+// NetworkACLsOVNRetries returns the number of times a network ACL change is retried on transient OVN failures.
+func (c *Config) NetworkACLsOVNRetries() int64 {
+	return c.m.GetInt64("network.acls.ovn_retries")
+}
+
+// NetworkACLsOVNRetryDelay returns the delay before the first retry of a network ACL change in OVN.
+func (c *Config) NetworkACLsOVNRetryDelay() time.Duration {
+	return time.Duration(c.m.GetInt64("network.acls.ovn_retry_delay")) * time.Second
+}
+
 // NetworkOVNIntegrationBridge returns the integration OVS bridge to use for OVN networks.
 func (c *Config) NetworkOVNIntegrationBridge() string {
 	return c.m.GetString("network.ovn.integration_bridge")
@@ -177,6 +197,11 @@ func (c *Config) InstancesNICHostname() string {
 	return c.m.GetString("instances.nic.host_name")
 }
 
+// InstancesConfigScriptlet returns the instances config scriptlet source code.
+func (c *Config) InstancesConfigScriptlet() string {
+	return c.m.GetString("instances.config.scriptlet")
+}
+
 // InstancesPlacementScriptlet returns the instances placement scriptlet source code.
 func (c *Config) InstancesPlacementScriptlet() string {
 	return c.m.GetString("instances.placement.scriptlet")
@@ -562,6 +587,15 @@ var ConfigSchema = config.Schema{
 	//  shortdesc: Whether to run LXCFS on a per-instance basis
 	"instances.lxcfs.per_instance": {Type: config.Bool, Validator: validate.Optional(validate.IsBool)},
 
+	// gendoc:generate(entity=server, group=miscellaneous, key=instances.config.scriptlet)
+	// When adjusting the configuration of new instances with custom logic, this option stores the scriptlet.
+	// See {ref}`instance-config-scriptlet` for more information.
+	// ---
+	//  type: string
+	//  scope: global
+	//  shortdesc: Instance config scriptlet for overriding the limits of new instances
+	"instances.config.scriptlet": {Validator: validate.Optional(scriptletLoad.InstanceConfigValidate)},
+
 	// gendoc:generate(entity=server, group=miscellaneous, key=instances.nic.host_name)
 	// Possible values are `random` and `mac`.
 	//
@@ -717,6 +751,47 @@ var ConfigSchema = config.Schema{
 
 	// OVN networking global keys.
 
+	// gendoc:generate(entity=server, group=miscellaneous, key=network.acls.revisions)
+	// Specify how many previous versions of each network ACL are retained for rollback.
+	// Set to `0` to disable revision history.
+	// ---
+	//  type: integer
+	//  scope: global
+	//  defaultdesc: `10`
+	//  shortdesc: Number of network ACL revisions to keep
+	"network.acls.revisions": {Type: config.Int64, Default: "10", Validator: validate.Optional(validate.IsInRange(0, 1000))},
+
+	// gendoc:generate(entity=server, group=miscellaneous, key=network.acls.repair_drift)
+	// Incus periodically compares the OVN port groups of network ACLs with their expected state.
+	// If disabled, differences are only logged and not repaired.
+	// ---
+	//  type: bool
+	//  scope: global
+	//  defaultdesc: `true`
+	//  shortdesc: Whether to repair drift between network ACLs and OVN
+	"network.acls.repair_drift": {Type: config.Bool, Default: "true"},
+
+	// gendoc:generate(entity=server, group=miscellaneous, key=network.acls.ovn_retries)
+	// Number of times a network ACL change is retried when OVN is temporarily unreachable,
+	// for example during a leader election of the northbound database.
+	// Set to `0` to disable retries.
+	// ---
+	//  type: integer
+	//  scope: global
+	//  defaultdesc: `3`
+	//  shortdesc: Number of retries of network ACL changes in OVN
+	"network.acls.ovn_retries": {Type: config.Int64, Default: "3", Validator: validate.Optional(validate.IsInRange(0, 10))},
+
+	// gendoc:generate(entity=server, group=miscellaneous, key=network.acls.ovn_retry_delay)
+	// Delay in seconds before the first retry of a network ACL change in OVN.
+	// The delay doubles with each subsequent retry, and retries stop once they would wait more than two minutes in total.
+	// ---
+	//  type: integer
+	//  scope: global
+	//  defaultdesc: `1`
+	//  shortdesc: Initial delay between retries of network ACL changes in OVN
+	"network.acls.ovn_retry_delay": {Type: config.Int64, Default: "1", Validator: validate.Optional(validate.IsInRange(1, 60))},
+
 	// gendoc:generate(entity=server, group=miscellaneous, key=network.ovn.integration_bridge)
 	//
 	// ---
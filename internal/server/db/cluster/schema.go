@@ -227,6 +227,7 @@ CREATE TABLE "networks_acls" (
     description TEXT NOT NULL,
     ingress TEXT NOT NULL,
     egress TEXT NOT NULL,
+    managed INTEGER NOT NULL DEFAULT 0,
     UNIQUE (project_id, name),
     FOREIGN KEY (project_id) REFERENCES "projects" (id) ON DELETE CASCADE
 );
@@ -238,6 +239,22 @@ CREATE TABLE "networks_acls_config" (
     UNIQUE (network_acl_id, key),
     FOREIGN KEY (network_acl_id) REFERENCES "networks_acls" (id) ON DELETE CASCADE
 );
+CREATE TABLE networks_acls_revisions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+    network_acl_id INTEGER NOT NULL,
+    revision INTEGER NOT NULL,
+    name TEXT NOT NULL,
+    description TEXT NOT NULL,
+    ingress TEXT NOT NULL,
+    egress TEXT NOT NULL,
+    config TEXT NOT NULL,
+    requestor_username TEXT NOT NULL,
+    requestor_protocol TEXT NOT NULL,
+    requestor_address TEXT NOT NULL,
+    date DATETIME NOT NULL,
+    UNIQUE (network_acl_id, revision),
+    FOREIGN KEY (network_acl_id) REFERENCES "networks_acls" (id) ON DELETE CASCADE
+);
 CREATE TABLE "networks_config" (
     id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
     network_id INTEGER NOT NULL,
@@ -656,5 +673,5 @@ CREATE TABLE "warnings" (
 );
 CREATE UNIQUE INDEX warnings_unique_node_id_project_id_entity_type_code_entity_id_type_code ON warnings(IFNULL(node_id, -1), IFNULL(project_id, -1), entity_type_code, entity_id, type_code);
 
-INSERT INTO schema (version, updated_at) VALUES (75, strftime("%s"))
+INSERT INTO schema (version, updated_at) VALUES (77, strftime("%s"))
 `
@@ -112,6 +112,47 @@ var updates = map[int]schema.Update{
 	73: updateFromV72,
 	74: updateFromV73,
 	75: updateFromV74,
+	76: updateFromV75,
+	77: updateFromV76,
+}
+
+// updateFromV76 adds a flag marking the network ACLs managed by the server.
+func updateFromV76(ctx context.Context, tx *sql.Tx) error {
+	q := `ALTER TABLE networks_acls ADD COLUMN managed INTEGER NOT NULL DEFAULT 0;`
+	_, err := tx.Exec(q)
+	if err != nil {
+		return fmt.Errorf("Failed adding managed column to network ACLs table: %w", err)
+	}
+
+	return nil
+}
+
+// updateFromV75 adds a table to keep track of previous network ACL revisions.
+func updateFromV75(ctx context.Context, tx *sql.Tx) error {
+	q := `
+CREATE TABLE networks_acls_revisions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+    network_acl_id INTEGER NOT NULL,
+    revision INTEGER NOT NULL,
+    name TEXT NOT NULL,
+    description TEXT NOT NULL,
+    ingress TEXT NOT NULL,
+    egress TEXT NOT NULL,
+    config TEXT NOT NULL,
+    requestor_username TEXT NOT NULL,
+    requestor_protocol TEXT NOT NULL,
+    requestor_address TEXT NOT NULL,
+    date DATETIME NOT NULL,
+    UNIQUE (network_acl_id, revision),
+    FOREIGN KEY (network_acl_id) REFERENCES "networks_acls" (id) ON DELETE CASCADE
+);
+`
+	_, err := tx.Exec(q)
+	if err != nil {
+		return fmt.Errorf("Failed creating network ACL revisions table: %w", err)
+	}
+
+	return nil
 }
 
 // updateFromV74 removes the index preventing the same integration to be used multiple times.
@@ -8,6 +8,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/lxc/incus/v6/internal/server/db/query"
 	"github.com/lxc/incus/v6/internal/version"
@@ -104,6 +105,52 @@ func (c *ClusterTx) GetNetworkACLIDsByNames(ctx context.Context, project string)
 	return acls, nil
 }
 
+// GetNetworkACLsUsage returns the number of Network ACLs and the total number of ingress and egress rules
+// across them in the given project. The ACL with the excludeID ID is not counted (use -1 to count all ACLs).
+// The ACLs managed by the server are not counted either.
+func (c *ClusterTx) GetNetworkACLsUsage(ctx context.Context, project string, excludeID int64) (int, int, error) {
+	q := `SELECT ingress, egress FROM networks_acls
+		WHERE project_id = (SELECT id FROM projects WHERE name = ? LIMIT 1) AND id != ? AND managed = 0
+	`
+
+	aclCount := 0
+	ruleCount := 0
+
+	err := query.Scan(ctx, c.tx, q, func(scan func(dest ...any) error) error {
+		var ingressJSON string
+		var egressJSON string
+
+		err := scan(&ingressJSON, &egressJSON)
+		if err != nil {
+			return err
+		}
+
+		for _, rulesJSON := range []string{ingressJSON, egressJSON} {
+			if rulesJSON == "" {
+				continue
+			}
+
+			var rules []api.NetworkACLRule
+
+			err = json.Unmarshal([]byte(rulesJSON), &rules)
+			if err != nil {
+				return fmt.Errorf("Failed unmarshalling rules: %w", err)
+			}
+
+			ruleCount += len(rules)
+		}
+
+		aclCount++
+
+		return nil
+	}, project, excludeID)
+	if err != nil {
+		return -1, -1, err
+	}
+
+	return aclCount, ruleCount, nil
+}
+
 // GetNetworkACL returns the Network ACL with the given name in the given project.
 func (c *ClusterTx) GetNetworkACL(ctx context.Context, projectName string, name string) (int64, *api.NetworkACL, error) {
 	var id int64 = int64(-1)
@@ -117,13 +164,13 @@ func (c *ClusterTx) GetNetworkACL(ctx context.Context, projectName string, name
 	}
 
 	q := `
-		SELECT id, description, ingress, egress
+		SELECT id, description, ingress, egress, managed
 		FROM networks_acls
 		WHERE project_id = (SELECT id FROM projects WHERE name = ? LIMIT 1) AND name=?
 		LIMIT 1
 	`
 
-	err := c.tx.QueryRowContext(ctx, q, projectName, name).Scan(&id, &acl.Description, &ingressJSON, &egressJSON)
+	err := c.tx.QueryRowContext(ctx, q, projectName, name).Scan(&id, &acl.Description, &ingressJSON, &egressJSON, &acl.Managed)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return -1, nil, api.StatusErrorf(http.StatusNotFound, "Network ACL not found")
@@ -160,12 +207,86 @@ func (c *ClusterTx) GetNetworkACL(ctx context.Context, projectName string, name
 	return id, &acl, nil
 }
 
+// GetNetworkACLsWithRules returns the Network ACLs of the given project with their rules and config, in the order
+// they were created. All the ACLs are loaded in a single query rather than one query for each ACL.
+func (c *ClusterTx) GetNetworkACLsWithRules(ctx context.Context, project string) ([]*api.NetworkACL, error) {
+	q := `
+		SELECT networks_acls.id, networks_acls.name, networks_acls.description, networks_acls.ingress, networks_acls.egress, networks_acls.managed, networks_acls_config.key, networks_acls_config.value
+		FROM networks_acls
+		LEFT JOIN networks_acls_config ON networks_acls_config.network_acl_id = networks_acls.id
+		WHERE networks_acls.project_id = (SELECT id FROM projects WHERE name = ? LIMIT 1)
+		ORDER BY networks_acls.id
+	`
+
+	acls := []*api.NetworkACL{}
+	aclIDs := map[int64]*api.NetworkACL{}
+
+	err := query.Scan(ctx, c.tx, q, func(scan func(dest ...any) error) error {
+		var id int64
+		var name, description, ingressJSON, egressJSON string
+		var managed bool
+		var key, value sql.NullString
+
+		err := scan(&id, &name, &description, &ingressJSON, &egressJSON, &managed, &key, &value)
+		if err != nil {
+			return err
+		}
+
+		acl, found := aclIDs[id]
+		if !found {
+			acl = &api.NetworkACL{
+				NetworkACLPost: api.NetworkACLPost{Name: name},
+				NetworkACLPut: api.NetworkACLPut{
+					Description: description,
+					Ingress:     []api.NetworkACLRule{},
+					Egress:      []api.NetworkACLRule{},
+					Config:      map[string]string{},
+				},
+				Managed: managed,
+			}
+
+			if ingressJSON != "" {
+				err = json.Unmarshal([]byte(ingressJSON), &acl.Ingress)
+				if err != nil {
+					return fmt.Errorf("Failed unmarshalling ingress rules of network ACL %q: %w", name, err)
+				}
+			}
+
+			if egressJSON != "" {
+				err = json.Unmarshal([]byte(egressJSON), &acl.Egress)
+				if err != nil {
+					return fmt.Errorf("Failed unmarshalling egress rules of network ACL %q: %w", name, err)
+				}
+			}
+
+			aclIDs[id] = acl
+			acls = append(acls, acl)
+		}
+
+		if key.Valid {
+			_, found := acl.Config[key.String]
+			if found {
+				return fmt.Errorf("Duplicate config row found for key %q for network ACL ID %d", key.String, id)
+			}
+
+			acl.Config[key.String] = value.String
+		}
+
+		return nil
+	}, project)
+	if err != nil {
+		return nil, err
+	}
+
+	return acls, nil
+}
+
 // GetNetworkACLNameAndProjectWithID returns the network ACL name and project name for the given ID.
 func (c *ClusterTx) GetNetworkACLNameAndProjectWithID(ctx context.Context, networkACLID int) (string, string, error) {
 	var networkACLName string
 	var projectName string
 
-	q := `SELECT networks_acls.name, projects.name FROM networks_acls JOIN projects ON projects.id=networks.project_id WHERE networks_acls.id=?`
+	q := `SELECT networks_acls.name, projects.name FROM networks_acls JOIN projects ON projects.id=networks_acls.project_id WHERE networks_acls.id=?`
 
 	err := c.tx.QueryRowContext(ctx, q, networkACLID).Scan(&networkACLName, &projectName)
 	if err != nil {
@@ -209,6 +330,16 @@ func networkACLConfig(ctx context.Context, tx *ClusterTx, id int64, acl *api.Net
 
 // CreateNetworkACL creates a new Network ACL.
 func (c *ClusterTx) CreateNetworkACL(ctx context.Context, projectName string, info *api.NetworkACLsPost) (int64, error) {
+	return c.createNetworkACL(ctx, projectName, info, false)
+}
+
+// CreateManagedNetworkACL creates a new Network ACL managed by the server, which users cannot modify.
+func (c *ClusterTx) CreateManagedNetworkACL(ctx context.Context, projectName string, info *api.NetworkACLsPost) (int64, error) {
+	return c.createNetworkACL(ctx, projectName, info, true)
+}
+
+// createNetworkACL creates a new Network ACL, marking it as managed by the server if requested.
+func (c *ClusterTx) createNetworkACL(ctx context.Context, projectName string, info *api.NetworkACLsPost, managed bool) (int64, error) {
 	var err error
 	var ingressJSON, egressJSON []byte
 
@@ -228,9 +359,9 @@ func (c *ClusterTx) CreateNetworkACL(ctx context.Context, projectName string, in
 
 	// Insert a new Network ACL record.
 	result, err := c.tx.ExecContext(ctx, `
-			INSERT INTO networks_acls (project_id, name, description, ingress, egress)
-			VALUES ((SELECT id FROM projects WHERE name = ? LIMIT 1), ?, ?, ?, ?)
-		`, projectName, info.Name, info.Description, string(ingressJSON), string(egressJSON))
+			INSERT INTO networks_acls (project_id, name, description, ingress, egress, managed)
+			VALUES ((SELECT id FROM projects WHERE name = ? LIMIT 1), ?, ?, ?, ?, ?)
+		`, projectName, info.Name, info.Description, string(ingressJSON), string(egressJSON), managed)
 	if err != nil {
 		return -1, err
 	}
@@ -327,9 +458,154 @@ func (c *ClusterTx) DeleteNetworkACL(ctx context.Context, id int64) error {
 	return err
 }
 
+// CreateNetworkACLRevision records the supplied ACL info as a new revision of the Network ACL with the given ID.
+// Only the most recent keep revisions are retained, older ones are removed.
+func (c *ClusterTx) CreateNetworkACLRevision(ctx context.Context, id int64, info *api.NetworkACL, requestor *api.EventLifecycleRequestor, keep int64) error {
+	ingressJSON, err := json.Marshal(info.Ingress)
+	if err != nil {
+		return fmt.Errorf("Failed marshalling ingress rules: %w", err)
+	}
+
+	egressJSON, err := json.Marshal(info.Egress)
+	if err != nil {
+		return fmt.Errorf("Failed marshalling egress rules: %w", err)
+	}
+
+	configJSON, err := json.Marshal(info.Config)
+	if err != nil {
+		return fmt.Errorf("Failed marshalling config: %w", err)
+	}
+
+	if requestor == nil {
+		requestor = &api.EventLifecycleRequestor{}
+	}
+
+	_, err = c.tx.ExecContext(ctx, `
+			INSERT INTO networks_acls_revisions (network_acl_id, revision, name, description, ingress, egress, config, requestor_username, requestor_protocol, requestor_address, date)
+			VALUES (?, (SELECT IFNULL(MAX(revision), 0) + 1 FROM networks_acls_revisions WHERE network_acl_id = ?), ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`, id, id, info.Name, info.Description, string(ingressJSON), string(egressJSON), string(configJSON), requestor.Username, requestor.Protocol, requestor.Address, time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("Failed inserting revision: %w", err)
+	}
+
+	// Remove revisions beyond the retention count.
+	_, err = c.tx.ExecContext(ctx, `
+			DELETE FROM networks_acls_revisions
+			WHERE network_acl_id = ? AND id NOT IN (
+				SELECT id FROM networks_acls_revisions WHERE network_acl_id = ? ORDER BY revision DESC LIMIT ?
+			)
+		`, id, id, keep)
+	if err != nil {
+		return fmt.Errorf("Failed removing old revisions: %w", err)
+	}
+
+	return nil
+}
+
+// GetNetworkACLRevisions returns the stored revisions of the Network ACL with the given ID (newest first).
+func (c *ClusterTx) GetNetworkACLRevisions(ctx context.Context, id int64) ([]api.NetworkACLRevision, error) {
+	q := `
+		SELECT revision, name, description, ingress, egress, config, requestor_username, requestor_protocol, requestor_address, date
+		FROM networks_acls_revisions
+		WHERE network_acl_id = ?
+		ORDER BY revision DESC
+	`
+
+	revisions := []api.NetworkACLRevision{}
+
+	err := query.Scan(ctx, c.tx, q, func(scan func(dest ...any) error) error {
+		revision, err := networkACLRevisionScan(scan)
+		if err != nil {
+			return err
+		}
+
+		revisions = append(revisions, *revision)
+
+		return nil
+	}, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return revisions, nil
+}
+
+// GetNetworkACLRevision returns a single stored revision of the Network ACL with the given ID.
+func (c *ClusterTx) GetNetworkACLRevision(ctx context.Context, id int64, revision int64) (*api.NetworkACLRevision, error) {
+	q := `
+		SELECT revision, name, description, ingress, egress, config, requestor_username, requestor_protocol, requestor_address, date
+		FROM networks_acls_revisions
+		WHERE network_acl_id = ? AND revision = ?
+		LIMIT 1
+	`
+
+	var result *api.NetworkACLRevision
+
+	err := query.Scan(ctx, c.tx, q, func(scan func(dest ...any) error) error {
+		var err error
+
+		result, err = networkACLRevisionScan(scan)
+
+		return err
+	}, id, revision)
+	if err != nil {
+		return nil, err
+	}
+
+	if result == nil {
+		return nil, api.StatusErrorf(http.StatusNotFound, "Network ACL revision not found")
+	}
+
+	return result, nil
+}
+
+// networkACLRevisionScan scans a single networks_acls_revisions row into an api.NetworkACLRevision.
+func networkACLRevisionScan(scan func(dest ...any) error) (*api.NetworkACLRevision, error) {
+	var ingressJSON, egressJSON, configJSON string
+
+	revision := api.NetworkACLRevision{
+		Requestor: &api.EventLifecycleRequestor{},
+	}
+
+	err := scan(&revision.Revision, &revision.Name, &revision.Description, &ingressJSON, &egressJSON, &configJSON, &revision.Requestor.Username, &revision.Requestor.Protocol, &revision.Requestor.Address, &revision.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal([]byte(ingressJSON), &revision.Ingress)
+	if err != nil {
+		return nil, fmt.Errorf("Failed unmarshalling ingress rules: %w", err)
+	}
+
+	err = json.Unmarshal([]byte(egressJSON), &revision.Egress)
+	if err != nil {
+		return nil, fmt.Errorf("Failed unmarshalling egress rules: %w", err)
+	}
+
+	err = json.Unmarshal([]byte(configJSON), &revision.Config)
+	if err != nil {
+		return nil, fmt.Errorf("Failed unmarshalling config: %w", err)
+	}
+
+	if revision.Ingress == nil {
+		revision.Ingress = []api.NetworkACLRule{}
+	}
+
+	if revision.Egress == nil {
+		revision.Egress = []api.NetworkACLRule{}
+	}
+
+	if revision.Config == nil {
+		revision.Config = map[string]string{}
+	}
+
+	return &revision, nil
+}
+
 // GetNetworkACLURIs returns the URIs for the network ACLs with the given project.
+// The ACLs managed by the server are left out, so that they don't prevent the project from being deleted.
 func (c *ClusterTx) GetNetworkACLURIs(ctx context.Context, projectID int, project string) ([]string, error) {
-	sql := `SELECT networks_acls.name from networks_acls WHERE networks_acls.project_id = ?`
+	sql := `SELECT networks_acls.name from networks_acls WHERE networks_acls.project_id = ? AND networks_acls.managed = 0`
 
 	names, err := query.SelectStrings(ctx, c.tx, sql, projectID)
 	if err != nil {
@@ -22,7 +22,7 @@ import (
 
 // NewTestNode creates a new Node for testing purposes, along with a function
 // that can be used to clean it up when done.
-func NewTestNode(t *testing.T) (*Node, func()) {
+func NewTestNode(t testing.TB) (*Node, func()) {
 	dir, err := os.MkdirTemp("", "incus-db-test-node-")
 	require.NoError(t, err)
 
@@ -58,7 +58,7 @@ func NewTestNodeTx(t *testing.T) (*NodeTx, func()) {
 
 // NewTestCluster creates a new Cluster for testing purposes, along with a function
 // that can be used to clean it up when done.
-func NewTestCluster(t *testing.T) (*Cluster, func()) {
+func NewTestCluster(t testing.TB) (*Cluster, func()) {
 	// Create an in-memory dqlite SQL server and associated store.
 	dir, store, serverCleanup := NewTestDqliteServer(t)
 
@@ -103,7 +103,7 @@ func NewTestClusterTx(t *testing.T) (*ClusterTx, func()) {
 //
 // Return the directory backing the test server and a newly created server
 // store that can be used to connect to it.
-func NewTestDqliteServer(t *testing.T) (string, driver.NodeStore, func()) {
+func NewTestDqliteServer(t testing.TB) (string, driver.NodeStore, func()) {
 	t.Helper()
 
 	listener, err := net.Listen("unix", "")
@@ -137,7 +137,7 @@ func NewTestDqliteServer(t *testing.T) (string, driver.NodeStore, func()) {
 }
 
 // Return a new temporary directory.
-func newDir(t *testing.T) (string, func()) {
+func newDir(t testing.TB) (string, func()) {
 	t.Helper()
 
 	dir, err := os.MkdirTemp("", "dqlite-replication-test-")
@@ -155,7 +155,7 @@ func newDir(t *testing.T) (string, func()) {
 	return dir, cleanup
 }
 
-func newLogFunc(t *testing.T) client.LogFunc {
+func newLogFunc(t testing.TB) client.LogFunc {
 	return func(l client.LogLevel, format string, a ...any) {
 		format = fmt.Sprintf("%s: %s", l.String(), format)
 		t.Logf(format, a...)
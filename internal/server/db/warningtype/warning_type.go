@@ -56,6 +56,10 @@ const (
 	StoragePoolUnvailable
 	// UnableToUpdateClusterCertificate represents the unable to update cluster certificate warning.
 	UnableToUpdateClusterCertificate
+	// NetworkACLApplyFailure represents a network ACL that couldn't be applied to a network.
+	NetworkACLApplyFailure
+	// NetworkACLUnsupportedNetwork represents a network ACL assigned to a network type that can't enforce it.
+	NetworkACLUnsupportedNetwork
 )
 
 // TypeNames associates a warning code to its name.
@@ -84,6 +88,8 @@ var TypeNames = map[Type]string{
 	InstanceTypeNotOperational:        "Instance type not operational",
 	StoragePoolUnvailable:             "Storage pool unavailable",
 	UnableToUpdateClusterCertificate:  "Unable to update cluster certificate",
+	NetworkACLApplyFailure:            "Failed to apply network ACL",
+	NetworkACLUnsupportedNetwork:      "Network ACL used by unsupported network type",
 }
 
 // Severity returns the severity of the warning type.
@@ -137,6 +143,10 @@ func (t Type) Severity() Severity {
 		return SeverityHigh
 	case UnableToUpdateClusterCertificate:
 		return SeverityLow
+	case NetworkACLApplyFailure:
+		return SeverityHigh
+	case NetworkACLUnsupportedNetwork:
+		return SeverityModerate
 	}
 
 	return SeverityLow
@@ -39,6 +39,7 @@ type ACLRule struct {
 	DestinationPort string
 	ICMPType        string
 	ICMPCode        string
+	RejectResponse  string // Either "icmp-port-unreachable", "tcp-reset" or empty for the default response.
 }
 
 // AddressForward represents a NAT address forward.
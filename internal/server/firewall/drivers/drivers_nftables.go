@@ -876,6 +876,15 @@ func (d Nftables) aclRuleCriteriaToRules(networkName string, ipVersion uint, rul
 
 	args = append(args, action)
 
+	if action == "reject" {
+		switch rule.RejectResponse {
+		case "tcp-reset":
+			args = append(args, "with", "tcp", "reset")
+		case "icmp-port-unreachable":
+			args = append(args, "with", "icmpx", "type", "port-unreachable")
+		}
+	}
+
 	return strings.Join(args, " "), isPartialRule, nil
 }
 
@@ -685,6 +685,19 @@ func (d Xtables) aclRuleCriteriaToArgs(networkName string, ipVersion uint, rule
 
 	actionArgs := append(args, "-j", strings.ToUpper(action))
 
+	if action == "reject" {
+		switch rule.RejectResponse {
+		case "tcp-reset":
+			actionArgs = append(actionArgs, "--reject-with", "tcp-reset")
+		case "icmp-port-unreachable":
+			if ipVersion == 4 {
+				actionArgs = append(actionArgs, "--reject-with", "icmp-port-unreachable")
+			} else {
+				actionArgs = append(actionArgs, "--reject-with", "icmp6-port-unreachable")
+			}
+		}
+	}
+
 	// Handle logging.
 	var logArgs []string
 	if rule.Log {
@@ -56,3 +56,12 @@ func TestMetricSet_FilterSamples(t *testing.T) {
 		require.Contains(t, hasKeys, "project")
 	}
 }
+
+func TestMetricSet_StringNetworkACLGauges(t *testing.T) {
+	set := NewMetricSet(nil)
+	set.AddSamples(NetworkACLUsedBy, Sample{Labels: map[string]string{"project": "default", "acl": "web", "type": "instance"}, Value: 0})
+
+	out := set.String()
+	require.Contains(t, out, "# TYPE incus_network_acl_usedby gauge\n")
+	require.Contains(t, out, `incus_network_acl_usedby{acl="web",project="default",type="instance"} 0`+"\n")
+}
@@ -144,6 +144,10 @@ const (
 	GoOtherSysBytes
 	// GoNextGCBytes represents the number of heap bytes when next garbage collection will take place.
 	GoNextGCBytes
+	// NetworkACLRules represents the number of rules of a network ACL.
+	NetworkACLRules
+	// NetworkACLUsedBy represents the number of entities using a network ACL.
+	NetworkACLUsedBy
 )
 
 // MetricNames associates a metric type to its name.
@@ -200,6 +204,8 @@ var MetricNames = map[MetricType]string{
 	MemoryUnevictableBytes:      "incus_memory_Unevictable_bytes",
 	MemoryWritebackBytes:        "incus_memory_Writeback_bytes",
 	MemoryOOMKillsTotal:         "incus_memory_OOM_kills_total",
+	NetworkACLRules:             "incus_network_acl_rules",
+	NetworkACLUsedBy:            "incus_network_acl_usedby",
 	NetworkReceiveBytesTotal:    "incus_network_receive_bytes_total",
 	NetworkReceiveDropTotal:     "incus_network_receive_drop_total",
 	NetworkReceiveErrsTotal:     "incus_network_receive_errs_total",
@@ -268,6 +274,8 @@ var MetricHeaders = map[MetricType]string{
 	MemoryUnevictableBytes:      "# HELP incus_memory_Unevictable_bytes The amount of unevictable memory.",
 	MemoryWritebackBytes:        "# HELP incus_memory_Writeback_bytes The amount of memory queued for syncing to disk.",
 	MemoryOOMKillsTotal:         "# HELP incus_memory_OOM_kills_total The number of out of memory kills.",
+	NetworkACLRules:             "# HELP incus_network_acl_rules The number of rules of a network ACL.",
+	NetworkACLUsedBy:            "# HELP incus_network_acl_usedby The number of entities using a network ACL.",
 	NetworkReceiveBytesTotal:    "# HELP incus_network_receive_bytes_total The amount of received bytes on a given interface.",
 	NetworkReceiveDropTotal:     "# HELP incus_network_receive_drop_total The amount of received dropped bytes on a given interface.",
 	NetworkReceiveErrsTotal:     "# HELP incus_network_receive_errs_total The amount of received errors on a given interface.",
@@ -0,0 +1,135 @@
+package acl
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/lxc/incus/v6/shared/api"
+	"github.com/lxc/incus/v6/shared/util"
+)
+
+// ConflictingRules returns the pairs of rules of the two ACLs matching some of the same traffic in the same
+// direction, one of them allowing it and the other dropping or rejecting it. Rules match the same traffic when their protocols, ICMP types and codes,
+// ports and source and destination subjects overlap, address subjects being compared by the addresses they match
+// and named subjects only overlapping with the same name. Other criteria and disabled rules are ignored.
+// This is only advisory, such rules can be applied together. Ingress conflicts are returned first, each direction
+// in the order of the rules of a. The supplied ACLs aren't modified.
+func ConflictingRules(a *api.NetworkACLPut, b *api.NetworkACLPut) []api.NetworkACLRuleConflict {
+	conflicts := []api.NetworkACLRuleConflict{}
+
+	for _, direction := range []ruleDirection{ruleDirectionIngress, ruleDirectionEgress} {
+		aRules, bRules := a.Ingress, b.Ingress
+		if direction == ruleDirectionEgress {
+			aRules, bRules = a.Egress, b.Egress
+		}
+
+		for i, aRule := range aRules {
+			aRule.Normalise()
+
+			for j, bRule := range bRules {
+				bRule.Normalise()
+
+				if ruleActionAllows(aRule.Action) == ruleActionAllows(bRule.Action) || !rulesOverlap(aRule, bRule) {
+					continue
+				}
+
+				conflicts = append(conflicts, api.NetworkACLRuleConflict{
+					Direction:    string(direction),
+					FirstIndex:   i,
+					FirstAction:  aRule.Action,
+					SecondIndex:  j,
+					SecondAction: bRule.Action,
+				})
+			}
+		}
+	}
+
+	return conflicts
+}
+
+// ruleActionAllows returns whether the rule action lets the traffic through.
+func ruleActionAllows(action string) bool {
+	return action == "allow" || action == "allow-stateless"
+}
+
+// rulesOverlap returns whether two normalised rules can match the same traffic, as described in ConflictingRules.
+func rulesOverlap(a api.NetworkACLRule, b api.NetworkACLRule) bool {
+	if a.State == "disabled" || b.State == "disabled" {
+		return false
+	}
+
+	for _, values := range [][2]string{{a.Protocol, b.Protocol}, {a.ICMPType, b.ICMPType}, {a.ICMPCode, b.ICMPCode}} {
+		// An empty value matches anything.
+		if values[0] != "" && values[1] != "" && values[0] != values[1] {
+			return false
+		}
+	}
+
+	return rulePortsOverlap(a.SourcePort, b.SourcePort) &&
+		rulePortsOverlap(a.DestinationPort, b.DestinationPort) &&
+		ruleSubjectsOverlap(a.Source, b.Source) &&
+		ruleSubjectsOverlap(a.Destination, b.Destination)
+}
+
+// ruleSubjectsOverlap returns whether two comma separated subject lists match some of the same addresses.
+// An empty list matches any address.
+func ruleSubjectsOverlap(a string, b string) bool {
+	if a == "" || b == "" {
+		return true
+	}
+
+	bSubjects := util.SplitNTrimSpace(b, ",", -1, true)
+	for _, aSubject := range expandRuleSubjects(util.SplitNTrimSpace(a, ",", -1, true)) {
+		for _, bSubject := range bSubjects {
+			if ruleSubjectRelation(bSubject, aSubject) != "" {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// rulePortsOverlap returns whether two comma separated lists of ports and port ranges have a port in common.
+// An empty list matches any port.
+func rulePortsOverlap(a string, b string) bool {
+	if a == "" || b == "" {
+		return true
+	}
+
+	for _, aPort := range util.SplitNTrimSpace(a, ",", -1, true) {
+		aStart, aEnd, ok := rulePortRange(aPort)
+		if !ok {
+			continue
+		}
+
+		for _, bPort := range util.SplitNTrimSpace(b, ",", -1, true) {
+			bStart, bEnd, ok := rulePortRange(bPort)
+			if ok && aStart <= bEnd && bStart <= aEnd {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// rulePortRange returns the first and last ports of a single port or "start-end" port range.
+func rulePortRange(port string) (uint64, uint64, bool) {
+	startPort, endPort, isRange := strings.Cut(port, "-")
+	if !isRange {
+		endPort = startPort
+	}
+
+	start, err := strconv.ParseUint(startPort, 10, 16)
+	if err != nil {
+		return 0, 0, false
+	}
+
+	end, err := strconv.ParseUint(endPort, 10, 16)
+	if err != nil {
+		return 0, 0, false
+	}
+
+	return start, end, true
+}
@@ -0,0 +1,55 @@
+package acl
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lxc/incus/v6/shared/api"
+)
+
+func TestConflictingRules(t *testing.T) {
+	a := &api.NetworkACLPut{
+		Ingress: []api.NetworkACLRule{
+			{Action: "allow", Source: "10.0.0.0/24", Protocol: "tcp", DestinationPort: "80,443", State: "enabled"},
+			{Action: "allow", Source: "web", State: "enabled"},
+		},
+		Egress: []api.NetworkACLRule{
+			{Action: "drop", Destination: "any", State: "logged"},
+		},
+	}
+
+	b := &api.NetworkACLPut{
+		Ingress: []api.NetworkACLRule{
+			{Action: "allow", Source: "10.0.0.5", State: "enabled"},                                                        // Same action.
+			{Action: "drop", Source: "10.0.0.5, 192.0.2.1", Protocol: "tcp", DestinationPort: "400-500", State: "enabled"}, // Contained address and overlapping ports.
+			{Action: "drop", Source: "web", State: "enabled"},                                                              // Same named subject.
+		},
+		Egress: []api.NetworkACLRule{
+			{Action: "allow", Destination: "2001:db8::/32", State: "enabled"},
+		},
+	}
+
+	assert.Equal(t, []api.NetworkACLRuleConflict{
+		{Direction: "ingress", FirstIndex: 0, FirstAction: "allow", SecondIndex: 1, SecondAction: "drop"},
+		{Direction: "ingress", FirstIndex: 1, FirstAction: "allow", SecondIndex: 2, SecondAction: "drop"},
+		{Direction: "egress", FirstIndex: 0, FirstAction: "drop", SecondIndex: 0, SecondAction: "allow"},
+	}, ConflictingRules(a, b))
+
+	// Rules with different actions that can't match the same traffic don't conflict.
+	c := &api.NetworkACLPut{
+		Ingress: []api.NetworkACLRule{
+			{Action: "drop", Source: "10.0.1.0/24", State: "enabled"},                                         // Other subnet.
+			{Action: "drop", Source: "10.0.0.0/24", Protocol: "udp", State: "enabled"},                        // Other protocol.
+			{Action: "drop", Source: "10.0.0.0/24", Protocol: "tcp", DestinationPort: "22", State: "enabled"}, // Other port.
+			{Action: "drop", Source: "db", State: "enabled"},                                                  // Other named subject.
+			{Action: "allow-stateless", Source: "10.0.0.0/24", State: "enabled"},                              // Both allowing.
+			{Action: "drop", Source: "10.0.0.0/24", State: "disabled"},                                        // Disabled.
+		},
+		Egress: []api.NetworkACLRule{
+			{Action: "reject", Destination: "any", State: "enabled"}, // Both blocking.
+		},
+	}
+
+	assert.Empty(t, ConflictingRules(a, c))
+}
@@ -3,9 +3,15 @@ package acl
 import (
 	"context"
 	"fmt"
+	"net"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/lxc/incus/v6/internal/server/db"
 	firewallDrivers "github.com/lxc/incus/v6/internal/server/firewall/drivers"
+	"github.com/lxc/incus/v6/internal/server/project"
+	"github.com/lxc/incus/v6/internal/server/response"
 	"github.com/lxc/incus/v6/internal/server/state"
 	"github.com/lxc/incus/v6/shared/api"
 	"github.com/lxc/incus/v6/shared/logger"
@@ -14,48 +20,76 @@ import (
 
 // FirewallApplyACLRules applies ACL rules to network firewall.
 func FirewallApplyACLRules(s *state.State, logger logger.Logger, aclProjectName string, aclNet NetworkACLUsage) error {
-	var dropRules []firewallDrivers.ACLRule
-	var rejectRules []firewallDrivers.ACLRule
-	var allowRules []firewallDrivers.ACLRule
-	var allowStatelessRules []firewallDrivers.ACLRule
+	actionRules := make(map[string][]firewallDrivers.ACLRule, len(ruleActionOrder))
+
+	// Rules outside of their validity window are left out until the scheduled refresh applies them.
+	now := time.Now()
 
 	// convertACLRules converts the ACL rules to Firewall ACL rules.
-	convertACLRules := func(direction string, logPrefix string, rules ...api.NetworkACLRule) error {
+	convertACLRules := func(direction string, logPrefix string, subnets []*net.IPNet, memberAddresses map[string][]string, inheritedRules int, rules ...api.NetworkACLRule) error {
 		for ruleIndex, rule := range rules {
-			if rule.State == "disabled" {
+			if rule.State == "disabled" || !ruleIsActive(rule, now) {
+				continue
+			}
+
+			err := firewallValidateRule(rule)
+			if err != nil {
+				return err
+			}
+
+			source, err := firewallRuleSubjects(rule.Source, subnets, memberAddresses)
+			if err != nil {
+				return err
+			}
+
+			destination, err := firewallRuleSubjects(rule.Destination, subnets, memberAddresses)
+			if err != nil {
+				return err
+			}
+
+			// Skip rules whose subjects don't match any address, such as ACLs without known members, rather
+			// than letting them match any address.
+			if (rule.Source != "" && len(source) == 0) || (rule.Destination != "" && len(destination) == 0) {
 				continue
 			}
 
 			firewallACLRule := firewallDrivers.ACLRule{
 				Direction:       direction,
 				Action:          rule.Action,
-				Source:          rule.Source,
-				Destination:     rule.Destination,
+				Source:          strings.Join(source, ","),
+				Destination:     strings.Join(destination, ","),
 				Protocol:        rule.Protocol,
 				SourcePort:      rule.SourcePort,
 				DestinationPort: rule.DestinationPort,
 				ICMPType:        rule.ICMPType,
 				ICMPCode:        rule.ICMPCode,
+				RejectResponse:  rule.RejectResponse,
 			}
 
 			if rule.State == "logged" {
 				firewallACLRule.Log = true
-				// Max 29 chars.
-				firewallACLRule.LogName = fmt.Sprintf("%s-%s-%d", logPrefix, direction, ruleIndex)
-			}
-
-			switch {
-			case rule.Action == "drop":
-				dropRules = append(dropRules, firewallACLRule)
-			case rule.Action == "reject":
-				rejectRules = append(rejectRules, firewallACLRule)
-			case rule.Action == "allow":
-				allowRules = append(allowRules, firewallACLRule)
-			case rule.Action == "allow-stateless": // TODO: add NOTRACK support
-				allowStatelessRules = append(allowStatelessRules, firewallACLRule)
-			default:
+				firewallACLRule.LogName = fmt.Sprintf("%s-%s", logPrefix, ruleLogLabel(direction, ruleIndex, inheritedRules)) // Max 29 chars.
+			}
+
+			// The firewall drivers don't know about the neighbor discovery virtual protocol, so expand it into
+			// one rule per ICMPv6 type.
+			firewallACLRules := []firewallDrivers.ACLRule{firewallACLRule}
+			if rule.Protocol == ruleProtocolICMP6NDP {
+				firewallACLRules = make([]firewallDrivers.ACLRule, 0, len(ruleICMP6NDPTypes))
+				for _, icmpType := range ruleICMP6NDPTypes {
+					ndpRule := firewallACLRule
+					ndpRule.Protocol = "icmp6"
+					ndpRule.ICMPType = icmpType
+					firewallACLRules = append(firewallACLRules, ndpRule)
+				}
+			}
+
+			// TODO: add NOTRACK support for allow-stateless rules.
+			if !slices.Contains(ruleActionOrder, rule.Action) {
 				return fmt.Errorf("Unrecognised action %q", rule.Action)
 			}
+
+			actionRules[rule.Action] = append(actionRules[rule.Action], firewallACLRules...)
 		}
 
 		return nil
@@ -64,13 +98,19 @@ func FirewallApplyACLRules(s *state.State, logger logger.Logger, aclProjectName
 	logPrefix := aclNet.Name
 
 	// Load ACLs specified by network.
+	aclInfos := []*composedACL{}
+	subjectNames := []string{}
 	for _, aclName := range util.SplitNTrimSpace(aclNet.Config["security.acls"], ",", -1, true) {
-		var aclInfo *api.NetworkACL
+		var aclInfo *composedACL
 
 		err := s.DB.Cluster.Transaction(context.TODO(), func(ctx context.Context, tx *db.ClusterTx) error {
-			var err error
+			_, info, err := tx.GetNetworkACL(ctx, aclProjectName, aclName)
+			if err != nil {
+				return err
+			}
 
-			_, aclInfo, err = tx.GetNetworkACL(ctx, aclProjectName, aclName)
+			// Apply the rules inherited from the parents of the ACL first.
+			aclInfo, err = withParentRules(ctx, tx, aclProjectName, info)
 
 			return err
 		})
@@ -78,22 +118,43 @@ func FirewallApplyACLRules(s *state.State, logger logger.Logger, aclProjectName
 			return fmt.Errorf("Failed loading ACL %q for network %q: %w", aclName, aclNet.Name, err)
 		}
 
-		err = convertACLRules("ingress", logPrefix, aclInfo.Ingress...)
+		aclInfos = append(aclInfos, aclInfo)
+		subjectNames = append(subjectNames, ruleSubjectNames(&aclInfo.NetworkACLPut)...)
+	}
+
+	// Named subjects are expanded to the static addresses of the instances using the ACLs they refer to.
+	memberAddresses := map[string][]string{}
+	if len(subjectNames) > 0 {
+		var err error
+
+		memberAddresses, err = firewallACLMemberAddresses(s, aclProjectName, subjectNames, nil)
 		if err != nil {
-			return fmt.Errorf("Failed converting ACL %q ingress rules for network %q: %w", aclInfo.Name, aclNet.Name, err)
+			return fmt.Errorf("Failed getting addresses of network ACL members: %w", err)
 		}
+	}
 
-		err = convertACLRules("egress", logPrefix, aclInfo.Egress...)
-		if err != nil {
-			return fmt.Errorf("Failed converting ACL %q egress rules for network %q: %w", aclInfo.Name, aclNet.Name, err)
+	subnets := firewallNetworkSubnets(aclNet.Config)
+
+	for _, aclInfo := range aclInfos {
+		if ruleDirectionEnabled(aclInfo.Config, ruleDirectionIngress) {
+			err := convertACLRules("ingress", logPrefix, subnets, memberAddresses, aclInfo.inheritedIngress, aclInfo.Ingress...)
+			if err != nil {
+				return fmt.Errorf("Failed converting ACL %q ingress rules for network %q: %w", aclInfo.Name, aclNet.Name, err)
+			}
+		}
+
+		if ruleDirectionEnabled(aclInfo.Config, ruleDirectionEgress) {
+			err := convertACLRules("egress", logPrefix, subnets, memberAddresses, aclInfo.inheritedEgress, aclInfo.Egress...)
+			if err != nil {
+				return fmt.Errorf("Failed converting ACL %q egress rules for network %q: %w", aclInfo.Name, aclNet.Name, err)
+			}
 		}
 	}
 
 	var rules []firewallDrivers.ACLRule
-	rules = append(rules, dropRules...)
-	rules = append(rules, rejectRules...)
-	rules = append(rules, allowRules...)
-	rules = append(rules, allowStatelessRules...)
+	for _, action := range ruleActionOrder {
+		rules = append(rules, actionRules[action]...)
+	}
 
 	// Add the automatic default ACL rule for the network.
 	egressAction, egressLogged := firewallACLDefaults(aclNet.Config, "egress")
@@ -120,16 +181,236 @@ func FirewallApplyACLRules(s *state.State, logger logger.Logger, aclProjectName
 // If the security.acls.default.{in,e}gress.action or security.acls.default.{in,e}gress.logged settings are not
 // specified in the network config, then it returns "reject" and false respectively.
 func firewallACLDefaults(netConfig map[string]string, direction string) (string, bool) {
-	defaults := map[string]string{
-		fmt.Sprintf("security.acls.default.%s.action", direction): "reject",
-		fmt.Sprintf("security.acls.default.%s.logged", direction): "false",
+	return NICDefaults(nil, netConfig, direction)
+}
+
+// firewallValidateRule checks that the rule only uses criteria the firewall drivers can enforce.
+// Logical switch ports only exist on OVN networks and the packet length isn't matched by the firewall drivers, so
+// ignoring them would widen the rule.
+func firewallValidateRule(rule api.NetworkACLRule) error {
+	if rule.InPort != "" || rule.OutPort != "" {
+		return fmt.Errorf("Rules matching on in or out ports aren't supported on bridge networks")
+	}
+
+	if rule.PacketLen != "" {
+		return fmt.Errorf("Rules matching on packet length aren't supported on bridge networks")
+	}
+
+	if rule.Related {
+		return fmt.Errorf("Rules allowing related traffic aren't supported on bridge networks")
+	}
+
+	if rule.DSCP != "" {
+		return fmt.Errorf("Rules marking traffic with a DSCP value aren't supported on bridge networks")
+	}
+
+	return nil
+}
+
+// firewallNetworkSubnets returns the subnets of a bridge network.
+func firewallNetworkSubnets(netConfig map[string]string) []*net.IPNet {
+	subnets := []*net.IPNet{}
+	for _, key := range []string{"ipv4.address", "ipv6.address"} {
+		_, subnet, err := net.ParseCIDR(netConfig[key])
+		if err == nil {
+			subnets = append(subnets, subnet)
+		}
+	}
+
+	return subnets
+}
+
+// firewallRuleSubjects converts the subjects of a rule into the addresses understood by the firewall drivers.
+// The @internal and @external subjects are resolved using the subnets of the network and named subjects are
+// resolved using the static addresses of the instances using the referenced ACLs. The @ipv4 and @ipv6 subjects
+// are resolved to all the addresses of their family, so the drivers only apply them to that family.
+func firewallRuleSubjects(subjects string, subnets []*net.IPNet, memberAddresses map[string][]string) ([]string, error) {
+	addresses := []string{}
+	for _, subject := range expandRuleSubjects(util.SplitNTrimSpace(subjects, ",", -1, true)) {
+		switch {
+		case slices.Contains(ruleSubjectIPv4Aliases, subject):
+			addresses = append(addresses, "0.0.0.0/0")
+		case slices.Contains(ruleSubjectIPv6Aliases, subject):
+			addresses = append(addresses, "::/0")
+		case ruleSubjectFamily(subject) != 0:
+			// The firewall drivers can't match the zone of link-local addresses.
+			_, zone := ruleSubjectZone(subject)
+			if zone != "" {
+				return nil, fmt.Errorf("Zoned address subject %q isn't supported on bridge networks", subject)
+			}
+
+			addresses = append(addresses, subject)
+		case slices.Contains(ruleSubjectInternalAliases, subject):
+			for _, subnet := range subnets {
+				addresses = append(addresses, subnet.String())
+			}
+
+		case slices.Contains(ruleSubjectExternalAliases, subject):
+			for _, anySubnet := range []string{"0.0.0.0/0", "::/0"} {
+				_, familySubnet, _ := net.ParseCIDR(anySubnet)
+
+				// Bridge networks have at most one subnet per family.
+				external := []string{anySubnet}
+				for _, subnet := range subnets {
+					if len(subnet.IP) == len(familySubnet.IP) {
+						external = subnetComplement(subnet)
+					}
+				}
+
+				addresses = append(addresses, external...)
+			}
+
+		case strings.HasPrefix(subject, ruleSubjectVLANPrefix):
+			return nil, fmt.Errorf("VLAN subject %q isn't supported on bridge networks", subject)
+		case strings.HasPrefix(subject, "@"):
+			return nil, fmt.Errorf("Network peer subject %q isn't supported on bridge networks", subject)
+		case ruleSubjectIsFQDN(subject):
+			return nil, fmt.Errorf("Host name subject %q isn't supported on bridge networks", subject)
+		default:
+			addresses = append(addresses, memberAddresses[subject]...)
+		}
+	}
+
+	return addresses, nil
+}
+
+// subnetComplement returns the CIDRs covering all the addresses of the subnet's family that are outside of it.
+func subnetComplement(subnet *net.IPNet) []string {
+	ones, bits := subnet.Mask.Size()
+
+	cidrs := make([]string, 0, ones)
+	for i := 0; i < ones; i++ {
+		// Flip the i-th bit of the subnet's prefix and drop the bits after it.
+		ip := slices.Clone(subnet.IP)
+		ip[i/8] ^= 0x80 >> (i % 8)
+
+		mask := net.CIDRMask(i+1, bits)
+		cidr := net.IPNet{IP: ip.Mask(mask), Mask: mask}
+		cidrs = append(cidrs, cidr.String())
+	}
+
+	return cidrs
+}
+
+// firewallACLMemberAddresses returns the static addresses of the instance NICs connected to the bridge networks
+// using each of the specified ACLs. The ACLs used by some networks can be overridden with networkACLNames, which
+// is used to validate a network's config before it is applied.
+func firewallACLMemberAddresses(s *state.State, aclProjectName string, aclNames []string, networkACLNames map[string][]string) (map[string][]string, error) {
+	memberAddresses := map[string][]string{}
+
+	err := s.DB.Cluster.Transaction(context.TODO(), func(ctx context.Context, tx *db.ClusterTx) error {
+		networkNames, err := tx.GetCreatedNetworkNamesByProject(ctx, aclProjectName)
+		if err != nil && !response.IsNotFoundError(err) {
+			return fmt.Errorf("Failed loading networks for project %q: %w", aclProjectName, err)
+		}
+
+		// Find the ACLs used by each bridge network.
+		bridgeACLNames := map[string][]string{}
+		for _, networkName := range networkNames {
+			_, network, _, err := tx.GetNetworkInAnyState(ctx, aclProjectName, networkName)
+			if err != nil {
+				return fmt.Errorf("Failed to get network config for %q: %w", networkName, err)
+			}
+
+			if network.Type == "bridge" {
+				bridgeACLNames[network.Name] = util.SplitNTrimSpace(network.Config["security.acls"], ",", -1, true)
+			}
+		}
+
+		for networkName, netACLNames := range networkACLNames {
+			bridgeACLNames[networkName] = netACLNames
+		}
+
+		return tx.InstanceList(ctx, func(inst db.InstanceArgs, p api.Project) error {
+			// Skip instances who's effective network project doesn't match this Network ACL's project.
+			if project.NetworkProjectFromRecord(&p) != aclProjectName {
+				return nil
+			}
+
+			devices := db.ExpandInstanceDevices(inst.Devices.Clone(), inst.Profiles)
+			for _, devConfig := range devices {
+				if devConfig["type"] != "nic" || devConfig["network"] == "" {
+					continue
+				}
+
+				for _, aclName := range bridgeACLNames[devConfig["network"]] {
+					if !slices.Contains(aclNames, aclName) {
+						continue
+					}
+
+					for _, key := range []string{"ipv4.address", "ipv6.address"} {
+						if devConfig[key] != "" {
+							memberAddresses[aclName] = append(memberAddresses[aclName], devConfig[key])
+						}
+					}
+				}
+			}
+
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return memberAddresses, nil
+}
+
+// FirewallValidateACLs checks that the subjects of the rules of the specified ACLs can be enforced on the bridge
+// network, assuming it uses the networkACLNames ACLs. Named subjects must refer to ACLs used by instances with
+// static addresses and network peers can't be used.
+func FirewallValidateACLs(s *state.State, aclProjectName string, networkName string, networkACLNames []string, aclNames []string) error {
+	subjectNames := []string{}
+
+	for _, aclName := range aclNames {
+		var aclInfo *api.NetworkACL
+
+		err := s.DB.Cluster.Transaction(context.TODO(), func(ctx context.Context, tx *db.ClusterTx) error {
+			var err error
+
+			_, aclInfo, err = tx.GetNetworkACL(ctx, aclProjectName, aclName)
+
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("Failed loading network ACL %q: %w", aclName, err)
+		}
+
+		for _, rule := range append(slices.Clone(aclInfo.Ingress), aclInfo.Egress...) {
+			err = firewallValidateRule(rule)
+			if err != nil {
+				return fmt.Errorf("Network ACL %q cannot be used: %w", aclName, err)
+			}
+
+			for _, subjects := range []string{rule.Source, rule.Destination} {
+				_, err = firewallRuleSubjects(subjects, nil, nil)
+				if err != nil {
+					return fmt.Errorf("Network ACL %q cannot be used: %w", aclName, err)
+				}
+			}
+		}
+
+		for _, subjectName := range ruleSubjectNames(&aclInfo.NetworkACLPut) {
+			if !slices.Contains(subjectNames, subjectName) {
+				subjectNames = append(subjectNames, subjectName)
+			}
+		}
+	}
+
+	if len(subjectNames) == 0 {
+		return nil
+	}
+
+	memberAddresses, err := firewallACLMemberAddresses(s, aclProjectName, subjectNames, map[string][]string{networkName: networkACLNames})
+	if err != nil {
+		return fmt.Errorf("Failed getting addresses of network ACL members: %w", err)
 	}
 
-	for k := range defaults {
-		if netConfig[k] != "" {
-			defaults[k] = netConfig[k]
+	for _, subjectName := range subjectNames {
+		if len(memberAddresses[subjectName]) == 0 {
+			return fmt.Errorf("Network ACL %q is referenced by the rules but has no instances with static IP addresses on bridge networks", subjectName)
 		}
 	}
 
-	return defaults[fmt.Sprintf("security.acls.default.%s.action", direction)], util.IsTrue(defaults[fmt.Sprintf("security.acls.default.%s.logged", direction)])
+	return nil
 }
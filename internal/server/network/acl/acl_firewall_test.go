@@ -0,0 +1,47 @@
+package acl
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubnetComplement(t *testing.T) {
+	_, subnet, err := net.ParseCIDR("10.0.0.0/8")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"128.0.0.0/1", "64.0.0.0/2", "32.0.0.0/3", "16.0.0.0/4", "0.0.0.0/5", "12.0.0.0/6", "8.0.0.0/7", "11.0.0.0/8"}, subnetComplement(subnet))
+
+	_, subnet, err = net.ParseCIDR("fd42::/16")
+	require.NoError(t, err)
+	assert.Len(t, subnetComplement(subnet), 16)
+	assert.Equal(t, "::/1", subnetComplement(subnet)[0])
+}
+
+func TestFirewallRuleSubjects(t *testing.T) {
+	subnets := []*net.IPNet{}
+	for _, cidr := range []string{"10.0.0.0/8", "fd42::/16"} {
+		_, subnet, err := net.ParseCIDR(cidr)
+		require.NoError(t, err)
+		subnets = append(subnets, subnet)
+	}
+
+	members := map[string][]string{"web": {"10.0.0.2", "fd42::2"}}
+
+	addresses, err := firewallRuleSubjects("192.0.2.1,@internal,web,db", subnets, members)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"192.0.2.1", "10.0.0.0/8", "fd42::/16", "10.0.0.2", "fd42::2"}, addresses)
+
+	addresses, err = firewallRuleSubjects("@external", subnets[:1], members)
+	require.NoError(t, err)
+	assert.Len(t, addresses, 9)
+	assert.Equal(t, "::/0", addresses[8])
+
+	addresses, err = firewallRuleSubjects("", subnets, members)
+	require.NoError(t, err)
+	assert.Empty(t, addresses)
+
+	_, err = firewallRuleSubjects("@ovn1/peer1", subnets, members)
+	assert.Error(t, err)
+}
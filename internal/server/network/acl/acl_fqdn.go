@@ -0,0 +1,246 @@
+package acl
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lxc/incus/v6/internal/server/db"
+	"github.com/lxc/incus/v6/internal/server/network/ovn"
+	"github.com/lxc/incus/v6/internal/server/state"
+	"github.com/lxc/incus/v6/shared/api"
+	"github.com/lxc/incus/v6/shared/logger"
+	"github.com/lxc/incus/v6/shared/util"
+)
+
+// ovnACLFQDNAddressSetPrefix prefix used when naming the address sets of host name subjects in OVN.
+const ovnACLFQDNAddressSetPrefix = "incus_acl_fqdn_"
+
+// fqdnResolveTimeout is the time allowed to resolve a host name subject.
+const fqdnResolveTimeout = 10 * time.Second
+
+// fqdnLabel matches a single label of a host name.
+var fqdnLabel = regexp.MustCompile(`^[a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?$`)
+
+// ruleSubjectIsFQDN returns whether the subject is a host name to resolve. Host names are told apart from ACL
+// names by containing a dot (which ACL names can't), and from IP addresses and ranges by their top-level label
+// not being numeric.
+func ruleSubjectIsFQDN(subject string) bool {
+	if len(subject) > 253 || !strings.Contains(subject, ".") || ruleSubjectFamily(subject) != 0 {
+		return false
+	}
+
+	labels := strings.Split(subject, ".")
+	if strings.Trim(labels[len(labels)-1], "0123456789") == "" {
+		return false
+	}
+
+	for _, label := range labels {
+		if !fqdnLabel.MatchString(label) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// OVNACLFQDNAddressSetPrefix returns the address set prefix for a host name subject. Host names are case-insensitive
+// so all the spellings of a host name share the same address sets.
+func OVNACLFQDNAddressSetPrefix(fqdn string) ovn.OVNAddressSet {
+	hash := sha256.Sum256([]byte(strings.ToLower(fqdn)))
+
+	return ovn.OVNAddressSet(ovnACLFQDNAddressSetPrefix + hex.EncodeToString(hash[0:8]))
+}
+
+// ruleSubjectFQDNs returns the sorted list of unique host names used as subjects in the rules.
+func ruleSubjectFQDNs(info *api.NetworkACLPut) []string {
+	fqdns := []string{}
+
+	for _, rule := range append(slices.Clone(info.Ingress), info.Egress...) {
+		for _, subjects := range []string{rule.Source, rule.Destination} {
+			for _, subject := range util.SplitNTrimSpace(subjects, ",", -1, true) {
+				subject = strings.ToLower(subject)
+				if ruleSubjectIsFQDN(subject) && !slices.Contains(fqdns, subject) {
+					fqdns = append(fqdns, subject)
+				}
+			}
+		}
+	}
+
+	slices.Sort(fqdns)
+
+	return fqdns
+}
+
+// resolveFQDN resolves the host name into the addresses to put in its address sets.
+func resolveFQDN(fqdn string) ([]net.IPNet, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), fqdnResolveTimeout)
+	defer cancel()
+
+	ips, err := net.DefaultResolver.LookupIP(ctx, "ip", fqdn)
+	if err != nil {
+		return nil, err
+	}
+
+	addresses := make([]net.IPNet, 0, len(ips))
+	for _, ip := range ips {
+		if ip.To4() != nil {
+			addresses = append(addresses, net.IPNet{IP: ip.To4(), Mask: net.CIDRMask(32, 32)})
+		} else {
+			addresses = append(addresses, net.IPNet{IP: ip, Mask: net.CIDRMask(128, 128)})
+		}
+	}
+
+	return addresses, nil
+}
+
+// fqdnAddressCache holds the addresses the host name subjects resolved to on the last refresh.
+type fqdnAddressCache struct {
+	mu        sync.Mutex
+	addresses map[string][]net.IPNet
+}
+
+// fqdnCache is filled by OVNRefreshFQDNs, so that applying the rules doesn't need to resolve the host names.
+var fqdnCache = &fqdnAddressCache{addresses: map[string][]net.IPNet{}}
+
+// get returns the cached addresses of the host name and whether it has been resolved yet.
+func (c *fqdnAddressCache) get(fqdn string) ([]net.IPNet, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	addresses, found := c.addresses[fqdn]
+
+	return addresses, found
+}
+
+// set replaces the cached addresses of the host names. Host names missing from the addresses are forgotten.
+func (c *fqdnAddressCache) set(addresses map[string][]net.IPNet) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.addresses = addresses
+}
+
+// ovnEnsureFQDNAddressSets adds the creation of the address sets of the host names used by the ACLs to the
+// transaction, so that the rules referencing them can be applied. No host name is resolved here: the sets get the
+// addresses cached by the last refresh, and host names that weren't resolved by it yet keep their current
+// addresses (if any) until the next refresh fills them in.
+func ovnEnsureFQDNAddressSets(txn *ovn.NBTransaction, aclInfos ...*api.NetworkACL) error {
+	fqdns := []string{}
+	for _, aclInfo := range aclInfos {
+		for _, fqdn := range ruleSubjectFQDNs(&aclInfo.NetworkACLPut) {
+			if !slices.Contains(fqdns, fqdn) {
+				fqdns = append(fqdns, fqdn)
+			}
+		}
+	}
+
+	for _, fqdn := range fqdns {
+		var err error
+
+		addresses, found := fqdnCache.get(fqdn)
+		if found {
+			err = txn.UpdateAddressSetReplace(context.TODO(), OVNACLFQDNAddressSetPrefix(fqdn), addresses...)
+		} else {
+			// Only create the missing sets, without touching the addresses of existing ones.
+			err = txn.UpdateAddressSetAdd(context.TODO(), OVNACLFQDNAddressSetPrefix(fqdn))
+		}
+
+		if err != nil {
+			return fmt.Errorf("Failed ensuring address set for host name %q: %w", fqdn, err)
+		}
+	}
+
+	return nil
+}
+
+// OVNRefreshFQDNs resolves the host names used by the network ACLs of all projects again, caches the result for
+// the rules applied later on, and replaces the addresses of their OVN address sets with it. Host names that fail
+// to resolve keep their previous addresses, and those without address sets aren't used by OVN networks so are
+// skipped. The address sets of the host names no longer used by any ACL are deleted.
+func OVNRefreshFQDNs(s *state.State, l logger.Logger, client *ovn.NB) error {
+	fqdns := []string{}
+
+	err := s.DB.Cluster.Transaction(context.TODO(), func(ctx context.Context, tx *db.ClusterTx) error {
+		projectACLNames, err := tx.GetNetworkACLsAllProjects(ctx)
+		if err != nil {
+			return err
+		}
+
+		for projectName, aclNames := range projectACLNames {
+			for _, aclName := range aclNames {
+				_, aclInfo, err := tx.GetNetworkACL(ctx, projectName, aclName)
+				if err != nil {
+					return fmt.Errorf("Failed loading network ACL %q in project %q: %w", aclName, projectName, err)
+				}
+
+				for _, fqdn := range ruleSubjectFQDNs(&aclInfo.NetworkACLPut) {
+					if !slices.Contains(fqdns, fqdn) {
+						fqdns = append(fqdns, fqdn)
+					}
+				}
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("Failed loading network ACLs: %w", err)
+	}
+
+	// Keep going on failure so that one broken host name doesn't hold back the others.
+	resolved := make(map[string][]net.IPNet, len(fqdns))
+	usedAddressSets := make([]ovn.OVNAddressSet, 0, len(fqdns))
+	for _, fqdn := range fqdns {
+		usedAddressSets = append(usedAddressSets, OVNACLFQDNAddressSetPrefix(fqdn))
+
+		addresses, err := resolveFQDN(fqdn)
+		if err != nil {
+			l.Warn("Failed resolving network ACL host name", logger.Ctx{"hostName": fqdn, "err": err})
+
+			// Keep the addresses from the previous refresh.
+			addresses, found := fqdnCache.get(fqdn)
+			if found {
+				resolved[fqdn] = addresses
+			}
+
+			continue
+		}
+
+		resolved[fqdn] = addresses
+
+		err = client.UpdateAddressSetReplace(context.TODO(), OVNACLFQDNAddressSetPrefix(fqdn), addresses...)
+		if err != nil && !errors.Is(err, ovn.ErrNotFound) {
+			l.Warn("Failed updating network ACL host name address set", logger.Ctx{"hostName": fqdn, "err": err})
+		}
+	}
+
+	fqdnCache.set(resolved)
+
+	// Delete the address sets of the host names that are no longer used.
+	addressSets, err := client.GetAddressSetsByPrefix(context.TODO(), ovnACLFQDNAddressSetPrefix)
+	if err != nil {
+		return fmt.Errorf("Failed getting network ACL host name address sets: %w", err)
+	}
+
+	for _, addressSet := range addressSets {
+		if slices.Contains(usedAddressSets, addressSet) {
+			continue
+		}
+
+		err = client.DeleteAddressSet(context.TODO(), addressSet)
+		if err != nil {
+			l.Warn("Failed deleting unused network ACL host name address set", logger.Ctx{"addressSet": addressSet, "err": err})
+		}
+	}
+
+	return nil
+}
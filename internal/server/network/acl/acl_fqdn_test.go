@@ -0,0 +1,69 @@
+package acl
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lxc/incus/v6/internal/server/network/ovn"
+	"github.com/lxc/incus/v6/internal/server/network/ovn/ovntest"
+	"github.com/lxc/incus/v6/shared/api"
+)
+
+func TestRuleSubjectIsFQDN(t *testing.T) {
+	for subject, expected := range map[string]bool{
+		"api.example.com":                 true,
+		"API.Example.COM":                 true,
+		"1.example.com":                   true,
+		"xn--bcher-kva.example":           true,
+		"web":                             false,
+		"web-servers":                     false,
+		"192.0.2.1":                       false,
+		"192.0.2.0/24":                    false,
+		"192.0.2.1-192.0.2.10":            false,
+		"10.0.0.300":                      false,
+		"2001:db8::1":                     false,
+		"@internal":                       false,
+		"@ovn1.peer":                      false,
+		"vlan:42":                         false,
+		"api..example.com":                false,
+		"-api.example.com":                false,
+		"api.example.com.":                false,
+		"api_v1.example.com":              false,
+		strings.Repeat("a.", 127) + "com": false,
+	} {
+		assert.Equal(t, expected, ruleSubjectIsFQDN(subject), subject)
+	}
+}
+
+func TestOVNEnsureFQDNAddressSets(t *testing.T) {
+	client, err := ovn.ConnectNB(ovntest.NewNBServer(t), "", "", "")
+	require.NoError(t, err)
+
+	cached := net.IPNet{IP: net.ParseIP("192.0.2.1").To4(), Mask: net.CIDRMask(32, 32)}
+
+	oldCache := fqdnCache
+	fqdnCache = &fqdnAddressCache{addresses: map[string][]net.IPNet{"api.example.com": {cached}}}
+	defer func() { fqdnCache = oldCache }()
+
+	require.NoError(t, client.CreateAddressSet(context.Background(), OVNACLFQDNAddressSetPrefix("api.example.com")))
+
+	// The sets of all the host names are created or updated without resolving them.
+	txn := client.NewTransaction()
+	require.NoError(t, ovnEnsureFQDNAddressSets(txn, &api.NetworkACL{NetworkACLPut: api.NetworkACLPut{
+		Egress: []api.NetworkACLRule{{Action: "allow", Destination: "API.Example.com,new.example.com,other.example.com", State: "enabled"}},
+	}}))
+	require.NoError(t, txn.Commit(context.Background()))
+
+	addressSets, err := client.GetAddressSetsByPrefix(context.Background(), ovnACLFQDNAddressSetPrefix)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []ovn.OVNAddressSet{
+		OVNACLFQDNAddressSetPrefix("api.example.com"),
+		OVNACLFQDNAddressSetPrefix("new.example.com"),
+		OVNACLFQDNAddressSetPrefix("other.example.com"),
+	}, addressSets)
+}
@@ -1,7 +1,11 @@
 package acl
 
 import (
+	"context"
+	"io"
+
 	"github.com/lxc/incus/v6/internal/server/cluster/request"
+	"github.com/lxc/incus/v6/internal/server/db"
 	"github.com/lxc/incus/v6/internal/server/state"
 	"github.com/lxc/incus/v6/shared/api"
 )
@@ -17,16 +21,27 @@ type NetworkACL interface {
 	Info() *api.NetworkACL
 	Etag() []any
 	UsedBy() ([]string, error)
+	AssignedToNetwork(networkName string) (bool, error)
+	State() (*api.NetworkACLState, error)
+	RuleStats() (map[string]api.NetworkACLRuleStats, error)
+	SimulatePacket(aclNet NetworkACLUsage, direction ruleDirection, pkt Packet) (int, string, error)
 
-	// GetLog.
+	// Log.
 	GetLog(clientType request.ClientType) (string, error)
+	FollowLog(clientType request.ClientType) (func(ctx context.Context, w io.Writer) error, error)
 
 	// Internal validation.
 	validateName(name string) error
-	validateConfig(config *api.NetworkACLPut) error
+	validateConfig(config *api.NetworkACLPut, mode ValidationMode) ([]string, error)
+	validateProjectLimits(ctx context.Context, tx *db.ClusterTx, config *api.NetworkACLPut) error
+
+	// Revisions.
+	Revisions() ([]api.NetworkACLRevision, error)
+	Revision(revision int64) (*api.NetworkACLRevision, error)
 
 	// Modifications.
-	Update(config *api.NetworkACLPut, clientType request.ClientType) error
-	Rename(newName string) error
+	Update(config *api.NetworkACLPut, clientType request.ClientType, requestor *api.EventLifecycleRequestor, mode ValidationMode) ([]string, error)
+	Rename(newName string, requestor *api.EventLifecycleRequestor) error
+	Refresh(applyOVN bool) error
 	Delete() error
 }
@@ -4,7 +4,11 @@ import (
 	"context"
 	"fmt"
 	"slices"
+	"strings"
+	"time"
 
+	"github.com/lxc/incus/v6/internal/revert"
+	"github.com/lxc/incus/v6/internal/server/cluster/request"
 	"github.com/lxc/incus/v6/internal/server/db"
 	"github.com/lxc/incus/v6/internal/server/db/cluster"
 	deviceConfig "github.com/lxc/incus/v6/internal/server/device/config"
@@ -12,6 +16,7 @@ import (
 	"github.com/lxc/incus/v6/internal/server/response"
 	"github.com/lxc/incus/v6/internal/server/state"
 	"github.com/lxc/incus/v6/shared/api"
+	"github.com/lxc/incus/v6/shared/logger"
 	"github.com/lxc/incus/v6/shared/util"
 )
 
@@ -37,32 +42,210 @@ func LoadByName(s *state.State, projectName string, name string) (NetworkACL, er
 	return acl, nil
 }
 
+// LoadByID loads and initializes a Network ACL from the database by ID.
+func LoadByID(s *state.State, id int64) (NetworkACL, error) {
+	var projectName string
+	var aclInfo *api.NetworkACL
+
+	err := s.DB.Cluster.Transaction(context.TODO(), func(ctx context.Context, tx *db.ClusterTx) error {
+		var err error
+		var name string
+
+		name, projectName, err = tx.GetNetworkACLNameAndProjectWithID(ctx, int(id))
+		if err != nil {
+			return err
+		}
+
+		_, aclInfo, err = tx.GetNetworkACL(ctx, projectName, name)
+
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var acl NetworkACL = &common{} // Only a single driver currently.
+	acl.init(s, id, projectName, aclInfo)
+
+	return acl, nil
+}
+
 // Create validates supplied record and creates new Network ACL record in the database.
-func Create(s *state.State, projectName string, aclInfo *api.NetworkACLsPost) error {
+// The deprecated constructs in the rules are converted and the warnings about them are returned.
+func Create(s *state.State, projectName string, aclInfo *api.NetworkACLsPost) ([]string, error) {
 	var acl NetworkACL = &common{} // Only a single driver currently.
-	acl.init(s, -1, projectName, nil)
+	acl.init(s, -1, projectName, &api.NetworkACL{NetworkACLPost: aclInfo.NetworkACLPost})
 
 	err := acl.validateName(aclInfo.Name)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	err = acl.validateConfig(&aclInfo.NetworkACLPut)
+	warnings, err := acl.validateConfig(&aclInfo.NetworkACLPut, ValidationModeWarn)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	err = s.DB.Cluster.Transaction(context.TODO(), func(ctx context.Context, tx *db.ClusterTx) error {
+		err := acl.validateProjectLimits(ctx, tx, &aclInfo.NetworkACLPut)
+		if err != nil {
+			return err
+		}
+
 		// Insert DB record.
-		_, err := tx.CreateNetworkACL(ctx, projectName, aclInfo)
+		_, err = tx.CreateNetworkACL(ctx, projectName, aclInfo)
 
 		return err
 	})
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	return nil
+	return warnings, nil
+}
+
+// CopyToProject copies the named ACLs from the source project into the target project.
+// ACLs are first created without rules so that rules referencing other ACLs within the copied set can be
+// validated afterwards (including circular references). ACLs whose rules reference ACLs outside of the copied set
+// or network peers (which cannot exist in the target project yet) are skipped with a warning.
+// The ACLs managed by the server aren't copied, as the target project gets its own (see CreateSystemACLs), but the
+// copied ACLs can still reference them.
+// The rules are added through Update, on behalf of the requestor.
+// Returns the names of the ACLs that were created.
+func CopyToProject(s *state.State, sourceProjectName string, targetProjectName string, aclNames []string, requestor *api.EventLifecycleRequestor) ([]string, error) {
+	aclInfos := make(map[string]*api.NetworkACL, len(aclNames))
+	systemACLNames := make(map[string]struct{})
+
+	err := s.DB.Cluster.Transaction(context.TODO(), func(ctx context.Context, tx *db.ClusterTx) error {
+		for _, aclName := range aclNames {
+			_, aclInfo, err := tx.GetNetworkACL(ctx, sourceProjectName, aclName)
+			if err != nil {
+				return fmt.Errorf("Failed loading network ACL %q from project %q: %w", aclName, sourceProjectName, err)
+			}
+
+			if aclInfo.Managed {
+				systemACLNames[aclName] = struct{}{}
+				continue
+			}
+
+			aclInfos[aclName] = aclInfo
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Repeatedly remove ACLs referencing subjects or parents that won't exist in the target project, as skipping
+	// one ACL may in turn invalidate other ACLs that reference it.
+	for {
+		skipped := false
+
+		for aclName, aclInfo := range aclInfos {
+			parentName := aclInfo.Config["parent"]
+			_, found := aclInfos[parentName]
+			_, isSystem := systemACLNames[parentName]
+			if parentName != "" && !found && !isSystem {
+				logger.Warn("Skipping copy of network ACL inheriting from missing parent", logger.Ctx{"networkACL": aclName, "parent": parentName, "project": targetProjectName})
+				delete(aclInfos, aclName)
+				skipped = true
+
+				continue
+			}
+
+			referencedACLs := make(map[string]struct{})
+			ovnAddReferencedACLs(aclInfo, referencedACLs)
+
+			for subject := range referencedACLs {
+				_, found := aclInfos[subject]
+				_, isSystem := systemACLNames[subject]
+				if found || isSystem || subject == aclName {
+					continue
+				}
+
+				logger.Warn("Skipping copy of network ACL referencing missing subject", logger.Ctx{"networkACL": aclName, "subject": subject, "project": targetProjectName})
+				delete(aclInfos, aclName)
+				skipped = true
+
+				break
+			}
+		}
+
+		if !skipped {
+			break
+		}
+	}
+
+	revert := revert.New()
+	defer revert.Fail()
+
+	// Create the ACLs without any rules first.
+	created := make([]string, 0, len(aclInfos))
+	for _, aclName := range aclNames {
+		aclInfo, found := aclInfos[aclName]
+		if !found {
+			continue
+		}
+
+		// The rules scriptlet is only added with the rules, as the rules it generates may reference other ACLs.
+		// The same goes for the parent, which may be created after this ACL.
+		config := make(map[string]string, len(aclInfo.Config))
+		for k, v := range aclInfo.Config {
+			if k != "rules.scriptlet" && k != "parent" {
+				config[k] = v
+			}
+		}
+
+		_, err := Create(s, targetProjectName, &api.NetworkACLsPost{
+			NetworkACLPost: api.NetworkACLPost{Name: aclName},
+			NetworkACLPut: api.NetworkACLPut{
+				Description: aclInfo.Description,
+				Config:      config,
+			},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("Failed creating network ACL %q: %w", aclName, err)
+		}
+
+		revert.Add(func() {
+			netACL, err := LoadByName(s, targetProjectName, aclName)
+			if err == nil {
+				_ = netACL.Delete()
+			}
+		})
+
+		created = append(created, aclName)
+	}
+
+	// Now that all of the copied ACLs exist, validate and add their rules.
+	for _, aclName := range created {
+		netACL, err := LoadByName(s, targetProjectName, aclName)
+		if err != nil {
+			return nil, err
+		}
+
+		aclInfo := aclInfos[aclName]
+
+		// Generated rules are generated again for the target project.
+		if aclInfo.Config["rules.scriptlet"] != "" {
+			aclInfo.Ingress = nil
+			aclInfo.Egress = nil
+		}
+
+		// The source ACLs may predate the current validation rules, so convert any deprecated constructs.
+		warnings, err := netACL.Update(&aclInfo.NetworkACLPut, request.ClientTypeNormal, requestor, ValidationModePermissive)
+		if err != nil {
+			return nil, fmt.Errorf("Failed adding rules to network ACL %q: %w", aclName, err)
+		}
+
+		for _, warning := range warnings {
+			logger.Warn("Converted deprecated network ACL rule during copy", logger.Ctx{"networkACL": aclName, "project": targetProjectName, "warning": warning})
+		}
+	}
+
+	revert.Success()
+	return created, nil
 }
 
 // Exists checks the ACL name(s) provided exists in the project.
@@ -99,9 +282,42 @@ func Exists(s *state.State, projectName string, name ...string) error {
 	return nil
 }
 
+// ListByUsage returns the ACLs of the project along with the number of resources using them, least used first.
+// ACLs with the same usage count are sorted by name.
+func ListByUsage(s *state.State, projectName string) ([]api.NetworkACLUsageSummary, error) {
+	usedBy, err := UsedByAll(s, projectName)
+	if err != nil {
+		return nil, err
+	}
+
+	summaries := make([]api.NetworkACLUsageSummary, 0, len(usedBy))
+	for aclName, aclUsedBy := range usedBy {
+		summaries = append(summaries, api.NetworkACLUsageSummary{
+			Name:       aclName,
+			Project:    projectName,
+			UsageCount: len(aclUsedBy),
+		})
+	}
+
+	slices.SortStableFunc(summaries, func(a api.NetworkACLUsageSummary, b api.NetworkACLUsageSummary) int {
+		if a.UsageCount != b.UsageCount {
+			return a.UsageCount - b.UsageCount
+		}
+
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	return summaries, nil
+}
+
 // UsedBy finds all networks, profiles and instance NICs that use any of the specified ACLs and executes usageFunc
 // once for each resource using one or more of the ACLs with info about the resource and matched ACLs being used.
-func UsedBy(s *state.State, aclProjectName string, usageFunc func(ctx context.Context, tx *db.ClusterTx, matchedACLNames []string, usageType any, nicName string, nicConfig map[string]string) error, matchACLNames ...string) error {
+// The usageName passed to usageFunc is the NIC device name for profiles and instances, and the first rule
+// referencing the matched ACLs (such as "ingress rule 3") for ACLs, or "parent" for ACLs only inheriting from
+// them. It is empty for networks.
+// The usageType passed to usageFunc always has its Project set to the project owning the resource, which is the
+// ACL's project for networks and ACLs, but can be another project using its networks for profiles and instances.
+func UsedBy(s *state.State, aclProjectName string, usageFunc func(ctx context.Context, tx *db.ClusterTx, matchedACLNames []string, usageType any, usageName string, nicConfig map[string]string) error, matchACLNames ...string) error {
 	if len(matchACLNames) <= 0 {
 		return nil
 	}
@@ -131,6 +347,10 @@ func UsedBy(s *state.State, aclProjectName string, usageFunc func(ctx context.Co
 			}
 
 			if len(matchedACLNames) > 0 {
+				// The network belongs to the ACL's project, which may be used by other projects through their
+				// features.networks setting.
+				network.Project = aclProjectName
+
 				// Call usageFunc with a list of matched ACLs and info about the network.
 				err := usageFunc(ctx, tx, matchedACLNames, network, "", nil)
 				if err != nil {
@@ -193,50 +413,56 @@ func UsedBy(s *state.State, aclProjectName string, usageFunc func(ctx context.Co
 		}
 	}
 
-	var aclNames []string
-
 	err = s.DB.Cluster.Transaction(context.TODO(), func(ctx context.Context, tx *db.ClusterTx) error {
 		// Find ACLs that have rules that reference the ACLs.
-		aclNames, err = tx.GetNetworkACLs(ctx, aclProjectName)
-
-		return err
-	})
-	if err != nil {
-		return err
-	}
-
-	err = s.DB.Cluster.Transaction(context.TODO(), func(ctx context.Context, tx *db.ClusterTx) error {
-		for _, aclName := range aclNames {
-			_, aclInfo, err := tx.GetNetworkACL(ctx, aclProjectName, aclName)
-			if err != nil {
-				return err
-			}
+		aclInfos, err := tx.GetNetworkACLsWithRules(ctx, aclProjectName)
+		if err != nil {
+			return err
+		}
 
+		for _, aclInfo := range aclInfos {
 			matchedACLNames := []string{}
+			firstRule := ""
 
-			// Ingress rules can specify ACL names in their Source subjects.
-			for _, rule := range aclInfo.Ingress {
-				for _, subject := range util.SplitNTrimSpace(rule.Source, ",", -1, true) {
+			// matchSubjects records the matching ACLs referenced by the subjects of a rule.
+			matchSubjects := func(direction ruleDirection, ruleIndex int, subjects string) {
+				for _, subject := range util.SplitNTrimSpace(subjects, ",", -1, true) {
 					// Look for new matching ACLs, but ignore our own ACL reference in our own rules.
 					if slices.Contains(matchACLNames, subject) && !slices.Contains(matchedACLNames, subject) && subject != aclInfo.Name {
 						matchedACLNames = append(matchedACLNames, subject)
+
+						if firstRule == "" {
+							firstRule = fmt.Sprintf("%s rule %d", direction, ruleIndex)
+						}
 					}
 				}
 			}
 
+			// Ingress rules can specify ACL names in their Source subjects.
+			for i, rule := range aclInfo.Ingress {
+				matchSubjects(ruleDirectionIngress, i, rule.Source)
+			}
+
 			// Egress rules can specify ACL names in their Destination subjects.
-			for _, rule := range aclInfo.Egress {
-				for _, subject := range util.SplitNTrimSpace(rule.Destination, ",", -1, true) {
-					// Look for new matching ACLs, but ignore our own ACL reference in our own rules.
-					if slices.Contains(matchACLNames, subject) && !slices.Contains(matchedACLNames, subject) && subject != aclInfo.Name {
-						matchedACLNames = append(matchedACLNames, subject)
-					}
+			for i, rule := range aclInfo.Egress {
+				matchSubjects(ruleDirectionEgress, i, rule.Destination)
+			}
+
+			// ACLs inherit the rules of their parent.
+			parentName := aclInfo.Config["parent"]
+			if slices.Contains(matchACLNames, parentName) && !slices.Contains(matchedACLNames, parentName) && parentName != aclInfo.Name {
+				matchedACLNames = append(matchedACLNames, parentName)
+
+				if firstRule == "" {
+					firstRule = "parent"
 				}
 			}
 
 			if len(matchedACLNames) > 0 {
+				aclInfo.Project = aclProjectName
+
 				// Call usageFunc with a list of matched ACLs and info about the ACL.
-				err = usageFunc(ctx, tx, matchedACLNames, aclInfo, "", nil)
+				err = usageFunc(ctx, tx, matchedACLNames, aclInfo, firstRule, nil)
 				if err != nil {
 					return err
 				}
@@ -282,6 +508,69 @@ func UsedBy(s *state.State, aclProjectName string, usageFunc func(ctx context.Co
 	return nil
 }
 
+// ProjectUsage returns the API endpoints referencing each of the ACLs of the project, keyed by ACL name.
+// ACLs that aren't used by anything have an empty list.
+func ProjectUsage(s *state.State, project string) (map[string][]string, error) {
+	return UsedByAll(s, project)
+}
+
+// UsedByAll returns the API endpoints referencing each of the ACLs of the project, keyed by ACL name.
+// This gives the same result as calling UsedBy on each ACL, but only walks the networks, profiles, ACLs and
+// instances once for all of them.
+func UsedByAll(s *state.State, aclProjectName string) (map[string][]string, error) {
+	var aclInfos []*api.NetworkACL
+
+	err := s.DB.Cluster.Transaction(context.TODO(), func(ctx context.Context, tx *db.ClusterTx) error {
+		var err error
+
+		aclInfos, err = tx.GetNetworkACLsWithRules(ctx, aclProjectName)
+
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Failed getting ACL references: %w", err)
+	}
+
+	graph := referenceGraph(aclInfos)
+
+	aclNames := make([]string, 0, len(aclInfos))
+	usedBy := make(map[string][]string, len(aclInfos))
+	for _, aclInfo := range aclInfos {
+		aclNames = append(aclNames, aclInfo.Name)
+		usedBy[aclInfo.Name] = []string{}
+	}
+
+	err = UsedBy(s, aclProjectName, func(ctx context.Context, tx *db.ClusterTx, matchedACLNames []string, usageType any, _ string, _ map[string]string) error {
+		uri, err := usageURI(usageType)
+		if err != nil {
+			return err
+		}
+
+		// A resource can list the same ACL more than once, but UsedBy only reports it once for each ACL.
+		seen := make(map[string]struct{}, len(matchedACLNames))
+		for _, aclName := range matchedACLNames {
+			_, found := seen[aclName]
+			if found {
+				continue
+			}
+
+			seen[aclName] = struct{}{}
+			usedBy[aclName] = append(usedBy[aclName], uri)
+		}
+
+		return nil
+	}, aclNames...)
+	if err != nil {
+		return nil, fmt.Errorf("Failed getting ACL usage: %w", err)
+	}
+
+	for _, aclName := range aclNames {
+		usedBy[aclName] = appendReferenceCycle(usedBy[aclName], aclProjectName, graph, aclName)
+	}
+
+	return usedBy, nil
+}
+
 // isInUseByDevice returns any of the supplied matching ACL names found referenced by the NIC device.
 func isInUseByDevice(d deviceConfig.Device, matchACLNames ...string) []string {
 	matchedACLNames := []string{}
@@ -310,57 +599,294 @@ type NetworkACLUsage struct {
 
 // NetworkUsage populates the provided aclNets map with networks that are using any of the specified ACLs.
 func NetworkUsage(s *state.State, aclProjectName string, aclNames []string, aclNets map[string]NetworkACLUsage) error {
-	supportedNetTypes := []string{"bridge", "ovn"}
-
 	// Find all networks and instance/profile NICs that use any of the specified Network ACLs.
+	err := UsedBy(s, aclProjectName, func(ctx context.Context, tx *db.ClusterTx, _ []string, usageType any, _ string, nicConfig map[string]string) error {
+		aclNet, err := usageNetwork(ctx, tx, aclProjectName, usageType, nicConfig, aclNets)
+		if err != nil {
+			return err
+		}
+
+		if aclNet != nil {
+			aclNets[aclNet.Name] = *aclNet
+		}
+
+		return nil
+	}, aclNames...)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// networkUsageByACL returns the networks using each of the specified ACLs keyed by ACL name, finding them with a
+// single scan as NetworkUsage does. ACLs not used by any network are left out.
+func networkUsageByACL(s *state.State, aclProjectName string, aclNames []string) (map[string]map[string]NetworkACLUsage, error) {
+	aclNets := map[string]NetworkACLUsage{}
+	aclNetsByACL := map[string]map[string]NetworkACLUsage{}
+
 	err := UsedBy(s, aclProjectName, func(ctx context.Context, tx *db.ClusterTx, matchedACLNames []string, usageType any, _ string, nicConfig map[string]string) error {
-		switch u := usageType.(type) {
-		case db.InstanceArgs, cluster.Profile:
-			networkID, network, _, err := tx.GetNetworkInAnyState(ctx, aclProjectName, nicConfig["network"])
-			if err != nil {
-				return fmt.Errorf("Failed to load network %q: %w", nicConfig["network"], err)
-			}
+		aclNet, err := usageNetwork(ctx, tx, aclProjectName, usageType, nicConfig, aclNets)
+		if err != nil {
+			return err
+		}
 
-			if slices.Contains(supportedNetTypes, network.Type) {
-				_, found := aclNets[network.Name]
-				if !found {
-					aclNets[network.Name] = NetworkACLUsage{
-						ID:     networkID,
-						Name:   network.Name,
-						Type:   network.Type,
-						Config: network.Config,
-					}
-				}
-			}
+		if aclNet == nil {
+			return nil
+		}
 
-		case *api.Network:
-			if slices.Contains(supportedNetTypes, u.Type) {
-				_, found := aclNets[u.Name]
-				if !found {
-					networkID, network, _, err := tx.GetNetworkInAnyState(ctx, aclProjectName, u.Name)
-					if err != nil {
-						return fmt.Errorf("Failed to load network %q: %w", u.Name, err)
-					}
+		aclNets[aclNet.Name] = *aclNet
 
-					aclNets[u.Name] = NetworkACLUsage{
-						ID:     networkID,
-						Name:   network.Name,
-						Type:   network.Type,
-						Config: network.Config,
-					}
-				}
+		for _, aclName := range matchedACLNames {
+			if aclNetsByACL[aclName] == nil {
+				aclNetsByACL[aclName] = map[string]NetworkACLUsage{}
 			}
 
-		case *api.NetworkACL:
-			return nil // Nothing to do for ACL rules referencing us.
-		default:
-			return fmt.Errorf("Unrecognised usage type %T", u)
+			aclNetsByACL[aclName][aclNet.Name] = *aclNet
 		}
 
 		return nil
 	}, aclNames...)
 	if err != nil {
+		return nil, err
+	}
+
+	return aclNetsByACL, nil
+}
+
+// usageNetwork returns the network of a usage reported by UsedBy, or nil if it isn't a network (or NIC) of a type
+// supporting ACLs. Networks already in knownNets aren't loaded again.
+func usageNetwork(ctx context.Context, tx *db.ClusterTx, aclProjectName string, usageType any, nicConfig map[string]string, knownNets map[string]NetworkACLUsage) (*NetworkACLUsage, error) {
+	supportedNetTypes := []string{"bridge", "ovn"}
+
+	var netName string
+
+	switch u := usageType.(type) {
+	case db.InstanceArgs, cluster.Profile:
+		netName = nicConfig["network"]
+	case *api.Network:
+		if !slices.Contains(supportedNetTypes, u.Type) {
+			return nil, nil
+		}
+
+		netName = u.Name
+	case *api.NetworkACL:
+		return nil, nil // Nothing to do for ACL rules referencing us.
+	default:
+		return nil, fmt.Errorf("Unrecognised usage type %T", u)
+	}
+
+	aclNet, found := knownNets[netName]
+	if found {
+		return &aclNet, nil
+	}
+
+	networkID, network, _, err := tx.GetNetworkInAnyState(ctx, aclProjectName, netName)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to load network %q: %w", netName, err)
+	}
+
+	if !slices.Contains(supportedNetTypes, network.Type) {
+		return nil, nil
+	}
+
+	return &NetworkACLUsage{
+		ID:     networkID,
+		Name:   network.Name,
+		Type:   network.Type,
+		Config: network.Config,
+	}, nil
+}
+
+// AffectedOVNNetworks returns the OVN networks using the specified ACL, either directly or through the NICs of
+// instances and profiles. Networks of other types using the ACL are left out.
+func AffectedOVNNetworks(s *state.State, aclProjectName string, aclName string) (map[string]NetworkACLUsage, error) {
+	aclNets := map[string]NetworkACLUsage{}
+	err := NetworkUsage(s, aclProjectName, []string{aclName}, aclNets)
+	if err != nil {
+		return nil, err
+	}
+
+	return ovnUsageNetworks(aclNets), nil
+}
+
+// ovnUsageNetworks returns the OVN networks of the specified networks.
+func ovnUsageNetworks(aclNets map[string]NetworkACLUsage) map[string]NetworkACLUsage {
+	aclOVNNets := map[string]NetworkACLUsage{}
+	for k, v := range aclNets {
+		if v.Type == "ovn" {
+			aclOVNNets[k] = v
+		}
+	}
+
+	return aclOVNNets
+}
+
+// parentChain returns the parents of the named ACL, starting with parentName and following their "parent" config
+// keys. It fails if one of them doesn't exist or if the chain leads back to an ACL already in it, in which case the
+// parents found before the problem are still returned.
+func parentChain(ctx context.Context, tx *db.ClusterTx, projectName string, aclName string, parentName string) ([]*api.NetworkACL, error) {
+	names := []string{aclName}
+	parents := []*api.NetworkACL{}
+
+	for parentName != "" {
+		if slices.Contains(names, parentName) {
+			return parents, fmt.Errorf("Network ACL parents form a cycle: %s", strings.Join(append(names, parentName), " -> "))
+		}
+
+		_, parentInfo, err := tx.GetNetworkACL(ctx, projectName, parentName)
+		if err != nil {
+			if response.IsNotFoundError(err) {
+				return parents, fmt.Errorf("Parent network ACL %q not found", parentName)
+			}
+
+			return parents, fmt.Errorf("Failed loading parent network ACL %q: %w", parentName, err)
+		}
+
+		names = append(names, parentName)
+		parents = append(parents, parentInfo)
+		parentName = parentInfo.Config["parent"]
+	}
+
+	return parents, nil
+}
+
+// composedACL is an ACL whose rules start with the rules it inherits from its parents (see withParentRules).
+type composedACL struct {
+	*api.NetworkACL
+
+	// Number of rules at the start of Ingress and Egress inherited from the parents.
+	inheritedIngress int
+	inheritedEgress  int
+}
+
+// inheritedRules returns the number of rules at the start of the direction's rules inherited from the parents.
+func (c *composedACL) inheritedRules(direction ruleDirection) int {
+	if direction == ruleDirectionEgress {
+		return c.inheritedEgress
+	}
+
+	return c.inheritedIngress
+}
+
+// withParentRules returns the ACL with the rules of its parents prepended to its own, starting with the rules of
+// the furthest parent. Inherited rules identical to one of the ACL's own rules, or to a rule inherited already, are
+// only kept once. The ACL's own rules are left as is, and a copy of the ACL is made if it has a parent.
+func withParentRules(ctx context.Context, tx *db.ClusterTx, projectName string, aclInfo *api.NetworkACL) (*composedACL, error) {
+	if aclInfo.Config["parent"] == "" {
+		return &composedACL{NetworkACL: aclInfo}, nil
+	}
+
+	parents, err := parentChain(ctx, tx, projectName, aclInfo.Name, aclInfo.Config["parent"])
+	if err != nil {
+		return nil, err
+	}
+
+	inheritRules := func(own []api.NetworkACLRule, direction ruleDirection) []api.NetworkACLRule {
+		rules := []api.NetworkACLRule{}
+		for i := len(parents) - 1; i >= 0; i-- {
+			parentRules := parents[i].Ingress
+			if direction == ruleDirectionEgress {
+				parentRules = parents[i].Egress
+			}
+
+			for _, rule := range parentRules {
+				if !slices.Contains(rules, rule) && !slices.Contains(own, rule) {
+					rules = append(rules, rule)
+				}
+			}
+		}
+
+		return rules
+	}
+
+	inheritedIngress := inheritRules(aclInfo.Ingress, ruleDirectionIngress)
+	inheritedEgress := inheritRules(aclInfo.Egress, ruleDirectionEgress)
+
+	composed := *aclInfo
+	composed.Ingress = append(inheritedIngress, aclInfo.Ingress...)
+	composed.Egress = append(inheritedEgress, aclInfo.Egress...)
+
+	return &composedACL{NetworkACL: &composed, inheritedIngress: len(inheritedIngress), inheritedEgress: len(inheritedEgress)}, nil
+}
+
+// childACLs returns the names of the ACLs of the project inheriting the rules of the named ACL, directly or through
+// other ACLs, sorted by name.
+func childACLs(ctx context.Context, tx *db.ClusterTx, projectName string, aclName string) ([]string, error) {
+	aclNames, err := tx.GetNetworkACLs(ctx, projectName)
+	if err != nil {
+		return nil, err
+	}
+
+	children := []string{}
+	for _, name := range aclNames {
+		if name == aclName {
+			continue
+		}
+
+		_, aclInfo, err := tx.GetNetworkACL(ctx, projectName, name)
+		if err != nil {
+			return nil, fmt.Errorf("Failed loading network ACL %q: %w", name, err)
+		}
+
+		// Broken chains are reported when validating and applying the ACLs, so only follow the valid part.
+		parents, _ := parentChain(ctx, tx, projectName, name, aclInfo.Config["parent"])
+		for _, parent := range parents {
+			if parent.Name == aclName {
+				children = append(children, name)
+				break
+			}
+		}
+	}
+
+	slices.Sort(children)
+
+	return children, nil
+}
+
+// RefreshScheduled reapplies the ACLs that have rules whose validity window opened or closed after since and up
+// to now. OVN networks are only updated if applyOVN is true.
+func RefreshScheduled(s *state.State, since time.Time, now time.Time, applyOVN bool) error {
+	var projectACLNames map[string][]string
+
+	err := s.DB.Cluster.Transaction(context.TODO(), func(ctx context.Context, tx *db.ClusterTx) error {
+		var err error
+
+		projectACLNames, err = tx.GetNetworkACLsAllProjects(ctx)
+
 		return err
+	})
+	if err != nil {
+		return fmt.Errorf("Failed loading network ACLs: %w", err)
+	}
+
+	for projectName, aclNames := range projectACLNames {
+		for _, aclName := range aclNames {
+			netACL, err := LoadByName(s, projectName, aclName)
+			if err != nil {
+				return fmt.Errorf("Failed loading network ACL %q in project %q: %w", aclName, projectName, err)
+			}
+
+			info := netACL.Info()
+
+			changed := false
+			for _, rule := range append(info.Ingress, info.Egress...) {
+				if ruleScheduleChanged(rule, since, now) {
+					changed = true
+					break
+				}
+			}
+
+			if !changed {
+				continue
+			}
+
+			// Keep going on failure so that one broken ACL doesn't hold back the others.
+			err = netACL.Refresh(applyOVN)
+			if err != nil {
+				logger.Warn("Failed refreshing scheduled network ACL rules", logger.Ctx{"project": projectName, "networkACL": aclName, "err": err})
+			}
+		}
 	}
 
 	return nil
@@ -0,0 +1,495 @@
+package acl
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lxc/incus/v6/internal/server/db"
+	dbCluster "github.com/lxc/incus/v6/internal/server/db/cluster"
+	"github.com/lxc/incus/v6/internal/server/state"
+	"github.com/lxc/incus/v6/shared/api"
+)
+
+func TestParentRules(t *testing.T) {
+	s, cleanup := state.NewTestState(t)
+	defer cleanup()
+
+	sshRule := api.NetworkACLRule{Action: "allow", State: "enabled", Protocol: "tcp", DestinationPort: "22"}
+	httpRule := api.NetworkACLRule{Action: "allow", State: "enabled", Protocol: "tcp", DestinationPort: "80"}
+	dropRule := api.NetworkACLRule{Action: "drop", State: "enabled", Destination: "192.0.2.0/24"}
+
+	err := s.DB.Cluster.Transaction(context.Background(), func(ctx context.Context, tx *db.ClusterTx) error {
+		acls := []api.NetworkACLsPost{
+			{NetworkACLPost: api.NetworkACLPost{Name: "base"}, NetworkACLPut: api.NetworkACLPut{Ingress: []api.NetworkACLRule{sshRule}}},
+			{NetworkACLPost: api.NetworkACLPost{Name: "web"}, NetworkACLPut: api.NetworkACLPut{Config: map[string]string{"parent": "base"}, Ingress: []api.NetworkACLRule{httpRule, sshRule}}},
+			{NetworkACLPost: api.NetworkACLPost{Name: "app"}, NetworkACLPut: api.NetworkACLPut{Config: map[string]string{"parent": "web"}, Egress: []api.NetworkACLRule{dropRule}}},
+		}
+
+		for _, acl := range acls {
+			_, err := tx.CreateNetworkACL(ctx, api.ProjectDefaultName, &acl)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	require.NoError(t, err)
+
+	// The rules of the furthest parent come first, and duplicated rules are only kept once.
+	var composed *composedACL
+	var children []string
+	err = s.DB.Cluster.Transaction(context.Background(), func(ctx context.Context, tx *db.ClusterTx) error {
+		_, aclInfo, err := tx.GetNetworkACL(ctx, api.ProjectDefaultName, "app")
+		if err != nil {
+			return err
+		}
+
+		composed, err = withParentRules(ctx, tx, api.ProjectDefaultName, aclInfo)
+		if err != nil {
+			return err
+		}
+
+		children, err = childACLs(ctx, tx, api.ProjectDefaultName, "base")
+
+		return err
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []api.NetworkACLRule{sshRule, httpRule}, composed.Ingress)
+	assert.Equal(t, []api.NetworkACLRule{dropRule}, composed.Egress)
+	assert.Equal(t, 2, composed.inheritedIngress)
+	assert.Equal(t, 0, composed.inheritedEgress)
+	assert.Equal(t, []string{"app", "web"}, children)
+
+	// The inherited rules are emitted in the priority band after the one of the ACL's own rules.
+	composedRules, _, err := ovnPortGroupRules(composed, "incus_acl3", nil, nil, nil)
+	require.NoError(t, err)
+	require.Len(t, composedRules, 4)
+
+	bandPriority := func(band int) int {
+		return (ovnACLPriorityBandMax - band) * ovnACLPriorityBandSize
+	}
+
+	assert.Equal(t, ovnACLPriorityPortGroupAllow+bandPriority(ovnACLPriorityBandDefault+1), composedRules[0].Priority)
+	assert.Equal(t, ovnACLPriorityPortGroupAllow+bandPriority(ovnACLPriorityBandDefault+1), composedRules[1].Priority)
+	assert.Equal(t, ovnACLPriorityPortGroupDrop+bandPriority(ovnACLPriorityBandDefault), composedRules[2].Priority)
+
+	// The parent is reported as used by the ACLs inheriting from it.
+	usages := map[string]string{}
+	err = UsedBy(s, api.ProjectDefaultName, func(ctx context.Context, tx *db.ClusterTx, _ []string, usageType any, usageName string, _ map[string]string) error {
+		usages[usageType.(*api.NetworkACL).Name] = usageName
+		return nil
+	}, "base")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"web": "parent"}, usages)
+
+	// Parents must exist and can't lead back to the ACL.
+	tests := []struct {
+		name   string
+		parent string
+		err    string
+	}{
+		{"base", "web", "Network ACL parents form a cycle: base -> web -> base"},
+		{"base", "app", "Network ACL parents form a cycle: base -> app -> web -> base"},
+		{"web", "web", "Network ACL parents form a cycle: web -> web"},
+		{"web", "missing", `Parent network ACL "missing" not found`},
+		{"web", "@internal", `Invalid value for config option "parent": Name cannot start with reserved character '@'`},
+		{"app", "base", ""},
+		{"other", "app", ""},
+	}
+
+	for _, test := range tests {
+		d := &common{}
+		d.init(s, -1, api.ProjectDefaultName, &api.NetworkACL{NetworkACLPost: api.NetworkACLPost{Name: test.name}})
+
+		err := validateStrict(d, &api.NetworkACLPut{Config: map[string]string{"parent": test.parent}})
+		if test.err == "" {
+			assert.NoError(t, err, "%s: %s", test.name, test.parent)
+		} else {
+			assert.EqualError(t, err, test.err, "%s: %s", test.name, test.parent)
+		}
+	}
+}
+
+func TestParentRulesOverride(t *testing.T) {
+	s, cleanup := state.NewTestState(t)
+	defer cleanup()
+
+	dropRule := api.NetworkACLRule{Action: "drop", State: "logged", Protocol: "tcp", DestinationPort: "22"}
+	allowRule := api.NetworkACLRule{Action: "allow", State: "logged", Source: "192.0.2.10", Protocol: "tcp", DestinationPort: "22"}
+
+	var composed *composedACL
+	err := s.DB.Cluster.Transaction(context.Background(), func(ctx context.Context, tx *db.ClusterTx) error {
+		acls := []api.NetworkACLsPost{
+			{NetworkACLPost: api.NetworkACLPost{Name: "locked"}, NetworkACLPut: api.NetworkACLPut{Ingress: []api.NetworkACLRule{dropRule}}},
+			{NetworkACLPost: api.NetworkACLPost{Name: "admin"}, NetworkACLPut: api.NetworkACLPut{Config: map[string]string{"parent": "locked"}, Ingress: []api.NetworkACLRule{allowRule}}},
+		}
+
+		for _, acl := range acls {
+			_, err := tx.CreateNetworkACL(ctx, api.ProjectDefaultName, &acl)
+			if err != nil {
+				return err
+			}
+		}
+
+		_, aclInfo, err := tx.GetNetworkACL(ctx, api.ProjectDefaultName, "admin")
+		if err != nil {
+			return err
+		}
+
+		composed, err = withParentRules(ctx, tx, api.ProjectDefaultName, aclInfo)
+
+		return err
+	})
+	require.NoError(t, err)
+
+	rules, _, err := ovnPortGroupRules(composed, "incus_acl2", nil, nil, nil)
+	require.NoError(t, err)
+	require.Len(t, rules, 3)
+
+	// The allow rule of the child overrides the drop rule of its parent, even though drop rules take precedence
+	// over allow rules within a priority band.
+	inheritedDrop := rules[0]
+	ownAllow := rules[1]
+	assert.Equal(t, "drop", inheritedDrop.Action)
+	assert.Equal(t, "allow-related", ownAllow.Action)
+	assert.Greater(t, ownAllow.Priority, inheritedDrop.Priority)
+
+	// The inherited rules are logged separately, so the child's own rules keep their index.
+	assert.Equal(t, "incus_acl2-ingress-i0", inheritedDrop.LogName)
+	assert.Equal(t, "incus_acl2-ingress-0", ownAllow.LogName)
+}
+
+func TestLoadByID(t *testing.T) {
+	s, cleanup := state.NewTestState(t)
+	defer cleanup()
+
+	err := s.DB.Cluster.Transaction(context.Background(), func(ctx context.Context, tx *db.ClusterTx) error {
+		id, err := dbCluster.CreateProject(ctx, tx.Tx(), dbCluster.Project{Name: "own"})
+		if err != nil {
+			return err
+		}
+
+		err = dbCluster.CreateProjectConfig(ctx, tx.Tx(), id, map[string]string{"features.networks": "true"})
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.CreateNetworkACL(ctx, "own", &api.NetworkACLsPost{
+			NetworkACLPost: api.NetworkACLPost{Name: "web"},
+			NetworkACLPut:  api.NetworkACLPut{Description: "Web servers"},
+		})
+
+		return err
+	})
+	require.NoError(t, err)
+
+	byName, err := LoadByName(s, "own", "web")
+	require.NoError(t, err)
+
+	// The ACL is loaded along with its project.
+	byID, err := LoadByID(s, byName.ID())
+	require.NoError(t, err)
+	assert.Equal(t, byName.ID(), byID.ID())
+	assert.Equal(t, "own", byID.Project())
+	assert.Equal(t, byName.Info(), byID.Info())
+
+	// Unknown IDs aren't found.
+	_, err = LoadByID(s, byName.ID()+1)
+	assert.True(t, api.StatusErrorCheck(err, http.StatusNotFound))
+}
+
+func TestListByUsage(t *testing.T) {
+	s, cleanup := state.NewTestState(t)
+	defer cleanup()
+
+	err := s.DB.Cluster.Transaction(context.Background(), func(ctx context.Context, tx *db.ClusterTx) error {
+		for _, name := range []string{"twice", "once", "unused"} {
+			_, err := tx.CreateNetworkACL(ctx, api.ProjectDefaultName, &api.NetworkACLsPost{NetworkACLPost: api.NetworkACLPost{Name: name}})
+			if err != nil {
+				return err
+			}
+		}
+
+		_, err := tx.CreateNetwork(ctx, api.ProjectDefaultName, "net1", "", db.NetworkTypeBridge, map[string]string{"security.acls": "twice"})
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.CreateNetwork(ctx, api.ProjectDefaultName, "net2", "", db.NetworkTypeBridge, map[string]string{"security.acls": "once,twice"})
+
+		return err
+	})
+	require.NoError(t, err)
+
+	summaries, err := ListByUsage(s, api.ProjectDefaultName)
+	require.NoError(t, err)
+
+	// Unused ACLs come first.
+	assert.Equal(t, []api.NetworkACLUsageSummary{
+		{Name: "unused", Project: api.ProjectDefaultName, UsageCount: 0},
+		{Name: "once", Project: api.ProjectDefaultName, UsageCount: 1},
+		{Name: "twice", Project: api.ProjectDefaultName, UsageCount: 2},
+	}, summaries)
+}
+
+func TestProjectUsage(t *testing.T) {
+	s, cleanup := state.NewTestState(t)
+	defer cleanup()
+
+	err := s.DB.Cluster.Transaction(context.Background(), func(ctx context.Context, tx *db.ClusterTx) error {
+		acls := map[string]api.NetworkACLPut{
+			"db":     {},
+			"unused": {},
+			"web": {Ingress: []api.NetworkACLRule{
+				{Action: "allow", State: "enabled", Source: "app"},
+				{Action: "allow", State: "enabled", Source: "app,db", Protocol: "tcp"},
+			}},
+			"app": {Egress: []api.NetworkACLRule{
+				{Action: "allow", State: "enabled", Destination: "db"},
+				{Action: "allow", State: "enabled", Destination: "web"},
+			}},
+			"child": {Config: map[string]string{"parent": "db"}},
+		}
+
+		for name, put := range acls {
+			_, err := tx.CreateNetworkACL(ctx, api.ProjectDefaultName, &api.NetworkACLsPost{NetworkACLPost: api.NetworkACLPost{Name: name}, NetworkACLPut: put})
+			if err != nil {
+				return err
+			}
+		}
+
+		// ACLs listed more than once by a resource are only reported once.
+		_, err := tx.CreateNetwork(ctx, api.ProjectDefaultName, "net1", "", db.NetworkTypeBridge, map[string]string{"security.acls": "web,db,web"})
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.CreateNetwork(ctx, api.ProjectDefaultName, "net2", "", db.NetworkTypeBridge, map[string]string{"security.acls": "db"})
+		if err != nil {
+			return err
+		}
+
+		id, err := dbCluster.CreateProfile(ctx, tx.Tx(), dbCluster.Profile{Project: api.ProjectDefaultName, Name: "p1"})
+		if err != nil {
+			return err
+		}
+
+		return dbCluster.CreateProfileDevices(ctx, tx.Tx(), id, map[string]dbCluster.Device{
+			"eth0": {Name: "eth0", Type: dbCluster.TypeNIC, Config: map[string]string{"network": "net1", "security.acls": "app,db"}},
+			"eth1": {Name: "eth1", Type: dbCluster.TypeNIC, Config: map[string]string{"network": "net2", "security.acls": "db,db"}},
+		})
+	})
+	require.NoError(t, err)
+
+	usedBy, err := ProjectUsage(s, api.ProjectDefaultName)
+	require.NoError(t, err)
+	assert.Len(t, usedBy, 5)
+
+	// The usage of each ACL matches the one computed for the ACL alone.
+	for _, aclName := range []string{"db", "unused", "web", "app", "child"} {
+		netACL, err := LoadByName(s, api.ProjectDefaultName, aclName)
+		require.NoError(t, err)
+
+		expected, err := netACL.UsedBy()
+		require.NoError(t, err)
+
+		assert.Equal(t, expected, usedBy[aclName], aclName)
+	}
+
+	assert.Equal(t, []string{}, usedBy["unused"])
+	assert.ElementsMatch(t, []string{"/1.0/networks/net1", "/1.0/network-acls/app"}, usedBy["web"])
+	assert.Contains(t, usedBy["db"], "/1.0/network-acls/child")
+}
+
+func BenchmarkProjectUsage(b *testing.B) {
+	s, cleanup := state.NewTestState(b)
+	defer cleanup()
+
+	const count = 20
+
+	aclNames := make([]string, 0, count)
+	err := s.DB.Cluster.Transaction(context.Background(), func(ctx context.Context, tx *db.ClusterTx) error {
+		for i := 0; i < count; i++ {
+			aclName := fmt.Sprintf("acl%d", i)
+			_, err := tx.CreateNetworkACL(ctx, api.ProjectDefaultName, &api.NetworkACLsPost{NetworkACLPost: api.NetworkACLPost{Name: aclName}})
+			if err != nil {
+				return err
+			}
+
+			aclNames = append(aclNames, aclName)
+		}
+
+		// Each network uses two of the ACLs.
+		for i := 0; i < count; i++ {
+			config := map[string]string{"security.acls": aclNames[i] + "," + aclNames[(i+1)%count]}
+			_, err := tx.CreateNetwork(ctx, api.ProjectDefaultName, fmt.Sprintf("net%d", i), "", db.NetworkTypeBridge, config)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	require.NoError(b, err)
+
+	b.Run("ProjectUsage", func(b *testing.B) {
+		for n := 0; n < b.N; n++ {
+			_, err := ProjectUsage(s, api.ProjectDefaultName)
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("UsedBy", func(b *testing.B) {
+		// Only the usage lookups are measured, not the loading of the ACLs.
+		netACLs := make([]NetworkACL, 0, len(aclNames))
+		for _, aclName := range aclNames {
+			netACL, err := LoadByName(s, api.ProjectDefaultName, aclName)
+			if err != nil {
+				b.Fatal(err)
+			}
+
+			netACLs = append(netACLs, netACL)
+		}
+
+		b.ResetTimer()
+
+		for n := 0; n < b.N; n++ {
+			for _, netACL := range netACLs {
+				_, err := netACL.UsedBy()
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	})
+}
+
+func TestUsedByProjects(t *testing.T) {
+	s, cleanup := state.NewTestState(t)
+	defer cleanup()
+
+	err := s.DB.Cluster.Transaction(context.Background(), func(ctx context.Context, tx *db.ClusterTx) error {
+		// Project "shared" uses the networks and ACLs of the default project, "own" has its own.
+		_, err := dbCluster.CreateProject(ctx, tx.Tx(), dbCluster.Project{Name: "shared"})
+		if err != nil {
+			return err
+		}
+
+		id, err := dbCluster.CreateProject(ctx, tx.Tx(), dbCluster.Project{Name: "own"})
+		if err != nil {
+			return err
+		}
+
+		err = dbCluster.CreateProjectConfig(ctx, tx.Tx(), id, map[string]string{"features.networks": "true"})
+		if err != nil {
+			return err
+		}
+
+		for _, projectName := range []string{api.ProjectDefaultName, "own"} {
+			_, err = tx.CreateNetworkACL(ctx, projectName, &api.NetworkACLsPost{NetworkACLPost: api.NetworkACLPost{Name: "web"}})
+			if err != nil {
+				return err
+			}
+
+			_, err = tx.CreateNetworkACL(ctx, projectName, &api.NetworkACLsPost{
+				NetworkACLPost: api.NetworkACLPost{Name: "app"},
+				NetworkACLPut:  api.NetworkACLPut{Ingress: []api.NetworkACLRule{{Action: "allow", State: "enabled", Source: "web"}}},
+			})
+			if err != nil {
+				return err
+			}
+
+			_, err = tx.CreateNetwork(ctx, projectName, "net1", "", db.NetworkTypeOVN, map[string]string{"security.acls": "web"})
+			if err != nil {
+				return err
+			}
+		}
+
+		for _, projectName := range []string{"shared", "own"} {
+			id, err := dbCluster.CreateProfile(ctx, tx.Tx(), dbCluster.Profile{Project: projectName, Name: "p1"})
+			if err != nil {
+				return err
+			}
+
+			err = dbCluster.CreateProfileDevices(ctx, tx.Tx(), id, map[string]dbCluster.Device{
+				"eth0": {Name: "eth0", Type: dbCluster.TypeNIC, Config: map[string]string{"network": "net1", "security.acls": "web"}},
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	require.NoError(t, err)
+
+	// Each resource is listed with the project owning it. The profile of the project without its own networks
+	// uses the ACL of the default project.
+	netACL, err := LoadByName(s, api.ProjectDefaultName, "web")
+	require.NoError(t, err)
+
+	usedBy, err := netACL.UsedBy()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"/1.0/networks/net1", "/1.0/profiles/p1?project=shared", "/1.0/network-acls/app"}, usedBy)
+
+	netACL, err = LoadByName(s, "own", "web")
+	require.NoError(t, err)
+
+	usedBy, err = netACL.UsedBy()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"/1.0/networks/net1?project=own", "/1.0/profiles/p1?project=own", "/1.0/network-acls/app?project=own"}, usedBy)
+
+	// The bulk usage reports the same URLs.
+	all, err := UsedByAll(s, api.ProjectDefaultName)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"/1.0/networks/net1", "/1.0/profiles/p1?project=shared", "/1.0/network-acls/app"}, all["web"])
+}
+
+func TestAffectedOVNNetworks(t *testing.T) {
+	s, cleanup := state.NewTestState(t)
+	defer cleanup()
+
+	var ovnNetID int64
+	err := s.DB.Cluster.Transaction(context.Background(), func(ctx context.Context, tx *db.ClusterTx) error {
+		for _, name := range []string{"web", "unused"} {
+			_, err := tx.CreateNetworkACL(ctx, api.ProjectDefaultName, &api.NetworkACLsPost{NetworkACLPost: api.NetworkACLPost{Name: name}})
+			if err != nil {
+				return err
+			}
+		}
+
+		_, err := tx.CreateNetwork(ctx, api.ProjectDefaultName, "br1", "", db.NetworkTypeBridge, map[string]string{"security.acls": "web"})
+		if err != nil {
+			return err
+		}
+
+		ovnNetID, err = tx.CreateNetwork(ctx, api.ProjectDefaultName, "ovn1", "", db.NetworkTypeOVN, map[string]string{"security.acls": "web"})
+
+		return err
+	})
+	require.NoError(t, err)
+
+	// Only the OVN network is kept, while both networks use the ACL.
+	aclNets := map[string]NetworkACLUsage{}
+	require.NoError(t, NetworkUsage(s, api.ProjectDefaultName, []string{"web"}, aclNets))
+	assert.Len(t, aclNets, 2)
+
+	aclOVNNets, err := AffectedOVNNetworks(s, api.ProjectDefaultName, "web")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]NetworkACLUsage{
+		"ovn1": {ID: ovnNetID, Name: "ovn1", Type: "ovn", Config: map[string]string{"security.acls": "web"}},
+	}, aclOVNNets)
+
+	aclOVNNets, err = AffectedOVNNetworks(s, api.ProjectDefaultName, "unused")
+	require.NoError(t, err)
+	assert.Empty(t, aclOVNNets)
+}
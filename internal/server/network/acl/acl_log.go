@@ -0,0 +1,188 @@
+package acl
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lxc/incus/v6/internal/server/state"
+)
+
+// ovnControllerLogPath is the path to the OVN controller log containing the ACL log entries.
+const ovnControllerLogPath = "/var/log/ovn/ovn-controller.log"
+
+// logFollowInterval is how often a followed log file is checked for new entries.
+var logFollowInterval = time.Second
+
+// logNetwork is a network using an ACL along with its subnets.
+type logNetwork struct {
+	name    string
+	subnets []*net.IPNet
+}
+
+// logNetworks returns the networks using the specified ACL along with their subnets.
+// This is used to find out which network a log entry belongs to as OVN doesn't include it.
+func logNetworks(s *state.State, aclProjectName string, aclName string) ([]logNetwork, error) {
+	aclNets := map[string]NetworkACLUsage{}
+	err := NetworkUsage(s, aclProjectName, []string{aclName}, aclNets)
+	if err != nil {
+		return nil, err
+	}
+
+	netNames := make([]string, 0, len(aclNets))
+	for netName := range aclNets {
+		netNames = append(netNames, netName)
+	}
+
+	sort.Strings(netNames)
+
+	networks := make([]logNetwork, 0, len(netNames))
+	for _, netName := range netNames {
+		network := logNetwork{name: netName}
+
+		for _, key := range []string{"ipv4.address", "ipv6.address"} {
+			// Skip unset and special values (such as "none").
+			_, subnet, err := net.ParseCIDR(aclNets[netName].Config[key])
+			if err != nil {
+				continue
+			}
+
+			network.subnets = append(network.subnets, subnet)
+		}
+
+		networks = append(networks, network)
+	}
+
+	return networks, nil
+}
+
+// logEntryNetwork returns the name of the network whose subnets contain the source or destination of the entry.
+// An empty string is returned if no network matches.
+func logEntryNetwork(networks []logNetwork, entry *ovnLogEntry) string {
+	for _, addr := range []string{entry.Src, entry.Dst} {
+		ip := net.ParseIP(addr)
+		if ip == nil {
+			continue
+		}
+
+		for _, network := range networks {
+			for _, subnet := range network.subnets {
+				if subnet.Contains(ip) {
+					return network.name
+				}
+			}
+		}
+	}
+
+	return ""
+}
+
+// followLogFile calls lineFunc for every line added to the log file at path until the context is cancelled.
+// Existing lines are skipped and the file is re-opened when it gets rotated.
+func followLogFile(ctx context.Context, path string, lineFunc func(line string) error) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+
+	defer func() { _ = file.Close() }()
+
+	offset, err := file.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+
+	reader := bufio.NewReader(file)
+	partial := ""
+
+	// readLines passes all the complete lines available to lineFunc.
+	readLines := func() error {
+		for {
+			line, err := reader.ReadString('\n')
+			offset += int64(len(line))
+
+			if err != nil {
+				// Keep incomplete lines until the rest is written.
+				partial += line
+
+				if errors.Is(err, io.EOF) {
+					return nil
+				}
+
+				return err
+			}
+
+			err = lineFunc(partial + strings.TrimSuffix(line, "\n"))
+			if err != nil {
+				return err
+			}
+
+			partial = ""
+		}
+	}
+
+	ticker := time.NewTicker(logFollowInterval)
+	defer ticker.Stop()
+
+	for {
+		err = readLines()
+		if err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		// The file may be missing while being rotated, check again later.
+		pathInfo, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+
+		fileInfo, err := file.Stat()
+		if err != nil {
+			return err
+		}
+
+		if os.SameFile(fileInfo, pathInfo) {
+			// Start over if the file was truncated.
+			if pathInfo.Size() < offset {
+				offset, err = file.Seek(0, io.SeekStart)
+				if err != nil {
+					return err
+				}
+
+				reader.Reset(file)
+				partial = ""
+			}
+
+			continue
+		}
+
+		// The file was rotated, get what was written to the old file before switching to the new one.
+		err = readLines()
+		if err != nil {
+			return err
+		}
+
+		newFile, err := os.Open(path)
+		if err != nil {
+			continue
+		}
+
+		_ = file.Close()
+		file = newFile
+		offset = 0
+		reader.Reset(file)
+		partial = ""
+	}
+}
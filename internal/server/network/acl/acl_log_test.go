@@ -0,0 +1,86 @@
+package acl
+
+import (
+	"context"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogEntryNetwork(t *testing.T) {
+	_, subnet4, _ := net.ParseCIDR("10.0.0.1/24")
+	_, subnet6, _ := net.ParseCIDR("fd42::1/64")
+	_, otherSubnet, _ := net.ParseCIDR("10.0.1.1/24")
+
+	networks := []logNetwork{
+		{name: "ovn0", subnets: []*net.IPNet{subnet4, subnet6}},
+		{name: "ovn1", subnets: []*net.IPNet{otherSubnet}},
+	}
+
+	assert.Equal(t, "ovn0", logEntryNetwork(networks, &ovnLogEntry{Src: "10.0.0.2", Dst: "192.0.2.1"}))
+	assert.Equal(t, "ovn0", logEntryNetwork(networks, &ovnLogEntry{Src: "2001:db8::1", Dst: "fd42::2"}))
+	assert.Equal(t, "ovn1", logEntryNetwork(networks, &ovnLogEntry{Src: "192.0.2.1", Dst: "10.0.1.2"}))
+	assert.Empty(t, logEntryNetwork(networks, &ovnLogEntry{Src: "192.0.2.1", Dst: "192.0.2.2"}))
+}
+
+func TestFollowLogFile(t *testing.T) {
+	logFollowInterval = 10 * time.Millisecond
+	defer func() { logFollowInterval = time.Second }()
+
+	path := t.TempDir() + "/ovn-controller.log"
+	require.NoError(t, os.WriteFile(path, []byte("existing\n"), 0o600))
+
+	appendLog := func(data string) {
+		f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+		require.NoError(t, err)
+
+		_, err = f.WriteString(data)
+		require.NoError(t, err)
+		require.NoError(t, f.Close())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	lines := make(chan string, 10)
+	done := make(chan error)
+
+	go func() {
+		done <- followLogFile(ctx, path, func(line string) error {
+			lines <- line
+			return nil
+		})
+	}()
+
+	nextLine := func() string {
+		select {
+		case line := <-lines:
+			return line
+		case <-time.After(5 * time.Second):
+			t.Fatal("Timed out waiting for log line")
+			return ""
+		}
+	}
+
+	// Wait for the file to be opened before writing to it.
+	time.Sleep(50 * time.Millisecond)
+
+	// Lines are only passed once complete.
+	appendLog("first")
+	time.Sleep(50 * time.Millisecond)
+	appendLog(" line\n")
+	assert.Equal(t, "first line", nextLine())
+
+	// Lines written before and after rotating the file are all passed.
+	appendLog("before rotation\n")
+	require.NoError(t, os.Rename(path, path+".1"))
+	appendLog("after rotation\n")
+	assert.Equal(t, "before rotation", nextLine())
+	assert.Equal(t, "after rotation", nextLine())
+
+	cancel()
+	require.NoError(t, <-done)
+	assert.Empty(t, lines)
+}
@@ -0,0 +1,54 @@
+package acl
+
+import (
+	"slices"
+
+	"github.com/lxc/incus/v6/shared/api"
+)
+
+// MergeRules returns the union of two ACLs. The rules of both ACLs are normalised and concatenated, with the rules
+// of a first, and rules identical to an earlier one (as detected by validateConfig) are removed.
+// The config of both ACLs is merged with the values of a taking precedence. The keys set to different values in
+// both ACLs are returned sorted so the caller can report them. The supplied ACLs aren't modified.
+func MergeRules(a *api.NetworkACLPut, b *api.NetworkACLPut) (*api.NetworkACLPut, []string) {
+	merged := &api.NetworkACLPut{
+		Description: a.Description,
+		Ingress:     mergeRuleSlices(a.Ingress, b.Ingress),
+		Egress:      mergeRuleSlices(a.Egress, b.Egress),
+		Config:      make(map[string]string, len(a.Config)+len(b.Config)),
+	}
+
+	conflicts := []string{}
+	for k, v := range b.Config {
+		merged.Config[k] = v
+	}
+
+	for k, v := range a.Config {
+		bValue, found := merged.Config[k]
+		if found && bValue != v {
+			conflicts = append(conflicts, k)
+		}
+
+		merged.Config[k] = v
+	}
+
+	slices.Sort(conflicts)
+
+	return merged, conflicts
+}
+
+// mergeRuleSlices returns the normalised rules of both slices without duplicates, keeping the first occurrence.
+func mergeRuleSlices(a []api.NetworkACLRule, b []api.NetworkACLRule) []api.NetworkACLRule {
+	rules := make([]api.NetworkACLRule, 0, len(a)+len(b))
+	for _, rule := range slices.Concat(a, b) {
+		rule.Normalise()
+
+		if slices.Contains(rules, rule) {
+			continue
+		}
+
+		rules = append(rules, rule)
+	}
+
+	return rules
+}
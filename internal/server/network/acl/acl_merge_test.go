@@ -0,0 +1,57 @@
+package acl
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lxc/incus/v6/shared/api"
+)
+
+func TestMergeRules(t *testing.T) {
+	a := &api.NetworkACLPut{
+		Description: "a",
+		Ingress: []api.NetworkACLRule{
+			{Action: "allow", Source: "192.0.2.1", State: "enabled"},
+			{Action: "drop", Protocol: "tcp", DestinationPort: "22", State: "enabled"},
+			{Action: "allow", Source: "192.0.2.2,192.0.2.3", Protocol: "tcp", DestinationPort: "80,443", State: "enabled"},
+		},
+		Egress: []api.NetworkACLRule{
+			{Action: "allow", Destination: "198.51.100.0/24", State: "enabled"},
+		},
+		Config: map[string]string{"user.owner": "a", "user.a": "1"},
+	}
+
+	b := &api.NetworkACLPut{
+		Description: "b",
+		Ingress: []api.NetworkACLRule{
+			{Action: "allow", Source: " 192.0.2.1 ", State: "enabled"}, // Same as a's first rule once normalised.
+			{Action: "reject", Protocol: "udp", State: "enabled"},
+			{Action: "allow", Source: "192.0.2.3, 192.0.2.2", Protocol: "tcp", DestinationPort: "443,80", State: "enabled"}, // Same as a's third rule once normalised.
+		},
+		Egress: []api.NetworkACLRule{
+			{Action: "allow", Destination: "198.51.100.0/24", State: "enabled"},
+			{Action: "allow", Destination: "198.51.100.0/24", State: "enabled"},
+		},
+		Config: map[string]string{"user.owner": "b", "user.b": "2"},
+	}
+
+	merged, conflicts := MergeRules(a, b)
+
+	// Duplicate rules collapse and the rules of a come first.
+	assert.Equal(t, []api.NetworkACLRule{
+		{Action: "allow", Source: "192.0.2.1", State: "enabled"},
+		{Action: "drop", Protocol: "tcp", DestinationPort: "22", State: "enabled"},
+		{Action: "allow", Source: "192.0.2.2,192.0.2.3", Protocol: "tcp", DestinationPort: "80,443", State: "enabled"},
+		{Action: "reject", Protocol: "udp", State: "enabled"},
+	}, merged.Ingress)
+	assert.Equal(t, []api.NetworkACLRule{{Action: "allow", Destination: "198.51.100.0/24", State: "enabled"}}, merged.Egress)
+
+	// Config values of a take precedence and the conflicting keys are reported.
+	assert.Equal(t, map[string]string{"user.owner": "a", "user.a": "1", "user.b": "2"}, merged.Config)
+	assert.Equal(t, []string{"user.owner"}, conflicts)
+	assert.Equal(t, "a", merged.Description)
+
+	// The supplied ACLs are left untouched.
+	assert.Equal(t, " 192.0.2.1 ", b.Ingress[0].Source)
+}
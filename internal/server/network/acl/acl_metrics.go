@@ -0,0 +1,92 @@
+package acl
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/lxc/incus/v6/internal/server/db"
+	"github.com/lxc/incus/v6/internal/server/db/cluster"
+	"github.com/lxc/incus/v6/internal/server/metrics"
+	"github.com/lxc/incus/v6/internal/server/state"
+	"github.com/lxc/incus/v6/shared/api"
+)
+
+// metricsUsageTypes are the types of entities reported by the usage metric, matching the types handled by UsedBy.
+var metricsUsageTypes = []string{"instance", "network", "network-acl", "profile"}
+
+// Metrics returns the number of rules and the number of users of each network ACL in the specified project.
+// A usage sample is returned for each usage type, even when the ACL isn't used, so that unused ACLs show up.
+func Metrics(s *state.State, projectName string) (*metrics.MetricSet, error) {
+	var aclInfos []*api.NetworkACL
+
+	err := s.DB.Cluster.Transaction(context.TODO(), func(ctx context.Context, tx *db.ClusterTx) error {
+		var err error
+
+		aclInfos, err = tx.GetNetworkACLsWithRules(ctx, projectName)
+
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Failed loading network ACLs for project %q: %w", projectName, err)
+	}
+
+	out := metrics.NewMetricSet(nil)
+	if len(aclInfos) == 0 {
+		return out, nil
+	}
+
+	aclNames := make([]string, 0, len(aclInfos))
+	usage := make(map[string]map[string]int, len(aclInfos))
+	for _, aclInfo := range aclInfos {
+		aclNames = append(aclNames, aclInfo.Name)
+		usage[aclInfo.Name] = make(map[string]int, len(metricsUsageTypes))
+	}
+
+	err = UsedBy(s, projectName, func(ctx context.Context, tx *db.ClusterTx, matchedACLNames []string, usageType any, _ string, _ map[string]string) error {
+		var usageTypeName string
+
+		switch u := usageType.(type) {
+		case db.InstanceArgs:
+			usageTypeName = "instance"
+		case *api.Network:
+			usageTypeName = "network"
+		case *api.NetworkACL:
+			usageTypeName = "network-acl"
+		case cluster.Profile:
+			usageTypeName = "profile"
+		default:
+			return fmt.Errorf("Unrecognised usage type %T", u)
+		}
+
+		for _, aclName := range matchedACLNames {
+			usage[aclName][usageTypeName]++
+		}
+
+		return nil
+	}, aclNames...)
+	if err != nil {
+		return nil, fmt.Errorf("Failed getting network ACL usage for project %q: %w", projectName, err)
+	}
+
+	for _, aclInfo := range aclInfos {
+		out.AddSamples(metrics.NetworkACLRules,
+			metrics.Sample{
+				Labels: map[string]string{"project": projectName, "acl": aclInfo.Name, "direction": "ingress"},
+				Value:  float64(len(aclInfo.Ingress)),
+			},
+			metrics.Sample{
+				Labels: map[string]string{"project": projectName, "acl": aclInfo.Name, "direction": "egress"},
+				Value:  float64(len(aclInfo.Egress)),
+			},
+		)
+
+		for _, usageTypeName := range metricsUsageTypes {
+			out.AddSamples(metrics.NetworkACLUsedBy, metrics.Sample{
+				Labels: map[string]string{"project": projectName, "acl": aclInfo.Name, "type": usageTypeName},
+				Value:  float64(usage[aclInfo.Name][usageTypeName]),
+			})
+		}
+	}
+
+	return out, nil
+}
@@ -0,0 +1,67 @@
+package acl
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lxc/incus/v6/internal/server/db"
+	"github.com/lxc/incus/v6/internal/server/state"
+	"github.com/lxc/incus/v6/shared/api"
+)
+
+func TestMetrics(t *testing.T) {
+	s, cleanup := state.NewTestState(t)
+	defer cleanup()
+
+	httpRule := api.NetworkACLRule{Action: "allow", State: "enabled", Protocol: "tcp", DestinationPort: "80"}
+	sshRule := api.NetworkACLRule{Action: "allow", State: "enabled", Protocol: "tcp", DestinationPort: "22"}
+	dbRule := api.NetworkACLRule{Action: "allow", State: "enabled", Destination: "db"}
+
+	err := s.DB.Cluster.Transaction(context.Background(), func(ctx context.Context, tx *db.ClusterTx) error {
+		acls := []api.NetworkACLsPost{
+			{NetworkACLPost: api.NetworkACLPost{Name: "db"}},
+			{NetworkACLPost: api.NetworkACLPost{Name: "web"}, NetworkACLPut: api.NetworkACLPut{Ingress: []api.NetworkACLRule{httpRule, sshRule}, Egress: []api.NetworkACLRule{dbRule}}},
+		}
+
+		for _, acl := range acls {
+			_, err := tx.CreateNetworkACL(ctx, api.ProjectDefaultName, &acl)
+			if err != nil {
+				return err
+			}
+		}
+
+		_, err := tx.CreateNetwork(ctx, api.ProjectDefaultName, "net1", "", db.NetworkTypeBridge, map[string]string{"security.acls": "web"})
+
+		return err
+	})
+	require.NoError(t, err)
+
+	out, err := Metrics(s, api.ProjectDefaultName)
+	require.NoError(t, err)
+
+	lines := out.String()
+
+	expected := []string{
+		`incus_network_acl_rules{acl="db",direction="egress",project="default"} 0`,
+		`incus_network_acl_rules{acl="db",direction="ingress",project="default"} 0`,
+		`incus_network_acl_rules{acl="web",direction="egress",project="default"} 1`,
+		`incus_network_acl_rules{acl="web",direction="ingress",project="default"} 2`,
+		`incus_network_acl_usedby{acl="db",project="default",type="network"} 0`,
+		`incus_network_acl_usedby{acl="db",project="default",type="network-acl"} 1`,
+		`incus_network_acl_usedby{acl="web",project="default",type="instance"} 0`,
+		`incus_network_acl_usedby{acl="web",project="default",type="network"} 1`,
+		`incus_network_acl_usedby{acl="web",project="default",type="profile"} 0`,
+	}
+
+	for _, line := range expected {
+		assert.Contains(t, lines, line+"\n")
+	}
+
+	// A project without any ACLs doesn't report any samples.
+	out, err = Metrics(s, "missing")
+	require.NoError(t, err)
+	assert.NotContains(t, out.String(), "incus_network_acl_")
+}
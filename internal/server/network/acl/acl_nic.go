@@ -0,0 +1,169 @@
+package acl
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/lxc/incus/v6/internal/server/db"
+	"github.com/lxc/incus/v6/internal/server/state"
+	"github.com/lxc/incus/v6/shared/api"
+	"github.com/lxc/incus/v6/shared/util"
+)
+
+// ruleActionOrder defines the order in which rules are applied depending on their action. Both the firewall and
+// OVN give precedence to drop rules, then to reject rules and finally to allow rules, whatever the order of the
+// ACLs and of the rules within them.
+var ruleActionOrder = []string{"drop", "reject", "allow", "allow-stateless"}
+
+// NICACLNames returns the names of the ACLs applying to an instance NIC connected to the specified network.
+// On OVN networks, the ACLs of the NIC come first followed by the ACLs of the network it doesn't already use.
+// Other networks only apply the ACLs of the network.
+func NICACLNames(netType string, netConfig map[string]string, nicConfig map[string]string) []string {
+	netACLNames := util.SplitNTrimSpace(netConfig["security.acls"], ",", -1, true)
+	if netType != "ovn" {
+		return netACLNames
+	}
+
+	aclNames := util.SplitNTrimSpace(nicConfig["security.acls"], ",", -1, true)
+	for _, aclName := range netACLNames {
+		if !slices.Contains(aclNames, aclName) {
+			aclNames = append(aclNames, aclName)
+		}
+	}
+
+	return aclNames
+}
+
+// NICDefaults returns the action and logging mode to use for the specified direction's default rule.
+// If the security.acls.default.{in,e}gress.action or security.acls.default.{in,e}gress.logged settings are not
+// specified in the NIC config, then the settings on the network are used, and if not specified there then it
+// returns "reject" and false respectively.
+func NICDefaults(nicConfig map[string]string, netConfig map[string]string, direction string) (string, bool) {
+	defaults := map[string]string{
+		fmt.Sprintf("security.acls.default.%s.action", direction): "reject",
+		fmt.Sprintf("security.acls.default.%s.logged", direction): "false",
+	}
+
+	for k := range defaults {
+		if nicConfig[k] != "" {
+			defaults[k] = nicConfig[k]
+		} else if netConfig[k] != "" {
+			defaults[k] = netConfig[k]
+		}
+	}
+
+	return defaults[fmt.Sprintf("security.acls.default.%s.action", direction)], util.IsTrue(defaults[fmt.Sprintf("security.acls.default.%s.logged", direction)])
+}
+
+// NICRules returns the rules of all the ACLs applying to an instance NIC connected to the specified network, in the
+// order they are applied, along with the default actions used for the traffic not matching any of them.
+func NICRules(s *state.State, aclProjectName string, aclNet NetworkACLUsage, nicConfig map[string]string) (*api.NetworkACLNICRules, error) {
+	aclNames := NICACLNames(aclNet.Type, aclNet.Config, nicConfig)
+
+	aclInfos := make([]*composedACL, 0, len(aclNames))
+
+	err := s.DB.Cluster.Transaction(context.TODO(), func(ctx context.Context, tx *db.ClusterTx) error {
+		for _, aclName := range aclNames {
+			_, info, err := tx.GetNetworkACL(ctx, aclProjectName, aclName)
+			if err != nil {
+				return fmt.Errorf("Failed loading ACL %q: %w", aclName, err)
+			}
+
+			aclInfo, err := withParentRules(ctx, tx, aclProjectName, info)
+			if err != nil {
+				return fmt.Errorf("Failed loading ACL %q: %w", aclName, err)
+			}
+
+			aclInfos = append(aclInfos, aclInfo)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	nicRules := &api.NetworkACLNICRules{
+		Network: aclNet.Name,
+		ACLs:    aclNames,
+		Rules:   mergeNICRules(aclNet.Type, aclInfos, time.Now()),
+	}
+
+	// Traffic isn't filtered when no ACL applies.
+	if len(aclNames) == 0 {
+		nicRules.DefaultIngressAction = "allow"
+		nicRules.DefaultEgressAction = "allow"
+
+		return nicRules, nil
+	}
+
+	// Only OVN NICs can override the default rules of their network.
+	if aclNet.Type != "ovn" {
+		nicConfig = nil
+	}
+
+	nicRules.DefaultIngressAction, nicRules.DefaultIngressLogged = NICDefaults(nicConfig, aclNet.Config, "ingress")
+	nicRules.DefaultEgressAction, nicRules.DefaultEgressLogged = NICDefaults(nicConfig, aclNet.Config, "egress")
+
+	return nicRules, nil
+}
+
+// mergeNICRules returns the rules of the ACLs in the order used when applying them to a NIC of a network of the
+// type. On OVN networks, the rules are ordered by priority band first, with the inherited rules of each ACL in the
+// band after its own. Within a band, or on other networks, they're ordered by action and then by ACL, direction and
+// position. Inactive rules, including those of a disabled direction, are included at the position they'd have if
+// they were active.
+func mergeNICRules(netType string, aclInfos []*composedACL, now time.Time) []api.NetworkACLNICRule {
+	bandRules := make([]map[string][]api.NetworkACLNICRule, ovnACLPriorityBandMax+1)
+	for band := range bandRules {
+		bandRules[band] = make(map[string][]api.NetworkACLNICRule, len(ruleActionOrder))
+	}
+
+	for _, aclInfo := range aclInfos {
+		band := 0
+		inheritedBand := 0
+		if netType == "ovn" {
+			band = ovnACLPriorityBand(aclInfo.Config)
+			inheritedBand = ovnACLInheritedPriorityBand(band)
+		}
+
+		for _, direction := range []ruleDirection{ruleDirectionIngress, ruleDirectionEgress} {
+			rules := aclInfo.Ingress
+			if direction == ruleDirectionEgress {
+				rules = aclInfo.Egress
+			}
+
+			inheritedRules := aclInfo.inheritedRules(direction)
+
+			for i, rule := range rules {
+				nicRule := api.NetworkACLNICRule{
+					NetworkACLRule: rule,
+					ACL:            aclInfo.Name,
+					Direction:      string(direction),
+					Index:          i - inheritedRules,
+					Active:         rule.State != "disabled" && ruleIsActive(rule, now) && ruleDirectionEnabled(aclInfo.Config, direction),
+				}
+
+				ruleBand := band
+				if i < inheritedRules {
+					nicRule.Index = i
+					nicRule.Inherited = true
+					ruleBand = inheritedBand
+				}
+
+				bandRules[ruleBand][rule.Action] = append(bandRules[ruleBand][rule.Action], nicRule)
+			}
+		}
+	}
+
+	merged := []api.NetworkACLNICRule{}
+	for _, actionRules := range bandRules {
+		for _, action := range ruleActionOrder {
+			merged = append(merged, actionRules[action]...)
+		}
+	}
+
+	return merged
+}
@@ -0,0 +1,131 @@
+package acl
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lxc/incus/v6/shared/api"
+)
+
+func TestMergeNICRules(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	web := &api.NetworkACL{
+		NetworkACLPost: api.NetworkACLPost{Name: "web"},
+		NetworkACLPut: api.NetworkACLPut{
+			Ingress: []api.NetworkACLRule{
+				{Action: "allow", State: "enabled", Protocol: "tcp", DestinationPort: "80"},
+				{Action: "drop", State: "logged", Source: "192.0.2.0/24"},
+			},
+			Egress: []api.NetworkACLRule{
+				{Action: "allow-stateless", State: "disabled"},
+			},
+		},
+	}
+
+	deny := &api.NetworkACL{
+		NetworkACLPost: api.NetworkACLPost{Name: "deny"},
+		NetworkACLPut: api.NetworkACLPut{
+			Ingress: []api.NetworkACLRule{
+				{Action: "reject", State: "enabled", Protocol: "udp", ValidUntil: "2024-04-01T00:00:00Z"},
+				{Action: "drop", State: "enabled", Protocol: "icmp4"},
+			},
+			Egress: []api.NetworkACLRule{
+				{Action: "allow", State: "enabled", Destination: "any"},
+			},
+		},
+	}
+
+	type ruleRef struct {
+		acl       string
+		direction string
+		index     int
+		inherited bool
+		active    bool
+	}
+
+	refs := func(netType string, aclInfos ...*composedACL) []ruleRef {
+		refs := []ruleRef{}
+		for _, rule := range mergeNICRules(netType, aclInfos, now) {
+			refs = append(refs, ruleRef{rule.ACL, rule.Direction, rule.Index, rule.Inherited, rule.Active})
+		}
+
+		return refs
+	}
+
+	// Rules are ordered by action first, and then keep the order of the ACLs, directions and rules.
+	assert.Equal(t, []ruleRef{
+		{"web", "ingress", 1, false, true},
+		{"deny", "ingress", 1, false, true},
+		{"deny", "ingress", 0, false, false},
+		{"web", "ingress", 0, false, true},
+		{"deny", "egress", 0, false, true},
+		{"web", "egress", 0, false, false},
+	}, refs("bridge", &composedACL{NetworkACL: web}, &composedACL{NetworkACL: deny}))
+
+	assert.Equal(t, []api.NetworkACLNICRule{}, mergeNICRules("ovn", nil, now))
+
+	// On OVN networks, the rules of an ACL in a lower band come first whatever their action.
+	deny.Config = map[string]string{"priority.band": "4"}
+	assert.Equal(t, []ruleRef{
+		{"deny", "ingress", 1, false, true},
+		{"deny", "ingress", 0, false, false},
+		{"deny", "egress", 0, false, true},
+		{"web", "ingress", 1, false, true},
+		{"web", "ingress", 0, false, true},
+		{"web", "egress", 0, false, false},
+	}, refs("ovn", &composedACL{NetworkACL: web}, &composedACL{NetworkACL: deny}))
+
+	// Inherited rules are placed in the band after the one of their ACL and indexed separately.
+	admin := &composedACL{
+		NetworkACL: &api.NetworkACL{
+			NetworkACLPost: api.NetworkACLPost{Name: "admin"},
+			NetworkACLPut: api.NetworkACLPut{
+				Ingress: []api.NetworkACLRule{
+					{Action: "drop", State: "enabled", Protocol: "tcp", DestinationPort: "22"},
+					{Action: "allow", State: "enabled", Source: "192.0.2.10", Protocol: "tcp", DestinationPort: "22"},
+				},
+			},
+		},
+		inheritedIngress: 1,
+	}
+
+	assert.Equal(t, []ruleRef{
+		{"admin", "ingress", 0, false, true},
+		{"admin", "ingress", 0, true, true},
+	}, refs("ovn", admin))
+
+	assert.Equal(t, []ruleRef{
+		{"admin", "ingress", 0, true, true},
+		{"admin", "ingress", 0, false, true},
+	}, refs("bridge", admin))
+}
+
+func TestNICACLNames(t *testing.T) {
+	netConfig := map[string]string{"security.acls": "web,deny"}
+	nicConfig := map[string]string{"security.acls": "deny,ssh"}
+
+	// OVN NICs use their own ACLs first, followed by the network ones.
+	assert.Equal(t, []string{"deny", "ssh", "web"}, NICACLNames("ovn", netConfig, nicConfig))
+
+	// Bridge NICs only use the network ones.
+	assert.Equal(t, []string{"web", "deny"}, NICACLNames("bridge", netConfig, nicConfig))
+}
+
+func TestNICDefaults(t *testing.T) {
+	netConfig := map[string]string{"security.acls.default.ingress.action": "drop", "security.acls.default.egress.logged": "true"}
+	nicConfig := map[string]string{"security.acls.default.ingress.action": "allow"}
+
+	action, logged := NICDefaults(nicConfig, netConfig, "ingress")
+	assert.Equal(t, "allow", action)
+	assert.False(t, logged)
+
+	action, logged = NICDefaults(nicConfig, netConfig, "egress")
+	assert.Equal(t, "reject", action)
+	assert.True(t, logged)
+
+	action, _ = NICDefaults(nil, netConfig, "ingress")
+	assert.Equal(t, "drop", action)
+}
@@ -1,11 +1,14 @@
 package acl
 
 import (
+	"cmp"
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
+	"maps"
 	"net"
 	"slices"
+	"strconv"
 	"strings"
 	"time"
 
@@ -14,6 +17,8 @@ import (
 	"github.com/lxc/incus/v6/internal/server/db/cluster"
 	"github.com/lxc/incus/v6/internal/server/instance"
 	"github.com/lxc/incus/v6/internal/server/network/ovn"
+	"github.com/lxc/incus/v6/internal/server/network/ovs"
+	"github.com/lxc/incus/v6/internal/server/project"
 	"github.com/lxc/incus/v6/internal/server/state"
 	"github.com/lxc/incus/v6/shared/api"
 	"github.com/lxc/incus/v6/shared/logger"
@@ -33,9 +38,27 @@ const ovnACLPriorityPortGroupAllow = 300
 const ovnACLPriorityPortGroupReject = 400
 const ovnACLPriorityPortGroupDrop = 500
 
+// ACL rules are given priorities within the band set by the ACL's priority.band config key, so that the rules of
+// an ACL in a lower band are always evaluated before those of an ACL in a higher band. Bands are spaced by
+// ovnACLPriorityBandSize, with the highest band keeping the port group priorities above unchanged.
+const ovnACLPriorityBandDefault = 5
+const ovnACLPriorityBandMax = 9
+const ovnACLPriorityBandSize = 1000
+
+// ovnACLPriorityNetworkLogOffset is added to the priority of the copies of logged rules using a network's log
+// settings. It must stay lower than the gap between the port group priorities.
+const ovnACLPriorityNetworkLogOffset = 1
+
 // ovnACLPortGroupPrefix prefix used when naming ACL related port groups in OVN.
 const ovnACLPortGroupPrefix = "incus_acl"
 
+// Define the states of an ACL's rules in OVN relative to its definition.
+const (
+	ovnACLStateSynced  = "synced"
+	ovnACLStatePending = "pending"
+	ovnACLStateError   = "error"
+)
+
 // OVNACLPortGroupName returns the port group name for a Network ACL ID.
 func OVNACLPortGroupName(networkACLID int64) ovn.OVNPortGroup {
 	// OVN doesn't match port groups that have a "-" in them. So use an "_" for the separator.
@@ -50,6 +73,23 @@ func OVNACLNetworkPortGroupName(networkACLID int64, networkID int64) ovn.OVNPort
 	return ovn.OVNPortGroup(fmt.Sprintf("%s%d_net%d", ovnACLPortGroupPrefix, networkACLID, networkID))
 }
 
+// ovnACLPortGroupACLID returns the network ACL ID of an ACL port group or ACL network port group name.
+func ovnACLPortGroupACLID(portGroupName ovn.OVNPortGroup) (int64, bool) {
+	suffix, found := strings.CutPrefix(string(portGroupName), ovnACLPortGroupPrefix)
+	if !found {
+		return -1, false
+	}
+
+	aclID, _, _ := strings.Cut(suffix, "_net")
+
+	id, err := strconv.ParseInt(aclID, 10, 64)
+	if err != nil {
+		return -1, false
+	}
+
+	return id, true
+}
+
 // OVNIntSwitchPortGroupName returns the port group name for a Network ID.
 func OVNIntSwitchPortGroupName(networkID int64) ovn.OVNPortGroup {
 	return ovn.OVNPortGroup(fmt.Sprintf("incus_net%d", networkID))
@@ -65,6 +105,17 @@ func OVNNetworkPrefix(networkID int64) string {
 	return fmt.Sprintf("incus-net%d", networkID)
 }
 
+// OVNNetworkLogMeterName returns the name of the meter rate limiting the ACL log messages for a Network ID.
+func OVNNetworkLogMeterName(networkID int64) ovn.OVNMeter {
+	return ovn.OVNMeter(fmt.Sprintf("%s-acl-log", OVNNetworkPrefix(networkID)))
+}
+
+// ovnACLLogMeterName returns the name of the meter rate limiting the log messages of the logged rules of a
+// direction of the ACL using the specified port group.
+func ovnACLLogMeterName(portGroupName ovn.OVNPortGroup, direction string) ovn.OVNMeter {
+	return ovn.OVNMeter(fmt.Sprintf("%s-%s-log", portGroupName, direction))
+}
+
 // OVNIntSwitchName returns the internal logical switch name for a Network ID.
 func OVNIntSwitchName(networkID int64) ovn.OVNSwitch {
 	return ovn.OVNSwitch(fmt.Sprintf("%s-ls-int", OVNNetworkPrefix(networkID)))
@@ -75,17 +126,63 @@ func OVNIntSwitchRouterPortName(networkID int64) ovn.OVNSwitchPort {
 	return ovn.OVNSwitchPort(fmt.Sprintf("%s-lsp-router", OVNIntSwitchName(networkID)))
 }
 
+// OVNIntSwitchInstancePortName returns OVN logical internal switch port name of an instance NIC.
+func OVNIntSwitchInstancePortName(networkID int64, instanceUUID string, deviceName string) ovn.OVNSwitchPort {
+	return ovn.OVNSwitchPort(fmt.Sprintf("%s-instance-%s-%s", OVNNetworkPrefix(networkID), instanceUUID, deviceName))
+}
+
+// ovnRetryMaxDelay is the maximum total time spent waiting between the retries of a change in OVN, so that a
+// long outage fails the change rather than holding the ACL lock for hours.
+var ovnRetryMaxDelay = 2 * time.Minute
+
+// ovnRetry runs f, retrying it with an exponential backoff when it fails because OVN is temporarily unreachable.
+// Any other error is returned straight away, as is the last error once the retries or ovnRetryMaxDelay run out.
+// Updates are the only ACL changes applied to OVN: an ACL must be unused to be renamed or deleted, and as its
+// port groups are named after its ID, neither creating, renaming nor deleting it changes anything in OVN.
+func ovnRetry(l logger.Logger, retries int64, delay time.Duration, f func() error) error {
+	var waited time.Duration
+	for attempt := int64(1); ; attempt++ {
+		err := f()
+		if err == nil || attempt > retries || !ovn.IsTransientError(err) || waited+delay > ovnRetryMaxDelay {
+			return err
+		}
+
+		l.Warn("Transient OVN failure, retrying", logger.Ctx{"attempt": attempt, "retries": retries, "delay": delay, "err": err})
+		time.Sleep(delay)
+		waited += delay
+		delay *= 2
+	}
+}
+
 // OVNEnsureACLs ensures that the requested aclNames exist as OVN port groups (creates & applies ACL rules if not),
 // If reapplyRules is true then the current ACL rules in the database are applied to the existing port groups
 // rather than just new ones. Any ACLs referenced in the requested ACLs rules are also created as empty OVN port
 // groups if needed. If a requested ACL exists, but has no ACL rules applied, then the current rules are loaded out
 // of the database and applied. For each network provided in aclNets, the network specific port group for each ACL
 // is checked for existence (it is created & applies network specific ACL rules if not).
+// The rules applied to the port groups of an ACL with a parent, set by its "parent" config key, start with the
+// rules inherited from its parents, which are given a lower priority than the ACL's own rules.
+// All changes are applied to OVN in a single transaction.
 // Returns a revert fail function that can be used to undo this function if a subsequent step fails.
 func OVNEnsureACLs(s *state.State, l logger.Logger, client *ovn.NB, aclProjectName string, aclNameIDs map[string]int64, aclNets map[string]NetworkACLUsage, aclNames []string, reapplyRules bool) (revert.Hook, error) {
-	revert := revert.New()
-	defer revert.Fail()
+	txn := client.NewTransaction()
+
+	cleanup, err := ovnEnsureACLs(s, l, client, txn, aclProjectName, aclNameIDs, aclNets, aclNames, reapplyRules)
+	if err != nil {
+		return nil, err
+	}
 
+	err = txn.Commit(context.TODO())
+	if err != nil {
+		return nil, fmt.Errorf("Failed applying security ACL changes to OVN: %w", err)
+	}
+
+	return cleanup, nil
+}
+
+// ovnEnsureACLs adds the changes needed by OVNEnsureACLs to the transaction without committing it.
+// The returned revert function undoes the port group creations once the transaction has been committed.
+func ovnEnsureACLs(s *state.State, l logger.Logger, client *ovn.NB, txn *ovn.NBTransaction, aclProjectName string, aclNameIDs map[string]int64, aclNets map[string]NetworkACLUsage, aclNames []string, reapplyRules bool) (revert.Hook, error) {
 	var err error
 	var projectID int64
 	err = s.DB.Cluster.Transaction(context.TODO(), func(ctx context.Context, tx *db.ClusterTx) error {
@@ -100,30 +197,25 @@ func OVNEnsureACLs(s *state.State, l logger.Logger, client *ovn.NB, aclProjectNa
 		return nil, err
 	}
 
-	peerTargetNetIDs, err := s.DB.Cluster.GetNetworkPeersTargetNetworkIDs(aclProjectName, db.NetworkTypeOVN)
+	peerTargetNetIDs, err := ovnResolveACLs(s, aclProjectName, aclNameIDs, aclNames)
 	if err != nil {
-		return nil, fmt.Errorf("Failed getting peer connection mappings: %w", err)
-	}
-
-	// First check all ACL Names map to IDs in supplied aclNameIDs.
-	for _, aclName := range aclNames {
-		_, found := aclNameIDs[aclName]
-		if !found {
-			return nil, fmt.Errorf("Cannot find security ACL ID for %q", aclName)
-		}
+		return nil, err
 	}
 
 	// Next check which OVN port groups need creating and which exist already.
 	type aclStatus struct {
 		name       string
 		uuid       ovn.OVNPortGroupUUID
-		aclInfo    *api.NetworkACL
+		aclInfo    *composedACL
 		addACLNets map[string]NetworkACLUsage
 	}
 
 	existingACLPortGroups := []aclStatus{}
 	createACLPortGroups := []aclStatus{}
 
+	// Keep track of the port groups created so that they can be removed together on revert.
+	createdPortGroups := []ovn.OVNPortGroup{}
+
 	for _, aclName := range aclNames {
 		portGroupName := OVNACLPortGroupName(aclNameIDs[aclName])
 
@@ -134,11 +226,16 @@ func OVNEnsureACLs(s *state.State, l logger.Logger, client *ovn.NB, aclProjectNa
 		}
 
 		if portGroupUUID == "" {
-			var aclInfo *api.NetworkACL
+			var aclInfo *composedACL
 
 			err := s.DB.Cluster.Transaction(context.TODO(), func(ctx context.Context, tx *db.ClusterTx) error {
 				// Load the config we'll need to create the port group with ACL rules.
-				_, aclInfo, err = tx.GetNetworkACL(ctx, aclProjectName, aclName)
+				_, info, err := tx.GetNetworkACL(ctx, aclProjectName, aclName)
+				if err != nil {
+					return err
+				}
+
+				aclInfo, err = withParentRules(ctx, tx, aclProjectName, info)
 
 				return err
 			})
@@ -148,7 +245,7 @@ func OVNEnsureACLs(s *state.State, l logger.Logger, client *ovn.NB, aclProjectNa
 
 			createACLPortGroups = append(createACLPortGroups, aclStatus{name: aclName, aclInfo: aclInfo})
 		} else {
-			var aclInfo *api.NetworkACL
+			var aclInfo *composedACL
 			addACLNets := make(map[string]NetworkACLUsage)
 
 			// Check each per-ACL-per-network port group exists.
@@ -172,7 +269,12 @@ func OVNEnsureACLs(s *state.State, l logger.Logger, client *ovn.NB, aclProjectNa
 			// new per-ACL-per-network port groups.
 			if reapplyRules || !portGroupHasACLs || len(addACLNets) > 0 {
 				err = s.DB.Cluster.Transaction(context.TODO(), func(ctx context.Context, tx *db.ClusterTx) error {
-					_, aclInfo, err = tx.GetNetworkACL(ctx, aclProjectName, aclName)
+					_, info, err := tx.GetNetworkACL(ctx, aclProjectName, aclName)
+					if err != nil {
+						return err
+					}
+
+					aclInfo, err = withParentRules(ctx, tx, aclProjectName, info)
 
 					return err
 				})
@@ -192,13 +294,13 @@ func OVNEnsureACLs(s *state.State, l logger.Logger, client *ovn.NB, aclProjectNa
 	// port groups.
 	referencedACLs := make(map[string]struct{}, 0)
 	for _, aclStatus := range createACLPortGroups {
-		ovnAddReferencedACLs(aclStatus.aclInfo, referencedACLs)
+		ovnAddReferencedACLs(aclStatus.aclInfo.NetworkACL, referencedACLs)
 	}
 
 	if reapplyRules {
 		// Also add referenced ACLs in existing ACL rulesets if reapplying rules, as they may have changed.
 		for _, aclStatus := range existingACLPortGroups {
-			ovnAddReferencedACLs(aclStatus.aclInfo, referencedACLs)
+			ovnAddReferencedACLs(aclStatus.aclInfo.NetworkACL, referencedACLs)
 		}
 	}
 
@@ -223,26 +325,45 @@ func OVNEnsureACLs(s *state.State, l logger.Logger, client *ovn.NB, aclProjectNa
 		if portGroupUUID == "" {
 			l.Debug("Creating empty referenced ACL OVN port group", logger.Ctx{"networkACL": aclName, "portGroup": portGroupName})
 
-			err := client.CreatePortGroup(context.TODO(), projectID, portGroupName, "", "")
+			err := txn.CreatePortGroup(context.TODO(), projectID, portGroupName, "", "")
 			if err != nil {
 				return nil, fmt.Errorf("Failed creating port group %q for referenced security ACL %q setup: %w", portGroupName, aclName, err)
 			}
 
-			revert.Add(func() { _ = client.DeletePortGroup(context.TODO(), portGroupName) })
+			createdPortGroups = append(createdPortGroups, portGroupName)
 		}
 	}
 
+	// Create the address sets of the host names used by the rules we are about to apply.
+	fqdnACLInfos := []*api.NetworkACL{}
+	for _, aclStatus := range append(slices.Clone(createACLPortGroups), existingACLPortGroups...) {
+		if aclStatus.aclInfo != nil {
+			fqdnACLInfos = append(fqdnACLInfos, aclStatus.aclInfo.NetworkACL)
+		}
+	}
+
+	err = ovnEnsureFQDNAddressSets(txn, fqdnACLInfos...)
+	if err != nil {
+		return nil, err
+	}
+
 	// Create the needed port groups and then apply ACL rules to new port groups.
 	for _, aclStatus := range createACLPortGroups {
 		portGroupName := OVNACLPortGroupName(aclNameIDs[aclStatus.name])
 		l.Debug("Creating ACL OVN port group", logger.Ctx{"networkACL": aclStatus.name, "portGroup": portGroupName})
 
-		err := client.CreatePortGroup(context.TODO(), projectID, portGroupName, "", "")
+		err := txn.CreatePortGroup(context.TODO(), projectID, portGroupName, "", "")
 		if err != nil {
 			return nil, fmt.Errorf("Failed creating port group %q for security ACL %q setup: %w", portGroupName, aclStatus.name, err)
 		}
 
-		revert.Add(func() { _ = client.DeletePortGroup(context.TODO(), portGroupName) })
+		createdPortGroups = append(createdPortGroups, portGroupName)
+
+		// Create the log meters used by the rules in the same transaction.
+		err = ovnUpdateACLLogMeters(txn, portGroupName, aclStatus.aclInfo.Config)
+		if err != nil {
+			return nil, err
+		}
 
 		// Create any per-ACL-per-network port groups needed.
 		for _, aclNet := range aclNets {
@@ -250,16 +371,16 @@ func OVNEnsureACLs(s *state.State, l logger.Logger, client *ovn.NB, aclProjectNa
 			l.Debug("Creating ACL OVN network port group", logger.Ctx{"networkACL": aclStatus.name, "portGroup": netPortGroupName})
 
 			// Create OVN network specific port group and link it to switch by adding the router port.
-			err = client.CreatePortGroup(context.TODO(), projectID, netPortGroupName, portGroupName, OVNIntSwitchName(aclNet.ID), OVNIntSwitchRouterPortName(aclNet.ID))
+			err = txn.CreatePortGroup(context.TODO(), projectID, netPortGroupName, portGroupName, OVNIntSwitchName(aclNet.ID), OVNIntSwitchRouterPortName(aclNet.ID))
 			if err != nil {
 				return nil, fmt.Errorf("Failed creating port group %q for security ACL %q and network %q setup: %w", portGroupName, aclStatus.name, aclNet.Name, err)
 			}
 
-			revert.Add(func() { _ = client.DeletePortGroup(context.TODO(), netPortGroupName) })
+			createdPortGroups = append(createdPortGroups, netPortGroupName)
 		}
 
 		// Now apply our ACL rules to port group (and any per-ACL-per-network port groups needed).
-		err = ovnApplyToPortGroup(l, client, aclStatus.aclInfo, portGroupName, aclNameIDs, aclNets, peerTargetNetIDs)
+		err = ovnApplyToPortGroup(l, txn, aclStatus.aclInfo, portGroupName, aclNameIDs, aclNets, peerTargetNetIDs)
 		if err != nil {
 			return nil, fmt.Errorf("Failed applying ACL rules to port group %q for security ACL %q setup: %w", portGroupName, aclStatus.name, err)
 		}
@@ -276,12 +397,12 @@ func OVNEnsureACLs(s *state.State, l logger.Logger, client *ovn.NB, aclProjectNa
 			l.Debug("Creating ACL OVN network port group", logger.Ctx{"networkACL": aclStatus.name, "portGroup": netPortGroupName})
 
 			// Create OVN network specific port group and link it to switch by adding the router port.
-			err := client.CreatePortGroup(context.TODO(), projectID, netPortGroupName, portGroupName, OVNIntSwitchName(aclNet.ID), OVNIntSwitchRouterPortName(aclNet.ID))
+			err := txn.CreatePortGroup(context.TODO(), projectID, netPortGroupName, portGroupName, OVNIntSwitchName(aclNet.ID), OVNIntSwitchRouterPortName(aclNet.ID))
 			if err != nil {
 				return nil, fmt.Errorf("Failed creating port group %q for security ACL %q and network %q setup: %w", portGroupName, aclStatus.name, aclNet.Name, err)
 			}
 
-			revert.Add(func() { _ = client.DeletePortGroup(context.TODO(), netPortGroupName) })
+			createdPortGroups = append(createdPortGroups, netPortGroupName)
 		}
 
 		// If aclInfo has been loaded, then we should use it to apply ACL rules to the existing port group
@@ -289,18 +410,92 @@ func OVNEnsureACLs(s *state.State, l logger.Logger, client *ovn.NB, aclProjectNa
 		if aclStatus.aclInfo != nil {
 			l.Debug("Applying ACL rules to OVN port group", logger.Ctx{"networkACL": aclStatus.name, "portGroup": portGroupName})
 
-			err := ovnApplyToPortGroup(l, client, aclStatus.aclInfo, portGroupName, aclNameIDs, aclNets, peerTargetNetIDs)
+			err := ovnUpdateACLLogMeters(txn, portGroupName, aclStatus.aclInfo.Config)
+			if err != nil {
+				return nil, err
+			}
+
+			err = ovnApplyToPortGroup(l, txn, aclStatus.aclInfo, portGroupName, aclNameIDs, aclNets, peerTargetNetIDs)
 			if err != nil {
 				return nil, fmt.Errorf("Failed applying ACL rules to port group %q for security ACL %q setup: %w", portGroupName, aclStatus.name, err)
 			}
 		}
 	}
 
-	cleanup := revert.Clone().Fail
-	revert.Success()
+	cleanup := func() {
+		if len(createdPortGroups) > 0 {
+			_ = client.DeletePortGroup(context.TODO(), createdPortGroups...)
+		}
+	}
+
 	return cleanup, nil
 }
 
+// ovnResolveACLs checks that all the aclNames map to IDs in aclNameIDs and returns the target networks of the
+// peer connections of the project, which are needed to translate the ACL rules.
+func ovnResolveACLs(s *state.State, aclProjectName string, aclNameIDs map[string]int64, aclNames []string) (map[db.NetworkPeer]int64, error) {
+	peerTargetNetIDs, err := s.DB.Cluster.GetNetworkPeersTargetNetworkIDs(aclProjectName, db.NetworkTypeOVN)
+	if err != nil {
+		return nil, fmt.Errorf("Failed getting peer connection mappings: %w", err)
+	}
+
+	for _, aclName := range aclNames {
+		_, found := aclNameIDs[aclName]
+		if !found {
+			return nil, fmt.Errorf("Cannot find security ACL ID for %q", aclName)
+		}
+	}
+
+	return peerTargetNetIDs, nil
+}
+
+// OVNValidateACLs translates the requested aclNames into OVN port groups and rules the same way OVNEnsureACLs does
+// when reapplying rules, but without reading from or writing to OVN. The current ACL rules are loaded out of the
+// database and the would-be definitions are returned in the order of aclNames, so that translation and name
+// resolution errors can be found before changing anything.
+func OVNValidateACLs(s *state.State, aclProjectName string, aclNameIDs map[string]int64, aclNets map[string]NetworkACLUsage, aclNames []string) ([]OVNPortGroupDefinition, error) {
+	peerTargetNetIDs, err := ovnResolveACLs(s, aclProjectName, aclNameIDs, aclNames)
+	if err != nil {
+		return nil, err
+	}
+
+	aclInfos := make([]*composedACL, 0, len(aclNames))
+	err = s.DB.Cluster.Transaction(context.TODO(), func(ctx context.Context, tx *db.ClusterTx) error {
+		for _, aclName := range aclNames {
+			_, info, err := tx.GetNetworkACL(ctx, aclProjectName, aclName)
+			if err != nil {
+				return fmt.Errorf("Failed loading Network ACL %q: %w", aclName, err)
+			}
+
+			aclInfo, err := withParentRules(ctx, tx, aclProjectName, info)
+			if err != nil {
+				return fmt.Errorf("Failed loading Network ACL %q: %w", aclName, err)
+			}
+
+			aclInfos = append(aclInfos, aclInfo)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	defs := make([]OVNPortGroupDefinition, 0, len(aclInfos))
+	for _, aclInfo := range aclInfos {
+		portGroupName := OVNACLPortGroupName(aclNameIDs[aclInfo.Name])
+
+		def, err := ovnPortGroupDefinition(aclInfo, portGroupName, aclNameIDs, aclNets, peerTargetNetIDs)
+		if err != nil {
+			return nil, fmt.Errorf("Failed translating ACL rules to port group %q for security ACL %q: %w", portGroupName, aclInfo.Name, err)
+		}
+
+		defs = append(defs, *def)
+	}
+
+	return defs, nil
+}
+
 // ovnAddReferencedACLs adds to the referencedACLNames any ACLs referenced by the rules in the supplied ACL.
 func ovnAddReferencedACLs(info *api.NetworkACL, referencedACLNames map[string]struct{}) {
 	addACLNamesFrom := func(ruleSubjects []string) {
@@ -310,14 +505,23 @@ func ovnAddReferencedACLs(info *api.NetworkACL, referencedACLNames map[string]st
 				continue // Skip subjects already seen.
 			}
 
-			if slices.Contains(append(ruleSubjectInternalAliases, ruleSubjectExternalAliases...), subject) {
+			if slices.Contains(slices.Concat(ruleSubjectInternalAliases, ruleSubjectExternalAliases, ruleSubjectIPv4Aliases, ruleSubjectIPv6Aliases), subject) {
 				continue // Skip special reserved subjects that are not ACL names.
 			}
 
+			_, found = ruleSubjectAny[subject]
+			if found {
+				continue // Skip shorthand subjects matching any IP address.
+			}
+
 			if validate.IsNetworkAddressCIDR(subject) == nil || validate.IsNetworkRange(subject) == nil {
 				continue // Skip if the subject is an IP CIDR or IP range.
 			}
 
+			if ruleSubjectIsFQDN(subject) {
+				continue // Skip host names, which use address sets rather than port groups.
+			}
+
 			// Anything else must be a referenced ACL name.
 			// Record newly seen referenced ACL into authoritative list.
 			referencedACLNames[subject] = struct{}{}
@@ -333,50 +537,98 @@ func ovnAddReferencedACLs(info *api.NetworkACL, referencedACLNames map[string]st
 	}
 }
 
-// ovnApplyToPortGroup applies the rules in the specified ACL to the specified port group.
-func ovnApplyToPortGroup(l logger.Logger, client *ovn.NB, aclInfo *api.NetworkACL, portGroupName ovn.OVNPortGroup, aclNameIDs map[string]int64, aclNets map[string]NetworkACLUsage, peerTargetNetIDs map[db.NetworkPeer]int64) error {
+// ovnACLPriorityBand returns the priority band of the ACL's rules set by its priority.band config key.
+func ovnACLPriorityBand(config map[string]string) int {
+	band, err := strconv.Atoi(config["priority.band"])
+	if err != nil || band < 0 || band > ovnACLPriorityBandMax {
+		return ovnACLPriorityBandDefault
+	}
+
+	return band
+}
+
+// ovnACLInheritedPriorityBand returns the priority band of the rules inherited from its parents by an ACL in the band.
+// They are given the next higher band, so that the ACL's own rules are evaluated before them. ACLs in the highest
+// band share it with their inherited rules.
+func ovnACLInheritedPriorityBand(band int) int {
+	return min(band+1, ovnACLPriorityBandMax)
+}
+
+// ovnPortGroupRules converts the rules in the specified ACL into the OVN ACL rules for the specified port group.
+// Returns the rules for the ACL port group and the network specific rules for the per-ACL-per-network port groups.
+func ovnPortGroupRules(aclInfo *composedACL, portGroupName ovn.OVNPortGroup, aclNameIDs map[string]int64, aclNets map[string]NetworkACLUsage, peerTargetNetIDs map[db.NetworkPeer]int64) ([]ovn.OVNACLRule, []ovn.OVNACLRule, error) {
 	// Create slice for port group rules that has the capacity for ingress and egress rules, plus default rule.
 	portGroupRules := make([]ovn.OVNACLRule, 0, len(aclInfo.Ingress)+len(aclInfo.Egress)+1)
 	networkRules := make([]ovn.OVNACLRule, 0)
 	networkPeersNeeded := make([]db.NetworkPeer, 0)
 
+	// Rules outside of their validity window are left out until the scheduled refresh applies them.
+	now := time.Now()
+
+	band := ovnACLPriorityBand(aclInfo.Config)
+	bandOffset := (ovnACLPriorityBandMax - band) * ovnACLPriorityBandSize
+	inheritedBandOffset := (ovnACLPriorityBandMax - ovnACLInheritedPriorityBand(band)) * ovnACLPriorityBandSize
+
 	// convertACLRules converts the ACL rules to OVN ACL rules.
 	convertACLRules := func(direction string, rules ...api.NetworkACLRule) error {
+		inheritedRules := aclInfo.inheritedRules(ruleDirection(direction))
+
 		for ruleIndex, rule := range rules {
-			if rule.State == "disabled" {
+			if rule.State == "disabled" || !ruleIsActive(rule, now) {
 				continue
 			}
 
-			ovnACLRule, networkSpecific, networkPeers, err := ovnRuleCriteriaToOVNACLRule(direction, &rule, portGroupName, aclNameIDs, peerTargetNetIDs)
-			if err != nil {
-				return err
+			ruleBandOffset := bandOffset
+			if ruleIndex < inheritedRules {
+				ruleBandOffset = inheritedBandOffset
 			}
 
-			if rule.State == "logged" {
-				ovnACLRule.Log = true
-				ovnACLRule.LogName = fmt.Sprintf("%s-%s-%d", portGroupName, direction, ruleIndex)
-			}
+			// Rules mixing both IP families on each side are emitted as one OVN ACL per family.
+			for _, familyRule := range SplitByFamily(rule) {
+				ovnACLRule, networkSpecific, networkPeers, err := ovnRuleCriteriaToOVNACLRule(direction, &familyRule, portGroupName, aclNameIDs, peerTargetNetIDs)
+				if err != nil {
+					return err
+				}
 
-			if networkSpecific {
-				networkRules = append(networkRules, ovnACLRule)
-			} else {
-				portGroupRules = append(portGroupRules, ovnACLRule)
-			}
+				ovnACLRule.Priority += ruleBandOffset
+				ovnACLRule.RuleID = ruleID(ruleDirection(direction), rule)
 
-			networkPeersNeeded = append(networkPeersNeeded, networkPeers...)
+				if rule.State == "logged" {
+					ovnACLRule.Log = true
+
+					ovnACLRule.LogName = fmt.Sprintf("%s-%s", portGroupName, ruleLogLabel(direction, ruleIndex, inheritedRules))
+
+					if aclInfo.Config[direction+".log_rate"] != "" {
+						ovnACLRule.LogMeter = string(ovnACLLogMeterName(portGroupName, direction))
+					}
+				}
+
+				if networkSpecific {
+					networkRules = append(networkRules, ovnACLRule)
+				} else {
+					portGroupRules = append(portGroupRules, ovnACLRule)
+				}
+
+				networkPeersNeeded = append(networkPeersNeeded, networkPeers...)
+			}
 		}
 
 		return nil
 	}
 
-	err := convertACLRules("ingress", aclInfo.Ingress...)
-	if err != nil {
-		return fmt.Errorf("Failed converting ACL %q ingress rules for port group %q: %w", aclInfo.Name, portGroupName, err)
+	// The rules of a disabled direction are left out, so the default action applies to its traffic.
+	if ruleDirectionEnabled(aclInfo.Config, ruleDirectionIngress) {
+		err := convertACLRules("ingress", aclInfo.Ingress...)
+		if err != nil {
+			return nil, nil, fmt.Errorf("Failed converting ACL %q ingress rules for port group %q: %w", aclInfo.Name, portGroupName, err)
+		}
 	}
 
-	err = convertACLRules("egress", aclInfo.Egress...)
-	if err != nil {
-		return fmt.Errorf("Failed converting ACL %q egress rules for port group %q: %w", aclInfo.Name, portGroupName, err)
+	if ruleDirectionEnabled(aclInfo.Config, ruleDirectionEgress) {
+		err := convertACLRules("egress", aclInfo.Egress...)
+		if err != nil {
+			return nil, nil, fmt.Errorf("Failed converting ACL %q egress rules for port group %q: %w", aclInfo.Name, portGroupName, err)
+		}
 	}
 
 	// Add default rule to port group ACL.
@@ -397,32 +649,536 @@ func ovnApplyToPortGroup(l logger.Logger, client *ovn.NB, aclInfo *api.NetworkAC
 	for _, aclNet := range aclNets {
 		for _, peer := range networkPeersNeeded {
 			if peer.NetworkName != aclNet.Name {
-				return fmt.Errorf(`ACL requiring peer "%s/%s" cannot be applied to network %q`, peer.NetworkName, peer.PeerName, aclNet.Name)
+				return nil, nil, fmt.Errorf(`ACL requiring peer "%s/%s" cannot be applied to network %q`, peer.NetworkName, peer.PeerName, aclNet.Name)
 			}
 		}
 	}
 
-	// Clear all existing ACL rules from port group then add the new rules to the port group.
-	err = client.UpdatePortGroupACLRules(context.TODO(), portGroupName, nil, portGroupRules...)
+	return portGroupRules, networkRules, nil
+}
+
+// ovnNetworkRules returns the rules to apply to the per-ACL-per-network port group of the specified network.
+// When the network overrides the log settings of ACL rules, these are used by its logged network specific rules.
+// The log rate of the network takes precedence over the one of the ACL, which is kept if the network has none.
+// The logged rules of the shared ACL port group are also copied with a higher priority, so that the copies using
+// the network's log settings take precedence on its logical switch. As the copies have the same action as the
+// original rules, this doesn't change which traffic is allowed.
+func ovnNetworkRules(aclNet NetworkACLUsage, portGroupRules []ovn.OVNACLRule, networkRules []ovn.OVNACLRule) []ovn.OVNACLRule {
+	logLevel := aclNet.Config["security.acls.logging.severity"]
+
+	logMeter := ""
+	if aclNet.Config["security.acls.logging.rate"] != "" {
+		logMeter = string(OVNNetworkLogMeterName(aclNet.ID))
+	}
+
+	if logLevel == "" && logMeter == "" {
+		return networkRules
+	}
+
+	rules := make([]ovn.OVNACLRule, 0, len(networkRules)+len(portGroupRules))
+	for _, rule := range networkRules {
+		if rule.Log {
+			rule.LogLevel = logLevel
+			if logMeter != "" {
+				rule.LogMeter = logMeter
+			}
+		}
+
+		rules = append(rules, rule)
+	}
+
+	for _, rule := range portGroupRules {
+		if !rule.Log {
+			continue
+		}
+
+		rule.Priority += ovnACLPriorityNetworkLogOffset
+		rule.LogLevel = logLevel
+		if logMeter != "" {
+			rule.LogMeter = logMeter
+		}
+
+		rules = append(rules, rule)
+	}
+
+	return rules
+}
+
+// OVNUpdateNetworkLogMeter creates or updates the meter rate limiting the ACL log messages of a network when its
+// security.acls.logging.rate setting is set. The meter is deleted when it isn't.
+func OVNUpdateNetworkLogMeter(client *ovn.NB, networkID int64, netConfig map[string]string) error {
+	meterName := OVNNetworkLogMeterName(networkID)
+
+	if netConfig["security.acls.logging.rate"] == "" {
+		err := client.DeleteMeter(context.TODO(), meterName)
+		if err != nil {
+			return fmt.Errorf("Failed deleting ACL log meter %q: %w", meterName, err)
+		}
+
+		return nil
+	}
+
+	rate, err := strconv.Atoi(netConfig["security.acls.logging.rate"])
 	if err != nil {
-		return fmt.Errorf("Failed applying ACL %q rules to port group %q: %w", aclInfo.Name, portGroupName, err)
+		return fmt.Errorf("Invalid ACL log rate: %w", err)
 	}
 
-	// Now apply the network specific rules to all networks requested (even if networkRules is empty).
-	for _, aclNet := range aclNets {
+	err = client.UpdateMeter(context.TODO(), meterName, rate)
+	if err != nil {
+		return fmt.Errorf("Failed updating ACL log meter %q: %w", meterName, err)
+	}
+
+	return nil
+}
+
+// ovnUpdateACLLogMeters creates or updates the meters rate limiting the log messages of the logged rules of the ACL
+// using the specified port group, for each direction with a <direction>.log_rate setting. The meters of the other
+// directions are deleted, leaving their log messages unthrottled. The changes are added to the transaction.
+func ovnUpdateACLLogMeters(txn *ovn.NBTransaction, portGroupName ovn.OVNPortGroup, aclConfig map[string]string) error {
+	for _, direction := range []ruleDirection{ruleDirectionIngress, ruleDirectionEgress} {
+		meterName := ovnACLLogMeterName(portGroupName, string(direction))
+		rateKey := string(direction) + ".log_rate"
+
+		if aclConfig[rateKey] == "" {
+			err := txn.DeleteMeter(context.TODO(), meterName)
+			if err != nil {
+				return fmt.Errorf("Failed deleting ACL log meter %q: %w", meterName, err)
+			}
+
+			continue
+		}
+
+		rate, err := strconv.Atoi(aclConfig[rateKey])
+		if err != nil {
+			return fmt.Errorf("Invalid %s log rate: %w", direction, err)
+		}
+
+		err = txn.UpdateMeter(context.TODO(), meterName, rate)
+		if err != nil {
+			return fmt.Errorf("Failed updating ACL log meter %q: %w", meterName, err)
+		}
+	}
+
+	return nil
+}
+
+// OVNApplyNetworkLogging reapplies the rules of the per-ACL-per-network port groups of the specified network so
+// that they use its current log settings. The ACL port groups shared with other networks are left untouched.
+func OVNApplyNetworkLogging(s *state.State, l logger.Logger, client *ovn.NB, aclProjectName string, aclNet NetworkACLUsage) error {
+	// Make sure the meter exists before rules start using it.
+	if aclNet.Config["security.acls.logging.rate"] != "" {
+		err := OVNUpdateNetworkLogMeter(client, aclNet.ID, aclNet.Config)
+		if err != nil {
+			return err
+		}
+	}
+
+	peerTargetNetIDs, err := s.DB.Cluster.GetNetworkPeersTargetNetworkIDs(aclProjectName, db.NetworkTypeOVN)
+	if err != nil {
+		return fmt.Errorf("Failed getting peer connection mappings: %w", err)
+	}
+
+	var aclNameIDs map[string]int64
+	aclInfos := []*composedACL{}
+
+	err = s.DB.Cluster.Transaction(context.TODO(), func(ctx context.Context, tx *db.ClusterTx) error {
+		aclNameIDs, err = tx.GetNetworkACLIDsByNames(ctx, aclProjectName)
+		if err != nil {
+			return err
+		}
+
+		// Only the ACLs that have a port group for the network are applied to it.
+		for aclName, aclID := range aclNameIDs {
+			netPortGroupUUID, _, err := client.GetPortGroupInfo(ctx, OVNACLNetworkPortGroupName(aclID, aclNet.ID))
+			if err != nil {
+				return err
+			}
+
+			if netPortGroupUUID == "" {
+				continue
+			}
+
+			_, info, err := tx.GetNetworkACL(ctx, aclProjectName, aclName)
+			if err != nil {
+				return err
+			}
+
+			// The port groups also hold the rules inherited from the parents of the ACL.
+			aclInfo, err := withParentRules(ctx, tx, aclProjectName, info)
+			if err != nil {
+				return err
+			}
+
+			aclInfos = append(aclInfos, aclInfo)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("Failed loading network ACLs of network %q: %w", aclNet.Name, err)
+	}
+
+	aclNets := map[string]NetworkACLUsage{aclNet.Name: aclNet}
+	txn := client.NewTransaction()
+
+	for _, aclInfo := range aclInfos {
+		portGroupName := OVNACLPortGroupName(aclNameIDs[aclInfo.Name])
 		netPortGroupName := OVNACLNetworkPortGroupName(aclNameIDs[aclInfo.Name], aclNet.ID)
-		l.Debug("Applying network specific ACL rules to network OVN port group", logger.Ctx{"networkACL": aclInfo.Name, "network": aclNet.Name, "portGroup": netPortGroupName})
 
-		// Setup per-network dynamic replacements for @internal/@external subject port selectors.
-		matchReplace := map[string]string{
-			fmt.Sprintf("@%s", ruleSubjectInternal): fmt.Sprintf("@%s", OVNIntSwitchPortGroupName(aclNet.ID)),
-			fmt.Sprintf("@%s", ruleSubjectExternal): fmt.Sprintf(`"%s"`, OVNIntSwitchRouterPortName(aclNet.ID)),
+		portGroupRules, networkRules, err := ovnPortGroupRules(aclInfo, portGroupName, aclNameIDs, aclNets, peerTargetNetIDs)
+		if err != nil {
+			return err
+		}
+
+		l.Debug("Applying network ACL log settings to network OVN port group", logger.Ctx{"networkACL": aclInfo.Name, "network": aclNet.Name, "portGroup": netPortGroupName})
+
+		err = txn.UpdatePortGroupACLRules(context.TODO(), netPortGroupName, ovnNetworkPortGroupMatchReplace(aclNet.ID), ovnNetworkRules(aclNet, portGroupRules, networkRules)...)
+		if err != nil {
+			return fmt.Errorf("Failed applying ACL %q rules to port group %q for network %q: %w", aclInfo.Name, netPortGroupName, aclNet.Name, err)
+		}
+	}
+
+	err = txn.Commit(context.TODO())
+	if err != nil {
+		return fmt.Errorf("Failed applying network ACL log settings: %w", err)
+	}
+
+	// Remove the meter once no rules use it anymore.
+	if aclNet.Config["security.acls.logging.rate"] == "" {
+		err = OVNUpdateNetworkLogMeter(client, aclNet.ID, aclNet.Config)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ovnNetworkPortGroupMatchReplace returns the per-network replacements for the @internal/@external subject port
+// selectors used in network specific rules.
+func ovnNetworkPortGroupMatchReplace(networkID int64) map[string]string {
+	return map[string]string{
+		fmt.Sprintf("@%s", ruleSubjectInternal): fmt.Sprintf("@%s", OVNIntSwitchPortGroupName(networkID)),
+		fmt.Sprintf("@%s", ruleSubjectExternal): fmt.Sprintf(`"%s"`, OVNIntSwitchRouterPortName(networkID)),
+	}
+}
+
+// OVNACLState compares the rules applied to the OVN port groups of the specified ACL with the rules expected from
+// its current definition for each of the networks in aclNets. Nothing is changed in OVN.
+func OVNACLState(s *state.State, client *ovn.NB, aclProjectName string, aclInfo *api.NetworkACL, aclNameIDs map[string]int64, aclNets map[string]NetworkACLUsage) (map[string]api.NetworkACLNetworkState, error) {
+	peerTargetNetIDs, err := s.DB.Cluster.GetNetworkPeersTargetNetworkIDs(aclProjectName, db.NetworkTypeOVN)
+	if err != nil {
+		return nil, fmt.Errorf("Failed getting peer connection mappings: %w", err)
+	}
+
+	aclID, found := aclNameIDs[aclInfo.Name]
+	if !found {
+		return nil, fmt.Errorf("Cannot find security ACL ID for %q", aclInfo.Name)
+	}
+
+	// The port groups also hold the rules inherited from the parents of the ACL.
+	var composed *composedACL
+	err = s.DB.Cluster.Transaction(context.TODO(), func(ctx context.Context, tx *db.ClusterTx) error {
+		composed, err = withParentRules(ctx, tx, aclProjectName, aclInfo)
+
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Failed loading the parents of network ACL %q: %w", aclInfo.Name, err)
+	}
+
+	states := make(map[string]api.NetworkACLNetworkState, len(aclNets))
+	portGroupName := OVNACLPortGroupName(aclID)
+
+	portGroupRules, networkRules, err := ovnPortGroupRules(composed, portGroupName, aclNameIDs, aclNets, peerTargetNetIDs)
+	if err != nil {
+		// The rules cannot be applied to any of the networks.
+		for _, aclNet := range aclNets {
+			states[aclNet.Name] = api.NetworkACLNetworkState{Status: ovnACLStateError, Detail: err.Error()}
+		}
+
+		return states, nil
+	}
+
+	// The ACL port group is shared by all networks, so only check it once.
+	portGroupState := ovnPortGroupState(client, portGroupName, portGroupRules, nil)
+
+	for _, aclNet := range aclNets {
+		if portGroupState.Status != ovnACLStateSynced {
+			states[aclNet.Name] = portGroupState
+			continue
+		}
+
+		netPortGroupName := OVNACLNetworkPortGroupName(aclID, aclNet.ID)
+		states[aclNet.Name] = ovnPortGroupState(client, netPortGroupName, ovnNetworkRules(aclNet, portGroupRules, networkRules), ovnNetworkPortGroupMatchReplace(aclNet.ID))
+	}
+
+	return states, nil
+}
+
+// OVNACLObjects returns the names of the OVN port groups of an ACL used by the specified networks, along with the
+// UUIDs of the OVN ACL rows currently backing each of its rules. Port groups that don't exist yet have no rows.
+// The rows of the port groups that can't be read are left out and the errors are returned keyed by network name,
+// with the errors of the port group shared by all networks reported for each of them.
+func OVNACLObjects(client *ovn.NB, aclID int64, aclNets map[string]NetworkACLUsage) (*api.NetworkACLOVNState, map[string][]string) {
+	objects := &api.NetworkACLOVNState{
+		PortGroup:         string(OVNACLPortGroupName(aclID)),
+		NetworkPortGroups: make(map[string]string, len(aclNets)),
+		RuleACLs:          map[string][]string{},
+	}
+
+	netErrors := map[string][]string{}
+
+	addRows := func(portGroupName ovn.OVNPortGroup, netNames ...string) {
+		ruleIDs, err := client.GetPortGroupACLRuleIDs(context.TODO(), portGroupName)
+		if err != nil {
+			if errors.Is(err, ovn.ErrNotFound) {
+				return
+			}
+
+			for _, netName := range netNames {
+				netErrors[netName] = append(netErrors[netName], fmt.Sprintf("Failed getting ACL rows of port group %q: %v", portGroupName, err))
+			}
+
+			return
 		}
 
-		err = client.UpdatePortGroupACLRules(context.TODO(), netPortGroupName, matchReplace, networkRules...)
-		if err != nil {
-			return fmt.Errorf("Failed applying ACL %q rules to port group %q for network %q: %w", aclInfo.Name, netPortGroupName, aclNet.Name, err)
-		}
+		for aclUUID, ruleID := range ruleIDs {
+			// Skip rows that aren't generated from a rule, such as the default rules.
+			if ruleID == "" {
+				continue
+			}
+
+			objects.RuleACLs[ruleID] = append(objects.RuleACLs[ruleID], aclUUID)
+		}
+	}
+
+	addRows(OVNACLPortGroupName(aclID), slices.Collect(maps.Keys(aclNets))...)
+
+	for _, aclNet := range aclNets {
+		netPortGroupName := OVNACLNetworkPortGroupName(aclID, aclNet.ID)
+		objects.NetworkPortGroups[aclNet.Name] = string(netPortGroupName)
+		addRows(netPortGroupName, aclNet.Name)
+	}
+
+	for _, aclUUIDs := range objects.RuleACLs {
+		slices.Sort(aclUUIDs)
+	}
+
+	return objects, netErrors
+}
+
+// ovnPortGroupState compares the rules applied to a port group with the expected rules.
+func ovnPortGroupState(client *ovn.NB, portGroupName ovn.OVNPortGroup, expectedRules []ovn.OVNACLRule, matchReplace map[string]string) api.NetworkACLNetworkState {
+	appliedRules, err := client.GetPortGroupACLRules(context.TODO(), portGroupName)
+	if err != nil {
+		if errors.Is(err, ovn.ErrNotFound) {
+			return api.NetworkACLNetworkState{Status: ovnACLStatePending, Detail: fmt.Sprintf("Port group %q doesn't exist", portGroupName)}
+		}
+
+		return api.NetworkACLNetworkState{Status: ovnACLStateError, Detail: fmt.Sprintf("Failed getting rules of port group %q: %v", portGroupName, err)}
+	}
+
+	// Transform the expected rules the same way as when they are applied.
+	wantRules := make([]ovn.OVNACLRule, 0, len(expectedRules))
+	for _, rule := range expectedRules {
+		for find, replace := range matchReplace {
+			rule.Match = strings.ReplaceAll(rule.Match, find, replace)
+		}
+
+		// The log settings are only stored for logged rules.
+		if !rule.Log {
+			rule.LogName = ""
+			rule.LogLevel = ""
+			rule.LogMeter = ""
+		}
+
+		wantRules = append(wantRules, rule)
+	}
+
+	slices.SortFunc(wantRules, compareOVNACLRules)
+	slices.SortFunc(appliedRules, compareOVNACLRules)
+
+	if !slices.Equal(wantRules, appliedRules) {
+		return api.NetworkACLNetworkState{Status: ovnACLStatePending, Detail: fmt.Sprintf("Port group %q rules don't match the ACL (%d applied, %d expected)", portGroupName, len(appliedRules), len(wantRules))}
+	}
+
+	return api.NetworkACLNetworkState{Status: ovnACLStateSynced}
+}
+
+// compareOVNACLRules orders OVN ACL rules so that sets of rules can be compared.
+func compareOVNACLRules(a ovn.OVNACLRule, b ovn.OVNACLRule) int {
+	return cmp.Or(
+		cmp.Compare(a.Priority, b.Priority),
+		cmp.Compare(a.Direction, b.Direction),
+		cmp.Compare(a.Action, b.Action),
+		cmp.Compare(a.Match, b.Match),
+		cmp.Compare(a.LogName, b.LogName),
+		cmp.Compare(a.LogLevel, b.LogLevel),
+		cmp.Compare(a.LogMeter, b.LogMeter),
+		cmp.Compare(a.RuleID, b.RuleID),
+	)
+}
+
+// ovnACLCounterSource retrieves the counters of the OVN ACLs applied to a port group keyed by Incus rule ID.
+type ovnACLCounterSource interface {
+	PortGroupRuleCounters(ctx context.Context, portGroupName ovn.OVNPortGroup) (map[string]ovs.FlowStats, error)
+}
+
+// ovnACLCounters retrieves OVN ACL counters from the OpenFlow flows installed on the local integration bridge.
+type ovnACLCounters struct {
+	nb      *ovn.NB
+	sb      *ovn.SB
+	vswitch *ovs.VSwitch
+	bridge  string
+}
+
+// PortGroupRuleCounters maps the port group's OVN ACLs to their logical flows and sums up the counters of the
+// OpenFlow flows installed for them.
+func (c *ovnACLCounters) PortGroupRuleCounters(ctx context.Context, portGroupName ovn.OVNPortGroup) (map[string]ovs.FlowStats, error) {
+	ruleIDs, err := c.nb.GetPortGroupACLRuleIDs(ctx, portGroupName)
+	if err != nil {
+		if errors.Is(err, ovn.ErrNotFound) {
+			return map[string]ovs.FlowStats{}, nil
+		}
+
+		return nil, err
+	}
+
+	if len(ruleIDs) == 0 {
+		return map[string]ovs.FlowStats{}, nil
+	}
+
+	aclUUIDs := make([]string, 0, len(ruleIDs))
+	for aclUUID := range ruleIDs {
+		aclUUIDs = append(aclUUIDs, aclUUID)
+	}
+
+	cookies, err := c.sb.GetACLLogicalFlowCookies(ctx, aclUUIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	flowStats, err := c.vswitch.GetBridgeFlowStats(ctx, c.bridge)
+	if err != nil {
+		return nil, err
+	}
+
+	counters := make(map[string]ovs.FlowStats, len(ruleIDs))
+	for aclUUID, ruleID := range ruleIDs {
+		ruleCounters := counters[ruleID]
+		for _, cookie := range cookies[aclUUID] {
+			ruleCounters.Packets += flowStats[cookie].Packets
+			ruleCounters.Bytes += flowStats[cookie].Bytes
+		}
+
+		counters[ruleID] = ruleCounters
+	}
+
+	return counters, nil
+}
+
+// OVNPortGroupDefinition is the OVN port group a network ACL translates to, along with the rules applied to it.
+type OVNPortGroupDefinition struct {
+	ACL       string
+	PortGroup ovn.OVNPortGroup
+	Rules     []ovn.OVNACLRule
+	Networks  []OVNNetworkPortGroupDefinition
+}
+
+// OVNNetworkPortGroupDefinition is the per-ACL-per-network port group of a network ACL for one of its networks.
+// The @internal/@external subject port selectors in the rules are replaced using MatchReplace when applied.
+type OVNNetworkPortGroupDefinition struct {
+	Network      string
+	PortGroup    ovn.OVNPortGroup
+	Switch       ovn.OVNSwitch
+	MatchReplace map[string]string
+	Rules        []ovn.OVNACLRule
+	QoSRules     []ovn.OVNACLRule
+}
+
+// ovnPortGroupDefinition translates the rules in the specified ACL into the definition of its port group and of its
+// per-ACL-per-network port groups for each network in aclNets. The networks are sorted by name.
+func ovnPortGroupDefinition(aclInfo *composedACL, portGroupName ovn.OVNPortGroup, aclNameIDs map[string]int64, aclNets map[string]NetworkACLUsage, peerTargetNetIDs map[db.NetworkPeer]int64) (*OVNPortGroupDefinition, error) {
+	portGroupRules, networkRules, err := ovnPortGroupRules(aclInfo, portGroupName, aclNameIDs, aclNets, peerTargetNetIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	def := &OVNPortGroupDefinition{
+		ACL:       aclInfo.Name,
+		PortGroup: portGroupName,
+		Rules:     portGroupRules,
+		Networks:  make([]OVNNetworkPortGroupDefinition, 0, len(aclNets)),
+	}
+
+	for _, aclNet := range aclNets {
+		def.Networks = append(def.Networks, OVNNetworkPortGroupDefinition{
+			Network:      aclNet.Name,
+			PortGroup:    OVNACLNetworkPortGroupName(aclNameIDs[aclInfo.Name], aclNet.ID),
+			Switch:       OVNIntSwitchName(aclNet.ID),
+			MatchReplace: ovnNetworkPortGroupMatchReplace(aclNet.ID),
+			Rules:        ovnNetworkRules(aclNet, portGroupRules, networkRules),
+
+			// The DSCP marks of both the port group and network specific rules apply to the network's switch.
+			QoSRules: append(slices.Clone(portGroupRules), networkRules...),
+		})
+	}
+
+	slices.SortFunc(def.Networks, func(a OVNNetworkPortGroupDefinition, b OVNNetworkPortGroupDefinition) int {
+		return strings.Compare(a.Network, b.Network)
+	})
+
+	return def, nil
+}
+
+// ovnApplyToPortGroup adds applying the rules in the specified ACL to the specified port group to the transaction.
+func ovnApplyToPortGroup(l logger.Logger, txn *ovn.NBTransaction, aclInfo *composedACL, portGroupName ovn.OVNPortGroup, aclNameIDs map[string]int64, aclNets map[string]NetworkACLUsage, peerTargetNetIDs map[db.NetworkPeer]int64) error {
+	def, err := ovnPortGroupDefinition(aclInfo, portGroupName, aclNameIDs, aclNets, peerTargetNetIDs)
+	if err != nil {
+		return err
+	}
+
+	// Apply the new rules to the port group, only touching the OVN ACLs that have changed.
+	err = txn.UpdatePortGroupACLRules(context.TODO(), portGroupName, nil, def.Rules...)
+	if err != nil {
+		return fmt.Errorf("Failed applying ACL %q rules to port group %q: %w", aclInfo.Name, portGroupName, err)
+	}
+
+	// Now apply the network specific rules to all networks requested (even if there are no network rules).
+	for _, netDef := range def.Networks {
+		l.Debug("Applying network specific ACL rules to network OVN port group", logger.Ctx{"networkACL": aclInfo.Name, "network": netDef.Network, "portGroup": netDef.PortGroup})
+
+		err = txn.UpdatePortGroupACLRules(context.TODO(), netDef.PortGroup, netDef.MatchReplace, netDef.Rules...)
+		if err != nil {
+			return fmt.Errorf("Failed applying ACL %q rules to port group %q for network %q: %w", aclInfo.Name, netDef.PortGroup, netDef.Network, err)
+		}
+
+		err = txn.UpdatePortGroupQoSRules(context.TODO(), netDef.PortGroup, netDef.Switch, netDef.MatchReplace, netDef.QoSRules...)
+		if err != nil {
+			return fmt.Errorf("Failed applying ACL %q DSCP marks to port group %q for network %q: %w", aclInfo.Name, netDef.PortGroup, netDef.Network, err)
+		}
+	}
+
+	return nil
+}
+
+// ovnValidateRule checks that the rule only uses criteria OVN can enforce.
+// OVN has no match field for the length of IP packets, so packet length rules are rejected.
+func ovnValidateRule(rule *api.NetworkACLRule) error {
+	if rule.PacketLen != "" {
+		return fmt.Errorf("Rules matching on packet length aren't supported on OVN networks")
+	}
+
+	// OVN can't be told how to reject traffic, it always answers TCP traffic with a reset and other traffic with
+	// an ICMP port unreachable message, so only the reject response it sends for the rule's protocol can be used.
+	ovnRejectResponse := ruleRejectResponseICMPPortUnreachable
+	if rule.Protocol == "tcp" {
+		ovnRejectResponse = ruleRejectResponseTCPReset
+	}
+
+	if rule.RejectResponse != "" && (rule.Protocol == "" || rule.RejectResponse != ovnRejectResponse) {
+		return fmt.Errorf("Reject response %q isn't supported on OVN networks for rules of protocol %q", rule.RejectResponse, rule.Protocol)
 	}
 
 	return nil
@@ -431,10 +1187,16 @@ func ovnApplyToPortGroup(l logger.Logger, client *ovn.NB, aclInfo *api.NetworkAC
 // ovnRuleCriteriaToOVNACLRule converts an ACL rule into an OVNACLRule for an OVN port group or network.
 // Returns a bool indicating if any of the rule subjects are network specific.
 func ovnRuleCriteriaToOVNACLRule(direction string, rule *api.NetworkACLRule, portGroupName ovn.OVNPortGroup, aclNameIDs map[string]int64, peerTargetNetIDs map[db.NetworkPeer]int64) (ovn.OVNACLRule, bool, []db.NetworkPeer, error) {
+	err := ovnValidateRule(rule)
+	if err != nil {
+		return ovn.OVNACLRule{}, false, nil, err
+	}
+
 	networkSpecific := false
 	networkPeersNeeded := make([]db.NetworkPeer, 0)
 	portGroupRule := ovn.OVNACLRule{
 		Direction: "to-lport", // Always use this so that outport is available to Match.
+		DSCP:      rule.DSCP,
 	}
 
 	// Populate Action and Priority based on rule's Action.
@@ -445,7 +1207,14 @@ func ovnRuleCriteriaToOVNACLRule(direction string, rule *api.NetworkACLRule, por
 	case "allow-stateless":
 		portGroupRule.Action = "allow-stateless"
 		portGroupRule.Priority = ovnACLPriorityPortGroupAllow
+
+		// Track the connections so that reply traffic is allowed without a rule of its own.
+		if rule.Related {
+			portGroupRule.Action = "allow-related"
+		}
+
 	case "reject":
+		// OVN chooses the response itself, which ovnValidateRule checked is the rule's reject response (if any).
 		portGroupRule.Action = "reject"
 		portGroupRule.Priority = ovnACLPriorityPortGroupReject
 	case "drop":
@@ -465,33 +1234,60 @@ func ovnRuleCriteriaToOVNACLRule(direction string, rule *api.NetworkACLRule, por
 		matchParts = []string{fmt.Sprintf("inport == @%s || outport == @%s", portGroupName, portGroupName)}
 	}
 
+	// Add logical port filters for the other end of the traffic.
+	if rule.InPort != "" {
+		matchParts = append(matchParts, fmt.Sprintf(`inport == "%s"`, rule.InPort))
+	}
+
+	if rule.OutPort != "" {
+		matchParts = append(matchParts, fmt.Sprintf(`outport == "%s"`, rule.OutPort))
+	}
+
 	// Add subject filters.
-	if rule.Source != "" {
-		match, netSpecificMatch, networkPeers, err := ovnRuleSubjectToOVNACLMatch("src", aclNameIDs, peerTargetNetIDs, util.SplitNTrimSpace(rule.Source, ",", -1, false)...)
-		if err != nil {
-			return ovn.OVNACLRule{}, false, nil, err
+	// VLAN subjects match the tag of the traffic rather than its addresses, and are alternatives to the other
+	// subjects of their field like any other subject.
+	for _, field := range []struct {
+		direction string
+		subjects  string
+	}{
+		{direction: "src", subjects: rule.Source},
+		{direction: "dst", subjects: rule.Destination},
+	} {
+		if field.subjects == "" {
+			continue
 		}
 
-		if netSpecificMatch {
-			networkSpecific = true
-		}
+		subjects, vlans := ovnRuleSplitVLANSubjects(expandRuleSubjects(util.SplitNTrimSpace(field.subjects, ",", -1, false)))
 
-		matchParts = append(matchParts, match)
-		networkPeersNeeded = append(networkPeersNeeded, networkPeers...)
-	}
+		fieldParts := make([]string, 0, 2)
+		if len(subjects) > 0 {
+			match, netSpecificMatch, networkPeers, err := ovnRuleSubjectToOVNACLMatch(field.direction, aclNameIDs, peerTargetNetIDs, subjects...)
+			if err != nil {
+				return ovn.OVNACLRule{}, false, nil, err
+			}
 
-	if rule.Destination != "" {
-		match, netSpecificMatch, networkPeers, err := ovnRuleSubjectToOVNACLMatch("dst", aclNameIDs, peerTargetNetIDs, util.SplitNTrimSpace(rule.Destination, ",", -1, false)...)
-		if err != nil {
-			return ovn.OVNACLRule{}, false, nil, err
+			if netSpecificMatch {
+				networkSpecific = true
+			}
+
+			fieldParts = append(fieldParts, match)
+			networkPeersNeeded = append(networkPeersNeeded, networkPeers...)
 		}
 
-		if netSpecificMatch {
-			networkSpecific = true
+		if len(vlans) > 0 {
+			match, err := ovnRuleVLANToOVNACLMatch(vlans...)
+			if err != nil {
+				return ovn.OVNACLRule{}, false, nil, err
+			}
+
+			fieldParts = append(fieldParts, match)
 		}
 
-		matchParts = append(matchParts, match)
-		networkPeersNeeded = append(networkPeersNeeded, networkPeers...)
+		if len(fieldParts) > 1 {
+			matchParts = append(matchParts, fmt.Sprintf("(%s)", strings.Join(fieldParts, ") || (")))
+		} else {
+			matchParts = append(matchParts, fieldParts...)
+		}
 	}
 
 	// Add protocol filters.
@@ -515,6 +1311,13 @@ func ovnRuleCriteriaToOVNACLRule(direction string, rule *api.NetworkACLRule, por
 		if rule.ICMPCode != "" {
 			matchParts = append(matchParts, fmt.Sprintf("%s.code == %s", rule.Protocol, rule.ICMPCode))
 		}
+	} else if rule.Protocol == ruleProtocolICMP6NDP {
+		typeParts := make([]string, 0, len(ruleICMP6NDPTypes))
+		for _, icmpType := range ruleICMP6NDPTypes {
+			typeParts = append(typeParts, fmt.Sprintf("icmp6.type == %s", icmpType))
+		}
+
+		matchParts = append(matchParts, "icmp6", strings.Join(typeParts, " || "))
 	}
 
 	// Populate the Match field with the generated match parts.
@@ -540,6 +1343,37 @@ func ovnRulePortToOVNACLMatch(protocol string, direction string, portCriteria ..
 	return strings.Join(fieldParts, " || ")
 }
 
+// ovnRuleSplitVLANSubjects splits the VLAN subjects from the other subjects.
+func ovnRuleSplitVLANSubjects(subjects []string) ([]string, []string) {
+	var others, vlans []string
+	for _, subject := range subjects {
+		if strings.HasPrefix(subject, ruleSubjectVLANPrefix) {
+			vlans = append(vlans, subject)
+		} else {
+			others = append(others, subject)
+		}
+	}
+
+	return others, vlans
+}
+
+// ovnRuleVLANToOVNACLMatch converts VLAN subjects into an OVN match statement on the VLAN tag. The match requires
+// the tag to be present (bit 12 of vlan.tci) and the VLAN ID (the lowest 12 bits) to be the one of a subject.
+func ovnRuleVLANToOVNACLMatch(vlanSubjects ...string) (string, error) {
+	fieldParts := make([]string, 0, len(vlanSubjects))
+
+	for _, subject := range vlanSubjects {
+		vlanID, err := ruleSubjectVLAN(subject)
+		if err != nil {
+			return "", err
+		}
+
+		fieldParts = append(fieldParts, fmt.Sprintf("vlan.tci == 0x%04x/0x1fff", 0x1000|vlanID))
+	}
+
+	return strings.Join(fieldParts, " || "), nil
+}
+
 // ovnRuleSubjectToOVNACLMatch converts direction (src/dst) and subject criteria list into an OVN match statement.
 // Returns a bool indicating if any of the subjects are network specific.
 func ovnRuleSubjectToOVNACLMatch(direction string, aclNameIDs map[string]int64, peerTargetNetIDs map[db.NetworkPeer]int64, subjectCriteria ...string) (string, bool, []db.NetworkPeer, error) {
@@ -566,6 +1400,13 @@ func ovnRuleSubjectToOVNACLMatch(direction string, aclNameIDs map[string]int64,
 			}
 		} else {
 			// Try parsing subject as single IP or CIDR.
+			// OVN can't match the zone of link-local addresses, so zoned subjects are rejected rather than
+			// matching the address on all the ports.
+			_, zone := ruleSubjectZone(subjectCriterion)
+			if zone != "" {
+				return "", false, nil, fmt.Errorf("Zoned address subject %q isn't supported on OVN networks", subjectCriterion)
+			}
+
 			ip := net.ParseIP(subjectCriterion)
 			if ip == nil {
 				ip, _, _ = net.ParseCIDR(subjectCriterion)
@@ -578,6 +1419,16 @@ func ovnRuleSubjectToOVNACLMatch(direction string, aclNameIDs map[string]int64,
 				}
 
 				fieldParts = append(fieldParts, fmt.Sprintf("%s.%s == %s", protocol, direction, subjectCriterion))
+			} else if slices.Contains(ruleSubjectIPv4Aliases, subjectCriterion) {
+				// The family selectors match all the traffic of their family, whatever the address.
+				fieldParts = append(fieldParts, "ip4")
+			} else if slices.Contains(ruleSubjectIPv6Aliases, subjectCriterion) {
+				fieldParts = append(fieldParts, "ip6")
+			} else if ruleSubjectIsFQDN(subjectCriterion) {
+				// Host names match the addresses they resolve to, which are kept in address sets.
+				addrSetPrefix := OVNACLFQDNAddressSetPrefix(subjectCriterion)
+
+				fieldParts = append(fieldParts, fmt.Sprintf("ip6.%s == $%s_ip6 || ip4.%s == $%s_ip4", direction, addrSetPrefix, direction, addrSetPrefix))
 			} else {
 				// If not valid IP subnet, check if subject is ACL name or network peer name.
 				var subjectPortSelector ovn.OVNPortGroup
@@ -761,6 +1612,43 @@ func OVNApplyNetworkBaselineRules(client *ovn.NB, switchName ovn.OVNSwitch, rout
 // the desired ACLs are considered unused by the usage type even if the referring config has not yet been removed
 // from the database.
 func OVNPortGroupDeleteIfUnused(s *state.State, l logger.Logger, client *ovn.NB, aclProjectName string, ignoreUsageType any, ignoreUsageNicName string, keepACLs ...string) error {
+	txn := client.NewTransaction()
+
+	err := ovnPortGroupDeleteIfUnused(s, l, client, txn, aclProjectName, ignoreUsageType, ignoreUsageNicName, keepACLs...)
+	if err != nil {
+		return err
+	}
+
+	err = txn.Commit(context.TODO())
+	if err != nil {
+		return fmt.Errorf("Failed to delete unused OVN port groups: %w", err)
+	}
+
+	return nil
+}
+
+// ovnPortGroupDeleteIfUnused adds the deletion of unused port groups to the transaction without committing it.
+func ovnPortGroupDeleteIfUnused(s *state.State, l logger.Logger, client *ovn.NB, txn *ovn.NBTransaction, aclProjectName string, ignoreUsageType any, ignoreUsageNicName string, keepACLs ...string) error {
+	removePortGroups, err := ovnUnusedPortGroups(s, client, aclProjectName, ignoreUsageType, ignoreUsageNicName, keepACLs...)
+	if err != nil {
+		return err
+	}
+
+	for _, removePortGroup := range removePortGroups {
+		l.Debug("Scheduled deletion of unused ACL OVN port group", logger.Ctx{"portGroup": removePortGroup})
+
+		err = ovnUpdateACLLogMeters(txn, removePortGroup, nil)
+		if err != nil {
+			return err
+		}
+	}
+
+	return txn.DeletePortGroup(context.TODO(), removePortGroups...)
+}
+
+// ovnUnusedPortGroups returns the ACL port groups of the project that aren't needed by any OVN entity anymore.
+// See OVNPortGroupDeleteIfUnused for the meaning of the arguments.
+func ovnUnusedPortGroups(s *state.State, client *ovn.NB, aclProjectName string, ignoreUsageType any, ignoreUsageNicName string, keepACLs ...string) ([]ovn.OVNPortGroup, error) {
 	var aclNameIDs map[string]int64
 	var aclNames []string
 	var projectID int64
@@ -789,13 +1677,13 @@ func OVNPortGroupDeleteIfUnused(s *state.State, l logger.Logger, client *ovn.NB,
 		return nil
 	})
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// Get list of OVN port groups associated to this project.
 	portGroups, err := client.GetPortGroupsByProject(context.TODO(), projectID)
 	if err != nil {
-		return fmt.Errorf("Failed getting port groups for project %q: %w", aclProjectName, err)
+		return nil, fmt.Errorf("Failed getting port groups for project %q: %w", aclProjectName, err)
 	}
 
 	// hasKeeperPrefix indicates if the port group provided matches the prefix of one of the keepACLs.
@@ -941,7 +1829,7 @@ func OVNPortGroupDeleteIfUnused(s *state.State, l logger.Logger, client *ovn.NB,
 		return nil
 	}, aclNames...)
 	if err != nil && err != db.ErrInstanceListStop {
-		return fmt.Errorf("Failed getting ACL usage: %w", err)
+		return nil, fmt.Errorf("Failed getting ACL usage: %w", err)
 	}
 
 	// usedByOvn checks if any of the aclNames are in use by an OVN entity (network or instance/profile NIC).
@@ -966,17 +1854,323 @@ func OVNPortGroupDeleteIfUnused(s *state.State, l logger.Logger, client *ovn.NB,
 		}
 	}
 
-	// Now remove any remaining port groups left in removeACLPortGroups.
+	// Return the remaining port groups left in removeACLPortGroups.
 	removePortGroups := make([]ovn.OVNPortGroup, 0, len(removeACLPortGroups))
 	for removeACLPortGroup := range removeACLPortGroups {
 		removePortGroups = append(removePortGroups, removeACLPortGroup)
-		l.Debug("Scheduled deletion of unused ACL OVN port group", logger.Ctx{"portGroup": removeACLPortGroup})
 	}
 
-	if len(removePortGroups) > 0 {
-		err = client.DeletePortGroup(context.TODO(), removePortGroups...)
+	slices.Sort(removePortGroups)
+
+	return removePortGroups, nil
+}
+
+// OVNDrift describes the differences found between the network ACLs and their OVN port groups.
+type OVNDrift struct {
+	MissingPortGroups  []string `json:"missing_port_groups"`  // ACL port groups that don't exist in OVN.
+	StaleACLs          []string `json:"stale_acls"`           // ACLs whose rules in OVN don't match their definition (as "<project>/<name>").
+	OrphanedPortGroups []string `json:"orphaned_port_groups"` // ACL port groups that no OVN entity needs anymore.
+}
+
+// Empty returns whether no drift was found.
+func (d *OVNDrift) Empty() bool {
+	return len(d.MissingPortGroups) == 0 && len(d.StaleACLs) == 0 && len(d.OrphanedPortGroups) == 0
+}
+
+// OVNReconcile compares the OVN port groups of the network ACLs in all projects with the state expected from
+// their current definitions and usage. Unless dryRun is true, missing port groups are recreated, stale rules are
+// reapplied and orphaned port groups, including those of deleted ACLs, are deleted. Recreated ACL port groups
+// get the ports of the instance NICs using the ACL added back. In dry run mode, the rules of the ACLs needing
+// repair are only checked to translate cleanly.
+func OVNReconcile(s *state.State, l logger.Logger, client *ovn.NB, dryRun bool) (*OVNDrift, error) {
+	var projectNames []string
+
+	err := s.DB.Cluster.Transaction(context.TODO(), func(ctx context.Context, tx *db.ClusterTx) error {
+		var err error
+
+		projectNames, err = cluster.GetProjectNames(ctx, tx.Tx())
+
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Failed loading projects: %w", err)
+	}
+
+	drift := &OVNDrift{}
+
+	for _, projectName := range projectNames {
+		err = ovnReconcileProject(s, l, client, projectName, dryRun, drift)
+		if err != nil {
+			return nil, fmt.Errorf("Failed reconciling network ACLs of project %q: %w", projectName, err)
+		}
+	}
+
+	// Look for port groups left behind by ACLs deleted while OVN was unreachable, including those of deleted
+	// projects which aren't covered above.
+	deletedPortGroups, err := ovnDeletedACLPortGroups(s, client)
+	if err != nil {
+		return nil, err
+	}
+
+	orphanedPortGroups := []ovn.OVNPortGroup{}
+	for _, portGroupName := range deletedPortGroups {
+		if !slices.Contains(drift.OrphanedPortGroups, string(portGroupName)) {
+			drift.OrphanedPortGroups = append(drift.OrphanedPortGroups, string(portGroupName))
+			orphanedPortGroups = append(orphanedPortGroups, portGroupName)
+		}
+	}
+
+	if !dryRun && len(orphanedPortGroups) > 0 {
+		err = client.DeletePortGroup(context.TODO(), orphanedPortGroups...)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to delete orphaned OVN port groups: %w", err)
+		}
+	}
+
+	return drift, nil
+}
+
+// ovnRestorePortGroupMembers adds the switch ports of the instance NICs using the ACL on the specified OVN
+// networks to the ACL port group. Ports of NICs that aren't running don't exist in OVN and are skipped.
+func ovnRestorePortGroupMembers(s *state.State, client *ovn.NB, aclProjectName string, aclName string, aclID int64, aclOVNNets map[string]NetworkACLUsage) error {
+	// Get the active ports of the networks.
+	activePorts := map[ovn.OVNSwitchPort]ovn.OVNSwitchPortUUID{}
+	for _, aclNet := range aclOVNNets {
+		ports, err := client.GetLogicalSwitchPorts(context.TODO(), OVNIntSwitchName(aclNet.ID))
+		if err != nil {
+			return fmt.Errorf("Failed getting ports of network %q: %w", aclNet.Name, err)
+		}
+
+		for portName, portUUID := range ports {
+			activePorts[portName] = portUUID
+		}
+	}
+
+	portUUIDs := []ovn.OVNSwitchPortUUID{}
+
+	err := s.DB.Cluster.Transaction(context.TODO(), func(ctx context.Context, tx *db.ClusterTx) error {
+		return tx.InstanceList(ctx, func(inst db.InstanceArgs, p api.Project) error {
+			// Skip instances who's effective network project doesn't match this Network ACL's project.
+			if project.NetworkProjectFromRecord(&p) != aclProjectName {
+				return nil
+			}
+
+			devices := db.ExpandInstanceDevices(inst.Devices.Clone(), inst.Profiles)
+			for devName, devConfig := range devices {
+				aclNet, found := aclOVNNets[devConfig["network"]]
+				if devConfig["type"] != "nic" || !found {
+					continue
+				}
+
+				if !slices.Contains(NICACLNames(aclNet.Type, aclNet.Config, devConfig), aclName) {
+					continue
+				}
+
+				portUUID, found := activePorts[OVNIntSwitchInstancePortName(aclNet.ID, inst.Config["volatile.uuid"], devName)]
+				if found {
+					portUUIDs = append(portUUIDs, portUUID)
+				}
+			}
+
+			return nil
+		})
+	})
+	if err != nil {
+		return fmt.Errorf("Failed loading instance NICs: %w", err)
+	}
+
+	if len(portUUIDs) == 0 {
+		return nil
+	}
+
+	return client.UpdatePortGroupMembers(context.TODO(), map[ovn.OVNPortGroup][]ovn.OVNSwitchPortUUID{OVNACLPortGroupName(aclID): portUUIDs}, nil)
+}
+
+// ovnDeletedACLPortGroups returns the ACL port groups without any member ports whose network ACL doesn't exist
+// anymore in any project.
+func ovnDeletedACLPortGroups(s *state.State, client *ovn.NB) ([]ovn.OVNPortGroup, error) {
+	// List the port groups before loading the ACLs. As an ACL's database record is committed before its port
+	// groups are created, this ensures the port groups of ACLs being created concurrently on other members are
+	// always matched by an existing ACL.
+	portGroups, err := client.GetPortGroupsByPrefix(context.TODO(), ovnACLPortGroupPrefix)
+	if err != nil {
+		return nil, fmt.Errorf("Failed getting ACL port groups: %w", err)
+	}
+
+	aclIDs := map[int64]struct{}{}
+
+	err = s.DB.Cluster.Transaction(context.TODO(), func(ctx context.Context, tx *db.ClusterTx) error {
+		projectNames, err := cluster.GetProjectNames(ctx, tx.Tx())
+		if err != nil {
+			return err
+		}
+
+		for _, projectName := range projectNames {
+			aclNameIDs, err := tx.GetNetworkACLIDsByNames(ctx, projectName)
+			if err != nil {
+				return err
+			}
+
+			for _, aclID := range aclNameIDs {
+				aclIDs[aclID] = struct{}{}
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Failed getting network ACL IDs: %w", err)
+	}
+
+	deletedPortGroups := []ovn.OVNPortGroup{}
+	for portGroupName, ports := range portGroups {
+		if ports > 0 {
+			continue
+		}
+
+		aclID, ok := ovnACLPortGroupACLID(portGroupName)
+		if !ok {
+			continue
+		}
+
+		_, found := aclIDs[aclID]
+		if !found {
+			deletedPortGroups = append(deletedPortGroups, portGroupName)
+		}
+	}
+
+	slices.Sort(deletedPortGroups)
+
+	return deletedPortGroups, nil
+}
+
+// ovnReconcileProject reconciles the OVN port groups of the network ACLs in a project and records the drift found.
+func ovnReconcileProject(s *state.State, l logger.Logger, client *ovn.NB, projectName string, dryRun bool, drift *OVNDrift) error {
+	var aclNameIDs map[string]int64
+
+	err := s.DB.Cluster.Transaction(context.TODO(), func(ctx context.Context, tx *db.ClusterTx) error {
+		var err error
+
+		// Get map of ACL names to DB IDs (used for generating OVN port group names).
+		aclNameIDs, err = tx.GetNetworkACLIDsByNames(ctx, projectName)
+
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("Failed getting network ACL IDs: %w", err)
+	}
+
+	aclNames := make([]string, 0, len(aclNameIDs))
+	for aclName := range aclNameIDs {
+		aclNames = append(aclNames, aclName)
+	}
+
+	slices.Sort(aclNames)
+
+	for _, aclName := range aclNames {
+		aclOVNNets, err := AffectedOVNNetworks(s, projectName, aclName)
+		if err != nil {
+			return fmt.Errorf("Failed getting usage of network ACL %q: %w", aclName, err)
+		}
+
+		if len(aclOVNNets) == 0 {
+			continue
+		}
+
+		// Check that the ACL port group and the per-ACL-per-network port groups exist.
+		portGroupNames := []ovn.OVNPortGroup{OVNACLPortGroupName(aclNameIDs[aclName])}
+		for _, aclNet := range aclOVNNets {
+			portGroupNames = append(portGroupNames, OVNACLNetworkPortGroupName(aclNameIDs[aclName], aclNet.ID))
+		}
+
+		missing := false
+		for _, portGroupName := range portGroupNames {
+			portGroupUUID, _, err := client.GetPortGroupInfo(context.TODO(), portGroupName)
+			if err != nil {
+				return fmt.Errorf("Failed getting port group %q: %w", portGroupName, err)
+			}
+
+			if portGroupUUID == "" {
+				drift.MissingPortGroups = append(drift.MissingPortGroups, string(portGroupName))
+				missing = true
+			}
+		}
+
+		// Check that the rules applied to existing port groups match the ACL.
+		stale := false
+		if !missing {
+			var aclInfo *api.NetworkACL
+
+			err = s.DB.Cluster.Transaction(context.TODO(), func(ctx context.Context, tx *db.ClusterTx) error {
+				_, aclInfo, err = tx.GetNetworkACL(ctx, projectName, aclName)
+
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("Failed loading network ACL %q: %w", aclName, err)
+			}
+
+			states, err := OVNACLState(s, client, projectName, aclInfo, aclNameIDs, aclOVNNets)
+			if err != nil {
+				return fmt.Errorf("Failed getting OVN state of network ACL %q: %w", aclName, err)
+			}
+
+			// Rules that cannot be applied at all are left to the warnings raised when applying them.
+			for _, aclState := range states {
+				if aclState.Status == ovnACLStatePending {
+					stale = true
+					break
+				}
+			}
+
+			if stale {
+				drift.StaleACLs = append(drift.StaleACLs, fmt.Sprintf("%s/%s", projectName, aclName))
+			}
+		}
+
+		if !missing && !stale {
+			continue
+		}
+
+		// Only check that the rules would translate cleanly when not repairing.
+		if dryRun {
+			_, err = OVNValidateACLs(s, projectName, aclNameIDs, aclOVNNets, []string{aclName})
+			if err != nil {
+				l.Warn("Network ACL OVN port groups can't be repaired", logger.Ctx{"project": projectName, "networkACL": aclName, "err": err})
+			}
+
+			continue
+		}
+
+		// Keep going on failure so that one broken ACL doesn't hold back the others.
+		_, err = OVNEnsureACLs(s, l, client, projectName, aclNameIDs, aclOVNNets, []string{aclName}, true)
+		if err != nil {
+			l.Warn("Failed repairing network ACL OVN port groups", logger.Ctx{"project": projectName, "networkACL": aclName, "err": err})
+			continue
+		}
+
+		if missing {
+			err = ovnRestorePortGroupMembers(s, client, projectName, aclName, aclNameIDs[aclName], aclOVNNets)
+			if err != nil {
+				l.Warn("Failed restoring network ACL OVN port group members", logger.Ctx{"project": projectName, "networkACL": aclName, "err": err})
+			}
+		}
+	}
+
+	// Look for port groups left behind by ACLs that are no longer in use by OVN entities.
+	orphanedPortGroups, err := ovnUnusedPortGroups(s, client, projectName, nil, "")
+	if err != nil {
+		return err
+	}
+
+	for _, portGroupName := range orphanedPortGroups {
+		drift.OrphanedPortGroups = append(drift.OrphanedPortGroups, string(portGroupName))
+	}
+
+	if !dryRun && len(orphanedPortGroups) > 0 {
+		err = client.DeletePortGroup(context.TODO(), orphanedPortGroups...)
 		if err != nil {
-			return fmt.Errorf("Failed to delete unused OVN port groups: %w", err)
+			return fmt.Errorf("Failed to delete orphaned OVN port groups: %w", err)
 		}
 	}
 
@@ -1034,29 +2228,36 @@ func OVNApplyInstanceNICDefaultRules(client *ovn.NB, switchPortGroup ovn.OVNPort
 
 // ovnLogEntry is the type used for the JSON encoded entries on the log endpoint (when coming from OVN).
 type ovnLogEntry struct {
-	Time     string `json:"time"`
-	Proto    string `json:"proto"`
-	Src      string `json:"src"`
-	Dst      string `json:"dst"`
-	SrcPort  string `json:"src_port,omitempty"`
-	DstPort  string `json:"dst_port,omitempty"`
-	ICMPType string `json:"icmp_type,omitempty"`
-	ICMPCode string `json:"icmp_code,omitempty"`
-	Action   string `json:"action"`
+	Time      string `json:"time"`
+	Network   string `json:"network,omitempty"`
+	Direction string `json:"direction"`
+	Rule      int    `json:"rule"`
+	Inherited bool   `json:"inherited,omitempty"`
+	Label     string `json:"label"`
+	Proto     string `json:"proto"`
+	Src       string `json:"src"`
+	Dst       string `json:"dst"`
+	SrcPort   string `json:"src_port,omitempty"`
+	DstPort   string `json:"dst_port,omitempty"`
+	ICMPType  string `json:"icmp_type,omitempty"`
+	ICMPCode  string `json:"icmp_code,omitempty"`
+	Action    string `json:"action"`
 }
 
-// ovnParseLogEntry takes a log line and expected ACL prefix and returns a re-formated log entry if matching.
-func ovnParseLogEntry(input string, prefix string) string {
+// ovnParseLogEntry takes a log line and expected ACL prefix and returns the parsed log entry if matching.
+// The direction and index of the rule are taken from the log label of the rule (see ruleLogLabel). For the rules
+// inherited from the parents of the ACL, the index is into the inherited rules.
+func ovnParseLogEntry(input string, prefix string) *ovnLogEntry {
 	fields := strings.Split(input, "|")
 
 	// Skip unknown formatting.
 	if len(fields) != 5 {
-		return ""
+		return nil
 	}
 
 	// We only care about ACLs.
 	if !strings.HasPrefix(fields[2], "acl_log") {
-		return ""
+		return nil
 	}
 
 	// Parse the ACL log entry.
@@ -1071,20 +2272,34 @@ func ovnParseLogEntry(input string, prefix string) string {
 	}
 
 	// Filter for our ACL.
-	if !strings.HasPrefix(aclEntry["name"], prefix) {
-		return ""
+	label, ok := strings.CutPrefix(aclEntry["name"], prefix)
+	if !ok {
+		return nil
+	}
+
+	// Get the rule from the label.
+	direction, index, ok := strings.Cut(label, "-")
+	if !ok {
+		return nil
+	}
+
+	index, inherited := strings.CutPrefix(index, "i")
+
+	ruleIndex, err := strconv.Atoi(index)
+	if err != nil {
+		return nil
 	}
 
 	// Parse the timestamp.
 	logTime, err := time.Parse(time.RFC3339, fields[0])
 	if err != nil {
-		return ""
+		return nil
 	}
 
 	// Get the protocol.
 	directionFields := strings.Split(aclEntry["direction"], " ")
 	if len(directionFields) != 2 {
-		return ""
+		return nil
 	}
 
 	protocol := directionFields[1]
@@ -1094,7 +2309,7 @@ func ovnParseLogEntry(input string, prefix string) string {
 	if !ok {
 		srcAddr, ok = aclEntry["ipv6_src"]
 		if !ok {
-			return ""
+			return nil
 		}
 	}
 
@@ -1102,19 +2317,23 @@ func ovnParseLogEntry(input string, prefix string) string {
 	if !ok {
 		dstAddr, ok = aclEntry["ipv6_dst"]
 		if !ok {
-			return ""
+			return nil
 		}
 	}
 
 	// Prepare the core log entry.
 	newEntry := ovnLogEntry{
-		Time:     logTime.UTC().Format(time.RFC3339),
-		Proto:    protocol,
-		Src:      srcAddr,
-		Dst:      dstAddr,
-		ICMPType: aclEntry["icmp_type"],
-		ICMPCode: aclEntry["icmp_code"],
-		Action:   aclEntry["verdict"],
+		Time:      logTime.UTC().Format(time.RFC3339),
+		Direction: direction,
+		Rule:      ruleIndex,
+		Inherited: inherited,
+		Label:     aclEntry["name"],
+		Proto:     protocol,
+		Src:       srcAddr,
+		Dst:       dstAddr,
+		ICMPType:  aclEntry["icmp_type"],
+		ICMPCode:  aclEntry["icmp_code"],
+		Action:    aclEntry["verdict"],
 	}
 
 	// Add the source and destination ports.
@@ -1128,10 +2347,5 @@ func ovnParseLogEntry(input string, prefix string) string {
 		newEntry.DstPort = dstPort
 	}
 
-	out, err := json.Marshal(&newEntry)
-	if err != nil {
-		return ""
-	}
-
-	return string(out)
+	return &newEntry
 }
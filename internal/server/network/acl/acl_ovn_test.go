@@ -0,0 +1,731 @@
+package acl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	ovsClient "github.com/ovn-org/libovsdb/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lxc/incus/v6/internal/server/db"
+	"github.com/lxc/incus/v6/internal/server/db/cluster"
+	"github.com/lxc/incus/v6/internal/server/instance/instancetype"
+	"github.com/lxc/incus/v6/internal/server/network/ovn"
+	"github.com/lxc/incus/v6/internal/server/network/ovn/ovntest"
+	"github.com/lxc/incus/v6/internal/server/network/ovs"
+	"github.com/lxc/incus/v6/internal/server/state"
+	"github.com/lxc/incus/v6/shared/api"
+	"github.com/lxc/incus/v6/shared/logger"
+)
+
+func TestOVNRulePorts(t *testing.T) {
+	rule := api.NetworkACLRule{Action: "allow", State: "enabled", Protocol: "tcp", DestinationPort: "80", InPort: "lsp1"}
+	ovnRule, _, _, err := ovnRuleCriteriaToOVNACLRule("ingress", &rule, "incus_acl1", nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, `(outport == @incus_acl1) && (inport == "lsp1") && (tcp) && (tcp.dst == 80)`, ovnRule.Match)
+
+	rule = api.NetworkACLRule{Action: "drop", State: "enabled", OutPort: "lsp2"}
+	ovnRule, _, _, err = ovnRuleCriteriaToOVNACLRule("egress", &rule, "incus_acl1", nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, `(inport == @incus_acl1) && (outport == "lsp2")`, ovnRule.Match)
+
+	// Bridge networks can't enforce port matches.
+	assert.Error(t, firewallValidateRule(rule))
+	assert.NoError(t, firewallValidateRule(api.NetworkACLRule{Action: "drop", State: "enabled"}))
+}
+
+func TestOVNRulePacketLen(t *testing.T) {
+	// OVN has no match field for the packet length.
+	rule := api.NetworkACLRule{Action: "drop", State: "enabled", Protocol: "tcp", PacketLen: "1400-1500"}
+	_, _, _, err := ovnRuleCriteriaToOVNACLRule("egress", &rule, "incus_acl1", nil, nil)
+	assert.EqualError(t, err, "Rules matching on packet length aren't supported on OVN networks")
+
+	// Bridge networks can't enforce packet length matches either.
+	assert.Error(t, firewallValidateRule(rule))
+}
+
+func TestOVNRuleRejectResponse(t *testing.T) {
+	// The reject responses OVN sends for the rule's protocol are accepted.
+	for _, rule := range []api.NetworkACLRule{
+		{Action: "reject", State: "enabled", Protocol: "tcp", RejectResponse: "tcp-reset"},
+		{Action: "reject", State: "enabled", Protocol: "udp", RejectResponse: "icmp-port-unreachable"},
+		{Action: "reject", State: "enabled", Protocol: "udp"},
+	} {
+		ovnRule, _, _, err := ovnRuleCriteriaToOVNACLRule("ingress", &rule, "incus_acl1", nil, nil)
+		require.NoError(t, err, rule.RejectResponse)
+		assert.Equal(t, "reject", ovnRule.Action)
+	}
+
+	// Other responses, such as those of stored rules predating the protocol checks, can't be honoured.
+	rule := api.NetworkACLRule{Action: "reject", State: "enabled", RejectResponse: "icmp-port-unreachable"}
+	_, _, _, err := ovnRuleCriteriaToOVNACLRule("ingress", &rule, "incus_acl1", nil, nil)
+	assert.EqualError(t, err, `Reject response "icmp-port-unreachable" isn't supported on OVN networks for rules of protocol ""`)
+
+	rule = api.NetworkACLRule{Action: "reject", State: "enabled", Protocol: "udp", RejectResponse: "tcp-reset"}
+	_, _, _, err = ovnRuleCriteriaToOVNACLRule("ingress", &rule, "incus_acl1", nil, nil)
+	assert.EqualError(t, err, `Reject response "tcp-reset" isn't supported on OVN networks for rules of protocol "udp"`)
+}
+
+func TestOVNRuleVLAN(t *testing.T) {
+	rule := api.NetworkACLRule{Action: "allow", State: "enabled", Source: "192.0.2.1,vlan:42", Destination: "vlan:100"}
+	ovnRule, _, _, err := ovnRuleCriteriaToOVNACLRule("ingress", &rule, "incus_acl1", nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, `(outport == @incus_acl1) && ((ip4.src == 192.0.2.1) || (vlan.tci == 0x102a/0x1fff)) && (vlan.tci == 0x1064/0x1fff)`, ovnRule.Match)
+
+	rule = api.NetworkACLRule{Action: "drop", State: "enabled", Destination: "vlan:0"}
+	ovnRule, _, _, err = ovnRuleCriteriaToOVNACLRule("egress", &rule, "incus_acl1", nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, `(inport == @incus_acl1) && (vlan.tci == 0x1000/0x1fff)`, ovnRule.Match)
+
+	// Bridge networks can't match on the VLAN tag.
+	_, err = firewallRuleSubjects("vlan:42", nil, nil)
+	assert.ErrorContains(t, err, `VLAN subject "vlan:42" isn't supported on bridge networks`)
+}
+
+func TestOVNRuleFQDN(t *testing.T) {
+	rule := api.NetworkACLRule{Action: "allow", State: "enabled", Destination: "API.example.com"}
+	ovnRule, _, _, err := ovnRuleCriteriaToOVNACLRule("egress", &rule, "incus_acl1", nil, nil)
+	require.NoError(t, err)
+
+	addrSetPrefix := OVNACLFQDNAddressSetPrefix("api.example.com")
+	assert.Equal(t, fmt.Sprintf("(inport == @incus_acl1) && (ip6.dst == $%s_ip6 || ip4.dst == $%s_ip4)", addrSetPrefix, addrSetPrefix), ovnRule.Match)
+	assert.Regexp(t, `^incus_acl_fqdn_[0-9a-f]{16}$`, string(addrSetPrefix))
+
+	// Bridge networks can't resolve host names.
+	_, err = firewallRuleSubjects("api.example.com", nil, nil)
+	assert.ErrorContains(t, err, `Host name subject "api.example.com" isn't supported on bridge networks`)
+}
+
+// mockOVNACLCounters returns fixed rule counters for each port group, failing for the port groups without any.
+type mockOVNACLCounters map[ovn.OVNPortGroup]map[string]ovs.FlowStats
+
+func (m mockOVNACLCounters) PortGroupRuleCounters(ctx context.Context, portGroupName ovn.OVNPortGroup) (map[string]ovs.FlowStats, error) {
+	counters, found := m[portGroupName]
+	if !found {
+		return nil, errors.New("not found")
+	}
+
+	return counters, nil
+}
+
+func TestOVNRuleStats(t *testing.T) {
+	ingressRule := api.NetworkACLRule{Action: "allow", State: "enabled", Destination: "192.0.2.1"}
+	egressRule := api.NetworkACLRule{Action: "drop", State: "enabled", Destination: "192.0.2.2"}
+	unusedRule := api.NetworkACLRule{Action: "reject", State: "enabled", Source: "192.0.2.3"}
+
+	d := &common{
+		id: 1,
+		info: &api.NetworkACL{
+			NetworkACLPut: api.NetworkACLPut{
+				Ingress: []api.NetworkACLRule{ingressRule, unusedRule},
+				Egress:  []api.NetworkACLRule{egressRule},
+			},
+		},
+	}
+
+	ingressID := ruleID(ruleDirectionIngress, ingressRule)
+	egressID := ruleID(ruleDirectionEgress, egressRule)
+	unusedID := ruleID(ruleDirectionIngress, unusedRule)
+
+	counters := mockOVNACLCounters{
+		OVNACLPortGroupName(1): {
+			ingressID: {Packets: 10, Bytes: 1000},
+			egressID:  {Packets: 2, Bytes: 120},
+			"stale":   {Packets: 5, Bytes: 500},
+		},
+		OVNACLNetworkPortGroupName(1, 2): {
+			ingressID: {Packets: 1, Bytes: 100},
+		},
+	}
+
+	aclNets := map[string]NetworkACLUsage{
+		"net2": {ID: 2, Name: "net2", Type: "ovn"},
+		"net3": {ID: 3, Name: "net3", Type: "ovn"},
+	}
+
+	// The counters of net3's port group can't be read, which is only reported for net3.
+	stats, netErrors := d.ovnRuleStats(counters, aclNets)
+
+	assert.Equal(t, map[string]api.NetworkACLRuleStats{
+		ingressID: {Direction: "ingress", Index: 0, Packets: 11, Bytes: 1100},
+		unusedID:  {Direction: "ingress", Index: 1},
+		egressID:  {Direction: "egress", Index: 0, Packets: 2, Bytes: 120},
+	}, stats)
+
+	assert.Equal(t, map[string][]string{
+		"net3": {`Failed getting rule counters of port group "incus_acl1_net3": not found`},
+	}, netErrors)
+
+	// The errors of the port group shared by all networks are reported for each of them.
+	delete(counters, OVNACLPortGroupName(1))
+	stats, netErrors = d.ovnRuleStats(counters, aclNets)
+
+	assert.Equal(t, int64(1), stats[ingressID].Packets)
+	assert.Len(t, netErrors["net2"], 1)
+	assert.Len(t, netErrors["net3"], 2)
+}
+
+func TestOVNRetry(t *testing.T) {
+	l := logger.AddContext(logger.Ctx{})
+
+	// Transient failures are retried until the operation succeeds.
+	calls := 0
+	err := ovnRetry(l, 3, time.Millisecond, func() error {
+		calls++
+		if calls < 3 {
+			return fmt.Errorf("Failed ensuring ACL is configured in OVN: %w", ovsClient.ErrNotConnected)
+		}
+
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, calls)
+
+	// Retries are bounded.
+	calls = 0
+	err = ovnRetry(l, 2, time.Millisecond, func() error {
+		calls++
+		return fmt.Errorf("endpoint is not leader")
+	})
+	assert.Error(t, err)
+	assert.Equal(t, 3, calls)
+
+	// The total delay is bounded too, here to the 1ms and 2ms delays of the first two retries.
+	oldMaxDelay := ovnRetryMaxDelay
+	ovnRetryMaxDelay = 3 * time.Millisecond
+	defer func() { ovnRetryMaxDelay = oldMaxDelay }()
+
+	calls = 0
+	err = ovnRetry(l, 10, time.Millisecond, func() error {
+		calls++
+		return fmt.Errorf("endpoint is not leader")
+	})
+	assert.Error(t, err)
+	assert.Equal(t, 3, calls)
+
+	// Other errors aren't retried.
+	calls = 0
+	err = ovnRetry(l, 3, time.Millisecond, func() error {
+		calls++
+		return fmt.Errorf("constraint violation")
+	})
+	assert.Error(t, err)
+	assert.Equal(t, 1, calls)
+}
+
+func TestOVNACLPortGroupACLID(t *testing.T) {
+	for portGroupName, expected := range map[ovn.OVNPortGroup]int64{
+		OVNACLPortGroupName(12):               12,
+		OVNACLNetworkPortGroupName(12, 3):     12,
+		OVNIntSwitchPortGroupName(12):         -1,
+		ovn.OVNPortGroup("incus_aclfoo"):      -1,
+		ovn.OVNPortGroup("incus_acl_net3"):    -1,
+		ovn.OVNPortGroup("other_incus_acl12"): -1,
+	} {
+		aclID, ok := ovnACLPortGroupACLID(portGroupName)
+		assert.Equal(t, expected, aclID, portGroupName)
+		assert.Equal(t, expected >= 0, ok, portGroupName)
+	}
+}
+
+func TestOVNNetworkRules(t *testing.T) {
+	portGroupRules := []ovn.OVNACLRule{
+		{Action: "drop", Priority: ovnACLPriorityPortGroupDrop, Match: "a", Log: true, LogName: "a"},
+		{Action: "allow-related", Priority: ovnACLPriorityPortGroupAllow, Match: "b"},
+	}
+
+	networkRules := []ovn.OVNACLRule{
+		{Action: "allow-related", Priority: ovnACLPriorityPortGroupAllow, Match: "c", Log: true, LogName: "c"},
+		{Action: "reject", Priority: ovnACLPriorityPortGroupReject, Match: "d"},
+	}
+
+	// Without overrides only the network specific rules are applied.
+	aclNet := NetworkACLUsage{ID: 1, Name: "net1", Type: "ovn", Config: map[string]string{}}
+	assert.Equal(t, networkRules, ovnNetworkRules(aclNet, portGroupRules, networkRules))
+
+	// With overrides the logged rules use the network's settings and the shared logged rules are copied.
+	aclNet.Config = map[string]string{"security.acls.logging.severity": "debug", "security.acls.logging.rate": "10"}
+	meter := string(OVNNetworkLogMeterName(1))
+
+	assert.Equal(t, []ovn.OVNACLRule{
+		{Action: "allow-related", Priority: ovnACLPriorityPortGroupAllow, Match: "c", Log: true, LogName: "c", LogLevel: "debug", LogMeter: meter},
+		{Action: "reject", Priority: ovnACLPriorityPortGroupReject, Match: "d"},
+		{Action: "drop", Priority: ovnACLPriorityPortGroupDrop + ovnACLPriorityNetworkLogOffset, Match: "a", Log: true, LogName: "a", LogLevel: "debug", LogMeter: meter},
+	}, ovnNetworkRules(aclNet, portGroupRules, networkRules))
+
+	// The original rules are left untouched.
+	assert.Empty(t, networkRules[0].LogLevel)
+	assert.Equal(t, ovnACLPriorityPortGroupDrop, portGroupRules[0].Priority)
+}
+
+func TestOVNPortGroupRulesDirectionEnabled(t *testing.T) {
+	aclInfo := &api.NetworkACL{
+		NetworkACLPost: api.NetworkACLPost{Name: "web"},
+		NetworkACLPut: api.NetworkACLPut{
+			Config: map[string]string{"egress.enabled": "false"},
+			Ingress: []api.NetworkACLRule{
+				{Action: "allow", State: "enabled", Protocol: "tcp", DestinationPort: "80"},
+			},
+			Egress: []api.NetworkACLRule{
+				{Action: "drop", State: "enabled", Destination: "192.0.2.0/24"},
+			},
+		},
+	}
+
+	matches := func() []string {
+		portGroupRules, networkRules, err := ovnPortGroupRules(&composedACL{NetworkACL: aclInfo}, "incus_acl1", nil, nil, nil)
+		require.NoError(t, err)
+		assert.Empty(t, networkRules)
+
+		matches := []string{}
+		for _, rule := range portGroupRules {
+			matches = append(matches, rule.Match)
+		}
+
+		return matches
+	}
+
+	// The egress rules are suppressed, leaving the ingress rules and the default rule.
+	assert.Equal(t, []string{
+		"(outport == @incus_acl1) && (tcp) && (tcp.dst == 80)",
+		"(inport == @incus_acl1 || outport == @incus_acl1)",
+	}, matches())
+
+	// Re-enabling the direction emits its rules again.
+	aclInfo.Config["egress.enabled"] = "true"
+	assert.Equal(t, []string{
+		"(outport == @incus_acl1) && (tcp) && (tcp.dst == 80)",
+		"(inport == @incus_acl1) && (ip4.dst == 192.0.2.0/24)",
+		"(inport == @incus_acl1 || outport == @incus_acl1)",
+	}, matches())
+
+	// Disabled directions are reported as inactive in the NIC rules.
+	aclInfo.Config = map[string]string{"ingress.enabled": "false"}
+	for _, rule := range mergeNICRules("ovn", []*composedACL{{NetworkACL: aclInfo}}, time.Now()) {
+		assert.Equal(t, rule.Direction == "egress", rule.Active, rule.Direction)
+	}
+}
+
+func TestOVNPortGroupRulesPriorityBand(t *testing.T) {
+	aclInfo := &api.NetworkACL{
+		NetworkACLPost: api.NetworkACLPost{Name: "web"},
+		NetworkACLPut: api.NetworkACLPut{
+			Config: map[string]string{},
+			Ingress: []api.NetworkACLRule{
+				{Action: "allow", State: "enabled", Protocol: "tcp", DestinationPort: "80"},
+				{Action: "drop", State: "enabled", Source: "192.0.2.0/24"},
+			},
+		},
+	}
+
+	priorities := func() []int {
+		portGroupRules, _, err := ovnPortGroupRules(&composedACL{NetworkACL: aclInfo}, "incus_acl1", nil, nil, nil)
+		require.NoError(t, err)
+
+		priorities := []int{}
+		for _, rule := range portGroupRules {
+			priorities = append(priorities, rule.Priority)
+		}
+
+		return priorities
+	}
+
+	// Rules use the default band, while the default rule keeps the lowest priority.
+	defaultPriorities := priorities()
+	assert.Equal(t, []int{ovnACLPriorityPortGroupAllow + 4000, ovnACLPriorityPortGroupDrop + 4000, ovnACLPriorityPortGroupDefaultAction}, defaultPriorities)
+
+	// Any rule of a lower band is evaluated before all the rules of the default band.
+	aclInfo.Config["priority.band"] = "0"
+	assert.Greater(t, priorities()[0], defaultPriorities[1])
+
+	// The highest band keeps the port group priorities.
+	aclInfo.Config["priority.band"] = "9"
+	assert.Equal(t, []int{ovnACLPriorityPortGroupAllow, ovnACLPriorityPortGroupDrop, ovnACLPriorityPortGroupDefaultAction}, priorities())
+}
+
+func TestOVNPortGroupRulesLogRate(t *testing.T) {
+	aclInfo := &api.NetworkACL{
+		NetworkACLPost: api.NetworkACLPost{Name: "web"},
+		NetworkACLPut: api.NetworkACLPut{
+			Config: map[string]string{"ingress.log_rate": "10"},
+			Ingress: []api.NetworkACLRule{
+				{Action: "allow", State: "logged", Protocol: "tcp", DestinationPort: "80"},
+				{Action: "allow", State: "enabled", Protocol: "tcp", DestinationPort: "443"},
+			},
+			Egress: []api.NetworkACLRule{
+				{Action: "drop", State: "logged", Destination: "192.0.2.0/24"},
+			},
+		},
+	}
+
+	portGroupRules, _, err := ovnPortGroupRules(&composedACL{NetworkACL: aclInfo}, "incus_acl1", nil, nil, nil)
+	require.NoError(t, err)
+	require.Len(t, portGroupRules, 4)
+
+	// Only the logged rules of the direction with a log rate use its meter, the others are unthrottled.
+	assert.Equal(t, "incus_acl1-ingress-log", portGroupRules[0].LogMeter)
+	assert.Empty(t, portGroupRules[1].LogMeter)
+	assert.True(t, portGroupRules[2].Log)
+	assert.Empty(t, portGroupRules[2].LogMeter)
+
+	// The rate of the network takes precedence, while the ACL's is kept when the network only sets the severity.
+	aclNet := NetworkACLUsage{ID: 1, Name: "net1", Type: "ovn", Config: map[string]string{"security.acls.logging.severity": "debug"}}
+	networkRules := ovnNetworkRules(aclNet, portGroupRules, nil)
+	assert.Equal(t, "incus_acl1-ingress-log", networkRules[0].LogMeter)
+
+	aclNet.Config["security.acls.logging.rate"] = "5"
+	networkRules = ovnNetworkRules(aclNet, portGroupRules, nil)
+	assert.Equal(t, string(OVNNetworkLogMeterName(1)), networkRules[0].LogMeter)
+}
+
+func TestOVNValidateACLs(t *testing.T) {
+	s, cleanup := state.NewTestState(t)
+	defer cleanup()
+
+	aclNameIDs := map[string]int64{}
+	err := s.DB.Cluster.Transaction(context.Background(), func(ctx context.Context, tx *db.ClusterTx) error {
+		acls := map[string]api.NetworkACLPut{
+			"web": {Ingress: []api.NetworkACLRule{{Action: "allow", State: "enabled", Source: "app,@internal", Protocol: "tcp", DestinationPort: "80"}}},
+			"app": {},
+			"bad": {Egress: []api.NetworkACLRule{{Action: "allow", State: "enabled", Destination: "missing"}}},
+		}
+
+		for name, put := range acls {
+			id, err := tx.CreateNetworkACL(ctx, api.ProjectDefaultName, &api.NetworkACLsPost{NetworkACLPost: api.NetworkACLPost{Name: name}, NetworkACLPut: put})
+			if err != nil {
+				return err
+			}
+
+			aclNameIDs[name] = id
+		}
+
+		return nil
+	})
+	require.NoError(t, err)
+
+	aclNets := map[string]NetworkACLUsage{
+		"net2": {ID: 2, Name: "net2", Type: "ovn", Config: map[string]string{}},
+		"net1": {ID: 1, Name: "net1", Type: "ovn", Config: map[string]string{}},
+	}
+
+	// No OVN client is taken, so the translation can't change anything in OVN.
+	defs, err := OVNValidateACLs(s, api.ProjectDefaultName, aclNameIDs, aclNets, []string{"web"})
+	require.NoError(t, err)
+	require.Len(t, defs, 1)
+
+	def := defs[0]
+	webPortGroup := OVNACLPortGroupName(aclNameIDs["web"])
+	assert.Equal(t, "web", def.ACL)
+	assert.Equal(t, webPortGroup, def.PortGroup)
+	require.Len(t, def.Networks, 2)
+	assert.Equal(t, "net1", def.Networks[0].Network)
+	assert.Equal(t, "net2", def.Networks[1].Network)
+
+	// The rule referencing @internal is network specific, the default rule isn't.
+	require.Len(t, def.Rules, 1)
+	assert.Equal(t, fmt.Sprintf("(inport == @%s || outport == @%s)", webPortGroup, webPortGroup), def.Rules[0].Match)
+
+	netDef := def.Networks[0]
+	assert.Equal(t, OVNACLNetworkPortGroupName(aclNameIDs["web"], 1), netDef.PortGroup)
+	assert.Equal(t, OVNIntSwitchName(1), netDef.Switch)
+	assert.Equal(t, ovnNetworkPortGroupMatchReplace(1), netDef.MatchReplace)
+	require.Len(t, netDef.Rules, 1)
+	assert.Equal(t, fmt.Sprintf("(outport == @%s) && (inport == @%s || inport == @internal) && (tcp) && (tcp.dst == 80)", webPortGroup, OVNACLPortGroupName(aclNameIDs["app"])), netDef.Rules[0].Match)
+	assert.Len(t, netDef.QoSRules, 2)
+
+	// Translation errors are still reported.
+	_, err = OVNValidateACLs(s, api.ProjectDefaultName, aclNameIDs, aclNets, []string{"web", "bad"})
+	assert.ErrorContains(t, err, `Cannot find security ACL ID for "missing"`)
+
+	_, err = OVNValidateACLs(s, api.ProjectDefaultName, aclNameIDs, aclNets, []string{"unknown"})
+	assert.EqualError(t, err, `Cannot find security ACL ID for "unknown"`)
+}
+
+func TestOVNEnsureACLsParentReferences(t *testing.T) {
+	s, cleanup := state.NewTestState(t)
+	defer cleanup()
+
+	client, err := ovn.ConnectNB(ovntest.NewNBServer(t), "", "", "")
+	require.NoError(t, err)
+
+	aclNameIDs := map[string]int64{}
+	err = s.DB.Cluster.Transaction(context.Background(), func(ctx context.Context, tx *db.ClusterTx) error {
+		acls := map[string]api.NetworkACLPut{
+			"web":    {},
+			"parent": {},
+			"child":  {Config: map[string]string{"parent": "parent"}},
+		}
+
+		for name, put := range acls {
+			id, err := tx.CreateNetworkACL(ctx, api.ProjectDefaultName, &api.NetworkACLsPost{NetworkACLPost: api.NetworkACLPost{Name: name}, NetworkACLPut: put})
+			if err != nil {
+				return err
+			}
+
+			aclNameIDs[name] = id
+		}
+
+		return nil
+	})
+	require.NoError(t, err)
+
+	_, err = OVNEnsureACLs(s, logger.Log, client, api.ProjectDefaultName, aclNameIDs, nil, []string{"parent", "child"}, false)
+	require.NoError(t, err)
+
+	// The parent gains a rule referencing an ACL without a port group, which the child inherits.
+	err = s.DB.Cluster.Transaction(context.Background(), func(ctx context.Context, tx *db.ClusterTx) error {
+		return tx.UpdateNetworkACL(ctx, aclNameIDs["parent"], &api.NetworkACLPut{
+			Ingress: []api.NetworkACLRule{{Action: "allow", State: "enabled", Source: "web"}},
+		})
+	})
+	require.NoError(t, err)
+
+	// Both ACLs are reapplied in a single transaction, as when updating the parent, and the referenced port group
+	// is only created once.
+	txn := client.NewTransaction()
+	for _, aclName := range []string{"parent", "child"} {
+		_, err = ovnEnsureACLs(s, logger.Log, client, txn, api.ProjectDefaultName, aclNameIDs, nil, []string{aclName}, true)
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, txn.Commit(context.Background()))
+
+	portGroupUUID, _, err := client.GetPortGroupInfo(context.Background(), OVNACLPortGroupName(aclNameIDs["web"]))
+	require.NoError(t, err)
+	assert.NotEmpty(t, portGroupUUID)
+}
+
+func TestOVNApplyNetworkLoggingParentRules(t *testing.T) {
+	s, cleanup := state.NewTestState(t)
+	defer cleanup()
+
+	client, err := ovn.ConnectNB(ovntest.NewNBServer(t), "", "", "")
+	require.NoError(t, err)
+
+	aclNameIDs := map[string]int64{}
+	err = s.DB.Cluster.Transaction(context.Background(), func(ctx context.Context, tx *db.ClusterTx) error {
+		acls := map[string]api.NetworkACLPut{
+			"parent": {Ingress: []api.NetworkACLRule{{Action: "allow", State: "logged", Source: "@internal", Protocol: "tcp", DestinationPort: "22"}}},
+			"child":  {Config: map[string]string{"parent": "parent"}, Ingress: []api.NetworkACLRule{{Action: "allow", State: "logged", Protocol: "tcp", DestinationPort: "80"}}},
+		}
+
+		for name, put := range acls {
+			id, err := tx.CreateNetworkACL(ctx, api.ProjectDefaultName, &api.NetworkACLsPost{NetworkACLPost: api.NetworkACLPost{Name: name}, NetworkACLPut: put})
+			if err != nil {
+				return err
+			}
+
+			aclNameIDs[name] = id
+		}
+
+		return nil
+	})
+	require.NoError(t, err)
+
+	aclNet := NetworkACLUsage{ID: 1, Name: "net1", Type: "ovn", Config: map[string]string{"security.acls.logging.level": "debug"}}
+
+	// Set up the port groups of the child, including the one for the network.
+	_, err = OVNEnsureACLs(s, logger.Log, client, api.ProjectDefaultName, aclNameIDs, nil, []string{"child"}, false)
+	require.NoError(t, err)
+
+	txn := client.NewTransaction()
+	require.NoError(t, txn.CreatePortGroup(context.Background(), 1, OVNACLNetworkPortGroupName(aclNameIDs["child"], aclNet.ID), OVNACLPortGroupName(aclNameIDs["child"]), OVNIntSwitchName(aclNet.ID)))
+	require.NoError(t, txn.Commit(context.Background()))
+
+	// The network port group gets the network specific rule inherited from the parent.
+	err = OVNApplyNetworkLogging(s, logger.Log, client, api.ProjectDefaultName, aclNet)
+	require.NoError(t, err)
+
+	var childInfo *api.NetworkACL
+	err = s.DB.Cluster.Transaction(context.Background(), func(ctx context.Context, tx *db.ClusterTx) error {
+		_, childInfo, err = tx.GetNetworkACL(ctx, api.ProjectDefaultName, "child")
+
+		return err
+	})
+	require.NoError(t, err)
+
+	states, err := OVNACLState(s, client, api.ProjectDefaultName, childInfo, aclNameIDs, map[string]NetworkACLUsage{aclNet.Name: aclNet})
+	require.NoError(t, err)
+	assert.Equal(t, ovnACLStateSynced, states[aclNet.Name].Status, states[aclNet.Name].Detail)
+}
+
+func TestOVNParseLogEntry(t *testing.T) {
+	line := `2024-01-08T15:56:47.418Z|00009|acl_log(ovn_pinctrl0)|INFO|name="incus_acl1-egress-2", verdict=drop, severity=info, direction=from-lport: tcp,vlan_tci=0x0000,dl_src=00:16:3e:00:00:01,dl_dst=00:16:3e:00:00:02,nw_src=10.0.0.2,nw_dst=192.0.2.1,nw_tos=0,nw_ecn=0,nw_ttl=64,tp_src=50000,tp_dst=80,tcp_flags=syn`
+
+	entry := ovnParseLogEntry(line, "incus_acl1-")
+	require.NotNil(t, entry)
+	assert.Equal(t, &ovnLogEntry{
+		Time:      "2024-01-08T15:56:47Z",
+		Direction: "egress",
+		Rule:      2,
+		Label:     "incus_acl1-egress-2",
+		Proto:     "tcp",
+		Src:       "10.0.0.2",
+		Dst:       "192.0.2.1",
+		SrcPort:   "50000",
+		DstPort:   "80",
+		Action:    "drop",
+	}, entry)
+
+	// The rules inherited from the parents of the ACL are labelled separately.
+	entry = ovnParseLogEntry(strings.Replace(line, "incus_acl1-egress-2", "incus_acl1-egress-i0", 1), "incus_acl1-")
+	require.NotNil(t, entry)
+	assert.Equal(t, 0, entry.Rule)
+	assert.True(t, entry.Inherited)
+
+	// Entries of other ACLs, ACL IDs sharing a prefix and non-ACL lines are skipped.
+	assert.Nil(t, ovnParseLogEntry(line, "incus_acl2-"))
+	assert.Nil(t, ovnParseLogEntry(strings.Replace(line, "incus_acl1-", "incus_acl12-", 1), "incus_acl1-"))
+	assert.Nil(t, ovnParseLogEntry("2024-01-08T15:56:47.418Z|00010|binding|INFO|Claiming lport", "incus_acl1-"))
+}
+
+func TestOVNEnsureACLsSchedule(t *testing.T) {
+	s, cleanup := state.NewTestState(t)
+	defer cleanup()
+
+	client, err := ovn.ConnectNB(ovntest.NewNBServer(t), "", "", "")
+	require.NoError(t, err)
+
+	now := time.Now().UTC()
+
+	var aclID int64
+	err = s.DB.Cluster.Transaction(context.Background(), func(ctx context.Context, tx *db.ClusterTx) error {
+		aclID, err = tx.CreateNetworkACL(ctx, api.ProjectDefaultName, &api.NetworkACLsPost{
+			NetworkACLPost: api.NetworkACLPost{Name: "maintenance"},
+			NetworkACLPut: api.NetworkACLPut{
+				Ingress: []api.NetworkACLRule{
+					{Action: "allow", State: "enabled", Protocol: "tcp", DestinationPort: "22", ValidUntil: now.Add(-time.Hour).Format(time.RFC3339)},
+					{Action: "allow", State: "enabled", Protocol: "tcp", DestinationPort: "80", ValidFrom: now.Add(-time.Hour).Format(time.RFC3339), ValidUntil: now.Add(time.Hour).Format(time.RFC3339)},
+					{Action: "allow", State: "enabled", Protocol: "tcp", DestinationPort: "443", ValidFrom: now.Add(time.Hour).Format(time.RFC3339)},
+				},
+			},
+		})
+
+		return err
+	})
+	require.NoError(t, err)
+
+	_, err = OVNEnsureACLs(s, logger.Log, client, api.ProjectDefaultName, map[string]int64{"maintenance": aclID}, nil, []string{"maintenance"}, false)
+	require.NoError(t, err)
+
+	// Only the rule within its validity window is applied, the expired and future rules are kept out of OVN.
+	rules, err := client.GetPortGroupACLRules(context.Background(), OVNACLPortGroupName(aclID))
+	require.NoError(t, err)
+
+	matches := []string{}
+	for _, rule := range rules {
+		if strings.Contains(rule.Match, "tcp.dst") {
+			matches = append(matches, rule.Match)
+		}
+	}
+
+	require.Len(t, matches, 1)
+	assert.Contains(t, matches[0], "tcp.dst == 80")
+}
+
+func TestOVNACLObjects(t *testing.T) {
+	s, cleanup := state.NewTestState(t)
+	defer cleanup()
+
+	client, err := ovn.ConnectNB(ovntest.NewNBServer(t), "", "", "")
+	require.NoError(t, err)
+
+	rule := api.NetworkACLRule{Action: "allow", State: "enabled", Protocol: "tcp", DestinationPort: "80"}
+
+	var aclID int64
+	err = s.DB.Cluster.Transaction(context.Background(), func(ctx context.Context, tx *db.ClusterTx) error {
+		aclID, err = tx.CreateNetworkACL(ctx, api.ProjectDefaultName, &api.NetworkACLsPost{
+			NetworkACLPost: api.NetworkACLPost{Name: "web"},
+			NetworkACLPut:  api.NetworkACLPut{Ingress: []api.NetworkACLRule{rule}},
+		})
+
+		return err
+	})
+	require.NoError(t, err)
+
+	_, err = OVNEnsureACLs(s, logger.Log, client, api.ProjectDefaultName, map[string]int64{"web": aclID}, nil, []string{"web"}, false)
+	require.NoError(t, err)
+
+	// The network's port group doesn't exist yet, which isn't an error.
+	objects, netErrors := OVNACLObjects(client, aclID, map[string]NetworkACLUsage{"net1": {ID: 1, Name: "net1", Type: "ovn"}})
+	assert.Empty(t, netErrors)
+	assert.Equal(t, string(OVNACLPortGroupName(aclID)), objects.PortGroup)
+	assert.Equal(t, map[string]string{"net1": string(OVNACLNetworkPortGroupName(aclID, 1))}, objects.NetworkPortGroups)
+	assert.Len(t, objects.RuleACLs[ruleID(ruleDirectionIngress, rule)], 1)
+}
+
+func TestOVNReconcile(t *testing.T) {
+	s, cleanup := state.NewTestState(t)
+	defer cleanup()
+
+	client, err := ovn.ConnectNB(ovntest.NewNBServer(t), "", "", "")
+	require.NoError(t, err)
+
+	var aclID, netID int64
+	err = s.DB.Cluster.Transaction(context.Background(), func(ctx context.Context, tx *db.ClusterTx) error {
+		aclID, err = tx.CreateNetworkACL(ctx, api.ProjectDefaultName, &api.NetworkACLsPost{
+			NetworkACLPost: api.NetworkACLPost{Name: "web"},
+			NetworkACLPut:  api.NetworkACLPut{Ingress: []api.NetworkACLRule{{Action: "allow", State: "enabled", Protocol: "tcp", DestinationPort: "80"}}},
+		})
+		if err != nil {
+			return err
+		}
+
+		netID, err = tx.CreateNetwork(ctx, api.ProjectDefaultName, "net1", "", db.NetworkTypeOVN, map[string]string{})
+		if err != nil {
+			return err
+		}
+
+		instID, err := cluster.CreateInstance(ctx, tx.Tx(), cluster.Instance{Project: api.ProjectDefaultName, Name: "c1", Node: "none", Type: instancetype.Container, Architecture: 1})
+		if err != nil {
+			return err
+		}
+
+		err = cluster.CreateInstanceConfig(ctx, tx.Tx(), instID, map[string]string{"volatile.uuid": "uuid1"})
+		if err != nil {
+			return err
+		}
+
+		return cluster.CreateInstanceDevices(ctx, tx.Tx(), instID, map[string]cluster.Device{
+			"eth0": {Name: "eth0", Type: cluster.TypeNIC, Config: map[string]string{"network": "net1", "security.acls": "web"}},
+		})
+	})
+	require.NoError(t, err)
+
+	// The NIC's port exists in OVN, but none of the ACL's port groups do.
+	portName := OVNIntSwitchInstancePortName(netID, "uuid1", "eth0")
+	require.NoError(t, client.CreateLogicalSwitch(context.Background(), OVNIntSwitchName(netID), false))
+	require.NoError(t, client.CreateLogicalSwitchPort(context.Background(), OVNIntSwitchName(netID), portName, &ovn.OVNSwitchPortOpts{}, false))
+
+	portGroupName := OVNACLPortGroupName(aclID)
+	networkPortGroupName := OVNACLNetworkPortGroupName(aclID, netID)
+
+	// A dry run only reports the missing port groups.
+	drift, err := OVNReconcile(s, logger.Log, client, true)
+	require.NoError(t, err)
+	assert.Equal(t, []string{string(portGroupName), string(networkPortGroupName)}, drift.MissingPortGroups)
+
+	portGroupUUID, _, err := client.GetPortGroupInfo(context.Background(), portGroupName)
+	require.NoError(t, err)
+	assert.Empty(t, portGroupUUID)
+
+	// The repair recreates the port groups and adds the NIC's port back to the ACL port group.
+	drift, err = OVNReconcile(s, logger.Log, client, false)
+	require.NoError(t, err)
+	assert.Len(t, drift.MissingPortGroups, 2)
+
+	portGroups, err := client.GetPortGroupsByPrefix(context.Background(), string(portGroupName))
+	require.NoError(t, err)
+	assert.Equal(t, 1, portGroups[portGroupName])
+	assert.Contains(t, portGroups, networkPortGroupName)
+
+	// Nothing is left to repair.
+	drift, err = OVNReconcile(s, logger.Log, client, true)
+	require.NoError(t, err)
+	assert.True(t, drift.Empty())
+}
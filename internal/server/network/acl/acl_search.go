@@ -0,0 +1,245 @@
+package acl
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"slices"
+	"strings"
+
+	"github.com/lxc/incus/v6/internal/server/db"
+	"github.com/lxc/incus/v6/internal/server/state"
+	"github.com/lxc/incus/v6/shared/api"
+	"github.com/lxc/incus/v6/shared/util"
+)
+
+// Define the ways a rule subject can relate to a searched subject.
+const (
+	ruleMatchEqual     = "equal"
+	ruleMatchContains  = "contains"
+	ruleMatchContained = "contained"
+	ruleMatchOverlaps  = "overlaps"
+)
+
+// SearchRules returns the rules of the ACL whose description contains the search string, ignoring case.
+// Ingress rules are returned first, each direction in the order of its rules.
+func SearchRules(aclInfo *api.NetworkACL, search string) []api.NetworkACLRuleSearchResult {
+	search = strings.ToLower(search)
+	results := []api.NetworkACLRuleSearchResult{}
+
+	for _, direction := range []ruleDirection{ruleDirectionIngress, ruleDirectionEgress} {
+		rules := aclInfo.Ingress
+		if direction == ruleDirectionEgress {
+			rules = aclInfo.Egress
+		}
+
+		for i, rule := range rules {
+			if !strings.Contains(strings.ToLower(rule.Description), search) {
+				continue
+			}
+
+			results = append(results, api.NetworkACLRuleSearchResult{
+				Project:     aclInfo.Project,
+				ACL:         aclInfo.Name,
+				Direction:   string(direction),
+				Index:       i,
+				Description: rule.Description,
+			})
+		}
+	}
+
+	return results
+}
+
+// SearchMatches returns whether the description of the ACL or of one of its rules contains the search string,
+// ignoring case.
+func SearchMatches(aclInfo *api.NetworkACL, search string) bool {
+	if strings.Contains(strings.ToLower(aclInfo.Description), strings.ToLower(search)) {
+		return true
+	}
+
+	return len(SearchRules(aclInfo, search)) > 0
+}
+
+// ruleSubjectAddressRange returns the first and last addresses matched by an IP address, CIDR or IP range subject,
+// or by the shorthand and family subjects matching all the addresses of a family. IPv4-mapped IPv6 addresses are
+// treated as IPv4 addresses, as they are when applying the rules.
+// Returns false for the other subjects, and for the "any" subject which matches both families.
+func ruleSubjectAddressRange(subject string) (netip.Addr, netip.Addr, bool) {
+	switch {
+	case slices.Contains(ruleSubjectIPv4Aliases, subject):
+		subject = "0.0.0.0/0"
+	case slices.Contains(ruleSubjectIPv6Aliases, subject):
+		subject = "::/0"
+	}
+
+	cidrs, found := ruleSubjectAny[subject]
+	if found && len(cidrs) == 1 {
+		subject = cidrs[0]
+	}
+
+	start, end, isRange := strings.Cut(subject, "-")
+	if isRange {
+		startAddr, err := netip.ParseAddr(start)
+		if err != nil {
+			return netip.Addr{}, netip.Addr{}, false
+		}
+
+		endAddr, err := netip.ParseAddr(end)
+		if err != nil {
+			return netip.Addr{}, netip.Addr{}, false
+		}
+
+		startAddr = startAddr.Unmap()
+		endAddr = endAddr.Unmap()
+		if startAddr.Is4() != endAddr.Is4() || startAddr.Compare(endAddr) > 0 {
+			return netip.Addr{}, netip.Addr{}, false
+		}
+
+		return startAddr, endAddr, true
+	}
+
+	prefix, err := netip.ParsePrefix(subject)
+	if err == nil {
+		prefix = prefix.Masked()
+
+		// Fill in the host bits of the network address to get its last address.
+		last := prefix.Addr().AsSlice()
+		for i := prefix.Bits(); i < len(last)*8; i++ {
+			last[i/8] |= 0x80 >> (i % 8)
+		}
+
+		lastAddr, _ := netip.AddrFromSlice(last)
+
+		if prefix.Addr().Is4In6() && prefix.Bits() >= 96 {
+			return prefix.Addr().Unmap(), lastAddr.Unmap(), true
+		}
+
+		return prefix.Addr(), lastAddr, true
+	}
+
+	addr, err := netip.ParseAddr(subject)
+	if err == nil {
+		// Zoned addresses match the same traffic as the address alone.
+		addr = addr.Unmap().WithZone("")
+
+		return addr, addr, true
+	}
+
+	return netip.Addr{}, netip.Addr{}, false
+}
+
+// ruleSubjectRelation returns how a rule subject relates to the searched subject. Address subjects are compared
+// by the addresses they match, other subjects (such as ACL and host names) only match themselves.
+// Returns an empty string if they don't match.
+func ruleSubjectRelation(ruleSubject string, search string) string {
+	searchStart, searchEnd, searchIsAddress := ruleSubjectAddressRange(search)
+	if !searchIsAddress {
+		if ruleSubject == search {
+			return ruleMatchEqual
+		}
+
+		return ""
+	}
+
+	// The "any" subject matches both families so is checked against each of them.
+	ruleSubjects := []string{ruleSubject}
+	cidrs, found := ruleSubjectAny[ruleSubject]
+	if found {
+		ruleSubjects = cidrs
+	}
+
+	for _, subject := range ruleSubjects {
+		start, end, isAddress := ruleSubjectAddressRange(subject)
+		if !isAddress || start.Is4() != searchStart.Is4() {
+			continue
+		}
+
+		switch {
+		case start == searchStart && end == searchEnd:
+			return ruleMatchEqual
+		case start.Compare(searchStart) <= 0 && end.Compare(searchEnd) >= 0:
+			return ruleMatchContains
+		case start.Compare(searchStart) >= 0 && end.Compare(searchEnd) <= 0:
+			return ruleMatchContained
+		case start.Compare(searchEnd) <= 0 && end.Compare(searchStart) >= 0:
+			return ruleMatchOverlaps
+		}
+	}
+
+	return ""
+}
+
+// SearchRulesBySubject returns the rules of the ACL with a source or destination subject equal to, containing,
+// contained in or overlapping with the searched subject. Address subjects (IP addresses, CIDRs and ranges) are
+// compared by the addresses they match, while named subjects only match the same name.
+// Ingress rules are returned first, each direction in the order of its rules and each rule source first.
+func SearchRulesBySubject(aclInfo *api.NetworkACL, subject string) []api.NetworkACLRuleMatch {
+	subject = strings.TrimSpace(subject)
+	results := []api.NetworkACLRuleMatch{}
+
+	for _, direction := range []ruleDirection{ruleDirectionIngress, ruleDirectionEgress} {
+		rules := aclInfo.Ingress
+		if direction == ruleDirectionEgress {
+			rules = aclInfo.Egress
+		}
+
+		for i, rule := range rules {
+			for _, field := range []string{"source", "destination"} {
+				subjects := rule.Source
+				if field == "destination" {
+					subjects = rule.Destination
+				}
+
+				for _, ruleSubject := range util.SplitNTrimSpace(subjects, ",", -1, true) {
+					relation := ruleSubjectRelation(ruleSubject, subject)
+					if relation == "" {
+						continue
+					}
+
+					results = append(results, api.NetworkACLRuleMatch{
+						Project:   aclInfo.Project,
+						ACL:       aclInfo.Name,
+						Direction: string(direction),
+						Index:     i,
+						Field:     field,
+						Subject:   ruleSubject,
+						Relation:  relation,
+					})
+				}
+			}
+		}
+	}
+
+	return results
+}
+
+// FindRulesBySubject returns the rules of all the ACLs in the project with a subject matching the searched subject
+// as described in SearchRulesBySubject. The ACLs are returned in the order they were created in.
+func FindRulesBySubject(s *state.State, projectName string, subject string) ([]api.NetworkACLRuleMatch, error) {
+	results := []api.NetworkACLRuleMatch{}
+
+	err := s.DB.Cluster.Transaction(context.TODO(), func(ctx context.Context, tx *db.ClusterTx) error {
+		aclNames, err := tx.GetNetworkACLs(ctx, projectName)
+		if err != nil {
+			return err
+		}
+
+		for _, aclName := range aclNames {
+			_, aclInfo, err := tx.GetNetworkACL(ctx, projectName, aclName)
+			if err != nil {
+				return fmt.Errorf("Failed loading network ACL %q: %w", aclName, err)
+			}
+
+			aclInfo.Project = projectName
+			results = append(results, SearchRulesBySubject(aclInfo, subject)...)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
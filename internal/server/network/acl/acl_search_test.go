@@ -0,0 +1,156 @@
+package acl
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lxc/incus/v6/internal/server/db"
+	"github.com/lxc/incus/v6/internal/server/state"
+	"github.com/lxc/incus/v6/shared/api"
+)
+
+func TestSearchRules(t *testing.T) {
+	aclInfo := &api.NetworkACL{
+		NetworkACLPost: api.NetworkACLPost{Name: "web"},
+		NetworkACLPut: api.NetworkACLPut{
+			Ingress: []api.NetworkACLRule{
+				{Action: "allow", Description: "HTTP (SEC-123)"},
+				{Action: "allow", Description: "HTTPS"},
+				{Action: "drop", Description: "Scanners, see sec-123"},
+			},
+			Egress: []api.NetworkACLRule{
+				{Action: "allow", Description: "DNS [SEC-123]"},
+			},
+		},
+		Project: "default",
+	}
+
+	results := SearchRules(aclInfo, "SEC-123")
+	assert.Equal(t, []api.NetworkACLRuleSearchResult{
+		{Project: "default", ACL: "web", Direction: "ingress", Index: 0, Description: "HTTP (SEC-123)"},
+		{Project: "default", ACL: "web", Direction: "ingress", Index: 2, Description: "Scanners, see sec-123"},
+		{Project: "default", ACL: "web", Direction: "egress", Index: 0, Description: "DNS [SEC-123]"},
+	}, results)
+
+	assert.Equal(t, []api.NetworkACLRuleSearchResult{}, SearchRules(aclInfo, "SEC-456"))
+}
+
+func TestRuleSubjectRelation(t *testing.T) {
+	tests := []struct {
+		ruleSubject string
+		search      string
+		relation    string
+	}{
+		{"10.40.0.0/16", "10.40.0.0/16", "equal"},
+		{"10.40.0.0/16", "10.40.1.2/16", "equal"},
+		{"10.40.0.1", "10.40.0.1/32", "equal"},
+		{"10.40.0.0-10.40.255.255", "10.40.0.0/16", "equal"},
+		{"10.0.0.0/8", "10.40.0.0/16", "contains"},
+		{"any", "10.40.0.0/16", "contains"},
+		{"any4", "10.40.0.0/16", "contains"},
+		{"@ipv4", "10.40.0.0/16", "contains"},
+		{"10.40.3.0/24", "10.40.0.0/16", "contained"},
+		{"10.40.0.1", "10.40.0.0/16", "contained"},
+		{"10.40.0.100-10.40.0.200", "10.40.0.0/16", "contained"},
+		{"::ffff:10.40.0.1", "10.40.0.0/16", "contained"},
+		{"10.39.255.0-10.40.0.10", "10.40.0.0/16", "overlaps"},
+		{"10.41.0.0/16", "10.40.0.0/16", ""},
+		{"any6", "10.40.0.0/16", ""},
+		{"2001:db8::/32", "2001:db8:1::1", "contains"},
+		{"2001:db8::/32", "10.40.0.0/16", ""},
+		{"web", "web", "equal"},
+		{"web", "Web", ""},
+		{"@ovn1/peer1", "@ovn1/peer1", "equal"},
+		{"api.example.com", "api.example.com", "equal"},
+		{"web", "10.40.0.0/16", ""},
+		{"10.40.0.0/16", "web", ""},
+		{"@internal", "10.40.0.0/16", ""},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.relation, ruleSubjectRelation(test.ruleSubject, test.search), "%s vs %s", test.ruleSubject, test.search)
+	}
+}
+
+func TestSearchRulesBySubject(t *testing.T) {
+	aclInfo := &api.NetworkACL{
+		NetworkACLPost: api.NetworkACLPost{Name: "web"},
+		NetworkACLPut: api.NetworkACLPut{
+			Ingress: []api.NetworkACLRule{
+				{Action: "allow", Source: "10.40.1.0/24,192.0.2.1", Destination: "10.0.0.0/8"},
+				{Action: "allow", Source: "app"},
+			},
+			Egress: []api.NetworkACLRule{
+				{Action: "drop", Destination: "10.41.0.0/16"},
+				{Action: "allow", Source: "10.40.0.0/16", Destination: "app"},
+			},
+		},
+		Project: "default",
+	}
+
+	assert.Equal(t, []api.NetworkACLRuleMatch{
+		{Project: "default", ACL: "web", Direction: "ingress", Index: 0, Field: "source", Subject: "10.40.1.0/24", Relation: "contained"},
+		{Project: "default", ACL: "web", Direction: "ingress", Index: 0, Field: "destination", Subject: "10.0.0.0/8", Relation: "contains"},
+		{Project: "default", ACL: "web", Direction: "egress", Index: 1, Field: "source", Subject: "10.40.0.0/16", Relation: "equal"},
+	}, SearchRulesBySubject(aclInfo, "10.40.0.0/16"))
+
+	// Named subjects only match by name.
+	assert.Equal(t, []api.NetworkACLRuleMatch{
+		{Project: "default", ACL: "web", Direction: "ingress", Index: 1, Field: "source", Subject: "app", Relation: "equal"},
+		{Project: "default", ACL: "web", Direction: "egress", Index: 1, Field: "destination", Subject: "app", Relation: "equal"},
+	}, SearchRulesBySubject(aclInfo, "app"))
+
+	assert.Equal(t, []api.NetworkACLRuleMatch{}, SearchRulesBySubject(aclInfo, "2001:db8::/32"))
+}
+
+func TestFindRulesBySubject(t *testing.T) {
+	s, cleanup := state.NewTestState(t)
+	defer cleanup()
+
+	err := s.DB.Cluster.Transaction(context.Background(), func(ctx context.Context, tx *db.ClusterTx) error {
+		acls := []api.NetworkACLsPost{
+			{NetworkACLPost: api.NetworkACLPost{Name: "web"}, NetworkACLPut: api.NetworkACLPut{Ingress: []api.NetworkACLRule{{Action: "allow", State: "enabled", Source: "10.40.0.0/24"}}}},
+			{NetworkACLPost: api.NetworkACLPost{Name: "db"}, NetworkACLPut: api.NetworkACLPut{Egress: []api.NetworkACLRule{{Action: "allow", State: "enabled", Destination: "192.0.2.0/24"}}}},
+			{NetworkACLPost: api.NetworkACLPost{Name: "app"}, NetworkACLPut: api.NetworkACLPut{Egress: []api.NetworkACLRule{{Action: "drop", State: "enabled", Destination: "10.0.0.0/8"}}}},
+		}
+
+		for _, acl := range acls {
+			_, err := tx.CreateNetworkACL(ctx, api.ProjectDefaultName, &acl)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	require.NoError(t, err)
+
+	// All the ACLs of the project are searched.
+	matches, err := FindRulesBySubject(s, api.ProjectDefaultName, "10.40.0.0/16")
+	require.NoError(t, err)
+	assert.Equal(t, []api.NetworkACLRuleMatch{
+		{Project: api.ProjectDefaultName, ACL: "web", Direction: "ingress", Index: 0, Field: "source", Subject: "10.40.0.0/24", Relation: "contained"},
+		{Project: api.ProjectDefaultName, ACL: "app", Direction: "egress", Index: 0, Field: "destination", Subject: "10.0.0.0/8", Relation: "contains"},
+	}, matches)
+
+	matches, err = FindRulesBySubject(s, api.ProjectDefaultName, "198.51.100.1")
+	require.NoError(t, err)
+	assert.Empty(t, matches)
+}
+
+func TestSearchMatches(t *testing.T) {
+	aclInfo := &api.NetworkACL{
+		NetworkACLPost: api.NetworkACLPost{Name: "web"},
+		NetworkACLPut: api.NetworkACLPut{
+			Description: "Web servers (SEC-123)",
+			Ingress:     []api.NetworkACLRule{{Action: "allow", Description: "HTTPS (SEC-456)"}},
+		},
+	}
+
+	assert.True(t, SearchMatches(aclInfo, "sec-123"))
+	assert.True(t, SearchMatches(aclInfo, "SEC-456"))
+	assert.False(t, SearchMatches(aclInfo, "SEC-789"))
+}
@@ -0,0 +1,216 @@
+package acl
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/netip"
+	"slices"
+	"strconv"
+
+	"github.com/lxc/incus/v6/internal/server/db"
+	"github.com/lxc/incus/v6/internal/server/project"
+	"github.com/lxc/incus/v6/internal/server/response"
+	"github.com/lxc/incus/v6/internal/server/state"
+	"github.com/lxc/incus/v6/shared/api"
+	"github.com/lxc/incus/v6/shared/util"
+)
+
+// Packet represents the attributes of a packet checked against the rules of a network ACL by SimulatePacket.
+type Packet struct {
+	Source          string // Source IP address.
+	Destination     string // Destination IP address.
+	Protocol        string // Protocol (tcp, udp, icmp4 or icmp6).
+	SourcePort      int    // Source port (tcp and udp only).
+	DestinationPort int    // Destination port (tcp and udp only).
+	ICMPType        int    // ICMP message type (icmp4 and icmp6 only).
+	ICMPCode        int    // ICMP message code (icmp4 and icmp6 only).
+}
+
+// validatePacket checks that the packet has addresses of the same family and a protocol and ports that can be
+// matched by rules.
+func validatePacket(pkt Packet) error {
+	src, err := netip.ParseAddr(pkt.Source)
+	if err != nil {
+		return fmt.Errorf("Invalid source address %q", pkt.Source)
+	}
+
+	dst, err := netip.ParseAddr(pkt.Destination)
+	if err != nil {
+		return fmt.Errorf("Invalid destination address %q", pkt.Destination)
+	}
+
+	if src.Unmap().Is4() != dst.Unmap().Is4() {
+		return fmt.Errorf("Source and destination addresses must be of the same family")
+	}
+
+	switch pkt.Protocol {
+	case "tcp", "udp":
+		for _, port := range []int{pkt.SourcePort, pkt.DestinationPort} {
+			if port < 0 || port > 65535 {
+				return fmt.Errorf("Invalid port %d", port)
+			}
+		}
+
+	case "icmp4", "icmp6":
+		family := 6
+		if src.Unmap().Is4() {
+			family = 4
+		}
+
+		if pkt.Protocol != fmt.Sprintf("icmp%d", family) {
+			return fmt.Errorf("Cannot use %q protocol with IPv%d addresses", pkt.Protocol, family)
+		}
+
+		for _, value := range []int{pkt.ICMPType, pkt.ICMPCode} {
+			if value < 0 || value > 255 {
+				return fmt.Errorf("Invalid ICMP type or code %d", value)
+			}
+		}
+
+	default:
+		return fmt.Errorf("Invalid protocol %q, must be one of tcp, udp, icmp4 or icmp6", pkt.Protocol)
+	}
+
+	return nil
+}
+
+// rulePacketMatch returns whether a normalised rule matches the packet. Only the addresses, protocol, ports and ICMP
+// type and code of the packet are known, so rules using other criteria (DSCP, packet length and the ports of the
+// switch) never match. The subjects of the rule are converted into addresses by the resolve function.
+func rulePacketMatch(rule api.NetworkACLRule, pkt Packet, resolve func(subject string) []string) bool {
+	if rule.DSCP != "" || rule.PacketLen != "" || rule.InPort != "" || rule.OutPort != "" {
+		return false
+	}
+
+	switch rule.Protocol {
+	case "", pkt.Protocol:
+	case ruleProtocolICMP6NDP:
+		if pkt.Protocol != "icmp6" || !slices.Contains(ruleICMP6NDPTypes, strconv.Itoa(pkt.ICMPType)) {
+			return false
+		}
+
+	default:
+		return false
+	}
+
+	if rule.ICMPType != "" && rule.ICMPType != strconv.Itoa(pkt.ICMPType) {
+		return false
+	}
+
+	if rule.ICMPCode != "" && rule.ICMPCode != strconv.Itoa(pkt.ICMPCode) {
+		return false
+	}
+
+	if rule.SourcePort != "" && !rulePortsOverlap(rule.SourcePort, strconv.Itoa(pkt.SourcePort)) {
+		return false
+	}
+
+	if rule.DestinationPort != "" && !rulePortsOverlap(rule.DestinationPort, strconv.Itoa(pkt.DestinationPort)) {
+		return false
+	}
+
+	return ruleSubjectsMatchAddress(rule.Source, pkt.Source, resolve) && ruleSubjectsMatchAddress(rule.Destination, pkt.Destination, resolve)
+}
+
+// ruleSubjectsMatchAddress returns whether one of the comma separated subjects contains the address.
+// An empty list matches any address.
+func ruleSubjectsMatchAddress(subjects string, addr string, resolve func(subject string) []string) bool {
+	if subjects == "" {
+		return true
+	}
+
+	for _, subject := range util.SplitNTrimSpace(subjects, ",", -1, true) {
+		for _, address := range resolve(subject) {
+			if ruleSubjectRelation(address, addr) != "" {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// simulateSubjectResolver returns a function converting a rule subject into the addresses it matches on a network
+// with the subnets. ACL names are converted into the static addresses of the instance NICs using the ACLs and host
+// names into their addresses last resolved by the refresh task. VLAN, zoned address and network peer subjects
+// can't match the packet so don't have any address.
+func simulateSubjectResolver(subnets []*net.IPNet, memberAddresses map[string][]string) func(subject string) []string {
+	return func(subject string) []string {
+		if ruleSubjectIsFQDN(subject) {
+			cached, _ := fqdnCache.get(subject)
+
+			addresses := make([]string, 0, len(cached))
+			for _, address := range cached {
+				addresses = append(addresses, address.String())
+			}
+
+			return addresses
+		}
+
+		// The firewall conversion rejects the subjects the packet can't match.
+		addresses, err := firewallRuleSubjects(subject, subnets, memberAddresses)
+		if err != nil {
+			return nil
+		}
+
+		return addresses
+	}
+}
+
+// aclMemberAddresses returns the static addresses of the instance NICs using each of the specified ACLs, either
+// through their own config or through the config of their network.
+func aclMemberAddresses(s *state.State, aclProjectName string, aclNames []string) (map[string][]string, error) {
+	memberAddresses := map[string][]string{}
+
+	err := s.DB.Cluster.Transaction(context.TODO(), func(ctx context.Context, tx *db.ClusterTx) error {
+		networkNames, err := tx.GetCreatedNetworkNamesByProject(ctx, aclProjectName)
+		if err != nil && !response.IsNotFoundError(err) {
+			return fmt.Errorf("Failed loading networks for project %q: %w", aclProjectName, err)
+		}
+
+		networks := make(map[string]*api.Network, len(networkNames))
+		for _, networkName := range networkNames {
+			_, network, _, err := tx.GetNetworkInAnyState(ctx, aclProjectName, networkName)
+			if err != nil {
+				return fmt.Errorf("Failed to get network config for %q: %w", networkName, err)
+			}
+
+			networks[networkName] = network
+		}
+
+		return tx.InstanceList(ctx, func(inst db.InstanceArgs, p api.Project) error {
+			// Skip instances who's effective network project doesn't match this Network ACL's project.
+			if project.NetworkProjectFromRecord(&p) != aclProjectName {
+				return nil
+			}
+
+			devices := db.ExpandInstanceDevices(inst.Devices.Clone(), inst.Profiles)
+			for _, devConfig := range devices {
+				network := networks[devConfig["network"]]
+				if devConfig["type"] != "nic" || network == nil {
+					continue
+				}
+
+				for _, aclName := range NICACLNames(network.Type, network.Config, devConfig) {
+					if !slices.Contains(aclNames, aclName) {
+						continue
+					}
+
+					for _, key := range []string{"ipv4.address", "ipv6.address"} {
+						if devConfig[key] != "" {
+							memberAddresses[aclName] = append(memberAddresses[aclName], devConfig[key])
+						}
+					}
+				}
+			}
+
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return memberAddresses, nil
+}
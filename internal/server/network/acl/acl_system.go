@@ -0,0 +1,107 @@
+package acl
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/lxc/incus/v6/internal/server/db"
+	"github.com/lxc/incus/v6/internal/server/db/cluster"
+	"github.com/lxc/incus/v6/internal/server/state"
+	"github.com/lxc/incus/v6/shared/api"
+	"github.com/lxc/incus/v6/shared/logger"
+	"github.com/lxc/incus/v6/shared/util"
+)
+
+// systemACLs are the network ACLs managed by the server. They are created in every project having its own
+// networks and can be assigned like any other ACL, but cannot be modified, renamed or deleted.
+var systemACLs = []api.NetworkACLsPost{
+	{
+		NetworkACLPost: api.NetworkACLPost{Name: "allow-dhcp-dns"},
+		NetworkACLPut: api.NetworkACLPut{
+			Description: "Allow DHCP and DNS traffic",
+			Ingress: []api.NetworkACLRule{
+				{Action: "allow", State: "enabled", Protocol: "udp", DestinationPort: "68", Description: "DHCPv4 client"},
+				{Action: "allow", State: "enabled", Protocol: "udp", DestinationPort: "546", Description: "DHCPv6 client"},
+			},
+			Egress: []api.NetworkACLRule{
+				{Action: "allow", State: "enabled", Protocol: "udp", DestinationPort: "53", Description: "DNS"},
+				{Action: "allow", State: "enabled", Protocol: "tcp", DestinationPort: "53", Description: "DNS"},
+				{Action: "allow", State: "enabled", Protocol: "udp", DestinationPort: "67", Description: "DHCPv4 server"},
+				{Action: "allow", State: "enabled", Protocol: "udp", DestinationPort: "547", Description: "DHCPv6 server"},
+			},
+		},
+	},
+	{
+		NetworkACLPost: api.NetworkACLPost{Name: "drop-all"},
+		NetworkACLPut: api.NetworkACLPut{
+			Description: "Drop all traffic",
+			Ingress:     []api.NetworkACLRule{{Action: "drop", State: "enabled"}},
+			Egress:      []api.NetworkACLRule{{Action: "drop", State: "enabled"}},
+		},
+	},
+}
+
+// EnsureSystemACLs creates the missing network ACLs managed by the server in the default project and in the
+// projects having their own networks. Existing ACLs are left untouched, so this can be run at every startup.
+// A user ACL with the same name as a system ACL is kept and the system ACL isn't created in its project.
+func EnsureSystemACLs(s *state.State) error {
+	return s.DB.Cluster.Transaction(context.TODO(), func(ctx context.Context, tx *db.ClusterTx) error {
+		projects, err := cluster.GetProjects(ctx, tx.Tx())
+		if err != nil {
+			return fmt.Errorf("Failed loading projects: %w", err)
+		}
+
+		for _, p := range projects {
+			if p.Name != api.ProjectDefaultName {
+				config, err := cluster.GetProjectConfig(ctx, tx.Tx(), p.ID)
+				if err != nil {
+					return fmt.Errorf("Failed loading config of project %q: %w", p.Name, err)
+				}
+
+				// Projects without their own networks use the ACLs of the default project.
+				if util.IsFalseOrEmpty(config["features.networks"]) {
+					continue
+				}
+			}
+
+			_, err = CreateSystemACLs(ctx, tx, p.Name)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// CreateSystemACLs creates the missing network ACLs managed by the server in the project, which must have its own
+// networks. This is used when a project gains its own networks, see EnsureSystemACLs for the existing projects.
+// Returns the names of the ACLs that were created.
+func CreateSystemACLs(ctx context.Context, tx *db.ClusterTx, projectName string) ([]string, error) {
+	created := []string{}
+
+	for _, aclInfo := range systemACLs {
+		_, existing, err := tx.GetNetworkACL(ctx, projectName, aclInfo.Name)
+		if err == nil {
+			if !existing.Managed {
+				logger.Warn("Skipping creation of system network ACL as a user ACL uses its name", logger.Ctx{"project": projectName, "networkACL": aclInfo.Name})
+			}
+
+			continue
+		}
+
+		if !api.StatusErrorCheck(err, http.StatusNotFound) {
+			return nil, fmt.Errorf("Failed loading network ACL %q of project %q: %w", aclInfo.Name, projectName, err)
+		}
+
+		_, err = tx.CreateManagedNetworkACL(ctx, projectName, &aclInfo)
+		if err != nil {
+			return nil, fmt.Errorf("Failed creating system network ACL %q in project %q: %w", aclInfo.Name, projectName, err)
+		}
+
+		created = append(created, aclInfo.Name)
+	}
+
+	return created, nil
+}
@@ -0,0 +1,202 @@
+package acl
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lxc/incus/v6/internal/server/cluster/request"
+	"github.com/lxc/incus/v6/internal/server/db"
+	dbCluster "github.com/lxc/incus/v6/internal/server/db/cluster"
+	"github.com/lxc/incus/v6/internal/server/state"
+	"github.com/lxc/incus/v6/shared/api"
+)
+
+func TestManagedACL(t *testing.T) {
+	s, cleanup := state.NewTestState(t)
+	defer cleanup()
+
+	err := s.DB.Cluster.Transaction(context.Background(), func(ctx context.Context, tx *db.ClusterTx) error {
+		_, err := tx.CreateManagedNetworkACL(ctx, api.ProjectDefaultName, &api.NetworkACLsPost{NetworkACLPost: api.NetworkACLPost{Name: "system"}})
+		return err
+	})
+	require.NoError(t, err)
+
+	netACL, err := LoadByName(s, api.ProjectDefaultName, "system")
+	require.NoError(t, err)
+	assert.True(t, netACL.Info().Managed)
+
+	// The ACL can still be looked up for assignment.
+	usedBy, err := netACL.UsedBy()
+	require.NoError(t, err)
+	assert.Empty(t, usedBy)
+
+	// But it cannot be changed by users.
+	_, err = netACL.Update(&netACL.Info().NetworkACLPut, request.ClientTypeNormal, nil, ValidationModeStrict)
+	assert.EqualError(t, err, "Cannot update an ACL managed by the server")
+	assert.True(t, api.StatusErrorCheck(err, http.StatusForbidden))
+
+	err = netACL.Rename("other", nil)
+	assert.EqualError(t, err, "Cannot rename an ACL managed by the server")
+
+	err = netACL.Delete()
+	assert.EqualError(t, err, "Cannot delete an ACL managed by the server")
+
+	_, err = LoadByName(s, api.ProjectDefaultName, "system")
+	assert.NoError(t, err)
+}
+
+func TestEnsureSystemACLs(t *testing.T) {
+	s, cleanup := state.NewTestState(t)
+	defer cleanup()
+
+	err := s.DB.Cluster.Transaction(context.Background(), func(ctx context.Context, tx *db.ClusterTx) error {
+		id, err := dbCluster.CreateProject(ctx, tx.Tx(), dbCluster.Project{Name: "p1"})
+		if err != nil {
+			return err
+		}
+
+		err = dbCluster.CreateProjectConfig(ctx, tx.Tx(), id, map[string]string{"features.networks": "true"})
+		if err != nil {
+			return err
+		}
+
+		_, err = dbCluster.CreateProject(ctx, tx.Tx(), dbCluster.Project{Name: "p2"})
+		if err != nil {
+			return err
+		}
+
+		// A user ACL using the name of a system ACL.
+		_, err = tx.CreateNetworkACL(ctx, "p1", &api.NetworkACLsPost{NetworkACLPost: api.NetworkACLPost{Name: "drop-all"}})
+
+		return err
+	})
+	require.NoError(t, err)
+
+	// Running it again doesn't change anything.
+	require.NoError(t, EnsureSystemACLs(s))
+	require.NoError(t, EnsureSystemACLs(s))
+
+	managed := func(projectName string) map[string]bool {
+		acls := map[string]bool{}
+
+		err := s.DB.Cluster.Transaction(context.Background(), func(ctx context.Context, tx *db.ClusterTx) error {
+			names, err := tx.GetNetworkACLs(ctx, projectName)
+			if err != nil {
+				return err
+			}
+
+			for _, name := range names {
+				_, info, err := tx.GetNetworkACL(ctx, projectName, name)
+				if err != nil {
+					return err
+				}
+
+				acls[name] = info.Managed
+			}
+
+			return nil
+		})
+		require.NoError(t, err)
+
+		return acls
+	}
+
+	assert.Equal(t, map[string]bool{"allow-dhcp-dns": true, "drop-all": true}, managed(api.ProjectDefaultName))
+	assert.Equal(t, map[string]bool{"allow-dhcp-dns": true, "drop-all": false}, managed("p1"))
+	assert.Empty(t, managed("p2"))
+
+	// The system ACLs are valid.
+	d := &common{}
+	d.init(s, -1, api.ProjectDefaultName, nil)
+
+	for _, aclInfo := range systemACLs {
+		info := &api.NetworkACLPut{
+			Ingress: slices.Clone(aclInfo.Ingress),
+			Egress:  slices.Clone(aclInfo.Egress),
+		}
+
+		assert.NoError(t, validateStrict(d, info), aclInfo.Name)
+	}
+}
+
+func TestCreateSystemACLs(t *testing.T) {
+	s, cleanup := state.NewTestState(t)
+	defer cleanup()
+
+	require.NoError(t, EnsureSystemACLs(s))
+
+	err := s.DB.Cluster.Transaction(context.Background(), func(ctx context.Context, tx *db.ClusterTx) error {
+		id, err := dbCluster.CreateProject(ctx, tx.Tx(), dbCluster.Project{Name: "p1"})
+		if err != nil {
+			return err
+		}
+
+		err = dbCluster.CreateProjectConfig(ctx, tx.Tx(), id, map[string]string{"features.networks": "true"})
+		if err != nil {
+			return err
+		}
+
+		created, err := CreateSystemACLs(ctx, tx, "p1")
+		if err != nil {
+			return err
+		}
+
+		assert.Equal(t, []string{"allow-dhcp-dns", "drop-all"}, created)
+
+		// The existing ACLs are left alone.
+		created, err = CreateSystemACLs(ctx, tx, "p1")
+		if err != nil {
+			return err
+		}
+
+		assert.Empty(t, created)
+
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+func TestCopyToProjectSystemACLs(t *testing.T) {
+	s, cleanup := state.NewTestState(t)
+	defer cleanup()
+
+	require.NoError(t, EnsureSystemACLs(s))
+
+	err := s.DB.Cluster.Transaction(context.Background(), func(ctx context.Context, tx *db.ClusterTx) error {
+		id, err := dbCluster.CreateProject(ctx, tx.Tx(), dbCluster.Project{Name: "p1"})
+		if err != nil {
+			return err
+		}
+
+		err = dbCluster.CreateProjectConfig(ctx, tx.Tx(), id, map[string]string{"features.networks": "true"})
+		if err != nil {
+			return err
+		}
+
+		_, err = CreateSystemACLs(ctx, tx, "p1")
+		if err != nil {
+			return err
+		}
+
+		// A user ACL referencing a system ACL.
+		_, err = tx.CreateNetworkACL(ctx, api.ProjectDefaultName, &api.NetworkACLsPost{
+			NetworkACLPost: api.NetworkACLPost{Name: "web"},
+			NetworkACLPut: api.NetworkACLPut{
+				Egress: []api.NetworkACLRule{{Action: "allow", Destination: "allow-dhcp-dns", State: "enabled"}},
+			},
+		})
+
+		return err
+	})
+	require.NoError(t, err)
+
+	// System ACLs aren't copied, but can still be referenced by the copied ACLs.
+	created, err := CopyToProject(s, api.ProjectDefaultName, "p1", []string{"drop-all", "web"}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"web"}, created)
+}
@@ -1,12 +1,164 @@
 package acl
 
 import (
+	"context"
 	"fmt"
+	"net/netip"
+	"slices"
+	"strings"
 
+	"github.com/lxc/incus/v6/internal/server/db"
+	"github.com/lxc/incus/v6/internal/server/response"
+	"github.com/lxc/incus/v6/internal/server/state"
+	"github.com/lxc/incus/v6/shared/api"
 	"github.com/lxc/incus/v6/shared/util"
 	"github.com/lxc/incus/v6/shared/validate"
 )
 
+// ValidationErrors lists all the problems found when validating an ACL, rather than only the first one.
+type ValidationErrors []error
+
+// Error returns the problems on a single line. A single problem is returned as is.
+func (e ValidationErrors) Error() string {
+	if len(e) == 1 {
+		return e[0].Error()
+	}
+
+	msgs := make([]string, 0, len(e))
+	for _, err := range e {
+		msgs = append(msgs, err.Error())
+	}
+
+	return fmt.Sprintf("Found %d problems: %s", len(e), strings.Join(msgs, "; "))
+}
+
+// Unwrap returns the individual problems so they can be inspected with errors.Is and errors.As.
+func (e ValidationErrors) Unwrap() []error {
+	return e
+}
+
+// ValidationMode controls how validation handles the deprecated constructs found in the rules.
+type ValidationMode string
+
+const (
+	// ValidationModeStrict rejects deprecated constructs.
+	ValidationModeStrict ValidationMode = "strict"
+
+	// ValidationModeWarn replaces deprecated constructs with their current form and reports them as warnings,
+	// but otherwise validates the rules like the strict mode. This is the default.
+	ValidationModeWarn ValidationMode = "warn"
+
+	// ValidationModePermissive replaces deprecated constructs with their current form and reports them as
+	// warnings, and also converts CIDR subjects with host bits set. This is meant for rules coming from older
+	// versions, such as restored or copied ACLs.
+	ValidationModePermissive ValidationMode = "permissive"
+)
+
+// ParseValidationMode returns the validation mode with the given name, defaulting to warn when empty.
+func ParseValidationMode(name string) (ValidationMode, error) {
+	switch ValidationMode(name) {
+	case "", ValidationModeWarn:
+		return ValidationModeWarn, nil
+	case ValidationModeStrict:
+		return ValidationModeStrict, nil
+	case ValidationModePermissive:
+		return ValidationModePermissive, nil
+	}
+
+	return "", fmt.Errorf("Invalid validation mode %q, must be one of: %s, %s, %s", name, ValidationModeStrict, ValidationModeWarn, ValidationModePermissive)
+}
+
+// deprecatedRuleSubjects looks for the deprecated subject aliases in the source and destination of the rules.
+// In strict mode an error is returned for each of them. Otherwise they are replaced in place by their current form
+// and a warning is returned for each of them.
+func deprecatedRuleSubjects(info *api.NetworkACLPut, mode ValidationMode) ([]string, ValidationErrors) {
+	var warnings []string
+	var errs ValidationErrors
+
+	for _, direction := range []ruleDirection{ruleDirectionIngress, ruleDirectionEgress} {
+		rules := info.Ingress
+		if direction == ruleDirectionEgress {
+			rules = info.Egress
+		}
+
+		for i := range rules {
+			for _, field := range []*string{&rules[i].Source, &rules[i].Destination} {
+				if *field == "" {
+					continue
+				}
+
+				subjects := util.SplitNTrimSpace(*field, ",", -1, false)
+				for j, subject := range subjects {
+					replacement, found := ruleSubjectDeprecatedAliases[subject]
+					if !found {
+						continue
+					}
+
+					if mode == ValidationModeStrict {
+						errs = append(errs, fmt.Errorf("Invalid %s rule %d: Subject %q is deprecated, use %q instead", direction, i, subject, replacement))
+						continue
+					}
+
+					subjects[j] = replacement
+					warnings = append(warnings, fmt.Sprintf("Replaced deprecated subject %q with %q in %s rule %d", subject, replacement, direction, i))
+				}
+
+				*field = strings.Join(subjects, ",")
+			}
+		}
+	}
+
+	return warnings, errs
+}
+
+// hostBitsRuleSubjects looks for CIDR subjects with host bits set in the source and destination of the rules, as
+// "10.1.2.3/24" matches the whole subnet rather than only the address it mentions.
+// When strict, an error suggesting the bare address or the network address is returned for each of them.
+// Otherwise they are replaced in place by their network address and a warning is returned for each of them.
+func hostBitsRuleSubjects(info *api.NetworkACLPut, strict bool) ([]string, ValidationErrors) {
+	var warnings []string
+	var errs ValidationErrors
+
+	for _, direction := range []ruleDirection{ruleDirectionIngress, ruleDirectionEgress} {
+		rules := info.Ingress
+		if direction == ruleDirectionEgress {
+			rules = info.Egress
+		}
+
+		for i := range rules {
+			for _, field := range []*string{&rules[i].Source, &rules[i].Destination} {
+				if *field == "" {
+					continue
+				}
+
+				replaced := false
+				subjects := util.SplitNTrimSpace(*field, ",", -1, false)
+				for j, subject := range subjects {
+					prefix, err := netip.ParsePrefix(subject)
+					if err != nil || prefix.Masked() == prefix {
+						continue
+					}
+
+					if strict {
+						errs = append(errs, fmt.Errorf("Invalid %s rule %d: CIDR subject %q has host bits set, use %q to only match the address or %q to match the whole network", direction, i, subject, prefix.Addr().String(), prefix.Masked().String()))
+						continue
+					}
+
+					subjects[j] = prefix.Masked().String()
+					replaced = true
+					warnings = append(warnings, fmt.Sprintf("Replaced CIDR subject %q with its network address %q in %s rule %d", subject, subjects[j], direction, i))
+				}
+
+				if replaced {
+					*field = strings.Join(subjects, ",")
+				}
+			}
+		}
+	}
+
+	return warnings, errs
+}
+
 // ValidName checks the ACL name is valid.
 func ValidName(name string) error {
 	if name == "" {
@@ -19,6 +171,12 @@ func ValidName(name string) error {
 		return fmt.Errorf("Name cannot start with reserved character %q", name[0])
 	}
 
+	// Don't allow ACL names that conflict with the shorthand subjects matching any IP address.
+	_, found := ruleSubjectAny[strings.ToLower(name)]
+	if found {
+		return fmt.Errorf("Name %q is reserved", name)
+	}
+
 	// Ensures we can differentiate an ACL name from an IP in rules that reference this ACL.
 	err := validate.IsHostname(name)
 	if err != nil {
@@ -27,3 +185,262 @@ func ValidName(name string) error {
 
 	return nil
 }
+
+// ValidateSubjects checks the subjects of the source or destination field (fieldName is "Source" or "Destination")
+// of a rule of the given direction, as done when validating the ACL, so they can be checked on their own while
+// they are edited. Named subjects are resolved against the ACLs of the project.
+func ValidateSubjects(s *state.State, projectName string, fieldName string, direction string, subjects []string) error {
+	if fieldName != "Source" && fieldName != "Destination" {
+		return fmt.Errorf("Invalid field %q, must be one of: Source, Destination", fieldName)
+	}
+
+	if direction != string(ruleDirectionIngress) && direction != string(ruleDirectionEgress) {
+		return fmt.Errorf("Invalid direction %q, must be one of: %s, %s", direction, ruleDirectionIngress, ruleDirectionEgress)
+	}
+
+	// Avoid loading the ACLs if none of the subjects can be an ACL name.
+	var aclNameIDs map[string]int64
+	maybeName := func(subject string) bool {
+		return ruleSubjectFamily(subject) == 0 && ValidName(subject) == nil
+	}
+
+	if slices.ContainsFunc(expandRuleSubjects(subjects), maybeName) {
+		err := s.DB.Cluster.Transaction(context.TODO(), func(ctx context.Context, tx *db.ClusterTx) error {
+			var err error
+
+			aclNameIDs, err = tx.GetNetworkACLIDsByNames(ctx, projectName)
+
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("Failed getting network ACLs: %w", err)
+		}
+	}
+
+	d := &common{}
+	_, _, _, err := d.validateRuleSubjects(fieldName, ruleDirection(direction), subjects, ruleValidSubjectNames(aclNameIDs))
+	if err != nil {
+		return fmt.Errorf("Invalid %s: %w", fieldName, err)
+	}
+
+	return nil
+}
+
+// undefinedReferences returns the ACL names referenced by the rules that aren't keys of aclNameIDs.
+func undefinedReferences(info *api.NetworkACLPut, aclNameIDs map[string]int64) []string {
+	undefined := []string{}
+	for _, name := range ruleSubjectNames(info) {
+		_, found := aclNameIDs[name]
+		if !found {
+			undefined = append(undefined, name)
+		}
+	}
+
+	return undefined
+}
+
+// ruleSubjectNames returns the sorted list of unique ACL names used as subjects in the rules.
+func ruleSubjectNames(info *api.NetworkACLPut) []string {
+	names := []string{}
+
+	addNames := func(subjects string) {
+		for _, subject := range expandRuleSubjects(util.SplitNTrimSpace(subjects, ",", -1, false)) {
+			if ruleSubjectFamily(subject) != 0 || ValidName(subject) != nil {
+				continue // Skip addresses and subjects that can't be ACL names, such as reserved ones.
+			}
+
+			if !slices.Contains(names, subject) {
+				names = append(names, subject)
+			}
+		}
+	}
+
+	for _, rules := range [][]api.NetworkACLRule{info.Ingress, info.Egress} {
+		for _, rule := range rules {
+			if rule.Source != "" {
+				addNames(rule.Source)
+			}
+
+			if rule.Destination != "" {
+				addNames(rule.Destination)
+			}
+		}
+	}
+
+	slices.Sort(names)
+
+	return names
+}
+
+// referenceGraph returns the ACL names referenced as subjects by the rules of each of the ACLs.
+func referenceGraph(aclInfos []*api.NetworkACL) map[string][]string {
+	graph := make(map[string][]string, len(aclInfos))
+	for _, aclInfo := range aclInfos {
+		graph[aclInfo.Name] = ruleSubjectNames(&aclInfo.NetworkACLPut)
+	}
+
+	return graph
+}
+
+// reachableReferenceGraph returns the reference graph of the ACLs the named ACL references directly or transitively,
+// starting from its own info. Only those ACLs are loaded, as they include every ACL that can form a reference cycle
+// with it. References to missing ACLs are left without any edges.
+func reachableReferenceGraph(ctx context.Context, tx *db.ClusterTx, projectName string, aclName string, info *api.NetworkACLPut) (map[string][]string, error) {
+	graph := map[string][]string{aclName: ruleSubjectNames(info)}
+	queue := slices.Clone(graph[aclName])
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+
+		_, found := graph[current]
+		if found {
+			continue
+		}
+
+		graph[current] = []string{}
+
+		_, aclInfo, err := tx.GetNetworkACL(ctx, projectName, current)
+		if err != nil {
+			if response.IsNotFoundError(err) {
+				continue
+			}
+
+			return nil, fmt.Errorf("Failed loading network ACL %q: %w", current, err)
+		}
+
+		graph[current] = ruleSubjectNames(&aclInfo.NetworkACLPut)
+		queue = append(queue, graph[current]...)
+	}
+
+	return graph, nil
+}
+
+// referenceCycle returns the sorted names of the other ACLs forming a reference cycle with the named ACL, that is
+// the ACLs it references directly or transitively and that in turn reference it. Self-references are ignored.
+func referenceCycle(graph map[string][]string, name string) []string {
+	// reachable returns the ACLs reachable from the named ACL by following the edges returned by next.
+	reachable := func(next func(string) []string) map[string]struct{} {
+		seen := map[string]struct{}{}
+		queue := []string{name}
+		for len(queue) > 0 {
+			current := queue[0]
+			queue = queue[1:]
+
+			for _, ref := range next(current) {
+				_, found := seen[ref]
+				if found || ref == name {
+					continue
+				}
+
+				seen[ref] = struct{}{}
+				queue = append(queue, ref)
+			}
+		}
+
+		return seen
+	}
+
+	referenced := reachable(func(aclName string) []string { return graph[aclName] })
+	referencing := reachable(func(aclName string) []string {
+		referrers := []string{}
+		for referrer, refs := range graph {
+			if slices.Contains(refs, aclName) {
+				referrers = append(referrers, referrer)
+			}
+		}
+
+		return referrers
+	})
+
+	cycle := []string{}
+	for aclName := range referenced {
+		_, found := referencing[aclName]
+		if found {
+			cycle = append(cycle, aclName)
+		}
+	}
+
+	slices.Sort(cycle)
+
+	return cycle
+}
+
+// ruleSubjectMaxSuggestions is the maximum number of names suggested for an unknown subject.
+const ruleSubjectMaxSuggestions = 3
+
+// ruleSubjectSuggestions returns the names closest to an unknown subject, for when it is a typo'd name. Only names
+// within a couple of edits of the subject are returned, closest first. Deprecated aliases are never suggested.
+func ruleSubjectSuggestions(subject string, validSubjectNames []string) []string {
+	// Allow fewer edits for short subjects so that unrelated short names aren't suggested.
+	maxDistance := min(2, len(subject)/3)
+
+	type suggestion struct {
+		name     string
+		distance int
+	}
+
+	suggestions := []suggestion{}
+	for _, name := range validSubjectNames {
+		_, deprecated := ruleSubjectDeprecatedAliases[name]
+		if deprecated {
+			continue
+		}
+
+		distance := editDistance(subject, name)
+		if distance > 0 && distance <= maxDistance {
+			suggestions = append(suggestions, suggestion{name: name, distance: distance})
+		}
+	}
+
+	slices.SortFunc(suggestions, func(a suggestion, b suggestion) int {
+		if a.distance != b.distance {
+			return a.distance - b.distance
+		}
+
+		return strings.Compare(a.name, b.name)
+	})
+
+	suggestions = suggestions[:min(len(suggestions), ruleSubjectMaxSuggestions)]
+
+	names := make([]string, 0, len(suggestions))
+	for _, s := range suggestions {
+		names = append(names, s.name)
+	}
+
+	return names
+}
+
+// editDistance returns the number of single character insertions, deletions, substitutions or transpositions of
+// neighbouring characters needed to turn a into b (optimal string alignment distance).
+func editDistance(a string, b string) int {
+	ra := []rune(a)
+	rb := []rune(b)
+
+	// Only the last two rows are needed to fill in the next one.
+	prev2 := make([]int, len(rb)+1)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				curr[j] = min(curr[j], prev2[j-2]+1)
+			}
+		}
+
+		prev2, prev, curr = prev, curr, prev2
+	}
+
+	return prev[len(rb)]
+}
@@ -0,0 +1,174 @@
+package acl
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lxc/incus/v6/internal/server/db"
+	"github.com/lxc/incus/v6/internal/server/state"
+	"github.com/lxc/incus/v6/shared/api"
+)
+
+func TestEditDistance(t *testing.T) {
+	assert.Equal(t, 0, editDistance("web", "web"))
+	assert.Equal(t, 1, editDistance("web", "webs"))
+	assert.Equal(t, 1, editDistance("wbe", "web"))
+	assert.Equal(t, 1, editDistance("@intenral", "@internal"))
+	assert.Equal(t, 2, editDistance("wbe", "webs"))
+	assert.Equal(t, 3, editDistance("", "web"))
+	assert.Equal(t, 3, editDistance("kitten", "sitting"))
+}
+
+func TestValidNameAnyReserved(t *testing.T) {
+	for _, name := range []string{"any", "any4", "ANY6"} {
+		assert.ErrorContains(t, ValidName(name), "is reserved")
+	}
+
+	assert.NoError(t, ValidName("anything"))
+}
+
+func TestUndefinedReferences(t *testing.T) {
+	info := &api.NetworkACLPut{
+		Ingress: []api.NetworkACLRule{
+			{Action: "allow", Source: "web, dbs, 192.0.2.0/24, @internal", Destination: "2001:db8::1"},
+			{Action: "allow", Source: "any, @peer, #external, web"},
+		},
+		Egress: []api.NetworkACLRule{
+			{Action: "allow", Destination: "dbs, cache"},
+		},
+	}
+
+	// Only the names not matching an existing ACL are returned, once and sorted.
+	aclNameIDs := map[string]int64{"web": 1, "other": 2}
+	assert.Equal(t, []string{"cache", "dbs"}, undefinedReferences(info, aclNameIDs))
+
+	aclNameIDs["dbs"] = 3
+	aclNameIDs["cache"] = 4
+	assert.Empty(t, undefinedReferences(info, aclNameIDs))
+
+	// Rules without named subjects don't reference any ACL.
+	assert.Empty(t, ruleSubjectNames(&api.NetworkACLPut{Ingress: []api.NetworkACLRule{{Action: "drop", Source: "any4, 192.0.2.1-192.0.2.10"}}}))
+}
+
+func TestValidateSubjects(t *testing.T) {
+	s, cleanup := state.NewTestState(t)
+	defer cleanup()
+
+	err := s.DB.Cluster.Transaction(context.Background(), func(ctx context.Context, tx *db.ClusterTx) error {
+		_, err := tx.CreateNetworkACL(ctx, api.ProjectDefaultName, &api.NetworkACLsPost{NetworkACLPost: api.NetworkACLPost{Name: "web"}})
+		return err
+	})
+	require.NoError(t, err)
+
+	// Addresses are valid on either side, and existing ACLs on the remote side.
+	assert.NoError(t, ValidateSubjects(s, api.ProjectDefaultName, "Source", "ingress", []string{"192.0.2.0/24", "web", "@internal"}))
+	assert.NoError(t, ValidateSubjects(s, api.ProjectDefaultName, "Destination", "ingress", []string{"2001:db8::1"}))
+	assert.NoError(t, ValidateSubjects(s, api.ProjectDefaultName, "Destination", "egress", []string{"web"}))
+	assert.NoError(t, ValidateSubjects(s, api.ProjectDefaultName, "Source", "egress", nil))
+
+	// Names are resolved in the project, and reported at their position.
+	err = ValidateSubjects(s, api.ProjectDefaultName, "Source", "ingress", []string{"192.0.2.1", "wbe"})
+	assert.ErrorIs(t, err, ErrUnknownSubject)
+	assert.ErrorContains(t, err, `Invalid Source: element 1 "wbe"`)
+	assert.ErrorContains(t, err, `did you mean "web"?`)
+
+	err = ValidateSubjects(s, "other", "Source", "ingress", []string{"web"})
+	assert.ErrorIs(t, err, ErrUnknownSubject)
+
+	// Names can't be used on the local side.
+	err = ValidateSubjects(s, api.ProjectDefaultName, "Destination", "ingress", []string{"web"})
+	assert.EqualError(t, err, `Invalid Destination: element 0 "web": Named subjects not allowed in "Destination" for "ingress" rules`)
+
+	// The field and direction must be known.
+	assert.EqualError(t, ValidateSubjects(s, api.ProjectDefaultName, "source", "ingress", nil), `Invalid field "source", must be one of: Source, Destination`)
+	assert.EqualError(t, ValidateSubjects(s, api.ProjectDefaultName, "Source", "inbound", nil), `Invalid direction "inbound", must be one of: ingress, egress`)
+}
+
+func TestReferenceCycle(t *testing.T) {
+	// A chain of three ACLs without a cycle.
+	graph := map[string][]string{
+		"web": {"app"},
+		"app": {"db"},
+		"db":  {},
+	}
+
+	assert.Equal(t, []string{}, referenceCycle(graph, "web"))
+	assert.Equal(t, []string{}, referenceCycle(graph, "db"))
+
+	// Closing the chain makes all three ACLs part of the cycle.
+	graph["db"] = []string{"web"}
+	assert.Equal(t, []string{"app", "db"}, referenceCycle(graph, "web"))
+	assert.Equal(t, []string{"app", "web"}, referenceCycle(graph, "db"))
+
+	// ACLs only referencing the cycle, or only referenced by it, aren't part of it.
+	graph["cache"] = []string{"web"}
+	graph["app"] = []string{"db", "log"}
+	graph["log"] = []string{}
+	assert.Equal(t, []string{"app", "db"}, referenceCycle(graph, "web"))
+	assert.Equal(t, []string{}, referenceCycle(graph, "cache"))
+
+	// Longer cycles, including ones sharing ACLs, are followed transitively.
+	graph["log"] = []string{"cache"}
+	assert.Equal(t, []string{"app", "cache", "db", "log"}, referenceCycle(graph, "web"))
+
+	// Mutual references form a cycle of two, and self-references are ignored.
+	graph = map[string][]string{
+		"web": {"web", "db"},
+		"db":  {"web"},
+	}
+
+	assert.Equal(t, []string{"db"}, referenceCycle(graph, "web"))
+}
+
+func TestReachableReferenceGraph(t *testing.T) {
+	s, cleanup := state.NewTestState(t)
+	defer cleanup()
+
+	references := func(names ...string) api.NetworkACLPut {
+		rules := []api.NetworkACLRule{}
+		for _, name := range names {
+			rules = append(rules, api.NetworkACLRule{Action: "allow", State: "enabled", Destination: name})
+		}
+
+		return api.NetworkACLPut{Egress: rules}
+	}
+
+	// "cache" references the cycle but isn't reachable from "web", and "missing" doesn't exist.
+	acls := map[string]api.NetworkACLPut{
+		"web":   references("app"),
+		"app":   references("db", "missing"),
+		"db":    references("web"),
+		"cache": references("web"),
+	}
+
+	var graph map[string][]string
+
+	err := s.DB.Cluster.Transaction(context.Background(), func(ctx context.Context, tx *db.ClusterTx) error {
+		for name, info := range acls {
+			_, err := tx.CreateNetworkACL(ctx, api.ProjectDefaultName, &api.NetworkACLsPost{NetworkACLPost: api.NetworkACLPost{Name: name}, NetworkACLPut: info})
+			if err != nil {
+				return err
+			}
+		}
+
+		info := acls["web"]
+
+		var err error
+		graph, err = reachableReferenceGraph(ctx, tx, api.ProjectDefaultName, "web", &info)
+
+		return err
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string][]string{
+		"web":     {"app"},
+		"app":     {"db", "missing"},
+		"db":      {"web"},
+		"missing": {},
+	}, graph)
+
+	assert.Equal(t, []string{"app", "db"}, referenceCycle(graph, "web"))
+}
@@ -2,14 +2,28 @@ package acl
 
 import (
 	"bufio"
+	"bytes"
+	"cmp"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"maps"
+	"math"
 	"net"
+	"net/http"
 	"os"
 	"slices"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
+
+	"golang.org/x/sync/errgroup"
 
 	"github.com/lxc/incus/v6/client"
 	internalInstance "github.com/lxc/incus/v6/internal/instance"
@@ -18,8 +32,14 @@ import (
 	"github.com/lxc/incus/v6/internal/server/cluster/request"
 	"github.com/lxc/incus/v6/internal/server/db"
 	dbCluster "github.com/lxc/incus/v6/internal/server/db/cluster"
+	"github.com/lxc/incus/v6/internal/server/db/warningtype"
+	"github.com/lxc/incus/v6/internal/server/locking"
+	"github.com/lxc/incus/v6/internal/server/network/ovn"
+	"github.com/lxc/incus/v6/internal/server/scriptlet"
+	scriptletLoad "github.com/lxc/incus/v6/internal/server/scriptlet/load"
 	"github.com/lxc/incus/v6/internal/server/state"
 	localUtil "github.com/lxc/incus/v6/internal/server/util"
+	"github.com/lxc/incus/v6/internal/server/warnings"
 	"github.com/lxc/incus/v6/internal/version"
 	"github.com/lxc/incus/v6/shared/api"
 	"github.com/lxc/incus/v6/shared/logger"
@@ -33,10 +53,16 @@ type ruleDirection string
 const ruleDirectionIngress ruleDirection = "ingress"
 const ruleDirectionEgress ruleDirection = "egress"
 
+// ruleDirectionEnabled returns whether the rules of the direction are enforced. The rules of a direction can be
+// disabled with the "<direction>.enabled" config key, leaving its traffic to the default action.
+func ruleDirectionEnabled(config map[string]string, direction ruleDirection) bool {
+	return util.IsTrueOrEmpty(config[string(direction)+".enabled"])
+}
+
 // ReservedNetworkSubects contains a list of reserved network peer names (those starting with @ character) that
 // cannot be used when to name peering connections. Otherwise peer connections wouldn't be able to be referenced
 // in ACL rules using the "@<peer name>" format without the potential of conflicts.
-var ReservedNetworkSubects = []string{"internal", "external"}
+var ReservedNetworkSubects = []string{"internal", "external", "ipv4", "ipv6"}
 
 // Define reserved ACL subjects.
 const ruleSubjectInternal = "@internal"
@@ -47,9 +73,192 @@ const ruleSubjectExternal = "@external"
 var ruleSubjectInternalAliases = []string{ruleSubjectInternal, "#internal"}
 var ruleSubjectExternalAliases = []string{ruleSubjectExternal, "#external"}
 
+// Define reserved ACL subjects matching all the traffic of one IP family, along with their aliases.
+const ruleSubjectIPv4 = "@ipv4"
+const ruleSubjectIPv6 = "@ipv6"
+
+var ruleSubjectIPv4Aliases = []string{ruleSubjectIPv4}
+var ruleSubjectIPv6Aliases = []string{ruleSubjectIPv6}
+
+// ruleSubjectDeprecatedAliases maps the deprecated aliases to the reserved ACL subjects replacing them. They are
+// still understood when applying existing rules but only accepted by validation in permissive mode.
+var ruleSubjectDeprecatedAliases = map[string]string{
+	"#internal": ruleSubjectInternal,
+	"#external": ruleSubjectExternal,
+}
+
+// ruleSubjectVLANPrefix is the prefix of the subjects matching the VLAN ID of the traffic ("vlan:<id>").
+const ruleSubjectVLANPrefix = "vlan:"
+
+// Define the responses that can be sent back for rejected traffic.
+const ruleRejectResponseICMPPortUnreachable = "icmp-port-unreachable"
+const ruleRejectResponseTCPReset = "tcp-reset"
+
+// ruleSubjectAny defines the shorthand subjects that match any IP address and the CIDRs they expand to.
+var ruleSubjectAny = map[string][]string{
+	"any":  {"0.0.0.0/0", "::/0"},
+	"any4": {"0.0.0.0/0"},
+	"any6": {"::/0"},
+}
+
+// expandRuleSubjects returns the subjects with the "any", "any4" and "any6" shorthand subjects replaced by the
+// CIDRs they match.
+func expandRuleSubjects(subjects []string) []string {
+	expanded := make([]string, 0, len(subjects))
+	for _, subject := range subjects {
+		cidrs, found := ruleSubjectAny[subject]
+		if found {
+			expanded = append(expanded, cidrs...)
+			continue
+		}
+
+		expanded = append(expanded, subject)
+	}
+
+	return expanded
+}
+
+// ruleSubjectVLAN returns the VLAN ID of a "vlan:<id>" subject.
+func ruleSubjectVLAN(subject string) (uint16, error) {
+	id, err := strconv.ParseUint(strings.TrimPrefix(subject, ruleSubjectVLANPrefix), 10, 16)
+	if err != nil || id > 4094 {
+		return 0, fmt.Errorf("Invalid VLAN subject %q, VLAN ID must be between 0 and 4094", subject)
+	}
+
+	return uint16(id), nil
+}
+
+// ruleSubjectZone splits an IPv6 address subject with a zone, such as "fe80::1%eth0", into the address and the
+// zone. Other subjects are returned as is with an empty zone.
+func ruleSubjectZone(subject string) (string, string) {
+	addr, zone, found := strings.Cut(subject, "%")
+	if !found {
+		return subject, ""
+	}
+
+	return addr, zone
+}
+
+// validateRuleSubjectZone checks that the zone of an address subject is an interface name and that the address is
+// an IPv6 link-local one, as zones are only meaningful for those.
+func validateRuleSubjectZone(addr string, zone string) error {
+	ip := net.ParseIP(addr)
+	if ip == nil || ip.To4() != nil {
+		return fmt.Errorf("Zones can only be used with IPv6 addresses")
+	}
+
+	if !ip.IsLinkLocalUnicast() {
+		return fmt.Errorf("Zones can only be used with link-local addresses")
+	}
+
+	err := validate.IsInterfaceName(zone)
+	if err != nil {
+		return fmt.Errorf("Invalid zone %q: %w", zone, err)
+	}
+
+	return nil
+}
+
+// ruleSubjectFamily returns the IP family (4 or 6) of an address, CIDR or IP range subject, or of the @ipv4 and
+// @ipv6 selectors. Returns 0 for subjects that aren't addresses, such as ACL names and the @internal and @external
+// selectors, and for invalid IP ranges such as ones mixing both families.
+func ruleSubjectFamily(subject string) uint {
+	if slices.Contains(ruleSubjectIPv4Aliases, subject) {
+		return 4
+	}
+
+	if slices.Contains(ruleSubjectIPv6Aliases, subject) {
+		return 6
+	}
+
+	// Only the address matters for the family of zoned addresses. The zone is stripped first as interface
+	// names can contain dashes.
+	addr, _ := ruleSubjectZone(subject)
+
+	// Both ends of a range are checked as they must be of the same family.
+	if strings.Contains(addr, "-") {
+		family, err := ruleSubjectRangeFamily(addr)
+		if err != nil {
+			return 0
+		}
+
+		return family
+	}
+
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		ip, _, _ = net.ParseCIDR(subject)
+	}
+
+	if ip == nil {
+		return 0
+	}
+
+	if ip.To4() == nil {
+		return 6
+	}
+
+	return 4
+}
+
+// SplitByFamily splits a rule whose source and destination both contain IPv4 and IPv6 addresses into an IPv4 rule
+// and an IPv6 rule. Subjects that aren't addresses are kept in both rules. Other rules are returned unchanged.
+func SplitByFamily(rule api.NetworkACLRule) []api.NetworkACLRule {
+	splitSubjects := func(subjects string) ([]string, []string) {
+		var ipv4Subjects, ipv6Subjects []string
+
+		for _, subject := range expandRuleSubjects(util.SplitNTrimSpace(subjects, ",", -1, true)) {
+			switch ruleSubjectFamily(subject) {
+			case 4:
+				ipv4Subjects = append(ipv4Subjects, subject)
+			case 6:
+				ipv6Subjects = append(ipv6Subjects, subject)
+			default:
+				ipv4Subjects = append(ipv4Subjects, subject)
+				ipv6Subjects = append(ipv6Subjects, subject)
+			}
+		}
+
+		return ipv4Subjects, ipv6Subjects
+	}
+
+	srcIPv4, srcIPv6 := splitSubjects(rule.Source)
+	dstIPv4, dstIPv6 := splitSubjects(rule.Destination)
+
+	// Only split when both sides mix the families, subjects that aren't addresses don't count.
+	hasFamily := func(subjects []string, family uint) bool {
+		return slices.ContainsFunc(subjects, func(subject string) bool { return ruleSubjectFamily(subject) == family })
+	}
+
+	if !hasFamily(srcIPv4, 4) || !hasFamily(srcIPv6, 6) || !hasFamily(dstIPv4, 4) || !hasFamily(dstIPv6, 6) {
+		return []api.NetworkACLRule{rule}
+	}
+
+	ipv4Rule := rule
+	ipv4Rule.Source = strings.Join(srcIPv4, ",")
+	ipv4Rule.Destination = strings.Join(dstIPv4, ",")
+
+	ipv6Rule := rule
+	ipv6Rule.Source = strings.Join(srcIPv6, ",")
+	ipv6Rule.Destination = strings.Join(dstIPv6, ",")
+
+	return []api.NetworkACLRule{ipv4Rule, ipv6Rule}
+}
+
 // ValidActions defines valid actions for rules.
 var ValidActions = []string{"allow", "allow-stateless", "drop", "reject"}
 
+// ruleProtocolICMP6NDP is a virtual protocol matching the ICMPv6 neighbor discovery message types.
+const ruleProtocolICMP6NDP = "icmp6-ndp"
+
+// ruleICMP6NDPTypes contains the ICMPv6 types used by neighbor discovery: router solicitation, router
+// advertisement, neighbor solicitation, neighbor advertisement and redirect.
+var ruleICMP6NDPTypes = []string{"133", "134", "135", "136", "137"}
+
+// maxRules is the maximum combined number of ingress and egress rules allowed in a single ACL.
+// Very large rulesets make applying the ACL to OVN extremely slow.
+var maxRules = 10000
+
 // common represents a Network ACL.
 type common struct {
 	logger      logger.Logger
@@ -114,85 +323,210 @@ func (d *common) Info() *api.NetworkACL {
 	info.Config = localUtil.CopyConfig(d.info.Config)
 	info.UsedBy = nil // To indicate its not populated (use Usedby() function to populate).
 	info.Project = d.projectName
+	info.IngressCount = len(d.info.Ingress)
+	info.EgressCount = len(d.info.Egress)
+	info.Managed = d.info.Managed
 
 	return &info
 }
 
-// usedBy returns a list of API endpoints referencing this ACL.
-// If firstOnly is true then search stops at first result.
-func (d *common) usedBy(firstOnly bool) ([]string, error) {
+// UsedBy returns a list of API endpoints referencing this ACL.
+func (d *common) UsedBy() ([]string, error) {
 	usedBy := []string{}
 
 	// Find all networks, profiles and instance NICs that use this Network ACL.
 	err := UsedBy(d.state, d.projectName, func(ctx context.Context, tx *db.ClusterTx, _ []string, usageType any, _ string, _ map[string]string) error {
+		uri, err := usageURI(usageType)
+		if err != nil {
+			return err
+		}
+
+		usedBy = append(usedBy, uri)
+
+		return nil
+	}, d.Info().Name)
+	if err != nil {
+		return nil, fmt.Errorf("Failed getting ACL usage: %w", err)
+	}
+
+	// Also list the ACLs only referencing this ACL transitively through a reference cycle, so that cycles are
+	// visible from each of the ACLs involved.
+	var graph map[string][]string
+
+	err = d.state.DB.Cluster.Transaction(context.TODO(), func(ctx context.Context, tx *db.ClusterTx) error {
+		var err error
+
+		graph, err = reachableReferenceGraph(ctx, tx, d.projectName, d.info.Name, &d.info.NetworkACLPut)
+
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Failed getting ACL references: %w", err)
+	}
+
+	return appendReferenceCycle(usedBy, d.projectName, graph, d.info.Name), nil
+}
+
+// appendReferenceCycle adds the ACLs forming a reference cycle with the named ACL to its list of users, unless
+// they are already in it.
+func appendReferenceCycle(usedBy []string, aclProjectName string, graph map[string][]string, aclName string) []string {
+	for _, cycleACLName := range referenceCycle(graph, aclName) {
+		uri, _ := usageURI(&api.NetworkACL{NetworkACLPost: api.NetworkACLPost{Name: cycleACLName}, Project: aclProjectName})
+		if !slices.Contains(usedBy, uri) {
+			usedBy = append(usedBy, uri)
+		}
+	}
+
+	return usedBy
+}
+
+// usageURI returns the API endpoint of a resource using an ACL, as passed to the UsedBy usageFunc.
+// The endpoint refers to the project owning the resource, which isn't necessarily the ACL's project.
+func usageURI(usageType any) (string, error) {
+	var uri string
+	var projectName string
+
+	switch u := usageType.(type) {
+	case db.InstanceArgs:
+		uri = fmt.Sprintf("/%s/instances/%s", version.APIVersion, u.Name)
+		projectName = u.Project
+	case *api.Network:
+		uri = fmt.Sprintf("/%s/networks/%s", version.APIVersion, u.Name)
+		projectName = u.Project
+	case dbCluster.Profile:
+		uri = fmt.Sprintf("/%s/profiles/%s", version.APIVersion, u.Name)
+		projectName = u.Project
+	case *api.NetworkACL:
+		uri = fmt.Sprintf("/%s/network-acls/%s", version.APIVersion, u.Name)
+		projectName = u.Project
+	default:
+		return "", fmt.Errorf("Unrecognised usage type %T", u)
+	}
+
+	if projectName != api.ProjectDefaultName {
+		uri += fmt.Sprintf("?project=%s", projectName)
+	}
+
+	return uri, nil
+}
+
+// AssignedToNetwork returns whether the ACL is assigned to the named network through its security.acls setting.
+// Usages other than networks are ignored, and the search stops at the first matching network.
+func (d *common) AssignedToNetwork(networkName string) (bool, error) {
+	assigned := false
+
+	err := UsedBy(d.state, d.projectName, func(ctx context.Context, tx *db.ClusterTx, _ []string, usageType any, _ string, _ map[string]string) error {
+		network, ok := usageType.(*api.Network)
+		if !ok || network.Name != networkName {
+			return nil
+		}
+
+		assigned = true
+
+		return db.ErrInstanceListStop
+	}, d.info.Name)
+	if err != nil && err != db.ErrInstanceListStop {
+		return false, fmt.Errorf("Failed getting ACL usage: %w", err)
+	}
+
+	return assigned, nil
+}
+
+// maxInUseReferences is the maximum number of resources listed in the errors about an ACL being in use.
+const maxInUseReferences = 5
+
+// inUseError returns the error preventing the action on the ACL when it is in use, or nil if it isn't.
+// The error lists the first resources using the ACL, such as "ACL web (ingress rule 3)" for the rules of other
+// ACLs referencing it and "profile default (device eth0)" for NICs, so that the blockers can be found.
+func (d *common) inUseError(action string) error {
+	references := []string{}
+	more := false
+
+	err := UsedBy(d.state, d.projectName, func(ctx context.Context, tx *db.ClusterTx, _ []string, usageType any, usageName string, _ map[string]string) error {
+		if len(references) >= maxInUseReferences {
+			more = true
+			return db.ErrInstanceListStop
+		}
+
 		switch u := usageType.(type) {
 		case db.InstanceArgs:
-			uri := fmt.Sprintf("/%s/instances/%s", version.APIVersion, u.Name)
-			if u.Project != api.ProjectDefaultName {
-				uri += fmt.Sprintf("?project=%s", u.Project)
+			reference := "instance " + u.Name
+			if u.Project != d.projectName {
+				reference += " in project " + u.Project
 			}
 
-			usedBy = append(usedBy, uri)
+			references = append(references, fmt.Sprintf("%s (device %s)", reference, usageName))
 		case *api.Network:
-			uri := fmt.Sprintf("/%s/networks/%s", version.APIVersion, u.Name)
-			if d.projectName != api.ProjectDefaultName {
-				uri += fmt.Sprintf("?project=%s", d.projectName)
-			}
-
-			usedBy = append(usedBy, uri)
+			references = append(references, "network "+u.Name)
 		case dbCluster.Profile:
-			uri := fmt.Sprintf("/%s/profiles/%s", version.APIVersion, u.Name)
-			if u.Project != api.ProjectDefaultName {
-				uri += fmt.Sprintf("?project=%s", u.Project)
+			reference := "profile " + u.Name
+			if u.Project != d.projectName {
+				reference += " in project " + u.Project
 			}
 
-			usedBy = append(usedBy, uri)
+			references = append(references, fmt.Sprintf("%s (device %s)", reference, usageName))
 		case *api.NetworkACL:
-			uri := fmt.Sprintf("/%s/network-acls/%s", version.APIVersion, u.Name)
-			if d.projectName != api.ProjectDefaultName {
-				uri += fmt.Sprintf("?project=%s", d.projectName)
-			}
-
-			usedBy = append(usedBy, uri)
+			references = append(references, fmt.Sprintf("ACL %s (%s)", u.Name, usageName))
 		default:
 			return fmt.Errorf("Unrecognised usage type %T", u)
 		}
 
-		if firstOnly {
-			return db.ErrInstanceListStop
-		}
+		return nil
+	}, d.info.Name)
+	if err != nil && err != db.ErrInstanceListStop {
+		return fmt.Errorf("Failed getting ACL usage: %w", err)
+	}
 
+	if len(references) == 0 {
 		return nil
-	}, d.Info().Name)
-	if err != nil {
-		if err == db.ErrInstanceListStop {
-			return usedBy, nil
-		}
+	}
 
-		return nil, fmt.Errorf("Failed getting ACL usage: %w", err)
+	if more {
+		references = append(references, "and more")
 	}
 
-	return usedBy, nil
+	return fmt.Errorf("Cannot %s an ACL that is in use by %s", action, strings.Join(references, ", "))
 }
 
-// UsedBy returns a list of API endpoints referencing this ACL.
-func (d *common) UsedBy() ([]string, error) {
-	return d.usedBy(false)
-}
+// Etag returns the values used for etag generation.
+// This is a hash of a canonical form of the ACL, with its rules normalised (which also sorts their subject and port
+// lists) and its config keys sorted, so that semantically identical ACLs always get the same etag whatever the
+// ordering used by the client that last wrote them.
+func (d *common) Etag() []any {
+	type configEntry struct {
+		Key   string
+		Value string
+	}
 
-// isUsed returns whether or not the ACL is in use.
-func (d *common) isUsed() (bool, error) {
-	usedBy, err := d.usedBy(true)
-	if err != nil {
-		return false, err
+	normalise := func(rules []api.NetworkACLRule) []api.NetworkACLRule {
+		normalised := slices.Clone(rules)
+		for i := range normalised {
+			normalised[i].Normalise()
+		}
+
+		return normalised
 	}
 
-	return len(usedBy) > 0, nil
-}
+	configKeys := make([]string, 0, len(d.info.Config))
+	for k := range d.info.Config {
+		configKeys = append(configKeys, k)
+	}
 
-// Etag returns the values used for etag generation.
-func (d *common) Etag() []any {
-	return []any{d.info.Name, d.info.Description, d.info.Ingress, d.info.Egress, d.info.Config}
+	slices.Sort(configKeys)
+
+	config := make([]configEntry, 0, len(configKeys))
+	for _, k := range configKeys {
+		config = append(config, configEntry{Key: k, Value: d.info.Config[k]})
+	}
+
+	canonical := []any{d.info.Name, d.info.Description, normalise(d.info.Ingress), normalise(d.info.Egress), config}
+
+	hash, err := localUtil.EtagHash(canonical)
+	if err != nil {
+		return canonical
+	}
+
+	return []any{hash}
 }
 
 // validateName checks name is valid.
@@ -201,133 +535,417 @@ func (d *common) validateName(name string) error {
 }
 
 // validateConfig checks the config and rules are valid.
-func (d *common) validateConfig(info *api.NetworkACLPut) error {
-	err := d.validateConfigMap(info.Config, nil)
+// All the invalid config keys and rules are reported together as ValidationErrors. Only failures preventing the
+// validation itself, such as too many rules or failing to load the project's ACLs, are returned immediately.
+// The mode controls how deprecated constructs and CIDR subjects with host bits set are handled, and the warnings
+// about the ones replaced in permissive mode are returned.
+func (d *common) validateConfig(info *api.NetworkACLPut, mode ValidationMode) ([]string, error) {
+	// Generate the rules of ACLs using a scriptlet, so they go through the same validation as static rules.
+	if info.Config["rules.scriptlet"] != "" {
+		err := d.scriptletRules(info)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err := d.validateRuleCount(info)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	// Normalise rules before validation for duplicate detection.
-	for i := range info.Ingress {
-		info.Ingress[i].Normalise()
+	var errs ValidationErrors
+
+	rules := map[string]func(value string) error{
+		"ingress.enabled": validate.Optional(validate.IsBool),
+		"egress.enabled":  validate.Optional(validate.IsBool),
+
+		// A rate of 0 would drop all the log messages, so turning logging off is left to the rule states.
+		"ingress.log_rate": validate.Optional(validate.IsInRange(1, math.MaxUint32)),
+		"egress.log_rate":  validate.Optional(validate.IsInRange(1, math.MaxUint32)),
+
+		"parent": validate.Optional(ValidName),
+
+		"priority.band": validate.Optional(validate.IsInRange(0, ovnACLPriorityBandMax)),
+
+		"rules.scriptlet": validate.Optional(scriptletLoad.NetworkACLRulesValidate),
+
+		"validation.strict_cidr": validate.Optional(validate.IsBool),
 	}
 
-	for i := range info.Egress {
-		info.Egress[i].Normalise()
+	err = d.validateConfigMap(info.Config, rules)
+	if err != nil {
+		var configErrs ValidationErrors
+		if !errors.As(err, &configErrs) {
+			configErrs = ValidationErrors{err}
+		}
+
+		errs = append(errs, configErrs...)
 	}
 
-	// Validate each ingress rule.
-	for i, ingressRule := range info.Ingress {
-		err := d.validateRule(ruleDirectionIngress, ingressRule)
+	// Check that the parent exists and that the ACL doesn't end up inheriting from itself.
+	if info.Config["parent"] != "" && ValidName(info.Config["parent"]) == nil {
+		err = d.state.DB.Cluster.Transaction(context.TODO(), func(ctx context.Context, tx *db.ClusterTx) error {
+			_, err := parentChain(ctx, tx, d.projectName, d.info.Name, info.Config["parent"])
+
+			return err
+		})
 		if err != nil {
-			return fmt.Errorf("Invalid ingress rule %d: %w", i, err)
+			errs = append(errs, err)
 		}
+	}
 
-		// Check for duplicates.
-		for ri, r := range info.Ingress {
-			if ri == i {
-				continue // Skip ourselves.
-			}
+	// Normalise the elements of the rules in place, so that validation errors report their position as supplied.
+	// The rules are fully normalised after validation for duplicate detection.
+	for i := range info.Ingress {
+		info.Ingress[i].NormaliseElements()
+	}
 
-			if r == ingressRule {
-				return fmt.Errorf("Duplicate of ingress rule %d", i)
-			}
-		}
+	for i := range info.Egress {
+		info.Egress[i].NormaliseElements()
 	}
 
-	// Validate each egress rule.
-	for i, egressRule := range info.Egress {
-		err := d.validateRule(ruleDirectionEgress, egressRule)
-		if err != nil {
-			return fmt.Errorf("Invalid egress rule %d: %w", i, err)
-		}
+	// Replace or reject the deprecated constructs before validating the rules.
+	warnings, deprecatedErrs := deprecatedRuleSubjects(info, mode)
+	errs = append(errs, deprecatedErrs...)
 
-		// Check for duplicates.
-		for ri, r := range info.Egress {
-			if ri == i {
-				continue // Skip ourselves.
-			}
+	// Replace or reject the CIDR subjects with host bits set. Rejecting them can be turned off for each ACL.
+	strictCIDR := mode != ValidationModePermissive && util.IsTrueOrEmpty(info.Config["validation.strict_cidr"])
+	cidrWarnings, cidrErrs := hostBitsRuleSubjects(info, strictCIDR)
+	warnings = append(warnings, cidrWarnings...)
+	errs = append(errs, cidrErrs...)
 
-			if r == egressRule {
-				return fmt.Errorf("Duplicate of egress rule %d", i)
-			}
+	// Load the ACL names once for all the rules, rather than for each rule.
+	var aclNameIDs map[string]int64
+	if len(info.Ingress) > 0 || len(info.Egress) > 0 {
+		err = d.state.DB.Cluster.Transaction(context.TODO(), func(ctx context.Context, tx *db.ClusterTx) error {
+			var err error
+
+			// Get map of ACL names to DB IDs (used for generating OVN port group names).
+			aclNameIDs, err = tx.GetNetworkACLIDsByNames(ctx, d.Project())
+
+			return err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("Failed getting network ACLs for security ACL subject validation: %w", err)
 		}
 	}
 
-	return nil
-}
+	// Check that the ACLs referenced by the rules exist to report typos precisely.
+	// Named subjects only ever resolve to the ACLs of this ACL's project, there is no fallback to the default
+	// project, so a name existing in both projects always refers to the ACL of this project.
+	undefined := undefinedReferences(info, aclNameIDs)
+	if len(undefined) > 0 {
+		errs = append(errs, d.undefinedReferencesError(undefined))
+	}
 
-// validateConfigMap checks ACL config map against rules.
-func (d *common) validateConfigMap(config map[string]string, rules map[string]func(value string) error) error {
-	checkedFields := map[string]struct{}{}
+	// Reject rules referencing the ACL itself. References between different ACLs are allowed, even when they
+	// form a cycle, and such cycles are reported by UsedBy.
+	if d.info.Name != "" && slices.Contains(ruleSubjectNames(info), d.info.Name) {
+		errs = append(errs, fmt.Errorf("Rules cannot reference the network ACL itself (%s)", d.info.Name))
+	}
 
-	// Run the validator against each field.
-	for k, validator := range rules {
-		checkedFields[k] = struct{}{} //Mark field as checked.
-		err := validator(config[k])
+	validSubjectNames := ruleValidSubjectNames(aclNameIDs)
+
+	// Validate each ingress rule.
+	for i := range info.Ingress {
+		err := d.validateRule(ruleDirectionIngress, info.Ingress[i], validSubjectNames)
 		if err != nil {
-			return fmt.Errorf("Invalid value for config option %q: %w", k, err)
+			errs = append(errs, fmt.Errorf("Invalid ingress rule %d: %w", i, err))
+		}
+
+		// Check for duplicates of the previous rules. As the subject and port lists are normalised, this also
+		// catches rules only differing by the order or form of their subjects and ports.
+		info.Ingress[i].Normalise()
+		ri := slices.Index(info.Ingress[:i], info.Ingress[i])
+		if ri >= 0 {
+			errs = append(errs, fmt.Errorf("Ingress rule %d is a duplicate of ingress rule %d", i, ri))
 		}
 	}
 
-	// Look for any unchecked fields, as these are unknown fields and validation should fail.
-	for k := range config {
-		_, checked := checkedFields[k]
-		if checked {
-			continue
+	// Validate each egress rule.
+	for i := range info.Egress {
+		err := d.validateRule(ruleDirectionEgress, info.Egress[i], validSubjectNames)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("Invalid egress rule %d: %w", i, err))
 		}
 
-		// User keys are not validated.
-		if internalInstance.IsUserConfig(k) {
-			continue
+		// Check for duplicates of the previous rules.
+		info.Egress[i].Normalise()
+		ri := slices.Index(info.Egress[:i], info.Egress[i])
+		if ri >= 0 {
+			errs = append(errs, fmt.Errorf("Egress rule %d is a duplicate of egress rule %d", i, ri))
 		}
+	}
 
-		return fmt.Errorf("Invalid config option %q", k)
+	if len(errs) > 0 {
+		return nil, errs
 	}
 
-	return nil
+	return warnings, nil
 }
 
-// validateRule validates the rule supplied.
-func (d *common) validateRule(direction ruleDirection, rule api.NetworkACLRule) error {
-	// Validate Action field (required).
-	if !slices.Contains(ValidActions, rule.Action) {
-		return fmt.Errorf("Action must be one of: %s", strings.Join(ValidActions, ", "))
-	}
-
-	// Validate State field (required).
-	validStates := []string{"enabled", "disabled", "logged"}
-	if !slices.Contains(validStates, rule.State) {
-		return fmt.Errorf("State must be one of: %s", strings.Join(validStates, ", "))
-	}
+// scriptletRulesTimeout is how long a network ACL rules scriptlet can run for.
+const scriptletRulesTimeout = 10 * time.Second
 
-	var acls map[string]int64
+// scriptletRules replaces the rules of the supplied config by the ones generated by its rules.scriptlet.
+// The generated rules are stored as the ACL's rules, so the rules currently stored or generated can be supplied
+// back unchanged (as when editing the ACL), but any other static rules are rejected.
+func (d *common) scriptletRules(info *api.NetworkACLPut) error {
+	var project *api.Project
 
 	err := d.state.DB.Cluster.Transaction(context.TODO(), func(ctx context.Context, tx *db.ClusterTx) error {
-		var err error
+		dbProject, err := dbCluster.GetProject(ctx, tx.Tx(), d.projectName)
+		if err != nil {
+			return err
+		}
 
-		// Get map of ACL names to DB IDs (used for generating OVN port group names).
-		acls, err = tx.GetNetworkACLIDsByNames(ctx, d.Project())
+		project, err = dbProject.ToAPI(ctx, tx.Tx())
 
 		return err
 	})
 	if err != nil {
-		return fmt.Errorf("Failed getting network ACLs for security ACL subject validation: %w", err)
+		return fmt.Errorf("Failed loading project %q: %w", d.projectName, err)
 	}
 
-	validSubjectNames := make([]string, 0, len(acls)+len(ruleSubjectInternalAliases)+len(ruleSubjectExternalAliases))
-	validSubjectNames = append(validSubjectNames, ruleSubjectInternalAliases...)
-	validSubjectNames = append(validSubjectNames, ruleSubjectExternalAliases...)
+	// The scriptlet runs with the ACL locked, so don't let it hold the lock for long.
+	ctx, cancel := context.WithTimeout(context.TODO(), scriptletRulesTimeout)
+	defer cancel()
 
-	for aclName := range acls {
-		validSubjectNames = append(validSubjectNames, aclName)
+	ingress, egress, err := scriptlet.NetworkACLRulesRun(ctx, d.logger, info.Config["rules.scriptlet"], project, d.info.Name)
+	if err != nil {
+		return fmt.Errorf("Failed generating rules with scriptlet: %w", err)
 	}
 
-	var srcHasName, srcHasIPv4, srcHasIPv6 bool
-	var dstHasName, dstHasIPv4, dstHasIPv6 bool
+	normalise := func(rules []api.NetworkACLRule) {
+		for i := range rules {
+			rules[i].Normalise()
+		}
+	}
 
-	// Validate Source field.
-	if rule.Source != "" {
+	normalise(ingress)
+	normalise(egress)
+
+	if len(info.Ingress) > 0 || len(info.Egress) > 0 {
+		normalise(info.Ingress)
+		normalise(info.Egress)
+
+		sameRules := func(otherIngress []api.NetworkACLRule, otherEgress []api.NetworkACLRule) bool {
+			return slices.Equal(info.Ingress, otherIngress) && slices.Equal(info.Egress, otherEgress)
+		}
+
+		// The rules currently stored for a scriptlet may differ from the ones it generates now, for example
+		// after a change of the project's config, and are accepted too so that the ACL can be edited.
+		storedScriptletRules := d.info.Config["rules.scriptlet"] != "" && sameRules(d.info.Ingress, d.info.Egress)
+
+		if !sameRules(ingress, egress) && !storedScriptletRules {
+			return fmt.Errorf("Static rules cannot be used together with %q", "rules.scriptlet")
+		}
+	}
+
+	info.Ingress = ingress
+	info.Egress = egress
+
+	return nil
+}
+
+// validateRuleCount checks the combined number of ingress and egress rules doesn't exceed maxRules.
+func (d *common) validateRuleCount(info *api.NetworkACLPut) error {
+	ruleCount := len(info.Ingress) + len(info.Egress)
+	if ruleCount > maxRules {
+		return fmt.Errorf("Too many rules (%d), an ACL can contain at most %d ingress and egress rules combined", ruleCount, maxRules)
+	}
+
+	return nil
+}
+
+// validateProjectLimits checks that storing the supplied config doesn't exceed the limits.network_acls and
+// limits.network_acl_rules settings of the ACL's project. The current ACL is excluded from the existing usage.
+// This must be called within the same transaction as the database write to avoid races with concurrent requests.
+func (d *common) validateProjectLimits(ctx context.Context, tx *db.ClusterTx, info *api.NetworkACLPut) error {
+	dbProject, err := dbCluster.GetProject(ctx, tx.Tx(), d.projectName)
+	if err != nil {
+		return fmt.Errorf("Failed loading project %q: %w", d.projectName, err)
+	}
+
+	projectConfig, err := dbCluster.GetProjectConfig(ctx, tx.Tx(), dbProject.ID)
+	if err != nil {
+		return fmt.Errorf("Failed loading project %q config: %w", d.projectName, err)
+	}
+
+	if projectConfig["limits.network_acls"] == "" && projectConfig["limits.network_acl_rules"] == "" {
+		return nil
+	}
+
+	aclCount, ruleCount, err := tx.GetNetworkACLsUsage(ctx, d.projectName, d.id)
+	if err != nil {
+		return fmt.Errorf("Failed loading project's network ACL usage for limits check: %w", err)
+	}
+
+	// Only check the ACL count when creating a new ACL so that existing ACLs can still be updated if the limit
+	// has been lowered below the current usage.
+	if d.id < 0 && projectConfig["limits.network_acls"] != "" {
+		aclLimit, err := strconv.Atoi(projectConfig["limits.network_acls"])
+		if err != nil {
+			return fmt.Errorf("Invalid project limits.network_acls value: %w", err)
+		}
+
+		if aclCount+1 > aclLimit {
+			return api.StatusErrorf(http.StatusBadRequest, "Network ACLs limit reached for project (%d/%d)", aclCount, aclLimit)
+		}
+	}
+
+	if projectConfig["limits.network_acl_rules"] != "" {
+		ruleLimit, err := strconv.Atoi(projectConfig["limits.network_acl_rules"])
+		if err != nil {
+			return fmt.Errorf("Invalid project limits.network_acl_rules value: %w", err)
+		}
+
+		// Allow updates that don't increase the number of rules of the ACL even if the project is over its limit.
+		oldRuleCount := 0
+		if d.info != nil {
+			oldRuleCount = len(d.info.Ingress) + len(d.info.Egress)
+		}
+
+		newRuleCount := ruleCount + len(info.Ingress) + len(info.Egress)
+		if newRuleCount > ruleLimit && len(info.Ingress)+len(info.Egress) > oldRuleCount {
+			return api.StatusErrorf(http.StatusBadRequest, "Network ACL rules limit reached for project (%d/%d)", newRuleCount, ruleLimit)
+		}
+	}
+
+	return nil
+}
+
+// validateConfigMap checks ACL config map against rules.
+func (d *common) validateConfigMap(config map[string]string, rules map[string]func(value string) error) error {
+	var errs ValidationErrors
+
+	checkedFields := map[string]struct{}{}
+
+	// Run the validator against each field (sorted so problems are reported in a stable order).
+	ruleKeys := make([]string, 0, len(rules))
+	for k := range rules {
+		ruleKeys = append(ruleKeys, k)
+	}
+
+	slices.Sort(ruleKeys)
+
+	for _, k := range ruleKeys {
+		checkedFields[k] = struct{}{} //Mark field as checked.
+		err := rules[k](config[k])
+		if err != nil {
+			errs = append(errs, fmt.Errorf("Invalid value for config option %q: %w", k, err))
+		}
+	}
+
+	// Look for any unchecked fields, as these are unknown fields and validation should fail.
+	configKeys := make([]string, 0, len(config))
+	for k := range config {
+		configKeys = append(configKeys, k)
+	}
+
+	slices.Sort(configKeys)
+
+	for _, k := range configKeys {
+		_, checked := checkedFields[k]
+		if checked {
+			continue
+		}
+
+		// User keys are not validated.
+		if internalInstance.IsUserConfig(k) {
+			continue
+		}
+
+		errs = append(errs, fmt.Errorf("Invalid config option %q", k))
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+// undefinedReferencesError returns the error reported for rules referencing undefined ACLs. As named subjects
+// don't resolve to the ACLs of the default project, referencing one of those is reported explicitly so that it
+// isn't mistaken for a typo.
+func (d *common) undefinedReferencesError(undefined []string) error {
+	err := fmt.Errorf("Rules reference undefined network ACLs: %s", strings.Join(undefined, ", "))
+
+	if d.projectName == api.ProjectDefaultName {
+		return err
+	}
+
+	var defaultACLNameIDs map[string]int64
+
+	dbErr := d.state.DB.Cluster.Transaction(context.TODO(), func(ctx context.Context, tx *db.ClusterTx) error {
+		var err error
+
+		defaultACLNameIDs, err = tx.GetNetworkACLIDsByNames(ctx, api.ProjectDefaultName)
+
+		return err
+	})
+	if dbErr != nil {
+		return err
+	}
+
+	defaultNames := []string{}
+	for _, name := range undefined {
+		_, found := defaultACLNameIDs[name]
+		if found {
+			defaultNames = append(defaultNames, name)
+		}
+	}
+
+	if len(defaultNames) > 0 {
+		return fmt.Errorf("%w (%s only exist in project %q and cannot be referenced from project %q)", err, strings.Join(defaultNames, ", "), api.ProjectDefaultName, d.projectName)
+	}
+
+	return err
+}
+
+// ruleValidSubjectNames returns the subject names that rules can use given the ACLs of the project.
+func ruleValidSubjectNames(aclNameIDs map[string]int64) []string {
+	validSubjectNames := make([]string, 0, len(aclNameIDs)+len(ruleSubjectInternalAliases)+len(ruleSubjectExternalAliases))
+	validSubjectNames = append(validSubjectNames, ruleSubjectInternalAliases...)
+	validSubjectNames = append(validSubjectNames, ruleSubjectExternalAliases...)
+
+	for aclName := range aclNameIDs {
+		validSubjectNames = append(validSubjectNames, aclName)
+	}
+
+	return validSubjectNames
+}
+
+// validateRule validates the rule supplied. The names that can be used as subjects are provided by the caller so
+// that they are only loaded once when validating multiple rules.
+func (d *common) validateRule(direction ruleDirection, rule api.NetworkACLRule, validSubjectNames []string) error {
+	// Validate Action field (required).
+	if !slices.Contains(ValidActions, rule.Action) {
+		return fmt.Errorf("Action must be one of: %s", strings.Join(ValidActions, ", "))
+	}
+
+	// Validate State field (required).
+	validStates := []string{"enabled", "disabled", "logged"}
+	if !slices.Contains(validStates, rule.State) {
+		return fmt.Errorf("State must be one of: %s", strings.Join(validStates, ", "))
+	}
+
+	// Validate ValidFrom and ValidUntil fields.
+	validFrom, validUntil, err := ruleSchedule(rule)
+	if err != nil {
+		return err
+	}
+
+	if !validFrom.IsZero() && !validUntil.IsZero() && !validUntil.After(validFrom) {
+		return fmt.Errorf("Valid until time must be after valid from time")
+	}
+
+	var srcHasName, srcHasIPv4, srcHasIPv6 bool
+	var dstHasName, dstHasIPv4, dstHasIPv6 bool
+
+	// Validate Source field.
+	if rule.Source != "" {
 		srcHasName, srcHasIPv4, srcHasIPv6, err = d.validateRuleSubjects("Source", direction, util.SplitNTrimSpace(rule.Source, ",", -1, false), validSubjectNames)
 		if err != nil {
 			return fmt.Errorf("Invalid Source: %w", err)
@@ -343,7 +961,10 @@ func (d *common) validateRule(direction ruleDirection, rule api.NetworkACLRule)
 	}
 
 	// Check combination of subject types is valid for source/destination.
-	if rule.Source != "" && rule.Destination != "" {
+	// Fields only containing VLAN subjects don't restrict the IP family of the other field.
+	srcHasAddress := srcHasName || srcHasIPv4 || srcHasIPv6
+	dstHasAddress := dstHasName || dstHasIPv4 || dstHasIPv6
+	if srcHasAddress && dstHasAddress {
 		if (srcHasIPv4 && !dstHasIPv4 && !dstHasName) ||
 			(dstHasIPv4 && !srcHasIPv4 && !srcHasName) ||
 			(srcHasIPv6 && !dstHasIPv6 && !dstHasName) ||
@@ -354,7 +975,7 @@ func (d *common) validateRule(direction ruleDirection, rule api.NetworkACLRule)
 
 	// Validate Protocol field.
 	if rule.Protocol != "" {
-		validProtocols := []string{"icmp4", "icmp6", "tcp", "udp"}
+		validProtocols := []string{"icmp4", "icmp6", ruleProtocolICMP6NDP, "tcp", "udp"}
 		if !slices.Contains(validProtocols, rule.Protocol) {
 			return fmt.Errorf("Protocol must be one of: %s", strings.Join(validProtocols, ", "))
 		}
@@ -385,7 +1006,7 @@ func (d *common) validateRule(direction ruleDirection, rule api.NetworkACLRule)
 				return fmt.Errorf("Invalid Destination port: %w", err)
 			}
 		}
-	} else if slices.Contains([]string{"icmp4", "icmp6"}, rule.Protocol) {
+	} else if slices.Contains([]string{"icmp4", "icmp6", ruleProtocolICMP6NDP}, rule.Protocol) {
 		if rule.SourcePort != "" {
 			return fmt.Errorf("Source port cannot be used with %q protocol", rule.Protocol)
 		}
@@ -402,7 +1023,7 @@ func (d *common) validateRule(direction ruleDirection, rule api.NetworkACLRule)
 			if dstHasIPv6 {
 				return fmt.Errorf("Cannot use IPv6 destination addresses with %q protocol", rule.Protocol)
 			}
-		} else if rule.Protocol == "icmp6" {
+		} else {
 			if srcHasIPv4 {
 				return fmt.Errorf("Cannot use IPv4 source addresses with %q protocol", rule.Protocol)
 			}
@@ -412,6 +1033,17 @@ func (d *common) validateRule(direction ruleDirection, rule api.NetworkACLRule)
 			}
 		}
 
+		// The neighbor discovery protocol implies its own ICMP types.
+		if rule.Protocol == ruleProtocolICMP6NDP {
+			if rule.ICMPType != "" {
+				return fmt.Errorf("ICMP type cannot be used with %q protocol", rule.Protocol)
+			}
+
+			if rule.ICMPCode != "" {
+				return fmt.Errorf("ICMP code cannot be used with %q protocol", rule.Protocol)
+			}
+		}
+
 		// Validate ICMPType field.
 		if rule.ICMPType != "" {
 			err := validate.IsUint8(rule.ICMPType)
@@ -432,294 +1064,1224 @@ func (d *common) validateRule(direction ruleDirection, rule api.NetworkACLRule)
 			return fmt.Errorf("ICMP type cannot be used without specifying protocol")
 		}
 
-		if rule.ICMPCode != "" {
-			return fmt.Errorf("ICMP code cannot be used without specifying protocol")
-		}
+		if rule.ICMPCode != "" {
+			return fmt.Errorf("ICMP code cannot be used without specifying protocol")
+		}
+
+		if rule.SourcePort != "" {
+			return fmt.Errorf("Source port cannot be used without specifying protocol")
+		}
+
+		if rule.DestinationPort != "" {
+			return fmt.Errorf("Destination port cannot be used without specifying protocol")
+		}
+	}
+
+	// Validate RejectResponse field.
+	// OVN answers rejected TCP traffic with a reset and other traffic with an ICMP port unreachable message, so
+	// the response must be consistent with the protocol for it to be the same on all network types.
+	if rule.RejectResponse != "" {
+		if rule.Action != "reject" {
+			return fmt.Errorf("Reject response can only be used with %q action", "reject")
+		}
+
+		switch rule.RejectResponse {
+		case ruleRejectResponseTCPReset:
+			if rule.Protocol != "tcp" {
+				return fmt.Errorf("Reject response %q can only be used with %q protocol", rule.RejectResponse, "tcp")
+			}
+
+		case ruleRejectResponseICMPPortUnreachable:
+			if rule.Protocol == "" || rule.Protocol == "tcp" {
+				return fmt.Errorf("Reject response %q requires a non-TCP protocol", rule.RejectResponse)
+			}
+
+		default:
+			return fmt.Errorf("Reject response must be one of: %s", strings.Join([]string{ruleRejectResponseICMPPortUnreachable, ruleRejectResponseTCPReset}, ", "))
+		}
+	}
+
+	// Validate DSCP field.
+	// Only allowed traffic can be marked, as dropped and rejected traffic never leaves the switch.
+	if rule.DSCP != "" {
+		if !slices.Contains([]string{"allow", "allow-stateless"}, rule.Action) {
+			return fmt.Errorf("DSCP can only be used with %q or %q action", "allow", "allow-stateless")
+		}
+
+		dscp, err := strconv.ParseUint(rule.DSCP, 10, 8)
+		if err != nil || dscp > 63 {
+			return fmt.Errorf("DSCP must be a number between 0 and 63")
+		}
+	}
+
+	// Validate Related field.
+	// Only connections of allowed traffic can be tracked, and only TCP and UDP have replies to allow.
+	if rule.Related {
+		if !slices.Contains([]string{"allow", "allow-stateless"}, rule.Action) {
+			return fmt.Errorf("Related can only be used with %q or %q action", "allow", "allow-stateless")
+		}
+
+		if !slices.Contains([]string{"tcp", "udp"}, rule.Protocol) {
+			return fmt.Errorf("Related can only be used with %q or %q protocol", "tcp", "udp")
+		}
+	}
+
+	// Validate PacketLen field.
+	// The length of ICMP packets doesn't tell anything about the traffic, so it can't be combined with them.
+	if rule.PacketLen != "" {
+		if slices.Contains([]string{"icmp4", "icmp6", ruleProtocolICMP6NDP}, rule.Protocol) {
+			return fmt.Errorf("Packet length cannot be used with %q protocol", rule.Protocol)
+		}
+
+		err := validateRulePacketLen(rule.PacketLen)
+		if err != nil {
+			return fmt.Errorf("Invalid packet length: %w", err)
+		}
+	}
+
+	// Validate InPort and OutPort fields.
+	// Ingress rules match the traffic going to the instance and egress rules the traffic leaving it, so only
+	// the port at the other end of the traffic can be restricted.
+	if rule.InPort != "" {
+		if direction != ruleDirectionIngress {
+			return fmt.Errorf("In port can only be used with ingress rules")
+		}
+
+		err := validateRuleLogicalPort(rule.InPort)
+		if err != nil {
+			return fmt.Errorf("Invalid in port: %w", err)
+		}
+	}
+
+	if rule.OutPort != "" {
+		if direction != ruleDirectionEgress {
+			return fmt.Errorf("Out port can only be used with egress rules")
+		}
+
+		err := validateRuleLogicalPort(rule.OutPort)
+		if err != nil {
+			return fmt.Errorf("Invalid out port: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// validateRulePacketLen checks that the packet length is a single value or an inclusive range of IP packet lengths.
+func validateRulePacketLen(value string) error {
+	startValue, endValue, isRange := strings.Cut(value, "-")
+
+	start, err := strconv.ParseUint(startValue, 10, 16)
+	if err != nil {
+		return fmt.Errorf("Length %q must be a number between 0 and 65535", startValue)
+	}
+
+	if !isRange {
+		return nil
+	}
+
+	end, err := strconv.ParseUint(endValue, 10, 16)
+	if err != nil {
+		return fmt.Errorf("Length %q must be a number between 0 and 65535", endValue)
+	}
+
+	if start > end {
+		return fmt.Errorf("Range is reversed, start length must not be higher than end length")
+	}
+
+	return nil
+}
+
+// validateRuleLogicalPort checks that the logical switch port name used in a rule can be safely quoted in an OVN
+// match.
+func validateRuleLogicalPort(name string) error {
+	if len(name) > 255 {
+		return fmt.Errorf("Port name %q is longer than 255 characters", name)
+	}
+
+	for _, r := range name {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || strings.ContainsRune("-_.:", r) {
+			continue
+		}
+
+		return fmt.Errorf("Port name %q contains invalid character %q", name, r)
+	}
+
+	return nil
+}
+
+// ErrUnknownSubject is returned by ClassifySubject for subjects that aren't of any known type.
+var ErrUnknownSubject = errors.New("not an IP address, CIDR, range or known ACL name")
+
+// ruleSubjectAddressFamily returns the IP family (4 or 6) of a single IP address subject.
+func ruleSubjectAddressFamily(value string) (uint, error) {
+	ip := net.ParseIP(value)
+	if ip == nil {
+		return 0, fmt.Errorf("Not an IP address %q", value)
+	}
+
+	if ip.To4() == nil {
+		return 6, nil
+	}
+
+	return 4, nil
+}
+
+// ruleSubjectRangeFamily returns the IP family (4 or 6) of an IP range subject. Both ends of the range are
+// checked, so that the IP family returned applies to the whole range.
+func ruleSubjectRangeFamily(value string) (uint, error) {
+	start, end, found := strings.Cut(value, "-")
+	if !found {
+		return 0, fmt.Errorf("IP range must contain start and end IP addresses")
+	}
+
+	startVersion, err := ruleSubjectAddressFamily(start)
+	if err != nil {
+		return 0, fmt.Errorf("Invalid start address %q in IP range", start)
+	}
+
+	endVersion, err := ruleSubjectAddressFamily(end)
+	if err != nil {
+		return 0, fmt.Errorf("Invalid end address %q in IP range", end)
+	}
+
+	if startVersion != endVersion {
+		return 0, fmt.Errorf("IP range mixes IPv%d and IPv%d addresses", startVersion, endVersion)
+	}
+
+	if bytes.Compare(net.ParseIP(start).To16(), net.ParseIP(end).To16()) > 0 {
+		return 0, fmt.Errorf("IP range is reversed, start address must not be higher than end address")
+	}
+
+	return startVersion, nil
+}
+
+// ClassifySubject returns the type of a single rule subject. The validNames are the ACL and reserved subject names
+// that can be used, as returned for a project by the ACL validation. Network peer names ("@<network>/<peer>") are
+// recognised by their prefix. Returns ErrUnknownSubject if the subject isn't of any type, or the reason an
+// intended IP range or VLAN subject is invalid.
+func ClassifySubject(subject string, validNames []string) (api.NetworkACLSubjectType, error) {
+	if strings.HasPrefix(subject, ruleSubjectVLANPrefix) {
+		_, err := ruleSubjectVLAN(subject)
+		if err != nil {
+			return "", err
+		}
+
+		return api.NetworkACLSubjectTypeSpecial, nil
+	}
+
+	_, found := ruleSubjectAny[subject]
+	if found {
+		return api.NetworkACLSubjectTypeSpecial, nil
+	}
+
+	if slices.Contains(ruleSubjectIPv4Aliases, subject) || slices.Contains(ruleSubjectIPv6Aliases, subject) {
+		return api.NetworkACLSubjectTypeSpecial, nil
+	}
+
+	// Link-local IPv6 addresses can include the zone (interface) they are scoped to.
+	addr, zone := ruleSubjectZone(subject)
+	if addr != subject && net.ParseIP(addr) != nil {
+		err := validateRuleSubjectZone(addr, zone)
+		if err != nil {
+			return "", err
+		}
+
+		return api.NetworkACLSubjectTypeIPv6, nil
+	}
+
+	ipVersion, err := ruleSubjectAddressFamily(subject)
+	if err == nil {
+		if ipVersion == 4 {
+			return api.NetworkACLSubjectTypeIPv4, nil
+		}
+
+		return api.NetworkACLSubjectTypeIPv6, nil
+	}
+
+	_, _, err = net.ParseCIDR(subject)
+	if err == nil {
+		return api.NetworkACLSubjectTypeCIDR, nil
+	}
+
+	_, err = ruleSubjectRangeFamily(subject)
+	if err == nil {
+		return api.NetworkACLSubjectTypeRange, nil
+	}
+
+	// Check if it is a host name, which is resolved to addresses of either family.
+	if ruleSubjectIsFQDN(subject) {
+		if slices.Contains(validNames, subject) {
+			return "", fmt.Errorf("Ambiguous as it is both a host name and a network ACL name")
+		}
+
+		return api.NetworkACLSubjectTypeHostName, nil
+	}
+
+	if slices.Contains(validNames, subject) {
+		if slices.Contains(ruleSubjectInternalAliases, subject) || slices.Contains(ruleSubjectExternalAliases, subject) {
+			return api.NetworkACLSubjectTypeSpecial, nil
+		}
+
+		return api.NetworkACLSubjectTypeName, nil
+	}
+
+	// Check if it looks like a network peer connection name.
+	if strings.HasPrefix(subject, "@") {
+		return api.NetworkACLSubjectTypeName, nil
+	}
+
+	// Report why a subject meant as an IP range is invalid rather than a generic error.
+	start, end, found := strings.Cut(subject, "-")
+	if found && (net.ParseIP(start) != nil || net.ParseIP(end) != nil) {
+		_, err := ruleSubjectRangeFamily(subject)
+		return "", err
+	}
+
+	return "", ErrUnknownSubject
+}
+
+// validateRuleSubjects checks that the source or destination subjects for a rule are valid.
+// Accepts a validSubjectNames list of valid ACL or special classifier names.
+// Returns whether the subjects include names, IPv4 and IPv6 addresses respectively.
+func (d *common) validateRuleSubjects(fieldName string, direction ruleDirection, subjects []string, validSubjectNames []string) (bool, bool, bool, error) {
+	// Check if named subjects are allowed in field/direction combination.
+	allowSubjectNames := false
+	if (fieldName == "Source" && direction == ruleDirectionIngress) || (fieldName == "Destination" && direction == ruleDirectionEgress) {
+		allowSubjectNames = true
+	}
+
+	validSubject := func(subject string) (api.NetworkACLSubjectType, error) {
+		subjectType, err := ClassifySubject(subject, validSubjectNames)
+		if err != nil {
+			// Point at the closest names when the subject looks like a typo'd one.
+			if allowSubjectNames && errors.Is(err, ErrUnknownSubject) {
+				suggestions := ruleSubjectSuggestions(subject, validSubjectNames)
+				if len(suggestions) > 0 {
+					quoted := make([]string, 0, len(suggestions))
+					for _, suggestion := range suggestions {
+						quoted = append(quoted, fmt.Sprintf("%q", suggestion))
+					}
+
+					return "", fmt.Errorf("%w, did you mean %s?", err, strings.Join(quoted, " or "))
+				}
+			}
+
+			return "", err
+		}
+
+		// Names, including the @internal and @external selectors, can only be used on the remote side.
+		isName := subjectType == api.NetworkACLSubjectTypeName || (subjectType == api.NetworkACLSubjectTypeSpecial && slices.Contains(validSubjectNames, subject))
+		if isName && !allowSubjectNames {
+			return "", fmt.Errorf("Named subjects not allowed in %q for %q rules", fieldName, direction)
+		}
+
+		return subjectType, nil
+	}
+
+	hasIPv4 := false
+	hasIPv6 := false
+	hasName := false
+
+	// Errors report the position of the subject in the list (starting at 0) so it can be found in long lists.
+	for i, subject := range subjects {
+		subjectType, err := validSubject(subject)
+		if err != nil {
+			return false, false, false, fmt.Errorf("element %d %q: %w", i, subject, err)
+		}
+
+		switch subjectType {
+		case api.NetworkACLSubjectTypeName, api.NetworkACLSubjectTypeHostName:
+			hasName = true
+		case api.NetworkACLSubjectTypeSpecial:
+			// VLAN subjects match the tag of the traffic rather than an address, so they don't count as any
+			// IP family. The "any" subjects count as the families of the CIDRs they expand to.
+			if strings.HasPrefix(subject, ruleSubjectVLANPrefix) {
+				continue
+			}
+
+			// The @ipv4 and @ipv6 selectors count as their family, and can be used on either side of a rule.
+			switch ruleSubjectFamily(subject) {
+			case 4:
+				hasIPv4 = true
+				continue
+			case 6:
+				hasIPv6 = true
+				continue
+			}
+
+			cidrs, found := ruleSubjectAny[subject]
+			if !found {
+				hasName = true
+				continue
+			}
+
+			for _, cidr := range cidrs {
+				hasIPv4 = hasIPv4 || ruleSubjectFamily(cidr) == 4
+				hasIPv6 = hasIPv6 || ruleSubjectFamily(cidr) == 6
+			}
+		default:
+			switch ruleSubjectFamily(subject) {
+			case 4:
+				hasIPv4 = true
+			case 6:
+				hasIPv6 = true
+			}
+		}
+	}
+
+	return hasName, hasIPv4, hasIPv6, nil
+}
+
+// ruleSchedule parses the optional validity window of a rule. Zero times are returned for unset fields.
+func ruleSchedule(rule api.NetworkACLRule) (time.Time, time.Time, error) {
+	var validFrom, validUntil time.Time
+	var err error
+
+	if rule.ValidFrom != "" {
+		validFrom, err = time.Parse(time.RFC3339, rule.ValidFrom)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("Invalid valid from time: %w", err)
+		}
+	}
+
+	if rule.ValidUntil != "" {
+		validUntil, err = time.Parse(time.RFC3339, rule.ValidUntil)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("Invalid valid until time: %w", err)
+		}
+	}
+
+	return validFrom, validUntil, nil
+}
+
+// ruleIsActive returns whether the rule applies at the given time according to its validity window.
+func ruleIsActive(rule api.NetworkACLRule, now time.Time) bool {
+	validFrom, validUntil, err := ruleSchedule(rule)
+	if err != nil {
+		return true // Stored rules have been validated so this shouldn't happen, keep the rule as is.
+	}
+
+	if !validFrom.IsZero() && now.Before(validFrom) {
+		return false
+	}
+
+	if !validUntil.IsZero() && now.After(validUntil) {
+		return false
+	}
+
+	return true
+}
+
+// ruleScheduleChanged returns whether the rule's validity window opened or closed after since and up to now.
+func ruleScheduleChanged(rule api.NetworkACLRule, since time.Time, now time.Time) bool {
+	validFrom, validUntil, err := ruleSchedule(rule)
+	if err != nil {
+		return false
+	}
+
+	for _, t := range []time.Time{validFrom, validUntil} {
+		if !t.IsZero() && t.After(since) && !t.After(now) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ruleID returns an identifier for a rule derived from its direction and content. The description is left out so
+// that documenting a rule doesn't reset its statistics.
+func ruleID(direction ruleDirection, rule api.NetworkACLRule) string {
+	rule.Description = ""
+
+	data, err := json.Marshal(rule)
+	if err != nil {
+		return ""
+	}
+
+	hash := sha256.Sum256(append([]byte(direction+":"), data...))
+
+	return hex.EncodeToString(hash[0:8])
+}
+
+// ruleLogLabel returns the label identifying a rule in the logs from its index in the rules of a composedACL. The
+// rules inherited from the parents have their own index space (prefixed with "i"), so that the ACL's own rules keep
+// their index.
+func ruleLogLabel(direction string, index int, inheritedRules int) string {
+	if index < inheritedRules {
+		return fmt.Sprintf("%s-i%d", direction, index)
+	}
+
+	return fmt.Sprintf("%s-%d", direction, index-inheritedRules)
+}
+
+// validatePorts checks that the source or destination ports for a rule are valid.
+// Each entry is either a single port or a "start-end" range, and entries can't overlap each other.
+// Errors report the position of the entry in the list (starting at 0).
+func (d *common) validatePorts(ports []string) error {
+	type portRange struct {
+		index int
+		value string
+		start uint64
+		end   uint64
+	}
+
+	ranges := make([]portRange, 0, len(ports))
+	for i, port := range ports {
+		startPort, endPort, isRange := strings.Cut(port, "-")
+		if !isRange {
+			endPort = startPort
+		}
+
+		err := validate.IsNetworkPort(startPort)
+		if err != nil {
+			return fmt.Errorf("element %d %q: %w", i, port, err)
+		}
+
+		err = validate.IsNetworkPort(endPort)
+		if err != nil {
+			return fmt.Errorf("element %d %q: %w", i, port, err)
+		}
+
+		start, _ := strconv.ParseUint(startPort, 10, 32)
+		end, _ := strconv.ParseUint(endPort, 10, 32)
+		if start > end {
+			return fmt.Errorf("element %d %q: Port range is reversed, start port must not be higher than end port", i, port)
+		}
+
+		ranges = append(ranges, portRange{index: i, value: port, start: start, end: end})
+	}
+
+	// Sort by start port so that any overlap shows up between neighbouring entries.
+	slices.SortStableFunc(ranges, func(a portRange, b portRange) int {
+		return cmp.Compare(a.start, b.start)
+	})
+
+	for i := 1; i < len(ranges); i++ {
+		// Identical entries are merged when normalising the list, so they aren't overlaps.
+		if ranges[i].start == ranges[i-1].start && ranges[i].end == ranges[i-1].end {
+			continue
+		}
+
+		if ranges[i].start <= ranges[i-1].end {
+			return fmt.Errorf("element %d %q: Port overlaps with element %d %q", ranges[i].index, ranges[i].value, ranges[i-1].index, ranges[i-1].value)
+		}
+	}
+
+	return nil
+}
+
+// Revisions returns the previous versions of the ACL that have been retained (newest first).
+func (d *common) Revisions() ([]api.NetworkACLRevision, error) {
+	var revisions []api.NetworkACLRevision
+
+	err := d.state.DB.Cluster.Transaction(context.TODO(), func(ctx context.Context, tx *db.ClusterTx) error {
+		var err error
+
+		revisions, err = tx.GetNetworkACLRevisions(ctx, d.id)
+
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return revisions, nil
+}
+
+// Revision returns a specific previous version of the ACL.
+func (d *common) Revision(revision int64) (*api.NetworkACLRevision, error) {
+	var info *api.NetworkACLRevision
+
+	err := d.state.DB.Cluster.Transaction(context.TODO(), func(ctx context.Context, tx *db.ClusterTx) error {
+		var err error
+
+		info, err = tx.GetNetworkACLRevision(ctx, d.id, revision)
+
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return info, nil
+}
+
+// recordRevision stores the supplied previous version of the ACL as a revision.
+func (d *common) recordRevision(previous *api.NetworkACL, requestor *api.EventLifecycleRequestor) error {
+	keep := d.state.GlobalConfig.NetworkACLsRevisions()
+	if keep <= 0 {
+		return nil
+	}
+
+	return d.state.DB.Cluster.Transaction(context.TODO(), func(ctx context.Context, tx *db.ClusterTx) error {
+		return tx.CreateNetworkACLRevision(ctx, d.id, previous, requestor, keep)
+	})
+}
+
+// lockName returns the name of the lock serialising the changes made to the ACL.
+func (d *common) lockName() string {
+	return fmt.Sprintf("NetworkACL_%s_%s", d.projectName, d.info.Name)
+}
+
+// lock waits for any other change to the ACL to complete and then takes exclusive access to it.
+// As the ACL may have been modified by the change it waited for, its definition is reloaded from the database
+// so that the caller works on, and applies, the version that is actually stored.
+func (d *common) lock() (locking.UnlockFunc, error) {
+	unlock, err := locking.Lock(context.TODO(), d.lockName())
+	if err != nil {
+		return nil, err
+	}
+
+	var id int64
+	var info *api.NetworkACL
+
+	err = d.state.DB.Cluster.Transaction(context.TODO(), func(ctx context.Context, tx *db.ClusterTx) error {
+		id, info, err = tx.GetNetworkACL(ctx, d.projectName, d.info.Name)
+
+		return err
+	})
+	if err != nil {
+		unlock()
+		return nil, err
+	}
+
+	// The ACL was renamed or deleted and another one created with its name while waiting.
+	if id != d.id {
+		unlock()
+		return nil, api.StatusErrorf(http.StatusNotFound, "Network ACL not found")
+	}
+
+	d.init(d.state, d.id, d.projectName, info)
+
+	return unlock, nil
+}
+
+// Update applies the supplied config to the ACL, validating it with the specified mode.
+// ACLs managed by the server cannot be updated. Returns the warnings about the deprecated constructs replaced in permissive mode.
+func (d *common) Update(config *api.NetworkACLPut, clientType request.ClientType, requestor *api.EventLifecycleRequestor, mode ValidationMode) ([]string, error) {
+	if d.info.Managed {
+		return nil, api.StatusErrorf(http.StatusForbidden, "Cannot update an ACL managed by the server")
+	}
+
+	unlock, err := d.lock()
+	if err != nil {
+		return nil, err
+	}
+
+	defer unlock()
+
+	// Validate the configuration.
+	warnings, err := d.validateConfig(config, mode)
+	if err != nil {
+		return nil, err
+	}
+
+	// Keep a copy of the current version so it can be recorded as a revision.
+	previous := d.Info()
+
+	revert := revert.New()
+	defer revert.Fail()
+
+	if clientType == request.ClientTypeNormal {
+		oldConfig := d.info.NetworkACLPut
+
+		err = d.state.DB.Cluster.Transaction(context.TODO(), func(ctx context.Context, tx *db.ClusterTx) error {
+			err := d.validateProjectLimits(ctx, tx, config)
+			if err != nil {
+				return err
+			}
+
+			// Update database. Its important this occurs before we attempt to apply to networks using the ACL
+			// as usage functions will inspect the database.
+			return tx.UpdateNetworkACL(ctx, d.id, config)
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		// Apply changes internally and reinitialize.
+		d.info.NetworkACLPut = *config
+		d.init(d.state, d.id, d.projectName, d.info)
+
+		revert.Add(func() {
+			_ = d.state.DB.Cluster.Transaction(context.TODO(), func(ctx context.Context, tx *db.ClusterTx) error {
+				return tx.UpdateNetworkACL(ctx, d.id, &oldConfig)
+			})
+
+			d.info.NetworkACLPut = oldConfig
+			d.init(d.state, d.id, d.projectName, d.info)
+
+			// Restore the previous rules on the bridge networks that may have been updated already.
+			d.restoreFirewall()
+		})
+	}
+
+	// Apply the changes to the networks using this ACL.
+	aclNets, err := d.applyRules(revert, clientType == request.ClientTypeNormal)
+	if err != nil {
+		return nil, err
+	}
+
+	// Apply ACL changes to non-OVN networks on cluster members.
+	if clientType == request.ClientTypeNormal && len(aclNets) > 0 {
+		// Notify all other nodes to update the network if no target specified.
+		notifier, err := cluster.NewNotifier(d.state, d.state.Endpoints.NetworkCert(), d.state.ServerCert(), cluster.NotifyAll)
+		if err != nil {
+			return nil, err
+		}
+
+		err = notifier(func(client incus.InstanceServer) error {
+			return client.UseProject(d.projectName).UpdateNetworkACL(d.info.Name, d.info.NetworkACLPut, "")
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Record the previous version now that the change has been applied everywhere.
+	if clientType == request.ClientTypeNormal {
+		err = d.recordRevision(previous, requestor)
+		if err != nil {
+			return nil, fmt.Errorf("Failed recording ACL revision: %w", err)
+		}
+	}
+
+	revert.Success()
+	return warnings, nil
+}
+
+// restoreFirewall reapplies the ACL's current rules to the non-OVN networks using it on all cluster members.
+// This is used when reverting a failed update, so errors are only logged.
+func (d *common) restoreFirewall() {
+	// The networks using the ACLs inheriting from this ACL also enforce its rules.
+	children, err := d.children()
+	if err != nil {
+		d.logger.Warn("Failed getting child ACLs", logger.Ctx{"err": err})
+		return
+	}
+
+	aclNets := map[string]NetworkACLUsage{}
+	err = NetworkUsage(d.state, d.projectName, append([]string{d.info.Name}, children...), aclNets)
+	if err != nil {
+		d.logger.Warn("Failed getting ACL network usage", logger.Ctx{"err": err})
+		return
+	}
+
+	hasFirewallNets := false
+	for _, aclNet := range aclNets {
+		if aclNet.Type != "bridge" {
+			continue
+		}
+
+		hasFirewallNets = true
+
+		err = FirewallApplyACLRules(d.state, d.logger, d.projectName, aclNet)
+		if err != nil {
+			d.logger.Warn("Failed restoring ACL rules on network", logger.Ctx{"network": aclNet.Name, "err": err})
+		}
+	}
+
+	if !hasFirewallNets {
+		return
+	}
+
+	notifier, err := cluster.NewNotifier(d.state, d.state.Endpoints.NetworkCert(), d.state.ServerCert(), cluster.NotifyAlive)
+	if err != nil {
+		d.logger.Warn("Failed creating cluster notifier", logger.Ctx{"err": err})
+		return
+	}
+
+	err = notifier(func(client incus.InstanceServer) error {
+		return client.UseProject(d.projectName).UpdateNetworkACL(d.info.Name, d.info.NetworkACLPut, "")
+	})
+	if err != nil {
+		d.logger.Warn("Failed restoring ACL rules on other cluster members", logger.Ctx{"err": err})
+	}
+}
+
+// applyRules applies the ACL's current rules to the networks using it. Non-OVN networks are only updated on the
+// local member and OVN networks are only updated if applyOVN is true. Returns the non-OVN networks using the ACL
+// or the ACLs inheriting from it.
+func (d *common) applyRules(reverter *revert.Reverter, applyOVN bool) (map[string]NetworkACLUsage, error) {
+	// The networks using the ACLs inheriting from this ACL also enforce its rules.
+	children, err := d.children()
+	if err != nil {
+		return nil, err
+	}
+
+	// Get a list of networks that are using this ACL or its children (either directly or indirectly via a NIC).
+	aclNetsByACL, err := networkUsageByACL(d.state, d.projectName, append([]string{d.info.Name}, children...))
+	if err != nil {
+		return nil, fmt.Errorf("Failed getting ACL network usage: %w", err)
+	}
+
+	aclNets := map[string]NetworkACLUsage{}
+	for _, usedNets := range aclNetsByACL {
+		maps.Copy(aclNets, usedNets)
+	}
+
+	// Separate out OVN networks from non-OVN networks. This is because OVN networks share ACL config, and
+	// so changes are not applied entirely on a per-network basis and need to be treated differently.
+	for k, v := range aclNets {
+		if v.Type == "ovn" {
+			delete(aclNets, k)
+		} else if v.Type != "bridge" {
+			d.raiseWarning(warningtype.NetworkACLUnsupportedNetwork, fmt.Sprintf("Network %q of type %q cannot enforce ACL", v.Name, v.Type))
+
+			return nil, fmt.Errorf("Unsupported network ACL type %q", v.Type)
+		}
+	}
+
+	// Apply ACL changes to non-OVN networks on this member.
+	for _, aclNet := range aclNets {
+		err = FirewallApplyACLRules(d.state, d.logger, d.projectName, aclNet)
+		if err != nil {
+			d.raiseWarning(warningtype.NetworkACLApplyFailure, fmt.Sprintf("Failed applying ACL to network %q: %v", aclNet.Name, err))
+
+			return nil, err
+		}
+	}
+
+	// If there are affected OVN networks, then apply the changes, but only if requested.
+	// This way we won't apply the same changes multiple times for each cluster member.
+	var aclOVNNets map[string]NetworkACLUsage
+	childOVNNets := map[string]map[string]NetworkACLUsage{}
+	if applyOVN {
+		aclOVNNets = ovnUsageNetworks(aclNetsByACL[d.info.Name])
+
+		// The OVN networks using the children hold a copy of this ACL's rules in the children's port groups.
+		for _, childName := range children {
+			childNets := ovnUsageNetworks(aclNetsByACL[childName])
+			if len(childNets) > 0 {
+				childOVNNets[childName] = childNets
+			}
+		}
+	}
+
+	if len(aclOVNNets) > 0 || len(childOVNNets) > 0 {
+		var aclNameIDs map[string]int64
+
+		err = d.state.DB.Cluster.Transaction(context.TODO(), func(ctx context.Context, tx *db.ClusterTx) error {
+			// Get map of ACL names to DB IDs (used for generating OVN port group names).
+			aclNameIDs, err = tx.GetNetworkACLIDsByNames(ctx, d.Project())
+
+			return err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("Failed getting network ACL IDs for security ACL update: %w", err)
+		}
+
+		// OVN may be briefly unreachable, for example during a northbound leader election, so the OVN changes
+		// are retried before giving up and reverting the database change.
+		var cleanup revert.Hook
+		err = ovnRetry(d.logger, d.state.GlobalConfig.NetworkACLsOVNRetries(), d.state.GlobalConfig.NetworkACLsOVNRetryDelay(), func() error {
+			// Check that OVN is available.
+			ovnnb, _, err := d.state.OVN()
+			if err != nil {
+				return err
+			}
+
+			// All the OVN changes of the update are applied in a single northbound transaction so that
+			// northd never sees a partially updated ACL.
+			txn := ovnnb.NewTransaction()
+
+			// Request that the ACL and any referenced ACLs in the ruleset are created in OVN.
+			// Pass aclOVNNets info, because although OVN networks share ACL port group definitions, when
+			// the ACL rules themselves use network specific selectors such as @internal/@external, we then
+			// need to apply those rules to each network affected by the ACL, so pass the full list of OVN
+			// networks affected by this ACL (either because the ACL is assigned directly or because it is
+			// assigned to an OVN NIC in an instance or profile).
+			cleanups := []revert.Hook{}
+			if len(aclOVNNets) > 0 {
+				cleanup, err := ovnEnsureACLs(d.state, d.logger, ovnnb, txn, d.projectName, aclNameIDs, aclOVNNets, []string{d.info.Name}, true)
+				if err != nil {
+					return fmt.Errorf("Failed ensuring ACL is configured in OVN: %w", err)
+				}
+
+				cleanups = append(cleanups, cleanup)
+			}
+
+			// The ACLs inheriting from this ACL hold a copy of its rules in their own port groups.
+			for childName, childNets := range childOVNNets {
+				cleanup, err := ovnEnsureACLs(d.state, d.logger, ovnnb, txn, d.projectName, aclNameIDs, childNets, []string{childName}, true)
+				if err != nil {
+					return fmt.Errorf("Failed ensuring child ACL %q is configured in OVN: %w", childName, err)
+				}
+
+				cleanups = append(cleanups, cleanup)
+			}
+
+			cleanup = func() {
+				for _, c := range cleanups {
+					c()
+				}
+			}
+
+			// Run unused port group cleanup in case any formerly referenced ACL in this ACL's rules means
+			// that an ACL port group is now considered unused.
+			err = ovnPortGroupDeleteIfUnused(d.state, d.logger, ovnnb, txn, d.projectName, nil, "", d.info.Name)
+			if err != nil {
+				return fmt.Errorf("Failed removing unused OVN port groups: %w", err)
+			}
+
+			err = txn.Commit(context.TODO())
+			if err != nil {
+				return fmt.Errorf("Failed ensuring ACL is configured in OVN: %w", err)
+			}
+
+			return nil
+		})
+		if err != nil {
+			ovnNetNames := make([]string, 0, len(aclOVNNets))
+			for netName := range aclOVNNets {
+				ovnNetNames = append(ovnNetNames, netName)
+			}
+
+			sort.Strings(ovnNetNames)
+			d.raiseWarning(warningtype.NetworkACLApplyFailure, fmt.Sprintf("Failed applying ACL in OVN to networks %s: %v", strings.Join(ovnNetNames, ", "), err))
 
-		if rule.SourcePort != "" {
-			return fmt.Errorf("Source port cannot be used without specifying protocol")
+			return nil, err
 		}
 
-		if rule.DestinationPort != "" {
-			return fmt.Errorf("Destination port cannot be used without specifying protocol")
-		}
+		reverter.Add(cleanup)
 	}
 
-	return nil
+	// The ACL is now enforced everywhere it's used on this member, so clear any earlier failure.
+	d.resolveWarnings(warningtype.NetworkACLApplyFailure, warningtype.NetworkACLUnsupportedNetwork)
+
+	return aclNets, nil
 }
 
-// validateRuleSubjects checks that the source or destination subjects for a rule are valid.
-// Accepts a validSubjectNames list of valid ACL or special classifier names.
-// Returns whether the subjects include names, IPv4 and IPv6 addresses respectively.
-func (d *common) validateRuleSubjects(fieldName string, direction ruleDirection, subjects []string, validSubjectNames []string) (bool, bool, bool, error) {
-	// Check if named subjects are allowed in field/direction combination.
-	allowSubjectNames := false
-	if (fieldName == "Source" && direction == ruleDirectionIngress) || (fieldName == "Destination" && direction == ruleDirectionEgress) {
-		allowSubjectNames = true
+// raiseWarning records a warning against the ACL on the local member. Failures are only logged as the error
+// that caused the warning is more relevant to the caller.
+func (d *common) raiseWarning(typeCode warningtype.Type, message string) {
+	err := d.state.DB.Cluster.Transaction(context.TODO(), func(ctx context.Context, tx *db.ClusterTx) error {
+		return tx.UpsertWarningLocalNode(ctx, d.projectName, dbCluster.TypeNetworkACL, int(d.id), typeCode, message)
+	})
+	if err != nil {
+		d.logger.Warn("Failed to create warning", logger.Ctx{"err": err})
 	}
+}
 
-	isNetworkAddress := func(value string) (uint, error) {
-		ip := net.ParseIP(value)
-		if ip == nil {
-			return 0, fmt.Errorf("Not an IP address %q", value)
+// resolveWarnings resolves the warnings of the given types recorded against the ACL on the local member.
+func (d *common) resolveWarnings(typeCodes ...warningtype.Type) {
+	for _, typeCode := range typeCodes {
+		err := warnings.ResolveWarningsByLocalNodeAndProjectAndTypeAndEntity(d.state.DB.Cluster, d.projectName, typeCode, dbCluster.TypeNetworkACL, int(d.id))
+		if err != nil {
+			d.logger.Warn("Failed to resolve warning", logger.Ctx{"err": err})
 		}
+	}
+}
 
-		var ipVersion uint = 4
-		if ip.To4() == nil {
-			ipVersion = 6
-		}
+// children returns the names of the ACLs inheriting from the ACL, directly or through other ACLs, sorted by name.
+func (d *common) children() ([]string, error) {
+	var children []string
+
+	err := d.state.DB.Cluster.Transaction(context.TODO(), func(ctx context.Context, tx *db.ClusterTx) error {
+		var err error
+
+		children, err = childACLs(ctx, tx, d.projectName, d.info.Name)
 
-		return ipVersion, nil
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Failed getting child ACLs: %w", err)
 	}
 
-	isNetworkAddressCIDR := func(value string) (uint, error) {
-		ip, _, err := net.ParseCIDR(value)
-		if err != nil {
-			return 0, err
-		}
+	return children, nil
+}
 
-		var ipVersion uint = 4
-		if ip.To4() == nil {
-			ipVersion = 6
-		}
+// ovnNetworks returns the OVN networks using the ACL.
+func (d *common) ovnNetworks() (map[string]NetworkACLUsage, error) {
+	aclOVNNets, err := AffectedOVNNetworks(d.state, d.projectName, d.info.Name)
+	if err != nil {
+		return nil, fmt.Errorf("Failed getting ACL network usage: %w", err)
+	}
+
+	return aclOVNNets, nil
+}
 
-		return ipVersion, nil
+// State returns whether the rules applied to the OVN networks using the ACL match its current definition.
+func (d *common) State() (*api.NetworkACLState, error) {
+	aclOVNNets, err := d.ovnNetworks()
+	if err != nil {
+		return nil, err
 	}
 
-	isNetworkRange := func(value string) (uint, error) {
-		err := validate.IsNetworkRange(value)
-		if err != nil {
-			return 0, err
-		}
+	aclState := &api.NetworkACLState{
+		Networks: map[string]api.NetworkACLNetworkState{},
+	}
 
-		ips := strings.SplitN(value, "-", 2)
-		if len(ips) != 2 {
-			return 0, fmt.Errorf("IP range must contain start and end IP addresses")
-		}
+	if len(aclOVNNets) == 0 {
+		return aclState, nil
+	}
 
-		ip := net.ParseIP(ips[0])
+	ovnnb, _, err := d.state.OVN()
+	if err != nil {
+		return nil, err
+	}
 
-		var ipVersion uint = 4
-		if ip.To4() == nil {
-			ipVersion = 6
-		}
+	var aclNameIDs map[string]int64
+
+	err = d.state.DB.Cluster.Transaction(context.TODO(), func(ctx context.Context, tx *db.ClusterTx) error {
+		// Get map of ACL names to DB IDs (used for generating OVN port group names).
+		aclNameIDs, err = tx.GetNetworkACLIDsByNames(ctx, d.Project())
+
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Failed getting network ACL IDs: %w", err)
+	}
 
-		return ipVersion, nil
+	aclState.Networks, err = OVNACLState(d.state, ovnnb, d.projectName, d.info, aclNameIDs, aclOVNNets)
+	if err != nil {
+		return nil, err
 	}
 
-	checks := []func(s string) (uint, error){
-		isNetworkAddress,
-		isNetworkAddressCIDR,
-		isNetworkRange,
+	// Failing to get the counters of a network doesn't prevent reporting the state of the others.
+	var netErrors map[string][]string
+	aclState.Rules, netErrors, err = d.ruleStats(aclOVNNets)
+	if err != nil {
+		return nil, err
 	}
 
-	validSubject := func(subject string) (uint, error) {
-		// Check if it is one of the network IP types.
-		for _, c := range checks {
-			ipVersion, err := c(subject)
-			if err == nil {
-				return ipVersion, nil // Found valid subject.
-			}
+	addNetErrors := func(netErrors map[string][]string) {
+		for netName, errs := range netErrors {
+			netState := aclState.Networks[netName]
+			netState.Errors = append(netState.Errors, errs...)
+			aclState.Networks[netName] = netState
 		}
+	}
 
-		// Check if it is one of the valid subject names.
-		for _, n := range validSubjectNames {
-			if subject == n {
-				if allowSubjectNames {
-					return 0, nil // Found valid subject.
-				}
+	addNetErrors(netErrors)
 
-				return 0, fmt.Errorf("Named subjects not allowed in %q for %q rules", fieldName, direction)
-			}
-		}
+	aclState.OVN, netErrors = OVNACLObjects(ovnnb, d.id, aclOVNNets)
+	addNetErrors(netErrors)
 
-		// Check if it looks like a network peer connection name.
-		if strings.HasPrefix(subject, "@") {
-			if allowSubjectNames {
-				return 0, nil // Found valid subject.
-			}
+	return aclState, nil
+}
 
-			return 0, fmt.Errorf("Named subjects not allowed in %q for %q rules", fieldName, direction)
-		}
+// SimulatePacket returns the index and action of the first rule of the direction matching the packet when the ACL
+// is applied to the network, or -1 and the network's default action if no rule matches. The rules inherited from
+// the parents of the ACL are included and counted first by the index. On OVN networks, the ACL's own rules are
+// evaluated before the inherited ones as those are placed in the next priority band.
+func (d *common) SimulatePacket(aclNet NetworkACLUsage, direction ruleDirection, pkt Packet) (int, string, error) {
+	if direction != ruleDirectionIngress && direction != ruleDirectionEgress {
+		return -1, "", fmt.Errorf("Invalid direction %q", direction)
+	}
+
+	err := validatePacket(pkt)
+	if err != nil {
+		return -1, "", fmt.Errorf("Invalid packet: %w", err)
+	}
 
-		return 0, fmt.Errorf("Invalid subject %q", subject)
+	defaultAction, _ := NICDefaults(nil, aclNet.Config, string(direction))
+
+	// The traffic of a disabled direction is left to the default action.
+	if !ruleDirectionEnabled(d.info.Config, direction) {
+		return -1, defaultAction, nil
 	}
 
-	hasIPv4 := false
-	hasIPv6 := false
-	hasName := false
+	var aclInfo *composedACL
+	err = d.state.DB.Cluster.Transaction(context.TODO(), func(ctx context.Context, tx *db.ClusterTx) error {
+		aclInfo, err = withParentRules(ctx, tx, d.projectName, d.info)
+
+		return err
+	})
+	if err != nil {
+		return -1, "", err
+	}
 
-	for _, s := range subjects {
-		ipVersion, err := validSubject(s)
+	memberAddresses := map[string][]string{}
+	subjectNames := ruleSubjectNames(&aclInfo.NetworkACLPut)
+	if len(subjectNames) > 0 {
+		memberAddresses, err = aclMemberAddresses(d.state, d.projectName, subjectNames)
 		if err != nil {
-			return false, false, false, err
+			return -1, "", fmt.Errorf("Failed getting addresses of network ACL members: %w", err)
 		}
+	}
 
-		switch ipVersion {
-		case 0:
-			hasName = true
-		case 4:
-			hasIPv4 = true
-		case 6:
-			hasIPv6 = true
-		}
+	resolve := simulateSubjectResolver(firewallNetworkSubnets(aclNet.Config), memberAddresses)
+
+	rules := aclInfo.Ingress
+	if direction == ruleDirectionEgress {
+		rules = aclInfo.Egress
 	}
 
-	return hasName, hasIPv4, hasIPv6, nil
-}
+	// The rules are evaluated by action within each group of indexes, in turn.
+	inheritedRules := aclInfo.inheritedRules(direction)
+	groups := [][]int{{0, len(rules)}}
+	band := ovnACLPriorityBand(d.info.Config)
+	if aclNet.Type == "ovn" && ovnACLInheritedPriorityBand(band) != band {
+		groups = [][]int{{inheritedRules, len(rules)}, {0, inheritedRules}}
+	}
 
-// validatePorts checks that the source or destination ports for a rule are valid.
-func (d *common) validatePorts(ports []string) error {
-	for _, port := range ports {
-		err := validate.IsNetworkPortRange(port)
-		if err != nil {
-			return err
+	now := time.Now()
+	for _, group := range groups {
+		for _, action := range ruleActionOrder {
+			for i := group[0]; i < group[1]; i++ {
+				rule := rules[i]
+				rule.Normalise()
+
+				if rule.Action != action || rule.State == "disabled" || !ruleIsActive(rule, now) {
+					continue
+				}
+
+				if rulePacketMatch(rule, pkt, resolve) {
+					return i, rule.Action, nil
+				}
+			}
 		}
 	}
 
-	return nil
+	return -1, defaultAction, nil
 }
 
-// Update applies the supplied config to the ACL.
-func (d *common) Update(config *api.NetworkACLPut, clientType request.ClientType) error {
-	// Validate the configuration.
-	err := d.validateConfig(config)
+// RuleStats returns the packet and byte counters of the ACL's rules keyed by rule ID.
+// Counters are only available for OVN networks and cover the traffic handled by the local server.
+func (d *common) RuleStats() (map[string]api.NetworkACLRuleStats, error) {
+	aclOVNNets, err := d.ovnNetworks()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	revert := revert.New()
-	defer revert.Fail()
-
-	if clientType == request.ClientTypeNormal {
-		oldConfig := d.info.NetworkACLPut
+	stats, netErrors, err := d.ruleStats(aclOVNNets)
+	if err != nil {
+		return nil, err
+	}
 
-		err = d.state.DB.Cluster.Transaction(context.TODO(), func(ctx context.Context, tx *db.ClusterTx) error {
-			// Update database. Its important this occurs before we attempt to apply to networks using the ACL
-			// as usage functions will inspect the database.
-			return tx.UpdateNetworkACL(ctx, d.id, config)
-		})
-		if err != nil {
-			return err
-		}
+	if len(netErrors) > 0 {
+		netName := slices.Sorted(maps.Keys(netErrors))[0]
 
-		// Apply changes internally and reinitialize.
-		d.info.NetworkACLPut = *config
-		d.init(d.state, d.id, d.projectName, d.info)
+		return nil, fmt.Errorf("Failed getting rule counters on network %q: %s", netName, netErrors[netName][0])
+	}
 
-		revert.Add(func() {
-			_ = d.state.DB.Cluster.Transaction(context.TODO(), func(ctx context.Context, tx *db.ClusterTx) error {
-				return tx.UpdateNetworkACL(ctx, d.id, &oldConfig)
-			})
+	return stats, nil
+}
 
-			d.info.NetworkACLPut = oldConfig
-			d.init(d.state, d.id, d.projectName, d.info)
-		})
+// ruleStats returns the counters of the ACL's rules on the specified OVN networks, along with the errors that
+// prevented getting the counters of some of the networks keyed by network name.
+func (d *common) ruleStats(aclOVNNets map[string]NetworkACLUsage) (map[string]api.NetworkACLRuleStats, map[string][]string, error) {
+	if len(aclOVNNets) == 0 {
+		return map[string]api.NetworkACLRuleStats{}, nil, nil
 	}
 
-	// Get a list of networks that are using this ACL (either directly or indirectly via a NIC).
-	aclNets := map[string]NetworkACLUsage{}
-	err = NetworkUsage(d.state, d.projectName, []string{d.info.Name}, aclNets)
+	ovnnb, ovnsb, err := d.state.OVN()
 	if err != nil {
-		return fmt.Errorf("Failed getting ACL network usage: %w", err)
+		return nil, nil, err
 	}
 
-	// Separate out OVN networks from non-OVN networks. This is because OVN networks share ACL config, and
-	// so changes are not applied entirely on a per-network basis and need to be treated differently.
-	aclOVNNets := map[string]NetworkACLUsage{}
-	for k, v := range aclNets {
-		if v.Type == "ovn" {
-			delete(aclNets, k)
-			aclOVNNets[k] = v
-		} else if v.Type != "bridge" {
-			return fmt.Errorf("Unsupported network ACL type %q", v.Type)
-		}
+	vswitch, err := d.state.OVS()
+	if err != nil {
+		return nil, nil, err
 	}
 
-	// Apply ACL changes to non-OVN networks on this member.
-	for _, aclNet := range aclNets {
-		err = FirewallApplyACLRules(d.state, d.logger, d.projectName, aclNet)
-		if err != nil {
-			return err
-		}
+	counters := &ovnACLCounters{
+		nb:      ovnnb,
+		sb:      ovnsb,
+		vswitch: vswitch,
+		bridge:  d.state.GlobalConfig.NetworkOVNIntegrationBridge(),
 	}
 
-	// If there are affected OVN networks, then apply the changes, but only if the request type is normal.
-	// This way we won't apply the same changes multiple times for each cluster member.
-	if len(aclOVNNets) > 0 && clientType == request.ClientTypeNormal {
-		// Check that OVN is available.
-		ovnnb, _, err := d.state.OVN()
-		if err != nil {
-			return err
+	stats, netErrors := d.ovnRuleStats(counters, aclOVNNets)
+
+	return stats, netErrors, nil
+}
+
+// ovnRuleStats sums up the counters of the OVN ACLs in the port groups of the ACL used by the specified networks for
+// each of the ACL's rules. The counters of the port groups that can't be read are left out and the errors are
+// returned keyed by network name, with the errors of the port group shared by all networks reported for each of them.
+func (d *common) ovnRuleStats(counters ovnACLCounterSource, aclOVNNets map[string]NetworkACLUsage) (map[string]api.NetworkACLRuleStats, map[string][]string) {
+	stats := map[string]api.NetworkACLRuleStats{}
+	netErrors := map[string][]string{}
+
+	for _, direction := range []ruleDirection{ruleDirectionIngress, ruleDirectionEgress} {
+		rules := d.info.Ingress
+		if direction == ruleDirectionEgress {
+			rules = d.info.Egress
 		}
 
-		var aclNameIDs map[string]int64
+		for i, rule := range rules {
+			id := ruleID(direction, rule)
 
-		err = d.state.DB.Cluster.Transaction(context.TODO(), func(ctx context.Context, tx *db.ClusterTx) error {
-			// Get map of ACL names to DB IDs (used for generating OVN port group names).
-			aclNameIDs, err = tx.GetNetworkACLIDsByNames(ctx, d.Project())
+			// Identical rules share the same ID, report the first one.
+			_, found := stats[id]
+			if found {
+				continue
+			}
 
-			return err
-		})
-		if err != nil {
-			return fmt.Errorf("Failed getting network ACL IDs for security ACL update: %w", err)
+			stats[id] = api.NetworkACLRuleStats{Direction: string(direction), Index: i}
 		}
+	}
 
-		// Request that the ACL and any referenced ACLs in the ruleset are created in OVN.
-		// Pass aclOVNNets info, because although OVN networks share ACL port group definitions, when the
-		// ACL rules themselves use network specific selectors such as @internal/@external, we then need to
-		// apply those rules to each network affected by the ACL, so pass the full list of OVN networks
-		// affected by this ACL (either because the ACL is assigned directly or because it is assigned to
-		// an OVN NIC in an instance or profile).
-		cleanup, err := OVNEnsureACLs(d.state, d.logger, ovnnb, d.projectName, aclNameIDs, aclOVNNets, []string{d.info.Name}, true)
+	addCounters := func(portGroupName ovn.OVNPortGroup, netNames ...string) {
+		portGroupCounters, err := counters.PortGroupRuleCounters(context.TODO(), portGroupName)
 		if err != nil {
-			return fmt.Errorf("Failed ensuring ACL is configured in OVN: %w", err)
+			for _, netName := range netNames {
+				netErrors[netName] = append(netErrors[netName], fmt.Sprintf("Failed getting rule counters of port group %q: %v", portGroupName, err))
+			}
+
+			return
 		}
 
-		revert.Add(cleanup)
+		for id, flowStats := range portGroupCounters {
+			ruleStats, found := stats[id]
+			if !found {
+				continue // Left over from a previous version of the rules.
+			}
 
-		// Run unused port group cleanup in case any formerly referenced ACL in this ACL's rules means that
-		// an ACL port group is now considered unused.
-		err = OVNPortGroupDeleteIfUnused(d.state, d.logger, ovnnb, d.projectName, nil, "", d.info.Name)
-		if err != nil {
-			return fmt.Errorf("Failed removing unused OVN port groups: %w", err)
+			ruleStats.Packets += int64(flowStats.Packets)
+			ruleStats.Bytes += int64(flowStats.Bytes)
+			stats[id] = ruleStats
 		}
 	}
 
-	// Apply ACL changes to non-OVN networks on cluster members.
-	if clientType == request.ClientTypeNormal && len(aclNets) > 0 {
-		// Notify all other nodes to update the network if no target specified.
-		notifier, err := cluster.NewNotifier(d.state, d.state.Endpoints.NetworkCert(), d.state.ServerCert(), cluster.NotifyAll)
-		if err != nil {
-			return err
-		}
+	addCounters(OVNACLPortGroupName(d.id), slices.Collect(maps.Keys(aclOVNNets))...)
 
-		err = notifier(func(client incus.InstanceServer) error {
-			return client.UseProject(d.projectName).UpdateNetworkACL(d.info.Name, d.info.NetworkACLPut, "")
-		})
-		if err != nil {
-			return err
-		}
+	for _, aclNet := range aclOVNNets {
+		addCounters(OVNACLNetworkPortGroupName(d.id, aclNet.ID), aclNet.Name)
 	}
 
-	revert.Success()
+	return stats, netErrors
+}
+
+// Refresh reapplies the ACL's current rules to the networks using it without changing its configuration.
+// This is used when rules enter or leave their validity window.
+func (d *common) Refresh(applyOVN bool) error {
+	reverter := revert.New()
+	defer reverter.Fail()
+
+	_, err := d.applyRules(reverter, applyOVN)
+	if err != nil {
+		return err
+	}
+
+	reverter.Success()
 	return nil
 }
 
-// Rename renames the ACL if not in use.
-func (d *common) Rename(newName string) error {
-	_, err := LoadByName(d.state, d.projectName, newName)
-	if err == nil {
-		return fmt.Errorf("An ACL by that name exists already")
+// Rename renames the ACL if not in use and not managed by the server.
+func (d *common) Rename(newName string, requestor *api.EventLifecycleRequestor) error {
+	if d.info.Managed {
+		return api.StatusErrorf(http.StatusForbidden, "Cannot rename an ACL managed by the server")
 	}
 
-	isUsed, err := d.isUsed()
+	unlock, err := d.lock()
 	if err != nil {
 		return err
 	}
 
-	if isUsed {
-		return fmt.Errorf("Cannot rename an ACL that is in use")
+	defer unlock()
+
+	_, err = LoadByName(d.state, d.projectName, newName)
+	if err == nil {
+		return fmt.Errorf("An ACL by that name exists already")
+	}
+
+	err = d.inUseError("rename")
+	if err != nil {
+		return err
 	}
 
 	err = d.validateName(newName)
@@ -727,6 +2289,8 @@ func (d *common) Rename(newName string) error {
 		return err
 	}
 
+	previous := d.Info()
+
 	err = d.state.DB.Cluster.Transaction(context.TODO(), func(ctx context.Context, tx *db.ClusterTx) error {
 		return tx.RenameNetworkACL(ctx, d.id, newName)
 	})
@@ -737,18 +2301,30 @@ func (d *common) Rename(newName string) error {
 	// Apply changes internally.
 	d.info.Name = newName
 
+	err = d.recordRevision(previous, requestor)
+	if err != nil {
+		return fmt.Errorf("Failed recording ACL revision: %w", err)
+	}
+
 	return nil
 }
 
-// Delete deletes the ACL.
+// Delete deletes the ACL if not in use and not managed by the server.
 func (d *common) Delete() error {
-	isUsed, err := d.isUsed()
+	if d.info.Managed {
+		return api.StatusErrorf(http.StatusForbidden, "Cannot delete an ACL managed by the server")
+	}
+
+	unlock, err := d.lock()
 	if err != nil {
 		return err
 	}
 
-	if isUsed {
-		return fmt.Errorf("Cannot delete an ACL that is in use")
+	defer unlock()
+
+	err = d.inUseError("delete")
+	if err != nil {
+		return err
 	}
 
 	return d.state.DB.Cluster.Transaction(context.TODO(), func(ctx context.Context, tx *db.ClusterTx) error {
@@ -759,13 +2335,18 @@ func (d *common) Delete() error {
 // GetLog gets the ACL log.
 func (d *common) GetLog(clientType request.ClientType) (string, error) {
 	// ACLs aren't specific to a particular network type but the log only works with OVN.
-	logPath := "/var/log/ovn/ovn-controller.log"
-	if !util.PathExists(logPath) {
+	if !util.PathExists(ovnControllerLogPath) {
 		return "", fmt.Errorf("Only OVN log entries may be retrieved at this time")
 	}
 
+	// Get the networks to attribute the log entries to.
+	networks, err := logNetworks(d.state, d.projectName, d.info.Name)
+	if err != nil {
+		return "", err
+	}
+
 	// Open the log file.
-	logFile, err := os.Open(logPath)
+	logFile, err := os.Open(ovnControllerLogPath)
 	if err != nil {
 		return "", fmt.Errorf("Couldn't open OVN log file: %w", err)
 	}
@@ -775,7 +2356,11 @@ func (d *common) GetLog(clientType request.ClientType) (string, error) {
 	logEntries := []string{}
 	scanner := bufio.NewScanner(logFile)
 	for scanner.Scan() {
-		logEntry := ovnParseLogEntry(scanner.Text(), fmt.Sprintf("incus_acl%d-", d.id))
+		logEntry, err := d.logEntry(networks, scanner.Text())
+		if err != nil {
+			return "", err
+		}
+
 		if logEntry == "" {
 			continue
 		}
@@ -843,3 +2428,138 @@ func (d *common) GetLog(clientType request.ClientType) (string, error) {
 
 	return strings.Join(logEntries, "\n") + "\n", nil
 }
+
+// FollowLog returns a function streaming the ACL log entries to w as they get logged until the context is cancelled.
+// The errors preventing the log from being followed are returned before streaming starts. Cluster members without an
+// OVN log are skipped with a warning, unless the log is only followed on this server.
+func (d *common) FollowLog(clientType request.ClientType) (func(ctx context.Context, w io.Writer) error, error) {
+	// Setup notifier to reach the rest of the cluster.
+	var notifier cluster.Notifier
+	if clientType == request.ClientTypeNormal && d.state.ServerClustered {
+		var err error
+
+		notifier, err = cluster.NewNotifier(d.state, d.state.Endpoints.NetworkCert(), d.state.ServerCert(), cluster.NotifyAlive)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// ACLs aren't specific to a particular network type but the log only works with OVN.
+	followLocal := util.PathExists(ovnControllerLogPath)
+	if !followLocal {
+		if notifier == nil {
+			return nil, fmt.Errorf("Only OVN log entries may be retrieved at this time")
+		}
+
+		d.logger.Warn("Skipping following the ACL log of the local member without an OVN log")
+	}
+
+	// Get the networks to attribute the log entries to.
+	networks, err := logNetworks(d.state, d.projectName, d.info.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(ctx context.Context, w io.Writer) error {
+		// Let the client know that the log is being followed before the first entry comes in.
+		_, err := w.Write(nil)
+		if err != nil {
+			return err
+		}
+
+		// Prevent concurrent writes of the log entries.
+		mu := sync.Mutex{}
+		writeEntry := func(entry string) error {
+			mu.Lock()
+			defer mu.Unlock()
+
+			_, err := fmt.Fprintln(w, entry)
+			return err
+		}
+
+		g, ctx := errgroup.WithContext(ctx)
+
+		if followLocal {
+			g.Go(func() error {
+				return followLogFile(ctx, ovnControllerLogPath, func(line string) error {
+					logEntry, err := d.logEntry(networks, line)
+					if err != nil {
+						return err
+					}
+
+					if logEntry == "" {
+						return nil
+					}
+
+					return writeEntry(logEntry)
+				})
+			})
+		}
+
+		if notifier != nil {
+			g.Go(func() error {
+				return notifier(func(client incus.InstanceServer) error {
+					entries, err := client.UseProject(d.projectName).GetNetworkACLLogStream(d.info.Name)
+					if err != nil {
+						// Members without an OVN log don't have entries to stream.
+						connInfo, _ := client.GetConnectionInfo()
+						d.logger.Warn("Skipping following the ACL log of a cluster member", logger.Ctx{"member": connInfo.URL, "err": err})
+
+						return nil
+					}
+
+					// Close the stream once done following the log.
+					go func() {
+						<-ctx.Done()
+						_ = entries.Close()
+					}()
+
+					scanner := bufio.NewScanner(entries)
+					for scanner.Scan() {
+						entry := scanner.Text()
+						if entry == "" {
+							continue
+						}
+
+						err = writeEntry(entry)
+						if err != nil {
+							return err
+						}
+					}
+
+					// Reading fails once the stream gets closed.
+					if ctx.Err() != nil {
+						return nil
+					}
+
+					err = scanner.Err()
+					if err != nil {
+						return fmt.Errorf("Failed to read OVN log stream: %w", err)
+					}
+
+					return nil
+				})
+			})
+		}
+
+		return g.Wait()
+	}, nil
+}
+
+// logEntry returns the JSON encoded log entry for the OVN log line if it belongs to the ACL.
+// An empty string is returned for lines not belonging to the ACL.
+func (d *common) logEntry(networks []logNetwork, line string) (string, error) {
+	entry := ovnParseLogEntry(line, fmt.Sprintf("incus_acl%d-", d.id))
+	if entry == nil {
+		return "", nil
+	}
+
+	entry.Network = logEntryNetwork(networks, entry)
+
+	out, err := json.Marshal(entry)
+	if err != nil {
+		return "", err
+	}
+
+	return string(out), nil
+}
@@ -0,0 +1,1639 @@
+package acl
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lxc/incus/v6/internal/filter"
+	clusterConfig "github.com/lxc/incus/v6/internal/server/cluster/config"
+	"github.com/lxc/incus/v6/internal/server/cluster/request"
+	"github.com/lxc/incus/v6/internal/server/db"
+	dbCluster "github.com/lxc/incus/v6/internal/server/db/cluster"
+	"github.com/lxc/incus/v6/internal/server/state"
+	localUtil "github.com/lxc/incus/v6/internal/server/util"
+	"github.com/lxc/incus/v6/shared/api"
+	"github.com/lxc/incus/v6/shared/util"
+)
+
+// validateStrict validates the ACL config in strict mode, ignoring the warnings.
+func validateStrict(d *common, info *api.NetworkACLPut) error {
+	_, err := d.validateConfig(info, ValidationModeStrict)
+
+	return err
+}
+
+func TestValidateRuleCount(t *testing.T) {
+	oldMaxRules := maxRules
+	maxRules = 3
+	defer func() { maxRules = oldMaxRules }()
+
+	rule := api.NetworkACLRule{Action: "allow", State: "enabled"}
+	d := &common{}
+
+	// Staying at the limit is allowed.
+	info := &api.NetworkACLPut{
+		Ingress: []api.NetworkACLRule{rule, rule},
+		Egress:  []api.NetworkACLRule{rule},
+	}
+
+	assert.NoError(t, d.validateRuleCount(info))
+
+	// Exceeding the limit with the combined ingress and egress rules is rejected.
+	info.Egress = append(info.Egress, rule)
+	assert.ErrorContains(t, d.validateRuleCount(info), "Too many rules (4)")
+
+	// The check runs before any per-rule validation.
+	assert.ErrorContains(t, validateStrict(d, info), "Too many rules (4)")
+}
+
+func TestValidateRuleSubjectsAny(t *testing.T) {
+	d := &common{}
+
+	tests := []struct {
+		subject string
+		hasIPv4 bool
+		hasIPv6 bool
+	}{
+		{subject: "any", hasIPv4: true, hasIPv6: true},
+		{subject: "any4", hasIPv4: true},
+		{subject: "any6", hasIPv6: true},
+	}
+
+	for _, test := range tests {
+		hasName, hasIPv4, hasIPv6, err := d.validateRuleSubjects("Destination", ruleDirectionIngress, []string{test.subject}, nil)
+		require.NoError(t, err)
+		assert.False(t, hasName, test.subject)
+		assert.Equal(t, test.hasIPv4, hasIPv4, test.subject)
+		assert.Equal(t, test.hasIPv6, hasIPv6, test.subject)
+	}
+}
+
+func TestValidateRuleSubjectsRange(t *testing.T) {
+	d := &common{}
+
+	tests := []struct {
+		subject string
+		hasIPv4 bool
+		hasIPv6 bool
+		err     string
+	}{
+		{subject: "192.0.2.1-192.0.2.10", hasIPv4: true},
+		{subject: "192.0.2.1-192.0.2.1", hasIPv4: true},
+		{subject: "2001:db8::1-2001:db8::ff", hasIPv6: true},
+		{subject: "::ffff:192.0.2.1-192.0.2.10", hasIPv4: true},
+		{subject: "192.168.1.10-192.168.1.1", err: `element 0 "192.168.1.10-192.168.1.1": IP range is reversed, start address must not be higher than end address`},
+		{subject: "2001:db8::ff-2001:db8::1", err: `element 0 "2001:db8::ff-2001:db8::1": IP range is reversed, start address must not be higher than end address`},
+		{subject: "10.0.0.1-fd42::1", err: `element 0 "10.0.0.1-fd42::1": IP range mixes IPv4 and IPv6 addresses`},
+		{subject: "fd42::1-10.0.0.1", err: `element 0 "fd42::1-10.0.0.1": IP range mixes IPv6 and IPv4 addresses`},
+		{subject: "10.0.0.300-10.0.0.1", err: `element 0 "10.0.0.300-10.0.0.1": Invalid start address "10.0.0.300" in IP range`},
+		{subject: "10.0.0.1-", err: `element 0 "10.0.0.1-": Invalid end address "" in IP range`},
+		{subject: "web-servers", err: `element 0 "web-servers": not an IP address, CIDR, range or known ACL name`},
+	}
+
+	for _, test := range tests {
+		hasName, hasIPv4, hasIPv6, err := d.validateRuleSubjects("Destination", ruleDirectionIngress, []string{test.subject}, nil)
+		if test.err != "" {
+			assert.EqualError(t, err, test.err, test.subject)
+			continue
+		}
+
+		require.NoError(t, err, test.subject)
+		assert.False(t, hasName, test.subject)
+		assert.Equal(t, test.hasIPv4, hasIPv4, test.subject)
+		assert.Equal(t, test.hasIPv6, hasIPv6, test.subject)
+	}
+
+	// The family of a range is used by the ICMP protocol checks.
+	rule := api.NetworkACLRule{Action: "allow", State: "enabled", Protocol: "icmp4", Destination: "2001:db8::1-2001:db8::ff"}
+	assert.EqualError(t, d.validateRule(ruleDirectionIngress, rule, nil), `Cannot use IPv6 destination addresses with "icmp4" protocol`)
+
+	rule.Destination = "10.0.0.1-fd42::1"
+	assert.ErrorContains(t, d.validateRule(ruleDirectionIngress, rule, nil), "mixes IPv4 and IPv6 addresses")
+}
+
+func TestValidateRuleSubjectsZone(t *testing.T) {
+	d := &common{}
+
+	tests := []struct {
+		subject string
+		err     string
+	}{
+		{subject: "fe80::1%eth0"},
+		{subject: "FE80::a:b%enp5s0"},
+		{subject: "fe80::1%", err: `element 0 "fe80::1%": Invalid zone "": Network interface is too short (minimum 2 characters)`},
+		{subject: "fe80::1%eth/0", err: `element 0 "fe80::1%eth/0": Invalid zone "eth/0": Network interface contains invalid characters`},
+		{subject: "2001:db8::1%eth0", err: `element 0 "2001:db8::1%eth0": Zones can only be used with link-local addresses`},
+		{subject: "169.254.0.1%eth0", err: `element 0 "169.254.0.1%eth0": Zones can only be used with IPv6 addresses`},
+		{subject: "fe80::/64%eth0", err: `element 0 "fe80::/64%eth0": not an IP address, CIDR, range or known ACL name`},
+	}
+
+	for _, test := range tests {
+		hasName, hasIPv4, hasIPv6, err := d.validateRuleSubjects("Destination", ruleDirectionIngress, []string{test.subject}, nil)
+		if test.err != "" {
+			assert.EqualError(t, err, test.err, test.subject)
+			continue
+		}
+
+		require.NoError(t, err, test.subject)
+		assert.False(t, hasName, test.subject)
+		assert.False(t, hasIPv4, test.subject)
+		assert.True(t, hasIPv6, test.subject)
+	}
+
+	// Zoned addresses are IPv6 ones for the ICMP protocol checks.
+	rule := api.NetworkACLRule{Action: "allow", State: "enabled", Protocol: "icmp4", Destination: "fe80::1%eth0"}
+	assert.EqualError(t, d.validateRule(ruleDirectionIngress, rule, nil), `Cannot use IPv6 destination addresses with "icmp4" protocol`)
+
+	// The zone is kept by normalisation, but rejected by OVN and the firewall drivers which can't match it.
+	rule = api.NetworkACLRule{Destination: "FE80:0::1%eth0"}
+	rule.Normalise()
+	assert.Equal(t, "fe80::1%eth0", rule.Destination)
+
+	_, _, _, err := ovnRuleSubjectToOVNACLMatch("dst", nil, nil, rule.Destination)
+	assert.EqualError(t, err, `Zoned address subject "fe80::1%eth0" isn't supported on OVN networks`)
+
+	_, err = firewallRuleSubjects(rule.Destination, nil, nil)
+	assert.EqualError(t, err, `Zoned address subject "fe80::1%eth0" isn't supported on bridge networks`)
+}
+
+func TestRuleSubjectFamily(t *testing.T) {
+	for subject, family := range map[string]uint{
+		"192.0.2.1":                   4,
+		"192.0.2.0/24":                4,
+		"192.0.2.1-192.0.2.10":        4,
+		"::ffff:192.0.2.1-192.0.2.10": 4,
+		"2001:db8::1":                 6,
+		"2001:db8::/32":               6,
+		"2001:db8::1-2001:db8::ff":    6,
+		"@ipv4":                       4,
+		"@ipv6":                       6,
+		"fe80::1%br-int":              6, // Zones can contain dashes.
+		"10.0.0.1-fd42::1":            0, // Both ends of ranges are checked.
+		"fd42::1-10.0.0.1":            0,
+		"web-servers":                 0,
+		"@internal":                   0,
+	} {
+		assert.Equal(t, family, ruleSubjectFamily(subject), subject)
+	}
+
+	// Subjects without a family are kept in both rules when splitting rules along the families.
+	rule := api.NetworkACLRule{Action: "allow", State: "enabled", Source: "192.0.2.1,2001:db8::1", Destination: "10.0.0.1-fd42::1,192.0.2.2,2001:db8::2"}
+	rules := SplitByFamily(rule)
+	require.Len(t, rules, 2)
+	assert.Equal(t, "10.0.0.1-fd42::1,192.0.2.2", rules[0].Destination)
+	assert.Equal(t, "10.0.0.1-fd42::1,2001:db8::2", rules[1].Destination)
+}
+
+func TestValidateRuleSubjectsErrors(t *testing.T) {
+	d := &common{}
+	validSubjectNames := ruleValidSubjectNames(map[string]int64{"web": 1, "webs": 2, "database": 3, "db": 4})
+
+	tests := []struct {
+		field     string
+		direction ruleDirection
+		subjects  string
+		err       string
+	}{
+		// The position of the element is reported, counting from 0.
+		{"Source", ruleDirectionIngress, "10.0.0.1,10.0.0.2,10.0.0", `element 2 "10.0.0": not an IP address, CIDR, range or known ACL name`},
+		{"Source", ruleDirectionIngress, "any,10.0.0.1-10.0.0", `element 1 "10.0.0.1-10.0.0": Invalid end address "10.0.0" in IP range`},
+		{"Source", ruleDirectionIngress, "web,vlan:5000", `element 1 "vlan:5000": Invalid VLAN subject "vlan:5000", VLAN ID must be between 0 and 4094`},
+		{"Destination", ruleDirectionIngress, "10.0.0.1,web", `element 1 "web": Named subjects not allowed in "Destination" for "ingress" rules`},
+
+		// Typo'd names suggest the closest names, closest first.
+		{"Source", ruleDirectionIngress, "webz", `element 0 "webz": not an IP address, CIDR, range or known ACL name, did you mean "web" or "webs"?`},
+		{"Source", ruleDirectionIngress, "wbe", `element 0 "wbe": not an IP address, CIDR, range or known ACL name, did you mean "web"?`},
+		{"Destination", ruleDirectionEgress, "10.0.0.1,databse", `element 1 "databse": not an IP address, CIDR, range or known ACL name, did you mean "database"?`},
+		{"Source", ruleDirectionIngress, "web,#intenral", `element 1 "#intenral": not an IP address, CIDR, range or known ACL name, did you mean "@internal"?`},
+
+		// Names too far off, and names in fields that can't use them, get no suggestions.
+		{"Source", ruleDirectionIngress, "frontend", `element 0 "frontend": not an IP address, CIDR, range or known ACL name`},
+		{"Source", ruleDirectionIngress, "dc", `element 0 "dc": not an IP address, CIDR, range or known ACL name`},
+		{"Destination", ruleDirectionIngress, "wbe", `element 0 "wbe": not an IP address, CIDR, range or known ACL name`},
+	}
+
+	for _, test := range tests {
+		_, _, _, err := d.validateRuleSubjects(test.field, test.direction, util.SplitNTrimSpace(test.subjects, ",", -1, false), validSubjectNames)
+		assert.EqualError(t, err, test.err, test.subjects)
+	}
+
+	// The field is reported along with the element.
+	rule := api.NetworkACLRule{Action: "allow", State: "enabled", Source: "192.0.2.1,192.0.2.300"}
+	assert.EqualError(t, d.validateRule(ruleDirectionIngress, rule, validSubjectNames), `Invalid Source: element 1 "192.0.2.300": not an IP address, CIDR, range or known ACL name`)
+}
+
+func TestClassifySubject(t *testing.T) {
+	validNames := ruleValidSubjectNames(map[string]int64{"web": 1, "api.example.com": 2})
+
+	tests := []struct {
+		subject     string
+		subjectType api.NetworkACLSubjectType
+		err         string
+	}{
+		{subject: "192.0.2.1", subjectType: api.NetworkACLSubjectTypeIPv4},
+		{subject: "2001:db8::1", subjectType: api.NetworkACLSubjectTypeIPv6},
+		{subject: "::ffff:192.0.2.1", subjectType: api.NetworkACLSubjectTypeIPv4},
+		{subject: "fe80::1%eth0", subjectType: api.NetworkACLSubjectTypeIPv6},
+		{subject: "192.0.2.0/24", subjectType: api.NetworkACLSubjectTypeCIDR},
+		{subject: "2001:db8::/64", subjectType: api.NetworkACLSubjectTypeCIDR},
+		{subject: "192.0.2.1-192.0.2.10", subjectType: api.NetworkACLSubjectTypeRange},
+		{subject: "2001:db8::1-2001:db8::ff", subjectType: api.NetworkACLSubjectTypeRange},
+		{subject: "web", subjectType: api.NetworkACLSubjectTypeName},
+		{subject: "@ovn1/peer1", subjectType: api.NetworkACLSubjectTypeName},
+		{subject: "www.example.com", subjectType: api.NetworkACLSubjectTypeHostName},
+		{subject: "@internal", subjectType: api.NetworkACLSubjectTypeSpecial},
+		{subject: "@external", subjectType: api.NetworkACLSubjectTypeSpecial},
+		{subject: "#internal", subjectType: api.NetworkACLSubjectTypeSpecial},
+		{subject: "any", subjectType: api.NetworkACLSubjectTypeSpecial},
+		{subject: "any4", subjectType: api.NetworkACLSubjectTypeSpecial},
+		{subject: "any6", subjectType: api.NetworkACLSubjectTypeSpecial},
+		{subject: "vlan:42", subjectType: api.NetworkACLSubjectTypeSpecial},
+		{subject: "@ipv4", subjectType: api.NetworkACLSubjectTypeSpecial},
+		{subject: "@ipv6", subjectType: api.NetworkACLSubjectTypeSpecial},
+		{subject: "vlan:4095", err: `Invalid VLAN subject "vlan:4095", VLAN ID must be between 0 and 4094`},
+		{subject: "192.0.2.10-192.0.2.1", err: "IP range is reversed, start address must not be higher than end address"},
+		{subject: "api.example.com", err: "Ambiguous as it is both a host name and a network ACL name"},
+		{subject: "10.0.0", err: ErrUnknownSubject.Error()},
+		{subject: "db", err: ErrUnknownSubject.Error()},
+	}
+
+	for _, test := range tests {
+		subjectType, err := ClassifySubject(test.subject, validNames)
+		if test.err != "" {
+			assert.EqualError(t, err, test.err, test.subject)
+			continue
+		}
+
+		require.NoError(t, err, test.subject)
+		assert.Equal(t, test.subjectType, subjectType, test.subject)
+	}
+
+	// Unknown subjects can be told apart from invalid ones.
+	_, err := ClassifySubject("db", validNames)
+	assert.ErrorIs(t, err, ErrUnknownSubject)
+
+	// Without the reserved names, the selectors are taken as network peer names and the aliases are unknown.
+	subjectType, err := ClassifySubject("@internal", nil)
+	require.NoError(t, err)
+	assert.Equal(t, api.NetworkACLSubjectTypeName, subjectType)
+
+	_, err = ClassifySubject("#internal", nil)
+	assert.ErrorIs(t, err, ErrUnknownSubject)
+}
+
+func TestValidateRuleAnyConflicts(t *testing.T) {
+	s, cleanup := state.NewTestState(t)
+	defer cleanup()
+
+	d := &common{}
+	d.init(s, -1, api.ProjectDefaultName, nil)
+
+	rule := api.NetworkACLRule{Action: "allow", State: "enabled", Source: "ANY4", Destination: "192.0.2.1"}
+	rule.Normalise()
+	assert.Equal(t, "any4", rule.Source)
+	assert.NoError(t, d.validateRule(ruleDirectionIngress, rule, ruleValidSubjectNames(nil)))
+
+	// An IPv4-only source cannot be combined with an IPv6-only destination.
+	rule.Destination = "2001:db8::1"
+	assert.ErrorContains(t, d.validateRule(ruleDirectionIngress, rule, ruleValidSubjectNames(nil)), "Conflicting IP family types")
+
+	rule.Destination = "any6"
+	assert.ErrorContains(t, d.validateRule(ruleDirectionIngress, rule, ruleValidSubjectNames(nil)), "Conflicting IP family types")
+
+	// The dual-stack shorthand requires both families on the other side.
+	rule.Source = "any"
+	assert.ErrorContains(t, d.validateRule(ruleDirectionIngress, rule, ruleValidSubjectNames(nil)), "Conflicting IP family types")
+
+	rule.Destination = "any"
+	assert.NoError(t, d.validateRule(ruleDirectionIngress, rule, ruleValidSubjectNames(nil)))
+}
+
+func TestValidateRuleICMP6NDP(t *testing.T) {
+	s, cleanup := state.NewTestState(t)
+	defer cleanup()
+
+	d := &common{}
+	d.init(s, -1, api.ProjectDefaultName, nil)
+
+	rule := api.NetworkACLRule{Action: "allow", State: "enabled", Protocol: "icmp6-ndp", Source: "any6"}
+	assert.NoError(t, d.validateRule(ruleDirectionIngress, rule, ruleValidSubjectNames(nil)))
+
+	// The ICMP types are implied by the protocol.
+	rule.ICMPType = "135"
+	assert.ErrorContains(t, d.validateRule(ruleDirectionIngress, rule, ruleValidSubjectNames(nil)), `ICMP type cannot be used with "icmp6-ndp" protocol`)
+
+	rule.ICMPType = ""
+	rule.ICMPCode = "0"
+	assert.ErrorContains(t, d.validateRule(ruleDirectionIngress, rule, ruleValidSubjectNames(nil)), `ICMP code cannot be used with "icmp6-ndp" protocol`)
+
+	// Neighbor discovery is IPv6 only.
+	rule.ICMPCode = ""
+	rule.Source = "192.0.2.0/24"
+	assert.ErrorContains(t, d.validateRule(ruleDirectionIngress, rule, ruleValidSubjectNames(nil)), "Cannot use IPv4 source addresses")
+}
+
+func TestValidateRuleRejectResponse(t *testing.T) {
+	s, cleanup := state.NewTestState(t)
+	defer cleanup()
+
+	d := &common{}
+	d.init(s, -1, api.ProjectDefaultName, nil)
+
+	rule := api.NetworkACLRule{Action: "reject", State: "enabled", Protocol: "tcp", DestinationPort: "22", RejectResponse: "tcp-reset"}
+	assert.NoError(t, d.validateRule(ruleDirectionIngress, rule, ruleValidSubjectNames(nil)))
+
+	// A TCP reset can only be sent back for TCP traffic.
+	rule.Protocol = "udp"
+	assert.ErrorContains(t, d.validateRule(ruleDirectionIngress, rule, ruleValidSubjectNames(nil)), `Reject response "tcp-reset" can only be used with "tcp" protocol`)
+
+	rule.RejectResponse = "icmp-port-unreachable"
+	assert.NoError(t, d.validateRule(ruleDirectionIngress, rule, ruleValidSubjectNames(nil)))
+
+	// The response only applies to rejected traffic.
+	rule.Action = "drop"
+	assert.ErrorContains(t, d.validateRule(ruleDirectionIngress, rule, ruleValidSubjectNames(nil)), `Reject response can only be used with "reject" action`)
+
+	rule.Action = "reject"
+	rule.RejectResponse = "host-unreachable"
+	assert.ErrorContains(t, d.validateRule(ruleDirectionIngress, rule, ruleValidSubjectNames(nil)), "Reject response must be one of")
+
+	// The default response is kept when not set.
+	rule.RejectResponse = ""
+	assert.NoError(t, d.validateRule(ruleDirectionIngress, rule, ruleValidSubjectNames(nil)))
+}
+
+func TestValidateRuleDSCP(t *testing.T) {
+	s, cleanup := state.NewTestState(t)
+	defer cleanup()
+
+	d := &common{}
+	d.init(s, -1, api.ProjectDefaultName, nil)
+
+	rule := api.NetworkACLRule{Action: "allow", State: "enabled", Protocol: "udp", DestinationPort: "5060", DSCP: "46"}
+	assert.NoError(t, d.validateRule(ruleDirectionEgress, rule, ruleValidSubjectNames(nil)))
+
+	rule.DSCP = "64"
+	assert.ErrorContains(t, d.validateRule(ruleDirectionEgress, rule, ruleValidSubjectNames(nil)), "DSCP must be a number between 0 and 63")
+
+	// Dropped and rejected traffic can't be marked.
+	rule.DSCP = "46"
+	rule.Action = "drop"
+	assert.ErrorContains(t, d.validateRule(ruleDirectionEgress, rule, ruleValidSubjectNames(nil)), `DSCP can only be used with "allow" or "allow-stateless" action`)
+
+	rule.Action = "reject"
+	assert.ErrorContains(t, d.validateRule(ruleDirectionEgress, rule, ruleValidSubjectNames(nil)), `DSCP can only be used with "allow" or "allow-stateless" action`)
+
+	// The mark is carried over to the OVN rule.
+	rule.Action = "allow"
+	ovnRule, _, _, err := ovnRuleCriteriaToOVNACLRule("egress", &rule, "incus_acl1", nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "46", ovnRule.DSCP)
+	assert.Equal(t, "allow-related", ovnRule.Action)
+
+	// Bridge networks can't mark traffic.
+	assert.EqualError(t, firewallValidateRule(rule), "Rules marking traffic with a DSCP value aren't supported on bridge networks")
+}
+
+func TestValidateRuleRelated(t *testing.T) {
+	s, cleanup := state.NewTestState(t)
+	defer cleanup()
+
+	d := &common{}
+	d.init(s, -1, api.ProjectDefaultName, nil)
+
+	rule := api.NetworkACLRule{Action: "allow-stateless", State: "enabled", Protocol: "tcp", DestinationPort: "80", Related: true}
+	assert.NoError(t, d.validateRule(ruleDirectionIngress, rule, ruleValidSubjectNames(nil)))
+
+	rule.Protocol = "udp"
+	assert.NoError(t, d.validateRule(ruleDirectionIngress, rule, ruleValidSubjectNames(nil)))
+
+	// Only TCP and UDP connections can be tracked.
+	for _, protocol := range []string{"icmp4", "icmp6", "icmp6-ndp", ""} {
+		rule := api.NetworkACLRule{Action: "allow", State: "enabled", Protocol: protocol, Related: true}
+		assert.EqualError(t, d.validateRule(ruleDirectionIngress, rule, ruleValidSubjectNames(nil)), `Related can only be used with "tcp" or "udp" protocol`, protocol)
+	}
+
+	// Only allowed traffic has replies.
+	rule.Action = "drop"
+	assert.EqualError(t, d.validateRule(ruleDirectionIngress, rule, ruleValidSubjectNames(nil)), `Related can only be used with "allow" or "allow-stateless" action`)
+
+	// Stateless rules become stateful on OVN networks, while other allow rules already are.
+	rule.Action = "allow-stateless"
+	ovnRule, _, _, err := ovnRuleCriteriaToOVNACLRule("ingress", &rule, "incus_acl1", nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "allow-related", ovnRule.Action)
+
+	rule.Related = false
+	ovnRule, _, _, err = ovnRuleCriteriaToOVNACLRule("ingress", &rule, "incus_acl1", nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "allow-stateless", ovnRule.Action)
+
+	rule = api.NetworkACLRule{Action: "allow", State: "enabled", Protocol: "tcp", Related: true}
+	ovnRule, _, _, err = ovnRuleCriteriaToOVNACLRule("ingress", &rule, "incus_acl1", nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "allow-related", ovnRule.Action)
+
+	// Bridge networks can't track the connections of stateless rules.
+	assert.EqualError(t, firewallValidateRule(rule), "Rules allowing related traffic aren't supported on bridge networks")
+}
+
+func TestValidateRulePorts(t *testing.T) {
+	s, cleanup := state.NewTestState(t)
+	defer cleanup()
+
+	d := &common{}
+	d.init(s, -1, api.ProjectDefaultName, nil)
+
+	rule := api.NetworkACLRule{Action: "allow", State: "enabled", InPort: "incus-net5-instance-abc-eth0"}
+	assert.NoError(t, d.validateRule(ruleDirectionIngress, rule, ruleValidSubjectNames(nil)))
+	assert.ErrorContains(t, d.validateRule(ruleDirectionEgress, rule, ruleValidSubjectNames(nil)), "In port can only be used with ingress rules")
+
+	rule = api.NetworkACLRule{Action: "allow", State: "enabled", OutPort: "incus-net5-instance-abc-eth0"}
+	assert.NoError(t, d.validateRule(ruleDirectionEgress, rule, ruleValidSubjectNames(nil)))
+	assert.ErrorContains(t, d.validateRule(ruleDirectionIngress, rule, ruleValidSubjectNames(nil)), "Out port can only be used with egress rules")
+
+	// Port names must be safe to quote in the OVN match.
+	rule.OutPort = `eth0" || 1`
+	assert.ErrorContains(t, d.validateRule(ruleDirectionEgress, rule, ruleValidSubjectNames(nil)), "Invalid out port")
+
+	rule.OutPort = strings.Repeat("a", 256)
+	assert.ErrorContains(t, d.validateRule(ruleDirectionEgress, rule, ruleValidSubjectNames(nil)), "longer than 255 characters")
+}
+
+func TestValidatePorts(t *testing.T) {
+	d := &common{}
+
+	tests := []struct {
+		ports string
+		err   string
+	}{
+		{"0", ""},
+		{"65535", ""},
+		{"0-65535", ""},
+		{"443-443", ""},
+		{"22,80-90,443", ""},
+		{"80-90,91", ""},
+		{"65536", `element 0 "65536": Out of port number range (0-65535) "65536"`},
+		{"80-65536", `element 0 "80-65536": Out of port number range (0-65535) "65536"`},
+		{"http", `element 0 "http": Invalid port number "http"`},
+		{"22,80-", `element 1 "80-": Invalid port number ""`},
+		{"-80", `element 0 "-80": Invalid port number ""`},
+		{"22,443,90-80", `element 2 "90-80": Port range is reversed, start port must not be higher than end port`},
+		{"80-90,85", `element 1 "85": Port overlaps with element 0 "80-90"`},
+		{"85,80-90", `element 0 "85": Port overlaps with element 1 "80-90"`},
+		{"80-90,90-100", `element 1 "90-100": Port overlaps with element 0 "80-90"`},
+		{"443,443-443", `element 1 "443-443": Port overlaps with element 0 "443"`},
+		{"1-10,20-30,5-6", `element 2 "5-6": Port overlaps with element 0 "1-10"`},
+	}
+
+	for _, test := range tests {
+		err := d.validatePorts(util.SplitNTrimSpace(test.ports, ",", -1, false))
+		if test.err == "" {
+			assert.NoError(t, err, test.ports)
+		} else {
+			assert.EqualError(t, err, test.err, test.ports)
+		}
+	}
+
+	// Overlapping ports are reported with the field they are in.
+	rule := api.NetworkACLRule{Action: "allow", State: "enabled", Protocol: "tcp", DestinationPort: "80-90,85"}
+	assert.EqualError(t, d.validateRule(ruleDirectionIngress, rule, ruleValidSubjectNames(nil)), `Invalid Destination port: element 1 "85": Port overlaps with element 0 "80-90"`)
+}
+
+func TestValidateRulePacketLen(t *testing.T) {
+	s, cleanup := state.NewTestState(t)
+	defer cleanup()
+
+	d := &common{}
+	d.init(s, -1, api.ProjectDefaultName, nil)
+
+	for _, packetLen := range []string{"1400", "1400-1500", "0-65535"} {
+		rule := api.NetworkACLRule{Action: "drop", State: "enabled", Protocol: "tcp", PacketLen: packetLen}
+		assert.NoError(t, d.validateRule(ruleDirectionEgress, rule, ruleValidSubjectNames(nil)), packetLen)
+	}
+
+	for packetLen, expected := range map[string]string{
+		"big":            `Invalid packet length: Length "big" must be a number between 0 and 65535`,
+		"1400-":          `Invalid packet length: Length "" must be a number between 0 and 65535`,
+		"1400-1500-1600": `Invalid packet length: Length "1500-1600" must be a number between 0 and 65535`,
+		"70000":          `Invalid packet length: Length "70000" must be a number between 0 and 65535`,
+		"1500-1400":      "Invalid packet length: Range is reversed, start length must not be higher than end length",
+	} {
+		rule := api.NetworkACLRule{Action: "drop", State: "enabled", PacketLen: packetLen}
+		assert.EqualError(t, d.validateRule(ruleDirectionEgress, rule, ruleValidSubjectNames(nil)), expected, packetLen)
+	}
+
+	// The length of ICMP packets can't be matched.
+	rule := api.NetworkACLRule{Action: "drop", State: "enabled", Protocol: "icmp4", PacketLen: "1400-1500"}
+	assert.EqualError(t, d.validateRule(ruleDirectionEgress, rule, ruleValidSubjectNames(nil)), `Packet length cannot be used with "icmp4" protocol`)
+}
+
+func TestValidateRuleVLAN(t *testing.T) {
+	s, cleanup := state.NewTestState(t)
+	defer cleanup()
+
+	d := &common{}
+	d.init(s, -1, api.ProjectDefaultName, nil)
+
+	for _, subject := range []string{"vlan:0", "vlan:42", "vlan:4094"} {
+		rule := api.NetworkACLRule{Action: "allow", State: "enabled", Source: subject}
+		assert.NoError(t, d.validateRule(ruleDirectionIngress, rule, ruleValidSubjectNames(nil)), subject)
+	}
+
+	for _, subject := range []string{"vlan:4095", "vlan:65536", "vlan:-1", "vlan:", "vlan:abc"} {
+		rule := api.NetworkACLRule{Action: "allow", State: "enabled", Source: subject}
+		assert.ErrorContains(t, d.validateRule(ruleDirectionIngress, rule, ruleValidSubjectNames(nil)), "VLAN ID must be between 0 and 4094", subject)
+	}
+
+	// VLAN subjects don't belong to an IP family, so they can be combined with any address.
+	rule := api.NetworkACLRule{Action: "allow", State: "enabled", Source: "192.0.2.1", Destination: "vlan:42"}
+	assert.NoError(t, d.validateRule(ruleDirectionEgress, rule, ruleValidSubjectNames(nil)))
+
+	rule.Destination = "vlan:42,2001:db8::1"
+	assert.ErrorContains(t, d.validateRule(ruleDirectionEgress, rule, ruleValidSubjectNames(nil)), "Conflicting IP family types")
+
+	// The VLAN ID is canonicalised.
+	rule = api.NetworkACLRule{Source: "VLAN:0042"}
+	rule.Normalise()
+	assert.Equal(t, "vlan:42", rule.Source)
+}
+
+func TestRuleSubjectFamilySelectors(t *testing.T) {
+	d := &common{}
+	validSubjectNames := ruleValidSubjectNames(nil)
+
+	// The family selectors can be used on either side of rules of both directions.
+	for _, direction := range []ruleDirection{ruleDirectionIngress, ruleDirectionEgress} {
+		for _, field := range []string{"Source", "Destination"} {
+			hasName, hasIPv4, hasIPv6, err := d.validateRuleSubjects(field, direction, []string{"@ipv6"}, validSubjectNames)
+			require.NoError(t, err)
+			assert.False(t, hasName)
+			assert.False(t, hasIPv4)
+			assert.True(t, hasIPv6)
+		}
+	}
+
+	// They count as their family in the family checks.
+	rule := api.NetworkACLRule{Action: "drop", State: "enabled", Destination: "@ipv6"}
+	assert.NoError(t, d.validateRule(ruleDirectionEgress, rule, validSubjectNames))
+
+	rule.Source = "192.0.2.1"
+	assert.ErrorContains(t, d.validateRule(ruleDirectionEgress, rule, validSubjectNames), "Conflicting IP family types")
+
+	rule = api.NetworkACLRule{Action: "allow", State: "enabled", Protocol: "icmp4", Source: "@ipv6"}
+	assert.EqualError(t, d.validateRule(ruleDirectionIngress, rule, validSubjectNames), `Cannot use IPv6 source addresses with "icmp4" protocol`)
+
+	// OVN matches on the family of the traffic.
+	rule = api.NetworkACLRule{Action: "drop", State: "enabled", Destination: "@ipv6"}
+	ovnRule, _, _, err := ovnRuleCriteriaToOVNACLRule("egress", &rule, "incus_acl1", nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, `(inport == @incus_acl1) && (ip6)`, ovnRule.Match)
+
+	rule = api.NetworkACLRule{Action: "allow", State: "enabled", Source: "@ipv4,2001:db8::1"}
+	ovnRule, _, _, err = ovnRuleCriteriaToOVNACLRule("ingress", &rule, "incus_acl1", nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, `(outport == @incus_acl1) && (ip4 || ip6.src == 2001:db8::1)`, ovnRule.Match)
+
+	// The selectors aren't references to other ACLs.
+	referenced := map[string]struct{}{}
+	ovnAddReferencedACLs(&api.NetworkACL{NetworkACLPut: api.NetworkACLPut{Ingress: []api.NetworkACLRule{rule}}}, referenced)
+	assert.Empty(t, referenced)
+
+	// Bridge networks match all the addresses of the family.
+	addresses, err := firewallRuleSubjects("@ipv4,@ipv6", nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"0.0.0.0/0", "::/0"}, addresses)
+
+	// Rules mixing the families are split along them.
+	rule = api.NetworkACLRule{Action: "allow", State: "enabled", Source: "@ipv4,@ipv6", Destination: "192.0.2.1,2001:db8::1"}
+	rules := SplitByFamily(rule)
+	require.Len(t, rules, 2)
+	assert.Equal(t, "@ipv4", rules[0].Source)
+	assert.Equal(t, "@ipv6", rules[1].Source)
+}
+
+func TestValidateRuleFQDN(t *testing.T) {
+	d := &common{}
+
+	// Host names can be used on both sides of a rule and with addresses of either family.
+	rule := api.NetworkACLRule{Action: "allow", State: "enabled", Source: "192.0.2.1", Destination: "api.example.com"}
+	assert.NoError(t, d.validateRule(ruleDirectionIngress, rule, ruleValidSubjectNames(nil)))
+
+	rule = api.NetworkACLRule{Action: "allow", State: "enabled", Source: "api.example.com", Destination: "2001:db8::1"}
+	assert.NoError(t, d.validateRule(ruleDirectionEgress, rule, ruleValidSubjectNames(nil)))
+
+	// Host names matching the name of an ACL are ambiguous.
+	validSubjectNames := ruleValidSubjectNames(map[string]int64{"web": 1, "api.example.com": 2})
+	rule = api.NetworkACLRule{Action: "allow", State: "enabled", Source: "web,api.example.com"}
+	assert.EqualError(t, d.validateRule(ruleDirectionIngress, rule, validSubjectNames), `Invalid Source: element 1 "api.example.com": Ambiguous as it is both a host name and a network ACL name`)
+
+	// Host names aren't references to other ACLs.
+	info := &api.NetworkACLPut{Ingress: []api.NetworkACLRule{{Action: "allow", State: "enabled", Source: "web,api.example.com"}}}
+	assert.Equal(t, []string{"web"}, ruleSubjectNames(info))
+	assert.Equal(t, []string{"api.example.com"}, ruleSubjectFQDNs(info))
+
+	referenced := map[string]struct{}{}
+	ovnAddReferencedACLs(&api.NetworkACL{NetworkACLPut: *info}, referenced)
+	assert.Equal(t, map[string]struct{}{"web": {}}, referenced)
+}
+
+func TestSplitByFamily(t *testing.T) {
+	rule := api.NetworkACLRule{
+		Action:          "allow",
+		State:           "enabled",
+		Source:          "192.0.2.0/24,2001:db8::/64,web",
+		Destination:     "198.51.100.1-198.51.100.9,any6",
+		Protocol:        "tcp",
+		DestinationPort: "80,443",
+	}
+
+	rules := SplitByFamily(rule)
+	require.Len(t, rules, 2)
+
+	ipv4Rule := rule
+	ipv4Rule.Source = "192.0.2.0/24,web"
+	ipv4Rule.Destination = "198.51.100.1-198.51.100.9"
+	assert.Equal(t, ipv4Rule, rules[0])
+
+	ipv6Rule := rule
+	ipv6Rule.Source = "2001:db8::/64,web"
+	ipv6Rule.Destination = "::/0"
+	assert.Equal(t, ipv6Rule, rules[1])
+
+	// Rules without both families on each side are left alone.
+	for _, unsplit := range []api.NetworkACLRule{
+		{Action: "allow", State: "enabled", Source: "192.0.2.0/24", Destination: "198.51.100.1"},
+		{Action: "allow", State: "enabled", Source: "192.0.2.0/24,2001:db8::/64", Destination: "198.51.100.1"},
+		{Action: "allow", State: "enabled", Source: "any", Destination: "@internal"},
+		{Action: "allow", State: "enabled", Protocol: "udp"},
+	} {
+		assert.Equal(t, []api.NetworkACLRule{unsplit}, SplitByFamily(unsplit))
+	}
+}
+
+func TestValidateRuleSchedule(t *testing.T) {
+	s, cleanup := state.NewTestState(t)
+	defer cleanup()
+
+	d := &common{}
+	d.init(s, -1, api.ProjectDefaultName, nil)
+
+	rule := api.NetworkACLRule{Action: "allow", State: "enabled", ValidFrom: "2024-05-01T08:00:00Z", ValidUntil: "2024-05-01T18:00:00+02:00"}
+	assert.NoError(t, d.validateRule(ruleDirectionIngress, rule, ruleValidSubjectNames(nil)))
+
+	rule.ValidFrom = "2024-05-01 08:00"
+	assert.ErrorContains(t, d.validateRule(ruleDirectionIngress, rule, ruleValidSubjectNames(nil)), "Invalid valid from time")
+
+	rule.ValidFrom = ""
+	rule.ValidUntil = "tomorrow"
+	assert.ErrorContains(t, d.validateRule(ruleDirectionIngress, rule, ruleValidSubjectNames(nil)), "Invalid valid until time")
+
+	// The window must not be empty.
+	rule.ValidFrom = "2024-05-01T18:00:00Z"
+	rule.ValidUntil = "2024-05-01T08:00:00Z"
+	assert.ErrorContains(t, d.validateRule(ruleDirectionIngress, rule, ruleValidSubjectNames(nil)), "Valid until time must be after valid from time")
+}
+
+func TestRuleIsActive(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	assert.True(t, ruleIsActive(api.NetworkACLRule{}, now))
+	assert.True(t, ruleIsActive(api.NetworkACLRule{ValidFrom: "2024-05-01T08:00:00Z", ValidUntil: "2024-05-01T18:00:00Z"}, now))
+
+	// Expired rules and rules that haven't started yet aren't emitted.
+	assert.False(t, ruleIsActive(api.NetworkACLRule{ValidUntil: "2024-05-01T11:59:59Z"}, now))
+	assert.False(t, ruleIsActive(api.NetworkACLRule{ValidFrom: "2024-05-01T12:00:01Z"}, now))
+}
+
+func TestRuleScheduleChanged(t *testing.T) {
+	since := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	now := since.Add(time.Minute)
+
+	rule := api.NetworkACLRule{ValidUntil: "2024-05-01T12:00:30Z"}
+	assert.True(t, ruleScheduleChanged(rule, since, now))
+	assert.False(t, ruleScheduleChanged(rule, now, now.Add(time.Minute)))
+	assert.False(t, ruleScheduleChanged(api.NetworkACLRule{}, since, now))
+}
+
+func TestRuleID(t *testing.T) {
+	rule := api.NetworkACLRule{Action: "allow", State: "enabled", Destination: "192.0.2.1"}
+
+	// The ID is stable and doesn't depend on the description.
+	described := rule
+	described.Description = "Web server"
+	assert.Equal(t, ruleID(ruleDirectionIngress, rule), ruleID(ruleDirectionIngress, described))
+
+	// The ID changes with the direction and the content of the rule.
+	assert.NotEqual(t, ruleID(ruleDirectionIngress, rule), ruleID(ruleDirectionEgress, rule))
+
+	changed := rule
+	changed.Action = "drop"
+	assert.NotEqual(t, ruleID(ruleDirectionIngress, rule), ruleID(ruleDirectionIngress, changed))
+}
+
+func TestRuleStatsNoOVN(t *testing.T) {
+	s, cleanup := state.NewTestState(t)
+	defer cleanup()
+
+	d := &common{}
+	d.init(s, 1, api.ProjectDefaultName, &api.NetworkACL{
+		NetworkACLPost: api.NetworkACLPost{Name: "web"},
+		NetworkACLPut: api.NetworkACLPut{
+			Ingress: []api.NetworkACLRule{{Action: "allow", State: "enabled"}},
+		},
+	})
+
+	stats, err := d.RuleStats()
+	require.NoError(t, err)
+	assert.Empty(t, stats)
+}
+
+func TestValidateConfigDirectionEnabled(t *testing.T) {
+	s, cleanup := state.NewTestState(t)
+	defer cleanup()
+
+	d := &common{}
+	d.init(s, -1, api.ProjectDefaultName, nil)
+
+	info := &api.NetworkACLPut{Config: map[string]string{"ingress.enabled": "true", "egress.enabled": "false"}}
+	assert.NoError(t, validateStrict(d, info))
+
+	info.Config["egress.enabled"] = "maybe"
+	assert.ErrorContains(t, validateStrict(d, info), `Invalid value for config option "egress.enabled"`)
+}
+
+func TestValidateConfigPriorityBand(t *testing.T) {
+	s, cleanup := state.NewTestState(t)
+	defer cleanup()
+
+	d := &common{}
+	d.init(s, -1, api.ProjectDefaultName, nil)
+
+	for _, band := range []string{"0", "5", "9"} {
+		info := &api.NetworkACLPut{Config: map[string]string{"priority.band": band}}
+		assert.NoError(t, validateStrict(d, info), band)
+	}
+
+	for _, band := range []string{"-1", "10", "high"} {
+		info := &api.NetworkACLPut{Config: map[string]string{"priority.band": band}}
+		assert.ErrorContains(t, validateStrict(d, info), `Invalid value for config option "priority.band"`, band)
+	}
+}
+
+func TestValidateConfigLogRate(t *testing.T) {
+	s, cleanup := state.NewTestState(t)
+	defer cleanup()
+
+	d := &common{}
+	d.init(s, -1, api.ProjectDefaultName, nil)
+
+	for _, rate := range []string{"1", "10", "4294967295"} {
+		info := &api.NetworkACLPut{Config: map[string]string{"ingress.log_rate": rate, "egress.log_rate": rate}}
+		assert.NoError(t, validateStrict(d, info), rate)
+	}
+
+	for _, rate := range []string{"0", "-1", "1.5", "fast", "4294967296"} {
+		info := &api.NetworkACLPut{Config: map[string]string{"egress.log_rate": rate}}
+		assert.ErrorContains(t, validateStrict(d, info), `Invalid value for config option "egress.log_rate"`, rate)
+	}
+}
+
+func TestValidateConfigScriptlet(t *testing.T) {
+	s, cleanup := state.NewTestState(t)
+	defer cleanup()
+
+	d := &common{}
+	d.init(s, -1, api.ProjectDefaultName, &api.NetworkACL{NetworkACLPost: api.NetworkACLPost{Name: "web"}})
+
+	src := `
+def network_acl_rules(project, name, direction):
+    if direction == "ingress":
+        return [{"action": "allow", "state": "enabled", "protocol": "tcp", "destination_port": "443, 80", "description": project.name}]
+
+    return [{"action": "drop", "state": "enabled"}]
+`
+
+	// The generated rules replace the ones of the config and are validated (and normalised) like static rules.
+	info := &api.NetworkACLPut{Config: map[string]string{"rules.scriptlet": src}}
+	require.NoError(t, validateStrict(d, info))
+	assert.Equal(t, []api.NetworkACLRule{{Action: "allow", State: "enabled", Protocol: "tcp", DestinationPort: "80,443", Description: api.ProjectDefaultName}}, info.Ingress)
+	assert.Equal(t, []api.NetworkACLRule{{Action: "drop", State: "enabled"}}, info.Egress)
+
+	// The generated rules can be supplied back unchanged, but not combined with other static rules.
+	assert.NoError(t, validateStrict(d, info))
+
+	info.Egress = append(info.Egress, api.NetworkACLRule{Action: "allow", State: "enabled"})
+	assert.EqualError(t, validateStrict(d, info), `Static rules cannot be used together with "rules.scriptlet"`)
+
+	// The rules stored from an earlier run of the scriptlet can be supplied back too, and are regenerated.
+	staleRules := []api.NetworkACLRule{{Action: "allow", State: "enabled", Protocol: "tcp", DestinationPort: "22"}}
+	d.info.NetworkACLPut = api.NetworkACLPut{Config: map[string]string{"rules.scriptlet": src}, Ingress: staleRules}
+
+	info = &api.NetworkACLPut{Config: map[string]string{"rules.scriptlet": src}, Ingress: staleRules}
+	require.NoError(t, validateStrict(d, info))
+	assert.Equal(t, "80,443", info.Ingress[0].DestinationPort)
+
+	d.info.NetworkACLPut = api.NetworkACLPut{}
+	info = &api.NetworkACLPut{Config: map[string]string{"rules.scriptlet": src}, Ingress: staleRules}
+	assert.EqualError(t, validateStrict(d, info), `Static rules cannot be used together with "rules.scriptlet"`)
+
+	// The generated rules must be valid.
+	info = &api.NetworkACLPut{Config: map[string]string{"rules.scriptlet": `
+def network_acl_rules(project, name, direction):
+    return [{"action": "accept", "state": "enabled"}]
+`}}
+	assert.ErrorContains(t, validateStrict(d, info), "Invalid ingress rule 0: Action must be one of")
+
+	info = &api.NetworkACLPut{Config: map[string]string{"rules.scriptlet": `
+def network_acl_rules(project, name, direction):
+    return [{"action": "allow", "ports": "80"}]
+`}}
+	assert.EqualError(t, validateStrict(d, info), `Failed generating rules with scriptlet: Invalid ingress rules: Invalid rule 0: Unknown field "ports"`)
+
+	info = &api.NetworkACLPut{Config: map[string]string{"rules.scriptlet": "def network_acl_rules(project, name, direction)"}}
+	assert.ErrorContains(t, validateStrict(d, info), "Failed generating rules with scriptlet")
+}
+
+func TestSimulatePacket(t *testing.T) {
+	s, cleanup := state.NewTestState(t)
+	defer cleanup()
+
+	d := &common{}
+	d.init(s, -1, api.ProjectDefaultName, &api.NetworkACL{
+		NetworkACLPost: api.NetworkACLPost{Name: "web"},
+		NetworkACLPut: api.NetworkACLPut{
+			Ingress: []api.NetworkACLRule{
+				{Action: "allow", State: "enabled", Source: "10.0.0.0/8", Protocol: "tcp", DestinationPort: "22"},
+				{Action: "allow", State: "enabled", Source: "192.0.2.0/24, 198.51.100.1-198.51.100.10", Protocol: "tcp", DestinationPort: "80,443"},
+				{Action: "allow", State: "enabled", Protocol: "icmp4", ICMPType: "8"},
+			},
+			Egress: []api.NetworkACLRule{
+				{Action: "allow", State: "enabled", Destination: "any"},
+				{Action: "drop", State: "enabled", Destination: "203.0.113.0/24"},
+				{Action: "drop", State: "disabled", Destination: "any"},
+			},
+		},
+	})
+
+	aclNet := NetworkACLUsage{Name: "br0", Type: "bridge", Config: map[string]string{"security.acls.default.ingress.action": "drop"}}
+
+	// The packet matches the second rule through the range subject.
+	index, action, err := d.SimulatePacket(aclNet, ruleDirectionIngress, Packet{Source: "198.51.100.5", Destination: "192.0.2.100", Protocol: "tcp", SourcePort: 50000, DestinationPort: 443})
+	require.NoError(t, err)
+	assert.Equal(t, 1, index)
+	assert.Equal(t, "allow", action)
+
+	// Packets not matching any rule get the default action of the network.
+	index, action, err = d.SimulatePacket(aclNet, ruleDirectionIngress, Packet{Source: "198.51.100.5", Destination: "192.0.2.100", Protocol: "udp", DestinationPort: 443})
+	require.NoError(t, err)
+	assert.Equal(t, -1, index)
+	assert.Equal(t, "drop", action)
+
+	index, action, err = d.SimulatePacket(NetworkACLUsage{Name: "br1", Type: "bridge"}, ruleDirectionIngress, Packet{Source: "198.51.100.5", Destination: "192.0.2.100", Protocol: "udp", DestinationPort: 443})
+	require.NoError(t, err)
+	assert.Equal(t, -1, index)
+	assert.Equal(t, "reject", action)
+
+	index, _, err = d.SimulatePacket(aclNet, ruleDirectionIngress, Packet{Source: "198.51.100.5", Destination: "192.0.2.100", Protocol: "icmp4", ICMPType: 8})
+	require.NoError(t, err)
+	assert.Equal(t, 2, index)
+
+	// Drop rules are applied before allow rules whatever their position, and disabled rules never match.
+	index, action, err = d.SimulatePacket(aclNet, ruleDirectionEgress, Packet{Source: "192.0.2.100", Destination: "203.0.113.1", Protocol: "udp", DestinationPort: 53})
+	require.NoError(t, err)
+	assert.Equal(t, 1, index)
+	assert.Equal(t, "drop", action)
+
+	index, action, err = d.SimulatePacket(aclNet, ruleDirectionEgress, Packet{Source: "2001:db8::1", Destination: "2001:db8::2", Protocol: "icmp6"})
+	require.NoError(t, err)
+	assert.Equal(t, 0, index)
+	assert.Equal(t, "allow", action)
+
+	// Invalid packets are rejected.
+	_, _, err = d.SimulatePacket(aclNet, ruleDirectionIngress, Packet{Source: "192.0.2.1", Destination: "2001:db8::1", Protocol: "tcp"})
+	assert.EqualError(t, err, "Invalid packet: Source and destination addresses must be of the same family")
+
+	_, _, err = d.SimulatePacket(aclNet, ruleDirectionIngress, Packet{Source: "192.0.2.1", Destination: "192.0.2.2", Protocol: "icmp6"})
+	assert.EqualError(t, err, `Invalid packet: Cannot use "icmp6" protocol with IPv4 addresses`)
+
+	_, _, err = d.SimulatePacket(aclNet, ruleDirectionIngress, Packet{Source: "192.0.2.1", Destination: "192.0.2.2", Protocol: "sctp"})
+	assert.EqualError(t, err, `Invalid packet: Invalid protocol "sctp", must be one of tcp, udp, icmp4 or icmp6`)
+}
+
+func TestSimulatePacketParentRules(t *testing.T) {
+	s, cleanup := state.NewTestState(t)
+	defer cleanup()
+
+	err := s.DB.Cluster.Transaction(context.Background(), func(ctx context.Context, tx *db.ClusterTx) error {
+		_, err := tx.CreateNetworkACL(ctx, api.ProjectDefaultName, &api.NetworkACLsPost{
+			NetworkACLPost: api.NetworkACLPost{Name: "locked"},
+			NetworkACLPut:  api.NetworkACLPut{Ingress: []api.NetworkACLRule{{Action: "drop", State: "enabled", Protocol: "tcp", DestinationPort: "22"}}},
+		})
+
+		return err
+	})
+	require.NoError(t, err)
+
+	oldCache := fqdnCache
+	_, cached, _ := net.ParseCIDR("198.51.100.7/32")
+	fqdnCache = &fqdnAddressCache{addresses: map[string][]net.IPNet{"admin.example.com": {*cached}}}
+	defer func() { fqdnCache = oldCache }()
+
+	d := &common{}
+	d.init(s, -1, api.ProjectDefaultName, &api.NetworkACL{
+		NetworkACLPost: api.NetworkACLPost{Name: "admin"},
+		NetworkACLPut: api.NetworkACLPut{
+			Config: map[string]string{"parent": "locked"},
+			Ingress: []api.NetworkACLRule{
+				{Action: "allow", State: "enabled", Source: "admin.example.com, vlan:42", Protocol: "tcp", DestinationPort: "22"},
+				{Action: "allow", State: "enabled", Source: "@internal", Protocol: "tcp", DestinationPort: "22"},
+			},
+		},
+	})
+
+	ovnNet := NetworkACLUsage{Name: "ovn0", Type: "ovn", Config: map[string]string{"ipv4.address": "10.0.0.1/24"}}
+	bridgeNet := NetworkACLUsage{Name: "br0", Type: "bridge", Config: map[string]string{"ipv4.address": "10.0.0.1/24"}}
+
+	// On OVN networks, the ACL's own rules come before the inherited ones, which are counted first by the index.
+	index, action, err := d.SimulatePacket(ovnNet, ruleDirectionIngress, Packet{Source: "198.51.100.7", Destination: "10.0.0.2", Protocol: "tcp", DestinationPort: 22})
+	require.NoError(t, err)
+	assert.Equal(t, 1, index)
+	assert.Equal(t, "allow", action)
+
+	index, action, err = d.SimulatePacket(ovnNet, ruleDirectionIngress, Packet{Source: "10.0.0.5", Destination: "10.0.0.2", Protocol: "tcp", DestinationPort: 22})
+	require.NoError(t, err)
+	assert.Equal(t, 2, index)
+	assert.Equal(t, "allow", action)
+
+	index, action, err = d.SimulatePacket(ovnNet, ruleDirectionIngress, Packet{Source: "203.0.113.1", Destination: "10.0.0.2", Protocol: "tcp", DestinationPort: 22})
+	require.NoError(t, err)
+	assert.Equal(t, 0, index)
+	assert.Equal(t, "drop", action)
+
+	// Bridge networks order all the rules by action.
+	index, action, err = d.SimulatePacket(bridgeNet, ruleDirectionIngress, Packet{Source: "10.0.0.5", Destination: "10.0.0.2", Protocol: "tcp", DestinationPort: 22})
+	require.NoError(t, err)
+	assert.Equal(t, 0, index)
+	assert.Equal(t, "drop", action)
+
+	// ACLs in the highest band share it with their inherited rules.
+	d.info.Config["priority.band"] = "9"
+	index, action, err = d.SimulatePacket(ovnNet, ruleDirectionIngress, Packet{Source: "10.0.0.5", Destination: "10.0.0.2", Protocol: "tcp", DestinationPort: 22})
+	require.NoError(t, err)
+	assert.Equal(t, 0, index)
+	assert.Equal(t, "drop", action)
+}
+
+// BenchmarkValidateRules validates a large rule set referencing other ACLs. The ACL names are loaded once by
+// validateConfig and shared by all the rules, so the number of database queries doesn't grow with the rules.
+func BenchmarkValidateRules(b *testing.B) {
+	s, cleanup := state.NewTestState(b)
+	defer cleanup()
+
+	err := s.DB.Cluster.Transaction(context.Background(), func(ctx context.Context, tx *db.ClusterTx) error {
+		for _, name := range []string{"web", "db"} {
+			_, err := tx.CreateNetworkACL(ctx, api.ProjectDefaultName, &api.NetworkACLsPost{NetworkACLPost: api.NetworkACLPost{Name: name}})
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	require.NoError(b, err)
+
+	d := &common{}
+	d.init(s, -1, api.ProjectDefaultName, &api.NetworkACL{NetworkACLPost: api.NetworkACLPost{Name: "bench"}})
+
+	rules := make([]api.NetworkACLRule, 0, 300)
+	for i := 0; i < 300; i++ {
+		rules = append(rules, api.NetworkACLRule{
+			Action:          "allow",
+			State:           "enabled",
+			Source:          "web",
+			Destination:     "db",
+			Protocol:        "tcp",
+			DestinationPort: fmt.Sprintf("%d", 1024+i),
+		})
+	}
+
+	b.ResetTimer()
+
+	for n := 0; n < b.N; n++ {
+		// The rules are normalised in place, so each run validates a fresh copy.
+		err := validateStrict(d, &api.NetworkACLPut{Ingress: slices.Clone(rules)})
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestValidateConfigSubjectProject(t *testing.T) {
+	s, cleanup := state.NewTestState(t)
+	defer cleanup()
+
+	err := s.DB.Cluster.Transaction(context.Background(), func(ctx context.Context, tx *db.ClusterTx) error {
+		_, err := dbCluster.CreateProject(ctx, tx.Tx(), dbCluster.Project{Name: "p1"})
+		if err != nil {
+			return err
+		}
+
+		for _, entry := range []struct{ project, name string }{{"default", "web"}, {"p1", "web"}, {"default", "cache"}} {
+			_, err = tx.CreateNetworkACL(ctx, entry.project, &api.NetworkACLsPost{NetworkACLPost: api.NetworkACLPost{Name: entry.name}})
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	require.NoError(t, err)
+
+	d := &common{}
+	d.init(s, -1, "p1", nil)
+
+	// A name existing in both projects resolves to the ACL of the ACL's own project.
+	info := &api.NetworkACLPut{Ingress: []api.NetworkACLRule{{Action: "allow", State: "enabled", Source: "web"}}}
+	assert.NoError(t, validateStrict(d, info))
+
+	// A name only existing in the default project is reported explicitly.
+	info.Ingress[0].Source = "cache"
+	assert.EqualError(t, validateStrict(d, info), `Rules reference undefined network ACLs: cache (cache only exist in project "default" and cannot be referenced from project "p1")`)
+
+	// Names existing nowhere are reported as undefined.
+	info.Ingress[0].Source = "typo"
+	assert.EqualError(t, validateStrict(d, info), "Rules reference undefined network ACLs: typo")
+}
+
+func TestValidateConfigSelfReference(t *testing.T) {
+	s, cleanup := state.NewTestState(t)
+	defer cleanup()
+
+	err := s.DB.Cluster.Transaction(context.Background(), func(ctx context.Context, tx *db.ClusterTx) error {
+		for _, name := range []string{"web", "app", "db"} {
+			_, err := tx.CreateNetworkACL(ctx, api.ProjectDefaultName, &api.NetworkACLsPost{NetworkACLPost: api.NetworkACLPost{Name: name}})
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	require.NoError(t, err)
+
+	d := &common{}
+	d.init(s, 1, api.ProjectDefaultName, &api.NetworkACL{NetworkACLPost: api.NetworkACLPost{Name: "web"}})
+
+	// References to other ACLs are allowed, including ones closing a chain of ACLs back to this one.
+	info := &api.NetworkACLPut{Ingress: []api.NetworkACLRule{{Action: "allow", State: "enabled", Source: "app,db"}}}
+	assert.NoError(t, validateStrict(d, info))
+
+	// Direct self-references aren't.
+	info.Egress = []api.NetworkACLRule{{Action: "allow", State: "enabled", Destination: "web"}}
+	assert.EqualError(t, validateStrict(d, info), "Rules cannot reference the network ACL itself (web)")
+}
+
+func TestValidateConfigAllErrors(t *testing.T) {
+	s, cleanup := state.NewTestState(t)
+	defer cleanup()
+
+	d := &common{}
+	d.init(s, -1, api.ProjectDefaultName, nil)
+
+	info := &api.NetworkACLPut{
+		Config: map[string]string{"foo": "bar", "user.foo": "bar"},
+		Ingress: []api.NetworkACLRule{
+			{Action: "allow", State: "enabled"},
+			{Action: "accept", State: "enabled"},
+			{Action: "allow", State: "enabled"},
+		},
+		Egress: []api.NetworkACLRule{
+			{Action: "drop", State: "on"},
+		},
+	}
+
+	// All the problems are reported along with their direction and index.
+	err := validateStrict(d, info)
+	require.Error(t, err)
+
+	var errs ValidationErrors
+	require.ErrorAs(t, err, &errs)
+	assert.Len(t, errs, 4)
+	assert.EqualError(t, err, `Found 4 problems: Invalid config option "foo"; Invalid ingress rule 1: Action must be one of: `+strings.Join(ValidActions, ", ")+`; Ingress rule 2 is a duplicate of ingress rule 0; Invalid egress rule 0: State must be one of: enabled, disabled, logged`)
+
+	// A single problem is reported as is.
+	info.Config = nil
+	info.Ingress = info.Ingress[:1]
+	info.Egress = nil
+	assert.NoError(t, validateStrict(d, info))
+
+	info.Ingress[0].Action = "accept"
+	assert.EqualError(t, validateStrict(d, info), "Invalid ingress rule 0: Action must be one of: "+strings.Join(ValidActions, ", "))
+}
+
+func TestValidateConfigMode(t *testing.T) {
+	s, cleanup := state.NewTestState(t)
+	defer cleanup()
+
+	d := &common{}
+	d.init(s, -1, api.ProjectDefaultName, nil)
+
+	newInfo := func() *api.NetworkACLPut {
+		return &api.NetworkACLPut{
+			Ingress: []api.NetworkACLRule{{Action: "allow", State: "enabled", Source: "#internal, 192.0.2.1"}},
+			Egress:  []api.NetworkACLRule{{Action: "allow", State: "enabled", Destination: "#external"}},
+		}
+	}
+
+	// Strict mode rejects the deprecated subject aliases.
+	info := newInfo()
+	warnings, err := d.validateConfig(info, ValidationModeStrict)
+	assert.EqualError(t, err, `Found 2 problems: Invalid ingress rule 0: Subject "#internal" is deprecated, use "@internal" instead; Invalid egress rule 0: Subject "#external" is deprecated, use "@external" instead`)
+	assert.Empty(t, warnings)
+
+	// The default warn mode and the permissive mode replace them and report them as warnings.
+	for _, mode := range []ValidationMode{ValidationModeWarn, ValidationModePermissive} {
+		info = newInfo()
+		warnings, err = d.validateConfig(info, mode)
+		require.NoError(t, err, mode)
+		assert.Equal(t, []string{
+			`Replaced deprecated subject "#internal" with "@internal" in ingress rule 0`,
+			`Replaced deprecated subject "#external" with "@external" in egress rule 0`,
+		}, warnings, mode)
+		assert.Equal(t, "192.0.2.1,@internal", info.Ingress[0].Source, mode)
+		assert.Equal(t, "@external", info.Egress[0].Destination, mode)
+	}
+
+	// The converted rules pass strict validation.
+	warnings, err = d.validateConfig(info, ValidationModeStrict)
+	require.NoError(t, err)
+	assert.Empty(t, warnings)
+
+	mode, err := ParseValidationMode("")
+	require.NoError(t, err)
+	assert.Equal(t, ValidationModeWarn, mode)
+
+	_, err = ParseValidationMode("lax")
+	assert.Error(t, err)
+}
+
+func TestValidateConfigElementIndex(t *testing.T) {
+	s, cleanup := state.NewTestState(t)
+	defer cleanup()
+
+	d := &common{}
+	d.init(s, -1, api.ProjectDefaultName, nil)
+
+	// Elements are reported at their supplied position, even though normalising sorts the lists.
+	info := &api.NetworkACLPut{
+		Ingress: []api.NetworkACLRule{{Action: "allow", State: "enabled", Source: "198.51.100.1, 192.0.2.300"}},
+		Egress:  []api.NetworkACLRule{{Action: "allow", State: "enabled", Protocol: "tcp", DestinationPort: "8080,443,http"}},
+	}
+
+	assert.EqualError(t, validateStrict(d, info), `Found 2 problems: Invalid ingress rule 0: Invalid Source: element 1 "192.0.2.300": not an IP address, CIDR, range or known ACL name; Invalid egress rule 0: Invalid Destination port: element 2 "http": Invalid port number "http"`)
+
+	// Identical ports are merged rather than reported as overlaps.
+	info = &api.NetworkACLPut{
+		Egress: []api.NetworkACLRule{{Action: "allow", State: "enabled", Protocol: "tcp", DestinationPort: "443,80,443"}},
+	}
+
+	require.NoError(t, validateStrict(d, info))
+	assert.Equal(t, "80,443", info.Egress[0].DestinationPort)
+}
+
+func TestValidateConfigStrictCIDR(t *testing.T) {
+	s, cleanup := state.NewTestState(t)
+	defer cleanup()
+
+	d := &common{}
+	d.init(s, -1, api.ProjectDefaultName, nil)
+
+	newInfo := func(config map[string]string) *api.NetworkACLPut {
+		return &api.NetworkACLPut{
+			Config:  config,
+			Ingress: []api.NetworkACLRule{{Action: "allow", State: "enabled", Source: "10.1.2.3/24,192.0.2.0/24"}},
+			Egress:  []api.NetworkACLRule{{Action: "allow", State: "enabled", Destination: "2001:db8::1/64"}},
+		}
+	}
+
+	// CIDR subjects with host bits set are rejected by default.
+	info := newInfo(nil)
+	warnings, err := d.validateConfig(info, ValidationModeStrict)
+	assert.EqualError(t, err, `Found 2 problems: Invalid ingress rule 0: CIDR subject "10.1.2.3/24" has host bits set, use "10.1.2.3" to only match the address or "10.1.2.0/24" to match the whole network; Invalid egress rule 0: CIDR subject "2001:db8::1/64" has host bits set, use "2001:db8::1" to only match the address or "2001:db8::/64" to match the whole network`)
+	assert.Empty(t, warnings)
+
+	// Turning the check off for the ACL replaces them with their network address.
+	for _, info := range []*api.NetworkACLPut{newInfo(map[string]string{"validation.strict_cidr": "false"}), newInfo(nil)} {
+		mode := ValidationModeStrict
+		if info.Config == nil {
+			mode = ValidationModePermissive
+		}
+
+		warnings, err = d.validateConfig(info, mode)
+		require.NoError(t, err)
+		assert.Equal(t, []string{
+			`Replaced CIDR subject "10.1.2.3/24" with its network address "10.1.2.0/24" in ingress rule 0`,
+			`Replaced CIDR subject "2001:db8::1/64" with its network address "2001:db8::/64" in egress rule 0`,
+		}, warnings)
+		assert.Equal(t, "10.1.2.0/24,192.0.2.0/24", info.Ingress[0].Source)
+		assert.Equal(t, "2001:db8::/64", info.Egress[0].Destination)
+	}
+
+	info = newInfo(map[string]string{"validation.strict_cidr": "maybe"})
+	_, err = d.validateConfig(info, ValidationModeStrict)
+	assert.ErrorContains(t, err, `Invalid value for config option "validation.strict_cidr"`)
+}
+
+func TestNormaliseRuleSubjects(t *testing.T) {
+	tests := []struct {
+		subjects   string
+		normalised string
+	}{
+		{" ", ""},
+		{"10.0.0.1, 10.0.0.1/32, 10.0.0.1", "10.0.0.1"},
+		{"2001:DB8:0:0::1/128,2001:db8::1", "2001:db8::1"},
+		{"2001:DB8::/32, 192.0.2.0/24", "192.0.2.0/24,2001:db8::/32"},
+		{"192.0.2.10-192.0.2.1, 2001:DB8::1-2001:db8::a", "192.0.2.10-192.0.2.1,2001:db8::1-2001:db8::a"},
+		{"web, ANY4, @Internal, Web, web", "@internal,Web,any4,web"},
+		{"@ovn1/peer1, db-servers", "@ovn1/peer1,db-servers"},
+		{"@IPv6, @ipv4", "@ipv4,@ipv6"},
+		{"FE80::1%eth0, fe80::1", "fe80::1,fe80::1%eth0"},
+	}
+
+	for _, test := range tests {
+		rule := api.NetworkACLRule{Source: test.subjects, Destination: test.subjects}
+		rule.Normalise()
+		assert.Equal(t, test.normalised, rule.Source, test.subjects)
+		assert.Equal(t, test.normalised, rule.Destination, test.subjects)
+	}
+}
+
+func TestNormaliseRulePorts(t *testing.T) {
+	tests := []struct {
+		ports      string
+		normalised string
+	}{
+		{" ", ""},
+		{"443, 80,443", "80,443"},
+		{"8000-8080,22, 8000-8080,8000", "22,8000,8000-8080"},
+		{"http,80", "80,http"},
+	}
+
+	for _, test := range tests {
+		rule := api.NetworkACLRule{SourcePort: test.ports, DestinationPort: test.ports}
+		rule.Normalise()
+		assert.Equal(t, test.normalised, rule.SourcePort, test.ports)
+		assert.Equal(t, test.normalised, rule.DestinationPort, test.ports)
+	}
+}
+
+func TestValidateConfigDuplicateSubjectOrder(t *testing.T) {
+	s, cleanup := state.NewTestState(t)
+	defer cleanup()
+
+	d := &common{}
+	d.init(s, -1, api.ProjectDefaultName, nil)
+
+	// Rules only differing by the order or form of their subjects and ports are duplicates.
+	info := &api.NetworkACLPut{
+		Ingress: []api.NetworkACLRule{
+			{Action: "allow", State: "enabled", Source: "192.0.2.1, 198.51.100.0/24"},
+			{Action: "allow", State: "enabled", Source: "198.51.100.0/24,192.0.2.1/32,192.0.2.1"},
+		},
+		Egress: []api.NetworkACLRule{
+			{Action: "allow", State: "enabled", Protocol: "tcp", SourcePort: "1024-65535", DestinationPort: "8080, 443,80"},
+			{Action: "allow", State: "enabled", Protocol: "tcp", SourcePort: "1024-65535", DestinationPort: "80"},
+			{Action: "allow", State: "enabled", Protocol: "tcp", SourcePort: "1024-65535", DestinationPort: "80,443,443,8080"},
+		},
+	}
+
+	assert.EqualError(t, validateStrict(d, info), "Found 2 problems: Ingress rule 1 is a duplicate of ingress rule 0; Egress rule 2 is a duplicate of egress rule 0")
+	assert.Equal(t, "192.0.2.1,198.51.100.0/24", info.Ingress[1].Source)
+	assert.Equal(t, "80,443,8080", info.Egress[0].DestinationPort)
+}
+
+func TestInfoRuleCounts(t *testing.T) {
+	s, cleanup := state.NewTestState(t)
+	defer cleanup()
+
+	err := s.DB.Cluster.Transaction(context.Background(), func(ctx context.Context, tx *db.ClusterTx) error {
+		var err error
+
+		s.GlobalConfig, err = clusterConfig.Load(ctx, tx)
+
+		return err
+	})
+	require.NoError(t, err)
+
+	_, err = Create(s, api.ProjectDefaultName, &api.NetworkACLsPost{NetworkACLPost: api.NetworkACLPost{Name: "web"}})
+	require.NoError(t, err)
+
+	netACL, err := LoadByName(s, api.ProjectDefaultName, "web")
+	require.NoError(t, err)
+	assert.Equal(t, 0, netACL.Info().IngressCount)
+	assert.Equal(t, 0, netACL.Info().EgressCount)
+
+	put := &api.NetworkACLPut{
+		Ingress: []api.NetworkACLRule{
+			{Action: "allow", State: "enabled", Protocol: "tcp", DestinationPort: "80"},
+			{Action: "allow", State: "enabled", Protocol: "tcp", DestinationPort: "443"},
+		},
+		Egress: []api.NetworkACLRule{
+			{Action: "drop", State: "enabled"},
+		},
+	}
+
+	_, err = netACL.Update(put, request.ClientTypeNormal, nil, ValidationModeStrict)
+	require.NoError(t, err)
+
+	info := netACL.Info()
+	assert.Equal(t, 2, info.IngressCount)
+	assert.Equal(t, 1, info.EgressCount)
+
+	// The counts are computed, so they aren't part of the etag.
+	other := &common{}
+	other.init(s, netACL.ID(), api.ProjectDefaultName, &api.NetworkACL{NetworkACLPost: info.NetworkACLPost, NetworkACLPut: info.NetworkACLPut})
+	assert.Equal(t, other.Etag(), netACL.Etag())
+}
+
+func TestEtag(t *testing.T) {
+	s, cleanup := state.NewTestState(t)
+	defer cleanup()
+
+	err := s.DB.Cluster.Transaction(context.Background(), func(ctx context.Context, tx *db.ClusterTx) error {
+		var err error
+
+		s.GlobalConfig, err = clusterConfig.Load(ctx, tx)
+
+		return err
+	})
+	require.NoError(t, err)
+
+	_, err = Create(s, api.ProjectDefaultName, &api.NetworkACLsPost{NetworkACLPost: api.NetworkACLPost{Name: "web"}})
+	require.NoError(t, err)
+
+	netACL, err := LoadByName(s, api.ProjectDefaultName, "web")
+	require.NoError(t, err)
+
+	put := &api.NetworkACLPut{
+		Ingress: []api.NetworkACLRule{
+			{Action: "allow", State: "enabled", Source: "192.0.2.1,198.51.100.0/24", Protocol: "tcp", DestinationPort: "80,443"},
+		},
+		Config: map[string]string{"user.a": "1", "user.b": "2"},
+	}
+
+	_, err = netACL.Update(put, request.ClientTypeNormal, nil, ValidationModeStrict)
+	require.NoError(t, err)
+
+	etag, err := localUtil.EtagHash(netACL.Etag())
+	require.NoError(t, err)
+
+	// Re-putting the same ACL with its subjects, ports and config keys reordered keeps the etag.
+	reordered := &api.NetworkACLPut{
+		Ingress: []api.NetworkACLRule{
+			{Action: "allow", State: "enabled", Source: " 198.51.100.0/24, 192.0.2.1", Protocol: "tcp", DestinationPort: "443, 80"},
+		},
+		Config: map[string]string{"user.b": "2", "user.a": "1"},
+	}
+
+	_, err = netACL.Update(reordered, request.ClientTypeNormal, nil, ValidationModeStrict)
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodPut, "/1.0/network-acls/web", nil)
+	req.Header.Set("If-Match", etag)
+	assert.NoError(t, localUtil.EtagCheck(req, netACL.Etag()))
+
+	// An actual change is still detected.
+	changed := &api.NetworkACLPut{
+		Ingress: []api.NetworkACLRule{
+			{Action: "allow", State: "enabled", Source: "192.0.2.1", Protocol: "tcp", DestinationPort: "80,443"},
+		},
+		Config: map[string]string{"user.a": "1", "user.b": "2"},
+	}
+
+	_, err = netACL.Update(changed, request.ClientTypeNormal, nil, ValidationModeStrict)
+	require.NoError(t, err)
+	assert.Error(t, localUtil.EtagCheck(req, netACL.Etag()))
+}
+
+func TestFilterNetworkACL(t *testing.T) {
+	aclInfo := api.NetworkACL{
+		NetworkACLPost: api.NetworkACLPost{Name: "web"},
+		NetworkACLPut: api.NetworkACLPut{
+			Description: "Web servers (SEC-123)",
+			Config:      map[string]string{"user.owner": "web-team"},
+		},
+	}
+
+	tests := []struct {
+		filter string
+		match  bool
+	}{
+		{"name eq web", true},
+		{"name eq WEB", true},
+		{"description eq .*sec-123.*", true},
+		{"description eq .*SEC-456.*", false},
+		{"config.user.owner eq web-team and name eq web", true},
+		{"config.user.owner eq db-team", false},
+		{"description contains sec-123", true},
+		{"description contains \"servers (SEC\"", true},
+		{"description contains SEC-456", false},
+	}
+
+	for _, tc := range tests {
+		clauses, err := filter.Parse(tc.filter, filter.QueryOperatorSet())
+		require.NoError(t, err)
+
+		match, err := filter.Match(aclInfo, *clauses)
+		require.NoError(t, err)
+		assert.Equal(t, tc.match, match, tc.filter)
+	}
+}
+
+func TestAssignedToNetwork(t *testing.T) {
+	s, cleanup := state.NewTestState(t)
+	defer cleanup()
+
+	err := s.DB.Cluster.Transaction(context.Background(), func(ctx context.Context, tx *db.ClusterTx) error {
+		for _, name := range []string{"web", "unused"} {
+			_, err := tx.CreateNetworkACL(ctx, api.ProjectDefaultName, &api.NetworkACLsPost{NetworkACLPost: api.NetworkACLPost{Name: name}})
+			if err != nil {
+				return err
+			}
+		}
+
+		_, err := tx.CreateNetwork(ctx, api.ProjectDefaultName, "net1", "", db.NetworkTypeBridge, map[string]string{"security.acls": "web"})
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.CreateNetwork(ctx, api.ProjectDefaultName, "net2", "", db.NetworkTypeBridge, map[string]string{})
+
+		return err
+	})
+	require.NoError(t, err)
+
+	netACL, err := LoadByName(s, api.ProjectDefaultName, "web")
+	require.NoError(t, err)
+
+	assigned, err := netACL.AssignedToNetwork("net1")
+	require.NoError(t, err)
+	assert.True(t, assigned)
+
+	assigned, err = netACL.AssignedToNetwork("net2")
+	require.NoError(t, err)
+	assert.False(t, assigned)
+
+	netACL, err = LoadByName(s, api.ProjectDefaultName, "unused")
+	require.NoError(t, err)
+
+	assigned, err = netACL.AssignedToNetwork("net1")
+	require.NoError(t, err)
+	assert.False(t, assigned)
+}
+
+func TestDeleteInUse(t *testing.T) {
+	s, cleanup := state.NewTestState(t)
+	defer cleanup()
+
+	err := s.DB.Cluster.Transaction(context.Background(), func(ctx context.Context, tx *db.ClusterTx) error {
+		acls := map[string]api.NetworkACLPut{
+			"db":    {},
+			"cache": {},
+			"web": {Ingress: []api.NetworkACLRule{
+				{Action: "allow", State: "enabled", Source: "192.0.2.1"},
+				{Action: "allow", State: "enabled", Source: "cache"},
+				{Action: "allow", State: "enabled", Source: "db"},
+			}},
+		}
+
+		for name, put := range acls {
+			_, err := tx.CreateNetworkACL(ctx, api.ProjectDefaultName, &api.NetworkACLsPost{NetworkACLPost: api.NetworkACLPost{Name: name}, NetworkACLPut: put})
+			if err != nil {
+				return err
+			}
+		}
+
+		id, err := dbCluster.CreateProfile(ctx, tx.Tx(), dbCluster.Profile{Project: api.ProjectDefaultName, Name: "p1"})
+		if err != nil {
+			return err
+		}
+
+		return dbCluster.CreateProfileDevices(ctx, tx.Tx(), id, map[string]dbCluster.Device{
+			"eth0": {Name: "eth0", Type: dbCluster.TypeNIC, Config: map[string]string{"network": "ovn1", "security.acls": "db"}},
+		})
+	})
+	require.NoError(t, err)
+
+	// The error lists the rules and NICs using the ACL.
+	netACL, err := LoadByName(s, api.ProjectDefaultName, "db")
+	require.NoError(t, err)
+	assert.EqualError(t, netACL.Delete(), "Cannot delete an ACL that is in use by profile p1 (device eth0), ACL web (ingress rule 2)")
+	assert.EqualError(t, netACL.Rename("other", nil), "Cannot rename an ACL that is in use by profile p1 (device eth0), ACL web (ingress rule 2)")
+
+	netACL, err = LoadByName(s, api.ProjectDefaultName, "cache")
+	require.NoError(t, err)
+	assert.EqualError(t, netACL.Delete(), "Cannot delete an ACL that is in use by ACL web (ingress rule 1)")
+
+	// ACLs not in use can be deleted.
+	netACL, err = LoadByName(s, api.ProjectDefaultName, "web")
+	require.NoError(t, err)
+	assert.NoError(t, netACL.Delete())
+
+	netACL, err = LoadByName(s, api.ProjectDefaultName, "cache")
+	require.NoError(t, err)
+	assert.NoError(t, netACL.Delete())
+}
+
+func TestUpdateConcurrent(t *testing.T) {
+	s, cleanup := state.NewTestState(t)
+	defer cleanup()
+
+	err := s.DB.Cluster.Transaction(context.Background(), func(ctx context.Context, tx *db.ClusterTx) error {
+		var err error
+
+		s.GlobalConfig, err = clusterConfig.Load(ctx, tx)
+
+		return err
+	})
+	require.NoError(t, err)
+
+	_, err = Create(s, api.ProjectDefaultName, &api.NetworkACLsPost{NetworkACLPost: api.NetworkACLPost{Name: "web"}, NetworkACLPut: api.NetworkACLPut{Description: "initial"}})
+	require.NoError(t, err)
+
+	// Load all the copies of the ACL first, as concurrent API requests would.
+	const updates = 5
+	netACLs := make([]NetworkACL, updates)
+	for i := range netACLs {
+		netACLs[i], err = LoadByName(s, api.ProjectDefaultName, "web")
+		require.NoError(t, err)
+	}
+
+	var wg sync.WaitGroup
+	for i, netACL := range netACLs {
+		wg.Add(1)
+		go func(i int, netACL NetworkACL) {
+			defer wg.Done()
+
+			put := &api.NetworkACLPut{
+				Description: fmt.Sprintf("update%d", i),
+				Ingress:     []api.NetworkACLRule{{Action: "allow", State: "enabled", Protocol: "tcp", DestinationPort: fmt.Sprintf("%d", 8000+i)}},
+			}
+
+			_, err := netACL.Update(put, request.ClientTypeNormal, nil, ValidationModeStrict)
+			assert.NoError(t, err)
+		}(i, netACL)
+	}
+
+	wg.Wait()
+
+	var final *api.NetworkACL
+	var revisions []api.NetworkACLRevision
+	err = s.DB.Cluster.Transaction(context.Background(), func(ctx context.Context, tx *db.ClusterTx) error {
+		var id int64
+		id, final, err = tx.GetNetworkACL(ctx, api.ProjectDefaultName, "web")
+		if err != nil {
+			return err
+		}
+
+		revisions, err = tx.GetNetworkACLRevisions(ctx, id)
+		return err
+	})
+	require.NoError(t, err)
+
+	// The updates were applied one after the other, each one replacing the version written by the previous one,
+	// so every version shows up exactly once in the history.
+	seen := []string{final.Description}
+	for _, revision := range revisions {
+		seen = append(seen, revision.Description)
+	}
+
+	slices.Sort(seen)
+	assert.Equal(t, []string{"initial", "update0", "update1", "update2", "update3", "update4"}, seen)
+
+	// The rules applied by the last update are the ones stored in the database.
+	for _, netACL := range netACLs {
+		if netACL.Info().Description == final.Description {
+			assert.Equal(t, final.Ingress, netACL.Info().Ingress)
+		}
+	}
+
+	// Changes waiting on an ACL deleted in the meantime fail.
+	stale, err := LoadByName(s, api.ProjectDefaultName, "web")
+	require.NoError(t, err)
+	require.NoError(t, netACLs[0].Delete())
+
+	_, err = stale.Update(&api.NetworkACLPut{}, request.ClientTypeNormal, nil, ValidationModeStrict)
+	assert.True(t, api.StatusErrorCheck(err, http.StatusNotFound))
+}
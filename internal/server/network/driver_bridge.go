@@ -417,10 +417,33 @@ func (n *bridge) Validate(config map[string]string) error {
 
 	// Check Security ACLs are supported and exist.
 	if config["security.acls"] != "" {
-		err = acl.Exists(n.state, n.Project(), util.SplitNTrimSpace(config["security.acls"], ",", -1, true)...)
+		aclNames := util.SplitNTrimSpace(config["security.acls"], ",", -1, true)
+
+		err = acl.Exists(n.state, n.Project(), aclNames...)
 		if err != nil {
 			return err
 		}
+
+		// Check that the rules of newly assigned ACLs can be enforced on the bridge. ACLs that were assigned
+		// already aren't checked again so that the network can still start after their members changed.
+		checkACLNames := aclNames
+		if n.LocalStatus() == api.NetworkStatusCreated {
+			currentACLNames := util.SplitNTrimSpace(n.config["security.acls"], ",", -1, true)
+
+			checkACLNames = []string{}
+			for _, aclName := range aclNames {
+				if !slices.Contains(currentACLNames, aclName) {
+					checkACLNames = append(checkACLNames, aclName)
+				}
+			}
+		}
+
+		if len(checkACLNames) > 0 {
+			err = acl.FirewallValidateACLs(n.state, n.Project(), n.Name(), aclNames, checkACLNames)
+			if err != nil {
+				return err
+			}
+		}
 	}
 
 	return nil
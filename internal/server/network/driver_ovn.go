@@ -386,6 +386,8 @@ func (n *ovn) Validate(config map[string]string) error {
 		"security.acls.default.egress.action":  validate.Optional(validate.IsOneOf(acl.ValidActions...)),
 		"security.acls.default.ingress.logged": validate.Optional(validate.IsBool),
 		"security.acls.default.egress.logged":  validate.Optional(validate.IsBool),
+		"security.acls.logging.severity":       validate.Optional(validate.IsOneOf("alert", "warning", "notice", "info", "debug")),
+		"security.acls.logging.rate":           validate.Optional(validate.IsUint32),
 
 		// Volatile keys populated automatically as needed.
 		ovnVolatileUplinkIPv4: validate.Optional(validate.IsNetworkAddressV4),
@@ -919,11 +921,6 @@ func (n *ovn) getIntSwitchRouterPortName() networkOVN.OVNSwitchPort {
 	return acl.OVNIntSwitchRouterPortName(n.id)
 }
 
-// getIntSwitchInstancePortPrefix returns OVN logical internal switch instance port name prefix.
-func (n *ovn) getIntSwitchInstancePortPrefix() string {
-	return fmt.Sprintf("%s-instance", n.getNetworkPrefix())
-}
-
 // getLoadBalancerName returns OVN load balancer name to use for a listen address.
 func (n *ovn) getLoadBalancerName(listenAddress string) networkOVN.OVNLoadBalancer {
 	return networkOVN.OVNLoadBalancer(fmt.Sprintf("%s-lb-%s", n.getNetworkPrefix(), listenAddress))
@@ -2558,6 +2555,14 @@ func (n *ovn) setup(update bool) error {
 		return fmt.Errorf("Failed to setup network port group: %w", err)
 	}
 
+	// Create the ACL log meter if needed, before any ACL rules using it are applied.
+	if n.config["security.acls.logging.rate"] != "" {
+		err = acl.OVNUpdateNetworkLogMeter(n.ovnnb, n.ID(), n.config)
+		if err != nil {
+			return err
+		}
+	}
+
 	// Ensure any network assigned security ACL port groups are created ready for instance NICs to use.
 	securityACLS := util.SplitNTrimSpace(n.config["security.acls"], ",", -1, true)
 	if len(securityACLS) > 0 {
@@ -2799,6 +2804,12 @@ func (n *ovn) Delete(clientType request.ClientType) error {
 			return err
 		}
 
+		// Delete the ACL log meter.
+		err = n.ovnnb.DeleteMeter(context.TODO(), acl.OVNNetworkLogMeterName(n.ID()))
+		if err != nil {
+			return err
+		}
+
 		// Delete the chassis group for the network.
 		err = n.ovnnb.DeleteChassisGroup(context.TODO(), n.getChassisGroupName())
 		if err != nil && err != networkOVN.ErrNotFound {
@@ -3307,6 +3318,21 @@ func (n *ovn) Update(newNetwork api.NetworkPut, targetNode string, clientType re
 				return err
 			}
 		}
+
+		// Reapply the network specific ACL rules if the network's ACL log settings have changed.
+		if slices.Contains(changedKeys, "security.acls.logging.severity") || slices.Contains(changedKeys, "security.acls.logging.rate") {
+			aclNet := acl.NetworkACLUsage{Name: n.Name(), Type: n.Type(), ID: n.ID(), Config: newNetwork.Config}
+
+			err = acl.OVNApplyNetworkLogging(n.state, n.logger, n.ovnnb, n.Project(), aclNet)
+			if err != nil {
+				return fmt.Errorf("Failed applying ACL log settings: %w", err)
+			}
+
+			revert.Add(func() {
+				aclNet.Config = oldNetwork.Config
+				_ = acl.OVNApplyNetworkLogging(n.state, n.logger, n.ovnnb, n.Project(), aclNet)
+			})
+		}
 	}
 
 	// If uplink network is changing, start network after config applied.
@@ -3329,7 +3355,7 @@ func (n *ovn) Update(newNetwork api.NetworkPut, targetNode string, clientType re
 
 // getInstanceDevicePortName returns the switch port name to use for an instance device.
 func (n *ovn) getInstanceDevicePortName(instanceUUID string, deviceName string) networkOVN.OVNSwitchPort {
-	return networkOVN.OVNSwitchPort(fmt.Sprintf("%s-%s-%s", n.getIntSwitchInstancePortPrefix(), instanceUUID, deviceName))
+	return acl.OVNIntSwitchInstancePortName(n.id, instanceUUID, deviceName)
 }
 
 // instanceDevicePortRoutesParse parses the instance NIC device config for internal routes and external routes.
@@ -3946,14 +3972,7 @@ func (n *ovn) InstanceDevicePortStart(opts *OVNInstanceNICSetupOpts, securityACL
 	}
 
 	// Merge network and NIC assigned security ACL lists.
-	netACLNames := util.SplitNTrimSpace(n.config["security.acls"], ",", -1, true)
-	nicACLNames := util.SplitNTrimSpace(opts.DeviceConfig["security.acls"], ",", -1, true)
-
-	for _, aclName := range netACLNames {
-		if !slices.Contains(nicACLNames, aclName) {
-			nicACLNames = append(nicACLNames, aclName)
-		}
-	}
+	nicACLNames := acl.NICACLNames(n.Type(), n.config, opts.DeviceConfig)
 
 	// Apply Security ACL port group settings.
 	addChangeSet := map[networkOVN.OVNPortGroup][]networkOVN.OVNSwitchPortUUID{}
@@ -4068,20 +4087,7 @@ func (n *ovn) InstanceDevicePortStart(opts *OVNInstanceNICSetupOpts, securityACL
 // specified in the NIC device config, then the settings on the network are used, and if not specified there then
 // it returns "reject" and false respectively.
 func (n *ovn) instanceDeviceACLDefaults(deviceConfig deviceConfig.Device, direction string) (string, bool) {
-	defaults := map[string]string{
-		fmt.Sprintf("security.acls.default.%s.action", direction): "reject",
-		fmt.Sprintf("security.acls.default.%s.logged", direction): "false",
-	}
-
-	for k := range defaults {
-		if deviceConfig[k] != "" {
-			defaults[k] = deviceConfig[k]
-		} else if n.config[k] != "" {
-			defaults[k] = n.config[k]
-		}
-	}
-
-	return defaults[fmt.Sprintf("security.acls.default.%s.action", direction)], util.IsTrue(defaults[fmt.Sprintf("security.acls.default.%s.logged", direction)])
+	return acl.NICDefaults(deviceConfig, n.config, direction)
 }
 
 // InstanceDevicePortIPs returns the allocated IPs for a device port.
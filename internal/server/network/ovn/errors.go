@@ -1,7 +1,12 @@
 package ovn
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"net"
+	"strings"
 
 	ovsdbClient "github.com/ovn-org/libovsdb/client"
 )
@@ -14,3 +19,30 @@ var ErrNotFound = ovsdbClient.ErrNotFound
 
 // ErrNotManaged indicates that a DB record wasn't created by Incus.
 var ErrNotManaged = fmt.Errorf("object not incus-managed")
+
+// IsTransientError returns whether the error is caused by the OVN database being temporarily unreachable, such as
+// during a connection loss or a leader election, meaning that the operation may succeed if retried.
+func IsTransientError(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	if errors.Is(err, ovsdbClient.ErrNotConnected) || errors.Is(err, context.DeadlineExceeded) || errors.Is(err, io.EOF) {
+		return true
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+
+	// libovsdb doesn't wrap the errors it reports when failing to (re)connect to the database cluster.
+	msg := err.Error()
+	for _, transientMsg := range []string{"unable to connect to any endpoints", "not leader", "while awaiting reconnection"} {
+		if strings.Contains(msg, transientMsg) {
+			return true
+		}
+	}
+
+	return false
+}
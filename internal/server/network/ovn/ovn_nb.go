@@ -33,23 +33,26 @@ func NewNB(dbAddr string, sslCACert string, sslClientCert string, sslClientKey s
 		return nb, nil
 	}
 
+	client, err := ConnectNB(dbAddr, sslCACert, sslClientCert, sslClientKey)
+	if err != nil {
+		return nil, err
+	}
+
+	nb = client
+	return client, nil
+}
+
+// ConnectNB initializes a new OVN client for Northbound operations, which unlike NewNB isn't shared.
+func ConnectNB(dbAddr string, sslCACert string, sslClientCert string, sslClientKey string) (*NB, error) {
 	// Create the NB struct.
 	client := &NB{}
 
 	// Prepare the OVSDB client.
-	dbSchema, err := ovnNB.FullDatabaseModel()
+	dbSchema, err := nbDatabaseModel()
 	if err != nil {
 		return nil, err
 	}
 
-	// Add some missing indexes.
-	dbSchema.SetIndexes(map[string][]ovsdbModel.ClientIndex{
-		"Load_Balancer":       {{Columns: []ovsdbModel.ColumnKey{{Column: "name"}}}},
-		"Logical_Router":      {{Columns: []ovsdbModel.ColumnKey{{Column: "name"}}}},
-		"Logical_Switch":      {{Columns: []ovsdbModel.ColumnKey{{Column: "name"}}}},
-		"Logical_Switch_Port": {{Columns: []ovsdbModel.ColumnKey{{Column: "name"}}}},
-	})
-
 	discard := logr.Discard()
 
 	options := []ovsdbClient.Option{ovsdbClient.WithLogger(&discard), ovsdbClient.WithReconnect(5*time.Second, &backoff.ZeroBackOff{})}
@@ -162,10 +165,27 @@ func NewNB(dbAddr string, sslCACert string, sslClientCert string, sslClientKey s
 		ovn.Close()
 	})
 
-	nb = client
 	return client, nil
 }
 
+// nbDatabaseModel returns the northbound database model used by the client.
+func nbDatabaseModel() (ovsdbModel.ClientDBModel, error) {
+	dbSchema, err := ovnNB.FullDatabaseModel()
+	if err != nil {
+		return ovsdbModel.ClientDBModel{}, err
+	}
+
+	// Add some missing indexes.
+	dbSchema.SetIndexes(map[string][]ovsdbModel.ClientIndex{
+		"Load_Balancer":       {{Columns: []ovsdbModel.ColumnKey{{Column: "name"}}}},
+		"Logical_Router":      {{Columns: []ovsdbModel.ColumnKey{{Column: "name"}}}},
+		"Logical_Switch":      {{Columns: []ovsdbModel.ColumnKey{{Column: "name"}}}},
+		"Logical_Switch_Port": {{Columns: []ovsdbModel.ColumnKey{{Column: "name"}}}},
+	})
+
+	return dbSchema, nil
+}
+
 // get is used to perform a libovsdb Get call while also makes use of the custom defined index.
 // For some reason the main Get() function only uses the built-in indices rather than considering the user provided ones.
 // This is apparently by design but makes it much more annoying to fetch records from some tables.
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net"
 	"slices"
+	"strconv"
 	"strings"
 	"time"
 
@@ -53,6 +54,9 @@ type OVNLoadBalancer string
 // OVNAddressSet OVN address set for ACLs.
 type OVNAddressSet string
 
+// OVNMeter OVN meter name.
+type OVNMeter string
+
 // OVNIPAllocationOpts defines IP allocation settings that can be applied to a logical switch.
 type OVNIPAllocationOpts struct {
 	PrefixIPv4  *net.IPNet
@@ -78,6 +82,8 @@ const ovnExtIDIncusSwitchPort = "incus_switch_port"
 const ovnExtIDIncusProjectID = "incus_project_id"
 const ovnExtIDIncusPortGroup = "incus_port_group"
 const ovnExtIDIncusLocation = "incus_location"
+const ovnExtIDIncusACLRule = "incus_acl_rule"
+const ovnExtIDIncusACLDSCP = "incus_acl_dscp"
 
 // OVNIPv6RAOpts IPv6 router advertisements options that can be applied to a router.
 type OVNIPv6RAOpts struct {
@@ -138,6 +144,10 @@ type OVNACLRule struct {
 	Priority  int    // Priority (between 0 and 32767, inclusive). Higher values take precedence.
 	Log       bool   // Whether or not to log matched packets.
 	LogName   string // Log label name (requires Log be true).
+	LogLevel  string // Log severity, OVN uses "info" if empty (requires Log be true).
+	LogMeter  string // Name of the meter rate limiting the log messages (requires Log be true).
+	RuleID    string // Identifier of the Incus ACL rule this was generated from (optional).
+	DSCP      string // DSCP value (0-63) to mark matching traffic with, or empty to leave it unchanged.
 }
 
 // OVNLoadBalancerTarget represents an OVN load balancer Virtual IP target.
@@ -2485,6 +2495,51 @@ func (o *NB) SetChassisGroupPriority(ctx context.Context, haChassisGroupName OVN
 	return nil
 }
 
+// NBTransaction collects changes to the northbound database so that they are applied in a single transaction.
+// Reads made while building the transaction come from the local cache and don't see the pending changes.
+type NBTransaction struct {
+	nb                 *NB
+	operations         []ovsdb.Operation
+	createdPortGroups  map[OVNPortGroup]struct{}
+	changedMeters      map[OVNMeter]struct{}
+	changedAddressSets map[OVNAddressSet]struct{}
+}
+
+// NewTransaction returns a new empty northbound transaction.
+func (o *NB) NewTransaction() *NBTransaction {
+	return &NBTransaction{
+		nb:                 o,
+		createdPortGroups:  map[OVNPortGroup]struct{}{},
+		changedMeters:      map[OVNMeter]struct{}{},
+		changedAddressSets: map[OVNAddressSet]struct{}{},
+	}
+}
+
+// Commit applies the changes collected in the transaction in a single round-trip.
+func (t *NBTransaction) Commit(ctx context.Context) error {
+	// Check if we have anything to do.
+	if len(t.operations) == 0 {
+		return nil
+	}
+
+	resp, err := t.nb.client.Transact(ctx, t.operations...)
+	if err != nil {
+		return err
+	}
+
+	_, err = ovsdb.CheckOperationResults(resp, t.operations)
+	if err != nil {
+		return err
+	}
+
+	t.operations = nil
+	t.createdPortGroups = map[OVNPortGroup]struct{}{}
+	t.changedMeters = map[OVNMeter]struct{}{}
+	t.changedAddressSets = map[OVNAddressSet]struct{}{}
+
+	return nil
+}
+
 // GetPortGroupInfo returns the port group UUID or empty string if port doesn't exist, and whether the port group has
 // any ACL rules defined on it.
 func (o *NB) GetPortGroupInfo(ctx context.Context, portGroupName OVNPortGroup) (OVNPortGroupUUID, bool, error) {
@@ -2506,6 +2561,27 @@ func (o *NB) GetPortGroupInfo(ctx context.Context, portGroupName OVNPortGroup) (
 
 // CreatePortGroup creates a new port group and optionally adds logical switch ports to the group.
 func (o *NB) CreatePortGroup(ctx context.Context, projectID int64, portGroupName OVNPortGroup, associatedPortGroup OVNPortGroup, associatedSwitch OVNSwitch, initialPortMembers ...OVNSwitchPort) error {
+	txn := o.NewTransaction()
+
+	err := txn.CreatePortGroup(ctx, projectID, portGroupName, associatedPortGroup, associatedSwitch, initialPortMembers...)
+	if err != nil {
+		return err
+	}
+
+	return txn.Commit(ctx)
+}
+
+// CreatePortGroup adds the creation of a new port group to the transaction and optionally adds logical switch
+// ports to the group. Creating a port group that the transaction already creates does nothing, as callers checking
+// for the port group in the cache can't see the pending creation.
+func (t *NBTransaction) CreatePortGroup(ctx context.Context, projectID int64, portGroupName OVNPortGroup, associatedPortGroup OVNPortGroup, associatedSwitch OVNSwitch, initialPortMembers ...OVNSwitchPort) error {
+	o := t.nb
+
+	_, created := t.createdPortGroups[portGroupName]
+	if created {
+		return nil
+	}
+
 	// Resolve the initial members.
 	members := []string{}
 	for _, portName := range initialPortMembers {
@@ -2546,32 +2622,38 @@ func (o *NB) CreatePortGroup(ctx context.Context, projectID int64, portGroupName
 		return err
 	}
 
-	// Apply the changes.
-	resp, err := o.client.Transact(ctx, operations...)
-	if err != nil {
-		return err
-	}
+	t.operations = append(t.operations, operations...)
+	t.createdPortGroups[portGroupName] = struct{}{}
 
-	_, err = ovsdb.CheckOperationResults(resp, operations)
+	return nil
+}
+
+// DeletePortGroup deletes port groups along with their ACL rules.
+func (o *NB) DeletePortGroup(ctx context.Context, portGroupNames ...OVNPortGroup) error {
+	txn := o.NewTransaction()
+
+	err := txn.DeletePortGroup(ctx, portGroupNames...)
 	if err != nil {
 		return err
 	}
 
-	return nil
+	return txn.Commit(ctx)
 }
 
-// DeletePortGroup deletes port groups along with their ACL rules.
-func (o *NB) DeletePortGroup(ctx context.Context, portGroupNames ...OVNPortGroup) error {
-	operations := []ovsdb.Operation{}
+// DeletePortGroup adds the deletion of port groups along with their ACL rules to the transaction.
+func (t *NBTransaction) DeletePortGroup(ctx context.Context, portGroupNames ...OVNPortGroup) error {
+	o := t.nb
 
 	for _, portGroupName := range portGroupNames {
 		pg := ovnNB.PortGroup{
 			Name: string(portGroupName),
 		}
 
+		_, created := t.createdPortGroups[portGroupName]
+
 		err := o.get(ctx, &pg)
 		if err != nil {
-			if err == ErrNotFound {
+			if err == ErrNotFound && !created {
 				// Already gone.
 				continue
 			}
@@ -2582,23 +2664,21 @@ func (o *NB) DeletePortGroup(ctx context.Context, portGroupNames ...OVNPortGroup
 			return err
 		}
 
-		operations = append(operations, deleteOps...)
-	}
+		t.operations = append(t.operations, deleteOps...)
+		delete(t.createdPortGroups, portGroupName)
 
-	// Check if we have anything to do.
-	if len(operations) == 0 {
-		return nil
-	}
+		// Remove the QoS rules matching on the port group from their logical switch.
+		qosRules, err := o.portGroupQoSRules(ctx, portGroupName)
+		if err != nil {
+			return err
+		}
 
-	// Apply the changes.
-	resp, err := o.client.Transact(ctx, operations...)
-	if err != nil {
-		return err
-	}
+		qosDeleteOps, err := o.qosRuleDeleteOperations(qosRules)
+		if err != nil {
+			return err
+		}
 
-	_, err = ovsdb.CheckOperationResults(resp, operations)
-	if err != nil {
-		return err
+		t.operations = append(t.operations, qosDeleteOps...)
 	}
 
 	return nil
@@ -2623,6 +2703,26 @@ func (o *NB) GetPortGroupsByProject(ctx context.Context, projectID int64) ([]OVN
 	return pgNames, nil
 }
 
+// GetPortGroupsByPrefix returns the port groups whose name starts with the prefix along with their number of
+// member ports.
+func (o *NB) GetPortGroupsByPrefix(ctx context.Context, prefix string) (map[OVNPortGroup]int, error) {
+	portGroups := []ovnNB.PortGroup{}
+
+	err := o.client.WhereCache(func(pg *ovnNB.PortGroup) bool {
+		return strings.HasPrefix(pg.Name, prefix)
+	}).List(ctx, &portGroups)
+	if err != nil {
+		return nil, err
+	}
+
+	pgPorts := make(map[OVNPortGroup]int, len(portGroups))
+	for _, portGroup := range portGroups {
+		pgPorts[OVNPortGroup(portGroup.Name)] = len(portGroup.Ports)
+	}
+
+	return pgPorts, nil
+}
+
 // UpdatePortGroupMembers adds/removes logical switch ports (by UUID) to/from existing port groups.
 func (o *NB) UpdatePortGroupMembers(ctx context.Context, addMembers map[OVNPortGroup][]OVNSwitchPortUUID, removeMembers map[OVNPortGroup][]OVNSwitchPortUUID) error {
 	operations := []ovsdb.Operation{}
@@ -2684,8 +2784,24 @@ func (o *NB) UpdatePortGroupMembers(ctx context.Context, addMembers map[OVNPortG
 	return nil
 }
 
-// UpdatePortGroupACLRules applies a set of rules to the specified port group. Any existing rules are removed.
+// UpdatePortGroupACLRules applies a set of rules to the specified port group. Any existing rules that aren't part
+// of the new set are removed.
 func (o *NB) UpdatePortGroupACLRules(ctx context.Context, portGroupName OVNPortGroup, matchReplace map[string]string, aclRules ...OVNACLRule) error {
+	txn := o.NewTransaction()
+
+	err := txn.UpdatePortGroupACLRules(ctx, portGroupName, matchReplace, aclRules...)
+	if err != nil {
+		return err
+	}
+
+	return txn.Commit(ctx)
+}
+
+// UpdatePortGroupACLRules adds applying a set of rules to the specified port group to the transaction. Any existing
+// rules that aren't part of the new set are removed, while rules that are already applied are left untouched.
+// The port group may be one created earlier in the same transaction.
+func (t *NBTransaction) UpdatePortGroupACLRules(ctx context.Context, portGroupName OVNPortGroup, matchReplace map[string]string, aclRules ...OVNACLRule) error {
+	o := t.nb
 	operations := []ovsdb.Operation{}
 
 	// Get the port group.
@@ -2695,11 +2811,42 @@ func (o *NB) UpdatePortGroupACLRules(ctx context.Context, portGroupName OVNPortG
 
 	err := o.get(ctx, &pg)
 	if err != nil {
+		_, created := t.createdPortGroups[portGroupName]
+		if err != ErrNotFound || !created {
+			return err
+		}
+	}
+
+	// Perform any replacements requested on the Match strings so the new rules can be compared to the applied ones.
+	newRules := make([]OVNACLRule, 0, len(aclRules))
+	for _, rule := range aclRules {
+		for find, replace := range matchReplace {
+			rule.Match = strings.ReplaceAll(rule.Match, find, replace)
+		}
+
+		if !rule.Log {
+			rule.LogName = ""
+			rule.LogLevel = ""
+			rule.LogMeter = ""
+		}
+
+		newRules = append(newRules, rule)
+	}
+
+	// Work out which of the existing rules can be kept. If any of them can't be loaded, fallback to replacing
+	// all of them.
+	removeUUIDs := pg.ACLs
+	addRules := newRules
+
+	existingRules, err := o.getACLRules(ctx, pg.ACLs)
+	if err == nil {
+		removeUUIDs, addRules = diffACLRules(existingRules, newRules)
+	} else if err != ErrNotFound {
 		return err
 	}
 
-	// Remove any existing rules assigned to the port group.
-	for _, aclUUID := range pg.ACLs {
+	// Remove the existing rules that are no longer needed.
+	for _, aclUUID := range removeUUIDs {
 		updateOps, err := o.client.Where(&pg).Mutate(&pg, ovsModel.Mutation{
 			Field:   &pg.ACLs,
 			Mutator: ovsdb.MutateOperationDelete,
@@ -2712,32 +2859,340 @@ func (o *NB) UpdatePortGroupACLRules(ctx context.Context, portGroupName OVNPortG
 		operations = append(operations, updateOps...)
 	}
 
-	// Add new rules.
+	// Add the missing rules.
 	externalIDs := map[string]string{
 		ovnExtIDIncusPortGroup: string(portGroupName),
 	}
 
-	createOps, err := o.aclRuleAddOperations(ctx, "port_group", string(portGroupName), externalIDs, matchReplace, aclRules...)
+	createOps, err := o.aclRuleAddOperations(ctx, "port_group", string(portGroupName), externalIDs, nil, addRules...)
 	if err != nil {
 		return err
 	}
 
 	operations = append(operations, createOps...)
+	t.operations = append(t.operations, operations...)
 
-	// Apply the changes.
-	resp, err := o.client.Transact(ctx, operations...)
+	return nil
+}
+
+// UpdatePortGroupQoSRules adds applying the DSCP marks of a set of port group rules to the specified logical switch
+// to the transaction. OVN ACLs can't change the packets they match, so the marks are applied by QoS rules of the
+// logical switch using the same match as the ACL rules. Rules without a DSCP mark are ignored. The existing QoS
+// rules for the port group are only replaced when they differ from the wanted ones.
+func (t *NBTransaction) UpdatePortGroupQoSRules(ctx context.Context, portGroupName OVNPortGroup, switchName OVNSwitch, matchReplace map[string]string, aclRules ...OVNACLRule) error {
+	o := t.nb
+
+	// Build the wanted QoS rules.
+	newRules := []ovnNB.QoS{}
+	for _, rule := range aclRules {
+		if rule.DSCP == "" {
+			continue
+		}
+
+		dscp, err := strconv.Atoi(rule.DSCP)
+		if err != nil {
+			return fmt.Errorf("Invalid DSCP value %q: %w", rule.DSCP, err)
+		}
+
+		for find, replace := range matchReplace {
+			rule.Match = strings.ReplaceAll(rule.Match, find, replace)
+		}
+
+		newRules = append(newRules, ovnNB.QoS{
+			Action:    map[string]int{ovnNB.QoSActionDSCP: dscp},
+			Direction: rule.Direction,
+			Match:     rule.Match,
+			Priority:  rule.Priority,
+			ExternalIDs: map[string]string{
+				ovnExtIDIncusPortGroup: string(portGroupName),
+				ovnExtIDIncusSwitch:    string(switchName),
+			},
+		})
+	}
+
+	slices.SortFunc(newRules, compareQoSRules)
+
+	existingRules, err := o.portGroupQoSRules(ctx, portGroupName)
 	if err != nil {
 		return err
 	}
 
-	_, err = ovsdb.CheckOperationResults(resp, operations)
+	// Leave the existing rules untouched if they already apply the wanted marks.
+	if len(existingRules) == len(newRules) {
+		changed := false
+		for i := range newRules {
+			if existingRules[i].ExternalIDs[ovnExtIDIncusSwitch] != string(switchName) || existingRules[i].Match != newRules[i].Match || existingRules[i].Priority != newRules[i].Priority || existingRules[i].Direction != newRules[i].Direction || existingRules[i].Action[ovnNB.QoSActionDSCP] != newRules[i].Action[ovnNB.QoSActionDSCP] {
+				changed = true
+				break
+			}
+		}
+
+		if !changed {
+			return nil
+		}
+	}
+
+	deleteOps, err := o.qosRuleDeleteOperations(existingRules)
 	if err != nil {
 		return err
 	}
 
+	t.operations = append(t.operations, deleteOps...)
+
+	ls := ovnNB.LogicalSwitch{
+		Name: string(switchName),
+	}
+
+	for i := range newRules {
+		newRules[i].UUID = fmt.Sprintf("qos_%s_%d", namedUUIDReplacer.Replace(string(portGroupName)), i)
+
+		createOps, err := o.client.Create(&newRules[i])
+		if err != nil {
+			return err
+		}
+
+		t.operations = append(t.operations, createOps...)
+
+		updateOps, err := o.client.Where(&ls).Mutate(&ls, ovsModel.Mutation{
+			Field:   &ls.QOSRules,
+			Mutator: ovsdb.MutateOperationInsert,
+			Value:   []string{newRules[i].UUID},
+		})
+		if err != nil {
+			return err
+		}
+
+		t.operations = append(t.operations, updateOps...)
+	}
+
 	return nil
 }
 
+// portGroupQoSRules returns the QoS rules applying the DSCP marks of a port group's rules, sorted so that they
+// can be compared with the wanted ones.
+func (o *NB) portGroupQoSRules(ctx context.Context, portGroupName OVNPortGroup) ([]ovnNB.QoS, error) {
+	qosRules := []ovnNB.QoS{}
+
+	err := o.client.WhereCache(func(qos *ovnNB.QoS) bool {
+		return qos.ExternalIDs != nil && qos.ExternalIDs[ovnExtIDIncusPortGroup] == string(portGroupName)
+	}).List(ctx, &qosRules)
+	if err != nil {
+		return nil, err
+	}
+
+	slices.SortFunc(qosRules, compareQoSRules)
+
+	return qosRules, nil
+}
+
+// compareQoSRules orders QoS rules by decreasing priority and then by match.
+func compareQoSRules(a ovnNB.QoS, b ovnNB.QoS) int {
+	if a.Priority != b.Priority {
+		return b.Priority - a.Priority
+	}
+
+	return strings.Compare(a.Match, b.Match)
+}
+
+// qosRuleDeleteOperations returns the operations that remove the provided QoS rules from their logical switch.
+func (o *NB) qosRuleDeleteOperations(qosRules []ovnNB.QoS) ([]ovsdb.Operation, error) {
+	operations := []ovsdb.Operation{}
+
+	for _, qos := range qosRules {
+		deleteOps, err := o.client.Where(&qos).Delete()
+		if err != nil {
+			return nil, err
+		}
+
+		operations = append(operations, deleteOps...)
+
+		ls := ovnNB.LogicalSwitch{
+			Name: qos.ExternalIDs[ovnExtIDIncusSwitch],
+		}
+
+		updateOps, err := o.client.Where(&ls).Mutate(&ls, ovsModel.Mutation{
+			Field:   &ls.QOSRules,
+			Mutator: ovsdb.MutateOperationDelete,
+			Value:   []string{qos.UUID},
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		operations = append(operations, updateOps...)
+	}
+
+	return operations, nil
+}
+
+// getACLRules returns the ACL rules for the specified ACL UUIDs keyed by UUID.
+func (o *NB) getACLRules(ctx context.Context, aclUUIDs []string) (map[string]OVNACLRule, error) {
+	aclRules := make(map[string]OVNACLRule, len(aclUUIDs))
+	for _, aclUUID := range aclUUIDs {
+		acl := ovnNB.ACL{
+			UUID: aclUUID,
+		}
+
+		err := o.get(ctx, &acl)
+		if err != nil {
+			return nil, err
+		}
+
+		aclRule := OVNACLRule{
+			Direction: acl.Direction,
+			Action:    acl.Action,
+			Match:     acl.Match,
+			Priority:  acl.Priority,
+			Log:       acl.Log,
+		}
+
+		if acl.Log {
+			if acl.Name != nil {
+				aclRule.LogName = *acl.Name
+			}
+
+			if acl.Severity != nil {
+				aclRule.LogLevel = *acl.Severity
+			}
+
+			if acl.Meter != nil {
+				aclRule.LogMeter = *acl.Meter
+			}
+		}
+
+		if acl.ExternalIDs != nil {
+			aclRule.RuleID = acl.ExternalIDs[ovnExtIDIncusACLRule]
+			aclRule.DSCP = acl.ExternalIDs[ovnExtIDIncusACLDSCP]
+		}
+
+		aclRules[acl.UUID] = aclRule
+	}
+
+	return aclRules, nil
+}
+
+// diffACLRules compares the existing ACL rules (keyed by UUID) with the wanted ones and returns the UUIDs of the
+// existing rules to remove along with the wanted rules that need adding. Identical rules are matched one for one
+// so that duplicates are handled correctly. As rule priorities are derived from the rule action rather than its
+// position, inserting a rule doesn't cause the other rules to be considered changed.
+func diffACLRules(existingRules map[string]OVNACLRule, newRules []OVNACLRule) ([]string, []OVNACLRule) {
+	existingUUIDs := make(map[OVNACLRule][]string, len(existingRules))
+	for aclUUID, rule := range existingRules {
+		existingUUIDs[rule] = append(existingUUIDs[rule], aclUUID)
+	}
+
+	addRules := []OVNACLRule{}
+	for _, rule := range newRules {
+		aclUUIDs := existingUUIDs[rule]
+		if len(aclUUIDs) > 0 {
+			existingUUIDs[rule] = aclUUIDs[1:]
+			continue
+		}
+
+		addRules = append(addRules, rule)
+	}
+
+	removeUUIDs := []string{}
+	for _, aclUUIDs := range existingUUIDs {
+		removeUUIDs = append(removeUUIDs, aclUUIDs...)
+	}
+
+	slices.Sort(removeUUIDs)
+
+	return removeUUIDs, addRules
+}
+
+// GetPortGroupACLRules returns the ACL rules currently applied to the specified port group.
+func (o *NB) GetPortGroupACLRules(ctx context.Context, portGroupName OVNPortGroup) ([]OVNACLRule, error) {
+	// Get the port group.
+	pg := ovnNB.PortGroup{
+		Name: string(portGroupName),
+	}
+
+	err := o.get(ctx, &pg)
+	if err != nil {
+		return nil, err
+	}
+
+	aclRules := make([]OVNACLRule, 0, len(pg.ACLs))
+	for _, aclUUID := range pg.ACLs {
+		acl := ovnNB.ACL{
+			UUID: aclUUID,
+		}
+
+		err := o.get(ctx, &acl)
+		if err != nil {
+			return nil, err
+		}
+
+		aclRule := OVNACLRule{
+			Direction: acl.Direction,
+			Action:    acl.Action,
+			Match:     acl.Match,
+			Priority:  acl.Priority,
+			Log:       acl.Log,
+		}
+
+		if acl.Name != nil {
+			aclRule.LogName = *acl.Name
+		}
+
+		if acl.Log && acl.Severity != nil {
+			aclRule.LogLevel = *acl.Severity
+		}
+
+		if acl.Log && acl.Meter != nil {
+			aclRule.LogMeter = *acl.Meter
+		}
+
+		if acl.ExternalIDs != nil {
+			aclRule.RuleID = acl.ExternalIDs[ovnExtIDIncusACLRule]
+			aclRule.DSCP = acl.ExternalIDs[ovnExtIDIncusACLDSCP]
+		}
+
+		aclRules = append(aclRules, aclRule)
+	}
+
+	return aclRules, nil
+}
+
+// GetPortGroupACLRuleIDs returns the Incus ACL rule identifiers of the OVN ACLs applied to a port group keyed by
+// OVN ACL UUID. OVN ACLs that weren't generated from an identified rule are not included.
+func (o *NB) GetPortGroupACLRuleIDs(ctx context.Context, portGroupName OVNPortGroup) (map[string]string, error) {
+	// Get the port group.
+	pg := ovnNB.PortGroup{
+		Name: string(portGroupName),
+	}
+
+	err := o.get(ctx, &pg)
+	if err != nil {
+		return nil, err
+	}
+
+	ruleIDs := make(map[string]string, len(pg.ACLs))
+	for _, aclUUID := range pg.ACLs {
+		acl := ovnNB.ACL{
+			UUID: aclUUID,
+		}
+
+		err := o.get(ctx, &acl)
+		if err != nil {
+			return nil, err
+		}
+
+		if acl.ExternalIDs == nil || acl.ExternalIDs[ovnExtIDIncusACLRule] == "" {
+			continue
+		}
+
+		ruleIDs[acl.UUID] = acl.ExternalIDs[ovnExtIDIncusACLRule]
+	}
+
+	return ruleIDs, nil
+}
+
+// namedUUIDReplacer replaces the characters of OVN entity names that aren't allowed in named UUIDs.
+var namedUUIDReplacer = strings.NewReplacer("-", "_", ".", "_")
+
 // aclRuleAddOperations returns the operations to add the provided ACL rules to the specified OVN entity.
 func (o *NB) aclRuleAddOperations(ctx context.Context, entityTable string, entityName string, externalIDs map[string]string, matchReplace map[string]string, aclRules ...OVNACLRule) ([]ovsdb.Operation, error) {
 	operations := []ovsdb.Operation{}
@@ -2748,9 +3203,10 @@ func (o *NB) aclRuleAddOperations(ctx context.Context, entityTable string, entit
 			rule.Match = strings.ReplaceAll(rule.Match, find, replace)
 		}
 
-		// Add new ACL.
+		// Add new ACL. The named UUID includes the entity so that rules for several entities can be added in
+		// the same transaction.
 		acl := ovnNB.ACL{
-			UUID:        fmt.Sprintf("acl%d", i),
+			UUID:        fmt.Sprintf("acl_%s_%d", namedUUIDReplacer.Replace(entityName), i),
 			Action:      rule.Action,
 			Direction:   rule.Direction,
 			Priority:    rule.Priority,
@@ -2765,12 +3221,30 @@ func (o *NB) aclRuleAddOperations(ctx context.Context, entityTable string, entit
 				logName := rule.LogName
 				acl.Name = &logName
 			}
+
+			if rule.LogLevel != "" {
+				logLevel := rule.LogLevel
+				acl.Severity = &logLevel
+			}
+
+			if rule.LogMeter != "" {
+				logMeter := rule.LogMeter
+				acl.Meter = &logMeter
+			}
 		}
 
 		for k, v := range externalIDs {
 			acl.ExternalIDs[k] = v
 		}
 
+		if rule.RuleID != "" {
+			acl.ExternalIDs[ovnExtIDIncusACLRule] = rule.RuleID
+		}
+
+		if rule.DSCP != "" {
+			acl.ExternalIDs[ovnExtIDIncusACLDSCP] = rule.DSCP
+		}
+
 		createOps, err := o.client.Create(&acl)
 		if err != nil {
 			return nil, err
@@ -3372,6 +3846,40 @@ func (o *NB) CreateAddressSet(ctx context.Context, addressSetPrefix OVNAddressSe
 // If the set is missing, it will get automatically created.
 // The address set name used is "<addressSetPrefix>_ip<IP version>", e.g. "foo_ip4".
 func (o *NB) UpdateAddressSetAdd(ctx context.Context, addressSetPrefix OVNAddressSet, addresses ...net.IPNet) error {
+	txn := o.NewTransaction()
+
+	err := txn.UpdateAddressSetAdd(ctx, addressSetPrefix, addresses...)
+	if err != nil {
+		return err
+	}
+
+	return txn.Commit(ctx)
+}
+
+// UpdateAddressSetAdd adds the supplied addresses to the address sets as part of the transaction, creating the
+// missing sets. As pending changes aren't visible in the cache, this is a no-op if the address sets were already
+// changed in the transaction.
+func (t *NBTransaction) UpdateAddressSetAdd(ctx context.Context, addressSetPrefix OVNAddressSet, addresses ...net.IPNet) error {
+	return t.updateAddressSet(ctx, addressSetPrefix, false, addresses...)
+}
+
+// UpdateAddressSetReplace replaces the addresses of the address sets with the supplied addresses as part of the
+// transaction, creating the missing sets. As pending changes aren't visible in the cache, this is a no-op if the
+// address sets were already changed in the transaction.
+func (t *NBTransaction) UpdateAddressSetReplace(ctx context.Context, addressSetPrefix OVNAddressSet, addresses ...net.IPNet) error {
+	return t.updateAddressSet(ctx, addressSetPrefix, true, addresses...)
+}
+
+// updateAddressSet adds the supplied addresses to the address sets, or replaces their addresses with them, as
+// part of the transaction. The missing sets are created.
+func (t *NBTransaction) updateAddressSet(ctx context.Context, addressSetPrefix OVNAddressSet, replace bool, addresses ...net.IPNet) error {
+	o := t.nb
+
+	_, changed := t.changedAddressSets[addressSetPrefix]
+	if changed {
+		return nil
+	}
+
 	// Get the address sets.
 	ipv4Set := ovnNB.AddressSet{
 		Name: fmt.Sprintf("%s_ip4", addressSetPrefix),
@@ -3382,10 +3890,6 @@ func (o *NB) UpdateAddressSetAdd(ctx context.Context, addressSetPrefix OVNAddres
 		return err
 	}
 
-	if ipv4Set.Addresses == nil {
-		ipv4Set.Addresses = []string{}
-	}
-
 	ipv6Set := ovnNB.AddressSet{
 		Name: fmt.Sprintf("%s_ip6", addressSetPrefix),
 	}
@@ -3395,7 +3899,11 @@ func (o *NB) UpdateAddressSetAdd(ctx context.Context, addressSetPrefix OVNAddres
 		return err
 	}
 
-	if ipv6Set.Addresses == nil {
+	if ipv4Set.Addresses == nil || replace {
+		ipv4Set.Addresses = []string{}
+	}
+
+	if ipv6Set.Addresses == nil || replace {
 		ipv6Set.Addresses = []string{}
 	}
 
@@ -3412,7 +3920,8 @@ func (o *NB) UpdateAddressSetAdd(ctx context.Context, addressSetPrefix OVNAddres
 		}
 	}
 
-	// Prepare the records.
+	// Prepare the records. The addresses are updated explicitly as replacing them with an empty list wouldn't
+	// be picked up otherwise.
 	operations := []ovsdb.Operation{}
 
 	if ipv4Set.UUID == "" {
@@ -3423,7 +3932,7 @@ func (o *NB) UpdateAddressSetAdd(ctx context.Context, addressSetPrefix OVNAddres
 
 		operations = append(operations, createOps...)
 	} else {
-		updateOps, err := o.client.Where(&ipv4Set).Update(&ipv4Set)
+		updateOps, err := o.client.Where(&ipv4Set).Update(&ipv4Set, &ipv4Set.Addresses)
 		if err != nil {
 			return err
 		}
@@ -3439,7 +3948,7 @@ func (o *NB) UpdateAddressSetAdd(ctx context.Context, addressSetPrefix OVNAddres
 
 		operations = append(operations, createOps...)
 	} else {
-		updateOps, err := o.client.Where(&ipv6Set).Update(&ipv6Set)
+		updateOps, err := o.client.Where(&ipv6Set).Update(&ipv6Set, &ipv6Set.Addresses)
 		if err != nil {
 			return err
 		}
@@ -3447,6 +3956,68 @@ func (o *NB) UpdateAddressSetAdd(ctx context.Context, addressSetPrefix OVNAddres
 		operations = append(operations, updateOps...)
 	}
 
+	t.operations = append(t.operations, operations...)
+	t.changedAddressSets[addressSetPrefix] = struct{}{}
+
+	return nil
+}
+
+// UpdateAddressSetReplace replaces the addresses of the existing address sets with the supplied addresses.
+// Returns ErrNotFound if the address sets don't exist.
+// The address set name used is "<addressSetPrefix>_ip<IP version>", e.g. "foo_ip4".
+func (o *NB) UpdateAddressSetReplace(ctx context.Context, addressSetPrefix OVNAddressSet, addresses ...net.IPNet) error {
+	// Get the address sets.
+	ipv4Set := ovnNB.AddressSet{
+		Name: fmt.Sprintf("%s_ip4", addressSetPrefix),
+	}
+
+	err := o.get(ctx, &ipv4Set)
+	if err != nil {
+		return err
+	}
+
+	ipv6Set := ovnNB.AddressSet{
+		Name: fmt.Sprintf("%s_ip6", addressSetPrefix),
+	}
+
+	err = o.get(ctx, &ipv6Set)
+	if err != nil {
+		return err
+	}
+
+	// Replace the addresses.
+	ipv4Set.Addresses = []string{}
+	ipv6Set.Addresses = []string{}
+	for _, address := range addresses {
+		if address.IP.To4() == nil {
+			if !slices.Contains(ipv6Set.Addresses, address.String()) {
+				ipv6Set.Addresses = append(ipv6Set.Addresses, address.String())
+			}
+		} else {
+			if !slices.Contains(ipv4Set.Addresses, address.String()) {
+				ipv4Set.Addresses = append(ipv4Set.Addresses, address.String())
+			}
+		}
+	}
+
+	// Prepare the records. The addresses are updated explicitly as replacing them with an empty list wouldn't
+	// be picked up otherwise.
+	operations := []ovsdb.Operation{}
+
+	updateOps, err := o.client.Where(&ipv4Set).Update(&ipv4Set, &ipv4Set.Addresses)
+	if err != nil {
+		return err
+	}
+
+	operations = append(operations, updateOps...)
+
+	updateOps, err = o.client.Where(&ipv6Set).Update(&ipv6Set, &ipv6Set.Addresses)
+	if err != nil {
+		return err
+	}
+
+	operations = append(operations, updateOps...)
+
 	// Apply the changes.
 	resp, err := o.client.Transact(ctx, operations...)
 	if err != nil {
@@ -3549,6 +4120,168 @@ func (o *NB) UpdateAddressSetRemove(ctx context.Context, addressSetPrefix OVNAdd
 	return nil
 }
 
+// UpdateMeter creates the meter if needed and sets it to drop packets above the specified rate (in packets per
+// second). This is used to rate limit the log messages of ACL rules.
+func (o *NB) UpdateMeter(ctx context.Context, meterName OVNMeter, rate int) error {
+	txn := o.NewTransaction()
+
+	err := txn.UpdateMeter(ctx, meterName, rate)
+	if err != nil {
+		return err
+	}
+
+	return txn.Commit(ctx)
+}
+
+// UpdateMeter creates the meter if needed and sets it to drop packets above the specified rate (in packets per
+// second) as part of the transaction. As pending changes aren't visible in the cache, this is a no-op if the
+// meter was already changed in the transaction.
+func (t *NBTransaction) UpdateMeter(ctx context.Context, meterName OVNMeter, rate int) error {
+	o := t.nb
+
+	_, changed := t.changedMeters[meterName]
+	if changed {
+		return nil
+	}
+
+	meter := ovnNB.Meter{
+		Name: string(meterName),
+	}
+
+	err := o.get(ctx, &meter)
+	if err != nil && err != ErrNotFound {
+		return err
+	}
+
+	// Check if the existing meter is already using the wanted rate.
+	if len(meter.Bands) == 1 {
+		band := ovnNB.MeterBand{
+			UUID: meter.Bands[0],
+		}
+
+		err = o.get(ctx, &band)
+		if err != nil && err != ErrNotFound {
+			return err
+		}
+
+		if err == nil && band.Rate == rate {
+			return nil
+		}
+	}
+
+	// Create the new band, named after the meter so that several meters can be updated in the same transaction.
+	operations := []ovsdb.Operation{}
+
+	band := ovnNB.MeterBand{
+		UUID:   fmt.Sprintf("meter_band_%s", namedUUIDReplacer.Replace(string(meterName))),
+		Action: ovnNB.MeterBandActionDrop,
+		Rate:   rate,
+	}
+
+	createOps, err := o.client.Create(&band)
+	if err != nil {
+		return err
+	}
+
+	operations = append(operations, createOps...)
+
+	// Create or update the meter. Replaced bands are garbage collected.
+	meter.Bands = []string{band.UUID}
+	if meter.UUID == "" {
+		meter.Unit = ovnNB.MeterUnitPktps
+
+		createOps, err := o.client.Create(&meter)
+		if err != nil {
+			return err
+		}
+
+		operations = append(operations, createOps...)
+	} else {
+		updateOps, err := o.client.Where(&meter).Update(&meter, &meter.Bands)
+		if err != nil {
+			return err
+		}
+
+		operations = append(operations, updateOps...)
+	}
+
+	t.operations = append(t.operations, operations...)
+	t.changedMeters[meterName] = struct{}{}
+
+	return nil
+}
+
+// DeleteMeter deletes the meter if it exists.
+func (o *NB) DeleteMeter(ctx context.Context, meterName OVNMeter) error {
+	txn := o.NewTransaction()
+
+	err := txn.DeleteMeter(ctx, meterName)
+	if err != nil {
+		return err
+	}
+
+	return txn.Commit(ctx)
+}
+
+// DeleteMeter adds the deletion of the meter to the transaction if it exists. This is a no-op if the meter was
+// already changed in the transaction.
+func (t *NBTransaction) DeleteMeter(ctx context.Context, meterName OVNMeter) error {
+	o := t.nb
+
+	_, changed := t.changedMeters[meterName]
+	if changed {
+		return nil
+	}
+
+	meter := ovnNB.Meter{
+		Name: string(meterName),
+	}
+
+	err := o.get(ctx, &meter)
+	if err != nil {
+		if err == ErrNotFound {
+			return nil
+		}
+
+		return err
+	}
+
+	deleteOps, err := o.client.Where(&meter).Delete()
+	if err != nil {
+		return err
+	}
+
+	t.operations = append(t.operations, deleteOps...)
+	t.changedMeters[meterName] = struct{}{}
+
+	return nil
+}
+
+// GetAddressSetsByPrefix returns the prefixes of the address sets whose name starts with the prefix.
+// The address sets of both IP versions are reported under their shared "<addressSetPrefix>".
+func (o *NB) GetAddressSetsByPrefix(ctx context.Context, prefix string) ([]OVNAddressSet, error) {
+	addressSets := []ovnNB.AddressSet{}
+
+	err := o.client.WhereCache(func(as *ovnNB.AddressSet) bool {
+		return strings.HasPrefix(as.Name, prefix)
+	}).List(ctx, &addressSets)
+	if err != nil {
+		return nil, err
+	}
+
+	addressSetPrefixes := make([]OVNAddressSet, 0, len(addressSets))
+	for _, addressSet := range addressSets {
+		name := strings.TrimSuffix(strings.TrimSuffix(addressSet.Name, "_ip4"), "_ip6")
+		if !slices.Contains(addressSetPrefixes, OVNAddressSet(name)) {
+			addressSetPrefixes = append(addressSetPrefixes, OVNAddressSet(name))
+		}
+	}
+
+	slices.Sort(addressSetPrefixes)
+
+	return addressSetPrefixes, nil
+}
+
 // DeleteAddressSet deletes address sets for IP versions 4 and 6 in the format "<addressSetPrefix>_ip<IP version>".
 func (o *NB) DeleteAddressSet(ctx context.Context, addressSetPrefix OVNAddressSet) error {
 	// Get the address sets.
@@ -0,0 +1,295 @@
+package ovn
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lxc/incus/v6/internal/server/network/ovn/ovntest"
+	ovnNB "github.com/lxc/incus/v6/internal/server/network/ovn/schema/ovn-nb"
+)
+
+func TestNBTransactionCreatePortGroupTwice(t *testing.T) {
+	client, err := ConnectNB(ovntest.NewNBServer(t), "", "", "")
+	require.NoError(t, err)
+
+	// The second creation can't see the first one in the cache, but doesn't add a conflicting row.
+	txn := client.NewTransaction()
+	require.NoError(t, txn.CreatePortGroup(context.Background(), 1, "incus_acl1", "", ""))
+	require.NoError(t, txn.CreatePortGroup(context.Background(), 1, "incus_acl1", "", ""))
+	require.NoError(t, txn.UpdatePortGroupACLRules(context.Background(), "incus_acl1", nil, OVNACLRule{Direction: "to-lport", Action: "drop", Priority: 0, Match: "outport == @incus_acl1"}))
+	require.NoError(t, txn.Commit(context.Background()))
+
+	portGroupUUID, hasACLs, err := client.GetPortGroupInfo(context.Background(), "incus_acl1")
+	require.NoError(t, err)
+	assert.NotEmpty(t, portGroupUUID)
+	assert.True(t, hasACLs)
+}
+
+func TestNBTransactionMeters(t *testing.T) {
+	client, err := ConnectNB(ovntest.NewNBServer(t), "", "", "")
+	require.NoError(t, err)
+
+	// Several meters can be created in the same transaction.
+	txn := client.NewTransaction()
+	require.NoError(t, txn.UpdateMeter(context.Background(), "incus_acl1-ingress-log", 10))
+	require.NoError(t, txn.UpdateMeter(context.Background(), "incus_acl1-egress-log", 20))
+	require.NoError(t, txn.UpdateMeter(context.Background(), "incus_acl1-egress-log", 20))
+	require.NoError(t, txn.Commit(context.Background()))
+
+	meters := []ovnNB.Meter{}
+	require.NoError(t, client.client.List(context.Background(), &meters))
+	assert.Len(t, meters, 2)
+
+	// Deleting a meter isn't applied until the transaction is committed.
+	txn = client.NewTransaction()
+	require.NoError(t, txn.DeleteMeter(context.Background(), "incus_acl1-ingress-log"))
+
+	meters = []ovnNB.Meter{}
+	require.NoError(t, client.client.List(context.Background(), &meters))
+	assert.Len(t, meters, 2)
+
+	require.NoError(t, txn.Commit(context.Background()))
+
+	meters = []ovnNB.Meter{}
+	require.NoError(t, client.client.List(context.Background(), &meters))
+	assert.Len(t, meters, 1)
+}
+
+func TestNBTransactionUpdateAddressSetAddTwice(t *testing.T) {
+	client, err := ConnectNB(ovntest.NewNBServer(t), "", "", "")
+	require.NoError(t, err)
+
+	addresses := []net.IPNet{{IP: net.ParseIP("192.0.2.1").To4(), Mask: net.CIDRMask(32, 32)}}
+
+	// The second addition can't see the sets created by the first one, but doesn't add conflicting rows.
+	txn := client.NewTransaction()
+	require.NoError(t, txn.UpdateAddressSetAdd(context.Background(), "incus_acl_fqdn_1", addresses...))
+	require.NoError(t, txn.UpdateAddressSetAdd(context.Background(), "incus_acl_fqdn_1", addresses...))
+	require.NoError(t, txn.Commit(context.Background()))
+
+	addressSets := []ovnNB.AddressSet{}
+	require.NoError(t, client.client.List(context.Background(), &addressSets))
+	assert.Len(t, addressSets, 2)
+}
+
+func TestNBTransactionUpdateAddressSetReplace(t *testing.T) {
+	client, err := ConnectNB(ovntest.NewNBServer(t), "", "", "")
+	require.NoError(t, err)
+
+	oldAddress := net.IPNet{IP: net.ParseIP("192.0.2.1").To4(), Mask: net.CIDRMask(32, 32)}
+	newAddress := net.IPNet{IP: net.ParseIP("2001:db8::1"), Mask: net.CIDRMask(128, 128)}
+
+	// The missing sets are created.
+	txn := client.NewTransaction()
+	require.NoError(t, txn.UpdateAddressSetReplace(context.Background(), "incus_acl_fqdn_1", oldAddress))
+	require.NoError(t, txn.Commit(context.Background()))
+
+	// The addresses of existing sets are replaced.
+	txn = client.NewTransaction()
+	require.NoError(t, txn.UpdateAddressSetReplace(context.Background(), "incus_acl_fqdn_1", newAddress))
+	require.NoError(t, txn.Commit(context.Background()))
+
+	ipv4Set := ovnNB.AddressSet{Name: "incus_acl_fqdn_1_ip4"}
+	require.NoError(t, client.get(context.Background(), &ipv4Set))
+	assert.Empty(t, ipv4Set.Addresses)
+
+	ipv6Set := ovnNB.AddressSet{Name: "incus_acl_fqdn_1_ip6"}
+	require.NoError(t, client.get(context.Background(), &ipv6Set))
+	assert.Equal(t, []string{"2001:db8::1/128"}, ipv6Set.Addresses)
+
+	// Adding no addresses only creates the missing sets.
+	txn = client.NewTransaction()
+	require.NoError(t, txn.UpdateAddressSetAdd(context.Background(), "incus_acl_fqdn_1"))
+	require.NoError(t, txn.Commit(context.Background()))
+
+	ipv6Set = ovnNB.AddressSet{Name: "incus_acl_fqdn_1_ip6"}
+	require.NoError(t, client.get(context.Background(), &ipv6Set))
+	assert.Equal(t, []string{"2001:db8::1/128"}, ipv6Set.Addresses)
+
+	// Both sets are listed under their shared prefix.
+	require.NoError(t, client.CreateAddressSet(context.Background(), "incus_other"))
+	addressSets, err := client.GetAddressSetsByPrefix(context.Background(), "incus_acl_fqdn_")
+	require.NoError(t, err)
+	assert.Equal(t, []OVNAddressSet{"incus_acl_fqdn_1"}, addressSets)
+}
+
+func TestDiffACLRules(t *testing.T) {
+	http := OVNACLRule{Direction: "to-lport", Action: "allow", Priority: 300, Match: "tcp.dst == 80"}
+	https := OVNACLRule{Direction: "to-lport", Action: "allow", Priority: 300, Match: "tcp.dst == 443"}
+	ssh := OVNACLRule{Direction: "to-lport", Action: "drop", Priority: 500, Match: "tcp.dst == 22"}
+	sshLogged := OVNACLRule{Direction: "to-lport", Action: "drop", Priority: 500, Match: "tcp.dst == 22", Log: true, LogName: "incus_acl1-ingress-2"}
+
+	tests := []struct {
+		name     string
+		existing map[string]OVNACLRule
+		rules    []OVNACLRule
+		remove   []string
+		add      []OVNACLRule
+	}{
+		{
+			name:     "unchanged",
+			existing: map[string]OVNACLRule{"a": http, "b": ssh},
+			rules:    []OVNACLRule{ssh, http},
+			remove:   []string{},
+			add:      []OVNACLRule{},
+		},
+		{
+			name:     "added",
+			existing: map[string]OVNACLRule{"a": http},
+			rules:    []OVNACLRule{https, http},
+			remove:   []string{},
+			add:      []OVNACLRule{https},
+		},
+		{
+			name:     "removed",
+			existing: map[string]OVNACLRule{"a": http, "b": https, "c": ssh},
+			rules:    []OVNACLRule{http},
+			remove:   []string{"b", "c"},
+			add:      []OVNACLRule{},
+		},
+		{
+			name:     "changed",
+			existing: map[string]OVNACLRule{"a": http, "b": ssh},
+			rules:    []OVNACLRule{http, sshLogged},
+			remove:   []string{"b"},
+			add:      []OVNACLRule{sshLogged},
+		},
+		{
+			name:     "duplicates",
+			existing: map[string]OVNACLRule{"a": http, "b": http},
+			rules:    []OVNACLRule{http, http, http},
+			remove:   []string{},
+			add:      []OVNACLRule{http},
+		},
+		{
+			name:     "empty",
+			existing: map[string]OVNACLRule{},
+			rules:    []OVNACLRule{http},
+			remove:   []string{},
+			add:      []OVNACLRule{http},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			remove, add := diffACLRules(tc.existing, tc.rules)
+			assert.Equal(t, tc.remove, remove)
+			assert.Equal(t, tc.add, add)
+		})
+	}
+}
+
+func TestGetACLRules(t *testing.T) {
+	http := OVNACLRule{Direction: "to-lport", Action: "allow", Priority: 300, Match: "outport == @incus_acl1 && tcp.dst == 80", RuleID: "rule1"}
+	https := OVNACLRule{Direction: "to-lport", Action: "allow", Priority: 300, Match: "outport == @incus_acl1 && tcp.dst == 443", RuleID: "rule2"}
+	ssh := OVNACLRule{Direction: "to-lport", Action: "drop", Priority: 500, Match: "outport == @incus_acl1 && tcp.dst == 22", RuleID: "rule3"}
+	sshLogged := OVNACLRule{Direction: "to-lport", Action: "drop", Priority: 500, Match: "outport == @incus_acl1 && tcp.dst == 22", RuleID: "rule3", Log: true, LogName: "incus_acl1-ingress-2", LogLevel: "info"}
+
+	tests := []struct {
+		name  string
+		rules []OVNACLRule
+		kept  []OVNACLRule
+	}{
+		{name: "initial", rules: []OVNACLRule{http, ssh}},
+		{name: "added", rules: []OVNACLRule{http, https, ssh}, kept: []OVNACLRule{http, ssh}},
+		{name: "removed", rules: []OVNACLRule{https, ssh}, kept: []OVNACLRule{https, ssh}},
+		{name: "changed", rules: []OVNACLRule{https, sshLogged}, kept: []OVNACLRule{https}},
+	}
+
+	client, err := ConnectNB(ovntest.NewNBServer(t), "", "", "")
+	require.NoError(t, err)
+
+	require.NoError(t, client.CreatePortGroup(context.Background(), 1, "incus_acl1", "", ""))
+
+	// getPortGroupRules returns the rules of the port group keyed by UUID.
+	getPortGroupRules := func(t *testing.T) map[string]OVNACLRule {
+		pg := ovnNB.PortGroup{Name: "incus_acl1"}
+		require.NoError(t, client.get(context.Background(), &pg))
+
+		rules, err := client.getACLRules(context.Background(), pg.ACLs)
+		require.NoError(t, err)
+
+		return rules
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			previous := getPortGroupRules(t)
+
+			require.NoError(t, client.UpdatePortGroupACLRules(context.Background(), "incus_acl1", nil, tc.rules...))
+
+			current := getPortGroupRules(t)
+			currentRules := make([]OVNACLRule, 0, len(current))
+			for _, rule := range current {
+				currentRules = append(currentRules, rule)
+			}
+
+			assert.ElementsMatch(t, tc.rules, currentRules)
+
+			// The rules that didn't change keep their OVN rows.
+			for _, rule := range tc.kept {
+				for aclUUID, previousRule := range previous {
+					if previousRule == rule {
+						assert.Equal(t, rule, current[aclUUID], aclUUID)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestNBTransactionAtomic(t *testing.T) {
+	client, err := ConnectNB(ovntest.NewNBServer(t), "", "", "")
+	require.NoError(t, err)
+
+	// addChanges adds the creation of two port groups with their rules and log meter to the transaction.
+	addChanges := func(txn *NBTransaction) {
+		for _, portGroupName := range []OVNPortGroup{"incus_acl1", "incus_acl2"} {
+			require.NoError(t, txn.CreatePortGroup(context.Background(), 1, portGroupName, "", ""))
+			require.NoError(t, txn.UpdatePortGroupACLRules(context.Background(), portGroupName, nil, OVNACLRule{Direction: "to-lport", Action: "drop", Priority: 0, Match: "outport == @" + string(portGroupName)}))
+			require.NoError(t, txn.UpdateMeter(context.Background(), OVNMeter(portGroupName+"-ingress-log"), 10))
+		}
+	}
+
+	// The changes aren't applied until the transaction is committed.
+	txn := client.NewTransaction()
+	addChanges(txn)
+
+	portGroupUUID, _, err := client.GetPortGroupInfo(context.Background(), "incus_acl1")
+	require.NoError(t, err)
+	assert.Empty(t, portGroupUUID)
+
+	// When one of the changes fails, none of them are applied.
+	require.NoError(t, client.CreatePortGroup(context.Background(), 1, "incus_acl2", "", ""))
+	require.Error(t, txn.Commit(context.Background()))
+
+	portGroupUUID, _, err = client.GetPortGroupInfo(context.Background(), "incus_acl1")
+	require.NoError(t, err)
+	assert.Empty(t, portGroupUUID)
+
+	meters := []ovnNB.Meter{}
+	require.NoError(t, client.client.List(context.Background(), &meters))
+	assert.Empty(t, meters)
+
+	// Once the conflicting port group is gone, all the changes are applied together.
+	require.NoError(t, client.DeletePortGroup(context.Background(), "incus_acl2"))
+
+	txn = client.NewTransaction()
+	addChanges(txn)
+	require.NoError(t, txn.Commit(context.Background()))
+
+	for _, portGroupName := range []OVNPortGroup{"incus_acl1", "incus_acl2"} {
+		portGroupUUID, hasACLs, err := client.GetPortGroupInfo(context.Background(), portGroupName)
+		require.NoError(t, err)
+		assert.NotEmpty(t, portGroupUUID)
+		assert.True(t, hasACLs)
+	}
+
+	meters = []ovnNB.Meter{}
+	require.NoError(t, client.client.List(context.Background(), &meters))
+	assert.Len(t, meters, 2)
+}
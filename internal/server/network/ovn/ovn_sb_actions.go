@@ -3,6 +3,7 @@ package ovn
 import (
 	"context"
 	"fmt"
+	"strconv"
 
 	ovnSB "github.com/lxc/incus/v6/internal/server/network/ovn/schema/ovn-sb"
 )
@@ -53,3 +54,47 @@ func (o *SB) GetServiceHealth(ctx context.Context, address string, protocol stri
 
 	return *services[0].Status, nil
 }
+
+// GetACLLogicalFlowCookies returns the OpenFlow cookies of the logical flows generated from the specified northbound
+// ACLs keyed by ACL UUID. The flows are matched using their stage hint and the cookies are the first 32 bits of the
+// logical flow UUIDs, which is what ovn-controller uses when installing the flows.
+func (o *SB) GetACLLogicalFlowCookies(ctx context.Context, aclUUIDs []string) (map[string][]uint64, error) {
+	aclHints := make(map[string]string, len(aclUUIDs))
+	for _, aclUUID := range aclUUIDs {
+		if len(aclUUID) < 8 {
+			continue
+		}
+
+		aclHints[aclUUID[0:8]] = aclUUID
+	}
+
+	flows := []ovnSB.LogicalFlow{}
+	err := o.client.WhereCache(func(flow *ovnSB.LogicalFlow) bool {
+		if flow.ExternalIDs == nil {
+			return false
+		}
+
+		_, found := aclHints[flow.ExternalIDs["stage-hint"]]
+		return found
+	}).List(ctx, &flows)
+	if err != nil {
+		return nil, err
+	}
+
+	cookies := make(map[string][]uint64, len(aclUUIDs))
+	for _, flow := range flows {
+		if len(flow.UUID) < 8 {
+			continue
+		}
+
+		cookie, err := strconv.ParseUint(flow.UUID[0:8], 16, 64)
+		if err != nil {
+			continue
+		}
+
+		aclUUID := aclHints[flow.ExternalIDs["stage-hint"]]
+		cookies[aclUUID] = append(cookies[aclUUID], cookie)
+	}
+
+	return cookies, nil
+}
@@ -0,0 +1,39 @@
+// Package ovntest provides in-memory OVN databases for tests.
+package ovntest
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ovn-org/libovsdb/database/inmemory"
+	ovsdbModel "github.com/ovn-org/libovsdb/model"
+	ovsdbServer "github.com/ovn-org/libovsdb/server"
+	"github.com/stretchr/testify/require"
+
+	ovnNB "github.com/lxc/incus/v6/internal/server/network/ovn/schema/ovn-nb"
+)
+
+// NewNBServer starts an empty in-memory northbound database, which is stopped when the test ends, and returns
+// its address.
+func NewNBServer(t testing.TB) string {
+	dbModel, err := ovnNB.FullDatabaseModel()
+	require.NoError(t, err)
+
+	serverModel, errs := ovsdbModel.NewDatabaseModel(ovnNB.Schema(), dbModel)
+	require.Empty(t, errs)
+
+	server, err := ovsdbServer.NewOvsdbServer(inmemory.NewDatabase(map[string]ovsdbModel.ClientDBModel{dbModel.Name(): dbModel}), serverModel)
+	require.NoError(t, err)
+
+	socketPath := filepath.Join(t.TempDir(), "ovnnb.sock")
+
+	go func() {
+		_ = server.Serve("unix", socketPath)
+	}()
+
+	require.Eventually(t, server.Ready, 5*time.Second, 10*time.Millisecond)
+	t.Cleanup(server.Close)
+
+	return "unix:" + socketPath
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net"
 	"slices"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -15,6 +16,7 @@ import (
 
 	"github.com/lxc/incus/v6/internal/server/ip"
 	ovsSwitch "github.com/lxc/incus/v6/internal/server/network/ovs/schema/ovs"
+	"github.com/lxc/incus/v6/shared/subprocess"
 	"github.com/lxc/incus/v6/shared/util"
 )
 
@@ -729,3 +731,51 @@ func (o *VSwitch) GetOVNSouthboundDBRemoteAddress(ctx context.Context) (string,
 
 	return val, nil
 }
+
+// FlowStats represents the packet and byte counters of a set of OpenFlow flows.
+type FlowStats struct {
+	Packets uint64
+	Bytes   uint64
+}
+
+// GetBridgeFlowStats returns the counters of the OpenFlow flows installed on a bridge, summed up by flow cookie.
+func (o *VSwitch) GetBridgeFlowStats(ctx context.Context, bridgeName string) (map[uint64]FlowStats, error) {
+	output, err := subprocess.RunCommandContext(ctx, "ovs-ofctl", "dump-flows", bridgeName)
+	if err != nil {
+		return nil, fmt.Errorf("Failed getting flows of bridge %q: %w", bridgeName, err)
+	}
+
+	stats := map[uint64]FlowStats{}
+	for _, line := range strings.Split(output, "\n") {
+		var cookie, packets, bytes uint64
+		var hasCookie bool
+
+		for _, field := range strings.Fields(line) {
+			key, value, found := strings.Cut(strings.TrimSuffix(field, ","), "=")
+			if !found {
+				continue
+			}
+
+			switch key {
+			case "cookie":
+				cookie, err = strconv.ParseUint(strings.TrimPrefix(value, "0x"), 16, 64)
+				hasCookie = err == nil
+			case "n_packets":
+				packets, _ = strconv.ParseUint(value, 10, 64)
+			case "n_bytes":
+				bytes, _ = strconv.ParseUint(value, 10, 64)
+			}
+		}
+
+		if !hasCookie {
+			continue
+		}
+
+		flowStats := stats[cookie]
+		flowStats.Packets += packets
+		flowStats.Bytes += bytes
+		stats[cookie] = flowStats
+	}
+
+	return stats, nil
+}
@@ -110,5 +110,28 @@ func GetCurrentAllocations(ctx context.Context, tx *db.ClusterTx, projectName st
 		Usage: int64(len(networks[projectName])),
 	}
 
+	// Get the network ACL limits and usage.
+	aclCount, ruleCount, err := tx.GetNetworkACLsUsage(ctx, projectName, -1)
+	if err != nil {
+		return nil, err
+	}
+
+	for key, usage := range map[string]int{"network-acls": aclCount, "network-acl-rules": ruleCount} {
+		limit = -1
+
+		value, ok := info.Project.Config[fmt.Sprintf("limits.%s", strings.ReplaceAll(key, "-", "_"))]
+		if ok {
+			limit, err = strconv.Atoi(value)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		result[key] = api.ProjectStateResource{
+			Limit: int64(limit),
+			Usage: int64(usage),
+		}
+	}
+
 	return result, nil
 }
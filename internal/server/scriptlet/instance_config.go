@@ -0,0 +1,122 @@
+package scriptlet
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"go.starlark.net/starlark"
+
+	scriptletLoad "github.com/lxc/incus/v6/internal/server/scriptlet/load"
+	"github.com/lxc/incus/v6/shared/logger"
+)
+
+// InstanceConfigRun runs the instance config scriptlet and returns the config overrides it requested.
+func InstanceConfigRun(ctx context.Context, l logger.Logger, projectName string, instanceName string, instanceType string, expandedConfig map[string]string) (map[string]string, error) {
+	logFunc := createLogger(l, "Instance config scriptlet")
+
+	// Remember to match the entries in scriptletLoad.InstanceConfigCompile() with this list so Starlark can
+	// perform compile time validation of functions used.
+	env := starlark.StringDict{
+		"log_info":  starlark.NewBuiltin("log_info", logFunc),
+		"log_warn":  starlark.NewBuiltin("log_warn", logFunc),
+		"log_error": starlark.NewBuiltin("log_error", logFunc),
+	}
+
+	for name, builtin := range networkBuiltins() {
+		env[name] = builtin
+	}
+
+	for name, builtin := range stringBuiltins() {
+		env[name] = builtin
+	}
+
+	for name, builtin := range moduleBuiltins() {
+		env[name] = builtin
+	}
+
+	prog, thread, err := scriptletLoad.InstanceConfigProgram()
+	if err != nil {
+		return nil, err
+	}
+
+	go func() {
+		<-ctx.Done()
+		thread.Cancel("Request finished")
+	}()
+
+	globals, err := prog.Init(thread, env)
+	if err != nil {
+		return nil, fmt.Errorf("Failed initializing: %w", err)
+	}
+
+	globals.Freeze()
+
+	// Retrieve a global variable from starlark environment.
+	instanceConfig := globals["instance_config"]
+	if instanceConfig == nil {
+		return nil, fmt.Errorf("Scriptlet missing instance_config function")
+	}
+
+	// The config is left mutable so that scriptlets can adjust it and return the keys they changed.
+	configv, err := StarlarkMarshalWithOpts(expandedConfig, StarlarkMarshalOpts{Mutable: true})
+	if err != nil {
+		return nil, fmt.Errorf("Marshalling config failed: %w", err)
+	}
+
+	// Call starlark function from Go.
+	v, err := starlark.Call(thread, instanceConfig, nil, []starlark.Tuple{
+		{
+			starlark.String("project"),
+			starlark.String(projectName),
+		}, {
+			starlark.String("name"),
+			starlark.String(instanceName),
+		}, {
+			starlark.String("type"),
+			starlark.String(instanceType),
+		}, {
+			starlark.String("config"),
+			configv,
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to run: %w", err)
+	}
+
+	return instanceConfigOverrides(v)
+}
+
+// instanceConfigOverrides converts the value returned by the instance config scriptlet into config overrides.
+// Only string values for limits.* keys can be returned, returning None means no overrides.
+func instanceConfigOverrides(v starlark.Value) (map[string]string, error) {
+	if v == starlark.None {
+		return nil, nil
+	}
+
+	_, ok := v.(*starlark.Dict)
+	if !ok {
+		return nil, fmt.Errorf("Failed with unexpected return value: %v", v)
+	}
+
+	value, err := StarlarkUnmarshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("Unmarshalling return value failed: %w", err)
+	}
+
+	overrides := make(map[string]string)
+	for key, keyValue := range value.(map[string]any) {
+		if !strings.HasPrefix(key, "limits.") {
+			return nil, fmt.Errorf("Config key %q can't be overridden", key)
+		}
+
+		s, ok := keyValue.(string)
+		if !ok {
+			return nil, fmt.Errorf("Value of config key %q must be a string", key)
+		}
+
+		overrides[key] = s
+	}
+
+	return overrides, nil
+}
@@ -0,0 +1,73 @@
+package scriptlet
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	scriptletLoad "github.com/lxc/incus/v6/internal/server/scriptlet/load"
+	"github.com/lxc/incus/v6/shared/logger"
+)
+
+// runInstanceConfig loads the instance config scriptlet and runs it against the given config.
+func runInstanceConfig(t *testing.T, src string, config map[string]string) (map[string]string, error) {
+	t.Helper()
+
+	require.NoError(t, scriptletLoad.InstanceConfigSet(src))
+	t.Cleanup(func() { _ = scriptletLoad.InstanceConfigSet("") })
+
+	return InstanceConfigRun(context.Background(), logger.Log, "default", "c1", "container", config)
+}
+
+func TestInstanceConfigRun(t *testing.T) {
+	src := `
+def instance_config(project, name, type, config):
+    if config.get("limits.cpu", "") == "" or int(config["limits.cpu"]) > 4:
+        return {"limits.cpu": "4"}
+`
+
+	overrides, err := runInstanceConfig(t, src, map[string]string{"limits.cpu": "16", "limits.memory": "1GiB"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"limits.cpu": "4"}, overrides)
+
+	overrides, err = runInstanceConfig(t, src, map[string]string{})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"limits.cpu": "4"}, overrides)
+
+	overrides, err = runInstanceConfig(t, src, map[string]string{"limits.cpu": "2"})
+	assert.NoError(t, err)
+	assert.Nil(t, overrides)
+
+	// The config can be adjusted before returning the changed keys.
+	src = `
+def instance_config(project, name, type, config):
+    config["limits.memory"] = "2GiB"
+    return {"limits.memory": config["limits.memory"]}
+`
+
+	overrides, err = runInstanceConfig(t, src, map[string]string{"limits.memory": "1GiB"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"limits.memory": "2GiB"}, overrides)
+}
+
+func TestInstanceConfigRunErrors(t *testing.T) {
+	tests := map[string]string{
+		`def instance_config(project, name, type, config):
+    return "limits.cpu=4"`: `Failed with unexpected return value: "limits.cpu=4"`,
+		`def instance_config(project, name, type, config):
+    return {"limits.cpu": 4}`: `Value of config key "limits.cpu" must be a string`,
+		`def instance_config(project, name, type, config):
+    return {"security.privileged": "true"}`: `Config key "security.privileged" can't be overridden`,
+		`def instance_config(project, name, type, config):
+    fail("Too many CPUs")`: `Failed to run: fail: Too many CPUs`,
+		`def other(project, name, type, config):
+    return None`: `Scriptlet missing instance_config function`,
+	}
+
+	for src, expected := range tests {
+		_, err := runInstanceConfig(t, src, map[string]string{"limits.cpu": "16"})
+		assert.EqualError(t, err, expected, src)
+	}
+}
@@ -3,6 +3,7 @@ package scriptlet
 import (
 	"context"
 	"fmt"
+	"reflect"
 	"strconv"
 
 	"go.starlark.net/starlark"
@@ -12,6 +13,7 @@ import (
 	"github.com/lxc/incus/v6/internal/server/db"
 	dbCluster "github.com/lxc/incus/v6/internal/server/db/cluster"
 	"github.com/lxc/incus/v6/internal/server/instance/drivers/qemudefault"
+	"github.com/lxc/incus/v6/internal/server/project"
 	"github.com/lxc/incus/v6/internal/server/resources"
 	scriptletLoad "github.com/lxc/incus/v6/internal/server/scriptlet/load"
 	"github.com/lxc/incus/v6/internal/server/state"
@@ -281,7 +283,8 @@ func InstancePlacementRun(ctx context.Context, l logger.Logger, s *state.State,
 			return nil, err
 		}
 
-		rv, err := StarlarkMarshal(instanceList)
+		// Keep the status codes as integers, as in the API.
+		rv, err := StarlarkMarshalWithOpts(instanceList, StarlarkMarshalOpts{RawStringerTypes: []reflect.Type{reflect.TypeFor[api.StatusCode]()}})
 		if err != nil {
 			return nil, fmt.Errorf("Marshalling instance resources failed: %w", err)
 		}
@@ -412,6 +415,59 @@ func InstancePlacementRun(ctx context.Context, l logger.Logger, s *state.State,
 		return rv, nil
 	}
 
+	getNetworkACLsFunc := func(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+		var projectName string
+
+		err := starlark.UnpackArgs(b.Name(), args, kwargs, "project", &projectName)
+		if err != nil {
+			return nil, err
+		}
+
+		acls := []*api.NetworkACL{}
+
+		err = s.DB.Cluster.Transaction(ctx, func(ctx context.Context, tx *db.ClusterTx) error {
+			dbProject, err := dbCluster.GetProject(ctx, tx.Tx(), projectName)
+			if err != nil {
+				return fmt.Errorf("Failed loading project %q: %w", projectName, err)
+			}
+
+			p, err := dbProject.ToAPI(ctx, tx.Tx())
+			if err != nil {
+				return fmt.Errorf("Failed loading project %q: %w", projectName, err)
+			}
+
+			// ACLs belong to the project that holds the project's networks.
+			aclProjectName := project.NetworkProjectFromRecord(p)
+
+			aclNames, err := tx.GetNetworkACLs(ctx, aclProjectName)
+			if err != nil {
+				return fmt.Errorf("Failed loading network ACLs of project %q: %w", aclProjectName, err)
+			}
+
+			for _, aclName := range aclNames {
+				_, acl, err := tx.GetNetworkACL(ctx, aclProjectName, aclName)
+				if err != nil {
+					return fmt.Errorf("Failed loading network ACL %q of project %q: %w", aclName, aclProjectName, err)
+				}
+
+				acl.Project = aclProjectName
+				acls = append(acls, acl)
+			}
+
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		rv, err := StarlarkMarshal(acls)
+		if err != nil {
+			return nil, fmt.Errorf("Marshalling network ACLs failed: %w", err)
+		}
+
+		return rv, nil
+	}
+
 	var err error
 	var raftNodes []db.RaftNode
 	err = s.DB.Node.Transaction(ctx, func(ctx context.Context, tx *db.NodeTx) error {
@@ -480,6 +536,19 @@ func InstancePlacementRun(ctx context.Context, l logger.Logger, s *state.State,
 		"get_instances":                starlark.NewBuiltin("get_instances", getInstancesFunc),
 		"get_cluster_members":          starlark.NewBuiltin("get_cluster_members", getClusterMembersFunc),
 		"get_project":                  starlark.NewBuiltin("get_project", getProjectFunc),
+		"get_network_acls":             starlark.NewBuiltin("get_network_acls", getNetworkACLsFunc),
+	}
+
+	for name, builtin := range networkBuiltins() {
+		env[name] = builtin
+	}
+
+	for name, builtin := range stringBuiltins() {
+		env[name] = builtin
+	}
+
+	for name, builtin := range moduleBuiltins() {
+		env[name] = builtin
 	}
 
 	prog, thread, err := scriptletLoad.InstancePlacementProgram()
@@ -505,12 +574,14 @@ func InstancePlacementRun(ctx context.Context, l logger.Logger, s *state.State,
 		return nil, fmt.Errorf("Scriptlet missing instance_placement function")
 	}
 
-	rv, err := StarlarkMarshal(req)
+	// The request and candidate members are left mutable so that scriptlets can adjust them, for example before
+	// passing them to their own functions.
+	rv, err := StarlarkMarshalWithOpts(req, StarlarkMarshalOpts{Mutable: true})
 	if err != nil {
 		return nil, fmt.Errorf("Marshalling request failed: %w", err)
 	}
 
-	candidateMembersv, err := StarlarkMarshal(candidateMembersInfo)
+	candidateMembersv, err := StarlarkMarshalWithOpts(candidateMembersInfo, StarlarkMarshalOpts{Mutable: true})
 	if err != nil {
 		return nil, fmt.Errorf("Marshalling candidate members failed: %w", err)
 	}
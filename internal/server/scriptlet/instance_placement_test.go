@@ -0,0 +1,171 @@
+package scriptlet
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	clusterConfig "github.com/lxc/incus/v6/internal/server/cluster/config"
+	"github.com/lxc/incus/v6/internal/server/db"
+	dbCluster "github.com/lxc/incus/v6/internal/server/db/cluster"
+	scriptletLoad "github.com/lxc/incus/v6/internal/server/scriptlet/load"
+	"github.com/lxc/incus/v6/internal/server/state"
+	"github.com/lxc/incus/v6/shared/api"
+	apiScriptlet "github.com/lxc/incus/v6/shared/api/scriptlet"
+	"github.com/lxc/incus/v6/shared/logger"
+)
+
+// runInstancePlacement loads the instance placement scriptlet and runs it for the request with all the cluster
+// members as candidates.
+func runInstancePlacement(t *testing.T, s *state.State, src string, req *apiScriptlet.InstancePlacement) (*db.NodeInfo, error) {
+	t.Helper()
+
+	require.NoError(t, scriptletLoad.InstancePlacementSet(src))
+	t.Cleanup(func() { _ = scriptletLoad.InstancePlacementSet("") })
+
+	var members []db.NodeInfo
+	err := s.DB.Cluster.Transaction(context.Background(), func(ctx context.Context, tx *db.ClusterTx) error {
+		var err error
+
+		s.GlobalConfig, err = clusterConfig.Load(ctx, tx)
+		if err != nil {
+			return err
+		}
+
+		members, err = tx.GetNodes(ctx)
+
+		return err
+	})
+	require.NoError(t, err)
+
+	return InstancePlacementRun(context.Background(), logger.Log, s, req, members, "")
+}
+
+func TestInstancePlacementRunMutable(t *testing.T) {
+	s, cleanup := state.NewTestState(t)
+	defer cleanup()
+
+	req := &apiScriptlet.InstancePlacement{
+		InstancesPost: api.InstancesPost{
+			Name:        "c1",
+			InstancePut: api.InstancePut{Config: map[string]string{"limits.cpu": "4"}},
+		},
+		Reason:  apiScriptlet.InstancePlacementReasonNew,
+		Project: api.ProjectDefaultName,
+	}
+
+	// The request and candidate members can be changed by the scriptlet.
+	src := `
+def pick(request, members):
+    if request.name == "c2" and request.config["limits.cpu"] == "2":
+        return members[-1].server_name
+
+    return None
+
+def instance_placement(request, candidate_members):
+    request.name = "c2"
+    request.config["limits.cpu"] = "2"
+    candidate_members.append(candidate_members[0])
+    candidate_members[-1].server_name = candidate_members[0].server_name
+    set_target(pick(request, candidate_members))
+`
+
+	target, err := runInstancePlacement(t, s, src, req)
+	require.NoError(t, err)
+	require.NotNil(t, target)
+
+	// The request passed in isn't modified.
+	assert.Equal(t, "c1", req.Name)
+	assert.Equal(t, "4", req.Config["limits.cpu"])
+
+	// Only existing fields can be set.
+	src = `
+def instance_placement(request, candidate_members):
+    request.target_member = "other"
+`
+
+	_, err = runInstancePlacement(t, s, src, req)
+	assert.ErrorContains(t, err, `Invalid field "target_member", must be one of:`)
+}
+
+func TestInstancePlacementGetNetworkACLs(t *testing.T) {
+	s, cleanup := state.NewTestState(t)
+	defer cleanup()
+
+	err := s.DB.Cluster.Transaction(context.Background(), func(ctx context.Context, tx *db.ClusterTx) error {
+		_, err := dbCluster.CreateProject(ctx, tx.Tx(), dbCluster.Project{Name: "legacy"})
+		if err != nil {
+			return err
+		}
+
+		id, err := dbCluster.CreateProject(ctx, tx.Tx(), dbCluster.Project{Name: "own"})
+		if err != nil {
+			return err
+		}
+
+		err = dbCluster.CreateProjectConfig(ctx, tx.Tx(), id, map[string]string{"features.networks": "true"})
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.CreateNetworkACL(ctx, api.ProjectDefaultName, &api.NetworkACLsPost{
+			NetworkACLPost: api.NetworkACLPost{Name: "web"},
+			NetworkACLPut: api.NetworkACLPut{Ingress: []api.NetworkACLRule{
+				{Action: "allow", State: "enabled", Protocol: "tcp", DestinationPort: "80"},
+				{Action: "allow", State: "enabled", Protocol: "tcp", DestinationPort: "443"},
+			}},
+		})
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.CreateNetworkACL(ctx, "own", &api.NetworkACLsPost{NetworkACLPost: api.NetworkACLPost{Name: "db"}})
+
+		return err
+	})
+	require.NoError(t, err)
+
+	// The scriptlet only picks a member when the ACLs of the requested project are the expected ones.
+	src := `
+def instance_placement(request, candidate_members):
+    acls = get_network_acls(request.project)
+    found = ",".join(["%s/%s:%d" % (acl.project, acl.name, len(acl.ingress)) for acl in acls])
+    if found != request.config["user.acls"]:
+        fail("Unexpected network ACLs: " + found)
+
+    set_target(candidate_members[0].server_name)
+`
+
+	tests := map[string]string{
+		api.ProjectDefaultName: "default/web:2",
+		"legacy":               "default/web:2", // Projects without their own networks use the default project's ACLs.
+		"own":                  "own/db:0",
+	}
+
+	for projectName, expected := range tests {
+		req := &apiScriptlet.InstancePlacement{
+			InstancesPost: api.InstancesPost{
+				Name:        "c1",
+				InstancePut: api.InstancePut{Config: map[string]string{"user.acls": expected}},
+			},
+			Reason:  apiScriptlet.InstancePlacementReasonNew,
+			Project: projectName,
+		}
+
+		target, err := runInstancePlacement(t, s, src, req)
+		require.NoError(t, err, projectName)
+		assert.NotNil(t, target, projectName)
+	}
+
+	// Unknown projects are reported.
+	req := &apiScriptlet.InstancePlacement{
+		InstancesPost: api.InstancesPost{Name: "c1"},
+		Reason:        apiScriptlet.InstancePlacementReasonNew,
+		Project:       "missing",
+	}
+
+	_, err = runInstancePlacement(t, s, src, req)
+	assert.ErrorContains(t, err, `Failed loading project "missing"`)
+}
@@ -12,13 +12,36 @@ import (
 // nameInstancePlacement is the name used in Starlark for the instance placement scriptlet.
 const nameInstancePlacement = "instance_placement"
 
+// nameInstanceConfig is the name used in Starlark for the instance config scriptlet.
+const nameInstanceConfig = "instance_config"
+
+// nameNetworkACLRules is the name used in Starlark for the network ACL rules scriptlets.
+const nameNetworkACLRules = "network_acl_rules"
+
 // prefixQEMU is the prefix used in Starlark for the QEMU scriptlet.
 const prefixQEMU = "qemu"
 
+// networkBuiltins are the network helper functions available to all scriptlets.
+var networkBuiltins = []string{
+	"cidr_contains",
+	"cidr_overlaps",
+	"ip_family",
+}
+
+// stringBuiltins are the string helper functions available to all scriptlets.
+var stringBuiltins = []string{
+	"render",
+}
+
+// moduleBuiltins are the Starlark library modules available to all scriptlets.
+var moduleBuiltins = []string{
+	"json",
+}
+
 // compile compiles a scriptlet.
 func compile(programName string, src string, preDeclared []string) (*starlark.Program, error) {
 	isPreDeclared := func(name string) bool {
-		return slices.Contains(preDeclared, name)
+		return slices.Contains(preDeclared, name) || slices.Contains(networkBuiltins, name) || slices.Contains(stringBuiltins, name) || slices.Contains(moduleBuiltins, name)
 	}
 
 	// Parse, resolve, and compile a Starlark source file.
@@ -80,6 +103,7 @@ func InstancePlacementCompile(name string, src string) (*starlark.Program, error
 		"get_instances",
 		"get_cluster_members",
 		"get_project",
+		"get_network_acls",
 	})
 }
 
@@ -100,6 +124,32 @@ func InstancePlacementProgram() (*starlark.Program, *starlark.Thread, error) {
 	return program("Instance placement", nameInstancePlacement)
 }
 
+// InstanceConfigCompile compiles the instance config scriptlet.
+func InstanceConfigCompile(name string, src string) (*starlark.Program, error) {
+	return compile(name, src, []string{
+		"log_info",
+		"log_warn",
+		"log_error",
+	})
+}
+
+// InstanceConfigValidate validates the instance config scriptlet.
+func InstanceConfigValidate(src string) error {
+	_, err := InstanceConfigCompile(nameInstanceConfig, src)
+	return err
+}
+
+// InstanceConfigSet compiles the instance config scriptlet into memory for use with InstanceConfigRun.
+// If empty src is provided the current program is deleted.
+func InstanceConfigSet(src string) error {
+	return set(InstanceConfigCompile, nameInstanceConfig, src)
+}
+
+// InstanceConfigProgram returns the precompiled instance config scriptlet program.
+func InstanceConfigProgram() (*starlark.Program, *starlark.Thread, error) {
+	return program("Instance config", nameInstanceConfig)
+}
+
 // QEMUCompile compiles the QEMU scriptlet.
 func QEMUCompile(name string, src string) (*starlark.Program, error) {
 	return compile(name, src, []string{
@@ -126,3 +176,31 @@ func QEMUSet(src string, instance string) error {
 func QEMUProgram(instance string) (*starlark.Program, *starlark.Thread, error) {
 	return program("QEMU", prefixQEMU+"/"+instance)
 }
+
+// NetworkACLRulesCompile compiles a network ACL rules scriptlet.
+func NetworkACLRulesCompile(name string, src string) (*starlark.Program, error) {
+	return compile(name, src, []string{
+		"log_info",
+		"log_warn",
+		"log_error",
+	})
+}
+
+// NetworkACLRulesValidate validates a network ACL rules scriptlet.
+func NetworkACLRulesValidate(src string) error {
+	_, err := NetworkACLRulesCompile(nameNetworkACLRules, src)
+	return err
+}
+
+// NetworkACLRulesProgram compiles a network ACL rules scriptlet for use with NetworkACLRulesRun.
+// Unlike the other scriptlets, these are stored on each ACL rather than loaded once into memory.
+func NetworkACLRulesProgram(src string) (*starlark.Program, *starlark.Thread, error) {
+	prog, err := NetworkACLRulesCompile(nameNetworkACLRules, src)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	thread := &starlark.Thread{Name: nameNetworkACLRules}
+
+	return prog, thread, nil
+}
@@ -0,0 +1,15 @@
+package scriptlet
+
+import (
+	starlarkjson "go.starlark.net/lib/json"
+	"go.starlark.net/starlark"
+)
+
+// moduleBuiltins returns the Starlark library modules available to all scriptlets.
+// Remember to match the entries in scriptletLoad.moduleBuiltins with this list so Starlark can perform compile
+// time validation of functions used.
+func moduleBuiltins() starlark.StringDict {
+	return starlark.StringDict{
+		"json": starlarkjson.Module,
+	}
+}
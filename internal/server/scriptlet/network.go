@@ -0,0 +1,104 @@
+package scriptlet
+
+import (
+	"fmt"
+	"net"
+
+	"go.starlark.net/starlark"
+)
+
+// networkBuiltins returns the network helper functions available to all scriptlets.
+// Remember to match the entries in scriptletLoad.networkBuiltins with this list so Starlark can perform compile
+// time validation of functions used.
+func networkBuiltins() starlark.StringDict {
+	return starlark.StringDict{
+		"cidr_contains": starlark.NewBuiltin("cidr_contains", cidrContainsFunc),
+		"cidr_overlaps": starlark.NewBuiltin("cidr_overlaps", cidrOverlapsFunc),
+		"ip_family":     starlark.NewBuiltin("ip_family", ipFamilyFunc),
+	}
+}
+
+// parseCIDR parses a CIDR argument of a scriptlet function.
+func parseCIDR(b *starlark.Builtin, value string) (*net.IPNet, error) {
+	_, subnet, err := net.ParseCIDR(value)
+	if err != nil {
+		return nil, fmt.Errorf("%s: Invalid CIDR %q", b.Name(), value)
+	}
+
+	return subnet, nil
+}
+
+// parseIP parses an IP address argument of a scriptlet function.
+func parseIP(b *starlark.Builtin, value string) (net.IP, error) {
+	ip := net.ParseIP(value)
+	if ip == nil {
+		return nil, fmt.Errorf("%s: Invalid IP address %q", b.Name(), value)
+	}
+
+	return ip, nil
+}
+
+// cidrContainsFunc returns whether the IP address is part of the CIDR.
+func cidrContainsFunc(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var cidr, address string
+
+	err := starlark.UnpackArgs(b.Name(), args, kwargs, "cidr", &cidr, "ip", &address)
+	if err != nil {
+		return nil, err
+	}
+
+	subnet, err := parseCIDR(b, cidr)
+	if err != nil {
+		return nil, err
+	}
+
+	ip, err := parseIP(b, address)
+	if err != nil {
+		return nil, err
+	}
+
+	return starlark.Bool(subnet.Contains(ip)), nil
+}
+
+// cidrOverlapsFunc returns whether the two CIDRs have any address in common.
+func cidrOverlapsFunc(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var cidrA, cidrB string
+
+	err := starlark.UnpackArgs(b.Name(), args, kwargs, "a", &cidrA, "b", &cidrB)
+	if err != nil {
+		return nil, err
+	}
+
+	subnetA, err := parseCIDR(b, cidrA)
+	if err != nil {
+		return nil, err
+	}
+
+	subnetB, err := parseCIDR(b, cidrB)
+	if err != nil {
+		return nil, err
+	}
+
+	return starlark.Bool(subnetA.Contains(subnetB.IP) || subnetB.Contains(subnetA.IP)), nil
+}
+
+// ipFamilyFunc returns the family (4 or 6) of the IP address.
+func ipFamilyFunc(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var address string
+
+	err := starlark.UnpackArgs(b.Name(), args, kwargs, "ip", &address)
+	if err != nil {
+		return nil, err
+	}
+
+	ip, err := parseIP(b, address)
+	if err != nil {
+		return nil, err
+	}
+
+	if ip.To4() != nil {
+		return starlark.MakeInt(4), nil
+	}
+
+	return starlark.MakeInt(6), nil
+}
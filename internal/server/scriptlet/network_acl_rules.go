@@ -0,0 +1,135 @@
+package scriptlet
+
+import (
+	"context"
+	"fmt"
+
+	"go.starlark.net/starlark"
+
+	scriptletLoad "github.com/lxc/incus/v6/internal/server/scriptlet/load"
+	"github.com/lxc/incus/v6/shared/api"
+	"github.com/lxc/incus/v6/shared/logger"
+)
+
+// networkACLRulesMaxSteps is the maximum number of Starlark execution steps of a network ACL rules scriptlet.
+const networkACLRulesMaxSteps = 10_000_000
+
+// NetworkACLRulesRun runs a network ACL rules scriptlet and returns the ingress and egress rules it generated.
+// The network_acl_rules function of the scriptlet is called once for each direction.
+func NetworkACLRulesRun(ctx context.Context, l logger.Logger, src string, project *api.Project, aclName string) ([]api.NetworkACLRule, []api.NetworkACLRule, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	logFunc := createLogger(l, "Network ACL rules scriptlet")
+
+	// Remember to match the entries in scriptletLoad.NetworkACLRulesCompile() with this list so Starlark can
+	// perform compile time validation of functions used.
+	env := starlark.StringDict{
+		"log_info":  starlark.NewBuiltin("log_info", logFunc),
+		"log_warn":  starlark.NewBuiltin("log_warn", logFunc),
+		"log_error": starlark.NewBuiltin("log_error", logFunc),
+	}
+
+	for name, builtin := range networkBuiltins() {
+		env[name] = builtin
+	}
+
+	for name, builtin := range stringBuiltins() {
+		env[name] = builtin
+	}
+
+	for name, builtin := range moduleBuiltins() {
+		env[name] = builtin
+	}
+
+	prog, thread, err := scriptletLoad.NetworkACLRulesProgram(src)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Bound the work done by the scriptlet, as it runs whenever the ACL is validated.
+	thread.SetMaxExecutionSteps(networkACLRulesMaxSteps)
+
+	go func() {
+		<-ctx.Done()
+		thread.Cancel("Request finished")
+	}()
+
+	globals, err := prog.Init(thread, env)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Failed initializing: %w", err)
+	}
+
+	globals.Freeze()
+
+	// Retrieve a global variable from starlark environment.
+	networkACLRulesFunc := globals["network_acl_rules"]
+	if networkACLRulesFunc == nil {
+		return nil, nil, fmt.Errorf("Scriptlet missing network_acl_rules function")
+	}
+
+	projectv, err := StarlarkMarshal(project)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Marshalling project failed: %w", err)
+	}
+
+	rules := make(map[string][]api.NetworkACLRule, 2)
+	for _, direction := range []string{"ingress", "egress"} {
+		// Call starlark function from Go.
+		v, err := starlark.Call(thread, networkACLRulesFunc, nil, []starlark.Tuple{
+			{
+				starlark.String("project"),
+				projectv,
+			}, {
+				starlark.String("name"),
+				starlark.String(aclName),
+			}, {
+				starlark.String("direction"),
+				starlark.String(direction),
+			},
+		})
+		if err != nil {
+			return nil, nil, fmt.Errorf("Failed to run: %w", err)
+		}
+
+		rules[direction], err = networkACLRules(v)
+		if err != nil {
+			return nil, nil, fmt.Errorf("Invalid %s rules: %w", direction, err)
+		}
+	}
+
+	return rules["ingress"], rules["egress"], nil
+}
+
+// networkACLRules converts the value returned by a network ACL rules scriptlet into rules.
+// A list of dicts using the same keys as the API is expected, returning None means no rules.
+func networkACLRules(v starlark.Value) ([]api.NetworkACLRule, error) {
+	if v == starlark.None {
+		return []api.NetworkACLRule{}, nil
+	}
+
+	list, ok := v.(*starlark.List)
+	if !ok {
+		return nil, fmt.Errorf("Failed with unexpected return value: %v", v)
+	}
+
+	rules := make([]api.NetworkACLRule, 0, list.Len())
+	for i := range list.Len() {
+		ruleValue := list.Index(i)
+
+		_, ok := ruleValue.(*starlark.Dict)
+		if !ok {
+			return nil, fmt.Errorf("Rule %d must be a dict", i)
+		}
+
+		var rule api.NetworkACLRule
+		err := StarlarkUnmarshalTo(ruleValue, &rule)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid rule %d: %w", i, err)
+		}
+
+		rules = append(rules, rule)
+	}
+
+	return rules, nil
+}
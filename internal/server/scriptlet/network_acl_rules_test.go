@@ -0,0 +1,92 @@
+package scriptlet
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lxc/incus/v6/shared/api"
+	"github.com/lxc/incus/v6/shared/logger"
+)
+
+func TestNetworkACLRulesRun(t *testing.T) {
+	src := `
+def network_acl_rules(project, name, direction):
+    if direction == "egress":
+        return None
+
+    rules = []
+    for port in project.config.get("user.web_ports", "").split(","):
+        rules.append({"action": "allow", "state": "enabled", "protocol": "tcp", "destination_port": port, "description": name})
+
+    return rules
+`
+
+	project := &api.Project{Name: "p1", ProjectPut: api.ProjectPut{Config: map[string]string{"user.web_ports": "80,443"}}}
+
+	ingress, egress, err := NetworkACLRulesRun(context.Background(), logger.Log, src, project, "web")
+	require.NoError(t, err)
+	assert.Equal(t, []api.NetworkACLRule{
+		{Action: "allow", State: "enabled", Protocol: "tcp", DestinationPort: "80", Description: "web"},
+		{Action: "allow", State: "enabled", Protocol: "tcp", DestinationPort: "443", Description: "web"},
+	}, ingress)
+	assert.Equal(t, []api.NetworkACLRule{}, egress)
+}
+
+func TestNetworkACLRulesRunJSON(t *testing.T) {
+	// The json module can decode structured config values, and encode values into strings.
+	src := `
+def network_acl_rules(project, name, direction):
+    if direction == "egress":
+        return None
+
+    web = json.decode(project.config["user.web"])
+    web["ports"].append(8080)
+    web["ports"] = sorted(web["ports"])
+
+    rules = []
+    for port in web["ports"]:
+        rules.append({"action": "allow", "state": "enabled", "protocol": "tcp", "destination_port": str(port), "description": json.encode(web)})
+
+    return rules
+`
+
+	project := &api.Project{Name: "p1", ProjectPut: api.ProjectPut{Config: map[string]string{"user.web": `{"ports": [443, 80], "owner": "web-team"}`}}}
+
+	ingress, _, err := NetworkACLRulesRun(context.Background(), logger.Log, src, project, "web")
+	require.NoError(t, err)
+
+	description := `{"owner":"web-team","ports":[80,443,8080]}`
+	assert.Equal(t, []api.NetworkACLRule{
+		{Action: "allow", State: "enabled", Protocol: "tcp", DestinationPort: "80", Description: description},
+		{Action: "allow", State: "enabled", Protocol: "tcp", DestinationPort: "443", Description: description},
+		{Action: "allow", State: "enabled", Protocol: "tcp", DestinationPort: "8080", Description: description},
+	}, ingress)
+}
+
+func TestNetworkACLRulesRunErrors(t *testing.T) {
+	tests := map[string]string{
+		`def network_acl_rules(project, name, direction):
+    return {"action": "allow"}`: `Invalid ingress rules: Failed with unexpected return value: {"action": "allow"}`,
+		`def network_acl_rules(project, name, direction):
+    return ["allow"]`: `Invalid ingress rules: Rule 0 must be a dict`,
+		`def network_acl_rules(project, name, direction):
+    return [{"action": "allow", "destination_port": 80}]`: `Invalid ingress rules: Invalid rule 0: Field "destination_port": Cannot convert int to string`,
+		`def network_acl_rules(project, name, direction):
+    return [{"action": "allow", "port": "80"}]`: `Invalid ingress rules: Invalid rule 0: Unknown field "port"`,
+		`def network_acl_rules(project, name, direction):
+    fail("No rules for " + name)`: `Failed to run: fail: No rules for web`,
+		`def network_acl_rules(project, name, direction):
+    for i in range(100000000):
+        pass`: `Failed to run: Starlark computation cancelled: too many steps`,
+		`def other(project, name, direction):
+    return None`: `Scriptlet missing network_acl_rules function`,
+	}
+
+	for src, expected := range tests {
+		_, _, err := NetworkACLRulesRun(context.Background(), logger.Log, src, &api.Project{Name: "p1"}, "web")
+		assert.EqualError(t, err, expected, src)
+	}
+}
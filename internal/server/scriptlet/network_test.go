@@ -0,0 +1,70 @@
+package scriptlet
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.starlark.net/starlark"
+)
+
+// evalNetworkBuiltin evaluates a Starlark expression using the network built-ins.
+func evalNetworkBuiltin(expr string) (starlark.Value, error) {
+	thread := &starlark.Thread{Name: "test"}
+
+	return starlark.Eval(thread, "test", expr, networkBuiltins())
+}
+
+func TestCIDRContains(t *testing.T) {
+	for expr, expected := range map[string]bool{
+		`cidr_contains("192.0.2.0/24", "192.0.2.10")`:     true,
+		`cidr_contains("192.0.2.0/24", "198.51.100.10")`:  false,
+		`cidr_contains("2001:db8::/32", "2001:db8::1")`:   true,
+		`cidr_contains("2001:db8::/32", "192.0.2.10")`:    false,
+		`cidr_contains(cidr="10.0.0.0/8", ip="10.1.2.3")`: true,
+	} {
+		v, err := evalNetworkBuiltin(expr)
+		assert.NoError(t, err, expr)
+		assert.Equal(t, starlark.Bool(expected), v, expr)
+	}
+
+	_, err := evalNetworkBuiltin(`cidr_contains("192.0.2.0", "192.0.2.10")`)
+	assert.ErrorContains(t, err, `cidr_contains: Invalid CIDR "192.0.2.0"`)
+
+	_, err = evalNetworkBuiltin(`cidr_contains("192.0.2.0/24", "foo")`)
+	assert.ErrorContains(t, err, `cidr_contains: Invalid IP address "foo"`)
+}
+
+func TestCIDROverlaps(t *testing.T) {
+	for expr, expected := range map[string]bool{
+		`cidr_overlaps("192.0.2.0/24", "192.0.2.128/25")`:   true,
+		`cidr_overlaps("192.0.2.128/25", "192.0.2.0/24")`:   true,
+		`cidr_overlaps("192.0.2.0/25", "192.0.2.128/25")`:   false,
+		`cidr_overlaps("2001:db8::/32", "2001:db8:1::/48")`: true,
+		`cidr_overlaps("2001:db8::/32", "192.0.2.0/24")`:    false,
+	} {
+		v, err := evalNetworkBuiltin(expr)
+		assert.NoError(t, err, expr)
+		assert.Equal(t, starlark.Bool(expected), v, expr)
+	}
+
+	_, err := evalNetworkBuiltin(`cidr_overlaps("192.0.2.0/24", "192.0.2.0/33")`)
+	assert.ErrorContains(t, err, `cidr_overlaps: Invalid CIDR "192.0.2.0/33"`)
+}
+
+func TestIPFamily(t *testing.T) {
+	for expr, expected := range map[string]int{
+		`ip_family("192.0.2.1")`:        4,
+		`ip_family("::ffff:192.0.2.1")`: 4,
+		`ip_family("2001:db8::1")`:      6,
+	} {
+		v, err := evalNetworkBuiltin(expr)
+		assert.NoError(t, err, expr)
+		assert.Equal(t, starlark.MakeInt(expected), v, expr)
+	}
+
+	_, err := evalNetworkBuiltin(`ip_family("192.0.2.0/24")`)
+	assert.ErrorContains(t, err, `ip_family: Invalid IP address "192.0.2.0/24"`)
+
+	_, err = evalNetworkBuiltin(`ip_family()`)
+	assert.Error(t, err)
+}
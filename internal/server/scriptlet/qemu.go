@@ -55,6 +55,18 @@ func QEMURun(l logger.Logger, m *qmp.Monitor, instance string, stage string) err
 		"run_qmp":   starlark.NewBuiltin("run_qmp", runQMPFunc),
 	}
 
+	for name, builtin := range networkBuiltins() {
+		env[name] = builtin
+	}
+
+	for name, builtin := range stringBuiltins() {
+		env[name] = builtin
+	}
+
+	for name, builtin := range moduleBuiltins() {
+		env[name] = builtin
+	}
+
 	prog, thread, err := scriptletLoad.QEMUProgram(instance)
 	if err != nil {
 		return err
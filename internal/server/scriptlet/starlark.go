@@ -1,30 +1,87 @@
 package scriptlet
 
 import (
+	"bytes"
+	"cmp"
+	"encoding"
+	"encoding/json"
 	"fmt"
 	"reflect"
+	"slices"
 	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"go.starlark.net/starlark"
 )
 
+// StarlarkMarshaler is implemented by types that convert themselves to a Starlark value, rather than being
+// converted according to their kind by StarlarkMarshal.
+type StarlarkMarshaler interface {
+	MarshalStarlark() (starlark.Value, error)
+}
+
+// StarlarkUnmarshaler is implemented by types that set themselves from a Starlark value, rather than being
+// converted according to their kind by StarlarkUnmarshalTo. It isn't called for None, which sets the zero value.
+type StarlarkUnmarshaler interface {
+	UnmarshalStarlark(starlark.Value) error
+}
+
 // starlarkObject wraps a starlark.Dict and is used to provide custom object types to the Starlark scriptlets.
-// This implements the starlark.HasAttrs interface.
+// This implements the starlark.HasSetField interface, so that scriptlets can change the fields of objects which
+// aren't frozen, as well as starlark.IterableMapping so that scriptlets can iterate over the field names with
+// "for k in obj" and convert objects with dict(obj).
+// The fields are ordered as the dict keys: struct fields in declaration order with the fields of anonymous
+// (embedded) structs in place of the struct, and map keys sorted.
+// Accessing a field that doesn't exist fails with suggestions of similar field names, unless missingAttrNone is set
+// in which case Attr follows the Starlark convention of returning nil, so that hasattr() and getattr() with a
+// default work.
 type starlarkObject struct {
-	d        *starlark.Dict
-	typeName string
+	d               *starlark.Dict
+	typeName        string
+	missingAttrNone bool
 }
 
 func (s *starlarkObject) Type() string {
 	return s.typeName
 }
 
+// String renders the object as TypeName(field=value, ...), with the values in their Starlark form.
 func (s *starlarkObject) String() string {
-	return s.d.String()
+	typeName := s.typeName
+	if typeName == "" {
+		typeName = "struct"
+	}
+
+	var b strings.Builder
+	b.WriteString(typeName)
+	b.WriteString("(")
+
+	for i, item := range s.d.Items() {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+
+		// Use the raw key rather than its quoted Starlark representation.
+		key, ok := starlark.AsString(item[0])
+		if !ok {
+			key = item[0].String()
+		}
+
+		b.WriteString(key)
+		b.WriteString("=")
+		b.WriteString(item[1].String())
+	}
+
+	b.WriteString(")")
+
+	return b.String()
 }
 
 func (s *starlarkObject) Freeze() {
+	s.d.Freeze()
 }
 
 func (s *starlarkObject) Hash() (uint32, error) {
@@ -35,11 +92,37 @@ func (s *starlarkObject) Truth() starlark.Bool {
 	return starlark.True
 }
 
+// Iterate returns an iterator over the field names.
+func (s *starlarkObject) Iterate() starlark.Iterator {
+	return s.d.Iterate()
+}
+
+// Items returns the field names and values.
+func (s *starlarkObject) Items() []starlark.Tuple {
+	return s.d.Items()
+}
+
+// Get returns the value of a field, so that "k in obj" and obj[k] work.
+func (s *starlarkObject) Get(k starlark.Value) (starlark.Value, bool, error) {
+	return s.d.Get(k)
+}
+
+// Len returns the number of fields.
+func (s *starlarkObject) Len() int {
+	return s.d.Len()
+}
+
 func (s *starlarkObject) AttrNames() []string {
 	keys := s.d.Keys()
 	keyNames := make([]string, 0, len(keys))
 	for _, k := range keys {
-		keyNames = append(keyNames, k.String())
+		// Use the raw key rather than its quoted Starlark representation.
+		keyName, ok := starlark.AsString(k)
+		if !ok {
+			keyName = k.String()
+		}
+
+		keyNames = append(keyNames, keyName)
 	}
 
 	return keyNames
@@ -52,23 +135,392 @@ func (s *starlarkObject) Attr(name string) (starlark.Value, error) {
 	}
 
 	if !found {
-		return nil, fmt.Errorf("Invalid field %q", name)
+		if s.missingAttrNone {
+			return nil, nil
+		}
+
+		typeName := s.typeName
+		if typeName == "" {
+			typeName = "struct"
+		}
+
+		suggestions := starlarkSuggestNames(name, s.AttrNames())
+		if len(suggestions) == 0 {
+			return nil, fmt.Errorf("%s has no field %q", typeName, name)
+		}
+
+		for i := range suggestions {
+			suggestions[i] = strconv.Quote(suggestions[i])
+		}
+
+		return nil, fmt.Errorf("%s has no field %q (did you mean %s?)", typeName, name, strings.Join(suggestions, " or "))
 	}
 
 	return field, nil
 }
 
+// starlarkSuggestNames returns up to three of names which are close to name, closest first.
+// A name is close when it can be turned into name with at most a third of its length (and at least two) single
+// character edits.
+func starlarkSuggestNames(name string, names []string) []string {
+	type suggestion struct {
+		name     string
+		distance int
+	}
+
+	maxDistance := max(2, len(name)/3)
+	suggestions := []suggestion{}
+	for _, n := range names {
+		distance := levenshteinDistance(name, n)
+		if distance <= maxDistance {
+			suggestions = append(suggestions, suggestion{name: n, distance: distance})
+		}
+	}
+
+	// Keep the field order for names which are as close as each other.
+	slices.SortStableFunc(suggestions, func(a suggestion, b suggestion) int {
+		return cmp.Compare(a.distance, b.distance)
+	})
+
+	result := make([]string, 0, 3)
+	for _, s := range suggestions {
+		if len(result) == cap(result) {
+			break
+		}
+
+		result = append(result, s.name)
+	}
+
+	return result
+}
+
+// levenshteinDistance returns the number of single character insertions, deletions and substitutions needed to
+// turn a into b.
+func levenshteinDistance(a string, b string) int {
+	ra := []rune(a)
+	rb := []rune(b)
+
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+
+		prev, curr = curr, prev
+	}
+
+	return prev[len(rb)]
+}
+
+// SetField sets the value of an existing field. New fields can't be added, and frozen objects can't be changed, so
+// only objects marshalled with the Mutable option can be.
+func (s *starlarkObject) SetField(name string, val starlark.Value) error {
+	_, found, err := s.d.Get(starlark.String(name))
+	if err != nil {
+		return err
+	}
+
+	if !found {
+		return fmt.Errorf("Invalid field %q, must be one of: %s", name, strings.Join(s.AttrNames(), ", "))
+	}
+
+	err = s.d.SetKey(starlark.String(name), val)
+	if err != nil {
+		return fmt.Errorf("Cannot set field %q of %s: %w", name, s.Type(), err)
+	}
+
+	return nil
+}
+
+// StarlarkMarshalOpts represents options that change how values are converted by StarlarkMarshalWithOpts.
+type StarlarkMarshalOpts struct {
+	SkipNilPointers bool // Omit struct fields holding a nil pointer rather than setting them to None.
+	SkipNilElements bool // Omit nil pointer and interface elements of slices and arrays rather than adding None.
+
+	KeyTransform func(key string) string // Applied to each dict key (struct field and map key names) before setting it.
+
+	// Omit struct fields with the "omitempty" option in their "json" tag when they hold an empty value, as
+	// encoding/json does: false, 0, an empty string, a nil pointer or interface, or an empty array, slice or map.
+	OmitEmpty bool
+
+	// Convert integer, bool and fmt.Stringer map keys to strings using fmt.Sprintf rather than failing.
+	// Integer and bool keys are ordered by value, and other keys by their string form.
+	StringifyMapKeys bool
+
+	// Convert the values of these types according to their kind, such as to an integer, rather than with their
+	// fmt.Stringer String method.
+	RawStringerTypes []reflect.Type
+
+	// Make objects return nil rather than an error for fields that don't exist, following the Starlark convention
+	// for missing attributes, so that hasattr(obj, name) and getattr(obj, name, default) can be used.
+	MissingAttrNone bool
+
+	// Leave the returned value unfrozen, so that scriptlets can change the fields of objects and the elements of
+	// dicts and lists, for example to adjust a value before returning it.
+	Mutable bool
+}
+
+// transformKey returns key after applying the KeyTransform function, if any.
+func (o StarlarkMarshalOpts) transformKey(key string) string {
+	if o.KeyTransform == nil {
+		return key
+	}
+
+	return o.KeyTransform(key)
+}
+
+// starlarkStringifiableKey returns whether map keys of type t can be converted to strings by starlarkKeyString.
+func starlarkStringifiableKey(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return true
+	case reflect.Bool:
+		return true
+	}
+
+	return t.Implements(reflect.TypeFor[fmt.Stringer]())
+}
+
+// starlarkKeyString returns the string form of map key k.
+func starlarkKeyString(k reflect.Value) string {
+	if k.Kind() == reflect.String {
+		return k.String()
+	}
+
+	return fmt.Sprintf("%v", k.Interface())
+}
+
+// starlarkCompareKeys compares map keys a and b, ordering integer and bool keys by value and other keys by their
+// string form.
+func starlarkCompareKeys(a reflect.Value, b reflect.Value) int {
+	switch a.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return cmp.Compare(a.Int(), b.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return cmp.Compare(a.Uint(), b.Uint())
+	case reflect.Bool:
+		if a.Bool() == b.Bool() {
+			return 0
+		}
+
+		if b.Bool() {
+			return -1
+		}
+
+		return 1
+	}
+
+	return strings.Compare(starlarkKeyString(a), starlarkKeyString(b))
+}
+
+// starlarkStructField is the metadata of an exported struct field used by starlarkMarshal.
+type starlarkStructField struct {
+	index     int
+	name      string         // Name from the "json" tag, or the Go field name if not set.
+	key       starlark.Value // Name as a Starlark string, used as dict key when no key transform is set.
+	anonymous bool
+	basic     bool // Whether the field holds a value of a basic kind without custom conversion.
+	omitEmpty bool // Whether the "json" tag has the "omitempty" option.
+}
+
+// starlarkStructFieldsCache holds the exported fields of the struct types marshalled so far, keyed by reflect.Type.
+var starlarkStructFieldsCache sync.Map
+
+// starlarkStructFields returns the exported fields of struct type t, leaving out the ones with a "json" tag of "-"
+// as encoding/json does. They are only looked up once per type, so that marshalling many values of the same type
+// only walks their values.
+func starlarkStructFields(t reflect.Type) []starlarkStructField {
+	cached, found := starlarkStructFieldsCache.Load(t)
+	if found {
+		return cached.([]starlarkStructField)
+	}
+
+	fields := make([]starlarkStructField, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+
+		name, tagOpts, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = field.Name
+		}
+
+		// Values of types converted by the type switch of starlarkMarshal aren't basic, even if their kind is.
+		basic := starlarkIsBasicKind(field.Type.Kind()) &&
+			field.Type != reflect.TypeFor[json.Number]() &&
+			field.Type != reflect.TypeFor[time.Duration]() &&
+			!field.Type.Implements(reflect.TypeFor[starlark.Value]()) &&
+			!reflect.PointerTo(field.Type).Implements(reflect.TypeFor[StarlarkMarshaler]()) &&
+			!reflect.PointerTo(field.Type).Implements(reflect.TypeFor[encoding.TextMarshaler]()) &&
+			!reflect.PointerTo(field.Type).Implements(reflect.TypeFor[fmt.Stringer]())
+
+		fields = append(fields, starlarkStructField{
+			index:     i,
+			name:      name,
+			key:       starlark.String(name),
+			anonymous: field.Anonymous,
+			basic:     basic,
+			omitEmpty: slices.Contains(strings.Split(tagOpts, ","), "omitempty"),
+		})
+	}
+
+	cached, _ = starlarkStructFieldsCache.LoadOrStore(t, fields)
+
+	return cached.([]starlarkStructField)
+}
+
+// starlarkIsEmptyValue returns whether v is empty as defined by the "omitempty" option of encoding/json.
+func starlarkIsEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool, reflect.Float32, reflect.Float64, reflect.Interface, reflect.Pointer:
+		return v.IsZero()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.IsZero()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.IsZero()
+	}
+
+	return false
+}
+
+// starlarkIsBasicKind returns whether values of kind k are converted by starlarkBasicValue.
+func starlarkIsBasicKind(k reflect.Kind) bool {
+	switch k {
+	case reflect.String, reflect.Bool, reflect.Float32, reflect.Float64:
+		return true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return true
+	}
+
+	return false
+}
+
+// starlarkTextValue returns the text form of v if its type, or a pointer to it, implements encoding.TextMarshaler.
+// Otherwise, if v is of a named type which isn't listed in the RawStringerTypes option, it returns the result of its
+// String method if there is one.
+// The pointer receiver methods are called on a copy of v, as v itself may not be addressable.
+func starlarkTextValue(v reflect.Value, opts StarlarkMarshalOpts) (string, bool, error) {
+	if v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		return "", false, nil
+	}
+
+	pt := reflect.PointerTo(v.Type())
+	if !pt.Implements(reflect.TypeFor[encoding.TextMarshaler]()) && !pt.Implements(reflect.TypeFor[fmt.Stringer]()) {
+		return "", false, nil
+	}
+
+	// The method set of the pointer type includes the value receiver methods.
+	pv := reflect.New(v.Type())
+	pv.Elem().Set(v)
+
+	switch t := pv.Interface().(type) {
+	case encoding.TextMarshaler:
+		text, err := t.MarshalText()
+		if err != nil {
+			return "", false, fmt.Errorf("Failed marshalling %v to text: %w", v.Type(), err)
+		}
+
+		return string(text), true, nil
+	case fmt.Stringer:
+		// Unnamed types, such as struct{ fmt.Stringer }, only get a String method from an embedded field.
+		if v.Type().Name() == "" || slices.Contains(opts.RawStringerTypes, v.Type()) {
+			return "", false, nil
+		}
+
+		return t.String(), true, nil
+	}
+
+	return "", false, nil
+}
+
+// starlarkBasicValue converts v to a starlark Value according to its kind, which must be a basic kind as reported
+// by starlarkIsBasicKind.
+func starlarkBasicValue(v reflect.Value) starlark.Value {
+	switch v.Kind() {
+	case reflect.String:
+		return starlark.String(v.String())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return starlark.MakeInt(int(v.Int()))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return starlark.MakeUint(uint(v.Uint()))
+	case reflect.Float32, reflect.Float64:
+		return starlark.Float(v.Float())
+	case reflect.Bool:
+		return starlark.Bool(v.Bool())
+	}
+
+	return nil
+}
+
 // StarlarkMarshal converts input to a starlark Value.
-// It only includes exported struct fields, and uses the "json" tag for field names.
+// It only includes exported struct fields, and uses the "json" tag for field names. Fields with a "json" tag of "-"
+// are left out, use StarlarkMarshalWithOpts with OmitEmpty to also leave out empty fields with "omitempty".
+// Nil pointers are converted to None, including when they are elements of a slice or array, so the resulting
+// list keeps the same length as the input. Use StarlarkMarshalWithOpts with SkipNilElements to drop them instead.
+// Only maps with string keys are supported, use StarlarkMarshalWithOpts with StringifyMapKeys to convert other keys.
+// Nil slices and maps are converted to an empty list and dict, the same as empty ones.
+// Values of type uintptr are converted to integers like the other unsigned integers.
+// Complex numbers are converted to a list of their real and imaginary parts, as floats.
+// Times are converted to RFC3339 strings (with fractional seconds if any), or None for zero times such as unset
+// expiries. Durations are converted to strings as formatted by time.Duration.String (for example "5m30s").
+// Values implementing encoding.TextMarshaler, such as net.IP, are converted to their text form, and values of named
+// types implementing fmt.Stringer, such as net.IPNet or api.StatusCode, to the result of their String method.
+// Either method may have a pointer receiver. Use StarlarkMarshalWithOpts with RawStringerTypes to convert the values
+// of some fmt.Stringer types according to their kind instead.
+// Values implementing StarlarkMarshaler, with a value or pointer receiver, are converted by its MarshalStarlark
+// method before any of the above, with errors including the path of the value that failed to convert.
+// The returned value is frozen, including all the dicts, lists and objects it contains, so that scriptlets can't
+// modify it. Use StarlarkMarshalWithOpts with Mutable to let scriptlets change it.
 func StarlarkMarshal(input any) (starlark.Value, error) {
-	return starlarkMarshal(input, nil)
+	return StarlarkMarshalWithOpts(input, StarlarkMarshalOpts{})
+}
+
+// StarlarkMarshalWithOpts converts input to a starlark Value using the provided options.
+// As with StarlarkMarshal, the returned value is frozen unless the Mutable option is set.
+func StarlarkMarshalWithOpts(input any, opts StarlarkMarshalOpts) (starlark.Value, error) {
+	sv, err := starlarkMarshal(input, nil, "", opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if !opts.Mutable {
+		sv.Freeze()
+	}
+
+	return sv, nil
 }
 
 // starlarkMarshal converts input to a starlark Value.
 // It only includes exported struct fields, and uses the "json" tag for field names.
+// Values held by interface fields, map values and list elements are marshalled according to their dynamic type,
+// as reflect unwraps them when passing them back as input, so nil interfaces are converted to None.
 // Takes optional parent Starlark dictionary which will be used to set fields from anonymous (embedded) structs
-// in to the parent struct.
-func starlarkMarshal(input any, parent *starlark.Dict) (starlark.Value, error) {
+// in to the parent struct. The path is the location of input within the top-level value and is used in errors.
+func starlarkMarshal(input any, parent *starlark.Dict, path string, opts StarlarkMarshalOpts) (starlark.Value, error) {
 	if input == nil {
 		return starlark.None, nil
 	}
@@ -81,24 +533,103 @@ func starlarkMarshal(input any, parent *starlark.Dict) (starlark.Value, error) {
 	var err error
 
 	v := reflect.ValueOf(input)
+	if v.Kind() == reflect.Pointer && v.IsNil() {
+		return starlark.None, nil
+	}
+
+	// Types with their own Starlark representation convert themselves.
+	marshaler, ok := input.(StarlarkMarshaler)
+	if !ok && v.Kind() != reflect.Pointer && reflect.PointerTo(v.Type()).Implements(reflect.TypeFor[StarlarkMarshaler]()) {
+		pv := reflect.New(v.Type())
+		pv.Elem().Set(v)
+		marshaler, ok = pv.Interface().(StarlarkMarshaler)
+	}
+
+	if ok {
+		sv, err := marshaler.MarshalStarlark()
+		if err != nil {
+			return nil, starlarkPathError(path, fmt.Errorf("Failed marshalling %v to Starlark: %w", v.Type(), err))
+		}
+
+		if sv == nil {
+			return starlark.None, nil
+		}
+
+		return sv, nil
+	}
+
+	switch t := input.(type) {
+	case json.RawMessage:
+		// Parse raw JSON into a generic structure so its content is accessible from Starlark.
+		if len(t) == 0 {
+			return starlark.None, nil
+		}
+
+		var data any
+
+		decoder := json.NewDecoder(bytes.NewReader(t))
+		decoder.UseNumber()
+
+		err = decoder.Decode(&data)
+		if err != nil {
+			return nil, fmt.Errorf("Failed parsing raw JSON: %w", err)
+		}
+
+		return starlarkMarshal(data, nil, path, opts)
+	case json.Number:
+		i, err := t.Int64()
+		if err == nil {
+			return starlark.MakeInt64(i), nil
+		}
+
+		f, err := t.Float64()
+		if err != nil {
+			return nil, fmt.Errorf("Failed parsing JSON number %q: %w", t, err)
+		}
+
+		return starlark.Float(f), nil
+	case time.Time:
+		if t.IsZero() {
+			return starlark.None, nil
+		}
+
+		return starlark.String(t.Format(time.RFC3339Nano)), nil
+	case time.Duration:
+		return starlark.String(t.String()), nil
+	}
+
+	// Convert types with a text form, such as net.IP, to strings rather than through their kind.
+	text, ok, err := starlarkTextValue(v, opts)
+	if err != nil {
+		return nil, starlarkPathError(path, err)
+	}
+
+	if ok {
+		return starlark.String(text), nil
+	}
 
 	switch v.Type().Kind() {
-	case reflect.String:
-		sv = starlark.String(v.String())
+	case reflect.String, reflect.Bool, reflect.Float32, reflect.Float64:
+		sv = starlarkBasicValue(v)
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-		sv = starlark.MakeInt(int(v.Int()))
-	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-		sv = starlark.MakeUint(uint(v.Uint()))
-	case reflect.Float32, reflect.Float64:
-		sv = starlark.Float(v.Float())
-	case reflect.Bool:
-		sv = starlark.Bool(v.Bool())
+		sv = starlarkBasicValue(v)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		sv = starlarkBasicValue(v)
+	case reflect.Complex64, reflect.Complex128:
+		// Starlark has no complex numbers, so use a list of the real and imaginary parts.
+		c := v.Complex()
+		sv = starlark.NewList([]starlark.Value{starlark.Float(real(c)), starlark.Float(imag(c))})
 	case reflect.Array, reflect.Slice:
 		vlen := v.Len()
 		listElems := make([]starlark.Value, 0, vlen)
 
 		for i := 0; i < vlen; i++ {
-			lv, err := StarlarkMarshal(v.Index(i).Interface())
+			elem := v.Index(i)
+			if opts.SkipNilElements && (elem.Kind() == reflect.Pointer || elem.Kind() == reflect.Interface) && elem.IsNil() {
+				continue
+			}
+
+			lv, err := starlarkMarshal(elem.Interface(), nil, fmt.Sprintf("%s[%d]", path, i), opts)
 			if err != nil {
 				return nil, err
 			}
@@ -111,65 +642,82 @@ func starlarkMarshal(input any, parent *starlark.Dict) (starlark.Value, error) {
 		mKeys := v.MapKeys()
 		d := starlark.NewDict(len(mKeys))
 
-		if v.Type().Key().Kind() != reflect.String {
-			return nil, fmt.Errorf("Only string keys are supported, found %s", v.Type().Key().Kind())
+		keyType := v.Type().Key()
+		if keyType.Kind() != reflect.String && (!opts.StringifyMapKeys || !starlarkStringifiableKey(keyType)) {
+			return nil, fmt.Errorf("Only string keys are supported, found %s", keyType.Kind())
 		}
 
 		sort.Slice(mKeys, func(i, j int) bool {
-			return mKeys[i].String() < mKeys[j].String()
+			return starlarkCompareKeys(mKeys[i], mKeys[j]) < 0
 		})
 
 		for _, k := range mKeys {
 			mv := v.MapIndex(k)
-			dv, err := StarlarkMarshal(mv.Interface())
+			dv, err := starlarkMarshal(mv.Interface(), nil, fmt.Sprintf("%s[%s]", path, starlarkKeyString(k)), opts)
 			if err != nil {
 				return nil, err
 			}
 
-			err = d.SetKey(starlark.String(k.String()), dv)
+			key := opts.transformKey(starlarkKeyString(k))
+			err = d.SetKey(starlark.String(key), dv)
 			if err != nil {
-				return nil, fmt.Errorf("Failed setting map key %q to %v: %w", k.String(), dv, err)
+				return nil, fmt.Errorf("Failed setting map key %q to %v: %w", key, dv, err)
 			}
 		}
 
 		sv = d
 	case reflect.Struct:
-		fieldCount := v.Type().NumField()
+		fields := starlarkStructFields(v.Type())
 
 		d := parent
 		if d == nil {
-			d = starlark.NewDict(fieldCount)
+			d = starlark.NewDict(len(fields))
 		}
 
-		for i := 0; i < fieldCount; i++ {
-			field := v.Type().Field(i)
-			fieldValue := v.Field(i)
+		for _, field := range fields {
+			fieldValue := v.Field(field.index)
+
+			if opts.SkipNilPointers && fieldValue.Kind() == reflect.Pointer && fieldValue.IsNil() {
+				continue
+			}
 
-			if !field.IsExported() {
+			if opts.OmitEmpty && field.omitEmpty && starlarkIsEmptyValue(fieldValue) {
 				continue
 			}
 
-			if field.Anonymous && fieldValue.Kind() == reflect.Struct {
+			if field.anonymous && fieldValue.Kind() == reflect.Struct {
 				// If anonymous struct field's value is another struct then pass the the current
 				// starlark dictionary to starlarkMarshal so its fields will be set on the parent.
-				_, err = starlarkMarshal(fieldValue.Interface(), d)
+				_, err = starlarkMarshal(fieldValue.Interface(), d, path, opts)
 				if err != nil {
 					return nil, err
 				}
 			} else {
-				dv, err := StarlarkMarshal(fieldValue.Interface())
-				if err != nil {
-					return nil, err
+				var dv starlark.Value
+
+				// Convert basic values directly rather than through an interface.
+				if field.basic {
+					dv = starlarkBasicValue(fieldValue)
+				} else {
+					fieldPath := field.name
+					if path != "" {
+						fieldPath = path + "." + field.name
+					}
+
+					dv, err = starlarkMarshal(fieldValue.Interface(), nil, fieldPath, opts)
+					if err != nil {
+						return nil, err
+					}
 				}
 
-				key, _, _ := strings.Cut(field.Tag.Get("json"), ",")
-				if key == "" {
-					key = field.Name
+				key := field.key
+				if opts.KeyTransform != nil {
+					key = starlark.String(opts.transformKey(field.name))
 				}
 
-				err = d.SetKey(starlark.String(key), dv)
+				err = d.SetKey(key, dv)
 				if err != nil {
-					return nil, fmt.Errorf("Failed setting struct field %q to %v: %w", key, dv, err)
+					return nil, fmt.Errorf("Failed setting struct field %s to %v: %w", key, dv, err)
 				}
 			}
 		}
@@ -177,8 +725,9 @@ func starlarkMarshal(input any, parent *starlark.Dict) (starlark.Value, error) {
 		// Only convert the top-level struct to a Starlark object.
 		if parent == nil {
 			ss := starlarkObject{
-				d:        d,
-				typeName: v.Type().Name(),
+				d:               d,
+				typeName:        v.Type().Name(),
+				missingAttrNone: opts.MissingAttrNone,
 			}
 
 			sv = &ss
@@ -190,7 +739,7 @@ func starlarkMarshal(input any, parent *starlark.Dict) (starlark.Value, error) {
 		if v.IsZero() {
 			sv = starlark.None
 		} else {
-			sv, err = StarlarkMarshal(v.Elem().Interface())
+			sv, err = starlarkMarshal(v.Elem().Interface(), nil, path, opts)
 			if err != nil {
 				return nil, err
 			}
@@ -205,27 +754,69 @@ func starlarkMarshal(input any, parent *starlark.Dict) (starlark.Value, error) {
 }
 
 // StarlarkUnmarshal converts a Starlark value into a Go value.
-// Only NoneType, Bool, Int, Float, String, List and Dict are supported.
+// Only NoneType, Bool, Int, Float, String, Bytes, List, Tuple, Set and Dict are supported.
+// Integers are returned as int64, or as uint64 if they are only positive enough to fit in the latter, and fail to
+// convert if they fit in neither rather than being truncated. Use StarlarkUnmarshalWithOpts to get them as *big.Int.
+// Bytes are returned as []byte. Lists, tuples and sets are returned as []any, with the elements of sets sorted by
+// their Starlark string form so that the result doesn't depend on the order they were added in.
 func StarlarkUnmarshal(input starlark.Value) (any, error) {
+	return StarlarkUnmarshalWithOpts(input, StarlarkUnmarshalOpts{})
+}
+
+// StarlarkUnmarshalWithOpts converts a Starlark value into a Go value using the provided options, as described in
+// StarlarkUnmarshal.
+func StarlarkUnmarshalWithOpts(input starlark.Value, opts StarlarkUnmarshalOpts) (any, error) {
 	switch v := input.(type) {
 	case starlark.NoneType:
 		return nil, nil
 	case starlark.Bool:
 		return bool(v), nil
 	case starlark.Int:
-		var result, _ = v.Int64()
-		return result, nil
+		result, ok := v.Int64()
+		if ok {
+			return result, nil
+		}
+
+		uresult, ok := v.Uint64()
+		if ok {
+			return uresult, nil
+		}
+
+		if opts.BigInts {
+			return v.BigInt(), nil
+		}
+
+		return nil, fmt.Errorf("Integer %s is out of range", v)
 	case starlark.Float:
 		return float64(v), nil
 	case starlark.String:
 		return string(v), nil
-	case *starlark.List:
-		length := v.Len()
+	case starlark.Bytes:
+		return []byte(v), nil
+	case *starlark.Set:
+		elems := make([]starlark.Value, 0, v.Len())
+
+		iter := v.Iterate()
+		defer iter.Done()
+
+		var elem starlark.Value
+		for iter.Next(&elem) {
+			elems = append(elems, elem)
+		}
+
+		slices.SortStableFunc(elems, func(a starlark.Value, b starlark.Value) int {
+			return strings.Compare(a.String(), b.String())
+		})
+
+		return StarlarkUnmarshalWithOpts(starlark.Tuple(elems), opts)
+	case *starlark.List, starlark.Tuple:
+		list := v.(starlark.Indexable)
+		length := list.Len()
 		result := make([]any, length)
 
-		// Iterate over the Starlark List
+		// Iterate over the Starlark List or Tuple
 		for i := 0; i < length; i++ {
-			value, err := StarlarkUnmarshal(v.Index(i))
+			value, err := StarlarkUnmarshalWithOpts(list.Index(i), opts)
 			if err != nil {
 				return nil, err
 			}
@@ -234,10 +825,12 @@ func StarlarkUnmarshal(input starlark.Value) (any, error) {
 		}
 
 		return result, nil
-	case *starlark.Dict:
+	case *starlarkObject:
+		return StarlarkUnmarshalWithOpts(v.d, opts)
+	case starlark.IterableMapping:
 		result := make(map[string]any)
 
-		// Iterate over the Starlark Dict
+		// Iterate over the Starlark Dict or other mapping
 		for _, kv := range v.Items() {
 			dictKey, dictValue := kv[0], kv[1]
 
@@ -246,7 +839,7 @@ func StarlarkUnmarshal(input starlark.Value) (any, error) {
 				return nil, fmt.Errorf("Only string keys are supported, found %s", dictKey.Type())
 			}
 
-			value, err := StarlarkUnmarshal(dictValue)
+			value, err := StarlarkUnmarshalWithOpts(dictValue, opts)
 			if err != nil {
 				return nil, err
 			}
@@ -259,3 +852,327 @@ func StarlarkUnmarshal(input starlark.Value) (any, error) {
 		return nil, fmt.Errorf("Unsupported type: %T", v)
 	}
 }
+
+// StarlarkUnmarshalOpts represents options that change how values are converted by StarlarkUnmarshalWithOpts and
+// StarlarkUnmarshalToWithOpts.
+type StarlarkUnmarshalOpts struct {
+	IgnoreUnknownKeys bool // Skip dict keys not matching any struct field rather than failing.
+	BigInts           bool // Return integers fitting in neither int64 nor uint64 as *big.Int rather than failing.
+}
+
+// StarlarkUnmarshalTo converts a Starlark value into the Go value pointed to by target, mirroring StarlarkMarshal.
+// Struct fields are set from the dict (or object) keys matching their "json" tag, or their name if not set, with the
+// fields of anonymous (embedded) structs read from the same dict. Lists and tuples are converted to slices and
+// arrays, bytes to byte slices, dicts to maps with string keys, and None to the zero value. Pointers are allocated
+// as needed, integers are checked to fit in the target type and strings are converted with encoding.TextUnmarshaler
+// if implemented. Values implementing StarlarkUnmarshaler are set by its UnmarshalStarlark method instead.
+// Times and durations are parsed from the strings StarlarkMarshal converts them to, with None giving a zero time.
+// Dict keys not matching any struct field are reported, use StarlarkUnmarshalToWithOpts to ignore them.
+// Errors include the path of the value that failed to convert.
+func StarlarkUnmarshalTo(input starlark.Value, target any) error {
+	return StarlarkUnmarshalToWithOpts(input, target, StarlarkUnmarshalOpts{})
+}
+
+// StarlarkUnmarshalToWithOpts converts a Starlark value into the Go value pointed to by target using the provided
+// options, as described in StarlarkUnmarshalTo.
+func StarlarkUnmarshalToWithOpts(input starlark.Value, target any, opts StarlarkUnmarshalOpts) error {
+	v := reflect.ValueOf(target)
+	if v.Kind() != reflect.Pointer || v.IsNil() {
+		return fmt.Errorf("Target must be a non-nil pointer, found %T", target)
+	}
+
+	return starlarkUnmarshalTo(input, v.Elem(), "", opts)
+}
+
+// starlarkPathError returns an error for the value at path, which is empty for the top-level value.
+func starlarkPathError(path string, err error) error {
+	if path == "" {
+		return err
+	}
+
+	return fmt.Errorf("Field %q: %w", path, err)
+}
+
+// starlarkTypeError returns an error for a Starlark value that can't be converted to a Go value of type t.
+func starlarkTypeError(path string, input starlark.Value, t reflect.Type) error {
+	return starlarkPathError(path, fmt.Errorf("Cannot convert %s to %v", input.Type(), t))
+}
+
+// starlarkDictItems returns the items of a mapping (such as a dict or an object), or false if input isn't one.
+func starlarkDictItems(input starlark.Value) ([]starlark.Tuple, bool) {
+	m, ok := input.(starlark.IterableMapping)
+	if !ok {
+		return nil, false
+	}
+
+	return m.Items(), true
+}
+
+// starlarkUnmarshalFields returns the index paths of the struct fields of type t by key, with the fields of
+// anonymous structs flattened in. As with starlarkMarshal, later fields take precedence over earlier ones.
+func starlarkUnmarshalFields(t reflect.Type, index []int, fields map[string][]int) {
+	for _, field := range starlarkStructFields(t) {
+		fieldIndex := append(slices.Clone(index), field.index)
+
+		fieldType := t.Field(field.index).Type
+		if field.anonymous && fieldType.Kind() == reflect.Struct {
+			starlarkUnmarshalFields(fieldType, fieldIndex, fields)
+			continue
+		}
+
+		fields[field.name] = fieldIndex
+	}
+}
+
+// starlarkUnmarshalTo sets v, which must be settable, from input. The path is the location of v within the
+// top-level value and is used in errors.
+func starlarkUnmarshalTo(input starlark.Value, v reflect.Value, path string, opts StarlarkUnmarshalOpts) error {
+	t := v.Type()
+
+	if input == starlark.None {
+		v.SetZero()
+		return nil
+	}
+
+	// Types with their own Starlark representation convert themselves.
+	if t.Kind() != reflect.Pointer && reflect.PointerTo(t).Implements(reflect.TypeFor[StarlarkUnmarshaler]()) {
+		err := v.Addr().Interface().(StarlarkUnmarshaler).UnmarshalStarlark(input)
+		if err != nil {
+			return starlarkPathError(path, fmt.Errorf("Failed unmarshalling %v from Starlark: %w", t, err))
+		}
+
+		return nil
+	}
+
+	// Starlark values are kept as is.
+	if t.Implements(reflect.TypeFor[starlark.Value]()) {
+		if !reflect.TypeOf(input).AssignableTo(t) {
+			return starlarkTypeError(path, input, t)
+		}
+
+		v.Set(reflect.ValueOf(input))
+		return nil
+	}
+
+	switch t {
+	case reflect.TypeFor[json.RawMessage]():
+		value, err := StarlarkUnmarshalWithOpts(input, opts)
+		if err != nil {
+			return starlarkPathError(path, err)
+		}
+
+		data, err := json.Marshal(value)
+		if err != nil {
+			return starlarkPathError(path, err)
+		}
+
+		v.SetBytes(data)
+		return nil
+	case reflect.TypeFor[time.Duration]():
+		s, ok := input.(starlark.String)
+		if !ok {
+			return starlarkTypeError(path, input, t)
+		}
+
+		duration, err := time.ParseDuration(string(s))
+		if err != nil {
+			return starlarkPathError(path, err)
+		}
+
+		v.SetInt(int64(duration))
+		return nil
+	case reflect.TypeFor[json.Number]():
+		switch n := input.(type) {
+		case starlark.Int:
+			v.SetString(n.String())
+		case starlark.Float:
+			v.SetString(strconv.FormatFloat(float64(n), 'g', -1, 64))
+		default:
+			return starlarkTypeError(path, input, t)
+		}
+
+		return nil
+	}
+
+	if t.Kind() != reflect.Pointer && reflect.PointerTo(t).Implements(reflect.TypeFor[encoding.TextUnmarshaler]()) {
+		s, ok := input.(starlark.String)
+		if !ok {
+			return starlarkTypeError(path, input, t)
+		}
+
+		err := v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s))
+		if err != nil {
+			return starlarkPathError(path, fmt.Errorf("Failed unmarshalling %v from text: %w", t, err))
+		}
+
+		return nil
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		s, ok := input.(starlark.String)
+		if !ok {
+			return starlarkTypeError(path, input, t)
+		}
+
+		v.SetString(string(s))
+	case reflect.Bool:
+		b, ok := input.(starlark.Bool)
+		if !ok {
+			return starlarkTypeError(path, input, t)
+		}
+
+		v.SetBool(bool(b))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, ok := input.(starlark.Int)
+		if !ok {
+			return starlarkTypeError(path, input, t)
+		}
+
+		i64, ok := i.Int64()
+		if !ok || v.OverflowInt(i64) {
+			return starlarkPathError(path, fmt.Errorf("Value %s overflows %v", i, t))
+		}
+
+		v.SetInt(i64)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		i, ok := input.(starlark.Int)
+		if !ok {
+			return starlarkTypeError(path, input, t)
+		}
+
+		u64, ok := i.Uint64()
+		if !ok || v.OverflowUint(u64) {
+			return starlarkPathError(path, fmt.Errorf("Value %s overflows %v", i, t))
+		}
+
+		v.SetUint(u64)
+	case reflect.Float32, reflect.Float64:
+		var f float64
+
+		switch n := input.(type) {
+		case starlark.Float:
+			f = float64(n)
+		case starlark.Int:
+			f = float64(n.Float())
+		default:
+			return starlarkTypeError(path, input, t)
+		}
+
+		if v.OverflowFloat(f) {
+			return starlarkPathError(path, fmt.Errorf("Value %v overflows %v", f, t))
+		}
+
+		v.SetFloat(f)
+	case reflect.Interface:
+		if t.NumMethod() != 0 {
+			return starlarkTypeError(path, input, t)
+		}
+
+		value, err := StarlarkUnmarshalWithOpts(input, opts)
+		if err != nil {
+			return starlarkPathError(path, err)
+		}
+
+		v.Set(reflect.ValueOf(value))
+	case reflect.Pointer:
+		if v.IsNil() {
+			v.Set(reflect.New(t.Elem()))
+		}
+
+		return starlarkUnmarshalTo(input, v.Elem(), path, opts)
+	case reflect.Slice, reflect.Array:
+		// Bytes are copied as is into byte slices.
+		b, ok := input.(starlark.Bytes)
+		if ok && t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8 {
+			v.SetBytes([]byte(b))
+			return nil
+		}
+
+		list, ok := input.(starlark.Indexable)
+		if !ok || input.Type() == "string" || input.Type() == "bytes" {
+			return starlarkTypeError(path, input, t)
+		}
+
+		length := list.Len()
+		if t.Kind() == reflect.Array && length != t.Len() {
+			return starlarkPathError(path, fmt.Errorf("Expected %d elements, found %d", t.Len(), length))
+		}
+
+		if t.Kind() == reflect.Slice {
+			v.Set(reflect.MakeSlice(t, length, length))
+		}
+
+		for i := 0; i < length; i++ {
+			err := starlarkUnmarshalTo(list.Index(i), v.Index(i), fmt.Sprintf("%s[%d]", path, i), opts)
+			if err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return starlarkPathError(path, fmt.Errorf("Only string keys are supported, found %s", t.Key().Kind()))
+		}
+
+		items, ok := starlarkDictItems(input)
+		if !ok {
+			return starlarkTypeError(path, input, t)
+		}
+
+		m := reflect.MakeMapWithSize(t, len(items))
+		for _, item := range items {
+			key, ok := starlark.AsString(item[0])
+			if !ok {
+				return starlarkPathError(path, fmt.Errorf("Only string keys are supported, found %s", item[0].Type()))
+			}
+
+			elem := reflect.New(t.Elem()).Elem()
+			err := starlarkUnmarshalTo(item[1], elem, fmt.Sprintf("%s[%s]", path, key), opts)
+			if err != nil {
+				return err
+			}
+
+			m.SetMapIndex(reflect.ValueOf(key).Convert(t.Key()), elem)
+		}
+
+		v.Set(m)
+	case reflect.Struct:
+		items, ok := starlarkDictItems(input)
+		if !ok {
+			return starlarkTypeError(path, input, t)
+		}
+
+		fields := map[string][]int{}
+		starlarkUnmarshalFields(t, nil, fields)
+
+		for _, item := range items {
+			key, ok := starlark.AsString(item[0])
+			if !ok {
+				return starlarkPathError(path, fmt.Errorf("Only string keys are supported, found %s", item[0].Type()))
+			}
+
+			fieldPath := key
+			if path != "" {
+				fieldPath = path + "." + key
+			}
+
+			index, found := fields[key]
+			if !found {
+				if opts.IgnoreUnknownKeys {
+					continue
+				}
+
+				return fmt.Errorf("Unknown field %q", fieldPath)
+			}
+
+			err := starlarkUnmarshalTo(item[1], v.FieldByIndex(index), fieldPath, opts)
+			if err != nil {
+				return err
+			}
+		}
+	default:
+		return starlarkTypeError(path, input, t)
+	}
+
+	return nil
+}
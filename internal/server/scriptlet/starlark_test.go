@@ -1,12 +1,24 @@
 package scriptlet
 
 import (
+	"encoding"
+	"encoding/json"
 	"fmt"
+	"math"
+	"math/big"
+	"net"
+	"reflect"
+	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"go.starlark.net/starlark"
+	"go.starlark.net/syntax"
+
+	"github.com/lxc/incus/v6/shared/api"
 )
 
 type starlarkMarshalTest struct {
@@ -33,6 +45,15 @@ var _ fmt.Stringer = DummyStringer(0)
 
 func (DummyStringer) String() string { return "(DummyStringer)" }
 
+// frozen freezes v so it can be compared with the frozen values returned by StarlarkMarshal.
+func frozen(v starlark.Value) starlark.Value {
+	if v != nil {
+		v.Freeze()
+	}
+
+	return v
+}
+
 func TestStarlarkMarshal(t *testing.T) {
 	type DummyEmbeddedStruct struct {
 		A string
@@ -77,6 +98,9 @@ func TestStarlarkMarshal(t *testing.T) {
 	}, {
 		from: uint64(1),
 		to:   starlark.MakeInt(1),
+	}, {
+		from: uintptr(1),
+		to:   starlark.MakeInt(1),
 	}, {
 		from: float32(0.5),
 		to:   starlark.Float(0.5),
@@ -158,7 +182,7 @@ func TestStarlarkMarshal(t *testing.T) {
 		from: struct{ fmt.Stringer }{Stringer: DummyStringer(0xbaa)},
 		to: func() starlark.Value {
 			d1 := starlark.NewDict(1)
-			assert.NoError(t, d1.SetKey(starlark.String("Stringer"), starlark.MakeInt(0xbaa)))
+			assert.NoError(t, d1.SetKey(starlark.String("Stringer"), starlark.String("(DummyStringer)")))
 			ret := &starlarkObject{d: d1}
 
 			return ret
@@ -169,7 +193,7 @@ func TestStarlarkMarshal(t *testing.T) {
 		}{Stringer: DummyStringer(0xbaa)},
 		to: func() starlark.Value {
 			d1 := starlark.NewDict(1)
-			assert.NoError(t, d1.SetKey(starlark.String("foo"), starlark.MakeInt(0xbaa)))
+			assert.NoError(t, d1.SetKey(starlark.String("foo"), starlark.String("(DummyStringer)")))
 			ret := &starlarkObject{d: d1}
 
 			return ret
@@ -207,7 +231,1156 @@ func TestStarlarkMarshal(t *testing.T) {
 				assert.True(t, strings.HasPrefix(err.Error(), scenario.errPrefix))
 			}
 
-			assert.Equal(t, scenario.to, sv)
+			assert.Equal(t, frozen(scenario.to), sv)
 		})
 	}
 }
+
+func TestStarlarkMarshalSkipNilPointers(t *testing.T) {
+	name := "foo"
+
+	type pointerStruct struct {
+		Name   *string `json:"name"`
+		Parent *string `json:"parent"`
+	}
+
+	input := pointerStruct{Name: &name}
+
+	// By default nil pointers are set to None.
+	sv, err := StarlarkMarshal(input)
+	assert.NoError(t, err)
+
+	d1 := starlark.NewDict(2)
+	assert.NoError(t, d1.SetKey(starlark.String("name"), starlark.String("foo")))
+	assert.NoError(t, d1.SetKey(starlark.String("parent"), starlark.None))
+	assert.Equal(t, frozen(&starlarkObject{d: d1, typeName: "pointerStruct"}), sv)
+
+	// Nil pointer fields are left out when skipping them.
+	sv, err = StarlarkMarshalWithOpts(input, StarlarkMarshalOpts{SkipNilPointers: true})
+	assert.NoError(t, err)
+
+	d2 := starlark.NewDict(1)
+	assert.NoError(t, d2.SetKey(starlark.String("name"), starlark.String("foo")))
+	assert.Equal(t, frozen(&starlarkObject{d: d2, typeName: "pointerStruct"}), sv)
+}
+
+func TestStarlarkMarshalJSONTags(t *testing.T) {
+	type nestedStruct struct {
+		Name string `json:"name,omitempty"`
+	}
+
+	type tagStruct struct {
+		Name     string            `json:"name"`
+		Secret   string            `json:"-"`
+		Dash     string            `json:"-,"`
+		Comment  string            `json:"comment,omitempty"`
+		Count    int               `json:"count,omitempty"`
+		Enabled  bool              `json:"enabled,omitempty"`
+		Parent   *string           `json:"parent,omitempty"`
+		Data     any               `json:"data,omitempty"`
+		Tags     []string          `json:"tags,omitempty"`
+		Config   map[string]string `json:"config,omitempty"`
+		Nested   nestedStruct      `json:"nested,omitempty"`
+		Required string            `json:"required"`
+	}
+
+	input := tagStruct{Name: "foo", Secret: "hunter2", Dash: "bar", Count: 1}
+
+	// Fields tagged "-" are never included, while fields named "-" are.
+	sv, err := StarlarkMarshal(input)
+	require.NoError(t, err)
+
+	obj := sv.(*starlarkObject)
+	assert.NotContains(t, obj.AttrNames(), "Secret")
+	assert.Equal(t, []string{"name", "-", "comment", "count", "enabled", "parent", "data", "tags", "config", "nested", "required"}, obj.AttrNames())
+	assert.NotContains(t, obj.String(), "hunter2")
+
+	// Empty fields with "omitempty" are left out when requested, structs are never empty.
+	sv, err = StarlarkMarshalWithOpts(input, StarlarkMarshalOpts{OmitEmpty: true})
+	require.NoError(t, err)
+
+	obj = sv.(*starlarkObject)
+	assert.Equal(t, []string{"name", "-", "count", "nested", "required"}, obj.AttrNames())
+	assert.NotContains(t, obj.String(), "hunter2")
+
+	nested, err := obj.Attr("nested")
+	require.NoError(t, err)
+	assert.Empty(t, nested.(*starlarkObject).AttrNames())
+}
+
+func TestStarlarkObject(t *testing.T) {
+	type EmbeddedStruct struct {
+		Project string `json:"project"`
+		Type    string `json:"type"`
+	}
+
+	type objectStruct struct {
+		Name string `json:"name"`
+		EmbeddedStruct
+		Config map[string]string `json:"config"`
+		Size   int               `json:"size"`
+	}
+
+	sv, err := StarlarkMarshal(objectStruct{
+		Name:           "c1",
+		EmbeddedStruct: EmbeddedStruct{Project: "default", Type: "container"},
+		Config:         map[string]string{"user.b": "2", "user.a": "1"},
+		Size:           3,
+	})
+	require.NoError(t, err)
+
+	// Fields are in declaration order with the embedded ones in place, and map keys are sorted.
+	obj := sv.(*starlarkObject)
+	assert.Equal(t, []string{"name", "project", "type", "config", "size"}, obj.AttrNames())
+	assert.Equal(t, `objectStruct(name="c1", project="default", type="container", config={"user.a": "1", "user.b": "2"}, size=3)`, obj.String())
+
+	// Objects can be iterated over and converted to dicts.
+	globals, err := starlark.ExecFile(&starlark.Thread{}, "test", `
+keys = [k for k in obj]
+d = dict(obj)
+n = len(obj)
+has_name = "name" in obj
+name = obj["name"]
+text = str(obj.config)
+`, starlark.StringDict{"obj": sv})
+	require.NoError(t, err)
+
+	assert.Equal(t, `["name", "project", "type", "config", "size"]`, globals["keys"].String())
+	assert.Equal(t, `{"name": "c1", "project": "default", "type": "container", "config": {"user.a": "1", "user.b": "2"}, "size": 3}`, globals["d"].String())
+	assert.Equal(t, starlark.MakeInt(5), globals["n"])
+	assert.Equal(t, starlark.True, globals["has_name"])
+	assert.Equal(t, starlark.String("c1"), globals["name"])
+
+	// Objects without a type name, such as anonymous structs, are rendered as structs.
+	sv, err = StarlarkMarshal(struct {
+		Name string `json:"name"`
+	}{Name: "c1"})
+	require.NoError(t, err)
+	assert.Equal(t, `struct(name="c1")`, sv.String())
+}
+
+func TestStarlarkObjectSetField(t *testing.T) {
+	type requestStruct struct {
+		Name   string            `json:"name"`
+		Config map[string]string `json:"config"`
+	}
+
+	// Mutable values can be changed, as long as the fields exist.
+	// This includes the dicts and lists they contain.
+	sv, err := StarlarkMarshalWithOpts(requestStruct{Name: "c1", Config: map[string]string{"limits.cpu": "1"}}, StarlarkMarshalOpts{Mutable: true})
+	require.NoError(t, err)
+
+	_, err = starlark.ExecFile(&starlark.Thread{}, "test", `
+req.name = "c2"
+req.config["limits.cpu"] = "2"
+`, starlark.StringDict{"req": sv})
+	require.NoError(t, err)
+
+	_, err = starlark.ExecFile(&starlark.Thread{}, "test", `req.description = "foo"`, starlark.StringDict{"req": sv})
+	assert.ErrorContains(t, err, `Invalid field "description", must be one of: name, config`)
+
+	// The changes are picked up when unmarshalling.
+	var result requestStruct
+	require.NoError(t, StarlarkUnmarshalTo(sv, &result))
+	assert.Equal(t, requestStruct{Name: "c2", Config: map[string]string{"limits.cpu": "2"}}, result)
+
+	value, err := StarlarkUnmarshal(sv)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"name": "c2", "config": map[string]any{"limits.cpu": "2"}}, value)
+
+	// Frozen objects can't be changed.
+	sv, err = StarlarkMarshal(requestStruct{Name: "c1"})
+	require.NoError(t, err)
+
+	_, err = starlark.ExecFile(&starlark.Thread{}, "test", `req.name = "c2"`, starlark.StringDict{"req": sv})
+	assert.ErrorContains(t, err, `Cannot set field "name" of requestStruct`)
+}
+
+func TestStarlarkObjectAttr(t *testing.T) {
+	type InstancePlacement struct {
+		Name        string `json:"name"`
+		Description string `json:"description"`
+		Project     string `json:"project"`
+		Profiles    string `json:"profiles"`
+	}
+
+	input := InstancePlacement{Name: "c1", Description: "foo"}
+
+	sv, err := StarlarkMarshal(input)
+	require.NoError(t, err)
+
+	// Missing fields fail, suggesting the closest field names.
+	_, err = starlark.ExecFile(&starlark.Thread{}, "test", `x = obj.descrption`, starlark.StringDict{"obj": sv})
+	assert.ErrorContains(t, err, `InstancePlacement has no field "descrption" (did you mean "description"?)`)
+
+	_, err = starlark.ExecFile(&starlark.Thread{}, "test", `x = obj.profile`, starlark.StringDict{"obj": sv})
+	assert.ErrorContains(t, err, `InstancePlacement has no field "profile" (did you mean "profiles"?)`)
+
+	_, err = starlark.ExecFile(&starlark.Thread{}, "test", `x = obj.location`, starlark.StringDict{"obj": sv})
+	assert.ErrorContains(t, err, `InstancePlacement has no field "location"`)
+	assert.NotContains(t, err.Error(), "did you mean")
+
+	// At most three suggestions are made, closest first and then in field order.
+	assert.Equal(t, []string{"cpus", "gpu"}, starlarkSuggestNames("cpu", []string{"mem", "cpus", "cpu_limit", "gpu"}))
+	assert.Equal(t, []string{"a", "b", "abd"}, starlarkSuggestNames("ab", []string{"xy", "a", "b", "abd", "abc"}))
+	assert.Equal(t, []string{}, starlarkSuggestNames("ab", nil))
+
+	// With MissingAttrNone, missing fields follow the Starlark convention so hasattr and getattr work.
+	sv, err = StarlarkMarshalWithOpts(input, StarlarkMarshalOpts{MissingAttrNone: true})
+	require.NoError(t, err)
+
+	globals, err := starlark.ExecFile(&starlark.Thread{}, "test", `
+has_name = hasattr(obj, "name")
+has_location = hasattr(obj, "location")
+location = getattr(obj, "location", "default")
+description = getattr(obj, "description", "default")
+`, starlark.StringDict{"obj": sv})
+	require.NoError(t, err)
+
+	assert.Equal(t, starlark.True, globals["has_name"])
+	assert.Equal(t, starlark.False, globals["has_location"])
+	assert.Equal(t, starlark.String("default"), globals["location"])
+	assert.Equal(t, starlark.String("foo"), globals["description"])
+}
+
+func TestLevenshteinDistance(t *testing.T) {
+	tests := []struct {
+		a        string
+		b        string
+		distance int
+	}{
+		{a: "", b: "", distance: 0},
+		{a: "name", b: "", distance: 4},
+		{a: "descrption", b: "description", distance: 1},
+		{a: "nmae", b: "name", distance: 2},
+		{a: "kitten", b: "sitting", distance: 3},
+		{a: "héllo", b: "hello", distance: 1},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.distance, levenshteinDistance(test.a, test.b), "%q -> %q", test.a, test.b)
+		assert.Equal(t, test.distance, levenshteinDistance(test.b, test.a), "%q -> %q", test.b, test.a)
+	}
+}
+
+func TestStarlarkMarshalInterface(t *testing.T) {
+	type nestedStruct struct {
+		Name string `json:"name"`
+	}
+
+	type interfaceStruct struct {
+		Data any `json:"data"`
+	}
+
+	nested := starlark.NewDict(1)
+	assert.NoError(t, nested.SetKey(starlark.String("name"), starlark.String("foo")))
+
+	// Interface fields are marshalled according to the value they hold, and nil interfaces become None.
+	for _, scenario := range []struct {
+		data any
+		to   starlark.Value
+	}{
+		{data: "foo", to: starlark.String("foo")},
+		{data: nestedStruct{Name: "foo"}, to: &starlarkObject{d: nested, typeName: "nestedStruct"}},
+		{data: &nestedStruct{Name: "foo"}, to: &starlarkObject{d: nested, typeName: "nestedStruct"}},
+		{data: nil, to: starlark.None},
+	} {
+		sv, err := StarlarkMarshal(interfaceStruct{Data: scenario.data})
+		require.NoError(t, err)
+
+		d := starlark.NewDict(1)
+		assert.NoError(t, d.SetKey(starlark.String("data"), scenario.to))
+		assert.Equal(t, frozen(&starlarkObject{d: d, typeName: "interfaceStruct"}), sv, "%v", scenario.data)
+	}
+}
+
+func TestStarlarkMarshalSkipNilElements(t *testing.T) {
+	type elemStruct struct {
+		Name string `json:"name"`
+	}
+
+	input := []*elemStruct{nil, {Name: "foo"}}
+
+	d := starlark.NewDict(1)
+	assert.NoError(t, d.SetKey(starlark.String("name"), starlark.String("foo")))
+	elem := &starlarkObject{d: d, typeName: "elemStruct"}
+
+	// By default nil elements are kept as None so the list has the same length as the slice.
+	sv, err := StarlarkMarshal(input)
+	assert.NoError(t, err)
+	assert.Equal(t, frozen(starlark.NewList([]starlark.Value{starlark.None, elem})), sv)
+
+	// Nil elements are left out when skipping them.
+	sv, err = StarlarkMarshalWithOpts(input, StarlarkMarshalOpts{SkipNilElements: true})
+	assert.NoError(t, err)
+	assert.Equal(t, frozen(starlark.NewList([]starlark.Value{elem})), sv)
+
+	// This also applies to nil interface elements.
+	sv, err = StarlarkMarshalWithOpts([]any{nil, "bar"}, StarlarkMarshalOpts{SkipNilElements: true})
+	assert.NoError(t, err)
+	assert.Equal(t, frozen(starlark.NewList([]starlark.Value{starlark.String("bar")})), sv)
+}
+
+func TestStarlarkMarshalKeyTransform(t *testing.T) {
+	type EmbeddedStruct struct {
+		Location string `json:"Location"`
+	}
+
+	type keyStruct struct {
+		EmbeddedStruct
+
+		Name   string            `json:"Name"`
+		Config map[string]string `json:"config"`
+	}
+
+	input := keyStruct{
+		EmbeddedStruct: EmbeddedStruct{Location: "node1"},
+		Name:           "foo",
+		Config:         map[string]string{"Limits.CPU": "2"},
+	}
+
+	// By default keys are used as they are.
+	sv, err := StarlarkMarshal(input)
+	require.NoError(t, err)
+
+	config := starlark.NewDict(1)
+	assert.NoError(t, config.SetKey(starlark.String("Limits.CPU"), starlark.String("2")))
+
+	d1 := starlark.NewDict(3)
+	assert.NoError(t, d1.SetKey(starlark.String("Location"), starlark.String("node1")))
+	assert.NoError(t, d1.SetKey(starlark.String("Name"), starlark.String("foo")))
+	assert.NoError(t, d1.SetKey(starlark.String("config"), config))
+	assert.Equal(t, frozen(&starlarkObject{d: d1, typeName: "keyStruct"}), sv)
+
+	// The transform applies to struct fields, including those of embedded structs, and to map keys.
+	sv, err = StarlarkMarshalWithOpts(input, StarlarkMarshalOpts{KeyTransform: strings.ToLower})
+	require.NoError(t, err)
+
+	config = starlark.NewDict(1)
+	assert.NoError(t, config.SetKey(starlark.String("limits.cpu"), starlark.String("2")))
+
+	d2 := starlark.NewDict(3)
+	assert.NoError(t, d2.SetKey(starlark.String("location"), starlark.String("node1")))
+	assert.NoError(t, d2.SetKey(starlark.String("name"), starlark.String("foo")))
+	assert.NoError(t, d2.SetKey(starlark.String("config"), config))
+	assert.Equal(t, frozen(&starlarkObject{d: d2, typeName: "keyStruct"}), sv)
+}
+
+func TestStarlarkMarshalUintptr(t *testing.T) {
+	type uintptrStruct struct {
+		Name string  `json:"name"`
+		Ptr  uintptr `json:"ptr"`
+	}
+
+	input := uintptrStruct{Name: "foo", Ptr: uintptr(4096)}
+
+	d := starlark.NewDict(2)
+	assert.NoError(t, d.SetKey(starlark.String("name"), starlark.String("foo")))
+	assert.NoError(t, d.SetKey(starlark.String("ptr"), starlark.MakeInt(4096)))
+
+	// The field is marshalled as an unsigned integer, the same way every time.
+	for i := 0; i < 2; i++ {
+		sv, err := StarlarkMarshal(input)
+		require.NoError(t, err)
+		assert.Equal(t, frozen(&starlarkObject{d: d, typeName: "uintptrStruct"}), sv)
+	}
+
+	// Map keys of type uintptr can be stringified too.
+	sv, err := StarlarkMarshalWithOpts(map[uintptr]string{2: "b", 1: "a"}, StarlarkMarshalOpts{StringifyMapKeys: true})
+	require.NoError(t, err)
+
+	d = starlark.NewDict(2)
+	assert.NoError(t, d.SetKey(starlark.String("1"), starlark.String("a")))
+	assert.NoError(t, d.SetKey(starlark.String("2"), starlark.String("b")))
+	assert.Equal(t, frozen(d), sv)
+}
+
+type dummyStringer struct {
+	ID int
+}
+
+func (d dummyStringer) String() string {
+	return fmt.Sprintf("id-%d", d.ID)
+}
+
+func TestStarlarkMarshalStringifyMapKeys(t *testing.T) {
+	opts := StarlarkMarshalOpts{StringifyMapKeys: true}
+
+	// Integer keys are converted to their string form and ordered by value.
+	sv, err := StarlarkMarshalWithOpts(map[int]string{10: "ten", 2: "two", -1: "minus one"}, opts)
+	require.NoError(t, err)
+
+	d1 := starlark.NewDict(3)
+	assert.NoError(t, d1.SetKey(starlark.String("-1"), starlark.String("minus one")))
+	assert.NoError(t, d1.SetKey(starlark.String("2"), starlark.String("two")))
+	assert.NoError(t, d1.SetKey(starlark.String("10"), starlark.String("ten")))
+	assert.Equal(t, frozen(d1), sv)
+	assert.Equal(t, []starlark.Value{starlark.String("-1"), starlark.String("2"), starlark.String("10")}, sv.(*starlark.Dict).Keys())
+
+	// Bool keys are ordered with false first.
+	sv, err = StarlarkMarshalWithOpts(map[bool]int{true: 1, false: 0}, opts)
+	require.NoError(t, err)
+	assert.Equal(t, []starlark.Value{starlark.String("false"), starlark.String("true")}, sv.(*starlark.Dict).Keys())
+
+	// Stringer keys use their String method and are ordered by it.
+	sv, err = StarlarkMarshalWithOpts(map[dummyStringer]string{{ID: 2}: "b", {ID: 1}: "a"}, opts)
+	require.NoError(t, err)
+	assert.Equal(t, []starlark.Value{starlark.String("id-1"), starlark.String("id-2")}, sv.(*starlark.Dict).Keys())
+
+	// Nested maps are converted too.
+	sv, err = StarlarkMarshalWithOpts(map[string]map[uint8]string{"a": {1: "b"}}, opts)
+	require.NoError(t, err)
+
+	nested := starlark.NewDict(1)
+	assert.NoError(t, nested.SetKey(starlark.String("1"), starlark.String("b")))
+
+	d2 := starlark.NewDict(1)
+	assert.NoError(t, d2.SetKey(starlark.String("a"), nested))
+	assert.Equal(t, frozen(d2), sv)
+
+	// Other key types are still rejected.
+	_, err = StarlarkMarshalWithOpts(map[float64]string{1.5: "a"}, opts)
+	assert.EqualError(t, err, "Only string keys are supported, found float64")
+}
+
+type dummyTextMarshaler struct {
+	Value string
+}
+
+var _ encoding.TextMarshaler = dummyTextMarshaler{}
+
+func (d dummyTextMarshaler) MarshalText() ([]byte, error) {
+	return []byte("text:" + d.Value), nil
+}
+
+func TestStarlarkMarshalTextMarshaler(t *testing.T) {
+	type textStruct struct {
+		Text    dummyTextMarshaler  `json:"text"`
+		TextPtr *dummyTextMarshaler `json:"text_ptr"`
+		Address net.IP              `json:"address"`
+	}
+
+	sv, err := StarlarkMarshal(textStruct{
+		Text:    dummyTextMarshaler{Value: "foo"},
+		Address: net.ParseIP("192.0.2.1"),
+	})
+	assert.NoError(t, err)
+
+	d1 := starlark.NewDict(3)
+	assert.NoError(t, d1.SetKey(starlark.String("text"), starlark.String("text:foo")))
+	assert.NoError(t, d1.SetKey(starlark.String("text_ptr"), starlark.None))
+	assert.NoError(t, d1.SetKey(starlark.String("address"), starlark.String("192.0.2.1")))
+	assert.Equal(t, frozen(&starlarkObject{d: d1, typeName: "textStruct"}), sv)
+}
+
+type dummyPointerTextMarshaler struct {
+	Value string
+}
+
+var _ encoding.TextMarshaler = &dummyPointerTextMarshaler{}
+
+func (d *dummyPointerTextMarshaler) MarshalText() ([]byte, error) {
+	return []byte("ptr:" + d.Value), nil
+}
+
+func TestStarlarkMarshalStringer(t *testing.T) {
+	_, network, err := net.ParseCIDR("10.0.0.0/24")
+	require.NoError(t, err)
+
+	hwaddr, err := net.ParseMAC("00:16:3e:00:00:01")
+	require.NoError(t, err)
+
+	type stringerStruct struct {
+		Addresses  []net.IP                  `json:"addresses"`
+		Network    net.IPNet                 `json:"network"`
+		NetworkPtr *net.IPNet                `json:"network_ptr"`
+		HWAddr     net.HardwareAddr          `json:"hwaddr"`
+		Custom     dummyStringer             `json:"custom"`
+		Text       dummyPointerTextMarshaler `json:"text"`
+		Number     DummyStringer             `json:"number"`
+	}
+
+	sv, err := StarlarkMarshal(stringerStruct{
+		Addresses:  []net.IP{net.ParseIP("10.0.0.1"), net.ParseIP("fd00::1")},
+		Network:    *network,
+		NetworkPtr: network,
+		HWAddr:     hwaddr,
+		Custom:     dummyStringer{ID: 1},
+		Text:       dummyPointerTextMarshaler{Value: "foo"},
+		Number:     DummyStringer(3),
+	})
+	require.NoError(t, err)
+
+	// Addresses are strings rather than lists of bytes, and methods with pointer receivers are used too.
+	d1 := starlark.NewDict(7)
+	assert.NoError(t, d1.SetKey(starlark.String("addresses"), starlark.NewList([]starlark.Value{starlark.String("10.0.0.1"), starlark.String("fd00::1")})))
+	assert.NoError(t, d1.SetKey(starlark.String("network"), starlark.String("10.0.0.0/24")))
+	assert.NoError(t, d1.SetKey(starlark.String("network_ptr"), starlark.String("10.0.0.0/24")))
+	assert.NoError(t, d1.SetKey(starlark.String("hwaddr"), starlark.String("00:16:3e:00:00:01")))
+	assert.NoError(t, d1.SetKey(starlark.String("custom"), starlark.String("id-1")))
+	assert.NoError(t, d1.SetKey(starlark.String("text"), starlark.String("ptr:foo")))
+
+	assert.NoError(t, d1.SetKey(starlark.String("number"), starlark.String("(DummyStringer)")))
+	assert.Equal(t, frozen(&starlarkObject{d: d1, typeName: "stringerStruct"}), sv)
+
+	// Top-level values are converted the same way.
+	sv, err = StarlarkMarshal(net.ParseIP("10.0.0.1"))
+	require.NoError(t, err)
+	assert.Equal(t, starlark.String("10.0.0.1"), sv)
+
+	sv, err = StarlarkMarshal(*network)
+	require.NoError(t, err)
+	assert.Equal(t, starlark.String("10.0.0.0/24"), sv)
+}
+
+type dummyState string
+
+func (s dummyState) String() string {
+	return strings.ToUpper(string(s))
+}
+
+type dummyPort uint16
+
+func TestStarlarkMarshalNamedStringer(t *testing.T) {
+	type stateStruct struct {
+		State  dummyState    `json:"state"`
+		Code   DummyStringer `json:"code"`
+		Port   dummyPort     `json:"port"`
+		States []dummyState  `json:"states"`
+	}
+
+	input := stateStruct{
+		State:  "running",
+		Code:   DummyStringer(3),
+		Port:   dummyPort(8443),
+		States: []dummyState{"stopped"},
+	}
+
+	// Named types of basic kinds use their String method if they have one.
+	sv, err := StarlarkMarshal(input)
+	require.NoError(t, err)
+
+	d1 := starlark.NewDict(4)
+	assert.NoError(t, d1.SetKey(starlark.String("state"), starlark.String("RUNNING")))
+	assert.NoError(t, d1.SetKey(starlark.String("code"), starlark.String("(DummyStringer)")))
+	assert.NoError(t, d1.SetKey(starlark.String("port"), starlark.MakeInt(8443)))
+	assert.NoError(t, d1.SetKey(starlark.String("states"), starlark.NewList([]starlark.Value{starlark.String("STOPPED")})))
+	assert.Equal(t, frozen(&starlarkObject{d: d1, typeName: "stateStruct"}), sv)
+
+	// Types listed in RawStringerTypes keep their raw value.
+	sv, err = StarlarkMarshalWithOpts(input, StarlarkMarshalOpts{RawStringerTypes: []reflect.Type{reflect.TypeFor[dummyState](), reflect.TypeFor[DummyStringer]()}})
+	require.NoError(t, err)
+
+	d2 := starlark.NewDict(4)
+	assert.NoError(t, d2.SetKey(starlark.String("state"), starlark.String("running")))
+	assert.NoError(t, d2.SetKey(starlark.String("code"), starlark.MakeInt(3)))
+	assert.NoError(t, d2.SetKey(starlark.String("port"), starlark.MakeInt(8443)))
+	assert.NoError(t, d2.SetKey(starlark.String("states"), starlark.NewList([]starlark.Value{starlark.String("stopped")})))
+	assert.Equal(t, frozen(&starlarkObject{d: d2, typeName: "stateStruct"}), sv)
+}
+
+type dummyID string
+
+var _ StarlarkMarshaler = dummyID("")
+var _ StarlarkUnmarshaler = new(dummyID)
+
+func (id dummyID) MarshalStarlark() (starlark.Value, error) {
+	n, err := strconv.Atoi(strings.TrimPrefix(string(id), "id-"))
+	if err != nil {
+		return nil, fmt.Errorf("Invalid ID %q", id)
+	}
+
+	return starlark.MakeInt(n), nil
+}
+
+func (id *dummyID) UnmarshalStarlark(v starlark.Value) error {
+	n, ok := v.(starlark.Int)
+	if !ok {
+		return fmt.Errorf("Expected int, found %s", v.Type())
+	}
+
+	*id = dummyID("id-" + n.String())
+	return nil
+}
+
+type dummyConfig struct {
+	entries [][2]string
+}
+
+var _ StarlarkMarshaler = &dummyConfig{}
+
+func (c *dummyConfig) MarshalStarlark() (starlark.Value, error) {
+	d := starlark.NewDict(len(c.entries))
+	for _, entry := range c.entries {
+		err := d.SetKey(starlark.String(entry[0]), starlark.String(entry[1]))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return d, nil
+}
+
+func TestStarlarkMarshaler(t *testing.T) {
+	type item struct {
+		ID     dummyID     `json:"id"`
+		Config dummyConfig `json:"config"`
+	}
+
+	type itemList struct {
+		Items []item `json:"items"`
+	}
+
+	input := itemList{Items: []item{
+		{ID: "id-1"},
+		{ID: "id-2", Config: dummyConfig{entries: [][2]string{{"user.foo", "bar"}}}},
+	}}
+
+	// The custom methods are used on values and through pointer receivers.
+	sv, err := StarlarkMarshal(input)
+	require.NoError(t, err)
+
+	config := starlark.NewDict(1)
+	assert.NoError(t, config.SetKey(starlark.String("user.foo"), starlark.String("bar")))
+
+	items := make([]starlark.Value, 0, 2)
+	for i, c := range []*starlark.Dict{starlark.NewDict(0), config} {
+		d := starlark.NewDict(2)
+		assert.NoError(t, d.SetKey(starlark.String("id"), starlark.MakeInt(i+1)))
+		assert.NoError(t, d.SetKey(starlark.String("config"), c))
+		items = append(items, &starlarkObject{d: d, typeName: "item"})
+	}
+
+	d1 := starlark.NewDict(1)
+	assert.NoError(t, d1.SetKey(starlark.String("items"), starlark.NewList(items)))
+	assert.Equal(t, frozen(&starlarkObject{d: d1, typeName: "itemList"}), sv)
+
+	// IDs are parsed back by UnmarshalStarlark.
+	var result itemList
+	require.NoError(t, StarlarkUnmarshalToWithOpts(sv, &result, StarlarkUnmarshalOpts{IgnoreUnknownKeys: true}))
+	assert.Equal(t, []dummyID{"id-1", "id-2"}, []dummyID{result.Items[0].ID, result.Items[1].ID})
+
+	// Errors of the custom methods include the path of the value.
+	input.Items[1].ID = "invalid"
+	_, err = StarlarkMarshal(input)
+	assert.EqualError(t, err, `Field "items[1].id": Failed marshalling scriptlet.dummyID to Starlark: Invalid ID "invalid"`)
+
+	err = StarlarkUnmarshalTo(starlark.NewList([]starlark.Value{starlark.String("id-1")}), &[]dummyID{})
+	assert.EqualError(t, err, `Field "[0]": Failed unmarshalling scriptlet.dummyID from Starlark: Expected int, found string`)
+}
+
+func TestStarlarkMarshalComplex(t *testing.T) {
+	type complexStruct struct {
+		Name   string     `json:"name"`
+		Value  complex128 `json:"value"`
+		Small  complex64  `json:"small"`
+		Values []complex128
+	}
+
+	input := complexStruct{Name: "foo", Value: complex(1.5, -2), Small: complex(0, 0.5), Values: []complex128{3i}}
+
+	// Complex fields don't fail the whole struct, and are lists of their real and imaginary parts.
+	d1 := starlark.NewDict(4)
+	assert.NoError(t, d1.SetKey(starlark.String("name"), starlark.String("foo")))
+	assert.NoError(t, d1.SetKey(starlark.String("value"), starlark.NewList([]starlark.Value{starlark.Float(1.5), starlark.Float(-2)})))
+	assert.NoError(t, d1.SetKey(starlark.String("small"), starlark.NewList([]starlark.Value{starlark.Float(0), starlark.Float(0.5)})))
+	assert.NoError(t, d1.SetKey(starlark.String("Values"), starlark.NewList([]starlark.Value{starlark.NewList([]starlark.Value{starlark.Float(0), starlark.Float(3)})})))
+
+	// The output is the same each time.
+	for i := 0; i < 3; i++ {
+		sv, err := StarlarkMarshal(input)
+		require.NoError(t, err)
+		assert.Equal(t, frozen(&starlarkObject{d: d1, typeName: "complexStruct"}), sv)
+		assert.Equal(t, `complexStruct(name="foo", value=[1.5, -2.0], small=[0.0, 0.5], Values=[[0.0, 3.0]])`, sv.String())
+	}
+}
+
+func TestStarlarkMarshalTime(t *testing.T) {
+	type timeStruct struct {
+		CreatedAt time.Time     `json:"created_at"`
+		ExpiresAt time.Time     `json:"expires_at"`
+		UpdatedAt *time.Time    `json:"updated_at"`
+		Interval  time.Duration `json:"interval"`
+		Timeout   time.Duration `json:"timeout"`
+	}
+
+	updatedAt := time.Date(2024, 5, 1, 10, 30, 0, 500000000, time.UTC)
+	input := timeStruct{
+		CreatedAt: time.Date(2024, 1, 2, 3, 4, 5, 0, time.FixedZone("", 2*60*60)),
+		UpdatedAt: &updatedAt,
+		Interval:  5*time.Minute + 30*time.Second,
+	}
+
+	// Times are RFC3339 strings, zero times are None and durations are strings such as "5m30s".
+	sv, err := StarlarkMarshal(input)
+	require.NoError(t, err)
+
+	d1 := starlark.NewDict(5)
+	assert.NoError(t, d1.SetKey(starlark.String("created_at"), starlark.String("2024-01-02T03:04:05+02:00")))
+	assert.NoError(t, d1.SetKey(starlark.String("expires_at"), starlark.None))
+	assert.NoError(t, d1.SetKey(starlark.String("updated_at"), starlark.String("2024-05-01T10:30:00.5Z")))
+	assert.NoError(t, d1.SetKey(starlark.String("interval"), starlark.String("5m30s")))
+	assert.NoError(t, d1.SetKey(starlark.String("timeout"), starlark.String("0s")))
+	assert.Equal(t, frozen(&starlarkObject{d: d1, typeName: "timeStruct"}), sv)
+
+	// They are parsed back into the same values.
+	var result timeStruct
+	require.NoError(t, StarlarkUnmarshalTo(sv, &result))
+	assert.True(t, input.CreatedAt.Equal(result.CreatedAt))
+	assert.True(t, result.ExpiresAt.IsZero())
+	assert.True(t, updatedAt.Equal(*result.UpdatedAt))
+	assert.Equal(t, input.Interval, result.Interval)
+	assert.Equal(t, time.Duration(0), result.Timeout)
+
+	// Durations can only be set from strings.
+	var interval time.Duration
+	assert.EqualError(t, StarlarkUnmarshalTo(starlark.MakeInt(10), &interval), "Cannot convert int to time.Duration")
+	assert.EqualError(t, StarlarkUnmarshalTo(starlark.String("soon"), &interval), `time: invalid duration "soon"`)
+
+	var createdAt time.Time
+	assert.ErrorContains(t, StarlarkUnmarshalTo(starlark.String("yesterday"), &createdAt), "Failed unmarshalling time.Time from text")
+}
+
+func TestStarlarkMarshalRawMessage(t *testing.T) {
+	type rawStruct struct {
+		Data  json.RawMessage `json:"data"`
+		Empty json.RawMessage `json:"empty"`
+	}
+
+	sv, err := StarlarkMarshal(rawStruct{
+		Data: json.RawMessage(`{"name": "foo", "count": 2, "ratio": 0.5, "tags": ["a", "b"], "parent": null}`),
+	})
+	assert.NoError(t, err)
+
+	data := starlark.NewDict(5)
+	assert.NoError(t, data.SetKey(starlark.String("count"), starlark.MakeInt(2)))
+	assert.NoError(t, data.SetKey(starlark.String("name"), starlark.String("foo")))
+	assert.NoError(t, data.SetKey(starlark.String("parent"), starlark.None))
+	assert.NoError(t, data.SetKey(starlark.String("ratio"), starlark.Float(0.5)))
+	assert.NoError(t, data.SetKey(starlark.String("tags"), starlark.NewList([]starlark.Value{starlark.String("a"), starlark.String("b")})))
+
+	d1 := starlark.NewDict(2)
+	assert.NoError(t, d1.SetKey(starlark.String("data"), data))
+	assert.NoError(t, d1.SetKey(starlark.String("empty"), starlark.None))
+	assert.Equal(t, frozen(&starlarkObject{d: d1, typeName: "rawStruct"}), sv)
+
+	// Invalid JSON is reported.
+	_, err = StarlarkMarshal(json.RawMessage(`{"name":`))
+	assert.ErrorContains(t, err, "Failed parsing raw JSON")
+}
+
+func TestStarlarkMarshalNetworkACL(t *testing.T) {
+	aclInfo := api.NetworkACL{
+		NetworkACLPost: api.NetworkACLPost{Name: "web"},
+		NetworkACLPut: api.NetworkACLPut{
+			Description: "Web servers",
+			Ingress: []api.NetworkACLRule{
+				{Action: "allow", State: "enabled", Source: "192.0.2.0/24,@internal", Protocol: "tcp", DestinationPort: "80,443"},
+			},
+			Config: map[string]string{"user.owner": "ops"},
+		},
+		Project: "default",
+	}
+
+	sv, err := StarlarkMarshal(aclInfo)
+	require.NoError(t, err)
+
+	// The fields read by scriptlets come back unchanged.
+	globals, err := starlark.ExecFile(&starlark.Thread{}, "acl.star", `
+source = acl.ingress[0].source
+ports = acl.ingress[0].destination_port
+owner = acl.config["user.owner"]
+used_by = acl.used_by
+egress = acl.egress
+attrs = dir(acl)
+`, starlark.StringDict{"acl": sv})
+	require.NoError(t, err)
+
+	assert.Equal(t, starlark.String("192.0.2.0/24,@internal"), globals["source"])
+	assert.Equal(t, starlark.String("80,443"), globals["ports"])
+	assert.Equal(t, starlark.String("ops"), globals["owner"])
+
+	for _, name := range []string{"used_by", "egress"} {
+		value, err := StarlarkUnmarshal(globals[name])
+		require.NoError(t, err)
+		assert.Equal(t, []any{}, value)
+	}
+
+	// Attribute names aren't quoted.
+	attrs, err := StarlarkUnmarshal(globals["attrs"])
+	require.NoError(t, err)
+	assert.Contains(t, attrs, "ingress")
+	assert.Contains(t, attrs, "used_by")
+
+	// Nil and empty slices marshal the same way, so UsedBy being populated or not doesn't change the output.
+	aclInfo.UsedBy = nil
+	nilUsedBy, err := StarlarkMarshal(aclInfo)
+	require.NoError(t, err)
+
+	aclInfo.UsedBy = []string{}
+	emptyUsedBy, err := StarlarkMarshal(aclInfo)
+	require.NoError(t, err)
+
+	assert.Equal(t, nilUsedBy, emptyUsedBy)
+	assert.Equal(t, nilUsedBy.String(), emptyUsedBy.String())
+
+	// The same goes for nil and empty rule lists and config.
+	aclInfo.Egress = []api.NetworkACLRule{}
+	aclInfo.Config = nil
+	emptyEgress, err := StarlarkMarshal(aclInfo)
+	require.NoError(t, err)
+
+	egress, err := emptyEgress.(starlark.HasAttrs).Attr("egress")
+	require.NoError(t, err)
+	assert.Equal(t, frozen(starlark.NewList([]starlark.Value{})), egress)
+
+	config, err := emptyEgress.(starlark.HasAttrs).Attr("config")
+	require.NoError(t, err)
+	assert.Equal(t, frozen(starlark.NewDict(0)), config)
+}
+
+func TestStarlarkMarshalFrozen(t *testing.T) {
+	type frozenStruct struct {
+		Names  []string          `json:"names"`
+		Config map[string]string `json:"config"`
+	}
+
+	sv, err := StarlarkMarshal(map[string]any{"object": frozenStruct{Names: []string{"a"}, Config: map[string]string{"k": "v"}}})
+	require.NoError(t, err)
+
+	// Nothing in the marshalled value can be modified by a scriptlet, however deeply nested.
+	for src, expected := range map[string]string{
+		`value["object"].names.append("b")`: "cannot append to frozen list",
+		`value["object"].config["k"] = "x"`: "cannot insert into frozen hash table",
+		`value["other"] = None`:             "cannot insert into frozen hash table",
+	} {
+		thread := &starlark.Thread{Name: "test"}
+		_, err := starlark.ExecFileOptions(syntax.LegacyFileOptions(), thread, "test", src, starlark.StringDict{"value": sv})
+		assert.ErrorContains(t, err, expected, src)
+	}
+
+	// The value is left unchanged.
+	thread := &starlark.Thread{Name: "test"}
+	unchanged, err := starlark.Eval(thread, "test", `len(value) == 1 and value["object"].names == ["a"] and value["object"].config == {"k": "v"}`, starlark.StringDict{"value": sv})
+	require.NoError(t, err)
+	assert.Equal(t, starlark.True, unchanged)
+}
+
+func TestStarlarkUnmarshalBigInt(t *testing.T) {
+	// Integers fitting in int64 are returned as such.
+	v, err := StarlarkUnmarshal(starlark.MakeInt64(math.MaxInt64))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(math.MaxInt64), v)
+
+	v, err = StarlarkUnmarshal(starlark.MakeInt64(math.MinInt64))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(math.MinInt64), v)
+
+	// Larger positive integers fitting in uint64 are returned as such.
+	v, err = StarlarkUnmarshal(starlark.MakeUint64(math.MaxInt64 + 1))
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(math.MaxInt64+1), v)
+
+	v, err = StarlarkUnmarshal(starlark.MakeUint64(math.MaxUint64))
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(math.MaxUint64), v)
+
+	// Other integers fail to convert rather than being truncated, including when nested in other values.
+	belowMin := new(big.Int).Sub(big.NewInt(math.MinInt64), big.NewInt(1))
+	aboveMax := new(big.Int).Add(new(big.Int).SetUint64(math.MaxUint64), big.NewInt(1))
+
+	_, err = StarlarkUnmarshal(starlark.MakeBigInt(belowMin))
+	assert.EqualError(t, err, "Integer -9223372036854775809 is out of range")
+
+	_, err = StarlarkUnmarshal(starlark.NewList([]starlark.Value{starlark.MakeBigInt(aboveMax)}))
+	assert.EqualError(t, err, "Integer 18446744073709551616 is out of range")
+
+	// Unless requested as *big.Int.
+	opts := StarlarkUnmarshalOpts{BigInts: true}
+	for _, expected := range []*big.Int{belowMin, aboveMax} {
+		v, err = StarlarkUnmarshalWithOpts(starlark.MakeBigInt(expected), opts)
+		assert.NoError(t, err)
+		assert.IsType(t, &big.Int{}, v)
+		assert.Equal(t, 0, expected.Cmp(v.(*big.Int)))
+	}
+
+	v, err = StarlarkUnmarshalWithOpts(starlark.NewList([]starlark.Value{starlark.MakeBigInt(aboveMax)}), opts)
+	assert.NoError(t, err)
+	assert.Equal(t, "18446744073709551616", v.([]any)[0].(*big.Int).String())
+
+	// Values computed by scriptlets behave the same.
+	globals, err := starlark.ExecFile(&starlark.Thread{}, "test", "value = 1 << 70", nil)
+	require.NoError(t, err)
+
+	_, err = StarlarkUnmarshal(globals["value"])
+	assert.EqualError(t, err, "Integer 1180591620717411303424 is out of range")
+}
+
+func TestStarlarkUnmarshalCollections(t *testing.T) {
+	globals, err := starlark.ExecFileOptions(&syntax.FileOptions{Set: true}, &starlark.Thread{}, "test", `
+tuple = (1, "a", None)
+bytes = b"data"
+strings = set(["b", "c", "a"])
+numbers = set([10, 9, 100])
+nested = {"ports": set([("tcp", 443), ("tcp", 80), ("udp", 53)]), "names": [("web", b"01")]}
+`, nil)
+	require.NoError(t, err)
+
+	for name, expected := range map[string]any{
+		"tuple":   []any{int64(1), "a", nil},
+		"bytes":   []byte("data"),
+		"strings": []any{"a", "b", "c"},
+		"numbers": []any{int64(10), int64(100), int64(9)}, // Sets are sorted by the string form of their elements.
+		"nested": map[string]any{
+			"ports": []any{[]any{"tcp", int64(443)}, []any{"tcp", int64(80)}, []any{"udp", int64(53)}},
+			"names": []any{[]any{"web", []byte("01")}},
+		},
+	} {
+		value, err := StarlarkUnmarshal(globals[name])
+		require.NoError(t, err, name)
+		assert.Equal(t, expected, value, name)
+	}
+
+	// The order elements are added to sets in doesn't matter.
+	a := starlark.NewSet(2)
+	require.NoError(t, a.Insert(starlark.String("x")))
+	require.NoError(t, a.Insert(starlark.String("y")))
+
+	b := starlark.NewSet(2)
+	require.NoError(t, b.Insert(starlark.String("y")))
+	require.NoError(t, b.Insert(starlark.String("x")))
+
+	aValue, err := StarlarkUnmarshal(a)
+	require.NoError(t, err)
+
+	bValue, err := StarlarkUnmarshal(b)
+	require.NoError(t, err)
+	assert.Equal(t, aValue, bValue)
+
+	// Unsupported elements are still reported.
+	_, err = StarlarkUnmarshal(starlark.Tuple{starlark.NewBuiltin("f", nil)})
+	assert.EqualError(t, err, "Unsupported type: *starlark.Builtin")
+}
+
+func TestStarlarkUnmarshalRoundTrip(t *testing.T) {
+	type address struct {
+		Host  string `json:"host"`
+		Ports []int  `json:"ports"`
+	}
+
+	type server struct {
+		Name      string             `json:"name"`
+		Primary   address            `json:"primary"`
+		Fallbacks []address          `json:"fallbacks"`
+		Labels    map[string]string  `json:"labels"`
+		Zones     map[string]address `json:"zones"`
+	}
+
+	data := server{
+		Name:      "web",
+		Primary:   address{Host: "10.0.0.1", Ports: []int{80, 443}},
+		Fallbacks: []address{{Host: "10.0.0.2", Ports: []int{8080}}},
+		Labels:    map[string]string{"env": "prod"},
+		Zones:     map[string]address{"east": {Host: "10.1.0.1", Ports: []int{22}}},
+	}
+
+	sv, err := StarlarkMarshal(data)
+	require.NoError(t, err)
+
+	// Objects are passed back by the scriptlet as is, or nested in new values.
+	globals, err := starlark.ExecFile(&starlark.Thread{}, "test", `
+def passthrough(server):
+	return server
+
+def nest(server):
+	return {"server": server, "primary": server.primary, "fallbacks": server.fallbacks}
+`, nil)
+	require.NoError(t, err)
+
+	expected := map[string]any{
+		"name":      "web",
+		"primary":   map[string]any{"host": "10.0.0.1", "ports": []any{int64(80), int64(443)}},
+		"fallbacks": []any{map[string]any{"host": "10.0.0.2", "ports": []any{int64(8080)}}},
+		"labels":    map[string]any{"env": "prod"},
+		"zones":     map[string]any{"east": map[string]any{"host": "10.1.0.1", "ports": []any{int64(22)}}},
+	}
+
+	ret, err := starlark.Call(&starlark.Thread{}, globals["passthrough"], starlark.Tuple{sv}, nil)
+	require.NoError(t, err)
+
+	value, err := StarlarkUnmarshal(ret)
+	require.NoError(t, err)
+	assert.Equal(t, expected, value)
+
+	var result server
+	require.NoError(t, StarlarkUnmarshalTo(ret, &result))
+	assert.Equal(t, data, result)
+
+	ret, err = starlark.Call(&starlark.Thread{}, globals["nest"], starlark.Tuple{sv}, nil)
+	require.NoError(t, err)
+
+	value, err = StarlarkUnmarshal(ret)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{
+		"server":    expected,
+		"primary":   expected["primary"],
+		"fallbacks": expected["fallbacks"],
+	}, value)
+}
+
+func TestStarlarkUnmarshalBytes(t *testing.T) {
+	globals, err := starlark.ExecFile(&starlark.Thread{}, "test", `
+def checksum():
+	return b"\x00\x01\xfeabc"
+
+def payload():
+	return {"name": "key", "data": b"\xff" + b"\x00"}
+`, nil)
+	require.NoError(t, err)
+
+	ret, err := starlark.Call(&starlark.Thread{}, globals["checksum"], nil, nil)
+	require.NoError(t, err)
+
+	value, err := StarlarkUnmarshal(ret)
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0x00, 0x01, 0xfe, 'a', 'b', 'c'}, value)
+
+	// Bytes can also be set on byte slices.
+	var data []byte
+	require.NoError(t, StarlarkUnmarshalTo(ret, &data))
+	assert.Equal(t, []byte{0x00, 0x01, 0xfe, 'a', 'b', 'c'}, data)
+
+	ret, err = starlark.Call(&starlark.Thread{}, globals["payload"], nil, nil)
+	require.NoError(t, err)
+
+	var payload struct {
+		Name string `json:"name"`
+		Data []byte `json:"data"`
+	}
+
+	require.NoError(t, StarlarkUnmarshalTo(ret, &payload))
+	assert.Equal(t, "key", payload.Name)
+	assert.Equal(t, []byte{0xff, 0x00}, payload.Data)
+
+	// But not on strings or other slices.
+	var s string
+	assert.EqualError(t, StarlarkUnmarshalTo(starlark.Bytes("abc"), &s), "Cannot convert bytes to string")
+
+	var ints []int
+	assert.EqualError(t, StarlarkUnmarshalTo(starlark.Bytes("abc"), &ints), "Cannot convert bytes to []int")
+}
+
+func TestStarlarkUnmarshalTo(t *testing.T) {
+	aclInfo := api.NetworkACL{
+		NetworkACLPost: api.NetworkACLPost{Name: "web"},
+		NetworkACLPut: api.NetworkACLPut{
+			Description: "Web servers",
+			Ingress: []api.NetworkACLRule{
+				{Action: "allow", State: "enabled", Source: "192.0.2.0/24", Protocol: "tcp", DestinationPort: "80,443"},
+			},
+			Egress: []api.NetworkACLRule{},
+			Config: map[string]string{"user.owner": "ops"},
+		},
+		UsedBy:  []string{},
+		Project: "default",
+	}
+
+	sv, err := StarlarkMarshal(aclInfo)
+	require.NoError(t, err)
+
+	// Marshalled values convert back to the same value, including the fields of embedded structs.
+	var result api.NetworkACL
+	require.NoError(t, StarlarkUnmarshalTo(sv, &result))
+	assert.Equal(t, aclInfo, result)
+
+	type numbers struct {
+		Int8    int8              `json:"int8"`
+		Uint    uint              `json:"uint"`
+		Float32 float32           `json:"float32"`
+		Pointer *int              `json:"pointer"`
+		Array   [2]int            `json:"array"`
+		Any     any               `json:"any"`
+		Address net.IP            `json:"address"`
+		Raw     json.RawMessage   `json:"raw"`
+		Nested  map[string][]uint `json:"nested"`
+	}
+
+	globals, err := starlark.ExecFile(&starlark.Thread{}, "numbers.star", `
+value = {"int8": -128, "uint": 42, "float32": 1, "pointer": 7, "array": (1, 2), "any": [1, "a"], "address": "192.0.2.1", "raw": {"a": [1]}, "nested": {"a": [1, 2]}}
+`, nil)
+	require.NoError(t, err)
+
+	var n numbers
+	require.NoError(t, StarlarkUnmarshalTo(globals["value"], &n))
+	assert.Equal(t, numbers{
+		Int8:    -128,
+		Uint:    42,
+		Float32: 1,
+		Pointer: func() *int { i := 7; return &i }(),
+		Array:   [2]int{1, 2},
+		Any:     []any{int64(1), "a"},
+		Address: net.ParseIP("192.0.2.1"),
+		Raw:     json.RawMessage(`{"a":[1]}`),
+		Nested:  map[string][]uint{"a": {1, 2}},
+	}, n)
+
+	// None resets values.
+	require.NoError(t, StarlarkUnmarshalTo(starlark.None, &n.Pointer))
+	assert.Nil(t, n.Pointer)
+
+	// Errors include the path of the value.
+	for src, errMsg := range map[string]string{
+		`{"int8": 128}`:                    `Field "int8": Value 128 overflows int8`,
+		`{"uint": -1}`:                     `Field "uint": Value -1 overflows uint`,
+		`{"pointer": "7"}`:                 `Field "pointer": Cannot convert string to int`,
+		`{"array": [1]}`:                   `Field "array": Expected 2 elements, found 1`,
+		`{"nested": {"a": [1, "b"]}}`:      `Field "nested[a][1]": Cannot convert string to uint`,
+		`{"address": "invalid"}`:           `Field "address": Failed unmarshalling net.IP from text: invalid IP address: invalid`,
+		`{"unknown": 1}`:                   `Unknown field "unknown"`,
+		`[1]`:                              `Cannot convert list to scriptlet.numbers`,
+		`{"nested": {"a": [1], "b": "c"}}`: `Field "nested[b]": Cannot convert string to []uint`,
+	} {
+		v, err := starlark.Eval(&starlark.Thread{}, "test", src, nil)
+		require.NoError(t, err)
+
+		assert.EqualError(t, StarlarkUnmarshalTo(v, &numbers{}), errMsg, src)
+	}
+
+	v, err := starlark.Eval(&starlark.Thread{}, "test", `[{"action": "allow", "unknown": 1}]`, nil)
+	require.NoError(t, err)
+
+	var rules []api.NetworkACLRule
+	assert.EqualError(t, StarlarkUnmarshalTo(v, &rules), `Unknown field "[0].unknown"`)
+
+	// Unknown keys can be ignored.
+	require.NoError(t, StarlarkUnmarshalToWithOpts(v, &rules, StarlarkUnmarshalOpts{IgnoreUnknownKeys: true}))
+	assert.Equal(t, []api.NetworkACLRule{{Action: "allow"}}, rules)
+
+	// The target must be a pointer.
+	assert.EqualError(t, StarlarkUnmarshalTo(v, rules), "Target must be a non-nil pointer, found []api.NetworkACLRule")
+}
+
+// BenchmarkStarlarkMarshalStructs marshals a large slice of structs of the same type, whose field metadata is
+// only looked up once.
+func BenchmarkStarlarkMarshalStructs(b *testing.B) {
+	rules := make([]api.NetworkACLRule, 0, 10000)
+	for i := 0; i < 10000; i++ {
+		rules = append(rules, api.NetworkACLRule{
+			Action:          "allow",
+			Source:          fmt.Sprintf("10.0.%d.%d", i/256, i%256),
+			Protocol:        "tcp",
+			DestinationPort: "80,443",
+			State:           "enabled",
+		})
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for n := 0; n < b.N; n++ {
+		_, err := StarlarkMarshal(rules)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package scriptlet
+
+import (
+	"fmt"
+	"strings"
+
+	"go.starlark.net/starlark"
+)
+
+// stringBuiltins returns the string helper functions available to all scriptlets.
+// Remember to match the entries in scriptletLoad.stringBuiltins with this list so Starlark can perform compile
+// time validation of functions used.
+func stringBuiltins() starlark.StringDict {
+	return starlark.StringDict{
+		"render": starlark.NewBuiltin("render", renderFunc),
+	}
+}
+
+// renderFunc replaces the "{{key}}" placeholders of the template with the values of the dict. Spaces around the
+// key are ignored. Placeholders whose key is missing from the dict are replaced by the default if provided, and
+// are an error otherwise.
+func renderFunc(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var template string
+	var values *starlark.Dict
+	var defaultValue starlark.Value = starlark.None
+
+	err := starlark.UnpackArgs(b.Name(), args, kwargs, "template", &template, "values", &values, "default?", &defaultValue)
+	if err != nil {
+		return nil, err
+	}
+
+	var defaultString *string
+	if defaultValue != starlark.None {
+		s, ok := starlark.AsString(defaultValue)
+		if !ok {
+			return nil, fmt.Errorf("%s: Default must be a string", b.Name())
+		}
+
+		defaultString = &s
+	}
+
+	var sb strings.Builder
+	rest := template
+	for {
+		start := strings.Index(rest, "{{")
+		if start < 0 {
+			sb.WriteString(rest)
+			break
+		}
+
+		end := strings.Index(rest[start+2:], "}}")
+		if end < 0 {
+			return nil, fmt.Errorf("%s: Unclosed placeholder in template %q", b.Name(), template)
+		}
+
+		key := strings.TrimSpace(rest[start+2 : start+2+end])
+
+		value, found, err := values.Get(starlark.String(key))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", b.Name(), err)
+		}
+
+		var replacement string
+		if found {
+			s, ok := starlark.AsString(value)
+			if !ok {
+				return nil, fmt.Errorf("%s: Value of key %q must be a string", b.Name(), key)
+			}
+
+			replacement = s
+		} else if defaultString != nil {
+			replacement = *defaultString
+		} else {
+			return nil, fmt.Errorf("%s: Missing key %q", b.Name(), key)
+		}
+
+		sb.WriteString(rest[:start])
+		sb.WriteString(replacement)
+		rest = rest[start+2+end+2:]
+	}
+
+	return starlark.String(sb.String()), nil
+}
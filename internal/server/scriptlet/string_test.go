@@ -0,0 +1,45 @@
+package scriptlet
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.starlark.net/starlark"
+)
+
+// evalStringBuiltin evaluates a Starlark expression using the string built-ins.
+func evalStringBuiltin(expr string) (starlark.Value, error) {
+	thread := &starlark.Thread{Name: "test"}
+
+	return starlark.Eval(thread, "test", expr, stringBuiltins())
+}
+
+func TestRender(t *testing.T) {
+	for expr, expected := range map[string]string{
+		`render("{{name}}-{{ project }}", {"name": "c1", "project": "default"})`: "c1-default",
+		`render("limits.cpu={{cpu}}", {"cpu": "4", "unused": "x"})`:              "limits.cpu=4",
+		`render("no placeholders", {})`:                                          "no placeholders",
+		`render("{{a}}{{a}}", {"a": "x"})`:                                       "xx",
+		`render("{{a}}", {"a": "{{b}}", "b": "y"})`:                              "{{b}}",
+		`render("{{missing}}/{{name}}", {"name": "c1"}, default="none")`:         "none/c1",
+		`render(template="{{name}}", values={"name": "c1"}, default="")`:         "c1",
+		`render("{{missing}}", {}, "")`:                                          "",
+	} {
+		v, err := evalStringBuiltin(expr)
+		assert.NoError(t, err, expr)
+		assert.Equal(t, starlark.String(expected), v, expr)
+	}
+}
+
+func TestRenderErrors(t *testing.T) {
+	for expr, expected := range map[string]string{
+		`render("{{name}}-{{project}}", {"name": "c1"})`: `render: Missing key "project"`,
+		`render("{{name", {"name": "c1"})`:               `render: Unclosed placeholder in template "{{name"`,
+		`render("{{cpu}}", {"cpu": 4})`:                  `render: Value of key "cpu" must be a string`,
+		`render("{{cpu}}", {}, default=4)`:               `render: Default must be a string`,
+		`render("{{cpu}}", ["cpu"])`:                     `render: for parameter values: got list, want dict`,
+	} {
+		_, err := evalStringBuiltin(expr)
+		assert.EqualError(t, err, expected, expr)
+	}
+}
@@ -17,7 +17,7 @@ import (
 //
 // Return the newly created State object, along with a function that can be
 // used for cleaning it up.
-func NewTestState(t *testing.T) (*State, func()) {
+func NewTestState(t testing.TB) (*State, func()) {
 	node, nodeCleanup := db.NewTestNode(t)
 	cluster, clusterCleanup := db.NewTestCluster(t)
 	os, osCleanup := sys.NewTestOS(t)
@@ -12,7 +12,7 @@ import (
 )
 
 // NewTestOS returns a new OS instance initialized with test values.
-func NewTestOS(t *testing.T) (*OS, func()) {
+func NewTestOS(t testing.TB) (*OS, func()) {
 	dir, err := os.MkdirTemp("", "incus-sys-os-test-")
 	require.NoError(t, err)
 	require.NoError(t, SetupTestCerts(dir))
@@ -445,6 +445,47 @@ var APIExtensions = []string{
 	"instances_state_os_info",
 	"network_load_balancer_state",
 	"instance_nic_macvlan_mode",
+	"network_acl_revisions",
+	"network_acl_seed",
+	"projects_limits_network_acls",
+	"network_acl_subject_any",
+	"network_acl_icmp6_ndp",
+	"network_acl_rule_schedule",
+	"network_acl_state",
+	"network_acl_rule_stats",
+	"network_acl_reject_response",
+	"network_acl_ovn_retry",
+	"scriptlet_network_functions",
+	"network_acl_ovn_objects",
+	"network_acl_bridge_subjects",
+	"network_acl_ovn_logging",
+	"metrics_network_acls",
+	"network_acl_rule_counts",
+	"network_acl_rule_dscp",
+	"network_acl_nic_rules",
+	"network_acl_filter",
+	"network_acl_rule_ports",
+	"network_acl_validation_mode",
+	"network_acl_vlan_subjects",
+	"instances_config_scriptlet",
+	"network_acl_direction_enabled",
+	"network_acl_fqdn_subjects",
+	"network_acl_strict_cidr",
+	"network_acl_family_subjects",
+	"network_acl_subject_search",
+	"scriptlet_render",
+	"network_acl_priority_band",
+	"network_acl_managed",
+	"network_acl_rule_packet_length",
+	"network_acl_rules_scriptlet",
+	"network_acl_log_follow",
+	"instances_scriptlet_get_network_acls",
+	"network_acl_rule_related",
+	"network_acl_log_rate",
+	"network_acl_parent",
+	"scriptlet_json",
+	"network_acl_state_errors",
+	"network_acl_conflicts",
 }
 
 // APIExtensionsCount returns the number of available API extensions.
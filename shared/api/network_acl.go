@@ -1,7 +1,12 @@
 package api
 
 import (
+	"fmt"
+	"net/netip"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 )
 
 // NetworkACLRule represents a single rule in an ACL ruleset.
@@ -50,50 +55,239 @@ type NetworkACLRule struct {
 	// State of the rule
 	// Example: enabled
 	State string `json:"state" yaml:"state"`
+
+	// Time (RFC3339) from which the rule applies
+	// Example: 2024-05-01T08:00:00Z
+	//
+	// API extension: network_acl_rule_schedule
+	ValidFrom string `json:"valid_from,omitempty" yaml:"valid_from,omitempty"`
+
+	// Time (RFC3339) after which the rule stops applying
+	// Example: 2024-05-01T18:00:00Z
+	//
+	// API extension: network_acl_rule_schedule
+	ValidUntil string `json:"valid_until,omitempty" yaml:"valid_until,omitempty"`
+
+	// Response sent back for rejected traffic (icmp-port-unreachable or tcp-reset)
+	// Example: tcp-reset
+	//
+	// API extension: network_acl_reject_response
+	RejectResponse string `json:"reject_response,omitempty" yaml:"reject_response,omitempty"`
+
+	// DSCP value (0-63) to mark allowed traffic with
+	// Example: 46
+	//
+	// API extension: network_acl_rule_dscp
+	DSCP string `json:"dscp,omitempty" yaml:"dscp,omitempty"`
+
+	// Name of the logical switch port the traffic comes from (ingress rules on OVN networks)
+	// Example: incus-net5-instance-bd5d9ac7-8a9c-4d1a-a8fc-dbd7ae3c2d27-eth0
+	//
+	// API extension: network_acl_rule_ports
+	InPort string `json:"in_port,omitempty" yaml:"in_port,omitempty"`
+
+	// Name of the logical switch port the traffic goes to (egress rules on OVN networks)
+	// Example: incus-net5-instance-bd5d9ac7-8a9c-4d1a-a8fc-dbd7ae3c2d27-eth0
+	//
+	// API extension: network_acl_rule_ports
+	OutPort string `json:"out_port,omitempty" yaml:"out_port,omitempty"`
+
+	// Length of the IP packets in bytes, as a single value or an inclusive range (OVN networks)
+	// Example: 1400-1500
+	//
+	// API extension: network_acl_rule_packet_length
+	PacketLen string `json:"packet_len,omitempty" yaml:"packet_len,omitempty"`
+
+	// Whether to track the connections of allowed TCP or UDP traffic so that reply traffic is allowed (OVN networks)
+	// Example: true
+	//
+	// API extension: network_acl_rule_related
+	Related bool `json:"related,omitempty" yaml:"related,omitempty"`
 }
 
 // Normalise normalises the fields in the rule so that they are comparable with ones stored.
 func (r *NetworkACLRule) Normalise() {
+	r.NormaliseElements()
+
+	// Sort and deduplicate Source and Destination subject lists.
+	r.Source = normaliseNetworkACLSubjects(r.Source)
+	r.Destination = normaliseNetworkACLSubjects(r.Destination)
+
+	// Sort and deduplicate SourcePort and DestinationPort port lists.
+	r.SourcePort = normaliseNetworkACLPorts(r.SourcePort)
+	r.DestinationPort = normaliseNetworkACLPorts(r.DestinationPort)
+}
+
+// NormaliseElements normalises the fields in the rule like Normalise, except that the elements of the subject and
+// port lists are only normalised in place, so their position still matches the supplied rule.
+func (r *NetworkACLRule) NormaliseElements() {
 	r.Action = strings.TrimSpace(r.Action)
 	r.Protocol = strings.TrimSpace(r.Protocol)
 	r.ICMPType = strings.TrimSpace(r.ICMPType)
 	r.ICMPCode = strings.TrimSpace(r.ICMPCode)
 	r.Description = strings.TrimSpace(r.Description)
 	r.State = strings.TrimSpace(r.State)
+	r.ValidFrom = strings.TrimSpace(r.ValidFrom)
+	r.ValidUntil = strings.TrimSpace(r.ValidUntil)
+	r.RejectResponse = strings.TrimSpace(r.RejectResponse)
+	r.DSCP = strings.TrimSpace(r.DSCP)
+	r.PacketLen = strings.TrimSpace(r.PacketLen)
+	r.InPort = strings.TrimSpace(r.InPort)
+	r.OutPort = strings.TrimSpace(r.OutPort)
+
+	r.Source = normaliseNetworkACLList(r.Source, normaliseNetworkACLSubject)
+	r.Destination = normaliseNetworkACLList(r.Destination, normaliseNetworkACLSubject)
+	r.SourcePort = normaliseNetworkACLList(r.SourcePort, strings.TrimSpace)
+	r.DestinationPort = normaliseNetworkACLList(r.DestinationPort, strings.TrimSpace)
+}
+
+// normaliseNetworkACLList applies the normalise function to each element of a comma separated list, keeping their
+// order.
+func normaliseNetworkACLList(list string, normalise func(string) string) string {
+	if strings.TrimSpace(list) == "" {
+		return ""
+	}
+
+	elements := strings.Split(list, ",")
+	for i, element := range elements {
+		elements[i] = normalise(element)
+	}
+
+	return strings.Join(elements, ",")
+}
+
+// normaliseNetworkACLPorts removes space from a comma separated list of ports and port ranges, and then sorts the
+// list by port number and removes the duplicates. Entries that aren't numeric are sorted last.
+func normaliseNetworkACLPorts(ports string) string {
+	if strings.TrimSpace(ports) == "" {
+		return ""
+	}
+
+	list := strings.Split(ports, ",")
+	for i, s := range list {
+		list[i] = strings.TrimSpace(s)
+	}
+
+	slices.SortFunc(list, func(a string, b string) int {
+		aStart, _, _ := strings.Cut(a, "-")
+		bStart, _, _ := strings.Cut(b, "-")
+
+		aPort, aErr := strconv.Atoi(aStart)
+		bPort, bErr := strconv.Atoi(bStart)
+
+		switch {
+		case aErr == nil && bErr == nil && aPort != bPort:
+			return aPort - bPort
+		case aErr == nil && bErr != nil:
+			return -1
+		case aErr != nil && bErr == nil:
+			return 1
+		}
+
+		return strings.Compare(a, b)
+	})
+
+	return strings.Join(slices.Compact(list), ",")
+}
+
+// normaliseNetworkACLSubjects normalises each subject of a comma separated list of rule subjects, and then sorts
+// the list and removes the duplicates. As the subjects of a rule are alternatives, this doesn't change what the
+// rule matches.
+func normaliseNetworkACLSubjects(subjects string) string {
+	if strings.TrimSpace(subjects) == "" {
+		return ""
+	}
+
+	list := strings.Split(subjects, ",")
+	for i, s := range list {
+		list[i] = normaliseNetworkACLSubject(s)
+	}
+
+	slices.Sort(list)
+
+	return strings.Join(slices.Compact(list), ",")
+}
+
+// normaliseNetworkACLSubject removes space from a rule subject, lower cases the reserved subjects and the "any",
+// "any4" and "any6" shorthand subjects, and converts VLAN IDs, IP addresses, CIDRs and ranges to their canonical
+// text form.
+// Single address CIDRs (/32 and /128) are converted to the bare address. ACL and network peer names are case
+// sensitive so they are kept as is.
+func normaliseNetworkACLSubject(subject string) string {
+	subject = strings.TrimSpace(subject)
 
-	// Remove space from Source subject list.
-	subjects := strings.Split(r.Source, ",")
-	for i, s := range subjects {
-		subjects[i] = strings.TrimSpace(s)
+	lower := strings.ToLower(subject)
+	if slices.Contains([]string{"any", "any4", "any6", "@internal", "@external", "@ipv4", "@ipv6", "#internal", "#external"}, lower) {
+		return lower
 	}
 
-	r.Source = strings.Join(subjects, ",")
+	vlanID, isVLAN := strings.CutPrefix(lower, "vlan:")
+	if isVLAN {
+		id, err := strconv.ParseUint(vlanID, 10, 16)
+		if err != nil {
+			return lower
+		}
 
-	// Remove space from Destination subject list.
-	subjects = strings.Split(r.Destination, ",")
-	for i, s := range subjects {
-		subjects[i] = strings.TrimSpace(s)
+		return fmt.Sprintf("vlan:%d", id)
 	}
 
-	r.Destination = strings.Join(subjects, ",")
+	start, end, isRange := strings.Cut(subject, "-")
+	if isRange {
+		startAddr, err := netip.ParseAddr(start)
+		if err != nil {
+			return subject
+		}
+
+		endAddr, err := netip.ParseAddr(end)
+		if err != nil {
+			return subject
+		}
 
-	// Remove space from SourcePort port list.
-	ports := strings.Split(r.SourcePort, ",")
-	for i, s := range ports {
-		ports[i] = strings.TrimSpace(s)
+		return startAddr.String() + "-" + endAddr.String()
 	}
 
-	r.SourcePort = strings.Join(ports, ",")
+	prefix, err := netip.ParsePrefix(subject)
+	if err == nil {
+		if prefix.IsSingleIP() {
+			return prefix.Addr().String()
+		}
 
-	// Remove space from DestinationPort port list.
-	ports = strings.Split(r.DestinationPort, ",")
-	for i, s := range ports {
-		ports[i] = strings.TrimSpace(s)
+		return prefix.String()
 	}
 
-	r.DestinationPort = strings.Join(ports, ",")
+	addr, err := netip.ParseAddr(subject)
+	if err == nil {
+		return addr.String()
+	}
+
+	return subject
 }
 
+// NetworkACLSubjectType represents the kind of a network ACL rule subject.
+type NetworkACLSubjectType string
+
+// NetworkACLSubjectTypeIPv4 defines the subject type of a single IPv4 address.
+const NetworkACLSubjectTypeIPv4 = NetworkACLSubjectType("ipv4")
+
+// NetworkACLSubjectTypeIPv6 defines the subject type of a single IPv6 address.
+const NetworkACLSubjectTypeIPv6 = NetworkACLSubjectType("ipv6")
+
+// NetworkACLSubjectTypeRange defines the subject type of an IP range ("<start>-<end>").
+const NetworkACLSubjectTypeRange = NetworkACLSubjectType("range")
+
+// NetworkACLSubjectTypeCIDR defines the subject type of an IP network in CIDR notation.
+const NetworkACLSubjectTypeCIDR = NetworkACLSubjectType("cidr")
+
+// NetworkACLSubjectTypeName defines the subject type of a network ACL or network peer name.
+const NetworkACLSubjectTypeName = NetworkACLSubjectType("name")
+
+// NetworkACLSubjectTypeHostName defines the subject type of a host name, matching the addresses it resolves to.
+const NetworkACLSubjectTypeHostName = NetworkACLSubjectType("hostname")
+
+// NetworkACLSubjectTypeSpecial defines the subject type of the reserved subjects ("@internal", "@external",
+// "@ipv4", "@ipv6", "any", "any4", "any6" and "vlan:<id>").
+const NetworkACLSubjectTypeSpecial = NetworkACLSubjectType("special")
+
 // NetworkACLPost used for renaming an ACL.
 //
 // swagger:model
@@ -145,6 +339,27 @@ type NetworkACL struct {
 	//
 	// API extension: network_acls_all_projects
 	Project string `json:"project" yaml:"project"` // Project the ACL belongs to.
+
+	// Number of ingress rules
+	// Read only: true
+	// Example: 2
+	//
+	// API extension: network_acl_rule_counts
+	IngressCount int `json:"ingress_count" yaml:"ingress_count"`
+
+	// Number of egress rules
+	// Read only: true
+	// Example: 1
+	//
+	// API extension: network_acl_rule_counts
+	EgressCount int `json:"egress_count" yaml:"egress_count"`
+
+	// Whether the ACL is managed by the server and so cannot be modified, renamed or deleted
+	// Read only: true
+	// Example: false
+	//
+	// API extension: network_acl_managed
+	Managed bool `json:"managed" yaml:"managed"`
 }
 
 // Writable converts a full NetworkACL struct into a NetworkACLPut struct (filters read-only fields).
@@ -161,3 +376,281 @@ type NetworkACLsPost struct {
 	NetworkACLPost `yaml:",inline"`
 	NetworkACLPut  `yaml:",inline"`
 }
+
+// NetworkACLRevision represents a previous version of an ACL.
+//
+// swagger:model
+//
+// API extension: network_acl_revisions.
+type NetworkACLRevision struct {
+	NetworkACLPost `yaml:",inline"`
+	NetworkACLPut  `yaml:",inline"`
+
+	// Revision number (incremented for each change made to the ACL)
+	// Example: 3
+	Revision int64 `json:"revision" yaml:"revision"`
+
+	// Requestor of the change which replaced this version
+	// Read only: true
+	Requestor *EventLifecycleRequestor `json:"requestor" yaml:"requestor"`
+
+	// When this version was replaced
+	// Read only: true
+	// Example: 2021-03-23T20:00:00-04:00
+	CreatedAt time.Time `json:"created_at" yaml:"created_at"`
+}
+
+// NetworkACLState represents the state of an ACL on the networks using it.
+//
+// swagger:model
+//
+// API extension: network_acl_state.
+type NetworkACLState struct {
+	// State of the ACL on each OVN network using it
+	Networks map[string]NetworkACLNetworkState `json:"networks" yaml:"networks"`
+
+	// Packet and byte counters of the ACL rules keyed by rule ID
+	//
+	// API extension: network_acl_rule_stats
+	Rules map[string]NetworkACLRuleStats `json:"rules,omitempty" yaml:"rules,omitempty"`
+
+	// OVN objects backing the ACL (only set when used by OVN networks)
+	//
+	// API extension: network_acl_ovn_objects
+	OVN *NetworkACLOVNState `json:"ovn,omitempty" yaml:"ovn,omitempty"`
+}
+
+// NetworkACLOVNState represents the OVN objects backing an ACL.
+//
+// swagger:model
+//
+// API extension: network_acl_ovn_objects.
+type NetworkACLOVNState struct {
+	// Name of the OVN port group holding the ACL rules
+	// Example: incus_acl3
+	PortGroup string `json:"port_group" yaml:"port_group"`
+
+	// Names of the per-network OVN port groups holding the network specific rules, keyed by network name
+	// Example: {"ovn0": "incus_acl3_net5"}
+	NetworkPortGroups map[string]string `json:"network_port_groups" yaml:"network_port_groups"`
+
+	// UUIDs of the OVN ACL rows currently backing each rule, keyed by rule ID
+	// Example: {"5f0c1d3e2a4b6c8d": ["8a3b4a3c-6d52-4a8f-9c1b-3f1d2e4c5b6a"]}
+	RuleACLs map[string][]string `json:"rule_acls" yaml:"rule_acls"`
+}
+
+// NetworkACLNetworkState represents whether an ACL is in sync with its backend on a network.
+//
+// swagger:model
+//
+// API extension: network_acl_state.
+type NetworkACLNetworkState struct {
+	// Whether the rules applied on the network match the ACL (synced, pending or error)
+	// Example: synced
+	Status string `json:"status" yaml:"status"`
+
+	// Details about the status
+	// Example: Port group "incus_acl3" doesn't exist
+	Detail string `json:"detail,omitempty" yaml:"detail,omitempty"`
+
+	// Errors that prevented getting parts of the state of the network
+	// Example: ["Failed getting rule counters of port group \"incus_acl3\": not found"]
+	//
+	// API extension: network_acl_state_errors
+	Errors []string `json:"errors,omitempty" yaml:"errors,omitempty"`
+}
+
+// NetworkACLRuleStats represents the traffic counters of an ACL rule.
+//
+// swagger:model
+//
+// API extension: network_acl_rule_stats.
+type NetworkACLRuleStats struct {
+	// Direction of the rule (ingress or egress)
+	// Example: ingress
+	Direction string `json:"direction" yaml:"direction"`
+
+	// Index of the rule in its direction's rule list
+	// Example: 0
+	Index int `json:"index" yaml:"index"`
+
+	// Number of packets matched by the rule
+	// Example: 1024
+	Packets int64 `json:"packets" yaml:"packets"`
+
+	// Number of bytes matched by the rule
+	// Example: 524288
+	Bytes int64 `json:"bytes" yaml:"bytes"`
+}
+
+// NetworkACLNICRules represents the network ACL rules applying to an instance NIC.
+//
+// swagger:model
+//
+// API extension: network_acl_nic_rules.
+type NetworkACLNICRules struct {
+	// Network the NIC is connected to
+	// Example: ovntest
+	Network string `json:"network" yaml:"network"`
+
+	// ACLs applying to the NIC, in the order their rules are merged
+	// Example: ["web", "default-deny"]
+	ACLs []string `json:"acls" yaml:"acls"`
+
+	// Rules of the ACLs, in the order they are applied
+	Rules []NetworkACLNICRule `json:"rules" yaml:"rules"`
+
+	// Action applied to ingress traffic not matching any rule
+	// Example: reject
+	DefaultIngressAction string `json:"default_ingress_action" yaml:"default_ingress_action"`
+
+	// Whether ingress traffic not matching any rule is logged
+	// Example: false
+	DefaultIngressLogged bool `json:"default_ingress_logged" yaml:"default_ingress_logged"`
+
+	// Action applied to egress traffic not matching any rule
+	// Example: reject
+	DefaultEgressAction string `json:"default_egress_action" yaml:"default_egress_action"`
+
+	// Whether egress traffic not matching any rule is logged
+	// Example: false
+	DefaultEgressLogged bool `json:"default_egress_logged" yaml:"default_egress_logged"`
+}
+
+// NetworkACLNICRule represents a network ACL rule applying to an instance NIC.
+//
+// swagger:model
+//
+// API extension: network_acl_nic_rules.
+type NetworkACLNICRule struct {
+	NetworkACLRule `yaml:",inline"`
+
+	// Name of the ACL the rule comes from
+	// Example: web
+	ACL string `json:"acl" yaml:"acl"`
+
+	// Direction of the rule (ingress or egress)
+	// Example: ingress
+	Direction string `json:"direction" yaml:"direction"`
+
+	// Index of the rule in the ACL's rules of that direction
+	// Example: 0
+	Index int `json:"index" yaml:"index"`
+
+	// Whether the rule is inherited from the parents of the ACL, in which case the index is into the inherited rules
+	// Example: false
+	//
+	// API extension: network_acl_parent
+	Inherited bool `json:"inherited" yaml:"inherited"`
+
+	// Whether the rule is currently applied (enabled or logged, and within its validity window)
+	// Example: true
+	Active bool `json:"active" yaml:"active"`
+}
+
+// NetworkACLRuleSearchResult represents a network ACL rule whose description matches a search.
+//
+// swagger:model
+//
+// API extension: network_acl_filter.
+type NetworkACLRuleSearchResult struct {
+	// Project the ACL belongs to
+	// Example: default
+	Project string `json:"project" yaml:"project"`
+
+	// Name of the ACL the rule belongs to
+	// Example: web
+	ACL string `json:"acl" yaml:"acl"`
+
+	// Direction of the rule (ingress or egress)
+	// Example: ingress
+	Direction string `json:"direction" yaml:"direction"`
+
+	// Index of the rule in the ACL's rules of that direction
+	// Example: 0
+	Index int `json:"index" yaml:"index"`
+
+	// Description of the rule
+	// Example: Allow web traffic (SEC-123)
+	Description string `json:"description" yaml:"description"`
+}
+
+// NetworkACLRuleMatch represents a network ACL rule subject matching a searched subject.
+//
+// swagger:model
+//
+// API extension: network_acl_subject_search.
+type NetworkACLRuleMatch struct {
+	// Project the ACL belongs to
+	// Example: default
+	Project string `json:"project" yaml:"project"`
+
+	// Name of the ACL the rule belongs to
+	// Example: web
+	ACL string `json:"acl" yaml:"acl"`
+
+	// Direction of the rule (ingress or egress)
+	// Example: ingress
+	Direction string `json:"direction" yaml:"direction"`
+
+	// Index of the rule in the ACL's rules of that direction
+	// Example: 0
+	Index int `json:"index" yaml:"index"`
+
+	// Field of the rule the subject is in (source or destination)
+	// Example: source
+	Field string `json:"field" yaml:"field"`
+
+	// Subject of the rule matching the searched subject
+	// Example: 10.40.0.0/24
+	Subject string `json:"subject" yaml:"subject"`
+
+	// How the rule subject relates to the searched subject (equal, contains, contained or overlaps)
+	// Example: contained
+	Relation string `json:"relation" yaml:"relation"`
+}
+
+// NetworkACLRuleConflict represents a rule of each of two network ACLs matching some of the same traffic, one of them
+// allowing it and the other dropping or rejecting it.
+//
+// swagger:model
+//
+// API extension: network_acl_conflicts.
+type NetworkACLRuleConflict struct {
+	// Direction of the rules (ingress or egress)
+	// Example: ingress
+	Direction string `json:"direction" yaml:"direction"`
+
+	// Index of the rule in the first ACL's rules of that direction
+	// Example: 0
+	FirstIndex int `json:"first_index" yaml:"first_index"`
+
+	// Action of the rule of the first ACL
+	// Example: allow
+	FirstAction string `json:"first_action" yaml:"first_action"`
+
+	// Index of the rule in the second ACL's rules of that direction
+	// Example: 2
+	SecondIndex int `json:"second_index" yaml:"second_index"`
+
+	// Action of the rule of the second ACL
+	// Example: drop
+	SecondAction string `json:"second_action" yaml:"second_action"`
+}
+
+// NetworkACLUsageSummary represents the number of resources using a network ACL.
+//
+// swagger:model
+type NetworkACLUsageSummary struct {
+	// Name of the ACL
+	// Example: web
+	Name string `json:"name" yaml:"name"`
+
+	// Project the ACL belongs to
+	// Example: default
+	Project string `json:"project" yaml:"project"`
+
+	// Number of networks, profiles, instance NICs and ACLs using the ACL
+	// Example: 2
+	UsageCount int `json:"usage_count" yaml:"usage_count"`
+}